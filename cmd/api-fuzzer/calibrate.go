@@ -0,0 +1,85 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"resttracefuzzer/internal/calibrate"
+	"resttracefuzzer/internal/config"
+	"resttracefuzzer/pkg/static"
+	"resttracefuzzer/pkg/utils/http"
+	"syscall"
+	"time"
+
+	"github.com/bytedance/sonic"
+	hertzclient "github.com/cloudwego/hertz/pkg/app/client"
+	"github.com/rs/zerolog/log"
+)
+
+// calibrateSubcommand probes the server with increasing request rates, and suggests rate and concurrency limits of fuzzing.
+const calibrateSubcommand = "calibrate"
+
+// runCalibration runs the calibrate subcommand.
+// The result is written to the run output directory, and suggestions are written to the config file if provided.
+func runCalibration(APIManager *static.APIManager, extraHeaders map[string]string, runOutputDir string) {
+	calibrator, err := calibrate.NewCalibrator(APIManager, extraHeaders, calibrate.CalibrationConfig{
+		MaxRequestsPerSecond: config.GlobalConfig.CalibrationMaxRequestsPerSecond,
+		StepDuration:         time.Duration(config.GlobalConfig.CalibrationStepDuration) * time.Second,
+	})
+	if err != nil {
+		log.Err(err).Msgf("[runCalibration] Failed to create calibrator")
+		return
+	}
+	// Politeness controls are not set, so the rate is controlled by the calibrator only.
+	httpClient, err := http.NewHTTPClientWithTransport(
+		config.GlobalConfig.ServerBaseURL,
+		[]string{},
+		http.EmptyHTTPClientMiddlewareSlice(),
+		http.TransportConfig{
+			ProxyURL:   config.GlobalConfig.HTTPClientProxyURL,
+			CAFilePath: config.GlobalConfig.HTTPClientCaFilePath,
+		},
+		hertzclient.WithDialTimeout(time.Duration(config.GlobalConfig.HTTPClientDialTimeout)*time.Second),
+	)
+	if err != nil {
+		log.Err(err).Msgf("[runCalibration] Failed to create HTTP client")
+		return
+	}
+
+	log.Info().Msgf("[runCalibration] Calibrating with %d operations, up to %d requests per second", len(calibrator.ProbeMethods), calibrator.Config.MaxRequestsPerSecond)
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	result := calibrator.Run(ctx, httpClient)
+
+	reportPath := fmt.Sprintf("%s/calibration_report.json", runOutputDir)
+	reportBytes, err := sonic.Marshal(result)
+	if err != nil {
+		log.Err(err).Msgf("[runCalibration] Failed to marshal the calibration report")
+		return
+	}
+	if err := os.WriteFile(reportPath, reportBytes, 0644); err != nil {
+		log.Err(err).Msgf("[runCalibration] Failed to write the calibration report to file")
+		return
+	}
+	log.Info().Msgf("[runCalibration] Calibration report has been written to %s", reportPath)
+
+	if result.MaxHealthyRequestsPerSecond == 0 {
+		log.Error().Msg("[runCalibration] The server is unhealthy even at 1 request per second, no suggestion is made")
+		return
+	}
+	log.Info().Msgf("[runCalibration] Max healthy rate: %d requests per second, suggested: --http-client-max-requests-per-second %d --http-client-max-concurrent-requests %d",
+		result.MaxHealthyRequestsPerSecond, result.SuggestedRequestsPerSecond, result.SuggestedConcurrentRequests)
+	if config.GlobalConfig.ConfigFilePath == "" {
+		return
+	}
+	err = config.UpdateConfigFile(config.GlobalConfig.ConfigFilePath, map[string]any{
+		"HTTPClientMaxRequestsPerSecond":  result.SuggestedRequestsPerSecond,
+		"HTTPClientMaxConcurrentRequests": result.SuggestedConcurrentRequests,
+	})
+	if err != nil {
+		log.Err(err).Msgf("[runCalibration] Failed to write suggestions to config file")
+		return
+	}
+	log.Info().Msgf("[runCalibration] Suggestions have been written to config file %s", config.GlobalConfig.ConfigFilePath)
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"resttracefuzzer/internal/config"
+	fuzzruntime "resttracefuzzer/pkg/runtime"
+
+	"github.com/rs/zerolog/log"
+)
+
+// Exit codes of the fuzzer, so that CI pipelines can gate merges on fuzzing outcomes without parsing reports.
+// If several outcomes apply, the one with the smallest non-zero code is returned.
+const (
+	// exitCodeOK indicates that the run completed, and no outcome threshold is violated.
+	exitCodeOK = 0
+
+	// exitCodeRunAborted indicates that the run is aborted due to errors, e.g., invalid config, exceeded error budget, or failing to generate reports.
+	exitCodeRunAborted = 1
+
+	// exitCodeNewFailuresFound indicates that new unique failures are found, at least `exit-code-new-failure-threshold` of them.
+	// Code 2 is skipped, as it is used by the flag package for invalid arguments.
+	exitCodeNewFailuresFound = 3
+
+	// exitCodeCoverageGoalNotMet indicates that internal service edge coverage is below `exit-code-coverage-goal`.
+	exitCodeCoverageGoalNotMet = 4
+
+	// exitCodeServerErrorsFound indicates that any response of server error (5xx) to a generated request is received, if `exit-code-fail-on-5xx` is set.
+	// Responses to replays and under chaos experiments are not counted, see [fuzzer.FuzzingProgress.PrimaryServerErrorCount].
+	exitCodeServerErrorsFound = 5
+)
+
+// resolveOutcomeExitCode returns the exit code of a completed run, by checking its outcomes against the thresholds in config.
+// newFailureCount is the number of new unique failures (failure signatures) found in the run,
+// and serverErrorCount is the number of responses of server errors (5xx) to generated requests received in the run.
+func resolveOutcomeExitCode(newFailureCount int, serverErrorCount int, callInfoGraph *fuzzruntime.CallInfoGraph) int {
+	newFailureThreshold := config.GlobalConfig.ExitCodeNewFailureThreshold
+	if newFailureThreshold > 0 && newFailureCount >= newFailureThreshold {
+		log.Warn().Msgf("[resolveOutcomeExitCode] %d new unique failure(s) found, reaching the threshold %d, exit with code %d", newFailureCount, newFailureThreshold, exitCodeNewFailuresFound)
+		return exitCodeNewFailuresFound
+	}
+
+	coverageGoal := config.GlobalConfig.ExitCodeCoverageGoal
+	if coverageGoal > 0 {
+		// Without edges to cover, the goal can never be met.
+		edgeCoverage := 0.0
+		if len(callInfoGraph.Edges) > 0 {
+			edgeCoverage = callInfoGraph.GetEdgeCoverage()
+		} else {
+			log.Warn().Msg("[resolveOutcomeExitCode] No internal service edge to cover, edge coverage is seen as 0")
+		}
+		if edgeCoverage*100 < float64(coverageGoal) {
+			log.Warn().Msgf("[resolveOutcomeExitCode] Edge coverage %.2f%% is below the goal %d%%, exit with code %d", edgeCoverage*100, coverageGoal, exitCodeCoverageGoalNotMet)
+			return exitCodeCoverageGoalNotMet
+		}
+	}
+
+	if config.GlobalConfig.ExitCodeFailOn5Xx && serverErrorCount > 0 {
+		log.Warn().Msgf("[resolveOutcomeExitCode] %d response(s) of server errors (5xx) received, exit with code %d", serverErrorCount, exitCodeServerErrorsFound)
+		return exitCodeServerErrorsFound
+	}
+	return exitCodeOK
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/bytedance/sonic"
+	"github.com/rs/zerolog"
+)
+
+// logLevels maps from the name of log level in config to zerolog level.
+var logLevels = map[string]zerolog.Level{
+	"":      zerolog.InfoLevel, // Default log level
+	"trace": zerolog.TraceLevel,
+	"debug": zerolog.DebugLevel,
+	"info":  zerolog.InfoLevel,
+	"warn":  zerolog.WarnLevel,
+	"error": zerolog.ErrorLevel,
+	"fatal": zerolog.FatalLevel,
+	"panic": zerolog.PanicLevel,
+}
+
+// componentLevelHook is a zerolog hook that filters log events by per-component levels.
+// The component of an event is extracted from the prefix of its message, e.g., `CaseManager` in `[CaseManager.Pop] ...`,
+// and added to the event as field `component`.
+// As zerolog drops events below global level before hooks run, the global level should be set to the lowest level of all components.
+type componentLevelHook struct {
+	// defaultLevel is the level of components without overrides.
+	defaultLevel zerolog.Level
+
+	// componentLevels maps from lowercase component name to its level.
+	componentLevels map[string]zerolog.Level
+}
+
+// Run implements [zerolog.Hook].
+func (h componentLevelHook) Run(e *zerolog.Event, level zerolog.Level, msg string) {
+	component := extractLogComponent(msg)
+	threshold := h.defaultLevel
+	if componentLevel, exists := h.componentLevels[strings.ToLower(component)]; exists {
+		threshold = componentLevel
+	}
+	if level < threshold {
+		e.Discard()
+		return
+	}
+	if component != "" {
+		e.Str("component", component)
+	}
+}
+
+// extractLogComponent extracts the component from the prefix of a log message.
+// For example, it returns `CaseManager` for `[CaseManager.Pop] ...`, and `main` for `[main] ...`.
+// It returns empty string if the message has no such prefix.
+func extractLogComponent(msg string) string {
+	if !strings.HasPrefix(msg, "[") {
+		return ""
+	}
+	end := strings.Index(msg, "]")
+	if end < 0 {
+		return ""
+	}
+	component, _, _ := strings.Cut(msg[1:end], ".")
+	return component
+}
+
+// newComponentLevelHook creates a componentLevelHook from the stringified JSON map of component levels.
+// It also returns the lowest level of all components (including the default one), which should be used as the global level.
+func newComponentLevelHook(defaultLevel zerolog.Level, componentLevelsJSON string) (componentLevelHook, zerolog.Level, error) {
+	hook := componentLevelHook{
+		defaultLevel:    defaultLevel,
+		componentLevels: make(map[string]zerolog.Level),
+	}
+	lowestLevel := defaultLevel
+	if componentLevelsJSON == "" {
+		return hook, lowestLevel, nil
+	}
+	var componentLevelNames map[string]string
+	err := sonic.UnmarshalString(componentLevelsJSON, &componentLevelNames)
+	if err != nil {
+		return hook, lowestLevel, err
+	}
+	for component, levelName := range componentLevelNames {
+		level, exists := logLevels[levelName]
+		if !exists {
+			return hook, lowestLevel, fmt.Errorf("unsupported log level %s of component %s", levelName, component)
+		}
+		hook.componentLevels[strings.ToLower(component)] = level
+		lowestLevel = min(lowestLevel, level)
+	}
+	return hook, lowestLevel, nil
+}
+
+// newLogWriter wraps the writer according to the log format.
+// Format `console` produces human-readable output (colored if `colored` is true), and `json` (or empty) keeps structured JSON output.
+func newLogWriter(w io.Writer, format string, colored bool) (io.Writer, error) {
+	switch format {
+	case "", "json":
+		return w, nil
+	case "console":
+		return zerolog.ConsoleWriter{Out: w, NoColor: !colored}, nil
+	default:
+		return nil, fmt.Errorf("unsupported log format: %s", format)
+	}
+}
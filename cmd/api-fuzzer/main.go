@@ -1,22 +1,38 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"math"
+	"math/rand/v2"
+	"net/url"
 	"os"
+	"os/signal"
+	"path/filepath"
 	"resttracefuzzer/internal/config"
+	"resttracefuzzer/internal/control"
 	"resttracefuzzer/internal/fuzzer"
+	"resttracefuzzer/internal/tui"
 	"resttracefuzzer/pkg/casemanager"
 	"resttracefuzzer/pkg/feedback"
 	"resttracefuzzer/pkg/feedback/trace"
+	"resttracefuzzer/pkg/knowledge"
 	"resttracefuzzer/pkg/parser"
 	"resttracefuzzer/pkg/report"
 	"resttracefuzzer/pkg/resource"
 	fuzzruntime "resttracefuzzer/pkg/runtime"
 	"resttracefuzzer/pkg/static"
 	"resttracefuzzer/pkg/strategy"
+	"resttracefuzzer/pkg/utils"
+	"resttracefuzzer/pkg/utils/grpc"
+	"resttracefuzzer/pkg/utils/http"
+	"slices"
+	"syscall"
 	"time"
 
 	"github.com/bytedance/sonic"
+	"github.com/getkin/kin-openapi/openapi3"
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
 )
@@ -36,6 +52,11 @@ const HELLO = "" +
 	" '----------------'  '----------------'  '----------------'  '----------------'  '----------------' \n"
 
 func main() {
+	os.Exit(run())
+}
+
+// run runs the fuzzer, and returns the exit code of the run, see [exitCodeOK].
+func run() int {
 
 	// Record the start time
 	t := time.Now()
@@ -43,51 +64,122 @@ func main() {
 	// Initialize logger with default log level (Info)
 	// If user specifies a log level in the command line arguments, we will override it later
 	zerolog.SetGlobalLevel(zerolog.InfoLevel)
-	fmt.Print(HELLO)
+
+	// The subcommand, if any, is the first argument, followed by flags
+	subcommand := ""
+	if len(os.Args) > 1 && os.Args[1] == calibrateSubcommand {
+		subcommand = os.Args[1]
+		os.Args = append(os.Args[:1], os.Args[2:]...)
+	}
 
 	// Parse command line arguments and environment variables
 	config.InitConfig()
-	config.ParseCmdArgs()
+	err := config.ParseCmdArgs()
+	if err != nil {
+		log.Err(err).Msgf("[main] Failed to parse config")
+		return exitCodeRunAborted
+	}
+
+	// Only the template is printed, so that stdout can be redirected to a config file.
+	if config.GlobalConfig.PrintDefaultConfig {
+		fmt.Print(config.GenerateDefaultConfigTemplate())
+		return exitCodeOK
+	}
+	fmt.Print(HELLO)
+
+	// Check required config before any work is done, with flags and environment variables to set them.
+	err = config.ValidateRequiredConfig(config.GlobalConfig)
+	if err != nil {
+		log.Err(err).Msgf("[main] Invalid configuration")
+		return exitCodeRunAborted
+	}
 
 	// Override log level if specified in the command line arguments
-	logLevels := map[string]zerolog.Level{
-		"":      zerolog.InfoLevel, // Default log level
-		"trace": zerolog.TraceLevel,
-		"debug": zerolog.DebugLevel,
-		"info":  zerolog.InfoLevel,
-		"warn":  zerolog.WarnLevel,
-		"error": zerolog.ErrorLevel,
-		"fatal": zerolog.FatalLevel,
-		"panic": zerolog.PanicLevel,
-	}
-
-	if level, exists := logLevels[config.GlobalConfig.LogLevel]; exists {
-		zerolog.SetGlobalLevel(level)
-	} else {
+	level, exists := logLevels[config.GlobalConfig.LogLevel]
+	if !exists {
 		log.Error().Msgf("[main] Unsupported log level: %s", config.GlobalConfig.LogLevel)
-		return
+		return exitCodeRunAborted
+	}
+	// Components may override the log level, so the global level is the lowest one of them,
+	// and events are filtered by the hook.
+	componentLevelHook, lowestLevel, err := newComponentLevelHook(level, config.GlobalConfig.LogComponentLevels)
+	if err != nil {
+		log.Err(err).Msgf("[main] Failed to parse log component levels")
+		return exitCodeRunAborted
+	}
+	zerolog.SetGlobalLevel(lowestLevel)
+	stderrWriter, err := newLogWriter(os.Stderr, config.GlobalConfig.LogFormat, true)
+	if err != nil {
+		log.Err(err).Msgf("[main] Failed to create log writer")
+		return exitCodeRunAborted
+	}
+	log.Logger = log.Output(stderrWriter).Hook(componentLevelHook)
+
+	// Seed the shared random number generator before any component is created, so that the run can be reproduced by the seed.
+	// The chosen seed is written back to the config, and recorded in the fuzzer state report.
+	if config.GlobalConfig.Seed == 0 {
+		config.GlobalConfig.Seed = rand.IntN(math.MaxInt32) + 1
+	}
+	utils.SeedSharedRand(uint64(config.GlobalConfig.Seed))
+	log.Info().Msgf("[main] Random seed: %d, use --seed %d to reproduce this run", config.GlobalConfig.Seed, config.GlobalConfig.Seed)
+
+	// Validate weight maps before any work is done, as the strategies fall back to default weights silently.
+	err = strategy.ValidateWeightMapConfig()
+	if err != nil {
+		log.Err(err).Msgf("[main] Invalid weight map configuration")
+		return exitCodeRunAborted
 	}
 
-	// used to format the time in the output file name
+	// used to format the time in the name of run output directory
 	// We do not use RFC3339 format because it contains colons, which are not allowed in Windows file names.
 	outputFileTimeFormat := "20060102150405"
 
+	// Artifacts of each run are written to a timestamped subdirectory of the output directory,
+	// and symlink `latest` points to the directory of the current run.
+	runOutputDir, err := utils.CreateRunOutputDir(config.GlobalConfig.OutputDir, t.Format(outputFileTimeFormat))
+	if err != nil {
+		log.Err(err).Msgf("[main] Failed to create the run output directory")
+		return exitCodeRunAborted
+	}
+
+	// The TUI refreshes the terminal in place, so it requires the standard output to be a terminal,
+	// and logs are written to file instead, otherwise they would mess up the display.
+	if config.GlobalConfig.TUI && !tui.IsTerminal(os.Stdout) {
+		log.Warn().Msgf("[main] The standard output is not a terminal, TUI is disabled")
+		config.GlobalConfig.TUI = false
+	}
+
 	// Log to file if specified
-	if config.GlobalConfig.LogToFile {
-		logFilePath := fmt.Sprintf("%s/log_%s.log", config.GlobalConfig.OutputDir, t.Format(outputFileTimeFormat))
+	if config.GlobalConfig.LogToFile || config.GlobalConfig.TUI {
+		logFilePath := fmt.Sprintf("%s/log.log", runOutputDir)
 		fileWriter, err := os.Create(logFilePath)
 		if err != nil {
 			log.Err(err).Msgf("[main] Failed to create log file: %s", logFilePath)
-			return
+			return exitCodeRunAborted
 		}
 		log.Info().Msgf("[main] Log to file is enabled, I will write logs to %s", logFilePath)
-		log.Logger = log.Output(fileWriter)
+		fileLogWriter, err := newLogWriter(fileWriter, config.GlobalConfig.LogFormat, false)
+		if err != nil {
+			log.Err(err).Msgf("[main] Failed to create log writer")
+			return exitCodeRunAborted
+		}
+		log.Logger = log.Output(fileLogWriter).Hook(componentLevelHook)
 
-		// log config again to file
-		configStr, _ := sonic.MarshalString(config.GlobalConfig)
+		// log config again to file, with secrets redacted
+		configStr, _ := sonic.MarshalString(config.GlobalConfig.Redacted())
 		log.Info().Msgf("[main] Fuzzer config: %s", configStr)
 	}
 
+	// Remove output directories of old runs, keeping the most recent ones (including the current one).
+	removedRunOutputDirs, err := utils.CleanupOldRunOutputDirs(config.GlobalConfig.OutputDir, config.GlobalConfig.OutputRunRetention)
+	if err != nil {
+		// Failing to remove old runs should not stop fuzzing.
+		log.Err(err).Msgf("[main] Failed to clean up old run output directories")
+	}
+	for _, removedDir := range removedRunOutputDirs {
+		log.Info().Msgf("[main] Removed old run output directory: %s", removedDir)
+	}
+
 	APIManager := static.NewAPIManager()
 
 	// read system OpenAPI spec and parse it
@@ -95,41 +187,171 @@ func main() {
 	systemDoc, err := APIParser.ParseSystemDocFromPath(config.GlobalConfig.OpenAPISpecPath)
 	if err != nil {
 		log.Err(err).Msgf("[main] Failed to parse system OpenAPI spec")
-		return
+		return exitCodeRunAborted
 	}
 
-	// Parse doc of internal services
-	serviceDoc, err := APIParser.ParseServiceDocFromPath(config.GlobalConfig.InternalServiceOpenAPIPath)
+	// Resolve the server base URL from servers of the system doc, if not specified.
+	// Servers are not resolved otherwise, so that invalid servers of the doc do not abort runs with the server base URL specified.
+	if config.GlobalConfig.ServerBaseURL == "" {
+		serverVariables := make(map[string]string)
+		if config.GlobalConfig.ServerVariables != "" {
+			err = sonic.UnmarshalString(config.GlobalConfig.ServerVariables, &serverVariables)
+			if err != nil {
+				log.Err(err).Msgf("[main] Failed to parse server variables")
+				return exitCodeRunAborted
+			}
+		}
+		serverURLs, err := static.ResolveServerURLs(systemDoc, serverVariables)
+		if err != nil {
+			log.Err(err).Msgf("[main] Failed to resolve servers of system OpenAPI spec")
+			return exitCodeRunAborted
+		}
+		serverIndex := config.GlobalConfig.ServerIndex
+		if config.GlobalConfig.ServerRotation {
+			// Relative servers are skipped in rotation, and the first absolute one comes first.
+			serverURLs = slices.DeleteFunc(serverURLs, func(serverURL string) bool {
+				return !static.IsAbsoluteServerURL(serverURL)
+			})
+			serverIndex = 0
+			APIManager.ServerURLs = serverURLs
+		}
+		if serverIndex < 0 || serverIndex >= len(serverURLs) {
+			log.Error().Msgf("[main] Server base URL is not specified by --server-base-url, and server %d is not found in %d server(s) of system OpenAPI spec", serverIndex, len(serverURLs))
+			return exitCodeRunAborted
+		}
+		if !static.IsAbsoluteServerURL(serverURLs[serverIndex]) {
+			log.Error().Msgf("[main] Server base URL is not specified by --server-base-url, and server %d of system OpenAPI spec is not an absolute URL: %s", serverIndex, serverURLs[serverIndex])
+			return exitCodeRunAborted
+		}
+		config.GlobalConfig.ServerBaseURL = serverURLs[serverIndex]
+		log.Info().Msgf("[main] Server base URL resolved from system OpenAPI spec: %s", config.GlobalConfig.ServerBaseURL)
+	} else if config.GlobalConfig.ServerRotation {
+		log.Warn().Msgf("[main] Server rotation is disabled, as server base URL is specified: %s", config.GlobalConfig.ServerBaseURL)
+	}
+
+	// Parse docs of additional targets (gateways) of the system, if any, whose operations are fuzzed together with those of the system doc
+	APITargets, err := static.ParseAPITargets(config.GlobalConfig.AdditionalTargets)
+	if err != nil {
+		log.Err(err).Msgf("[main] Failed to parse additional targets")
+		return exitCodeRunAborted
+	}
+	targetSpecPaths := make([]string, 0, len(APITargets))
+	for _, target := range APITargets {
+		target.Doc, err = APIParser.ParseSystemDocFromPath(target.OpenAPISpecPath)
+		if err != nil {
+			log.Err(err).Msgf("[main] Failed to parse OpenAPI spec of additional target %s", target.Name)
+			return exitCodeRunAborted
+		}
+		targetSpecPaths = append(targetSpecPaths, target.OpenAPISpecPath)
+		log.Info().Msgf("[main] Additional target %s: %s, spec: %s", target.Name, target.ServerBaseURL, target.OpenAPISpecPath)
+	}
+	APIManager.SetAPITargets(APITargets)
+
+	// Operations excluded (or not included) by filters are not fuzzed
+	operationFilter, err := static.NewOperationFilter(config.GlobalConfig.IncludeOperations, config.GlobalConfig.ExcludeOperations)
 	if err != nil {
-		log.Err(err).Msgf("[main] Failed to parse internal service OpenAPI spec")
-		return
+		log.Err(err).Msgf("[main] Failed to parse operation filters")
+		return exitCodeRunAborted
+	}
+	APIManager.SetOperationFilter(operationFilter)
+
+	// Parse doc of internal services
+	// If not provided, use an empty doc, i.e., no internal service API is known before fuzzing
+	serviceDoc := &openapi3.T{Paths: openapi3.NewPaths()}
+	if config.GlobalConfig.InternalServiceOpenAPIPath != "" {
+		serviceDoc, err = APIParser.ParseServiceDocFromPath(config.GlobalConfig.InternalServiceOpenAPIPath)
+		if err != nil {
+			log.Err(err).Msgf("[main] Failed to parse internal service OpenAPI spec")
+			return exitCodeRunAborted
+		}
+	} else {
+		log.Warn().Msg("[main] Internal service OpenAPI spec is not provided")
+	}
+
+	// Parse AsyncAPI docs if provided
+	// Channels of the system doc become message-producing operations, and those of the internal service doc contribute to the dataflow graph.
+	asyncAPIParser := parser.NewAsyncAPIParser()
+	var systemAsyncAPIDoc, serviceAsyncAPIDoc *static.AsyncAPIDoc
+	if config.GlobalConfig.AsyncAPISpecPath != "" {
+		if config.GlobalConfig.MessagingBrokerType == "" {
+			log.Error().Msgf("[main] Messaging broker type is required if AsyncAPI spec is provided")
+			return exitCodeRunAborted
+		}
+		systemAsyncAPIDoc, err = asyncAPIParser.ParseSystemDocFromPath(config.GlobalConfig.AsyncAPISpecPath)
+		if err != nil {
+			log.Err(err).Msgf("[main] Failed to parse AsyncAPI spec")
+			return exitCodeRunAborted
+		}
+	}
+	if config.GlobalConfig.InternalServiceAsyncAPISpecPath != "" {
+		serviceAsyncAPIDoc, err = asyncAPIParser.ParseServiceDocFromPath(config.GlobalConfig.InternalServiceAsyncAPISpecPath)
+		if err != nil {
+			log.Err(err).Msgf("[main] Failed to parse internal service AsyncAPI spec")
+			return exitCodeRunAborted
+		}
+	}
+
+	// Open the knowledge base of the docs if specified, to reuse artifacts learned in previous runs
+	var knowledgeBase *knowledge.KnowledgeBase
+	if config.GlobalConfig.KnowledgeBaseDir != "" {
+		specPaths := append([]string{config.GlobalConfig.OpenAPISpecPath, config.GlobalConfig.InternalServiceOpenAPIPath, config.GlobalConfig.AsyncAPISpecPath, config.GlobalConfig.InternalServiceAsyncAPISpecPath}, targetSpecPaths...)
+		knowledgeBase, err = knowledge.NewKnowledgeBase(config.GlobalConfig.KnowledgeBaseDir, specPaths...)
+		if err != nil {
+			log.Err(err).Msgf("[main] Failed to open knowledge base")
+			return exitCodeRunAborted
+		}
+	}
+
+	// gRPC operations of the system doc are executed natively only if descriptors of their services are loaded
+	if config.GlobalConfig.GrpcDescriptorSetFilePath != "" {
+		grpcDescriptors, err := grpc.LoadDescriptorSetFile(config.GlobalConfig.GrpcDescriptorSetFilePath)
+		if err != nil {
+			log.Err(err).Msgf("[main] Failed to load gRPC descriptor set file: %s", config.GlobalConfig.GrpcDescriptorSetFilePath)
+			return exitCodeRunAborted
+		}
+		APIManager.SetGRPCDescriptors(grpcDescriptors)
 	}
 
 	// Initialize the API manager using parsed docs
-	APIManager.InitFromDocs(systemDoc, serviceDoc)
+	// The dataflow graph is loaded from knowledge base if available, otherwise parsed from docs.
+	var dataflowGraph *static.APIDataflowGraph
+	if knowledgeBase != nil {
+		dataflowGraph, err = knowledgeBase.LoadDataflowGraph()
+		if err != nil {
+			log.Err(err).Msgf("[main] Failed to load dataflow graph from knowledge base, parse it from docs instead")
+		}
+	}
+	APIManager.InitFromDocsWithAsyncAPIDocs(systemDoc, serviceDoc, systemAsyncAPIDoc, serviceAsyncAPIDoc, dataflowGraph)
+	if len(APIManager.FilteredOperations) > 0 {
+		log.Info().Msgf("[main] %d operation(s) are excluded by operation filters, %d operation(s) to fuzz", len(APIManager.FilteredOperations), len(APIManager.APIMap))
+	}
 
 	// Read API dependency files
-	// You can generate the dependency files by running tools we support (e.g., Restler)
+	// You can generate the dependency files by running tools we support (e.g., Restler, EvoMaster, RestTestGen)
 	if config.GlobalConfig.DependencyFileType != "" {
-		dependencyFileParser, err := parser.NewAPIDependencyParserByType(config.GlobalConfig.DependencyFileType)
+		dependencyFileParser, err := parser.NewAPIDependencyParserByType(config.GlobalConfig.DependencyFileType, APIManager)
 		if err != nil {
 			log.Err(err).Msgf("[main] Failed to create dependency file parser, type: %s", config.GlobalConfig.DependencyFileType)
-			return
+			return exitCodeRunAborted
 		}
 		dependencyGraph, err := dependencyFileParser.ParseFromFile(config.GlobalConfig.DependencyFilePath)
 		if err != nil {
 			log.Err(err).Msgf("[main] Failed to parse dependency file, path: %s", config.GlobalConfig.DependencyFilePath)
-			return
+			return exitCodeRunAborted
 		}
 		var internalServiceAPIDependencyGraphMap map[string]*static.APIDependencyGraph
 		if config.GlobalConfig.InternalServiceAPIDependencyFilePath != "" {
 			internalServiceAPIDependencyGraphMap, err = dependencyFileParser.ParseFromServiceMapFile(config.GlobalConfig.InternalServiceAPIDependencyFilePath)
 			if err != nil {
 				log.Err(err).Msgf("[main] Failed to parse internal service API dependency map file, path: %s", config.GlobalConfig.InternalServiceAPIDependencyFilePath)
-				return
+				return exitCodeRunAborted
 			}
 		}
 		APIManager.InitDependencyGraph(dependencyGraph, internalServiceAPIDependencyGraphMap)
+	} else if config.GlobalConfig.InferAPIDependency {
+		// Without dependency files, infer system API dependencies from the OpenAPI doc instead
+		dependencyGraph := static.NewAPIDependencyInferencer().InferFromAPIMap(APIManager.APIMap)
+		APIManager.InitDependencyGraph(dependencyGraph, nil)
 	}
 
 	// Parse extra headers
@@ -138,12 +360,29 @@ func main() {
 		err = sonic.UnmarshalString(config.GlobalConfig.ExtraHeaders, &extraHeaders)
 		if err != nil {
 			log.Err(err).Msgf("[main] Failed to parse extra headers")
-			return
+			return exitCodeRunAborted
 		}
 	}
 
+	// The calibrate subcommand only probes rate limits of the server, and no fuzzing is performed
+	if subcommand == calibrateSubcommand {
+		runCalibration(APIManager, extraHeaders, runOutputDir)
+		return exitCodeOK
+	}
+
 	// Initialize necessary components
 	resourceManager := resource.NewResourceManager()
+	if config.GlobalConfig.FuzzValueDictLocaleWeights != "" {
+		localeWeights := make(map[string]int)
+		err = sonic.UnmarshalString(config.GlobalConfig.FuzzValueDictLocaleWeights, &localeWeights)
+		if err == nil {
+			err = resourceManager.SetLocaleWeights(localeWeights)
+		}
+		if err != nil {
+			log.Err(err).Msgf("[main] Failed to parse locale weights of fuzz value dictionary")
+			return exitCodeRunAborted
+		}
+	}
 	if config.GlobalConfig.FuzzValueDictFilePath != "" {
 		err = resourceManager.LoadFromExternalDictFile(config.GlobalConfig.FuzzValueDictFilePath)
 		// If failed to load resources from external dictionary file, log the error;
@@ -152,85 +391,560 @@ func main() {
 			log.Err(err).Msgf("[main] Failed to load resources from external dictionary file")
 		}
 	}
-	fuzzStrategist := strategy.NewFuzzStrategist(resourceManager)
+	// Values documented in the API doc are realistic, so they are used from the beginning of the campaign
+	resourceManager.StoreSpecExamples(APIManager.APIMap)
+	if knowledgeBase != nil {
+		err = knowledgeBase.LoadResourcePool(resourceManager)
+		if err != nil {
+			log.Err(err).Msgf("[main] Failed to load resource pool from knowledge base")
+		}
+	}
+	fuzzStrategist, err := strategy.NewFuzzStrategist(resourceManager)
+	if err != nil {
+		log.Err(err).Msgf("[main] Failed to create fuzz strategist")
+		return exitCodeRunAborted
+	}
 	resourceMutateStrategist := strategy.NewResourceMutateStrategy()
+	capabilityGaps := fuzzStrategist.SchemaToValueStrategy.FindCapabilityGaps(APIManager)
+	if len(capabilityGaps) > 0 {
+		log.Warn().Msgf("[main] %d capability gaps found in the API doc, which the fuzzer could not exercise, see capabilityGaps of the system report", len(capabilityGaps))
+	}
 	responseProcesser := feedback.NewResponseProcesser(APIManager, resourceManager)
+	crudOracle := feedback.NewCRUDOracle(APIManager)
+	responseProcesser.RegisterScenarioEvaluator(crudOracle)
+	securityOracle := feedback.NewSecurityOracle(fuzzStrategist.SchemaToValueStrategy.SecurityPayloadDict)
+	responseProcesser.RegisterScenarioEvaluator(securityOracle)
+	bolaOracle := feedback.NewBOLAOracle(APIManager)
+	latencySLOOracle, err := feedback.NewLatencySLOOracle(APIManager, config.GlobalConfig.LatencySlos, float64(config.GlobalConfig.LatencySloDefault), float64(config.GlobalConfig.LatencySloPercentile))
+	if err != nil {
+		log.Err(err).Msgf("[main] Failed to create latency SLO oracle")
+		return exitCodeRunAborted
+	}
+	responseProcesser.RegisterScenarioEvaluator(latencySLOOracle)
+	spanLatencyAnomalyDetector := feedback.NewSpanLatencyAnomalyDetector(float64(config.GlobalConfig.SpanLatencyAnomalyPercentile), float64(config.GlobalConfig.SpanLatencyAnomalyMultiplier), config.GlobalConfig.SpanLatencyAnomalyMinSamples)
+	responseProcesser.RegisterScenarioEvaluator(spanLatencyAnomalyDetector)
+	contextPropagationOracle := feedback.NewContextPropagationOracle(config.GlobalConfig.ContextPropagationHeaders)
+	responseProcesser.RegisterScenarioEvaluator(contextPropagationOracle)
+	statusCodeTargetTracker, err := feedback.NewStatusCodeTargetTracker(APIManager, config.GlobalConfig.StatusCodeTargets, config.GlobalConfig.StatusCodeTargetNegativePercent)
+	if err != nil {
+		log.Err(err).Msgf("[main] Failed to create status code target tracker")
+		return exitCodeRunAborted
+	}
+	responseProcesser.RegisterScenarioEvaluator(statusCodeTargetTracker)
+	// Created resources are always tracked, so that those left in the system are reported, even if they are not cleaned up.
+	createdResourceTracker := feedback.NewCreatedResourceTracker(APIManager)
+	responseProcesser.RegisterScenarioEvaluator(createdResourceTracker)
+	// Internal service APIs appearing in traces are tracked against the internal service doc, to find documented ones never reached.
+	var internalAPIReachTracker *feedback.InternalAPIReachTracker
+	if config.GlobalConfig.InternalServiceOpenAPIPath != "" {
+		internalAPIReachTracker = feedback.NewInternalAPIReachTracker(APIManager)
+		responseProcesser.RegisterScenarioEvaluator(internalAPIReachTracker)
+	}
+	scenarioMinimizer := feedback.NewScenarioMinimizer(config.GlobalConfig.ScenarioMinimizationMaxExecutions)
+	idempotencyOracle := feedback.NewIdempotencyOracle(config.GlobalConfig.IdempotencyCheckMaxReplays)
+	// Differential oracle compares responses of the baseline and the candidate versions, if a candidate is configured
+	differentialOracle, err := feedback.NewDifferentialOracle(config.GlobalConfig.DifferentialIgnoredFields)
+	if err != nil {
+		log.Err(err).Msgf("[main] Failed to create differential oracle")
+		return exitCodeRunAborted
+	}
+	// Failure signatures are always tracked, as new unique failures decide the exit code, see [exitCodeNewFailuresFound].
+	// Without knowledge base, all failure signatures found in this run are new.
+	var knownFailureSignatures []*knowledge.FailureSignature
+	if knowledgeBase != nil {
+		knownFailureSignatures, err = knowledgeBase.LoadFailureSignatures()
+		if err != nil {
+			log.Err(err).Msgf("[main] Failed to load failure signatures from knowledge base")
+		}
+	}
+	failureSignatureTracker := knowledge.NewFailureSignatureTracker(knownFailureSignatures)
+	responseProcesser.RegisterScenarioEvaluator(failureSignatureTracker)
+	enumCoverageTracker := feedback.NewEnumCoverageTracker(APIManager)
+	endpointPerformanceTracker := feedback.NewEndpointPerformanceTracker()
+	// Code coverage of services under test is an optional feedback signal, in addition to trace-based coverage.
+	var codeCoverageTracker *feedback.CodeCoverageTracker
+	coverageCollectors, err := feedback.NewCoverageCollectors(config.GlobalConfig.CodeCoverageCollectors, config.GlobalConfig.HTTPClientCaFilePath)
+	if err != nil {
+		log.Err(err).Msgf("[main] Failed to create code coverage collectors")
+		return exitCodeRunAborted
+	}
+	if len(coverageCollectors) > 0 {
+		codeCoverageTracker = feedback.NewCodeCoverageTracker(coverageCollectors)
+	}
 	traceDBs := make([]trace.TraceDB, 0) // traceDBs is a list of trace databases, used to store traces
+	var rawTraceSaver *trace.RawTraceFileSaver
 	if config.GlobalConfig.SaveRawTrace {
-		saveDir := fmt.Sprintf("%s/raw_trace_%s", config.GlobalConfig.OutputDir, t.Format(outputFileTimeFormat))
-		traceDBs = append(traceDBs, trace.NewRawTraceFileSaver(saveDir))
+		saveDir := fmt.Sprintf("%s/raw_trace", runOutputDir)
+		rawTraceSaver = trace.NewRawTraceFileSaver(saveDir, config.GlobalConfig.OutputCompression)
+		traceDBs = append(traceDBs, rawTraceSaver)
+	}
+	// The synthesizer also collects observed internal service APIs for schema drift detection.
+	var serviceDocSynthesizer *trace.ServiceDocSynthesizer
+	if config.GlobalConfig.SynthesizeInternalServiceOpenAPI || config.GlobalConfig.DetectSchemaDrift || config.GlobalConfig.DetectShadowAPIs {
+		serviceDocSynthesizer = trace.NewServiceDocSynthesizer()
+		traceDBs = append(traceDBs, serviceDocSynthesizer)
+	}
+	// The recent trace buffer keeps summaries of recent traces for the dashboard.
+	var recentTraceBuffer *trace.RecentTraceBuffer
+	if config.GlobalConfig.DashboardAddress != "" {
+		recentTraceBuffer = trace.NewRecentTraceBuffer(control.DashboardRecentTraceCount)
+		traceDBs = append(traceDBs, recentTraceBuffer)
 	}
 	traceManager := trace.NewTraceManager(traceDBs)
 	callInfoGraph := fuzzruntime.NewCallInfoGraph(APIManager.APIDataflowGraph)
 	reachabilityMap := fuzzruntime.NewRuntimeReachabilityMapFromStaticMap(APIManager.StaticReachabilityMap)
-	caseManager := casemanager.NewCaseManager(APIManager, resourceManager, fuzzStrategist, resourceMutateStrategist, reachabilityMap, extraHeaders)
+	reachabilityMap.SetDecayHalfLife(config.GlobalConfig.ReachabilityDecayHalfLife)
+	if knowledgeBase != nil {
+		err = knowledgeBase.LoadReachability(reachabilityMap)
+		if err != nil {
+			log.Err(err).Msgf("[main] Failed to load reachability from knowledge base")
+		}
+	}
+	// Parse operation header rules, i.e., extra headers of matched API methods
+	operationHeaderRules, err := casemanager.ParseOperationHeaderRules(config.GlobalConfig.OperationExtraHeaders)
+	if err != nil {
+		log.Err(err).Msgf("[main] Failed to parse operation extra headers")
+		return exitCodeRunAborted
+	}
+	// Parse user sessions, for BOLA (IDOR) detection
+	userSessions, err := casemanager.ParseUserSessions(config.GlobalConfig.UserSessions)
+	if err != nil {
+		log.Err(err).Msgf("[main] Failed to parse user sessions")
+		return exitCodeRunAborted
+	}
+	// Credentials of user sessions must not be overridden by OAuth2 or request signing, otherwise replays as other users are sent as the primary user
+	overriddenHeaders := make([]string, 0)
+	if config.GlobalConfig.Oauth2GrantType != "" || config.GlobalConfig.RequestSigningType == http.RequestSigningTypeSigV4 {
+		overriddenHeaders = append(overriddenHeaders, "Authorization")
+	}
+	if config.GlobalConfig.RequestSigningType == http.RequestSigningTypeHMAC {
+		overriddenHeaders = append(overriddenHeaders, config.GlobalConfig.RequestSigningHmacHeader)
+	}
+	if err := casemanager.CheckUserSessionHeadersNotOverridden(userSessions, overriddenHeaders); err != nil {
+		log.Err(err).Msgf("[main] User sessions conflict with OAuth2 or request signing")
+		return exitCodeRunAborted
+	}
+	// Scenario extension policy decides candidate operations to extend test scenarios with
+	extensionPolicy, err := casemanager.NewScenarioExtensionPolicy(config.GlobalConfig.ScenarioExtensionPolicyWeights, APIManager, reachabilityMap)
+	if err != nil {
+		log.Err(err).Msgf("[main] Failed to create scenario extension policy")
+		return exitCodeRunAborted
+	}
+	// Service mesh headers are added to each request, to keep traces stitched in mesh-based systems
+	meshHeaderPropagator, err := casemanager.NewMeshHeaderPropagator(config.GlobalConfig.MeshHeaderPresets)
+	if err != nil {
+		log.Err(err).Msgf("[main] Failed to create mesh header propagator")
+		return exitCodeRunAborted
+	}
+	// The call info graph guides scenario extension toward uncovered edges, if enabled
+	var extensionCallInfoGraph *fuzzruntime.CallInfoGraph
+	if config.GlobalConfig.EnableDistanceGuidedExtension {
+		extensionCallInfoGraph = callInfoGraph
+	}
+	caseManager := casemanager.NewCaseManager(APIManager, resourceManager, fuzzStrategist, resourceMutateStrategist, reachabilityMap, extensionCallInfoGraph, extraHeaders, operationHeaderRules, userSessions, extensionPolicy, statusCodeTargetTracker, meshHeaderPropagator)
+	// Recorded traffic is injected as initial test scenarios, if provided
+	if config.GlobalConfig.SeedTrafficFile != "" {
+		seedParser, err := parser.NewSeedParserByType(config.GlobalConfig.SeedTrafficFileType)
+		if err != nil {
+			log.Err(err).Msgf("[main] Failed to create seed traffic file parser, type: %s", config.GlobalConfig.SeedTrafficFileType)
+			return exitCodeRunAborted
+		}
+		seedScenarios, err := seedParser.ParseFromFile(config.GlobalConfig.SeedTrafficFile)
+		if err != nil {
+			log.Err(err).Msgf("[main] Failed to parse seed traffic file: %s", config.GlobalConfig.SeedTrafficFile)
+			return exitCodeRunAborted
+		}
+		injectedCount := caseManager.InjectSeedScenarios(seedScenarios, config.GlobalConfig.ServerBaseURL)
+		log.Info().Msgf("[main] Injected %d of %d seed scenarios from seed traffic file", injectedCount, len(seedScenarios))
+	}
 
 	// testLogReporter logs the tested operations
-	testLogReporter := report.NewTestLogReporter()
+	testLogReporter := report.NewTestLogReporter(config.GlobalConfig.OutputCompression)
+	if rawTraceSaver != nil {
+		// Operation cases in the report link to their saved traces, by paths relative to the run output directory.
+		testLogReporter.TraceFileLocator = func(traceID string) (string, bool) {
+			traceFile, saved := rawTraceSaver.GetSavedTraceFilePath(traceID)
+			if !saved {
+				return "", false
+			}
+			if relPath, err := filepath.Rel(runOutputDir, traceFile); err == nil {
+				traceFile = relPath
+			}
+			return traceFile, true
+		}
+	}
+
+	// scenarioExporter exports failing test scenarios as standalone test files, if specified
+	var scenarioExporter *report.ScenarioExporter
+	if config.GlobalConfig.ScenarioExportFormat != "" {
+		scenarioExporter, err = report.NewScenarioExporter(config.GlobalConfig.ScenarioExportFormat)
+		if err != nil {
+			log.Err(err).Msgf("[main] Failed to create scenario exporter")
+			return exitCodeRunAborted
+		}
+	}
+
+	// httpCaptureBuffer keeps the most recent requests sent by the fuzzer, for live debugging
+	httpCaptureBuffer := http.NewHTTPCaptureBuffer(config.GlobalConfig.HTTPCaptureBufferSize)
+	// harRecorder records the most recent requests sent to the system, to be saved as a HAR file, if enabled
+	var harRecorder *http.HARRecorder
+	if config.GlobalConfig.SaveHar {
+		harRecorder = http.NewHARRecorder(http.MaxHAREntries)
+	}
+
+	// Start control API if specified, to inspect the fuzzer while it is running
+	if config.GlobalConfig.ControlAPIAddress != "" {
+		// The control API is unauthenticated, and injected operation cases are sent with credentials of the primary user
+		if !control.IsLoopbackAddress(config.GlobalConfig.ControlAPIAddress) {
+			log.Warn().Msgf("[main] Control API listens on %s, which is not a loopback address, so anyone reaching it can inspect captured requests and send requests as the primary user", config.GlobalConfig.ControlAPIAddress)
+		}
+		controlServer := control.NewControlServer(config.GlobalConfig.ControlAPIAddress)
+		controlServer.RegisterHandler("/captures", func() (any, error) {
+			return httpCaptureBuffer.GetAll(), nil
+		})
+		controlServer.RegisterQueryHandler("/queue/scenarios", func(query url.Values) (any, error) {
+			limit, err := control.ParseQueueLimit(query)
+			if err != nil {
+				return nil, err
+			}
+			return caseManager.GetTopScenarios(limit), nil
+		})
+		controlServer.RegisterQueryHandler("/queue/operation-cases", func(query url.Values) (any, error) {
+			limit, err := control.ParseQueueLimit(query)
+			if err != nil {
+				return nil, err
+			}
+			return caseManager.GetTopOperationCases(limit), nil
+		})
+		controlServer.RegisterPostHandler("/queue/operation-cases", func(body []byte) (any, error) {
+			var operationCase casemanager.OperationCase
+			err := sonic.Unmarshal(body, &operationCase)
+			if err != nil {
+				return nil, fmt.Errorf("invalid operation case: %w", err)
+			}
+			testScenario, err := caseManager.InjectOperationCase(&operationCase)
+			if err != nil {
+				return nil, err
+			}
+			return testScenario, nil
+		})
+		controlServer.Start()
+		defer func() {
+			err := controlServer.Stop(5 * time.Second)
+			if err != nil {
+				log.Err(err).Msg("[main] Failed to stop control API")
+			}
+		}()
+	}
+
+	// methodProber probes undocumented method handling before fuzzing, if enabled
+	var methodProber *feedback.MethodProber
+	if config.GlobalConfig.EnableMethodProbe {
+		methodProber = feedback.NewMethodProber(APIManager, config.GlobalConfig.MethodProbeOverrideHeaders, extraHeaders)
+	}
+
+	// errorBudget aborts fuzzing early if too many requests fail in the same way, if specified
+	errorBudget, err := feedback.NewErrorBudget(config.GlobalConfig.ErrorBudget, config.GlobalConfig.ErrorBudgetMinRequests)
+	if err != nil {
+		log.Err(err).Msgf("[main] Failed to create error budget")
+		return exitCodeRunAborted
+	}
 
 	// start fuzzing loop
 	var mainFuzzer fuzzer.Fuzzer
 	if config.GlobalConfig.FuzzerType == "Basic" {
-		mainFuzzer = fuzzer.NewBasicFuzzer(
+		basicFuzzer, err := fuzzer.NewBasicFuzzer(
 			APIManager,
 			caseManager,
 			responseProcesser,
+			enumCoverageTracker,
 			traceManager,
 			callInfoGraph,
 			reachabilityMap,
 			testLogReporter,
+			httpCaptureBuffer,
+			harRecorder,
+			methodProber,
+			bolaOracle,
+			scenarioMinimizer,
+			idempotencyOracle,
+			codeCoverageTracker,
+			differentialOracle,
+			errorBudget,
+			endpointPerformanceTracker,
+			createdResourceTracker,
 		)
+		if err != nil {
+			log.Err(err).Msgf("[main] Failed to create fuzzer")
+			return exitCodeRunAborted
+		}
+		mainFuzzer = basicFuzzer
 	} else {
 		log.Err(err).Msgf("[main] Unsupported fuzzer type: %s", config.GlobalConfig.FuzzerType)
-		return
+		return exitCodeRunAborted
 	}
-	err = mainFuzzer.Start()
-	if err != nil {
-		log.Err(err).Msgf("[main] Fuzzer failed")
-		return
+	// Start web dashboard if specified, to monitor the fuzzer while it is running
+	if config.GlobalConfig.DashboardAddress != "" {
+		dashboardServer := control.NewDashboardServer(config.GlobalConfig.DashboardAddress, mainFuzzer, caseManager, failureSignatureTracker, recentTraceBuffer)
+		dashboardServer.Start()
+		log.Info().Msgf("[main] Dashboard is served at http://%s/", config.GlobalConfig.DashboardAddress)
+		defer func() {
+			err := dashboardServer.Stop(5 * time.Second)
+			if err != nil {
+				log.Err(err).Msg("[main] Failed to stop dashboard")
+			}
+		}()
 	}
 
-	// generate result report
-	// Reports are named using current timestamp, in yyyyMMddHHmmss format,
-	// with prefix "system_report_", "internal_service_report_", etc.
-	// The reports are saved in the output directory
-	// Create the output directory if it does not exist.
-	err = os.MkdirAll(config.GlobalConfig.OutputDir, os.ModePerm)
-	if err != nil {
-		log.Err(err).Msgf("[main] Failed to create the output directory")
-		return
+	// writeReports generates result reports of the work done so far.
+	// Reports are saved in the output directory of current run,
+	// named with prefix "system_report", "internal_service_report", etc.
+	// It is called after fuzzing, and periodically during fuzzing if intermediate reports are enabled, overwriting the previous reports.
+	// If summaryOnly, artifacts growing with the run (i.e., the test log, HAR file, exported scenarios and synthesized doc) are not written,
+	// so that intermediate reports do not cost more and more time of fuzzing.
+	writeReports := func(summaryOnly bool) error {
+		var err error
+		systemReporter := report.NewSystemReporter(APIManager)
+		systemReportPath := fmt.Sprintf("%s/system_report.json", runOutputDir)
+		err = systemReporter.GenerateSystemReport(&report.SystemReportSources{
+			ResponseProcesser:          responseProcesser,
+			CRUDOracle:                 crudOracle,
+			SecurityOracle:             securityOracle,
+			BOLAOracle:                 bolaOracle,
+			IdempotencyOracle:          idempotencyOracle,
+			DifferentialOracle:         differentialOracle,
+			ScenarioMinimizer:          scenarioMinimizer,
+			LatencySLOOracle:           latencySLOOracle,
+			SpanLatencyAnomalyDetector: spanLatencyAnomalyDetector,
+			ContextPropagationOracle:   contextPropagationOracle,
+			StatusCodeTargetTracker:    statusCodeTargetTracker,
+			EnumCoverageTracker:        enumCoverageTracker,
+			EndpointPerformanceTracker: endpointPerformanceTracker,
+			CreatedResourceTracker:     createdResourceTracker,
+			CapabilityGaps:             capabilityGaps,
+			TraceWaitStats:             traceManager.WaitEstimator.GetStats(),
+		}, systemReportPath)
+		if err != nil {
+			log.Err(err).Msgf("[main] Failed to generate system report")
+			return err
+		}
+		if statusCodeTargetGap := statusCodeTargetTracker.GetGap(); statusCodeTargetGap.TargetCount > 0 {
+			log.Info().Msgf("[main] Status code targets covered: %d/%d, uncovered targets are listed in the system report", statusCodeTargetGap.CoveredCount, statusCodeTargetGap.TargetCount)
+		}
+		// Schema drift is only detected against the internal service doc, if provided.
+		var schemaDrifts []*feedback.SchemaDrift
+		if config.GlobalConfig.DetectSchemaDrift && config.GlobalConfig.InternalServiceOpenAPIPath != "" {
+			schemaDrifts = feedback.DetectSchemaDrift(APIManager, serviceDocSynthesizer.GetObservedAPIs())
+			if len(schemaDrifts) > 0 {
+				log.Warn().Msgf("[main] %d likely drift(s) between the internal service doc and traces detected, see the internal service report", len(schemaDrifts))
+			}
+		}
+		var shadowAPIs []*feedback.ShadowAPI
+		if config.GlobalConfig.DetectShadowAPIs {
+			serverBaseURLs := append([]string{config.GlobalConfig.ServerBaseURL}, APIManager.ServerURLs...)
+			for _, target := range APIManager.APITargets {
+				serverBaseURLs = append(serverBaseURLs, target.ServerBaseURL)
+			}
+			shadowAPIs = feedback.DetectShadowAPIs(APIManager, serviceDocSynthesizer.GetObservedAPIs(), serverBaseURLs)
+			if len(shadowAPIs) > 0 {
+				log.Warn().Msgf("[main] %d shadow API(s) observed in traces but not declared in any API doc, see the internal service report", len(shadowAPIs))
+			}
+		}
+		// Without any trace (e.g., the trace backend is unavailable), every documented internal service API would be unreached, which tells nothing.
+		var unreachedInternalAPIs []*feedback.UnreachedInternalAPI
+		if internalAPIReachTracker != nil {
+			if internalAPIReachTracker.TraceCount > 0 {
+				unreachedInternalAPIs = internalAPIReachTracker.GetUnreachedAPIs()
+				log.Info().Msgf("[main] %d documented internal service API(s) never appear in %d traces, see the internal service report", len(unreachedInternalAPIs), internalAPIReachTracker.TraceCount)
+			} else {
+				log.Warn().Msg("[main] No trace is fetched, skip listing unreached internal service APIs")
+			}
+		}
+		internalServiceReporter := report.NewInternalServiceReporter()
+		internalServiceReportPath := fmt.Sprintf("%s/internal_service_report.json", runOutputDir)
+		err = internalServiceReporter.GenerateInternalServiceReport(
+			mainFuzzer.GetCallInfoGraph(),
+			reachabilityMap,
+			mainFuzzer.GetSpanErrorCoverage(),
+			mainFuzzer.GetDatabaseCoverage(),
+			codeCoverageTracker,
+			schemaDrifts,
+			shadowAPIs,
+			unreachedInternalAPIs,
+			internalServiceReportPath,
+		)
+		if err != nil {
+			log.Err(err).Msgf("[main] Failed to generate internal service report")
+			return err
+		}
+		// Graphs are exported for visualization of microservice coverage.
+		graphReporter := report.NewGraphReporter()
+		err = graphReporter.GenerateAPIDataflowGraphReport(APIManager.APIDataflowGraph, fmt.Sprintf("%s/api_dataflow_graph", runOutputDir))
+		if err != nil {
+			log.Err(err).Msgf("[main] Failed to generate API dataflow graph report")
+			return err
+		}
+		err = graphReporter.GenerateCallInfoGraphReport(mainFuzzer.GetCallInfoGraph(), fmt.Sprintf("%s/call_info_graph", runOutputDir))
+		if err != nil {
+			log.Err(err).Msgf("[main] Failed to generate call info graph report")
+			return err
+		}
+		fuzzerStateReporter := report.NewFuzzerStateReporter()
+		fuzzerStateReportPath := fmt.Sprintf("%s/fuzzer_state_report.json", runOutputDir)
+		err = fuzzerStateReporter.GenerateFuzzerStateReport(resourceManager, caseManager, reachabilityMap, config.GlobalConfig.Redacted(), fuzzerStateReportPath)
+		if err != nil {
+			log.Err(err).Msgf("[main] Failed to generate fuzzer state report")
+			return err
+		}
+		if methodProber != nil {
+			conformanceReporter := report.NewConformanceReporter()
+			conformanceReportPath := fmt.Sprintf("%s/conformance_report.json", runOutputDir)
+			err = conformanceReporter.GenerateConformanceReport(methodProber, conformanceReportPath)
+			if err != nil {
+				log.Err(err).Msgf("[main] Failed to generate conformance report")
+				return err
+			}
+		}
+		if summaryOnly {
+			return nil
+		}
+		testLogReportPath := fmt.Sprintf("%s/test_log_report.json", runOutputDir)
+		err = testLogReporter.GenerateTestLogReport(testLogReportPath)
+		if err != nil {
+			log.Err(err).Msgf("[main] Failed to generate test log report")
+			return err
+		}
+		if harRecorder != nil {
+			err = harRecorder.WriteHARFile(fmt.Sprintf("%s/requests.har", runOutputDir))
+			if err != nil {
+				log.Err(err).Msgf("[main] Failed to write HAR file")
+			}
+		}
+		if scenarioExporter != nil {
+			err = scenarioExporter.ExportFailingScenarios(testLogReporter.TestLogReport, scenarioMinimizer, config.GlobalConfig.ServerBaseURL, runOutputDir)
+			if err != nil {
+				log.Err(err).Msgf("[main] Failed to export failing scenarios")
+				return err
+			}
+		}
+		if config.GlobalConfig.SynthesizeInternalServiceOpenAPI {
+			synthesizedDocPath := fmt.Sprintf("%s/synthesized_internal_service_oas.json", runOutputDir)
+			err = serviceDocSynthesizer.SaveDoc(synthesizedDocPath)
+			if err != nil {
+				log.Err(err).Msgf("[main] Failed to save synthesized internal service OpenAPI doc")
+				return err
+			}
+		}
+		return nil
 	}
-	systemReporter := report.NewSystemReporter(APIManager)
-	systemReportPath := fmt.Sprintf("%s/system_report_%s.json", config.GlobalConfig.OutputDir, t.Format(outputFileTimeFormat))
-	err = systemReporter.GenerateSystemReport(responseProcesser, systemReportPath)
-	if err != nil {
-		log.Err(err).Msgf("[main] Failed to generate system report")
-		return
+
+	// writePartialRunMarker marks reports in the run output directory as those of a partial run, see [report.PartialRunMarker].
+	writePartialRunMarker := func(reason string, sig os.Signal) {
+		progress := mainFuzzer.GetProgress()
+		marker := &report.PartialRunMarker{
+			Reason:         reason,
+			WrittenAt:      time.Now(),
+			ElapsedSeconds: progress.GetElapsedTime().Seconds(),
+			BudgetSeconds:  progress.Budget.Seconds(),
+		}
+		if sig != nil {
+			marker.Signal = sig.String()
+		}
+		_ = report.WritePartialRunMarker(runOutputDir, marker)
 	}
-	internalServiceReporter := report.NewInternalServiceReporter()
-	internalServiceReportPath := fmt.Sprintf("%s/internal_service_report_%s.json", config.GlobalConfig.OutputDir, t.Format(outputFileTimeFormat))
-	err = internalServiceReporter.GenerateInternalServiceReport(
-		mainFuzzer.GetCallInfoGraph(),
-		reachabilityMap,
-		internalServiceReportPath,
-	)
-	if err != nil {
-		log.Err(err).Msgf("[main] Failed to generate internal service report")
-		return
+
+	// Intermediate reports are written periodically during fuzzing, if specified.
+	// The marker is written before reports, so that reports are marked partial even if the process is killed while writing them.
+	if config.GlobalConfig.ReportInterval > 0 {
+		mainFuzzer.SetIntermediateReportFunc(time.Duration(config.GlobalConfig.ReportInterval)*time.Second, func() {
+			writePartialRunMarker(report.PartialRunReasonIntermediate, nil)
+			if err := writeReports(true); err != nil {
+				log.Err(err).Msgf("[main] Failed to write intermediate reports")
+				return
+			}
+			log.Info().Msgf("[main] Intermediate reports have been written to %s", runOutputDir)
+		})
+	}
+
+	// Fuzzing is cancelled on the first interrupt or termination signal, and reports of the work done so far are still generated,
+	// marked as partial. Another signal terminates the process immediately, e.g., if writing reports takes too long.
+	fuzzCtx, cancelFuzz := context.WithCancel(context.Background())
+	defer cancelFuzz()
+	signalChan := make(chan os.Signal, 1)
+	signal.Notify(signalChan, os.Interrupt, syscall.SIGTERM)
+	defer func() {
+		signal.Stop(signalChan)
+		close(signalChan)
+	}()
+	interruptSignalChan := make(chan os.Signal, 1)
+	go func() {
+		sig, ok := <-signalChan
+		if !ok {
+			return
+		}
+		log.Warn().Msgf("[main] Received %v, stop fuzzing and write reports, send it again to terminate immediately", sig)
+		interruptSignalChan <- sig
+		cancelFuzz()
+		sig, ok = <-signalChan
+		if !ok {
+			return
+		}
+		log.Warn().Msgf("[main] Received %v again, terminate immediately", sig)
+		os.Exit(exitCodeRunAborted)
+	}()
+	var progressDisplay *tui.ProgressDisplay
+	if config.GlobalConfig.TUI {
+		progressDisplay = tui.NewProgressDisplay(os.Stdout, mainFuzzer.GetProgress, tui.DefaultRefreshInterval)
+		progressDisplay.Start()
+	}
+	err = mainFuzzer.Start(fuzzCtx)
+	var interruptSignal os.Signal
+	select {
+	case interruptSignal = <-interruptSignalChan:
+		writePartialRunMarker(report.PartialRunReasonInterrupted, interruptSignal)
+	default:
+	}
+	if progressDisplay != nil {
+		progressDisplay.Stop()
 	}
-	fuzzerStateReporter := report.NewFuzzerStateReporter()
-	fuzzerStateReportPath := fmt.Sprintf("%s/fuzzer_state_report_%s.json", config.GlobalConfig.OutputDir, t.Format(outputFileTimeFormat))
-	err = fuzzerStateReporter.GenerateFuzzerStateReport(resourceManager, fuzzerStateReportPath)
 	if err != nil {
-		log.Err(err).Msgf("[main] Failed to generate fuzzer state report")
-		return
+		log.Err(err).Msgf("[main] Fuzzer failed")
+		return exitCodeRunAborted
+	}
+
+	// Save artifacts learned in this run to knowledge base
+	// Failing to save them should not stop generating reports.
+	// Nothing is learned in dry run, as no request is sent.
+	if knowledgeBase != nil && !config.GlobalConfig.DryRun {
+		saveErrs := []error{
+			knowledgeBase.SaveDataflowGraph(APIManager.APIDataflowGraph),
+			knowledgeBase.SaveReachability(reachabilityMap),
+			knowledgeBase.SaveResourcePool(resourceManager),
+			knowledgeBase.SaveFailureSignatures(failureSignatureTracker),
+			knowledgeBase.SaveMeta(),
+		}
+		if err := errors.Join(saveErrs...); err != nil {
+			log.Err(err).Msgf("[main] Failed to save artifacts to knowledge base")
+		} else {
+			log.Info().Msgf("[main] Artifacts saved to knowledge base %s, %d new failure signature(s) found in this run", knowledgeBase.Dir, len(failureSignatureTracker.NewSignatures))
+		}
 	}
-	testLogReportPath := fmt.Sprintf("%s/test_log_report_%s.json", config.GlobalConfig.OutputDir, t.Format(outputFileTimeFormat))
-	err = testLogReporter.GenerateTestLogReport(testLogReportPath)
+
+	err = writeReports(false)
 	if err != nil {
-		log.Err(err).Msgf("[main] Failed to generate test log report")
-		return
+		return exitCodeRunAborted
+	}
+	if interruptSignal != nil {
+		log.Warn().Msgf("[main] Fuzzing was interrupted by %v, reports cover the work done so far", interruptSignal)
+	} else {
+		_ = report.RemovePartialRunMarker(runOutputDir)
 	}
 
 	log.Info().Msg("[main] Fuzzing completed")
+	if config.GlobalConfig.DryRun {
+		// There is no outcome to judge in dry run.
+		return exitCodeOK
+	}
+	serverErrorCount := mainFuzzer.GetProgress().PrimaryServerErrorCount
+	return resolveOutcomeExitCode(len(failureSignatureTracker.NewSignatures), serverErrorCount, mainFuzzer.GetCallInfoGraph())
 }
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"resttracefuzzer/internal/demo"
+	"syscall"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// main starts the demo target, and serves it until interrupted.
+// It writes the OpenAPI spec of the demo, and prints the command to fuzz it.
+func main() {
+	specPath := flag.String("openapi-spec-output", "demo_openapi.json", "Path to write the OpenAPI spec of the demo target to.")
+	flag.Parse()
+
+	if err := demo.WriteOpenAPISpec(*specPath); err != nil {
+		log.Err(err).Msgf("[main] Failed to write OpenAPI spec to %s", *specPath)
+		os.Exit(1)
+	}
+	d, err := demo.Start()
+	if err != nil {
+		log.Err(err).Msgf("[main] Failed to start demo target")
+		os.Exit(1)
+	}
+
+	fmt.Printf("Demo target is running, press Ctrl+C to stop.\n")
+	fmt.Printf("  Server base URL:   %s\n", d.ServerBaseURL)
+	fmt.Printf("  Trace backend URL: %s (Jaeger)\n", d.TraceBackendURL)
+	fmt.Printf("  OpenAPI spec:      %s\n", *specPath)
+	fmt.Printf("Fuzz it with:\n")
+	fmt.Printf("  go run ./cmd/api-fuzzer --openapi-spec %s --server-base-url %s --trace-backend-type Jaeger --trace-backend-url %s --fuzzer-budget 30 --context-propagation-headers %s --extra-headers '{\"%s\":\"tenant-1\"}'\n",
+		*specPath, d.ServerBaseURL, d.TraceBackendURL, demo.TenantIDHeader, demo.TenantIDHeader)
+
+	signalChan := make(chan os.Signal, 1)
+	signal.Notify(signalChan, syscall.SIGINT, syscall.SIGTERM)
+	<-signalChan
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := d.Stop(ctx); err != nil {
+		log.Err(err).Msgf("[main] Failed to stop demo target")
+	}
+}
@@ -0,0 +1,245 @@
+// Package calibrate probes the system under test with increasing request rates,
+// to find a request rate and concurrency it can sustain, so that fuzzing does not overload it.
+package calibrate
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"math"
+	"resttracefuzzer/pkg/static"
+	"resttracefuzzer/pkg/utils/http"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/cloudwego/hertz/pkg/protocol/consts"
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	// maxHealthyErrorRate is the max rate of failed requests (transport errors, 429 and 5xx) of a healthy step.
+	maxHealthyErrorRate = 0.01
+
+	// maxHealthyLatencyFactor is the max ratio of p95 latency of a healthy step to that of the first step.
+	maxHealthyLatencyFactor = 2.0
+
+	// minHealthyThroughputRatio is the min ratio of the achieved request rate to the target rate of a healthy step.
+	minHealthyThroughputRatio = 0.9
+
+	// minDegradedLatency is the min p95 latency regarded as degraded, in milliseconds.
+	// Latency of a fast server fluctuates a lot in relative terms, so a small absolute latency is always healthy.
+	minDegradedLatency = 10.0
+
+	// safetyFactor is applied to the max healthy rate, to get the suggested rate.
+	safetyFactor = 0.8
+)
+
+// CalibrationConfig is the configuration of calibration.
+type CalibrationConfig struct {
+	// MaxRequestsPerSecond is the max request rate probed.
+	// Rates probed start from 1 and double each step, until the server becomes unhealthy or the max rate is reached.
+	MaxRequestsPerSecond int
+
+	// StepDuration is how long requests of each rate are sent.
+	StepDuration time.Duration
+}
+
+// CalibrationStep is the result of probing a single request rate.
+type CalibrationStep struct {
+	// TargetRequestsPerSecond is the request rate probed.
+	TargetRequestsPerSecond int `json:"targetRequestsPerSecond"`
+
+	// AchievedRequestsPerSecond is the rate of requests completed.
+	AchievedRequestsPerSecond float64 `json:"achievedRequestsPerSecond"`
+
+	// RequestCount is the number of requests sent.
+	RequestCount int `json:"requestCount"`
+
+	// ErrorCount is the number of failed requests, i.e., transport errors, 429 (Too Many Requests) and 5xx responses.
+	ErrorCount int `json:"errorCount"`
+
+	// P50Latency and P95Latency are latency percentiles of requests, in milliseconds.
+	P50Latency float64 `json:"p50LatencyMs"`
+	P95Latency float64 `json:"p95LatencyMs"`
+
+	// Healthy is whether the server sustains the rate, see [Calibrator.isHealthy].
+	Healthy bool `json:"healthy"`
+}
+
+// CalibrationResult is the result of calibration.
+type CalibrationResult struct {
+	// Steps are the results of each rate probed, in order.
+	Steps []*CalibrationStep `json:"steps"`
+
+	// MaxHealthyRequestsPerSecond is the max request rate the server sustains. It is 0 if no rate is healthy.
+	MaxHealthyRequestsPerSecond int `json:"maxHealthyRequestsPerSecond"`
+
+	// SuggestedRequestsPerSecond is the suggested max request rate of fuzzing, with a safety margin.
+	SuggestedRequestsPerSecond int `json:"suggestedRequestsPerSecond"`
+
+	// SuggestedConcurrentRequests is the suggested max number of requests in flight,
+	// estimated by the suggested rate and p95 latency (Little's law).
+	SuggestedConcurrentRequests int `json:"suggestedConcurrentRequests"`
+}
+
+// Calibrator probes the system under test with increasing request rates.
+// Only GET operations without required parameters or request body are requested, so calibration would not change server states.
+type Calibrator struct {
+	// Config is the calibration configuration.
+	Config CalibrationConfig
+
+	// ProbeMethods are the API methods requested, in round robin.
+	ProbeMethods []static.SimpleAPIMethod
+
+	// ExtraHeaders are headers attached to every request, e.g., tokens.
+	ExtraHeaders map[string]string
+}
+
+// NewCalibrator creates a new Calibrator, probing safe operations of the API manager.
+// It returns an error if there is no operation to probe.
+func NewCalibrator(APIManager *static.APIManager, extraHeaders map[string]string, calibrationConfig CalibrationConfig) (*Calibrator, error) {
+	probeMethods := GetProbeMethods(APIManager)
+	if len(probeMethods) == 0 {
+		return nil, fmt.Errorf("no GET operation without required parameters to probe")
+	}
+	if calibrationConfig.MaxRequestsPerSecond <= 0 || calibrationConfig.StepDuration <= 0 {
+		return nil, fmt.Errorf("invalid calibration config, max requests per second: %d, step duration: %s", calibrationConfig.MaxRequestsPerSecond, calibrationConfig.StepDuration)
+	}
+	return &Calibrator{
+		Config:       calibrationConfig,
+		ProbeMethods: probeMethods,
+		ExtraHeaders: extraHeaders,
+	}, nil
+}
+
+// GetProbeMethods returns GET operations without required parameters or request body, sorted.
+func GetProbeMethods(APIManager *static.APIManager) []static.SimpleAPIMethod {
+	probeMethods := make([]static.SimpleAPIMethod, 0)
+	for _, method := range slices.SortedFunc(maps.Keys(APIManager.APIMap), static.CompareSimpleAPIMethod) {
+		operation := APIManager.APIMap[method]
+		if method.Method != consts.MethodGet || operation == nil || operation.RequestBody != nil {
+			continue
+		}
+		hasRequiredParam := slices.ContainsFunc(operation.Parameters, func(paramRef *openapi3.ParameterRef) bool {
+			return paramRef.Value != nil && paramRef.Value.Required
+		})
+		if !hasRequiredParam {
+			probeMethods = append(probeMethods, method)
+		}
+	}
+	return probeMethods
+}
+
+// Run probes the server with the HTTP client, from 1 request per second, doubling each step.
+// It stops at the first unhealthy step, at the max rate, or once ctx is done.
+// The HTTP client should not throttle requests itself, i.e., its politeness controls should be disabled.
+func (c *Calibrator) Run(ctx context.Context, httpClient *http.HTTPClient) *CalibrationResult {
+	result := &CalibrationResult{
+		Steps: make([]*CalibrationStep, 0),
+	}
+	var baselineP95Latency float64
+	var lastHealthyStep *CalibrationStep
+	for rate := 1; ; rate *= 2 {
+		rate = min(rate, c.Config.MaxRequestsPerSecond)
+		step := c.runStep(ctx, httpClient, rate)
+		if ctx.Err() != nil {
+			log.Warn().Msg("[Calibrator.Run] Context done, stop calibration")
+			break
+		}
+		if len(result.Steps) == 0 {
+			baselineP95Latency = step.P95Latency
+		}
+		step.Healthy = c.isHealthy(step, baselineP95Latency)
+		result.Steps = append(result.Steps, step)
+		log.Info().Msgf("[Calibrator.Run] Rate %d/s: achieved %.2f/s, %d/%d errors, p50 %.1fms, p95 %.1fms, healthy: %t",
+			rate, step.AchievedRequestsPerSecond, step.ErrorCount, step.RequestCount, step.P50Latency, step.P95Latency, step.Healthy)
+		if !step.Healthy {
+			break
+		}
+		result.MaxHealthyRequestsPerSecond = rate
+		lastHealthyStep = step
+		if rate >= c.Config.MaxRequestsPerSecond {
+			break
+		}
+	}
+
+	if lastHealthyStep != nil {
+		result.SuggestedRequestsPerSecond = max(int(float64(result.MaxHealthyRequestsPerSecond)*safetyFactor), 1)
+		result.SuggestedConcurrentRequests = max(int(math.Ceil(float64(result.SuggestedRequestsPerSecond)*lastHealthyStep.P95Latency/1000)), 1)
+	}
+	return result
+}
+
+// runStep sends requests at the given rate for the step duration, and waits for all of them to complete.
+func (c *Calibrator) runStep(ctx context.Context, httpClient *http.HTTPClient, rate int) *CalibrationStep {
+	interval := time.Second / time.Duration(rate)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	stepCtx, cancel := context.WithTimeout(ctx, c.Config.StepDuration)
+	defer cancel()
+
+	var (
+		mu         sync.Mutex
+		wg         sync.WaitGroup
+		latencies  = make([]time.Duration, 0)
+		errorCount int
+	)
+	start := time.Now()
+sendLoop:
+	for i := 0; ; i++ {
+		method := c.ProbeMethods[i%len(c.ProbeMethods)]
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			reqStart := time.Now()
+			statusCode, _, _, err := httpClient.PerformRequest(ctx, method.Endpoint, method.Method, maps.Clone(c.ExtraHeaders), nil, nil, nil)
+			latency := time.Since(reqStart)
+			mu.Lock()
+			defer mu.Unlock()
+			latencies = append(latencies, latency)
+			if err != nil || statusCode == consts.StatusTooManyRequests || statusCode >= 500 {
+				errorCount++
+			}
+		}()
+		select {
+		case <-ticker.C:
+		case <-stepCtx.Done():
+			break sendLoop
+		}
+	}
+	wg.Wait()
+	elapsed := time.Since(start)
+
+	slices.Sort(latencies)
+	return &CalibrationStep{
+		TargetRequestsPerSecond:   rate,
+		AchievedRequestsPerSecond: float64(len(latencies)) / elapsed.Seconds(),
+		RequestCount:              len(latencies),
+		ErrorCount:                errorCount,
+		P50Latency:                getLatencyPercentile(latencies, 0.5),
+		P95Latency:                getLatencyPercentile(latencies, 0.95),
+	}
+}
+
+// isHealthy returns whether the server sustains the rate of the step, i.e.,
+// few requests fail, the latency does not degrade much compared with the baseline, and requests complete at the target rate.
+func (c *Calibrator) isHealthy(step *CalibrationStep, baselineP95Latency float64) bool {
+	if step.RequestCount == 0 {
+		return false
+	}
+	errorRate := float64(step.ErrorCount) / float64(step.RequestCount)
+	latencyDegraded := step.P95Latency > minDegradedLatency && step.P95Latency > baselineP95Latency*maxHealthyLatencyFactor
+	throughputDegraded := step.AchievedRequestsPerSecond < float64(step.TargetRequestsPerSecond)*minHealthyThroughputRatio
+	return errorRate <= maxHealthyErrorRate && !latencyDegraded && !throughputDegraded
+}
+
+// getLatencyPercentile returns the percentile of sorted latencies, in milliseconds.
+func getLatencyPercentile(sortedLatencies []time.Duration, percentile float64) float64 {
+	if len(sortedLatencies) == 0 {
+		return 0
+	}
+	index := min(int(math.Ceil(float64(len(sortedLatencies))*percentile))-1, len(sortedLatencies)-1)
+	return float64(sortedLatencies[max(index, 0)].Microseconds()) / 1000
+}
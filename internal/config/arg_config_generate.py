@@ -48,7 +48,9 @@ def to_camel_case(snake_str: str, upper_first: bool = False) -> str:
         'http': 'HTTP',
         'https': 'HTTPS',
         'api': 'API',
-        'openapi': 'OpenAPI'
+        'apis': 'APIs',
+        'openapi': 'OpenAPI',
+        'tui': 'TUI'
     }
 
     components = snake_str.split('_')
@@ -127,7 +129,9 @@ def generate_arg_parse_code(config: List[Dict[str, Any]]) -> str:
         arg_parse_code_lines.append(f'import "{package}"')
     
     # parse method code
-    arg_parse_code_lines.append("func ParseCmdArgs() {")
+    arg_parse_code_lines.append("// ParseCmdArgs parses the config from command line arguments, the config file (if provided) and environment variables, into GlobalConfig.")
+    arg_parse_code_lines.append("// It returns an error if the config file cannot be loaded, e.g., it contains unknown keys.")
+    arg_parse_code_lines.append("func ParseCmdArgs() error {")
     for arg in config:
         arg_name = arg["arg_name"]
         config_name = arg["config_name"]
@@ -150,13 +154,10 @@ def generate_arg_parse_code(config: List[Dict[str, Any]]) -> str:
     # If config file is provided, load the config from the file
     arg_parse_code_lines.append("// If config file is provided, load the config from the file")
     arg_parse_code_lines.append("if GlobalConfig.ConfigFilePath != \"\" {")
-    arg_parse_code_lines.append("configData, err := os.ReadFile(GlobalConfig.ConfigFilePath)")
+    arg_parse_code_lines.append("err := LoadConfigFile(GlobalConfig.ConfigFilePath, GlobalConfig)")
     arg_parse_code_lines.append("if err != nil {")
-    arg_parse_code_lines.append('log.Err(err).Msgf("[ParseCmdArgs] Failed to read config file: %s", err)')
-    arg_parse_code_lines.append("}")
-    arg_parse_code_lines.append("err = sonic.Unmarshal(configData, GlobalConfig)")
-    arg_parse_code_lines.append("if err != nil {")
-    arg_parse_code_lines.append('log.Err(err).Msgf("[ParseCmdArgs] Failed to parse config file: %s", err)')
+    arg_parse_code_lines.append('log.Err(err).Msgf("[ParseCmdArgs] Failed to load config file: %s", GlobalConfig.ConfigFilePath)')
+    arg_parse_code_lines.append("return err")
     arg_parse_code_lines.append("}")
     arg_parse_code_lines.append("}")
     arg_parse_code_lines.append(BLANK_LINE)
@@ -193,6 +194,7 @@ def generate_arg_parse_code(config: List[Dict[str, Any]]) -> str:
 
     arg_parse_code_lines.append('jsonStr, _ := sonic.Marshal(GlobalConfig)')
     arg_parse_code_lines.append('log.Info().Msgf("[ParseCmdArgs] Parsed arguments: %s", jsonStr)')
+    arg_parse_code_lines.append("return nil")
     arg_parse_code_lines.append("}")
 
     return "\n".join(arg_parse_code_lines)
@@ -233,6 +235,45 @@ def generate_config_code(config: List[Dict[str, Any]]) -> str:
 
     return "\n".join(config_code_lines)
 
+def generate_config_schema_code(config: List[Dict[str, Any]]) -> str:
+    """
+    Generate the Go code for specs of config keys, used to validate config files and generate the default config template.
+
+    Args:
+        config (List[Dict[str, Any]]): The configuration.
+
+    Returns:
+        str: The generated Go code.
+    """
+    package_declaration = "package config"
+    config_schema_code_lines = []
+    config_schema_code_lines.append(DO_NOT_EDIT_HEADER)
+    config_schema_code_lines.append(package_declaration)
+
+    config_schema_code_lines.append("// ConfigKeySpecs are the specs of all config keys, sorted by argument name.")
+    config_schema_code_lines.append("var ConfigKeySpecs = []ConfigKeySpec{")
+    for arg in config:
+        config_name = arg["config_name"]
+        go_type = to_go_type(arg["type"])
+        if go_type == "bool":
+            default_value = "true" if arg["default"] else "false"
+        elif go_type == "int":
+            default_value = str(arg["default"])
+        else:
+            default_value = json.dumps(str(arg["default"]))
+        config_schema_code_lines.append("{")
+        config_schema_code_lines.append(f'Key: "{to_camel_case(config_name, False)}",')
+        config_schema_code_lines.append(f'ArgName: "{arg["arg_name"]}",')
+        config_schema_code_lines.append(f'EnvName: "{to_upper_snake_case(config_name)}",')
+        config_schema_code_lines.append(f'Type: "{arg["type"]}",')
+        config_schema_code_lines.append(f'Required: {"true" if arg["required"] else "false"},')
+        config_schema_code_lines.append(f'Default: {default_value},')
+        config_schema_code_lines.append(f'Description: {json.dumps(arg["description"])},')
+        config_schema_code_lines.append("},")
+    config_schema_code_lines.append("}")
+
+    return "\n".join(config_schema_code_lines)
+
 def write_to_file(filepath: str, content: str) -> None:
     """
     Write content to a file.
@@ -254,12 +295,15 @@ def main(config_path: str) -> None:
     config_file_path = config_path + '/arg_config.json'
     arg_parse_go_code_path = config_path + '/arg_parse.go'
     config_go_code_path = config_path + '/config.go'
+    config_schema_go_code_path = config_path + '/config_schema.go'
     config = load_config(config_file_path)
     arg_parse_code = generate_arg_parse_code(config)
     config_code = generate_config_code(config)
+    config_schema_code = generate_config_schema_code(config)
 
     write_to_file(arg_parse_go_code_path, arg_parse_code)
     write_to_file(config_go_code_path, config_code)
+    write_to_file(config_schema_go_code_path, config_schema_code)
 
 if __name__ == "__main__":
     if len(sys.argv) != 2:
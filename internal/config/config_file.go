@@ -0,0 +1,51 @@
+package config
+
+import (
+	"encoding/json"
+	"os"
+
+	"github.com/bytedance/sonic"
+	"github.com/rs/zerolog/log"
+)
+
+// UpdateConfigFile sets the values of config keys (as in the config file, e.g., `HTTPClientMaxRequestsPerSecond`) in the config file,
+// keeping other keys unchanged. The file is created if it does not exist.
+// A YAML config file is rewritten in JSON (which is also valid YAML), and its comments are dropped.
+func UpdateConfigFile(configFilePath string, values map[string]any) error {
+	configMap := make(map[string]json.RawMessage)
+	_, err := os.Stat(configFilePath)
+	if err != nil && !os.IsNotExist(err) {
+		log.Err(err).Msgf("[UpdateConfigFile] Failed to read config file: %s", configFilePath)
+		return err
+	}
+	if err == nil {
+		configData, err := readConfigFileAsJSON(configFilePath)
+		if err != nil {
+			log.Err(err).Msgf("[UpdateConfigFile] Failed to read config file: %s", configFilePath)
+			return err
+		}
+		if err := sonic.Unmarshal(configData, &configMap); err != nil {
+			log.Err(err).Msgf("[UpdateConfigFile] Failed to parse config file: %s", configFilePath)
+			return err
+		}
+	}
+	for key, value := range values {
+		valueData, err := sonic.Marshal(value)
+		if err != nil {
+			log.Err(err).Msgf("[UpdateConfigFile] Failed to marshal value of %s", key)
+			return err
+		}
+		configMap[key] = valueData
+	}
+	// Keys are sorted by the std config, and RawMessage keeps other values as they are.
+	configData, err := sonic.ConfigStd.MarshalIndent(configMap, "", "    ")
+	if err != nil {
+		log.Err(err).Msgf("[UpdateConfigFile] Failed to marshal config")
+		return err
+	}
+	if err := os.WriteFile(configFilePath, append(configData, '\n'), 0644); err != nil {
+		log.Err(err).Msgf("[UpdateConfigFile] Failed to write config file: %s", configFilePath)
+		return err
+	}
+	return nil
+}
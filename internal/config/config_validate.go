@@ -0,0 +1,328 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"resttracefuzzer/pkg/utils"
+	"slices"
+	"strings"
+
+	"github.com/bytedance/sonic"
+	"github.com/oasdiff/yaml"
+	"github.com/rs/zerolog/log"
+)
+
+// configKeySuggestionMinSimilarity is the minimal similarity between an unknown key and a known one, to suggest the known one.
+const configKeySuggestionMinSimilarity = 0.6
+
+// configTemplateLineWidth is the maximal width of comment lines in the generated config template.
+const configTemplateLineWidth = 100
+
+// ConfigProfilesKey is the key of named profiles in the config file.
+// A profile is an object of config keys, which override top-level ones of the config file if the profile is selected by `--profile`.
+const ConfigProfilesKey = "profiles"
+
+// configActionKeys are keys of actions run instead of fuzzing (e.g., printing the default config file and exiting), rather than options of runs.
+// They are taken from command line flags and environment variables only, and ignored in the config file,
+// as values of the config file override flags, e.g., `printDefaultConfig: false` would override `--print-default-config`.
+var configActionKeys = []string{"printDefaultConfig"}
+
+// ConfigKeySpec is the spec of a config key, generated from arg_config.json, see [ConfigKeySpecs].
+type ConfigKeySpec struct {
+	// Key is the key in the config file, e.g., `serverBaseURL`.
+	Key string
+
+	// ArgName is the name of the command line flag, e.g., `server-base-url`.
+	ArgName string
+
+	// EnvName is the name of the environment variable, e.g., `SERVER_BASE_URL`.
+	EnvName string
+
+	// Type is the type of the value, one of `boolean`, `number` and `string`.
+	Type string
+
+	// Required is true if the value must be set (to a non-zero value).
+	Required bool
+
+	// Default is the default value, of type bool, int or string according to Type.
+	Default any
+
+	// Description is the description of the config key.
+	Description string
+}
+
+// LoadConfigFile loads the config file into cfg, overriding values set before.
+// The file is parsed as YAML if its extension is `.yaml` or `.yml`, and as JSON otherwise.
+// If a profile is selected (by cfg.Profile set by `--profile`, or else the `profile` key of the file), values of the profile
+// under [ConfigProfilesKey] are loaded after top-level ones, overriding them.
+// It returns an error if the file contains unknown keys (with suggestions of similar known ones), values of wrong types,
+// or the selected profile is not found. Keys are matched case-insensitively, as in JSON decoding.
+// Action keys (see [configActionKeys]) in the file are ignored.
+func LoadConfigFile(configFilePath string, cfg *RuntimeConfig) error {
+	configData, err := readConfigFileAsJSON(configFilePath)
+	if err != nil {
+		return err
+	}
+	configMap := make(map[string]json.RawMessage)
+	if err := sonic.Unmarshal(configData, &configMap); err != nil {
+		return fmt.Errorf("failed to parse config file %s, a JSON or YAML object is expected: %w", configFilePath, err)
+	}
+
+	profiles := make(map[string]map[string]json.RawMessage)
+	for key, value := range configMap {
+		if !strings.EqualFold(key, ConfigProfilesKey) {
+			continue
+		}
+		if err := sonic.Unmarshal(value, &profiles); err != nil {
+			return fmt.Errorf("invalid config file %s: key %q expects an object from profile names to objects of config keys: %w", configFilePath, key, err)
+		}
+		delete(configMap, key)
+	}
+	problems := validateConfigMap(configMap, "")
+	profileNames := make([]string, 0, len(profiles))
+	for profileName := range profiles {
+		profileNames = append(profileNames, profileName)
+	}
+	slices.Sort(profileNames)
+	for _, profileName := range profileNames {
+		problems = append(problems, validateConfigMap(profiles[profileName], fmt.Sprintf("profile %q: ", profileName))...)
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("invalid config file %s: %s", configFilePath, strings.Join(problems, "; "))
+	}
+	removeConfigActionKeys(configMap, configFilePath, "")
+	for _, profileName := range profileNames {
+		removeConfigActionKeys(profiles[profileName], configFilePath, fmt.Sprintf("profile %q: ", profileName))
+	}
+
+	// The profile selected by the command line is kept, and the `profile` key of the file is only a default one.
+	selectedProfile := cfg.Profile
+	if err := unmarshalConfigMap(configMap, cfg); err != nil {
+		return fmt.Errorf("failed to load config file %s: %w", configFilePath, err)
+	}
+	if selectedProfile != "" {
+		cfg.Profile = selectedProfile
+	}
+	if cfg.Profile == "" {
+		return nil
+	}
+	profile, exists := profiles[cfg.Profile]
+	if !exists {
+		return fmt.Errorf("profile %q is not found in config file %s, available profiles: [%s]", cfg.Profile, configFilePath, strings.Join(profileNames, ", "))
+	}
+	if err := unmarshalConfigMap(profile, cfg); err != nil {
+		return fmt.Errorf("failed to load profile %q of config file %s: %w", cfg.Profile, configFilePath, err)
+	}
+	log.Info().Msgf("[LoadConfigFile] Profile %s of config file %s is loaded", cfg.Profile, configFilePath)
+	return nil
+}
+
+// ValidateRequiredConfig checks that all required config values are set, and returns an error listing the missing ones,
+// with their command line flags and environment variables.
+// It also returns an error if a profile is selected without a config file to define it.
+func ValidateRequiredConfig(cfg *RuntimeConfig) error {
+	if cfg.Profile != "" && cfg.ConfigFilePath == "" {
+		return fmt.Errorf("profile %q is selected, but no config file is provided by --config-file", cfg.Profile)
+	}
+	cfgValue := reflect.ValueOf(cfg).Elem()
+	missing := make([]string, 0)
+	for _, spec := range ConfigKeySpecs {
+		if !spec.Required {
+			continue
+		}
+		field := findConfigField(cfgValue, spec.Key)
+		if !field.IsValid() || field.IsZero() {
+			missing = append(missing, fmt.Sprintf("%s (flag --%s, env %s)", spec.Key, spec.ArgName, spec.EnvName))
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("missing required config: %s", strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// GenerateDefaultConfigTemplate generates a config file in YAML with all keys set to their default values,
+// each commented with its description, command line flag and environment variable.
+// Action keys (see [configActionKeys]) are left out, as they are ignored in the config file.
+func GenerateDefaultConfigTemplate() string {
+	var sb strings.Builder
+	sb.WriteString("# Config file of rest_trace_fuzzer, generated by --print-default-config, with all keys set to default values.\n")
+	sb.WriteString("# Load it by --config-file (files with extension .yaml or .yml are parsed as YAML, others as JSON).\n")
+	sb.WriteString("# Values in the config file override command line flags, and environment variables override both,\n")
+	sb.WriteString("# so keys not to be changed can be removed.\n")
+	for _, spec := range ConfigKeySpecs {
+		if slices.Contains(configActionKeys, spec.Key) {
+			continue
+		}
+		sb.WriteString("\n")
+		for _, line := range wrapConfigComment(spec.Description, configTemplateLineWidth-2) {
+			sb.WriteString("# " + line + "\n")
+		}
+		fmt.Fprintf(&sb, "# Flag: --%s, environment variable: %s, type: %s.\n", spec.ArgName, spec.EnvName, spec.Type)
+		if spec.Required {
+			sb.WriteString("# Required.\n")
+		}
+		value := fmt.Sprint(spec.Default)
+		if spec.Type == "string" {
+			// A JSON string is a valid YAML string, with special characters escaped.
+			valueData, _ := json.Marshal(spec.Default)
+			value = string(valueData)
+		}
+		fmt.Fprintf(&sb, "%s: %s\n", spec.Key, value)
+	}
+	sb.WriteString("\n")
+	sb.WriteString("# Named profiles, each overriding top-level values above if selected by --profile (or the profile key above), e.g.:\n")
+	fmt.Fprintf(&sb, "# %s:\n", ConfigProfilesKey)
+	sb.WriteString("#   smoke:\n")
+	sb.WriteString("#     fuzzerBudget: 60\n")
+	sb.WriteString("#     maxAllowedScenarios: 20\n")
+	sb.WriteString("#   nightly:\n")
+	sb.WriteString("#     fuzzerBudget: 3600\n")
+	sb.WriteString("#     HTTPClientMaxConcurrentRequests: 8\n")
+	return sb.String()
+}
+
+// validateConfigMap validates keys and types of values of the config map (i.e., top-level or a profile of the config file),
+// and returns problems found, each prefixed with problemPrefix.
+func validateConfigMap(configMap map[string]json.RawMessage, problemPrefix string) []string {
+	keys := make([]string, 0, len(configMap))
+	for key := range configMap {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	problems := make([]string, 0)
+	for _, key := range keys {
+		spec := findConfigKeySpec(key)
+		if spec == nil {
+			problem := fmt.Sprintf("%sunknown key %q", problemPrefix, key)
+			if suggestion := suggestConfigKey(key); suggestion != "" {
+				problem += fmt.Sprintf(" (did you mean %q?)", suggestion)
+			}
+			problems = append(problems, problem)
+			continue
+		}
+		if !isValueOfConfigType(configMap[key], spec.Type) {
+			problems = append(problems, fmt.Sprintf("%skey %q expects a %s value, but got %s", problemPrefix, key, spec.Type, string(configMap[key])))
+		}
+	}
+	return problems
+}
+
+// removeConfigActionKeys removes action keys (see [configActionKeys]) from the config map (i.e., top-level or a profile of the config file),
+// warning about those with values, which are prefixed with problemPrefix.
+func removeConfigActionKeys(configMap map[string]json.RawMessage, configFilePath, problemPrefix string) {
+	for key, value := range configMap {
+		if !slices.ContainsFunc(configActionKeys, func(actionKey string) bool { return strings.EqualFold(key, actionKey) }) {
+			continue
+		}
+		if valueStr := strings.TrimSpace(string(value)); valueStr != "false" && valueStr != "null" {
+			log.Warn().Msgf("[removeConfigActionKeys] %skey %q of config file %s is ignored, as it is an action taken only from the command line and environment variables", problemPrefix, key, configFilePath)
+		}
+		delete(configMap, key)
+	}
+}
+
+// unmarshalConfigMap loads the (validated) config map into cfg.
+func unmarshalConfigMap(configMap map[string]json.RawMessage, cfg *RuntimeConfig) error {
+	configData, err := sonic.Marshal(configMap)
+	if err != nil {
+		return err
+	}
+	return sonic.Unmarshal(configData, cfg)
+}
+
+// readConfigFileAsJSON reads the config file, converting it to JSON if it is in YAML.
+func readConfigFileAsJSON(configFilePath string) ([]byte, error) {
+	configData, err := os.ReadFile(configFilePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file %s: %w", configFilePath, err)
+	}
+	ext := strings.ToLower(filepath.Ext(configFilePath))
+	if ext != ".yaml" && ext != ".yml" {
+		return configData, nil
+	}
+	configData, err = yaml.YAMLToJSON(configData)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse config file %s as YAML: %w", configFilePath, err)
+	}
+	return configData, nil
+}
+
+// findConfigKeySpec finds the spec of the config key, case-insensitively, or returns nil if not found.
+func findConfigKeySpec(key string) *ConfigKeySpec {
+	for i := range ConfigKeySpecs {
+		if strings.EqualFold(ConfigKeySpecs[i].Key, key) {
+			return &ConfigKeySpecs[i]
+		}
+	}
+	return nil
+}
+
+// suggestConfigKey returns the known config key most similar to the unknown one, or empty if none is similar enough.
+func suggestConfigKey(key string) string {
+	calculator := utils.NewLevenshteinSimilarityCalculator()
+	suggestion, bestSimilarity := "", configKeySuggestionMinSimilarity
+	for _, spec := range ConfigKeySpecs {
+		similarity := calculator.CalculateSimilarity(strings.ToLower(key), strings.ToLower(spec.Key))
+		if similarity >= bestSimilarity {
+			suggestion, bestSimilarity = spec.Key, similarity
+		}
+	}
+	return suggestion
+}
+
+// isValueOfConfigType returns whether the JSON value is of the config type (see [ConfigKeySpec.Type]).
+// null is accepted for all types, and leaves the value unchanged.
+func isValueOfConfigType(value json.RawMessage, configType string) bool {
+	valueStr := strings.TrimSpace(string(value))
+	if valueStr == "null" {
+		return true
+	}
+	switch configType {
+	case "boolean":
+		return valueStr == "true" || valueStr == "false"
+	case "number":
+		var number int
+		return json.Unmarshal(value, &number) == nil
+	case "string":
+		return strings.HasPrefix(valueStr, "\"")
+	default:
+		return true
+	}
+}
+
+// findConfigField finds the field of the config struct by its JSON key, case-insensitively.
+func findConfigField(cfgValue reflect.Value, key string) reflect.Value {
+	cfgType := cfgValue.Type()
+	for i := 0; i < cfgType.NumField(); i++ {
+		jsonKey, _, _ := strings.Cut(cfgType.Field(i).Tag.Get("json"), ",")
+		if strings.EqualFold(jsonKey, key) {
+			return cfgValue.Field(i)
+		}
+	}
+	return reflect.Value{}
+}
+
+// wrapConfigComment wraps the text into lines of at most width characters, breaking at spaces.
+// Words longer than width are kept in their own lines.
+func wrapConfigComment(text string, width int) []string {
+	lines := make([]string, 0)
+	line := ""
+	for _, word := range strings.Fields(text) {
+		if line != "" && len(line)+1+len(word) > width {
+			lines = append(lines, line)
+			line = ""
+		}
+		if line != "" {
+			line += " "
+		}
+		line += word
+	}
+	if line != "" {
+		lines = append(lines, line)
+	}
+	return lines
+}
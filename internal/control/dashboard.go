@@ -0,0 +1,72 @@
+package control
+
+import (
+	_ "embed"
+	"fmt"
+	"net/url"
+	"resttracefuzzer/internal/fuzzer"
+	"resttracefuzzer/pkg/casemanager"
+	"resttracefuzzer/pkg/feedback/trace"
+	"resttracefuzzer/pkg/knowledge"
+	"strconv"
+)
+
+// DashboardRecentTraceCount is the number of most recent traces shown on the dashboard.
+const DashboardRecentTraceCount = 50
+
+// DefaultQueueLimit is the default number of test scenarios or operation cases listed by the control API and the dashboard.
+const DefaultQueueLimit = 10
+
+// DashboardPage is the HTML page of the dashboard, served at `/` of the dashboard server.
+// It polls the JSON APIs of the dashboard server (under `/api/`) periodically, and renders progress and coverage,
+// the scenario queue, found bugs and recent traces, so that a long-running fuzzing job can be monitored remotely.
+//
+//go:embed dashboard.html
+var DashboardPage []byte
+
+// ParseQueueLimit parses query parameter `limit` of the control API, i.e., the number of items to list.
+// It returns [DefaultQueueLimit] if the parameter is absent, or an error if it is not a non-negative integer.
+func ParseQueueLimit(query url.Values) (int, error) {
+	limitStr := query.Get("limit")
+	if limitStr == "" {
+		return DefaultQueueLimit, nil
+	}
+	limit, err := strconv.Atoi(limitStr)
+	if err != nil || limit < 0 {
+		return 0, fmt.Errorf("invalid limit %s, expected a non-negative integer", limitStr)
+	}
+	return limit, nil
+}
+
+// NewDashboardServer creates the server of the web dashboard listening on the address, serving the dashboard page at `/`
+// and its JSON APIs under `/api/`, i.e., progress and coverage of the fuzzer, the scenario queue, new failure signatures (found bugs) in this run and recent traces.
+// Like the control API, the server is unauthenticated, but unlike it, the server is read-only and serves no credentials,
+// so it can be exposed to monitor a remote long-running fuzzing job.
+// Thus, the scenario queue is listed as summaries (see [casemanager.TestScenarioSummary]), without request headers or bodies.
+func NewDashboardServer(
+	address string,
+	mainFuzzer fuzzer.Fuzzer,
+	caseManager *casemanager.CaseManager,
+	failureSignatureTracker *knowledge.FailureSignatureTracker,
+	recentTraceBuffer *trace.RecentTraceBuffer,
+) *ControlServer {
+	dashboardServer := NewControlServer(address)
+	dashboardServer.RegisterPage("/", DashboardPage)
+	dashboardServer.RegisterHandler("/api/progress", func() (any, error) {
+		return mainFuzzer.GetProgress(), nil
+	})
+	dashboardServer.RegisterQueryHandler("/api/queue/scenarios", func(query url.Values) (any, error) {
+		limit, err := ParseQueueLimit(query)
+		if err != nil {
+			return nil, err
+		}
+		return caseManager.GetTopScenarioSummaries(limit), nil
+	})
+	dashboardServer.RegisterHandler("/api/bugs", func() (any, error) {
+		return failureSignatureTracker.GetNewSignatures(), nil
+	})
+	dashboardServer.RegisterHandler("/api/traces", func() (any, error) {
+		return recentTraceBuffer.GetAll(), nil
+	})
+	return dashboardServer
+}
@@ -0,0 +1,157 @@
+// Package control provides the control API of the fuzzer,
+// an HTTP server used to inspect (and control) the fuzzer while it is running.
+package control
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/bytedance/sonic"
+	"github.com/rs/zerolog/log"
+)
+
+// ControlServer is the HTTP server of control API.
+// Each endpoint is registered with a handler returning a JSON-serializable value.
+// Handlers are called concurrently with fuzzing, so they must be safe to call from other goroutines.
+// Requests are not authenticated, so the control API should listen on a loopback address only, see [IsLoopbackAddress].
+type ControlServer struct {
+	// Address is the address the server listens on, e.g., 127.0.0.1:8089.
+	Address string
+
+	// mux routes requests to handlers.
+	mux *http.ServeMux
+
+	// server is the underlying HTTP server, set when the server starts.
+	server *http.Server
+}
+
+// NewControlServer creates a new ControlServer listening on the given address.
+func NewControlServer(address string) *ControlServer {
+	return &ControlServer{
+		Address: address,
+		mux:     http.NewServeMux(),
+	}
+}
+
+// IsLoopbackAddress checks whether the address (host:port) only accepts connections from the local host,
+// i.e., its host is `localhost` or a loopback IP. An empty host (e.g., `:8089`) listens on all interfaces.
+func IsLoopbackAddress(address string) bool {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return false
+	}
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// maxRequestBodySize is the maximum size of request bodies of POST endpoints, i.e., 1 MiB.
+const maxRequestBodySize = 1 << 20
+
+// RegisterHandler registers a GET endpoint of the control API.
+// The value returned by the handler is marshalled to JSON as response body.
+// If the handler returns an error, the response status code is 500.
+func (s *ControlServer) RegisterHandler(path string, handler func() (any, error)) {
+	s.handle(http.MethodGet, path, http.StatusInternalServerError, func(*http.Request) (any, error) {
+		return handler()
+	})
+}
+
+// RegisterQueryHandler registers a GET endpoint of the control API, whose handler reads the query parameters of the request.
+// The value returned by the handler is marshalled to JSON as response body.
+// If the handler returns an error, the response status code is 400, as the error is caused by invalid query parameters.
+func (s *ControlServer) RegisterQueryHandler(path string, handler func(query url.Values) (any, error)) {
+	s.handle(http.MethodGet, path, http.StatusBadRequest, func(r *http.Request) (any, error) {
+		return handler(r.URL.Query())
+	})
+}
+
+// RegisterPostHandler registers a POST endpoint of the control API, whose handler reads the request body (at most [maxRequestBodySize] bytes).
+// The value returned by the handler is marshalled to JSON as response body.
+// If the handler returns an error, the response status code is 400, as the error is caused by invalid request body.
+func (s *ControlServer) RegisterPostHandler(path string, handler func(body []byte) (any, error)) {
+	s.handle(http.MethodPost, path, http.StatusBadRequest, func(r *http.Request) (any, error) {
+		body, err := io.ReadAll(io.LimitReader(r.Body, maxRequestBodySize))
+		if err != nil {
+			return nil, err
+		}
+		return handler(body)
+	})
+}
+
+// RegisterPage registers a GET endpoint of the control API serving a static HTML page, e.g., the dashboard.
+// Path `/` serves the page at the root only, instead of all unmatched paths.
+func (s *ControlServer) RegisterPage(path string, page []byte) {
+	pattern := http.MethodGet + " " + path
+	if path == "/" {
+		pattern += "{$}"
+	}
+	s.mux.HandleFunc(pattern, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		_, err := w.Write(page)
+		if err != nil {
+			log.Err(err).Msgf("[ControlServer.RegisterPage] Failed to write page %s", path)
+		}
+	})
+}
+
+// handle registers the endpoint of the method and path, responding with errStatusCode if the handler returns an error.
+func (s *ControlServer) handle(method, path string, errStatusCode int, handler func(r *http.Request) (any, error)) {
+	s.mux.HandleFunc(method+" "+path, func(w http.ResponseWriter, r *http.Request) {
+		value, err := handler(r)
+		if err != nil {
+			log.Err(err).Msgf("[ControlServer.handle] Handler of %s %s failed", method, path)
+			http.Error(w, err.Error(), errStatusCode)
+			return
+		}
+		body, err := sonic.Marshal(value)
+		if err != nil {
+			log.Err(err).Msgf("[ControlServer.handle] Failed to marshal response of %s %s", method, path)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, err = w.Write(body)
+		if err != nil {
+			log.Err(err).Msgf("[ControlServer.handle] Failed to write response of %s %s", method, path)
+		}
+	})
+}
+
+// Handler returns the handler routing requests to registered endpoints, e.g., to serve them without listening on the address in tests.
+func (s *ControlServer) Handler() http.Handler {
+	return s.mux
+}
+
+// Start starts the server in background.
+// Errors after the server starts are logged, as the control API should not stop fuzzing.
+func (s *ControlServer) Start() {
+	s.server = &http.Server{
+		Addr:    s.Address,
+		Handler: s.mux,
+	}
+	go func() {
+		log.Info().Msgf("[ControlServer.Start] Control API listening on %s", s.Address)
+		err := s.server.ListenAndServe()
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Err(err).Msg("[ControlServer.Start] Control API stopped unexpectedly")
+		}
+	}()
+}
+
+// Stop gracefully shuts down the server, waiting at most the given timeout for in-flight requests.
+func (s *ControlServer) Stop(timeout time.Duration) error {
+	if s.server == nil {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	return s.server.Shutdown(ctx)
+}
@@ -0,0 +1,108 @@
+// Package demo provides a self-contained demo target of the fuzzer, i.e., a small system of services instrumented by OpenTelemetry,
+// and an in-process Jaeger-compatible trace backend collecting their traces.
+// It lets users try the fuzzer without deploying any system, and lets integration tests run the whole fuzzing loop.
+//
+// The system consists of three services:
+//   - order, the public service (see the OpenAPI spec embedded), which calls inventory and payment;
+//   - inventory, which keeps stocks of items, and fails with 500 when reserving a non-positive quantity;
+//   - payment, which declines payments of large amounts.
+//
+// The order service forwards header X-Tenant-ID to inventory but not to payment, which is found by the context propagation oracle.
+package demo
+
+import (
+	"context"
+	_ "embed"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+
+	"github.com/cloudwego/hertz/pkg/app/server"
+	"github.com/rs/zerolog/log"
+)
+
+// embeddedOpenAPISpec is the OpenAPI spec of the order service, i.e., the public API of the demo.
+//
+//go:embed openapi.json
+var embeddedOpenAPISpec []byte
+
+// Demo is a running demo target.
+type Demo struct {
+	// ServerBaseURL is the base URL of the public service, i.e., config `server-base-url` of the fuzzer.
+	ServerBaseURL string
+
+	// TraceBackendURL is the URL of the Jaeger-compatible trace backend, i.e., config `trace-backend-url` of the fuzzer.
+	TraceBackendURL string
+
+	// JaegerStub stores traces of the services.
+	JaegerStub *JaegerStub
+
+	// services are the services of the demo.
+	services []*service
+
+	// traceBackend is the server of the trace backend.
+	traceBackend *server.Hertz
+}
+
+// Start starts the demo target on random local ports.
+// As listeners are created before it returns, requests sent right after it are served once the servers are running.
+func Start() (*Demo, error) {
+	jaegerStub := NewJaegerStub()
+	inventory, err := newInventoryService(jaegerStub)
+	if err != nil {
+		return nil, err
+	}
+	payment, err := newPaymentService(jaegerStub)
+	if err != nil {
+		return nil, err
+	}
+	order, err := newOrderService(jaegerStub, inventory, payment)
+	if err != nil {
+		return nil, err
+	}
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen for trace backend: %w", err)
+	}
+	traceBackend := newHertzServer(listener)
+	jaegerStub.registerRoutes(traceBackend)
+
+	d := &Demo{
+		ServerBaseURL:   order.BaseURL,
+		TraceBackendURL: "http://" + listener.Addr().String(),
+		JaegerStub:      jaegerStub,
+		services:        []*service{inventory.service, payment.service, order.service},
+		traceBackend:    traceBackend,
+	}
+	for _, s := range d.services {
+		s.start()
+	}
+	go func() {
+		if err := traceBackend.Run(); err != nil {
+			log.Err(err).Msgf("[Demo.Start] Trace backend stopped")
+		}
+	}()
+	log.Info().Msgf("[Demo.Start] Demo started, server base URL: %s, trace backend URL: %s", d.ServerBaseURL, d.TraceBackendURL)
+	return d, nil
+}
+
+// Stop stops all services and the trace backend of the demo.
+func (d *Demo) Stop(ctx context.Context) error {
+	errs := make([]error, 0)
+	for _, s := range d.services {
+		errs = append(errs, s.stop(ctx))
+	}
+	errs = append(errs, d.traceBackend.Shutdown(ctx))
+	return errors.Join(errs...)
+}
+
+// WriteOpenAPISpec writes the OpenAPI spec of the public service of the demo to the path, i.e., config `openapi-spec` of the fuzzer.
+// The parent directory is created if it does not exist.
+func WriteOpenAPISpec(path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+		return fmt.Errorf("failed to create directory of %s: %w", path, err)
+	}
+	return os.WriteFile(path, embeddedOpenAPISpec, 0644)
+}
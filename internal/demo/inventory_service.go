@@ -0,0 +1,130 @@
+package demo
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/bytedance/sonic"
+	"github.com/cloudwego/hertz/pkg/app"
+	oteltrace "go.opentelemetry.io/otel/trace"
+)
+
+// item is an item in the inventory.
+type item struct {
+	// ItemID is the ID of the item.
+	ItemID string `json:"itemId"`
+
+	// Price is the price of a single item.
+	Price int `json:"price"`
+
+	// Stock is the number of items not reserved.
+	Stock int `json:"stock"`
+}
+
+// reservationRequest is the request body of reserving items.
+type reservationRequest struct {
+	// Quantity is the number of items to reserve.
+	Quantity int `json:"quantity"`
+}
+
+// inventoryService keeps stocks of items, and reserves items for orders. It is internal, i.e., called by the order service only.
+//
+// Bug seeded: reserving a non-positive quantity is not validated, and fails with 500 after the stock is corrupted,
+// which is recorded as an exception in its span.
+type inventoryService struct {
+	*service
+
+	// items maps from item IDs to items.
+	items map[string]*item
+
+	// mu protects items.
+	mu sync.Mutex
+}
+
+// newInventoryService creates a new inventoryService with seeded items.
+func newInventoryService(exporter *JaegerStub) (*inventoryService, error) {
+	svc, err := newService("inventory", exporter)
+	if err != nil {
+		return nil, err
+	}
+	s := &inventoryService{
+		service: svc,
+		items: map[string]*item{
+			"apple":  {ItemID: "apple", Price: 3, Stock: 100},
+			"banana": {ItemID: "banana", Price: 2, Stock: 50},
+			"cherry": {ItemID: "cherry", Price: 10, Stock: 5},
+		},
+	}
+	s.Hertz.GET("/items", s.listItems)
+	s.Hertz.GET("/items/:itemId", s.getItem)
+	s.Hertz.POST("/items/:itemId/reservations", s.reserveItem)
+	return s, nil
+}
+
+// listItems lists all items, sorted by item IDs.
+func (s *inventoryService) listItems(ctx context.Context, c *app.RequestContext) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	items := make([]item, 0, len(s.items))
+	for _, it := range s.items {
+		items = append(items, *it)
+	}
+	slices.SortFunc(items, func(a, b item) int {
+		return strings.Compare(a.ItemID, b.ItemID)
+	})
+	c.JSON(http.StatusOK, items)
+}
+
+// getItem gets an item by its ID.
+func (s *inventoryService) getItem(ctx context.Context, c *app.RequestContext) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	it, exist := s.items[c.Param("itemId")]
+	if !exist {
+		c.JSON(http.StatusNotFound, errorBody("item not found"))
+		return
+	}
+	c.JSON(http.StatusOK, it)
+}
+
+// reserveItem reserves a quantity of an item, and returns the reserved item, with the price of the whole quantity.
+func (s *inventoryService) reserveItem(ctx context.Context, c *app.RequestContext) {
+	var req reservationRequest
+	if err := sonic.Unmarshal(c.Request.Body(), &req); err != nil {
+		c.JSON(http.StatusBadRequest, errorBody("invalid reservation"))
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	it, exist := s.items[c.Param("itemId")]
+	if !exist {
+		c.JSON(http.StatusNotFound, errorBody("item not found"))
+		return
+	}
+	if req.Quantity > it.Stock {
+		c.JSON(http.StatusConflict, errorBody("insufficient stock"))
+		return
+	}
+	it.Stock -= req.Quantity
+	// The seeded bug: non-positive quantities corrupt the stock instead of being rejected.
+	if req.Quantity <= 0 {
+		err := errors.New("stock invariant violated: reserved quantity must be positive")
+		oteltrace.SpanFromContext(ctx).RecordError(err)
+		c.JSON(http.StatusInternalServerError, errorBody("internal error"))
+		return
+	}
+	c.JSON(http.StatusOK, map[string]any{
+		"itemId":   it.ItemID,
+		"quantity": req.Quantity,
+		"amount":   it.Price * req.Quantity,
+	})
+}
+
+// errorBody returns the response body of an error message.
+func errorBody(message string) map[string]string {
+	return map[string]string{"error": message}
+}
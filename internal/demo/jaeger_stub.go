@@ -0,0 +1,199 @@
+package demo
+
+import (
+	"context"
+	"maps"
+	"net/http"
+	"resttracefuzzer/pkg/feedback/trace"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/cloudwego/hertz/pkg/app/server"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+)
+
+// defaultJaegerStubTraceLimit is the default number of traces returned by searching traces of a service.
+const defaultJaegerStubTraceLimit = 20
+
+// JaegerStub is an in-process stand-in of the Jaeger query service, serving traces exported by services of the demo.
+// It implements [sdktrace.SpanExporter], so spans are queryable once they end, and serves the subset of Jaeger query API used by [trace.JaegerTraceFetcher]:
+//   - GET /api/services lists services;
+//   - GET /api/traces?service={service}&limit={limit} searches the latest traces of a service;
+//   - GET /api/traces/{traceID} gets a trace, or responds 404 if it does not exist.
+type JaegerStub struct {
+	// traces maps from trace IDs to traces, in Jaeger format.
+	traces map[string]*trace.JaegerTrace
+
+	// traceIDs are IDs of traces, in the order of their first spans exported.
+	traceIDs []string
+
+	// mu protects the states above.
+	mu sync.Mutex
+}
+
+// NewJaegerStub creates a new JaegerStub without any trace.
+func NewJaegerStub() *JaegerStub {
+	return &JaegerStub{
+		traces:   make(map[string]*trace.JaegerTrace),
+		traceIDs: make([]string, 0),
+	}
+}
+
+// ExportSpans implements [sdktrace.SpanExporter], storing the spans in Jaeger format.
+func (s *JaegerStub) ExportSpans(ctx context.Context, spans []sdktrace.ReadOnlySpan) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, span := range spans {
+		traceID := span.SpanContext().TraceID().String()
+		jaegerTrace, exist := s.traces[traceID]
+		if !exist {
+			jaegerTrace = &trace.JaegerTrace{
+				TraceID:   traceID,
+				Spans:     make([]trace.JaegerTraceSpan, 0),
+				Processes: make(map[string]*trace.JaegerProcessValueEntry),
+			}
+			s.traces[traceID] = jaegerTrace
+			s.traceIDs = append(s.traceIDs, traceID)
+		}
+		// Processes are identified by service names, as each service of the demo is a single process.
+		serviceName := getServiceName(span.Resource())
+		jaegerTrace.Processes[serviceName] = &trace.JaegerProcessValueEntry{ServiceName: serviceName}
+		jaegerTrace.Spans = append(jaegerTrace.Spans, convertSpanToJaegerSpan(span, serviceName))
+	}
+	return nil
+}
+
+// Shutdown implements [sdktrace.SpanExporter]. Traces are still served after it.
+func (s *JaegerStub) Shutdown(ctx context.Context) error {
+	return nil
+}
+
+// GetTraceCount returns the number of traces exported.
+func (s *JaegerStub) GetTraceCount() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.traceIDs)
+}
+
+// registerRoutes registers the Jaeger query API on the server.
+func (s *JaegerStub) registerRoutes(h *server.Hertz) {
+	h.GET("/api/services", func(ctx context.Context, c *app.RequestContext) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		services := make(map[string]struct{})
+		for _, jaegerTrace := range s.traces {
+			for serviceName := range jaegerTrace.Processes {
+				services[serviceName] = struct{}{}
+			}
+		}
+		c.JSON(http.StatusOK, map[string]any{"data": slices.Sorted(maps.Keys(services))})
+	})
+	h.GET("/api/traces", func(ctx context.Context, c *app.RequestContext) {
+		serviceName := c.Query("service")
+		limit, err := strconv.Atoi(c.Query("limit"))
+		if err != nil || limit <= 0 {
+			limit = defaultJaegerStubTraceLimit
+		}
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		traces := make([]*trace.JaegerTrace, 0)
+		// The latest traces are returned first, like Jaeger.
+		for i := len(s.traceIDs) - 1; i >= 0 && len(traces) < limit; i-- {
+			jaegerTrace := s.traces[s.traceIDs[i]]
+			if _, exist := jaegerTrace.Processes[serviceName]; exist || serviceName == "" {
+				traces = append(traces, jaegerTrace)
+			}
+		}
+		c.JSON(http.StatusOK, map[string]any{"data": traces})
+	})
+	h.GET("/api/traces/:traceID", func(ctx context.Context, c *app.RequestContext) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		jaegerTrace, exist := s.traces[strings.ToLower(c.Param("traceID"))]
+		if !exist {
+			c.JSON(http.StatusNotFound, map[string]any{"data": nil, "errors": []map[string]any{{"code": http.StatusNotFound, "msg": "trace not found"}}})
+			return
+		}
+		c.JSON(http.StatusOK, map[string]any{"data": []*trace.JaegerTrace{jaegerTrace}})
+	})
+}
+
+// convertSpanToJaegerSpan converts an OpenTelemetry span to a Jaeger span of the process of the service,
+// where the span kind and status are tags, and events are logs, as exported by Jaeger.
+func convertSpanToJaegerSpan(span sdktrace.ReadOnlySpan, serviceName string) trace.JaegerTraceSpan {
+	tags := make([]trace.JaegerTagEntry, 0, len(span.Attributes())+3)
+	for _, attr := range span.Attributes() {
+		tags = append(tags, convertAttributeToJaegerTag(attr))
+	}
+	tags = append(tags, trace.JaegerTagEntry{Key: "span.kind", Type: "string", Value: span.SpanKind().String()})
+	switch span.Status().Code {
+	case codes.Error:
+		tags = append(tags,
+			trace.JaegerTagEntry{Key: "otel.status_code", Type: "string", Value: "ERROR"},
+			trace.JaegerTagEntry{Key: "otel.status_description", Type: "string", Value: span.Status().Description},
+			trace.JaegerTagEntry{Key: "error", Type: "bool", Value: true},
+		)
+	case codes.Ok:
+		tags = append(tags, trace.JaegerTagEntry{Key: "otel.status_code", Type: "string", Value: "OK"})
+	}
+
+	logs := make([]trace.JaegerLogEntry, 0, len(span.Events()))
+	for _, event := range span.Events() {
+		fields := []trace.JaegerTagEntry{{Key: "event", Type: "string", Value: event.Name}}
+		for _, attr := range event.Attributes {
+			fields = append(fields, convertAttributeToJaegerTag(attr))
+		}
+		logs = append(logs, trace.JaegerLogEntry{Timestamp: event.Time.UnixMicro(), Fields: fields})
+	}
+
+	jaegerSpan := trace.JaegerTraceSpan{
+		TraceID:       span.SpanContext().TraceID().String(),
+		SpanID:        span.SpanContext().SpanID().String(),
+		OperationName: span.Name(),
+		References:    make([]map[string]string, 0),
+		StartTime:     span.StartTime().UnixMicro(),
+		Duration:      span.EndTime().Sub(span.StartTime()).Microseconds(),
+		Tags:          tags,
+		Logs:          logs,
+		ProcessID:     serviceName,
+	}
+	if span.Parent().IsValid() {
+		jaegerSpan.ParentID = span.Parent().SpanID().String()
+		jaegerSpan.References = append(jaegerSpan.References, map[string]string{
+			"refType": "CHILD_OF",
+			"traceID": jaegerSpan.TraceID,
+			"spanID":  jaegerSpan.ParentID,
+		})
+	}
+	return jaegerSpan
+}
+
+// convertAttributeToJaegerTag converts an OpenTelemetry attribute to a Jaeger tag.
+// Slices are converted to their string representations, as Jaeger has no slice tags.
+func convertAttributeToJaegerTag(attr attribute.KeyValue) trace.JaegerTagEntry {
+	switch attr.Value.Type() {
+	case attribute.BOOL:
+		return trace.JaegerTagEntry{Key: string(attr.Key), Type: "bool", Value: attr.Value.AsBool()}
+	case attribute.INT64:
+		return trace.JaegerTagEntry{Key: string(attr.Key), Type: "int64", Value: attr.Value.AsInt64()}
+	case attribute.FLOAT64:
+		return trace.JaegerTagEntry{Key: string(attr.Key), Type: "float64", Value: attr.Value.AsFloat64()}
+	default:
+		return trace.JaegerTagEntry{Key: string(attr.Key), Type: "string", Value: attr.Value.Emit()}
+	}
+}
+
+// getServiceName returns the service name of the resource, i.e., attribute `service.name`.
+func getServiceName(res *resource.Resource) string {
+	if value, exist := res.Set().Value(semconv.ServiceNameKey); exist {
+		return value.AsString()
+	}
+	return "unknown_service"
+}
@@ -0,0 +1,210 @@
+package demo
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/bytedance/sonic"
+	"github.com/cloudwego/hertz/pkg/app"
+)
+
+// order is an order of items.
+type order struct {
+	// OrderID is the ID of the order.
+	OrderID string `json:"orderId"`
+
+	// ItemID is the ID of the item ordered.
+	ItemID string `json:"itemId"`
+
+	// Quantity is the number of items ordered.
+	Quantity int `json:"quantity"`
+
+	// Amount is the amount paid for the order.
+	Amount int `json:"amount"`
+
+	// PaymentID is the ID of the payment of the order.
+	PaymentID string `json:"paymentId"`
+}
+
+// orderRequest is the request body of creating an order.
+type orderRequest struct {
+	// ItemID is the ID of the item to order.
+	ItemID string `json:"itemId"`
+
+	// Quantity is the number of items to order.
+	Quantity int `json:"quantity"`
+}
+
+// orderService is the public service of the demo, see [embeddedOpenAPISpec] for its API.
+// It reads items from the inventory service, and creates orders by reserving items in the inventory service and paying by the payment service.
+//
+// Bug seeded: [TenantIDHeader] is forwarded to the inventory service, but not to the payment service.
+type orderService struct {
+	*service
+
+	// inventory is the inventory service called.
+	inventory *inventoryService
+
+	// payment is the payment service called.
+	payment *paymentService
+
+	// orders maps from order IDs to orders.
+	orders map[string]*order
+
+	// orderCount is the number of orders created, used to generate order IDs.
+	orderCount int
+
+	// mu protects orders and orderCount.
+	mu sync.Mutex
+}
+
+// newOrderService creates a new orderService calling the inventory and payment services.
+func newOrderService(exporter *JaegerStub, inventory *inventoryService, payment *paymentService) (*orderService, error) {
+	svc, err := newService("order", exporter)
+	if err != nil {
+		return nil, err
+	}
+	s := &orderService{
+		service:   svc,
+		inventory: inventory,
+		payment:   payment,
+		orders:    make(map[string]*order),
+	}
+	s.Hertz.GET("/items", s.listItems)
+	s.Hertz.GET("/items/:itemId", s.getItem)
+	s.Hertz.POST("/orders", s.createOrder)
+	s.Hertz.GET("/orders", s.listOrders)
+	s.Hertz.GET("/orders/:orderId", s.getOrder)
+	s.Hertz.DELETE("/orders/:orderId", s.deleteOrder)
+	return s, nil
+}
+
+// listItems lists items in the inventory service.
+func (s *orderService) listItems(ctx context.Context, c *app.RequestContext) {
+	statusCode, body, err := s.call(ctx, s.inventory.service, http.MethodGet, "/items", s.getForwardedHeaders(c), nil)
+	s.respondWithDownstream(c, statusCode, body, err)
+}
+
+// getItem gets an item in the inventory service.
+func (s *orderService) getItem(ctx context.Context, c *app.RequestContext) {
+	path := fmt.Sprintf("/items/%s", c.Param("itemId"))
+	statusCode, body, err := s.call(ctx, s.inventory.service, http.MethodGet, path, s.getForwardedHeaders(c), nil)
+	s.respondWithDownstream(c, statusCode, body, err)
+}
+
+// createOrder reserves the item in the inventory service, pays for it by the payment service, and creates the order.
+// Failures of the inventory or payment service are responded as is.
+func (s *orderService) createOrder(ctx context.Context, c *app.RequestContext) {
+	var req orderRequest
+	if err := sonic.Unmarshal(c.Request.Body(), &req); err != nil || req.ItemID == "" {
+		c.JSON(http.StatusBadRequest, errorBody("invalid order"))
+		return
+	}
+
+	reservationBody, _ := sonic.Marshal(reservationRequest{Quantity: req.Quantity})
+	path := fmt.Sprintf("/items/%s/reservations", req.ItemID)
+	statusCode, body, err := s.call(ctx, s.inventory.service, http.MethodPost, path, s.getForwardedHeaders(c), reservationBody)
+	if err != nil || statusCode != http.StatusOK {
+		s.respondWithDownstream(c, statusCode, body, err)
+		return
+	}
+	var reservation struct {
+		Amount int `json:"amount"`
+	}
+	if err := sonic.Unmarshal(body, &reservation); err != nil {
+		c.JSON(http.StatusBadGateway, errorBody("invalid reservation from inventory"))
+		return
+	}
+
+	s.mu.Lock()
+	s.orderCount++
+	orderID := fmt.Sprintf("order-%d", s.orderCount)
+	s.mu.Unlock()
+	paymentBody, _ := sonic.Marshal(paymentRequest{OrderID: orderID, Amount: reservation.Amount})
+	// The seeded bug: headers are not forwarded to the payment service.
+	statusCode, body, err = s.call(ctx, s.payment.service, http.MethodPost, "/payments", nil, paymentBody)
+	if err != nil || statusCode != http.StatusCreated {
+		s.respondWithDownstream(c, statusCode, body, err)
+		return
+	}
+	var payment struct {
+		PaymentID string `json:"paymentId"`
+	}
+	if err := sonic.Unmarshal(body, &payment); err != nil {
+		c.JSON(http.StatusBadGateway, errorBody("invalid payment from payment service"))
+		return
+	}
+
+	o := &order{
+		OrderID:   orderID,
+		ItemID:    req.ItemID,
+		Quantity:  req.Quantity,
+		Amount:    reservation.Amount,
+		PaymentID: payment.PaymentID,
+	}
+	s.mu.Lock()
+	s.orders[orderID] = o
+	s.mu.Unlock()
+	c.JSON(http.StatusCreated, o)
+}
+
+// listOrders lists all orders, sorted by order IDs.
+func (s *orderService) listOrders(ctx context.Context, c *app.RequestContext) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	orders := make([]order, 0, len(s.orders))
+	for _, o := range s.orders {
+		orders = append(orders, *o)
+	}
+	slices.SortFunc(orders, func(a, b order) int {
+		return strings.Compare(a.OrderID, b.OrderID)
+	})
+	c.JSON(http.StatusOK, orders)
+}
+
+// getOrder gets an order by its ID.
+func (s *orderService) getOrder(ctx context.Context, c *app.RequestContext) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	o, exist := s.orders[c.Param("orderId")]
+	if !exist {
+		c.JSON(http.StatusNotFound, errorBody("order not found"))
+		return
+	}
+	c.JSON(http.StatusOK, o)
+}
+
+// deleteOrder deletes an order by its ID.
+func (s *orderService) deleteOrder(ctx context.Context, c *app.RequestContext) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	orderID := c.Param("orderId")
+	if _, exist := s.orders[orderID]; !exist {
+		c.JSON(http.StatusNotFound, errorBody("order not found"))
+		return
+	}
+	delete(s.orders, orderID)
+	c.Status(http.StatusNoContent)
+}
+
+// getForwardedHeaders returns the headers of the request forwarded to downstream services, i.e., [TenantIDHeader].
+func (s *orderService) getForwardedHeaders(c *app.RequestContext) map[string]string {
+	headers := make(map[string]string)
+	if tenantID := string(c.Request.Header.Peek(TenantIDHeader)); tenantID != "" {
+		headers[TenantIDHeader] = tenantID
+	}
+	return headers
+}
+
+// respondWithDownstream responds with the response of a downstream service, or 502 if the downstream service cannot be called.
+func (s *orderService) respondWithDownstream(c *app.RequestContext, statusCode int, body []byte, err error) {
+	if err != nil {
+		c.JSON(http.StatusBadGateway, errorBody("downstream service unavailable"))
+		return
+	}
+	c.Data(statusCode, "application/json", body)
+}
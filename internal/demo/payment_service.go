@@ -0,0 +1,68 @@
+package demo
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+
+	"github.com/bytedance/sonic"
+	"github.com/cloudwego/hertz/pkg/app"
+)
+
+// paymentDeclineThreshold is the max amount of a payment accepted.
+const paymentDeclineThreshold = 10000
+
+// paymentRequest is the request body of creating a payment.
+type paymentRequest struct {
+	// OrderID is the ID of the order paid.
+	OrderID string `json:"orderId"`
+
+	// Amount is the amount paid.
+	Amount int `json:"amount"`
+}
+
+// paymentService pays for orders. It is internal, i.e., called by the order service only.
+// Payments of amounts above [paymentDeclineThreshold] are declined with 402.
+type paymentService struct {
+	*service
+
+	// paymentCount is the number of payments created, used to generate payment IDs.
+	paymentCount int
+
+	// mu protects paymentCount.
+	mu sync.Mutex
+}
+
+// newPaymentService creates a new paymentService.
+func newPaymentService(exporter *JaegerStub) (*paymentService, error) {
+	svc, err := newService("payment", exporter)
+	if err != nil {
+		return nil, err
+	}
+	s := &paymentService{service: svc}
+	s.Hertz.POST("/payments", s.createPayment)
+	return s, nil
+}
+
+// createPayment creates a payment of an order.
+func (s *paymentService) createPayment(ctx context.Context, c *app.RequestContext) {
+	var req paymentRequest
+	if err := sonic.Unmarshal(c.Request.Body(), &req); err != nil || req.OrderID == "" {
+		c.JSON(http.StatusBadRequest, errorBody("invalid payment"))
+		return
+	}
+	if req.Amount > paymentDeclineThreshold {
+		c.JSON(http.StatusPaymentRequired, errorBody("payment declined"))
+		return
+	}
+	s.mu.Lock()
+	s.paymentCount++
+	paymentID := fmt.Sprintf("payment-%d", s.paymentCount)
+	s.mu.Unlock()
+	c.JSON(http.StatusCreated, map[string]any{
+		"paymentId": paymentID,
+		"orderId":   req.OrderID,
+		"amount":    req.Amount,
+	})
+}
@@ -0,0 +1,198 @@
+package demo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"regexp"
+	fuzzerhttp "resttracefuzzer/pkg/utils/http"
+	"strings"
+	"time"
+
+	"github.com/cloudwego/hertz/pkg/app"
+	"github.com/cloudwego/hertz/pkg/app/server"
+	"github.com/cloudwego/hertz/pkg/network/standard"
+	"github.com/cloudwego/hertz/pkg/protocol"
+	"github.com/rs/zerolog/log"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	oteltrace "go.opentelemetry.io/otel/trace"
+)
+
+const (
+	// TraceIDResponseHeader is the response header carrying the trace ID of each request, read by the fuzzer (see config `trace-id-header-key`).
+	TraceIDResponseHeader = "X-Trace-Id"
+
+	// TenantIDHeader is the context header of tenants, which services of the demo record in their spans.
+	TenantIDHeader = "X-Tenant-ID"
+)
+
+// hertzRouteParamRegex matches path params of Hertz routes, e.g., `:itemId`.
+var hertzRouteParamRegex = regexp.MustCompile(`:(\w+)`)
+
+// textMapPropagator propagates trace contexts between services of the demo, in W3C Trace Context format.
+var textMapPropagator = propagation.TraceContext{}
+
+// service is a service of the demo, i.e., a Hertz server instrumented by OpenTelemetry, whose spans are exported to a [JaegerStub].
+type service struct {
+	// Name is the name of the service, i.e., `service.name` of its spans.
+	Name string
+
+	// BaseURL is the base URL of the service, e.g., `http://127.0.0.1:12345`.
+	BaseURL string
+
+	// Hertz is the server of the service.
+	Hertz *server.Hertz
+
+	// TracerProvider provides the tracer of the service.
+	TracerProvider *sdktrace.TracerProvider
+
+	// Tracer creates spans of the service.
+	Tracer oteltrace.Tracer
+}
+
+// newService creates a new service listening on a random local port, whose spans are exported to the exporter synchronously,
+// so that spans are queryable once responses are sent. The service is not started until [service.start] is called.
+func newService(name string, exporter sdktrace.SpanExporter) (*service, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen for service %s: %w", name, err)
+	}
+	tracerProvider := sdktrace.NewTracerProvider(
+		sdktrace.WithSyncer(exporter),
+		sdktrace.WithResource(resource.NewSchemaless(semconv.ServiceName(name))),
+	)
+	s := &service{
+		Name:           name,
+		BaseURL:        "http://" + listener.Addr().String(),
+		Hertz:          newHertzServer(listener),
+		TracerProvider: tracerProvider,
+		Tracer:         tracerProvider.Tracer("resttracefuzzer/internal/demo"),
+	}
+	s.Hertz.Use(s.tracingMiddleware)
+	return s, nil
+}
+
+// newHertzServer creates a Hertz server on the listener, without printing routes.
+func newHertzServer(listener net.Listener) *server.Hertz {
+	return server.New(
+		server.WithListener(listener),
+		server.WithTransport(standard.NewTransporter),
+		server.WithDisablePrintRoute(true),
+		server.WithExitWaitTime(0),
+	)
+}
+
+// start starts the service in the background.
+// Unlike [server.Hertz.Spin], it does not handle signals, which is left to the caller.
+func (s *service) start() {
+	go func() {
+		if err := s.Hertz.Run(); err != nil {
+			log.Err(err).Msgf("[service.start] Service %s stopped", s.Name)
+		}
+	}()
+}
+
+// stop stops the service, and flushes its spans.
+func (s *service) stop(ctx context.Context) error {
+	return errors.Join(s.Hertz.Shutdown(ctx), s.TracerProvider.Shutdown(ctx))
+}
+
+// tracingMiddleware creates a server span for each request, continuing the trace context in request headers (if any).
+// The span is named `{method} {route}` with OpenAPI-style route params (e.g., `GET /items/{itemId}`), as by OpenTelemetry HTTP instrumentations,
+// and records [TenantIDHeader] as a request header attribute. The trace ID is sent back in [TraceIDResponseHeader].
+func (s *service) tracingMiddleware(ctx context.Context, c *app.RequestContext) {
+	ctx = textMapPropagator.Extract(ctx, &requestHeaderCarrier{header: &c.Request.Header})
+	method := string(c.Method())
+	route := hertzRouteParamRegex.ReplaceAllString(c.FullPath(), "{$1}")
+	if route == "" {
+		route = string(c.Path())
+	}
+	attrs := []attribute.KeyValue{
+		semconv.HTTPRequestMethodKey.String(method),
+		semconv.HTTPRoute(route),
+		semconv.URLPath(string(c.Path())),
+	}
+	if tenantID := string(c.Request.Header.Peek(TenantIDHeader)); tenantID != "" {
+		attrs = append(attrs, attribute.String(requestHeaderAttributeKey(TenantIDHeader), tenantID))
+	}
+	ctx, span := s.Tracer.Start(ctx, method+" "+route, oteltrace.WithSpanKind(oteltrace.SpanKindServer), oteltrace.WithAttributes(attrs...))
+	defer span.End()
+	c.Response.Header.Set(TraceIDResponseHeader, span.SpanContext().TraceID().String())
+
+	c.Next(ctx)
+
+	statusCode := c.Response.StatusCode()
+	span.SetAttributes(semconv.HTTPResponseStatusCode(statusCode))
+	if statusCode >= http.StatusInternalServerError {
+		span.SetStatus(codes.Error, http.StatusText(statusCode))
+	}
+}
+
+// call calls the API of another service in a client span, propagating the trace context of ctx and the headers.
+// It returns the status code and the response body.
+func (s *service) call(ctx context.Context, target *service, method, path string, headers map[string]string, body []byte) (int, []byte, error) {
+	ctx, span := s.Tracer.Start(ctx, method, oteltrace.WithSpanKind(oteltrace.SpanKindClient), oteltrace.WithAttributes(
+		semconv.HTTPRequestMethodKey.String(method),
+		semconv.ServerAddress(target.Name),
+		semconv.URLFull(target.BaseURL+path),
+	))
+	defer span.End()
+
+	carrier := propagation.MapCarrier{"Content-Type": "application/json"}
+	for key, value := range headers {
+		carrier.Set(key, value)
+	}
+	textMapPropagator.Inject(ctx, carrier)
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	client := fuzzerhttp.NewHTTPClient(target.BaseURL, []string{}, fuzzerhttp.EmptyHTTPClientMiddlewareSlice())
+	statusCode, _, respBody, err := client.PerformRequest(ctx, path, method, carrier, nil, nil, body)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return 0, nil, err
+	}
+	span.SetAttributes(semconv.HTTPResponseStatusCode(statusCode))
+	if statusCode >= http.StatusInternalServerError {
+		span.SetStatus(codes.Error, http.StatusText(statusCode))
+	}
+	return statusCode, respBody, nil
+}
+
+// requestHeaderCarrier adapts Hertz request headers to [propagation.TextMapCarrier].
+type requestHeaderCarrier struct {
+	// header is the request header.
+	header *protocol.RequestHeader
+}
+
+// Get implements [propagation.TextMapCarrier].
+func (c *requestHeaderCarrier) Get(key string) string {
+	return string(c.header.Peek(key))
+}
+
+// Set implements [propagation.TextMapCarrier].
+func (c *requestHeaderCarrier) Set(key, value string) {
+	c.header.Set(key, value)
+}
+
+// Keys implements [propagation.TextMapCarrier].
+func (c *requestHeaderCarrier) Keys() []string {
+	keys := make([]string, 0)
+	c.header.VisitAll(func(key, value []byte) {
+		keys = append(keys, string(key))
+	})
+	return keys
+}
+
+// requestHeaderAttributeKey returns the span attribute key recording the request header, by OpenTelemetry semantic conventions,
+// e.g., `http.request.header.x-tenant-id`.
+func requestHeaderAttributeKey(header string) string {
+	return "http.request.header." + strings.ToLower(header)
+}
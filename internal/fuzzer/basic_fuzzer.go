@@ -1,6 +1,10 @@
 package fuzzer
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
 	"resttracefuzzer/internal/config"
 	"resttracefuzzer/pkg/casemanager"
 	"resttracefuzzer/pkg/feedback"
@@ -8,10 +12,14 @@ import (
 	"resttracefuzzer/pkg/report"
 	fuzzruntime "resttracefuzzer/pkg/runtime"
 	"resttracefuzzer/pkg/static"
+	"resttracefuzzer/pkg/utils/grpc"
 	"resttracefuzzer/pkg/utils/http"
+	"resttracefuzzer/pkg/utils/messaging"
+	"runtime/debug"
 	"time"
 
 	hertzclient "github.com/cloudwego/hertz/pkg/app/client"
+	hertzconfig "github.com/cloudwego/hertz/pkg/common/config"
 	"github.com/rs/zerolog/log"
 )
 
@@ -26,6 +34,9 @@ type BasicFuzzer struct {
 	// ResponseProcesser checks and processes the response.
 	ResponseProcesser *feedback.ResponseProcesser
 
+	// EnumCoverageTracker tracks which enum members of parameters have been exercised.
+	EnumCoverageTracker *feedback.EnumCoverageTracker
+
 	// TraceManager manages traces.
 	TraceManager *trace.TraceManager
 
@@ -35,43 +46,235 @@ type BasicFuzzer struct {
 	// ReachabilityMap stores the reachability (from external APIs to internal service interfaces) information of the API.
 	ReachabilityMap *fuzzruntime.RuntimeReachabilityMap
 
+	// SpanErrorCoverage records errors (error status and exceptions) of spans in traces, as a coverage dimension.
+	SpanErrorCoverage *fuzzruntime.SpanErrorCoverage
+
+	// DatabaseCoverage records database operations of internal services in traces, as a coverage dimension.
+	DatabaseCoverage *fuzzruntime.DatabaseCoverage
+
 	// Budget is the budget of the fuzzer, which is the maximum time the fuzzer can run, in milliseconds.
 	Budget time.Duration
 
 	// HTTPClient is the HTTP client.
 	HTTPClient *http.HTTPClient
 
+	// ServerHTTPClients are HTTP clients of servers of the API doc to rotate among (see [static.APIManager.ServerURLs]),
+	// the first of which is HTTPClient. HTTPClient is switched to the next one for each test scenario.
+	// It is nil if servers are not rotated.
+	ServerHTTPClients []*http.HTTPClient
+
+	// serverIndex is the index of the current HTTPClient in ServerHTTPClients.
+	serverIndex int
+
+	// TargetHTTPClients are HTTP clients of additional targets of the system (see [static.APIManager.APITargets]), by target name.
+	// Requests of operations from an additional target are sent by its client, and others by HTTPClient.
+	TargetHTTPClients map[string]*http.HTTPClient
+
+	// GRPCClient is the client of gRPC calls, executing operations of type [static.SimpleAPIMethodTypeGRPC].
+	// If it is nil, such operations are executed as HTTP requests by HTTPClient.
+	GRPCClient *grpc.GRPCClient
+
+	// MessagePublisher publishes messages of operations of type [static.SimpleAPIMethodTypeMessaging] to the message broker.
+	// It is nil if no message broker is configured.
+	MessagePublisher messaging.MessagePublisher
+
 	// FuzzingSnapshot is the snapshot of the fuzzing process.
 	FuzzingSnapshot *FuzzingSnapshot
 
 	// TestLogReporter is responsible for logging the tested operations (with their results),
 	// and generating a report after the fuzzing process.
 	TestLogReporter *report.TestLogReporter
+
+	// MemoryWatchpoint tells when to compact the corpus, if the fuzzer uses too much memory.
+	MemoryWatchpoint *MemoryWatchpoint
+
+	// MethodProber probes undocumented method handling before fuzzing. Probing is skipped if it is nil.
+	MethodProber *feedback.MethodProber
+
+	// BOLAOracle detects broken object level authorization, by replaying requests on resources created in test scenarios as other users.
+	// Replay is skipped if fewer than 2 user sessions are configured in case manager.
+	BOLAOracle *feedback.BOLAOracle
+
+	// ScenarioMinimizer minimizes multi-operation test scenarios triggering server errors, by re-executing reduced scenarios.
+	ScenarioMinimizer *feedback.ScenarioMinimizer
+
+	// IdempotencyOracle verifies that the server honors idempotency keys, by replaying requests with the same keys.
+	IdempotencyOracle *feedback.IdempotencyOracle
+
+	// CodeCoverageTracker collects line and branch coverage of services under test after each scenario.
+	// It is nil if no coverage collector is configured.
+	CodeCoverageTracker *feedback.CodeCoverageTracker
+
+	// CandidateHTTPClient is the HTTP client of the candidate version of the system in differential fuzzing,
+	// to which HTTP requests are also sent, and compared by DifferentialOracle. It is nil if differential fuzzing is disabled.
+	CandidateHTTPClient *http.HTTPClient
+
+	// DifferentialOracle compares responses of the baseline (i.e., HTTPClient) and the candidate versions of the system.
+	DifferentialOracle *feedback.DifferentialOracle
+
+	// ChaosCoordinator runs chaos experiments during selected test scenarios, see [ChaosCoordinator].
+	ChaosCoordinator *ChaosCoordinator
+
+	// ScenarioHookRunner runs setup and teardown hooks of a script around each test scenario, see [ScenarioHookRunner].
+	// It is nil if no hook script is configured.
+	ScenarioHookRunner *ScenarioHookRunner
+
+	// ErrorBudget aborts fuzzing if too many requests fail in the same way, e.g., due to misconfiguration.
+	// It is nil if no budget is set.
+	ErrorBudget *feedback.ErrorBudget
+
+	// EndpointPerformanceTracker records response times, timeouts and connection errors of requests of each API method.
+	EndpointPerformanceTracker *feedback.EndpointPerformanceTracker
+
+	// CreatedResourceTracker tracks resources created during fuzzing, which are deleted after fuzzing if cleanup is enabled,
+	// see [BasicFuzzer.cleanupCreatedResources].
+	CreatedResourceTracker *feedback.CreatedResourceTracker
+
+	// Progress tracks the progress of fuzzing, for live display.
+	Progress *ProgressTracker
+
+	// ReportInterval is the interval of writing intermediate reports by ReportFunc during fuzzing.
+	// Intermediate reports are not written if it is not positive, or ReportFunc is nil.
+	ReportInterval time.Duration
+
+	// ReportFunc writes intermediate reports. It is called between test scenarios, so it does not race with fuzzing.
+	ReportFunc func()
 }
 
 // NewBasicFuzzer creates a new BasicFuzzer.
+// It returns an error if an explicitly configured component fails to be created, e.g., the transport of HTTP clients.
 func NewBasicFuzzer(
 	APIManager *static.APIManager,
 	caseManager *casemanager.CaseManager,
 	responseProcesser *feedback.ResponseProcesser,
+	enumCoverageTracker *feedback.EnumCoverageTracker,
 	traceManager *trace.TraceManager,
 	callInfoGraph *fuzzruntime.CallInfoGraph,
 	reachabilityMap *fuzzruntime.RuntimeReachabilityMap,
 	testLogReporter *report.TestLogReporter,
-) *BasicFuzzer {
-	httpClientMiddles := make([]http.HTTPClientMiddleware, 0)
-	if config.GlobalConfig.HTTPMiddlewareScriptPath != "" {
-		middleware := http.NewHTTPClientScriptMiddleware(config.GlobalConfig.HTTPMiddlewareScriptPath)
-		if middleware != nil {
-			httpClientMiddles = append(httpClientMiddles, middleware)
+	httpCaptureBuffer *http.HTTPCaptureBuffer,
+	harRecorder *http.HARRecorder,
+	methodProber *feedback.MethodProber,
+	bolaOracle *feedback.BOLAOracle,
+	scenarioMinimizer *feedback.ScenarioMinimizer,
+	idempotencyOracle *feedback.IdempotencyOracle,
+	codeCoverageTracker *feedback.CodeCoverageTracker,
+	differentialOracle *feedback.DifferentialOracle,
+	errorBudget *feedback.ErrorBudget,
+	endpointPerformanceTracker *feedback.EndpointPerformanceTracker,
+	createdResourceTracker *feedback.CreatedResourceTracker,
+) (*BasicFuzzer, error) {
+	// Created resources are tracked from Location headers of responses.
+	headersToCapture := []string{config.GlobalConfig.TraceIDHeaderKey, feedback.LocationHeaderKey}
+	// Method probing checks allowed methods in OPTIONS responses.
+	if methodProber != nil {
+		headersToCapture = append(headersToCapture, feedback.AllowHeaderKey)
+	}
+	httpClient, err := newServerHTTPClient(config.GlobalConfig.ServerBaseURL, headersToCapture)
+	if err != nil {
+		return nil, err
+	}
+	httpClient.CaptureBuffer = httpCaptureBuffer
+	httpClient.HARRecorder = harRecorder
+	httpClient.Politeness = newHTTPClientPoliteness()
+	// Servers rotated among are of the same API, sharing the capture buffer and HAR recorder, but with their own limits.
+	var serverHTTPClients []*http.HTTPClient
+	if len(APIManager.ServerURLs) > 1 {
+		serverHTTPClients = []*http.HTTPClient{httpClient}
+		for _, serverURL := range APIManager.ServerURLs[1:] {
+			serverHTTPClient, err := newServerHTTPClient(serverURL, headersToCapture)
+			if err != nil {
+				return nil, err
+			}
+			serverHTTPClient.CaptureBuffer = httpCaptureBuffer
+			serverHTTPClient.HARRecorder = harRecorder
+			serverHTTPClient.Politeness = newHTTPClientPoliteness()
+			serverHTTPClients = append(serverHTTPClients, serverHTTPClient)
+		}
+		log.Info().Msgf("[BasicFuzzer.NewBasicFuzzer] Rotate among %d servers: %v", len(serverHTTPClients), APIManager.ServerURLs)
+	}
+	// Each additional target is another gateway with its own limits, sharing the capture buffer and HAR recorder.
+	targetHTTPClients := make(map[string]*http.HTTPClient)
+	for _, target := range APIManager.APITargets {
+		targetHTTPClient, err := newServerHTTPClient(target.ServerBaseURL, headersToCapture)
+		if err != nil {
+			return nil, err
+		}
+		targetHTTPClient.CaptureBuffer = httpCaptureBuffer
+		targetHTTPClient.HARRecorder = harRecorder
+		targetHTTPClient.Politeness = newHTTPClientPoliteness()
+		targetHTTPClients[target.Name] = targetHTTPClient
+	}
+	// In differential fuzzing, requests are also sent to the candidate version, which is another server with its own limits.
+	var candidateHTTPClient *http.HTTPClient
+	if config.GlobalConfig.DifferentialServerBaseURL != "" {
+		candidateHTTPClient, err = newServerHTTPClient(config.GlobalConfig.DifferentialServerBaseURL, headersToCapture)
+		if err != nil {
+			return nil, err
+		}
+		candidateHTTPClient.Politeness = newHTTPClientPoliteness()
+		candidateHTTPClient.HARRecorder = harRecorder
+	}
+	// gRPC operations are executed natively only if their descriptors are loaded, see [static.APIManager.GRPCDescriptors].
+	var grpcClient *grpc.GRPCClient
+	if APIManager.GRPCDescriptors != nil {
+		grpcServerBaseURL := config.GlobalConfig.GrpcServerBaseURL
+		if grpcServerBaseURL == "" {
+			grpcServerBaseURL = config.GlobalConfig.ServerBaseURL
+		}
+		grpcClient, err = grpc.NewGRPCClient(
+			grpcServerBaseURL,
+			APIManager.GRPCDescriptors,
+			headersToCapture,
+			http.TransportConfig{
+				ProxyURL:   config.GlobalConfig.HTTPClientProxyURL,
+				CAFilePath: config.GlobalConfig.HTTPClientCaFilePath,
+			},
+			time.Duration(config.GlobalConfig.HTTPClientDialTimeout)*time.Second,
+		)
+		if err != nil {
+			log.Err(err).Msg("[BasicFuzzer.NewBasicFuzzer] Failed to create gRPC client")
+			return nil, err
+		}
+		// Requests of both clients count towards the same limits, and go through the same middlewares and capture path.
+		grpcClient.Politeness = httpClient.Politeness
+		grpcClient.Middlewares = httpClient.Middlewares
+		grpcClient.CaptureBuffer = httpCaptureBuffer
+		grpcClient.HARRecorder = harRecorder
+		grpcClient.InjectTraceContext = config.GlobalConfig.InjectTraceContext
+	}
+	// Message-producing operations are from the AsyncAPI spec, and published through the message broker.
+	var messagePublisher messaging.MessagePublisher
+	if config.GlobalConfig.MessagingBrokerType != "" {
+		messagePublisher, err = messaging.NewMessagePublisher(
+			config.GlobalConfig.MessagingBrokerType,
+			config.GlobalConfig.MessagingBrokerURL,
+			http.TransportConfig{
+				ProxyURL:   config.GlobalConfig.HTTPClientProxyURL,
+				CAFilePath: config.GlobalConfig.HTTPClientCaFilePath,
+			},
+			time.Duration(config.GlobalConfig.HTTPClientDialTimeout)*time.Second,
+			// Messages published count towards the same limits as requests.
+			httpClient.Politeness,
+		)
+		if err != nil {
+			log.Err(err).Msg("[BasicFuzzer.NewBasicFuzzer] Failed to create message publisher, message-producing operations will fail")
+		}
+	}
+	// Explicitly configured chaos experiments and scenario hooks should not be silently disabled, so the run is aborted if they are broken.
+	chaosCoordinator, err := NewChaosCoordinator(config.GlobalConfig.ChaosExperiments, APIManager)
+	if err != nil {
+		log.Err(err).Msg("[BasicFuzzer.NewBasicFuzzer] Failed to create chaos coordinator")
+		return nil, err
+	}
+	var scenarioHookRunner *ScenarioHookRunner
+	if config.GlobalConfig.ScenarioHookScriptPath != "" {
+		scenarioHookRunner, err = NewScenarioHookRunner(config.GlobalConfig.ScenarioHookScriptPath, httpClient)
+		if err != nil {
+			log.Err(err).Msg("[BasicFuzzer.NewBasicFuzzer] Failed to load scenario hook script")
+			return nil, err
 		}
 	}
-	httpClient := http.NewHTTPClient(
-		config.GlobalConfig.ServerBaseURL,
-		[]string{config.GlobalConfig.TraceIDHeaderKey},
-		httpClientMiddles,
-		hertzclient.WithDialTimeout(time.Duration(config.GlobalConfig.HTTPClientDialTimeout) * time.Second),
-	)
 	fuzzingSnapshot := NewFuzzingSnapshot()
 
 	// If budget is not positive, no fuzzing will be performed.
@@ -80,26 +283,167 @@ func NewBasicFuzzer(
 		log.Warn().Msg("[BasicFuzzer.NewBasicFuzzer] Fuzzer budget is not positive, no fuzzing will be performed")
 	}
 	
-	return &BasicFuzzer{
-		APIManager:        APIManager,
-		CaseManager:       caseManager,
-		ResponseProcesser: responseProcesser,
-		TraceManager:      traceManager,
-		Budget:            time.Duration(config.GlobalConfig.FuzzerBudget) * time.Second, // Convert seconds to nanoseconds.
-		HTTPClient:        httpClient,
-		CallInfoGraph:     callInfoGraph,
-		ReachabilityMap:   reachabilityMap,
-		FuzzingSnapshot:   fuzzingSnapshot,
-		TestLogReporter:   testLogReporter,
+	f := &BasicFuzzer{
+		APIManager:                 APIManager,
+		CaseManager:                caseManager,
+		ResponseProcesser:          responseProcesser,
+		EnumCoverageTracker:        enumCoverageTracker,
+		TraceManager:               traceManager,
+		Budget:                     time.Duration(config.GlobalConfig.FuzzerBudget) * time.Second, // Convert seconds to nanoseconds.
+		HTTPClient:                 httpClient,
+		ServerHTTPClients:          serverHTTPClients,
+		TargetHTTPClients:          targetHTTPClients,
+		GRPCClient:                 grpcClient,
+		MessagePublisher:           messagePublisher,
+		CallInfoGraph:              callInfoGraph,
+		ReachabilityMap:            reachabilityMap,
+		SpanErrorCoverage:          fuzzruntime.NewSpanErrorCoverage(),
+		DatabaseCoverage:           fuzzruntime.NewDatabaseCoverage(),
+		FuzzingSnapshot:            fuzzingSnapshot,
+		TestLogReporter:            testLogReporter,
+		MemoryWatchpoint:           NewMemoryWatchpoint(config.GlobalConfig.MemoryCompactionThreshold),
+		MethodProber:               methodProber,
+		BOLAOracle:                 bolaOracle,
+		ScenarioMinimizer:          scenarioMinimizer,
+		IdempotencyOracle:          idempotencyOracle,
+		CodeCoverageTracker:        codeCoverageTracker,
+		CandidateHTTPClient:        candidateHTTPClient,
+		DifferentialOracle:         differentialOracle,
+		ChaosCoordinator:           chaosCoordinator,
+		ScenarioHookRunner:         scenarioHookRunner,
+		ErrorBudget:                errorBudget,
+		Progress:                   NewProgressTracker(),
+		EndpointPerformanceTracker: endpointPerformanceTracker,
+		CreatedResourceTracker:     createdResourceTracker,
+	}
+	// Created resources are tracked per server, so that they are deleted on the server they are created on, see [BasicFuzzer.cleanupCreatedResources].
+	if createdResourceTracker != nil {
+		createdResourceTracker.ServerBaseURLOf = func(method static.SimpleAPIMethod) string {
+			return f.getHTTPClient(method).BaseURL
+		}
 	}
+	return f, nil
+}
+
+// newHTTPClientMiddlewares creates the configured middlewares of HTTP clients of the server at the base URL,
+// i.e., the script middleware, the OAuth2 auth manager and the request signer, in order.
+// It returns an error if the auth manager or the request signer fails to be created, as requests should not be sent without configured credentials.
+func newHTTPClientMiddlewares(baseURL string) ([]http.HTTPClientMiddleware, error) {
+	httpClientMiddles := make([]http.HTTPClientMiddleware, 0)
+	if config.GlobalConfig.HTTPMiddlewareScriptPath != "" {
+		middleware := http.NewHTTPClientScriptMiddleware(config.GlobalConfig.HTTPMiddlewareScriptPath)
+		if middleware != nil {
+			httpClientMiddles = append(httpClientMiddles, middleware)
+		}
+	}
+	if config.GlobalConfig.Oauth2GrantType != "" {
+		authManager, err := http.NewAuthManager(http.OAuth2Config{
+			GrantType:    config.GlobalConfig.Oauth2GrantType,
+			TokenURL:     config.GlobalConfig.Oauth2TokenURL,
+			ClientID:     config.GlobalConfig.Oauth2ClientID,
+			ClientSecret: config.GlobalConfig.Oauth2ClientSecret,
+			Username:     config.GlobalConfig.Oauth2Username,
+			Password:     config.GlobalConfig.Oauth2Password,
+			Scope:        config.GlobalConfig.Oauth2Scope,
+		})
+		if err != nil {
+			log.Err(err).Msg("[newHTTPClientMiddlewares] Failed to create auth manager")
+			return nil, err
+		}
+		httpClientMiddles = append(httpClientMiddles, authManager)
+	}
+	// Requests are signed after all other mutations, so the signer is the last middleware.
+	if config.GlobalConfig.RequestSigningType != "" {
+		requestSigner, err := http.NewRequestSigner(http.RequestSigningConfig{
+			Type:            config.GlobalConfig.RequestSigningType,
+			BaseURL:         baseURL,
+			AccessKeyID:     config.GlobalConfig.RequestSigningAccessKeyID,
+			SecretAccessKey: config.GlobalConfig.RequestSigningSecretAccessKey,
+			SessionToken:    config.GlobalConfig.RequestSigningSessionToken,
+			Region:          config.GlobalConfig.RequestSigningRegion,
+			Service:         config.GlobalConfig.RequestSigningService,
+			HMACHeader:      config.GlobalConfig.RequestSigningHmacHeader,
+		})
+		if err != nil {
+			log.Err(err).Msg("[newHTTPClientMiddlewares] Failed to create request signer")
+			return nil, err
+		}
+		httpClientMiddles = append(httpClientMiddles, requestSigner)
+	}
+	return httpClientMiddles, nil
+}
+
+// newServerHTTPClient creates the HTTP client of the server at the base URL, with configured middlewares and transport.
+// It returns an error if the transport fails to be configured, e.g., the proxy URL is invalid, as requests should not bypass the configured proxy or certificate verification,
+// or if the middlewares fail to be created, see [newHTTPClientMiddlewares].
+func newServerHTTPClient(baseURL string, headersToCapture []string) (*http.HTTPClient, error) {
+	httpClientMiddles, err := newHTTPClientMiddlewares(baseURL)
+	if err != nil {
+		log.Err(err).Msgf("[newServerHTTPClient] Failed to create HTTP client middlewares of %s", baseURL)
+		return nil, err
+	}
+	hertzClientOpts := []hertzconfig.ClientOption{
+		hertzclient.WithDialTimeout(time.Duration(config.GlobalConfig.HTTPClientDialTimeout) * time.Second),
+	}
+	// Requests slower than the request timeout fail as timeouts, see [feedback.EndpointPerformance].
+	if config.GlobalConfig.HTTPClientRequestTimeout > 0 {
+		requestTimeout := time.Duration(config.GlobalConfig.HTTPClientRequestTimeout) * time.Second
+		hertzClientOpts = append(hertzClientOpts, hertzclient.WithClientReadTimeout(requestTimeout), hertzclient.WithWriteTimeout(requestTimeout))
+	}
+	httpClient, err := http.NewHTTPClientWithTransport(
+		baseURL,
+		headersToCapture,
+		httpClientMiddles,
+		http.TransportConfig{
+			ProxyURL:   config.GlobalConfig.HTTPClientProxyURL,
+			CAFilePath: config.GlobalConfig.HTTPClientCaFilePath,
+		},
+		hertzClientOpts...,
+	)
+	if err != nil {
+		log.Err(err).Msgf("[newServerHTTPClient] Failed to configure HTTP client transport of %s", baseURL)
+		return nil, err
+	}
+	httpClient.InjectTraceContext = config.GlobalConfig.InjectTraceContext
+	return httpClient, nil
+}
+
+// newHTTPClientPoliteness creates the configured politeness of HTTP clients.
+func newHTTPClientPoliteness() *http.HTTPClientPoliteness {
+	return http.NewHTTPClientPoliteness(http.PolitenessConfig{
+		MaxRequestsPerSecond:  config.GlobalConfig.HTTPClientMaxRequestsPerSecond,
+		MaxConcurrentRequests: config.GlobalConfig.HTTPClientMaxConcurrentRequests,
+		BackoffMaxRetries:     config.GlobalConfig.HTTPClientBackoffMaxRetries,
+		BackoffMaxWait:        time.Duration(config.GlobalConfig.HTTPClientBackoffMaxWait) * time.Second,
+	})
 }
 
 // Start starts the fuzzer.
-// The fuzzer will run until the budget is exhausted or some error occurs.
-func (f *BasicFuzzer) Start() error {
+// The fuzzer will run until the budget is exhausted, ctx is done, or some error occurs.
+// Budget expiry is a deadline of ctx, so that in-flight requests and trace fetching are cancelled promptly.
+func (f *BasicFuzzer) Start(ctx context.Context) error {
 
 	startTime := time.Now()
 	log.Info().Msgf("[BasicFuzzer.Start] Fuzzer started at %v, Budget: %v", startTime, f.Budget)
+	ctx, cancel := context.WithTimeout(ctx, f.Budget)
+	defer cancel()
+	f.Progress.Start(startTime, f.Budget)
+
+	if config.GlobalConfig.DryRun {
+		f.dryRun(ctx)
+		log.Info().Msgf("[BasicFuzzer.Start] Dry run finished, populated test scenarios: %d", len(f.TestLogReporter.TestLogReport.TestedScenarios))
+		return nil
+	}
+
+	// Created resources are deleted after fuzzing, even if it is aborted or interrupted.
+	if config.GlobalConfig.CleanupCreatedResources {
+		defer f.cleanupCreatedResources(ctx)
+	}
+
+	// Probe method handling before fuzzing, which also consumes budget.
+	if f.MethodProber != nil {
+		f.MethodProber.Probe(ctx, f.HTTPClient)
+	}
 
 	// loop:
 	// 1. Pop a test scenario from the case manager.
@@ -109,30 +453,187 @@ func (f *BasicFuzzer) Start() error {
 	//   c. Process the response.
 	// 3. Analyse the result, generate a report, and update the case manager.
 	// 4. Go to step 1.
-	for time.Since(startTime) <= f.Budget {
-		testScenario, err := f.CaseManager.PopAndPopulate()
+	lastReportTime := startTime
+	for ctx.Err() == nil {
+		testScenario, err := f.CaseManager.PopAndPopulate(ctx)
+		if ctx.Err() != nil {
+			break
+		}
 		if err != nil {
 			log.Err(err).Msg("[BasicFuzzer.Start] Failed to pop a test scenario")
 			break
 		}
 
-		err = f.ExecuteTestScenario(testScenario)
+		f.rotateServer()
+		f.Progress.StartScenario(testScenario)
+		err = f.executeTestScenarioWithHooks(ctx, testScenario)
+		if ctx.Err() != nil {
+			break
+		}
 		if err != nil {
 			log.Err(err).Msg("[BasicFuzzer.Start] Failed to execute the test scenario")
 			break
 		}
+		// A run violating the error budget is aborted, as its reports are worthless.
+		if f.ErrorBudget != nil {
+			if err := f.ErrorBudget.CheckExceeded(); err != nil {
+				log.Err(err).Msgf("[BasicFuzzer.Start] Abort fuzzing, requests of kinds in error budget: %v", f.ErrorBudget.GetSortedKindCounts())
+				return err
+			}
+		}
+
+		// Stale energy of cases interesting long ago decays, if enabled.
+		f.CaseManager.DecayEnergy()
+
+		if f.MemoryWatchpoint.ShouldCompact() {
+			f.compact()
+		}
+
+		// Intermediate reports are written periodically, so that the work done is not lost if the process is killed.
+		if f.ReportFunc != nil && f.ReportInterval > 0 && time.Since(lastReportTime) >= f.ReportInterval {
+			f.ReportFunc()
+			lastReportTime = time.Now()
+		}
 
 		log.Info().Msgf("[BasicFuzzer.Start] A loop iteration finished, current consumed time: %v, budget: %v, scenario to be executed: %d", time.Since(startTime), f.Budget, f.CaseManager.GetScenarioSize())
 	}
 
-	log.Info().Msg("[BasicFuzzer.Start] Fuzzer stopped")
+	log.Info().Msgf("[BasicFuzzer.Start] Fuzzer stopped, reason: %v", context.Cause(ctx))
+	if degradedCount := f.TraceManager.FetchBreaker.GetDegradedCount(); degradedCount > 0 {
+		log.Warn().Msgf("[BasicFuzzer.Start] Trace backend was unavailable during fuzzing, switched to degradation mode %d times, and coverage may be underestimated", degradedCount)
+	}
+	traceWaitStats := f.TraceManager.WaitEstimator.GetStats()
+	log.Info().Msgf("[BasicFuzzer.Start] Traces of %s became queryable after p50 %.0fms, p90 %.0fms, max %.0fms (%d observed, %d missing, %d retries, retry budget exhausted %d times), final wait: %.0fms", traceWaitStats.Backend, traceWaitStats.P50Ms, traceWaitStats.P90Ms, traceWaitStats.MaxMs, traceWaitStats.ObservedCount, traceWaitStats.MissingCount, traceWaitStats.RetryCount, traceWaitStats.RetryBudgetExhaustedCount, traceWaitStats.FinalWaitMs)
 	return nil
 }
 
+// executeTestScenarioWithHooks executes the test scenario (see [BasicFuzzer.executeTestScenarioWithChaos]) between the scenario hooks of ScenarioHookRunner, if any.
+// Failures of hooks are logged, and do not stop the scenario.
+// If cookie jar is enabled in config, requests of the scenario (including those of hooks) share a new cookie jar, see [http.ContextWithCookieJar].
+func (f *BasicFuzzer) executeTestScenarioWithHooks(ctx context.Context, testScenario *casemanager.TestScenario) error {
+	if config.GlobalConfig.EnableCookieJar {
+		ctx = http.ContextWithCookieJar(ctx)
+	}
+	if f.ScenarioHookRunner == nil {
+		return f.executeTestScenarioWithChaos(ctx, testScenario)
+	}
+	if err := f.ScenarioHookRunner.BeforeScenario(ctx, testScenario); err != nil {
+		log.Err(err).Msgf("[BasicFuzzer.executeTestScenarioWithHooks] Failed to run hook before test scenario (UUID: %s)", testScenario.UUID.String())
+	}
+	defer func() {
+		if err := f.ScenarioHookRunner.AfterScenario(ctx, testScenario); err != nil {
+			log.Err(err).Msgf("[BasicFuzzer.executeTestScenarioWithHooks] Failed to run hook after test scenario (UUID: %s)", testScenario.UUID.String())
+		}
+	}()
+	return f.executeTestScenarioWithChaos(ctx, testScenario)
+}
+
+// executeTestScenarioWithChaos executes the test scenario, under the chaos experiment selected by ChaosCoordinator if any.
+// If the experiment fails to start, the scenario is executed without it.
+func (f *BasicFuzzer) executeTestScenarioWithChaos(ctx context.Context, testScenario *casemanager.TestScenario) error {
+	// The tag is of the current execution only, see [casemanager.TestScenario.ChaosExperiment].
+	testScenario.ChaosExperiment = ""
+	experiment := f.ChaosCoordinator.SelectExperiment(testScenario)
+	if experiment == nil {
+		return f.ExecuteTestScenario(ctx, testScenario)
+	}
+	if err := f.ChaosCoordinator.StartExperiment(ctx, experiment, testScenario); err != nil {
+		log.Err(err).Msgf("[BasicFuzzer.executeTestScenarioWithChaos] Failed to start chaos experiment %s, execute test scenario (UUID: %s) without it", experiment.Name, testScenario.UUID.String())
+		return f.ExecuteTestScenario(ctx, testScenario)
+	}
+	defer func() {
+		if err := f.ChaosCoordinator.StopExperiment(ctx, experiment, testScenario); err != nil {
+			log.Err(err).Msgf("[BasicFuzzer.executeTestScenarioWithChaos] Failed to stop chaos experiment %s", experiment.Name)
+		}
+	}()
+	return f.ExecuteTestScenario(contextWithChaosExperiment(ctx, experiment.Name), testScenario)
+}
+
+// dryRun pops and populates test scenarios until the queue is empty, budget is exhausted, or ctx is done,
+// and records the fully resolved requests in the test log report, without sending them.
+// Scenarios are not evaluated or extended, as there is no response.
+func (f *BasicFuzzer) dryRun(ctx context.Context) {
+	for ctx.Err() == nil && f.CaseManager.GetScenarioSize() > 0 {
+		testScenario, err := f.CaseManager.PopAndPopulate(ctx)
+		if ctx.Err() != nil {
+			break
+		}
+		if err != nil {
+			log.Err(err).Msg("[BasicFuzzer.dryRun] Failed to pop a test scenario")
+			break
+		}
+		f.rotateServer()
+		f.TestLogReporter.LogDryRunScenario(testScenario, func(method static.SimpleAPIMethod) string {
+			return f.getHTTPClient(method).BaseURL
+		})
+		for _, operationCase := range testScenario.OperationCases {
+			requestURL := http.BuildRequestURL(f.getHTTPClient(operationCase.APIMethod).BaseURL, operationCase.APIMethod.Endpoint, operationCase.RequestPathParams, operationCase.RequestQueryParams)
+			// Credentials are not logged, as logs may be shared.
+			log.Debug().Msgf("[BasicFuzzer.dryRun] Scenario (UUID: %s): %s %s, headers: %v, body: %s", testScenario.UUID.String(), operationCase.APIMethod.Method, requestURL, http.MaskCredentialHeaders(operationCase.RequestHeaders), string(operationCase.RequestBody))
+		}
+	}
+}
+
+// cleanupCreatedResources deletes resources created during fuzzing and not deleted yet (see [feedback.CreatedResourceTracker]),
+// in the reverse order of creation, to restore the system under test.
+// The cleanup is not part of the budget, so it runs even if ctx is done, but stops after the cleanup timeout.
+// Deletions are not processed as normal operation cases, i.e., they do not affect coverage or case queues.
+// If servers are rotated, each resource is deleted on the server it is created on.
+func (f *BasicFuzzer) cleanupCreatedResources(ctx context.Context) {
+	createdResources := f.CreatedResourceTracker.GetCreatedResources()
+	if len(createdResources) == 0 {
+		return
+	}
+	log.Info().Msgf("[BasicFuzzer.cleanupCreatedResources] Clean up %d created resources", len(createdResources))
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), time.Duration(config.GlobalConfig.CleanupTimeout)*time.Second)
+	defer cancel()
+	removedCount := 0
+	for i, createdResource := range createdResources {
+		if ctx.Err() != nil {
+			log.Warn().Msgf("[BasicFuzzer.cleanupCreatedResources] Cleanup timed out, %d created resources are not tried", len(createdResources)-i)
+			break
+		}
+		operationCase, err := f.CaseManager.NewDeletionOperationCase(createdResource.Deleter, createdResource.PathParams)
+		if err != nil {
+			log.Err(err).Msgf("[BasicFuzzer.cleanupCreatedResources] Failed to create operation case deleting resource %s", createdResource.ResourcePath)
+			continue
+		}
+		f.switchServer(createdResource.ServerBaseURL)
+		err = f.ExecuteCaseOperation(ctx, operationCase)
+		if err != nil {
+			log.Err(err).Msgf("[BasicFuzzer.cleanupCreatedResources] Failed to delete resource %s", createdResource.ResourcePath)
+			continue
+		}
+		result := f.CreatedResourceTracker.RecordCleanup(createdResource, operationCase.ResponseStatusCode)
+		if !result.Removed {
+			log.Warn().Msgf("[BasicFuzzer.cleanupCreatedResources] Failed to delete resource %s created by %s %s, status code: %d", createdResource.ResourcePath, createdResource.Creator.Method, createdResource.Creator.Endpoint, operationCase.ResponseStatusCode)
+			continue
+		}
+		removedCount++
+		// Resources of the deleted entity are removed from the pool, so that they are not saved to the knowledge base.
+		if config.GlobalConfig.InvalidateDeletedResources {
+			f.ResponseProcesser.ProcessDeletion(operationCase.APIMethod, operationCase.ResponseStatusCode, operationCase.RequestPathParams)
+		}
+	}
+	log.Info().Msgf("[BasicFuzzer.cleanupCreatedResources] Cleanup finished, %d of %d created resources are deleted", removedCount, len(createdResources))
+}
+
+// compact compacts the corpus to reduce memory usage:
+// it evicts low-energy test scenarios and operation cases, downsamples the resource pool, and drops old traces.
+// Coverage information (e.g., call info graph) is kept, as it is small and needed by reports.
+func (f *BasicFuzzer) compact() {
+	evictedScenarioCnt, evictedOperationCaseCnt := f.CaseManager.EvictLowEnergyCases(memoryCompactionRatio)
+	removedResourceCnt := f.CaseManager.ResourceManager.Downsample(memoryCompactionRatio)
+	droppedTraceCnt := f.TraceManager.DropOldTraces(memoryCompactionRatio)
+	// Return freed memory to OS as soon as possible.
+	debug.FreeOSMemory()
+	log.Info().Msgf("[BasicFuzzer.compact] Corpus compacted, evicted scenarios: %d, evicted operation cases: %d, removed resources: %d, dropped traces: %d", evictedScenarioCnt, evictedOperationCaseCnt, removedResourceCnt, droppedTraceCnt)
+}
+
 // ExecuteTestScenario executes a test scenario (a sequence of operation cases).
 // This method makes HTTP calls, processes the response, and updates the runtime call info graph.
 // If the analysers conclude that the test scenario or its test operation cases are interesting, the case manager will be updated (e.g., mutate the test scenario and add it back to queue).
-func (f *BasicFuzzer) ExecuteTestScenario(testScenario *casemanager.TestScenario) error {
+func (f *BasicFuzzer) ExecuteTestScenario(ctx context.Context, testScenario *casemanager.TestScenario) error {
 	var hasScenarioAchieveNewCoverage bool
 	operationCasesToBeExecuted := make([]*casemanager.OperationCase, len(testScenario.OperationCases))
 	copy(operationCasesToBeExecuted, testScenario.OperationCases)
@@ -141,16 +642,53 @@ func (f *BasicFuzzer) ExecuteTestScenario(testScenario *casemanager.TestScenario
 	if config.GlobalConfig.ExecuteLastCaseInScenarioOnly {
 		operationCasesToBeExecuted = operationCasesToBeExecuted[len(operationCasesToBeExecuted)-1:]
 	}
+	// Results of executed operation cases, which would be evaluated as a whole after the scenario is executed.
+	operationResults := make([]*feedback.OperationResult, 0, len(operationCasesToBeExecuted))
+	// Traces being fetched in the background, if traces are fetched concurrently, see [trace.TraceFetchPool].
+	pendingTraces := make([]*pendingOperationTrace, 0)
+	if f.TraceManager.FetchPool != nil {
+		// Wait for traces still being fetched on return (e.g., when the scenario is stopped by an error), so that trace DBs are not upserted afterward.
+		defer f.TraceManager.FetchPool.Wait()
+	}
+	f.BOLAOracle.BeginScenario()
+	f.DifferentialOracle.BeginScenario()
 	for _, operationCase := range operationCasesToBeExecuted {
+		// Stop the scenario if ctx is done, the remaining operation cases would not be executed.
+		if ctx.Err() != nil {
+			log.Warn().Msgf("[BasicFuzzer.ExecuteTestScenario] Context done, stop executing test scenario (UUID: %s)", testScenario.UUID.String())
+			return ctx.Err()
+		}
+		// Bind values in responses of producers executed before, e.g., the id of the created resource.
+		if config.GlobalConfig.BindProducerValues {
+			if boundCount := f.CaseManager.BindProducerValues(testScenario, operationCase); boundCount > 0 {
+				log.Debug().Msgf("[BasicFuzzer.ExecuteTestScenario] Bound %d params of operation %v from responses of producers", boundCount, operationCase.APIMethod)
+			}
+		}
+		// Before accessing a resource created in the scenario, try accessing it as other users.
+		f.replayAsOtherUsers(ctx, testScenario, operationCase)
 		// If error occurs during execution of the operation case, stop the whole test scenario.
 		// Otherwise, continue to the next operation case.
-		err := f.ExecuteCaseOperation(operationCase)
+		err := f.ExecuteCaseOperation(ctx, operationCase)
 		if err != nil {
 			log.Err(err).Msg("[BasicFuzzer.ExecuteTestScenario] Failed to execute operation")
 			return err
 		}
+		f.BOLAOracle.RecordOperationCase(operationCase)
+		// Server errors under chaos experiments are induced by the fuzzer itself.
+		if testScenario.ChaosExperiment == "" && operationCase.ResponseStatusCode >= 500 {
+			f.Progress.RecordPrimaryServerError()
+		}
+		// Send the same request to the candidate version, which should respond the same.
+		f.executeOnCandidate(ctx, testScenario, operationCase)
+		// Replay the request with the same idempotency key, which should get the same response.
+		f.replayWithIdempotencyKey(ctx, testScenario, operationCase)
+		operationResult := &feedback.OperationResult{
+			OperationCase: operationCase,
+		}
+		operationResults = append(operationResults, operationResult)
 		statusCode := operationCase.ResponseStatusCode
 		responseBody := operationCase.ResponseBody
+		f.EnumCoverageTracker.RecordRequest(operationCase.APIMethod, operationCase.RequestPathParams, operationCase.RequestQueryParams, operationCase.RequestBody)
 
 		// Process the response.
 		// This phase would check the response status code and response body.
@@ -161,56 +699,84 @@ func (f *BasicFuzzer) ExecuteTestScenario(testScenario *casemanager.TestScenario
 			log.Err(err).Msg("[BasicFuzzer.ExecuteTestScenario] Failed to process response")
 			continue // continue to the next operation case instead of stopping the fuzzing process
 		}
+		// Resources of entities deleted are removed from the pool, as they are known to be gone.
+		if config.GlobalConfig.InvalidateDeletedResources {
+			f.ResponseProcesser.ProcessDeletion(operationCase.APIMethod, statusCode, operationCase.RequestPathParams)
+		}
 
 		// fetch the trace from the service, parse it, and update local runtime call info graph.
 		traceID, exist := operationCase.ResponseHeaders[config.GlobalConfig.TraceIDHeaderKey]
 		if !exist || traceID == "" {
-			log.Warn().Msg("[BasicFuzzer.ExecuteTestScenario] No trace ID found in the response headers")
-			continue
+			// The trace started by the client (see [BasicFuzzer.ExecuteCaseOperation]) is used, if the trace ID is not returned.
+			traceID = operationCase.TraceID
+			exist = traceID != ""
 		}
-		newTrace, err := f.TraceManager.PullTraceByIDAndReturn(traceID)
-		if err != nil {
-			log.Err(err).Msg("[BasicFuzzer.ExecuteTestScenario] Failed to pull traces")
-			continue
+		if !exist || traceID == "" {
+			// Otherwise, the trace started by the traceparent or B3 header of the request (see [casemanager.GetClientTraceID]) is used, if any.
+			traceID, exist = casemanager.GetClientTraceID(operationCase.RequestHeaders)
 		}
-		// During the conversion, spans of kind 'internal' would be ignored, as we only care about the calls between services.
-		callInfoList, err := f.TraceManager.BatchConvertTrace2CallInfos([]*trace.SimplifiedTrace{newTrace})
-		if err != nil {
-			log.Err(err).Msg("[BasicFuzzer.ExecuteTestScenario] Failed to get call infos")
+		if (!exist || traceID == "") && config.GlobalConfig.TraceQueryHeaderKey != "" {
+			// Otherwise, the trace is queried by the header unique to the request, if any.
+			if headerValue := http.GetHeaderValue(operationCase.RequestHeaders, config.GlobalConfig.TraceQueryHeaderKey); headerValue != "" {
+				newTrace, callInfoList, err := f.TraceManager.PullTraceByQueryAndConvert(ctx, trace.NewRequestHeaderTraceQuery(config.GlobalConfig.TraceQueryHeaderKey, headerValue))
+				if newTrace != nil {
+					operationCase.TraceID = newTrace.TraceID
+				}
+				hasOperationAchieveNewCoverage, err := f.applyOperationTrace(operationCase, operationResult, newTrace, callInfoList, err)
+				if err != nil {
+					return err
+				}
+				hasScenarioAchieveNewCoverage = hasScenarioAchieveNewCoverage || hasOperationAchieveNewCoverage
+				continue
+			}
+		}
+		if !exist || traceID == "" {
+			log.Warn().Msg("[BasicFuzzer.ExecuteTestScenario] No trace ID found in the response headers")
 			continue
 		}
-
-		// Update runtime info, including call info graph and reachability map.
-		err = f.CallInfoGraph.UpdateFromCallInfos(callInfoList)
-		if err != nil {
-			log.Err(err).Msg("[BasicFuzzer.ExecuteTestScenario] Failed to update runtime call info graph")
+		operationCase.TraceID = traceID
+		// Traces fetched in the background are applied after all operation cases are executed.
+		if f.TraceManager.FetchPool != nil {
+			pendingTraces = append(pendingTraces, &pendingOperationTrace{
+				operationCase:   operationCase,
+				operationResult: operationResult,
+				resultChan:      f.TraceManager.FetchPool.Submit(ctx, traceID),
+			})
 			continue
 		}
-		err = f.ReachabilityMap.UpdateFromCallInfos(operationCase.APIMethod, callInfoList)
+		newTrace, callInfoList, err := f.TraceManager.PullTraceByIDAndConvert(ctx, traceID)
+		hasOperationAchieveNewCoverage, err := f.applyOperationTrace(operationCase, operationResult, newTrace, callInfoList, err)
 		if err != nil {
-			log.Err(err).Msg("[BasicFuzzer.ExecuteTestScenario] Failed to update reachability map")
-			continue
+			return err
 		}
-
-		log.Info().Msg("[BasicFuzzer.ExecuteTestScenario] Operation executed successfully")
-
-		hasOperationAchieveNewCoverage := f.FuzzingSnapshot.Update(
-			f.CallInfoGraph.GetEdgeCoveredCount(),
-			f.ResponseProcesser.GetCoveredStatusCodeCount(),
-		)
 		hasScenarioAchieveNewCoverage = hasScenarioAchieveNewCoverage || hasOperationAchieveNewCoverage
-
-		// Pass the operation and the its execution result back to the case manager,
-		// and:
-		//  1. decide whether its operation cases are interesting or not (i.e., update their energy)
-		//  2. may mutate the operation cases and add them to the operation case queue.
-		err = f.CaseManager.EvaluateOperationCaseAndTryUpdate(hasOperationAchieveNewCoverage, operationCase)
+	}
+	// Apply traces fetched in the background, in the order of operation cases.
+	for _, pendingTrace := range pendingTraces {
+		result := <-pendingTrace.resultChan
+		hasOperationAchieveNewCoverage, err := f.applyOperationTrace(pendingTrace.operationCase, pendingTrace.operationResult, result.Trace, result.CallInfos, result.Err)
 		if err != nil {
-			log.Err(err).Msg("[BasicFuzzer.ExecuteTestScenario] Failed to evaluate operation and try update")
 			return err
 		}
+		hasScenarioAchieveNewCoverage = hasScenarioAchieveNewCoverage || hasOperationAchieveNewCoverage
+	}
+
+	// Evaluate the whole scenario, e.g., check resource lifecycle invariants by CRUD oracle, and update scenario-level coverage.
+	// Findings are recorded in the evaluators, and would be reported after fuzzing.
+	hasNewStatusSequence := f.ResponseProcesser.ProcessScenario(testScenario, operationResults)
+	log.Debug().Msgf("[BasicFuzzer.ExecuteTestScenario] Covered status sequence count: %d, hasNewStatusSequence: %v", f.ResponseProcesser.GetCoveredStatusSequenceCount(), hasNewStatusSequence)
+
+	// Code coverage is cumulative per service process, so it is polled once after the scenario, and new coverage is attributed to the whole scenario.
+	if f.CodeCoverageTracker != nil {
+		codeCoverage := f.CodeCoverageTracker.Collect(ctx)
+		hasNewCodeCoverage := f.FuzzingSnapshot.UpdateCodeCoverage(codeCoverage.CoveredLines, codeCoverage.CoveredBranches)
+		log.Debug().Msgf("[BasicFuzzer.ExecuteTestScenario] Covered lines: %d/%d, covered branches: %d/%d, hasNewCodeCoverage: %v", codeCoverage.CoveredLines, codeCoverage.TotalLines, codeCoverage.CoveredBranches, codeCoverage.TotalBranches, hasNewCodeCoverage)
+		hasScenarioAchieveNewCoverage = hasScenarioAchieveNewCoverage || hasNewCodeCoverage
 	}
 
+	// If the scenario triggers a new server error, find the shortest scenario reproducing it.
+	f.minimizeFailure(ctx, testScenario)
+
 	log.Info().Msgf("[BasicFuzzer.ExecuteTestScenario] Finish execute current test scenario (UUID: %s), Edge covered count: %d, Edge coverage: %f, covered status code count: %d, hasScenarioAchieveNewCoverage: %v", testScenario.UUID.String(), f.CallInfoGraph.GetEdgeCoveredCount(), f.CallInfoGraph.GetEdgeCoverage(), f.ResponseProcesser.GetCoveredStatusCodeCount(), hasScenarioAchieveNewCoverage)
 
 	// Pass the scenario and the result back to the case manager,
@@ -229,9 +795,167 @@ func (f *BasicFuzzer) ExecuteTestScenario(testScenario *casemanager.TestScenario
 	return nil
 }
 
+// pendingOperationTrace is a trace of an executed operation case being fetched in the background.
+type pendingOperationTrace struct {
+	// operationCase is the executed operation case.
+	operationCase *casemanager.OperationCase
+
+	// operationResult is the result of the operation case, which the trace and call infos are filled into.
+	operationResult *feedback.OperationResult
+
+	// resultChan receives the result of fetching the trace.
+	resultChan <-chan *trace.TraceFetchResult
+}
+
+// applyOperationTrace applies the fetched trace of the executed operation case (with call infos converted from it) to runtime info and coverage,
+// and passes the operation case back to the case manager. fetchErr is the error of fetching the trace, if any.
+// In degradation mode (i.e., fetchErr is [trace.ErrTraceFetchDegraded]), coverage is collected from status codes only.
+// It returns whether the operation case achieves new coverage. The operation case is skipped if the trace is failed to fetch or apply.
+func (f *BasicFuzzer) applyOperationTrace(operationCase *casemanager.OperationCase, operationResult *feedback.OperationResult, newTrace *trace.SimplifiedTrace, callInfoList []*trace.CallInfo, fetchErr error) (bool, error) {
+	if fetchErr != nil && !errors.Is(fetchErr, trace.ErrTraceFetchDegraded) {
+		log.Err(fetchErr).Msg("[BasicFuzzer.applyOperationTrace] Failed to pull traces")
+		return false, nil
+	}
+	if fetchErr == nil {
+		operationResult.Trace = newTrace
+		operationResult.CallInfos = callInfoList
+
+		// Update runtime info, including call info graph and reachability map.
+		err := f.CallInfoGraph.UpdateFromCallInfos(callInfoList)
+		if err != nil {
+			log.Err(err).Msg("[BasicFuzzer.applyOperationTrace] Failed to update runtime call info graph")
+			return false, nil
+		}
+		err = f.ReachabilityMap.UpdateFromCallInfos(operationCase.APIMethod, callInfoList)
+		if err != nil {
+			log.Err(err).Msg("[BasicFuzzer.applyOperationTrace] Failed to update reachability map")
+			return false, nil
+		}
+		// Errors of internal services may be swallowed by upstream ones, e.g., responded with 200, so they are collected from spans.
+		if newSpanErrorCount := f.SpanErrorCoverage.UpdateFromTrace(newTrace); newSpanErrorCount > 0 {
+			log.Info().Msgf("[BasicFuzzer.applyOperationTrace] Found %d new span errors in trace %s", newSpanErrorCount, operationCase.TraceID)
+		}
+		if newDatabaseOperationCount := f.DatabaseCoverage.UpdateFromTrace(newTrace); newDatabaseOperationCount > 0 {
+			log.Info().Msgf("[BasicFuzzer.applyOperationTrace] Found %d new database operations in trace %s", newDatabaseOperationCount, operationCase.TraceID)
+		}
+	} else {
+		log.Debug().Msgf("[BasicFuzzer.applyOperationTrace] Trace backend is unavailable, skip fetching trace %s", operationCase.TraceID)
+	}
+
+	log.Info().Msg("[BasicFuzzer.applyOperationTrace] Operation executed successfully")
+
+	hasOperationAchieveNewCoverage := f.FuzzingSnapshot.Update(
+		f.CallInfoGraph.GetEdgeCoveredCount(),
+		f.ResponseProcesser.GetCoveredStatusCodeCount(),
+		f.SpanErrorCoverage.GetCoveredCount(),
+		f.DatabaseCoverage.GetCoveredCount(),
+	)
+	f.Progress.UpdateCoverage(f.CallInfoGraph.GetEdgeCoveredCount(), f.CallInfoGraph.GetEdgeCoverage(), f.ResponseProcesser.GetCoveredStatusCodeCount())
+
+	// Pass the operation and the its execution result back to the case manager,
+	// and:
+	//  1. decide whether its operation cases are interesting or not (i.e., update their energy)
+	//  2. may mutate the operation cases and add them to the operation case queue.
+	err := f.CaseManager.EvaluateOperationCaseAndTryUpdate(hasOperationAchieveNewCoverage, operationCase)
+	if err != nil {
+		log.Err(err).Msg("[BasicFuzzer.applyOperationTrace] Failed to evaluate operation and try update")
+		return hasOperationAchieveNewCoverage, err
+	}
+	return hasOperationAchieveNewCoverage, nil
+}
+
+// replayAsOtherUsers replays the operation case (to be executed) as each user other than the owner,
+// if it reads or mutates a resource created in current test scenario, and checks the replays by BOLA oracle.
+// Replays are not processed as normal operation cases, i.e., they do not affect coverage or case queues.
+func (f *BasicFuzzer) replayAsOtherUsers(ctx context.Context, testScenario *casemanager.TestScenario, operationCase *casemanager.OperationCase) {
+	if len(f.CaseManager.UserSessions) < 2 {
+		return
+	}
+	resourcePath, owner, accessed := f.BOLAOracle.GetAccessedOwnedResource(operationCase)
+	if !accessed {
+		return
+	}
+	for _, userSession := range f.CaseManager.UserSessions {
+		if userSession.Name == owner || ctx.Err() != nil {
+			continue
+		}
+		replayedOperationCase := f.CaseManager.DeriveOperationCaseForUser(operationCase, userSession)
+		log.Debug().Msgf("[BasicFuzzer.replayAsOtherUsers] Replay %s %s on resource %s of user %s as user %s", operationCase.APIMethod.Method, operationCase.APIMethod.Endpoint, resourcePath, owner, userSession.Name)
+		// Cookies of the scenario belong to the owner, so they are not sent with replays as other users.
+		if err := f.ExecuteCaseOperation(http.ContextWithoutCookieJar(ctx), replayedOperationCase); err != nil {
+			log.Err(err).Msg("[BasicFuzzer.replayAsOtherUsers] Failed to execute replayed operation")
+			continue
+		}
+		f.BOLAOracle.CheckReplay(testScenario.UUID, resourcePath, replayedOperationCase)
+	}
+}
+
+// replayWithIdempotencyKey replays the executed operation case with the same idempotency key, if it should be checked by IdempotencyOracle.
+// Replays are not processed as normal operation cases, i.e., they do not affect coverage or case queues.
+func (f *BasicFuzzer) replayWithIdempotencyKey(ctx context.Context, testScenario *casemanager.TestScenario, operationCase *casemanager.OperationCase) {
+	if ctx.Err() != nil || !f.IdempotencyOracle.ShouldReplay(operationCase) {
+		return
+	}
+	replayedOperationCase := operationCase.Copy()
+	log.Debug().Msgf("[BasicFuzzer.replayWithIdempotencyKey] Replay %s %s with the same idempotency key", operationCase.APIMethod.Method, operationCase.APIMethod.Endpoint)
+	if err := f.ExecuteCaseOperation(ctx, replayedOperationCase); err != nil {
+		log.Err(err).Msg("[BasicFuzzer.replayWithIdempotencyKey] Failed to execute replayed operation")
+		return
+	}
+	f.IdempotencyOracle.CheckReplay(testScenario.UUID, operationCase, replayedOperationCase)
+}
+
+// executeOnCandidate sends the request of the executed operation case to the candidate version of the system, if differential fuzzing is enabled,
+// and compares the responses by DifferentialOracle. Only HTTP requests are sent, as gRPC calls and messages go to the same server or broker.
+// The candidate is of the primary target, so operations of additional targets are not sent.
+// Requests to the candidate are not processed as normal operation cases, i.e., they do not affect coverage or case queues.
+// IDs the candidate generated replace those of the baseline in the request, and mesh headers are renewed, so that the request starts a trace of its own.
+func (f *BasicFuzzer) executeOnCandidate(ctx context.Context, testScenario *casemanager.TestScenario, operationCase *casemanager.OperationCase) {
+	if f.CandidateHTTPClient == nil || ctx.Err() != nil || operationCase.APIMethod.Typ == static.SimpleAPIMethodTypeMessaging || (operationCase.APIMethod.Typ == static.SimpleAPIMethodTypeGRPC && f.GRPCClient != nil) {
+		return
+	}
+	if _, ok := f.APIManager.GetOperationTarget(operationCase.APIMethod); ok {
+		return
+	}
+	candidateOperationCase := operationCase.Copy()
+	f.DifferentialOracle.MapRequestToCandidate(candidateOperationCase)
+	if f.CaseManager.MeshHeaderPropagator != nil {
+		candidateOperationCase.RequestHeaders = f.CaseManager.MeshHeaderPropagator.RenewHeaders(candidateOperationCase.RequestHeaders)
+	}
+	statusCode, headers, respBodyBytes, err := f.CandidateHTTPClient.PerformRequest(ctx, candidateOperationCase.APIMethod.Endpoint, candidateOperationCase.APIMethod.Method, candidateOperationCase.RequestHeaders, candidateOperationCase.RequestPathParams, candidateOperationCase.RequestQueryParams, candidateOperationCase.RequestBody)
+	if err != nil {
+		log.Err(err).Msg("[BasicFuzzer.executeOnCandidate] Failed to perform request to candidate")
+		return
+	}
+	candidateOperationCase.ResponseStatusCode = statusCode
+	candidateOperationCase.ResponseHeaders = headers
+	candidateOperationCase.ResponseBody = respBodyBytes
+	f.DifferentialOracle.CheckResponses(testScenario.UUID, operationCase, candidateOperationCase)
+}
+
+// minimizeFailure minimizes the test scenario if it triggers a server error not minimized yet, by ScenarioMinimizer.
+// Reduced scenarios are not processed as normal test scenarios, i.e., they do not affect coverage or case queues.
+func (f *BasicFuzzer) minimizeFailure(ctx context.Context, testScenario *casemanager.TestScenario) {
+	failingIndex := f.ScenarioMinimizer.FindFailureToMinimize(testScenario)
+	if failingIndex < 0 || ctx.Err() != nil {
+		return
+	}
+	log.Info().Msgf("[BasicFuzzer.minimizeFailure] Minimize test scenario (UUID: %s) triggering status code %d at operation %d", testScenario.UUID.String(), testScenario.OperationCases[failingIndex].ResponseStatusCode, failingIndex)
+	execute := func(operationCase *casemanager.OperationCase) error {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return f.ExecuteCaseOperation(ctx, operationCase)
+	}
+	if _, err := f.ScenarioMinimizer.Minimize(testScenario, failingIndex, execute); err != nil {
+		log.Err(err).Msg("[BasicFuzzer.minimizeFailure] Failed to minimize test scenario")
+	}
+}
+
 // ExecuteCaseOperation executes a case operation from a test case.
-// This method makes HTTP call, and fills the response in the operation case.
-func (f *BasicFuzzer) ExecuteCaseOperation(operationCase *casemanager.OperationCase) error {
+// This method makes HTTP (or gRPC, see [BasicFuzzer.GRPCClient]) call, or publishes a message (see [BasicFuzzer.MessagePublisher]),
+// and fills the response in the operation case.
+func (f *BasicFuzzer) ExecuteCaseOperation(ctx context.Context, operationCase *casemanager.OperationCase) error {
 	path := operationCase.APIMethod.Endpoint
 	method := operationCase.APIMethod.Method
 	headers := operationCase.RequestHeaders
@@ -239,21 +963,153 @@ func (f *BasicFuzzer) ExecuteCaseOperation(operationCase *casemanager.OperationC
 	queryParams := operationCase.RequestQueryParams
 	body := operationCase.RequestBody
 	log.Debug().Msgf("[BasicFuzzer.ExecuteCaseOperation] Execute operation: %s %s", method, path)
-	statusCode, headers, respBodyBytes, err := f.HTTPClient.PerformRequest(path, method, headers, pathParams, queryParams, body)
+	var (
+		statusCode    int
+		respBodyBytes []byte
+		err           error
+	)
+	// Clients measure the last attempt of the request, so that retries and waits do not count as latency of the server.
+	ctx, lastAttemptTime := http.ContextWithResponseTime(ctx)
+	// Likewise, the trace started by the last attempt is the one of the response, see [config.RuntimeConfig.InjectTraceContext].
+	ctx, clientTraceID := http.ContextWithClientTraceID(ctx)
+	start := time.Now()
+	if operationCase.APIMethod.Typ == static.SimpleAPIMethodTypeGRPC && f.GRPCClient != nil {
+		statusCode, headers, respBodyBytes, err = f.performGRPCRequest(ctx, operationCase)
+	} else if operationCase.APIMethod.Typ == static.SimpleAPIMethodTypeMessaging {
+		statusCode, headers, respBodyBytes, err = f.performMessagingRequest(ctx, operationCase)
+	} else {
+		statusCode, headers, respBodyBytes, err = f.getHTTPClient(operationCase.APIMethod).PerformRequest(ctx, path, method, headers, pathParams, queryParams, body)
+	}
 	if err != nil {
 		// A failed request will not stop the fuzzing process.
 		log.Err(err).Msg("[BasicFuzzer.ExecuteCaseOperation] Failed to perform request")
 	}
+	elapsed := time.Since(start)
+	if *lastAttemptTime > 0 {
+		elapsed = *lastAttemptTime
+	}
+	responseTime := float64(elapsed.Microseconds()) / 1000
+	if f.ErrorBudget != nil {
+		f.ErrorBudget.RecordResponse(statusCode, err != nil)
+	}
+	f.Progress.RecordResponse(statusCode, err != nil)
+	// Requests cut by ctx (e.g., budget expiry) tell nothing about the endpoint, nor do faults injected by chaos experiments.
+	if ctx.Err() == nil && !isUnderChaosExperiment(ctx) {
+		f.EndpointPerformanceTracker.RecordRequest(operationCase.APIMethod, statusCode, responseTime, err)
+	}
 
 	// Fill the response in the operation case.
 	operationCase.ResponseStatusCode = statusCode
 	operationCase.ResponseHeaders = headers
 	operationCase.ResponseBody = respBodyBytes
-	log.Debug().Msgf("[BasicFuzzer.ExecuteCaseOperation] Response status code: %d, body: %s", statusCode, string(respBodyBytes))
+	operationCase.ResponseTime = responseTime
+	operationCase.TraceID = *clientTraceID
+	log.Debug().Msgf("[BasicFuzzer.ExecuteCaseOperation] Response status code: %d, response time: %.1fms, body: %s", statusCode, operationCase.ResponseTime, string(respBodyBytes))
 	return nil
 }
 
+// rotateServer switches HTTPClient to the next server in ServerHTTPClients, if servers are rotated.
+// The first test scenario is sent to the first server.
+func (f *BasicFuzzer) rotateServer() {
+	if len(f.ServerHTTPClients) == 0 {
+		return
+	}
+	f.HTTPClient = f.ServerHTTPClients[f.serverIndex]
+	f.serverIndex = (f.serverIndex + 1) % len(f.ServerHTTPClients)
+}
+
+// switchServer switches HTTPClient to the server of the base URL in ServerHTTPClients, if servers are rotated and the server is found.
+func (f *BasicFuzzer) switchServer(serverBaseURL string) {
+	for _, serverHTTPClient := range f.ServerHTTPClients {
+		if serverHTTPClient.BaseURL == serverBaseURL {
+			f.HTTPClient = serverHTTPClient
+			return
+		}
+	}
+}
+
+// getHTTPClient returns the HTTP client to send requests of the operation, i.e., that of its additional target if any, or HTTPClient otherwise.
+func (f *BasicFuzzer) getHTTPClient(method static.SimpleAPIMethod) *http.HTTPClient {
+	if target, ok := f.APIManager.GetOperationTarget(method); ok {
+		if targetHTTPClient, exists := f.TargetHTTPClients[target.Name]; exists {
+			return targetHTTPClient
+		}
+	}
+	return f.HTTPClient
+}
+
+// performGRPCRequest executes the operation case as a gRPC call.
+// The gRPC method is identified by operationId of the operation, see [static.ParseGRPCOperationID],
+// and the request message consists of path and query parameters as well as the body, see [grpc.BuildRequestMessageJSON].
+func (f *BasicFuzzer) performGRPCRequest(ctx context.Context, operationCase *casemanager.OperationCase) (int, map[string]string, []byte, error) {
+	operation, exists := f.APIManager.APIMap[operationCase.APIMethod]
+	if !exists {
+		return 0, nil, nil, fmt.Errorf("operation %s %s not found", operationCase.APIMethod.Method, operationCase.APIMethod.Endpoint)
+	}
+	serviceName, methodName, ok := static.ParseGRPCOperationID(operation.OperationID)
+	if !ok {
+		return 0, nil, nil, fmt.Errorf("invalid operationId of gRPC operation: %s", operation.OperationID)
+	}
+	body, err := grpc.BuildRequestMessageJSON(operationCase.RequestPathParams, operationCase.RequestQueryParams, operationCase.RequestBody)
+	if err != nil {
+		return 0, nil, nil, err
+	}
+	return f.GRPCClient.PerformRequest(ctx, serviceName, methodName, operationCase.RequestHeaders, body)
+}
+
+// performMessagingRequest executes the operation case by publishing its request body as a message to the channel of the operation.
+// A new trace context is propagated in message headers (see [messaging.NewTraceParent]), and its trace ID is set as the trace ID header of the response,
+// so that spans of consumers of the message are fetched and fed back like those of HTTP requests.
+func (f *BasicFuzzer) performMessagingRequest(ctx context.Context, operationCase *casemanager.OperationCase) (int, map[string]string, []byte, error) {
+	if f.MessagePublisher == nil {
+		return 0, nil, nil, fmt.Errorf("no message publisher to publish message to channel %s", operationCase.APIMethod.Endpoint)
+	}
+	channel, exists := f.APIManager.MessagingChannelMap[operationCase.APIMethod]
+	if !exists {
+		return 0, nil, nil, fmt.Errorf("messaging channel %s not found", operationCase.APIMethod.Endpoint)
+	}
+	messageHeaders := maps.Clone(operationCase.RequestHeaders)
+	if messageHeaders == nil {
+		messageHeaders = make(map[string]string)
+	}
+	traceParent, traceID := messaging.NewTraceParent()
+	messageHeaders[messaging.TraceParentHeaderKey] = traceParent
+	destination := messaging.Destination{
+		KafkaTopic:      channel.KafkaTopic,
+		AMQPExchange:    channel.AMQPExchange,
+		AMQPRoutingKey:  channel.AMQPRoutingKey,
+		AMQPVirtualHost: channel.AMQPVirtualHost,
+	}
+	statusCode, respHeaders, respBody, err := f.MessagePublisher.Publish(ctx, destination, messageHeaders, operationCase.RequestBody)
+	if respHeaders == nil {
+		respHeaders = make(map[string]string)
+	}
+	respHeaders[config.GlobalConfig.TraceIDHeaderKey] = traceID
+	return statusCode, respHeaders, respBody, err
+}
+
 // GetCallInfoGraph gets the runtime call info graph.
 func (f *BasicFuzzer) GetCallInfoGraph() *fuzzruntime.CallInfoGraph {
 	return f.CallInfoGraph
 }
+
+// GetSpanErrorCoverage gets the runtime span error coverage.
+func (f *BasicFuzzer) GetSpanErrorCoverage() *fuzzruntime.SpanErrorCoverage {
+	return f.SpanErrorCoverage
+}
+
+// GetDatabaseCoverage gets the runtime database coverage.
+func (f *BasicFuzzer) GetDatabaseCoverage() *fuzzruntime.DatabaseCoverage {
+	return f.DatabaseCoverage
+}
+
+// SetIntermediateReportFunc sets the function writing intermediate reports every interval during fuzzing.
+func (f *BasicFuzzer) SetIntermediateReportFunc(interval time.Duration, reportFunc func()) {
+	f.ReportInterval = interval
+	f.ReportFunc = reportFunc
+}
+
+// GetProgress gets a snapshot of the progress of fuzzing.
+func (f *BasicFuzzer) GetProgress() FuzzingProgress {
+	return f.Progress.GetProgress()
+}
@@ -0,0 +1,198 @@
+package fuzzer
+
+import (
+	"context"
+	"fmt"
+	"math/rand/v2"
+	"resttracefuzzer/pkg/casemanager"
+	"resttracefuzzer/pkg/static"
+	"resttracefuzzer/pkg/utils"
+	"resttracefuzzer/pkg/utils/http"
+	"strings"
+
+	"github.com/bytedance/sonic"
+	"github.com/cloudwego/hertz/pkg/protocol/consts"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	// ChaosHookEventStart is the event of the webhook called before a test scenario, to start injecting faults.
+	ChaosHookEventStart = "start"
+
+	// ChaosHookEventStop is the event of the webhook called after a test scenario, to stop injecting faults.
+	ChaosHookEventStop = "stop"
+)
+
+// ChaosExperiment is a chaos experiment run during selected test scenarios, by calling webhooks of a chaos tool,
+// e.g., an adapter creating and deleting a Chaos Mesh NetworkChaos injecting latency into a target service.
+type ChaosExperiment struct {
+	// Name is the name of the experiment, which tags test scenarios run under it.
+	Name string `json:"name"`
+
+	// StartURL is the URL of the webhook called before a selected test scenario.
+	StartURL string `json:"startURL"`
+
+	// StopURL is the URL of the webhook called after a selected test scenario. It can be empty, e.g., the faults expire by themselves.
+	StopURL string `json:"stopURL"`
+
+	// Percent is the percentage (1-100) of eligible test scenarios selected for the experiment.
+	Percent int `json:"percent"`
+
+	// APIMethods are the API methods in the format of `METHOD path`, one of which a test scenario must contain to be eligible.
+	// All test scenarios are eligible if it is empty.
+	APIMethods []string `json:"APIMethods"`
+
+	// eligibleAPIMethods is the set of parsed APIMethods.
+	eligibleAPIMethods map[static.SimpleAPIMethod]struct{}
+
+	// startClient and stopClient are HTTP clients of the webhooks, whose base URLs are the URLs of the webhooks.
+	startClient, stopClient *http.HTTPClient
+}
+
+// chaosHookRequest is the JSON body of webhook requests.
+type chaosHookRequest struct {
+	// Event is [ChaosHookEventStart] or [ChaosHookEventStop].
+	Event string `json:"event"`
+
+	// Experiment is the name of the experiment.
+	Experiment string `json:"experiment"`
+
+	// TestScenarioUUID is the UUID of the test scenario run under the experiment.
+	TestScenarioUUID string `json:"testScenarioUUID"`
+}
+
+// ChaosCoordinator coordinates chaos experiments with test scenarios, so that resilience experiments can reuse the workload generation and trace-based verification of the fuzzer.
+// Before a test scenario, at most one experiment is selected and started (see [ChaosExperiment]), and the scenario is tagged with it.
+// After the scenario, the experiment is stopped.
+type ChaosCoordinator struct {
+	// Experiments are the chaos experiments, in order of selection.
+	Experiments []*ChaosExperiment
+
+	// Rand is the random number generator for experiment selection, which is [utils.SharedRand] by default.
+	Rand *rand.Rand
+}
+
+// NewChaosCoordinator creates a new ChaosCoordinator from a stringified JSON list of [ChaosExperiment], which can be empty, e.g.,
+// `[{"name": "payment-latency", "startURL": "http://chaos-adapter:8080/payment-latency/start", "stopURL": "http://chaos-adapter:8080/payment-latency/stop", "percent": 10, "APIMethods": ["POST /orders"]}]`.
+// It returns an error if experimentsStr is invalid, or refers to an undefined API method.
+func NewChaosCoordinator(experimentsStr string, APIManager *static.APIManager) (*ChaosCoordinator, error) {
+	experiments := make([]*ChaosExperiment, 0)
+	if experimentsStr != "" {
+		err := sonic.UnmarshalString(experimentsStr, &experiments)
+		if err != nil {
+			log.Err(err).Msg("[NewChaosCoordinator] Failed to unmarshal chaos experiments")
+			return nil, err
+		}
+	}
+	for _, experiment := range experiments {
+		if experiment == nil || experiment.Name == "" || experiment.StartURL == "" {
+			return nil, fmt.Errorf("chaos experiment name or start URL is empty")
+		}
+		if experiment.Percent <= 0 || experiment.Percent > 100 {
+			return nil, fmt.Errorf("invalid percent %d of chaos experiment %s, expected 1-100", experiment.Percent, experiment.Name)
+		}
+		experiment.eligibleAPIMethods = make(map[static.SimpleAPIMethod]struct{})
+		for _, key := range experiment.APIMethods {
+			method, path, found := strings.Cut(strings.TrimSpace(key), " ")
+			apiMethod := static.SimpleAPIMethod{
+				Method:   strings.ToUpper(method),
+				Endpoint: strings.TrimSpace(path),
+				Typ:      static.SimpleAPIMethodTypeHTTP,
+			}
+			if _, exist := APIManager.APIMap[apiMethod]; !found || !exist {
+				return nil, fmt.Errorf("API method %q of chaos experiment %s is not defined in the API doc", key, experiment.Name)
+			}
+			experiment.eligibleAPIMethods[apiMethod] = struct{}{}
+		}
+		experiment.startClient = http.NewHTTPClient(experiment.StartURL, []string{}, http.EmptyHTTPClientMiddlewareSlice())
+		if experiment.StopURL != "" {
+			experiment.stopClient = http.NewHTTPClient(experiment.StopURL, []string{}, http.EmptyHTTPClientMiddlewareSlice())
+		}
+	}
+	return &ChaosCoordinator{
+		Experiments: experiments,
+		Rand:        utils.SharedRand,
+	}, nil
+}
+
+// SelectExperiment selects the experiment to run during the test scenario, or nil if none is selected.
+// Each eligible experiment is selected by chance of its percent, in order.
+func (c *ChaosCoordinator) SelectExperiment(testScenario *casemanager.TestScenario) *ChaosExperiment {
+	for _, experiment := range c.Experiments {
+		if experiment.isEligible(testScenario) && c.Rand.IntN(100) < experiment.Percent {
+			return experiment
+		}
+	}
+	return nil
+}
+
+// StartExperiment calls the start webhook of the experiment before the test scenario, and tags the scenario with the experiment if it succeeds.
+func (c *ChaosCoordinator) StartExperiment(ctx context.Context, experiment *ChaosExperiment, testScenario *casemanager.TestScenario) error {
+	err := callChaosHook(ctx, experiment.startClient, ChaosHookEventStart, experiment.Name, testScenario)
+	if err != nil {
+		return err
+	}
+	testScenario.ChaosExperiment = experiment.Name
+	log.Info().Msgf("[ChaosCoordinator.StartExperiment] Chaos experiment %s started for test scenario (UUID: %s)", experiment.Name, testScenario.UUID.String())
+	return nil
+}
+
+// StopExperiment calls the stop webhook of the experiment (if any) after the test scenario.
+// It is called even if ctx is done (e.g., budget is exhausted), so that faults are not left injected.
+func (c *ChaosCoordinator) StopExperiment(ctx context.Context, experiment *ChaosExperiment, testScenario *casemanager.TestScenario) error {
+	if experiment.stopClient == nil {
+		return nil
+	}
+	return callChaosHook(context.WithoutCancel(ctx), experiment.stopClient, ChaosHookEventStop, experiment.Name, testScenario)
+}
+
+// isEligible checks whether the test scenario contains an eligible API method of the experiment.
+func (e *ChaosExperiment) isEligible(testScenario *casemanager.TestScenario) bool {
+	if len(e.eligibleAPIMethods) == 0 {
+		return true
+	}
+	for _, operationCase := range testScenario.OperationCases {
+		if _, exist := e.eligibleAPIMethods[operationCase.APIMethod]; exist {
+			return true
+		}
+	}
+	return false
+}
+
+// callChaosHook posts the event of the experiment and test scenario to the webhook, i.e., the base URL of the client.
+func callChaosHook(ctx context.Context, client *http.HTTPClient, event, experimentName string, testScenario *casemanager.TestScenario) error {
+	body, err := sonic.Marshal(chaosHookRequest{
+		Event:            event,
+		Experiment:       experimentName,
+		TestScenarioUUID: testScenario.UUID.String(),
+	})
+	if err != nil {
+		log.Err(err).Msg("[callChaosHook] Failed to marshal webhook request")
+		return err
+	}
+	headers := map[string]string{"Content-Type": "application/json"}
+	statusCode, _, _, err := client.PerformRequest(ctx, "", consts.MethodPost, headers, nil, nil, body)
+	if err != nil {
+		log.Err(err).Msgf("[callChaosHook] Failed to call %s webhook of chaos experiment %s", event, experimentName)
+		return err
+	}
+	if !http.IsStatusCodeSuccess(statusCode) {
+		log.Error().Msgf("[callChaosHook] Failed to call %s webhook of chaos experiment %s, statusCode: %d", event, experimentName, statusCode)
+		return fmt.Errorf("failed to call %s webhook of chaos experiment %s, status code: %d", event, experimentName, statusCode)
+	}
+	return nil
+}
+
+// chaosExperimentContextKey is the key of the name of the chaos experiment running in the context, see [contextWithChaosExperiment].
+type chaosExperimentContextKey struct{}
+
+// contextWithChaosExperiment returns a copy of ctx, in which requests are sent under the running chaos experiment of the name.
+func contextWithChaosExperiment(ctx context.Context, experimentName string) context.Context {
+	return context.WithValue(ctx, chaosExperimentContextKey{}, experimentName)
+}
+
+// isUnderChaosExperiment tells whether requests of ctx are sent under a chaos experiment (see [contextWithChaosExperiment]), i.e., faults may be injected.
+func isUnderChaosExperiment(ctx context.Context) bool {
+	experimentName, ok := ctx.Value(chaosExperimentContextKey{}).(string)
+	return ok && experimentName != ""
+}
@@ -1,12 +1,30 @@
 package fuzzer
 
-import fuzzruntime "resttracefuzzer/pkg/runtime"
+import (
+	"context"
+	fuzzruntime "resttracefuzzer/pkg/runtime"
+	"time"
+)
 
 // Fuzzer is the interface that defines the basic methods of a fuzzer.
 type Fuzzer interface {
 	// Start starts the fuzzer.
-	Start() error
+	// The fuzzer stops when ctx is done, e.g., on shutdown.
+	Start(ctx context.Context) error
 
 	// GetCallInfoGraph gets the runtime call info graph.
 	GetCallInfoGraph() *fuzzruntime.CallInfoGraph
+
+	// GetSpanErrorCoverage gets the runtime span error coverage.
+	GetSpanErrorCoverage() *fuzzruntime.SpanErrorCoverage
+
+	// GetDatabaseCoverage gets the runtime database coverage.
+	GetDatabaseCoverage() *fuzzruntime.DatabaseCoverage
+
+	// GetProgress gets a snapshot of the progress of fuzzing. It is safe to call while the fuzzer is running.
+	GetProgress() FuzzingProgress
+
+	// SetIntermediateReportFunc sets the function writing intermediate reports every interval during fuzzing.
+	// Intermediate reports are not written if interval is not positive.
+	SetIntermediateReportFunc(interval time.Duration, reportFunc func())
 }
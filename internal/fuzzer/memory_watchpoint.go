@@ -0,0 +1,58 @@
+package fuzzer
+
+import (
+	"resttracefuzzer/pkg/utils"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	// memoryCompactionRatio is the ratio of test scenarios, resources and traces removed in each compaction.
+	memoryCompactionRatio = 0.5
+
+	// memoryCompactionCooldown is the minimal interval between two compactions,
+	// as RSS may not drop immediately after compaction (memory is returned to OS lazily).
+	memoryCompactionCooldown = time.Minute
+)
+
+// MemoryWatchpoint monitors the resident memory (RSS) of the fuzzer itself,
+// and tells when the corpus should be compacted, to prevent long runs from being OOM-killed.
+type MemoryWatchpoint struct {
+	// ThresholdBytes is the RSS threshold, in bytes. Compaction is disabled if it is 0.
+	ThresholdBytes uint64
+
+	// lastCompactionTime is the time of the last compaction.
+	lastCompactionTime time.Time
+}
+
+// NewMemoryWatchpoint creates a new MemoryWatchpoint with threshold in MB.
+// If the threshold is not positive, the watchpoint never triggers compaction.
+func NewMemoryWatchpoint(thresholdMB int) *MemoryWatchpoint {
+	var thresholdBytes uint64
+	if thresholdMB > 0 {
+		thresholdBytes = uint64(thresholdMB) << 20
+	}
+	return &MemoryWatchpoint{
+		ThresholdBytes: thresholdBytes,
+	}
+}
+
+// ShouldCompact checks whether RSS exceeds the threshold, and it is not within cooldown of the last compaction.
+// If so, it records current time as the time of compaction, and the caller is expected to compact.
+func (w *MemoryWatchpoint) ShouldCompact() bool {
+	if w.ThresholdBytes == 0 || time.Since(w.lastCompactionTime) < memoryCompactionCooldown {
+		return false
+	}
+	rss, err := utils.GetProcessRSS()
+	if err != nil {
+		log.Err(err).Msg("[MemoryWatchpoint.ShouldCompact] Failed to get RSS")
+		return false
+	}
+	if rss <= w.ThresholdBytes {
+		return false
+	}
+	log.Warn().Msgf("[MemoryWatchpoint.ShouldCompact] RSS %d MB exceeds threshold %d MB", rss>>20, w.ThresholdBytes>>20)
+	w.lastCompactionTime = time.Now()
+	return true
+}
@@ -0,0 +1,133 @@
+package fuzzer
+
+import (
+	"maps"
+	"resttracefuzzer/pkg/casemanager"
+	"resttracefuzzer/pkg/utils/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// FuzzingProgress is a snapshot of the progress of fuzzing, e.g., for live display.
+type FuzzingProgress struct {
+	// StartTime is the time fuzzing started, or zero if it has not started.
+	StartTime time.Time `json:"startTime"`
+
+	// Budget is the time budget of fuzzing.
+	Budget time.Duration `json:"budget"`
+
+	// RequestCount is the number of requests sent (including replays), i.e., operation cases executed.
+	RequestCount int `json:"requestCount"`
+
+	// StatusClassCounts is the number of responses of each status code class (e.g., 200 for 2xx, see [http.GetStatusCodeClass]).
+	// Failed requests (without response) are counted by class 0.
+	StatusClassCounts map[int]int `json:"statusClassCounts"`
+
+	// PrimaryServerErrorCount is the number of responses of server errors (5xx) to operation cases of test scenarios,
+	// excluding those to replays (e.g., by BOLA and idempotency oracles), and those under chaos experiments, as the fuzzer induces them itself.
+	PrimaryServerErrorCount int `json:"primaryServerErrorCount"`
+
+	// ScenarioCount is the number of test scenarios executed, including the current one.
+	ScenarioCount int `json:"scenarioCount"`
+
+	// EdgeCoveredCount is the number of covered edges of the call info graph.
+	EdgeCoveredCount int `json:"edgeCoveredCount"`
+
+	// EdgeCoverage is the ratio of covered edges of the call info graph.
+	EdgeCoverage float64 `json:"edgeCoverage"`
+
+	// CoveredStatusCodeCount is the number of covered status codes of API methods.
+	CoveredStatusCodeCount int `json:"coveredStatusCodeCount"`
+
+	// CurrentScenarioUUID is the UUID of the test scenario being executed, or empty if none.
+	CurrentScenarioUUID string `json:"currentScenarioUUID"`
+
+	// CurrentScenarioOperations are the API methods of the test scenario being executed, e.g., `POST /pets`.
+	CurrentScenarioOperations []string `json:"currentScenarioOperations"`
+}
+
+// GetElapsedTime returns the time elapsed since fuzzing started, or 0 if it has not started.
+func (p FuzzingProgress) GetElapsedTime() time.Duration {
+	if p.StartTime.IsZero() {
+		return 0
+	}
+	return time.Since(p.StartTime)
+}
+
+// ProgressTracker tracks the progress of fuzzing, updated by the fuzzer and read by displays (see [FuzzingProgress]).
+// It is safe for concurrent use, as displays read it while the fuzzer is running.
+type ProgressTracker struct {
+	mu sync.Mutex
+
+	// progress is the current progress.
+	progress FuzzingProgress
+}
+
+// NewProgressTracker creates a new ProgressTracker.
+func NewProgressTracker() *ProgressTracker {
+	return &ProgressTracker{
+		progress: FuzzingProgress{
+			StatusClassCounts:         make(map[int]int),
+			CurrentScenarioOperations: make([]string, 0),
+		},
+	}
+}
+
+// Start records the start time and budget of fuzzing.
+func (t *ProgressTracker) Start(startTime time.Time, budget time.Duration) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.progress.StartTime = startTime
+	t.progress.Budget = budget
+}
+
+// StartScenario records the test scenario being executed.
+func (t *ProgressTracker) StartScenario(testScenario *casemanager.TestScenario) {
+	operations := make([]string, 0, len(testScenario.OperationCases))
+	for _, operationCase := range testScenario.OperationCases {
+		operations = append(operations, strings.TrimSpace(operationCase.APIMethod.Method+" "+operationCase.APIMethod.Endpoint))
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.progress.ScenarioCount++
+	t.progress.CurrentScenarioUUID = testScenario.UUID.String()
+	t.progress.CurrentScenarioOperations = operations
+}
+
+// RecordResponse records a response of the status code, or a failed request if failed is true.
+func (t *ProgressTracker) RecordResponse(statusCode int, failed bool) {
+	statusClass := 0
+	if !failed {
+		statusClass = http.GetStatusCodeClass(statusCode)
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.progress.RequestCount++
+	t.progress.StatusClassCounts[statusClass]++
+}
+
+// RecordPrimaryServerError records a response of server error (5xx) to an operation case of a test scenario, see [FuzzingProgress.PrimaryServerErrorCount].
+func (t *ProgressTracker) RecordPrimaryServerError() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.progress.PrimaryServerErrorCount++
+}
+
+// UpdateCoverage records the current coverage.
+func (t *ProgressTracker) UpdateCoverage(edgeCoveredCount int, edgeCoverage float64, coveredStatusCodeCount int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.progress.EdgeCoveredCount = edgeCoveredCount
+	t.progress.EdgeCoverage = edgeCoverage
+	t.progress.CoveredStatusCodeCount = coveredStatusCodeCount
+}
+
+// GetProgress returns a snapshot of the current progress.
+func (t *ProgressTracker) GetProgress() FuzzingProgress {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	progress := t.progress
+	progress.StatusClassCounts = maps.Clone(t.progress.StatusClassCounts)
+	return progress
+}
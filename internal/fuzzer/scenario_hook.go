@@ -0,0 +1,276 @@
+package fuzzer
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"resttracefuzzer/pkg/casemanager"
+	"resttracefuzzer/pkg/utils/http"
+	"strings"
+	"sync"
+
+	"github.com/bytedance/sonic"
+	"github.com/rs/zerolog/log"
+	starlarkjson "go.starlark.net/lib/json"
+	"go.starlark.net/starlark"
+	"go.starlark.net/syntax"
+)
+
+const (
+	// ScenarioHookBeforeFuncName is the name of the hook function called before each test scenario.
+	ScenarioHookBeforeFuncName = "before_scenario"
+
+	// ScenarioHookAfterFuncName is the name of the hook function called after each test scenario.
+	ScenarioHookAfterFuncName = "after_scenario"
+
+	// scenarioHookContextKey is the key of the thread-local context of hook executions, used by builtin http_request.
+	scenarioHookContextKey = "ctx"
+)
+
+// scenarioHookOperation is an operation case of the test scenario passed to hooks.
+type scenarioHookOperation struct {
+	// Method is the HTTP method of the operation.
+	Method string `json:"method"`
+
+	// Endpoint is the endpoint of the operation, e.g., `/pets/{petId}`.
+	Endpoint string `json:"endpoint"`
+
+	// PathParams are the path parameters of the request.
+	PathParams map[string]string `json:"pathParams"`
+
+	// QueryParams are the query parameters of the request.
+	QueryParams map[string]string `json:"queryParams"`
+
+	// Headers are the headers of the request.
+	Headers map[string]string `json:"headers"`
+
+	// RequestBody is the body of the request.
+	RequestBody string `json:"requestBody"`
+
+	// StatusCode is the status code of the response, or 0 if the operation is not executed.
+	StatusCode int `json:"statusCode"`
+
+	// ResponseBody is the body of the response, or empty if the operation is not executed.
+	ResponseBody string `json:"responseBody"`
+}
+
+// scenarioHookScenario is the test scenario passed to hooks.
+type scenarioHookScenario struct {
+	// UUID is the UUID of the test scenario.
+	UUID string `json:"uuid"`
+
+	// Operations are the operation cases of the test scenario, in order.
+	Operations []scenarioHookOperation `json:"operations"`
+}
+
+// ScenarioHookRunner runs setup and teardown hooks of a Starlark script around each test scenario,
+// e.g., to log in or seed prerequisite data before a scenario, and to clean up resources created by it.
+// The script can define (either or both of) the functions:
+//   - before_scenario(scenario), called before the scenario is executed. It returns None, or a Dict with key "headers",
+//     whose headers are added to all requests of the scenario, e.g., a token of a fresh login.
+//   - after_scenario(scenario), called after the scenario is executed, even if the budget is exhausted, with the responses filled.
+//
+// scenario is a Dict with keys "uuid" and "operations", where each operation has keys "method", "endpoint", "pathParams", "queryParams",
+// "headers", "requestBody", "statusCode" and "responseBody" (0 and empty before the scenario is executed).
+// Builtin http_request(method, path, headers=None, body=None) sends a request to the server under test, through the HTTP client of the fuzzer
+// (i.e., with its middlewares), and returns a Dict with keys "statusCode" and "body". Module "json" is predeclared, and a global Dict "state"
+// is shared among all hook executions, like the HTTP middleware script. For example:
+//
+//	# Example Starlark script
+//	def before_scenario(scenario):
+//	    resp = http_request("POST", "/login", body=json.encode({"user": "admin", "password": "admin"}))
+//	    return {"headers": {"Authorization": "Bearer " + json.decode(resp["body"])["token"]}}
+//
+//	def after_scenario(scenario):
+//	    for op in scenario["operations"]:
+//	        if op["method"] == "POST" and op["endpoint"] == "/pets" and op["statusCode"] == 201:
+//	            http_request("DELETE", "/pets/" + str(json.decode(op["responseBody"])["id"]))
+//
+// Requests sent by hooks are not evaluated as operation cases, i.e., they do not affect coverage or reports.
+type ScenarioHookRunner struct {
+	// ScriptPath is the path of the Starlark script, used for logging.
+	ScriptPath string
+
+	// HTTPClient is the HTTP client of the server under test, used by builtin http_request.
+	HTTPClient *http.HTTPClient
+
+	// beforeFunc and afterFunc are the hook functions defined by the script, or nil if not defined.
+	beforeFunc, afterFunc starlark.Callable
+
+	// state is the global "state" Dict of the script, shared among hook executions.
+	state *starlark.Dict
+
+	// mu serializes hook executions, as they share the state.
+	mu sync.Mutex
+}
+
+// NewScenarioHookRunner creates a new ScenarioHookRunner, executing the script at scriptPath once to load the hook functions.
+// It returns an error if the script cannot be loaded, or defines neither of the hook functions.
+func NewScenarioHookRunner(scriptPath string, httpClient *http.HTTPClient) (*ScenarioHookRunner, error) {
+	script, err := os.ReadFile(scriptPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read script %s: %w", scriptPath, err)
+	}
+	r := &ScenarioHookRunner{
+		ScriptPath: scriptPath,
+		HTTPClient: httpClient,
+		state:      starlark.NewDict(0),
+	}
+	predeclared := starlark.StringDict{
+		"json":         starlarkjson.Module,
+		"state":        r.state,
+		"http_request": starlark.NewBuiltin("http_request", r.httpRequest),
+	}
+	thread := &starlark.Thread{Name: "scenario_hook_script"}
+	globals, err := starlark.ExecFileOptions(syntax.LegacyFileOptions(), thread, scriptPath, script, predeclared)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute script %s: %w", scriptPath, err)
+	}
+	r.beforeFunc, _ = globals[ScenarioHookBeforeFuncName].(starlark.Callable)
+	r.afterFunc, _ = globals[ScenarioHookAfterFuncName].(starlark.Callable)
+	if r.beforeFunc == nil && r.afterFunc == nil {
+		return nil, fmt.Errorf("script %s defines neither %s nor %s", scriptPath, ScenarioHookBeforeFuncName, ScenarioHookAfterFuncName)
+	}
+	return r, nil
+}
+
+// BeforeScenario calls hook before_scenario (if defined) before the test scenario, and adds the headers it returns to all operation cases of the scenario.
+func (r *ScenarioHookRunner) BeforeScenario(ctx context.Context, testScenario *casemanager.TestScenario) error {
+	if r.beforeFunc == nil {
+		return nil
+	}
+	result, err := r.callHook(ctx, r.beforeFunc, testScenario)
+	if err != nil {
+		return err
+	}
+	if result == starlark.None {
+		return nil
+	}
+	resultDict, ok := result.(*starlark.Dict)
+	if !ok {
+		return fmt.Errorf("%s returns %s, expected None or dict", ScenarioHookBeforeFuncName, result.Type())
+	}
+	headersValue, found, err := resultDict.Get(starlark.String("headers"))
+	if err != nil || !found {
+		return err
+	}
+	headersDict, ok := headersValue.(*starlark.Dict)
+	if !ok {
+		return fmt.Errorf("headers returned by %s is %s, expected dict", ScenarioHookBeforeFuncName, headersValue.Type())
+	}
+	headers, err := convertStarlarkDictToStringMap(headersDict)
+	if err != nil {
+		return fmt.Errorf("invalid headers returned by %s: %w", ScenarioHookBeforeFuncName, err)
+	}
+	for _, operationCase := range testScenario.OperationCases {
+		if operationCase.RequestHeaders == nil {
+			operationCase.RequestHeaders = make(map[string]string)
+		}
+		for key, value := range headers {
+			operationCase.RequestHeaders[key] = value
+		}
+	}
+	log.Debug().Msgf("[ScenarioHookRunner.BeforeScenario] Added %d headers to test scenario (UUID: %s)", len(headers), testScenario.UUID.String())
+	return nil
+}
+
+// AfterScenario calls hook after_scenario (if defined) after the test scenario.
+// It is called even if ctx is done (e.g., budget is exhausted), so that resources created are cleaned up.
+func (r *ScenarioHookRunner) AfterScenario(ctx context.Context, testScenario *casemanager.TestScenario) error {
+	if r.afterFunc == nil {
+		return nil
+	}
+	_, err := r.callHook(context.WithoutCancel(ctx), r.afterFunc, testScenario)
+	return err
+}
+
+// callHook calls the hook function with the test scenario, and returns its result.
+func (r *ScenarioHookRunner) callHook(ctx context.Context, hookFunc starlark.Callable, testScenario *casemanager.TestScenario) (starlark.Value, error) {
+	scenario := scenarioHookScenario{
+		UUID:       testScenario.UUID.String(),
+		Operations: make([]scenarioHookOperation, 0, len(testScenario.OperationCases)),
+	}
+	for _, operationCase := range testScenario.OperationCases {
+		scenario.Operations = append(scenario.Operations, scenarioHookOperation{
+			Method:       operationCase.APIMethod.Method,
+			Endpoint:     operationCase.APIMethod.Endpoint,
+			PathParams:   operationCase.RequestPathParams,
+			QueryParams:  operationCase.RequestQueryParams,
+			Headers:      operationCase.RequestHeaders,
+			RequestBody:  string(operationCase.RequestBody),
+			StatusCode:   operationCase.ResponseStatusCode,
+			ResponseBody: string(operationCase.ResponseBody),
+		})
+	}
+	scenarioJSON, err := sonic.MarshalString(scenario)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal test scenario: %w", err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	thread := &starlark.Thread{Name: "scenario_hook_script"}
+	thread.SetLocal(scenarioHookContextKey, ctx)
+	scenarioValue, err := starlark.Call(thread, starlarkjson.Module.Members["decode"], starlark.Tuple{starlark.String(scenarioJSON)}, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to convert test scenario: %w", err)
+	}
+	result, err := starlark.Call(thread, hookFunc, starlark.Tuple{scenarioValue}, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to call hook of script %s: %w", r.ScriptPath, err)
+	}
+	return result, nil
+}
+
+// httpRequest implements builtin http_request(method, path, headers=None, body=None), see [ScenarioHookRunner].
+func (r *ScenarioHookRunner) httpRequest(thread *starlark.Thread, builtin *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var (
+		method, path string
+		headersDict  *starlark.Dict
+		body         string
+	)
+	if err := starlark.UnpackArgs(builtin.Name(), args, kwargs, "method", &method, "path", &path, "headers?", &headersDict, "body?", &body); err != nil {
+		return nil, err
+	}
+	headers := make(map[string]string)
+	if headersDict != nil {
+		var err error
+		if headers, err = convertStarlarkDictToStringMap(headersDict); err != nil {
+			return nil, fmt.Errorf("%s: invalid headers: %w", builtin.Name(), err)
+		}
+	}
+	if body != "" {
+		if _, exist := headers["Content-Type"]; !exist {
+			headers["Content-Type"] = "application/json"
+		}
+	}
+	ctx, ok := thread.Local(scenarioHookContextKey).(context.Context)
+	if !ok {
+		ctx = context.Background()
+	}
+	statusCode, _, respBody, err := r.HTTPClient.PerformRequest(ctx, path, strings.ToUpper(method), headers, nil, nil, []byte(body))
+	if err != nil {
+		return nil, fmt.Errorf("%s: failed to perform request %s %s: %w", builtin.Name(), method, path, err)
+	}
+	result := starlark.NewDict(2)
+	result.SetKey(starlark.String("statusCode"), starlark.MakeInt(statusCode))
+	result.SetKey(starlark.String("body"), starlark.String(respBody))
+	return result, nil
+}
+
+// convertStarlarkDictToStringMap converts a Starlark Dict of strings to a map of strings.
+func convertStarlarkDictToStringMap(dict *starlark.Dict) (map[string]string, error) {
+	result := make(map[string]string, dict.Len())
+	for _, item := range dict.Items() {
+		key, ok := starlark.AsString(item[0])
+		if !ok {
+			return nil, fmt.Errorf("key %s is not a string", item[0])
+		}
+		value, ok := starlark.AsString(item[1])
+		if !ok {
+			return nil, fmt.Errorf("value of key %s is not a string", key)
+		}
+		result[key] = value
+	}
+	return result, nil
+}
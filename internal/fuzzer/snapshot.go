@@ -1,7 +1,9 @@
 package fuzzer
 
 // FuzzingSnapshot represents a snapshot of the fuzzing process.
-// It includes metrics such as runtime call info graph edge coverage and the count of covered status codes.
+// It includes metrics such as runtime call info graph edge coverage, the count of covered status codes,
+// the count of span errors (i.e., (service, operation, error type) tuples from span status and exceptions),
+// and the count of database operations (i.e., (service, table, operation) tuples from database spans).
 // TODO: Add more metrics. @xunzhou24
 type FuzzingSnapshot struct {
 	// CallInfoGraphEdgeCoveredCount is the number of edges covered in the runtime call info graph.
@@ -9,6 +11,18 @@ type FuzzingSnapshot struct {
 
 	// CoveredStatusCodeCount is the number of unique status codes covered during fuzzing.
 	CoveredStatusCodeCount int `json:"coveredStatusCodeCount"`
+
+	// CoveredSpanErrorCount is the number of unique span errors covered during fuzzing.
+	CoveredSpanErrorCount int `json:"coveredSpanErrorCount"`
+
+	// CoveredDatabaseOperationCount is the number of unique database operations covered during fuzzing.
+	CoveredDatabaseOperationCount int `json:"coveredDatabaseOperationCount"`
+
+	// CoveredCodeLineCount is the number of covered lines of services under test, collected by coverage collectors.
+	CoveredCodeLineCount int `json:"coveredCodeLineCount"`
+
+	// CoveredCodeBranchCount is the number of covered branches of services under test, collected by coverage collectors.
+	CoveredCodeBranchCount int `json:"coveredCodeBranchCount"`
 }
 
 // NewFuzzingSnapshot creates a new FuzzingSnapshot.
@@ -16,12 +30,17 @@ func NewFuzzingSnapshot() *FuzzingSnapshot {
 	return &FuzzingSnapshot{
 		CallInfoGraphEdgeCoveredCount: 0,
 		CoveredStatusCodeCount:   0,
+		CoveredSpanErrorCount:    0,
+		CoveredDatabaseOperationCount: 0,
+		CoveredCodeLineCount:          0,
+		CoveredCodeBranchCount:        0,
 	}
 }
 
-// Update updates the snapshot with the edge coverage and the count of covered status codes.
+// Update updates the snapshot with the edge coverage, the count of covered status codes, the count of covered span errors,
+// and the count of covered database operations.
 // It returns whether the update is successful and a higher coverage is achieved.
-func (s *FuzzingSnapshot) Update(edgeCoveredCount int, statusCodeCount int) bool {
+func (s *FuzzingSnapshot) Update(edgeCoveredCount int, statusCodeCount int, spanErrorCount int, databaseOperationCount int) bool {
 	ret := false
 	if edgeCoveredCount > s.CallInfoGraphEdgeCoveredCount {
 		ret = true
@@ -31,5 +50,29 @@ func (s *FuzzingSnapshot) Update(edgeCoveredCount int, statusCodeCount int) bool
 		ret = true
 		s.CoveredStatusCodeCount = statusCodeCount
 	}
+	if spanErrorCount > s.CoveredSpanErrorCount {
+		ret = true
+		s.CoveredSpanErrorCount = spanErrorCount
+	}
+	if databaseOperationCount > s.CoveredDatabaseOperationCount {
+		ret = true
+		s.CoveredDatabaseOperationCount = databaseOperationCount
+	}
+	return ret
+}
+
+// UpdateCodeCoverage updates the snapshot with the counts of covered lines and branches of services under test.
+// It is separated from Update, as code coverage is collected per scenario rather than per operation.
+// It returns whether a higher coverage is achieved.
+func (s *FuzzingSnapshot) UpdateCodeCoverage(coveredLineCount int, coveredBranchCount int) bool {
+	ret := false
+	if coveredLineCount > s.CoveredCodeLineCount {
+		ret = true
+		s.CoveredCodeLineCount = coveredLineCount
+	}
+	if coveredBranchCount > s.CoveredCodeBranchCount {
+		ret = true
+		s.CoveredCodeBranchCount = coveredBranchCount
+	}
 	return ret
 }
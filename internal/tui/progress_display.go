@@ -0,0 +1,187 @@
+// Package tui provides a terminal UI showing the progress of fuzzing live, instead of raw log lines.
+package tui
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"resttracefuzzer/internal/fuzzer"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cloudwego/hertz/pkg/protocol/consts"
+)
+
+const (
+	// DefaultRefreshInterval is the default interval of refreshing the progress display.
+	DefaultRefreshInterval = 500 * time.Millisecond
+
+	// progressBarWidth is the width of the progress bar of elapsed budget, in characters.
+	progressBarWidth = 30
+
+	// maxScenarioLineWidth is the maximal width of the line of the current scenario, longer ones are truncated.
+	maxScenarioLineWidth = 120
+
+	// ANSI escape sequences used to refresh the display in place.
+	ansiHideCursor = "\033[?25l"
+	ansiShowCursor = "\033[?25h"
+	ansiClearLine  = "\r\033[K"
+)
+
+// ProgressDisplay renders the progress of fuzzing (see [fuzzer.FuzzingProgress]) in the terminal periodically, refreshing in place.
+// It shows elapsed budget, request rate, responses of each status code class, coverage and the current test scenario.
+// Logs should not be written to the terminal while it is running, otherwise they would be overwritten.
+type ProgressDisplay struct {
+	// Output is the terminal to render to.
+	Output io.Writer
+
+	// GetProgress gets the current progress to render.
+	GetProgress func() fuzzer.FuzzingProgress
+
+	// RefreshInterval is the interval of refreshing the display.
+	RefreshInterval time.Duration
+
+	// renderedLineCount is the number of lines of the last rendering, which are overwritten by the next one.
+	renderedLineCount int
+
+	// lastRequestCount and lastRenderTime are the request count and time of the last rendering, to calculate the current request rate.
+	lastRequestCount int
+	lastRenderTime   time.Time
+
+	// stopChan is closed to stop the display, and doneChan is closed once it is stopped.
+	stopChan, doneChan chan struct{}
+
+	// stopOnce makes Stop idempotent.
+	stopOnce sync.Once
+}
+
+// NewProgressDisplay creates a new ProgressDisplay rendering progress got by getProgress to output.
+// If refreshInterval is not positive, [DefaultRefreshInterval] is used.
+func NewProgressDisplay(output io.Writer, getProgress func() fuzzer.FuzzingProgress, refreshInterval time.Duration) *ProgressDisplay {
+	if refreshInterval <= 0 {
+		refreshInterval = DefaultRefreshInterval
+	}
+	return &ProgressDisplay{
+		Output:          output,
+		GetProgress:     getProgress,
+		RefreshInterval: refreshInterval,
+		stopChan:        make(chan struct{}),
+		doneChan:        make(chan struct{}),
+	}
+}
+
+// Start starts refreshing the display in background, until Stop is called.
+func (d *ProgressDisplay) Start() {
+	fmt.Fprint(d.Output, ansiHideCursor)
+	d.lastRenderTime = time.Now()
+	go func() {
+		defer close(d.doneChan)
+		ticker := time.NewTicker(d.RefreshInterval)
+		defer ticker.Stop()
+		d.render()
+		for {
+			select {
+			case <-d.stopChan:
+				return
+			case <-ticker.C:
+				d.render()
+			}
+		}
+	}()
+}
+
+// Stop stops refreshing the display, and renders the final progress, which is kept in the terminal.
+func (d *ProgressDisplay) Stop() {
+	d.stopOnce.Do(func() {
+		close(d.stopChan)
+		<-d.doneChan
+		d.render()
+		fmt.Fprint(d.Output, ansiShowCursor)
+	})
+}
+
+// render renders the current progress, overwriting the last rendering.
+func (d *ProgressDisplay) render() {
+	progress := d.GetProgress()
+	now := time.Now()
+	currentRate := 0.0
+	if elapsed := now.Sub(d.lastRenderTime).Seconds(); elapsed > 0 {
+		currentRate = float64(progress.RequestCount-d.lastRequestCount) / elapsed
+	}
+	d.lastRequestCount, d.lastRenderTime = progress.RequestCount, now
+
+	lines := FormatProgress(progress, currentRate)
+	var sb strings.Builder
+	if d.renderedLineCount > 0 {
+		fmt.Fprintf(&sb, "\033[%dA", d.renderedLineCount)
+	}
+	for _, line := range lines {
+		sb.WriteString(ansiClearLine)
+		sb.WriteString(line)
+		sb.WriteString("\n")
+	}
+	// Clear remaining lines of the last rendering, if it is longer.
+	for i := len(lines); i < d.renderedLineCount; i++ {
+		sb.WriteString(ansiClearLine + "\n")
+	}
+	fmt.Fprint(d.Output, sb.String())
+	d.renderedLineCount = max(len(lines), d.renderedLineCount)
+}
+
+// FormatProgress formats the progress as lines of the display, with the current request rate (requests per second).
+func FormatProgress(progress fuzzer.FuzzingProgress, currentRate float64) []string {
+	elapsed := progress.GetElapsedTime()
+	ratio := 0.0
+	if progress.Budget > 0 {
+		ratio = min(elapsed.Seconds()/progress.Budget.Seconds(), 1)
+	}
+	filledWidth := int(ratio * progressBarWidth)
+	progressBar := strings.Repeat("#", filledWidth) + strings.Repeat(".", progressBarWidth-filledWidth)
+	averageRate := 0.0
+	if elapsed > 0 {
+		averageRate = float64(progress.RequestCount) / elapsed.Seconds()
+	}
+
+	statusClasses := []struct {
+		name  string
+		class int
+	}{
+		{"1xx", consts.StatusContinue},
+		{"2xx", consts.StatusOK},
+		{"3xx", consts.StatusMultipleChoices},
+		{"4xx", consts.StatusBadRequest},
+		{"5xx", consts.StatusInternalServerError},
+		{"failed", 0},
+	}
+	statusCounts := make([]string, 0, len(statusClasses))
+	for _, statusClass := range statusClasses {
+		statusCounts = append(statusCounts, fmt.Sprintf("%s %d", statusClass.name, progress.StatusClassCounts[statusClass.class]))
+	}
+
+	currentScenario := "-"
+	if progress.CurrentScenarioUUID != "" {
+		currentScenario = fmt.Sprintf("%s  %s", progress.CurrentScenarioUUID[:min(8, len(progress.CurrentScenarioUUID))], strings.Join(progress.CurrentScenarioOperations, " -> "))
+	}
+	if len(currentScenario) > maxScenarioLineWidth {
+		currentScenario = currentScenario[:maxScenarioLineWidth-3] + "..."
+	}
+
+	return []string{
+		fmt.Sprintf("Elapsed      %s / %s [%s] %.1f%%", elapsed.Truncate(time.Second), progress.Budget, progressBar, ratio*100),
+		fmt.Sprintf("Requests     %d total, %.1f req/s (average %.1f req/s)", progress.RequestCount, currentRate, averageRate),
+		fmt.Sprintf("Responses    %s", strings.Join(statusCounts, "  ")),
+		fmt.Sprintf("Coverage     edges %d (%.1f%%), status codes %d", progress.EdgeCoveredCount, progress.EdgeCoverage*100, progress.CoveredStatusCodeCount),
+		fmt.Sprintf("Scenarios    %d executed", progress.ScenarioCount),
+		fmt.Sprintf("Current      %s", currentScenario),
+	}
+}
+
+// IsTerminal returns whether the file is a terminal (character device), where the display can be refreshed in place.
+func IsTerminal(file *os.File) bool {
+	fileInfo, err := file.Stat()
+	if err != nil {
+		return false
+	}
+	return fileInfo.Mode()&os.ModeCharDevice != 0
+}
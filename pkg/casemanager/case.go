@@ -3,11 +3,13 @@ package casemanager
 import (
 	"resttracefuzzer/pkg/resource"
 	"resttracefuzzer/pkg/static"
+	"resttracefuzzer/pkg/strategy"
 	"resttracefuzzer/pkg/utils"
 	"resttracefuzzer/pkg/utils/http"
 	"strings"
 
 	"maps"
+	"slices"
 
 	"github.com/getkin/kin-openapi/openapi3"
 	"github.com/google/uuid"
@@ -69,6 +71,14 @@ type OperationCase struct {
 	// It is a json object as a byte array.
 	ResponseBody []byte `json:"responseBody"`
 
+	// ResponseTime is the time taken by the last attempt of the request (i.e., excluding retries and waits for rate limits), in milliseconds.
+	// It is 0 if the operation case is not executed.
+	ResponseTime float64 `json:"responseTimeMs"`
+
+	// TraceID is the ID of the trace produced by the request, i.e., returned in the response headers, or started by the client or the mesh headers.
+	// It is empty if the request is not executed, or no trace ID is found.
+	TraceID string `json:"traceID,omitempty"`
+
 	// RequestPathParamResources is the resource representation of the path parameters.
 	// It is used to generate or mutate the request path parameters.
 	// The field would not be json encoded.
@@ -95,6 +105,26 @@ type OperationCase struct {
 
 	// UUID is the unique identifier of the test operation case.
 	UUID uuid.UUID `json:"uuid"`
+
+	// UserName is the name of the user session (see [UserSession]) the request is sent as.
+	// It is empty if no user session is configured.
+	UserName string `json:"userName,omitempty"`
+
+	// LowConfidenceReasons tell why the request is generated on a best-effort basis, e.g., a parameter or request body declares no schema.
+	// The request is of low confidence if it is not empty, see [OperationCase.IsLowConfidence].
+	LowConfidenceReasons []string `json:"lowConfidenceReasons,omitempty"`
+
+	// NegativeMode tells whether the request is populated in negative mode, i.e., biased toward error responses, see [NegativeModeSelector].
+	NegativeMode bool `json:"negativeMode,omitempty"`
+
+	// ExtensionRelations are the producer-consumer relations justifying extending the test scenario with the operation case.
+	// It is empty for operation cases not appended by extension (e.g., initial ones), or appended randomly.
+	ExtensionRelations []ProducerConsumerRelation `json:"extensionRelations,omitempty"`
+
+	// WeightMapSelections are the keys of adaptive weight maps (e.g., value sources) selected when populating the request,
+	// which are rewarded by coverage feedback of the request, see [strategy.AdaptiveWeightMapStrategy].
+	// They are not copied, as the request is populated again before each execution.
+	WeightMapSelections []*strategy.WeightMapSelection `json:"-"`
 }
 
 // A TestScenario is a sequence of [resttracefuzzer/pkg/casemanager/OperationCase].
@@ -112,6 +142,14 @@ type TestScenario struct {
 
 	// UUID is the unique identifier of the test scenario.
 	UUID uuid.UUID `json:"uuid"`
+
+	// ChaosExperiment is the name of the chaos experiment running during current execution of the test scenario.
+	// It is empty if no chaos experiment is running, and it is not copied, as it only tags one execution.
+	ChaosExperiment string `json:"chaosExperiment,omitempty"`
+
+	// Injected is whether the test scenario is injected by a user (see [CaseManager.InjectOperationCase]) and not executed yet.
+	// Requests of an injected scenario are sent as crafted instead of being populated, and it is not copied, as it only applies to the first execution.
+	Injected bool `json:"injected,omitempty"`
 }
 
 // NewTestScenario creates a new TestScenario.
@@ -253,6 +291,8 @@ func (oc *OperationCase) Copy() *OperationCase {
 		ResponseHeaders:    responseHeaders,
 		ResponseStatusCode: oc.ResponseStatusCode,
 		ResponseBody:       responseBody,
+		ResponseTime:       oc.ResponseTime,
+		TraceID:            oc.TraceID,
 
 		RequestPathParamResources:  requestPathParamResources,
 		RequestQueryParamResources: requestQueryParamResources,
@@ -261,9 +301,19 @@ func (oc *OperationCase) Copy() *OperationCase {
 		Energy:                   oc.Energy,
 		ExecutedCount:            oc.ExecutedCount,
 		UUID:                     oc.UUID,
+		UserName:                 oc.UserName,
+		LowConfidenceReasons:     slices.Clone(oc.LowConfidenceReasons),
+		NegativeMode:             oc.NegativeMode,
+		ExtensionRelations:       slices.Clone(oc.ExtensionRelations),
 	}
 }
 
+// IsLowConfidence checks whether the request of the operation case is generated on a best-effort basis,
+// i.e., some of its parameters or request body declare no schema.
+func (oc *OperationCase) IsLowConfidence() bool {
+	return len(oc.LowConfidenceReasons) > 0
+}
+
 // Reset resets the test operation case.
 // It resets the executed count and energy to 0, and gives the test operation case a new UUID.
 func (oc *OperationCase) Reset() {
@@ -282,20 +332,9 @@ func (oc *OperationCase) Reset() {
 func (oc *OperationCase) SetRequestPathParamsByResources(resources map[string]resource.Resource) {
 	requestPathParams := make(map[string]string)
 	for key, resrc := range resources {
-		var valueStr string
 		// For array type, we need to convert the array to string.
 		// For example, if the array is [1, 2, 3], we convert it to "1,2,3", instead of json-style (e.g., "[1,2,3]").
-		if resrc.Typ() == static.SimpleAPIPropertyTypeArray {
-			valueList := make([]string, 0)
-			for _, v := range resrc.(*resource.ResourceArray).Value {
-				vStr := v.String()
-				valueList = append(valueList, vStr)
-			}
-			valueStr = strings.Join(valueList, ",")
-		} else {
-			valueStr = resrc.String()
-		}
-		requestPathParams[key] = valueStr
+		requestPathParams[key] = strategy.PathParamResourceString(resrc)
 	}
 	oc.RequestPathParams = requestPathParams
 	oc.RequestPathParamResources = resources
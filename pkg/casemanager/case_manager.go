@@ -1,6 +1,8 @@
 package casemanager
 
 import (
+	"cmp"
+	"context"
 	"fmt"
 	"math/rand/v2"
 	"resttracefuzzer/internal/config"
@@ -8,29 +10,36 @@ import (
 	fuzzruntime "resttracefuzzer/pkg/runtime"
 	"resttracefuzzer/pkg/static"
 	"resttracefuzzer/pkg/strategy"
+	"resttracefuzzer/pkg/utils"
 	"sort"
 
 	"maps"
 
 	"slices"
+	"sync"
+	"time"
 
 	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/google/uuid"
 	"github.com/rs/zerolog/log"
 )
 
+// minCandidateConfidenceWeight is the minimum weight of candidates to extend test scenarios with, see [CaseManager.selectCandidateByConfidence].
+const minCandidateConfidenceWeight = 0.01
+
 // CaseManager manages the test cases.
 type CaseManager struct {
-	// TestScenarios is a list of test scenarios.
+	// TestScenarios is the priority queue of test scenarios, keyed by energy (if energy function is enabled in config), see [utils.PriorityQueue].
 	// In each loop of testing, a test scenario will be popped from the queue and executed.
 	// The test scenario is a combination of one or multiple operations.
-	TestScenarios []*TestScenario
+	TestScenarios *utils.PriorityQueue[*TestScenario]
 
 	// TestOperationCaseQueueMap is a map of test operation case queue.
-	// The key is the API method, and the value is a list of test operation cases.
+	// The key is the API method, and the value is a priority queue of test operation cases, keyed by energy like TestScenarios.
 	// Only cases that have already been executed will be put into its queue.
 	// So each operation case should have a non-empty request parameters, and energy as well.
 	// It is used to get a new operation case to execute when extending a test scenario.
-	TestOperationCaseQueueMap map[static.SimpleAPIMethod][]*OperationCase
+	TestOperationCaseQueueMap map[static.SimpleAPIMethod]*utils.PriorityQueue[*OperationCase]
 
 	// The API manager.
 	APIManager *static.APIManager
@@ -47,10 +56,60 @@ type CaseManager struct {
 	// The runtime reachability map. It is used to track the reachability of system components at runtime, and enhance the producer-consumer relationship.
 	RuntimeReachabilityMap *fuzzruntime.RuntimeReachabilityMap
 
+	// CallInfoGraph is the runtime call info graph, which guides the selection of candidates to extend test scenarios with toward uncovered edges,
+	// see [CaseManager.getCandidateDistanceWeights]. It can be nil, i.e., candidates are not guided by distance.
+	CallInfoGraph *fuzzruntime.CallInfoGraph
+
 	// GlobalExtraHeaders is the global extra headers, which will be added to each request.
 	// It is a map of header name to header value.
 	// It can be used for simple cases, e.g., adding an authorization header.
 	GlobalExtraHeaders map[string]string
+
+	// OperationHeaderRules add extra headers to requests of matched API methods, overriding GlobalExtraHeaders, see [OperationHeaderRule].
+	OperationHeaderRules []*OperationHeaderRule
+
+	// UserSessions are the identities requests can be sent as, see [UserSession].
+	// Test scenarios are executed as the first (primary) one. It is empty if no user session is configured.
+	UserSessions []*UserSession
+
+	// ExtensionPolicy resolves candidate API methods when extending a test scenario, see [ScenarioExtensionPolicy].
+	ExtensionPolicy ScenarioExtensionPolicy
+
+	// fallbackExtensionPolicy resolves candidates if ExtensionPolicy resolves none, i.e., a random API method.
+	fallbackExtensionPolicy ScenarioExtensionPolicy
+
+	// Rand is the random number generator for case selection, which is [utils.SharedRand] by default.
+	Rand *rand.Rand
+
+	// RelationTracker tracks the producer-consumer relations actually used to extend test scenarios, see [ProducerConsumerRelation].
+	RelationTracker *ProducerConsumerRelationTracker
+
+	// NegativeModeSelector decides which operation cases are populated in negative mode. It can be nil, i.e., never.
+	NegativeModeSelector NegativeModeSelector
+
+	// MeshHeaderPropagator adds service mesh headers to each request, see [MeshHeaderPropagator]. It can be nil, i.e., no mesh header is added.
+	MeshHeaderPropagator *MeshHeaderPropagator
+
+	// EnergyDecayHalfLife is the time in which the energy of queued test scenarios and operation cases is halved, see [CaseManager.DecayEnergy].
+	// Energy does not decay if it is not positive.
+	EnergyDecayHalfLife time.Duration
+
+	// lastEnergyDecayTime is the time energy was last decayed.
+	lastEnergyDecayTime time.Time
+
+	// injectedScenarios are the test scenarios injected by [CaseManager.InjectOperationCase], which are popped before TestScenarios, in injection order.
+	injectedScenarios []*TestScenario
+
+	// queueMu protects TestScenarios, TestOperationCaseQueueMap and injectedScenarios,
+	// as they are inspected and seeded by the control API while fuzzing.
+	queueMu sync.Mutex
+}
+
+// NegativeModeSelector decides whether to populate an operation case in negative mode,
+// where generated values are biased toward error responses, e.g., violating schema constraints.
+type NegativeModeSelector interface {
+	// ShouldUseNegativeMode returns whether to populate an operation case of the API method in negative mode.
+	ShouldUseNegativeMode(APIMethod static.SimpleAPIMethod) bool
 }
 
 // NewCaseManager creates a new CaseManager.
@@ -60,50 +119,96 @@ func NewCaseManager(
 	fuzzStrategist *strategy.FuzzStrategist,
 	resourceMutateStrategy *strategy.ResourceMutateStrategy,
 	runtimeReachabilityMap *fuzzruntime.RuntimeReachabilityMap,
+	callInfoGraph *fuzzruntime.CallInfoGraph,
 	globalExtraHeaders map[string]string,
+	operationHeaderRules []*OperationHeaderRule,
+	userSessions []*UserSession,
+	extensionPolicy ScenarioExtensionPolicy,
+	negativeModeSelector NegativeModeSelector,
+	meshHeaderPropagator *MeshHeaderPropagator,
 ) *CaseManager {
-	testScenarios := make([]*TestScenario, 0)
-	testOperationCaseQueueMap := make(map[static.SimpleAPIMethod][]*OperationCase)
+	var scenarioEnergyOf func(*TestScenario) int
+	if config.GlobalConfig.EnableEnergyScenario {
+		scenarioEnergyOf = func(testScenario *TestScenario) int { return testScenario.Energy }
+	}
+	testScenarios := utils.NewPriorityQueue(scenarioEnergyOf)
+	testOperationCaseQueueMap := make(map[static.SimpleAPIMethod]*utils.PriorityQueue[*OperationCase])
 	m := &CaseManager{
 		APIManager:                APIManager,
 		ResourceManager:           resourceManager,
 		FuzzStrategist:            fuzzStrategist,
 		ResourceMutateStrategy:    resourceMutateStrategy,
 		RuntimeReachabilityMap:    runtimeReachabilityMap,
+		CallInfoGraph:             callInfoGraph,
 		TestScenarios:             testScenarios,
 		GlobalExtraHeaders:        globalExtraHeaders,
+		OperationHeaderRules:      operationHeaderRules,
+		UserSessions:              userSessions,
 		TestOperationCaseQueueMap: testOperationCaseQueueMap,
+		ExtensionPolicy:           extensionPolicy,
+		fallbackExtensionPolicy:   NewRandomScenarioExtensionPolicy(APIManager),
+		Rand:                      utils.SharedRand,
+		RelationTracker:           NewProducerConsumerRelationTracker(),
+		NegativeModeSelector:      negativeModeSelector,
+		MeshHeaderPropagator:      meshHeaderPropagator,
+		EnergyDecayHalfLife:       time.Duration(config.GlobalConfig.EnergyDecayHalfLife) * time.Second,
 	}
 	m.initTestcasesFromDoc()
 	return m
 }
 
 // Pop pops a test scenario of highest priority from the queue.
+// Injected test scenarios (see [CaseManager.InjectOperationCase]) are popped first.
 func (m *CaseManager) Pop() (*TestScenario, error) {
-	// Select the first test scenario, as we have implemented the priority mechanism in the pushAndSort method.
-	if len(m.TestScenarios) == 0 {
+	m.queueMu.Lock()
+	defer m.queueMu.Unlock()
+	if testScenario, ok := m.popInjectedScenario(); ok {
+		return testScenario, nil
+	}
+	testScenario, ok := m.TestScenarios.Pop()
+	if !ok {
 		log.Error().Msg("[CaseManager.Pop] No test scenario available")
 		return nil, fmt.Errorf("no test scenario available")
 	}
-	testScenario := m.TestScenarios[0]
-	m.TestScenarios = m.TestScenarios[1:]
 	return testScenario, nil
 }
 
 // PopAndPopulate pops a test scenario of highest priority from the case manager
 // and populates the request part, including the headers, params and request body.
-func (m *CaseManager) PopAndPopulate() (*TestScenario, error) {
+// Population stops (and the scenario is dropped) if ctx is done.
+// Operation cases may be populated in negative mode, see [NegativeModeSelector].
+// Requests of injected test scenarios are kept as crafted, and only headers are added (see [CaseManager.InjectOperationCase]).
+func (m *CaseManager) PopAndPopulate(ctx context.Context) (*TestScenario, error) {
 	testScenario, err := m.Pop()
 	if err != nil {
 		log.Err(err).Msg("[CaseManager.PopAndFillRequest] Failed to pop a test scenario")
 		return nil, err
 	}
+	defer m.FuzzStrategist.SetNegativeMode(false)
+	defer m.FuzzStrategist.SetSchemaRefScope("")
+	defer m.FuzzStrategist.SetSchemaPaths()
 
-	for _, operationCase := range testScenario.OperationCases {
+	for i, operationCase := range testScenario.OperationCases {
+		if ctx.Err() != nil {
+			log.Warn().Msgf("[CaseManager.PopAndPopulate] Context done, stop populating scenario, scenario UUID: %s", testScenario.UUID.String())
+			return nil, ctx.Err()
+		}
 		log.Debug().Msgf("[CaseManager.PopAndPopulate] Start to populate request for operation %v", operationCase.APIMethod)
+		if testScenario.Injected {
+			requestHeaders := m.buildRequestHeaders(testScenario, i, operationCase)
+			maps.Copy(requestHeaders, operationCase.RequestHeaders)
+			operationCase.RequestHeaders = requestHeaders
+			continue
+		}
+		// Discard selections of adaptive weight maps made outside population.
+		m.takeWeightMapSelections()
+		operationCase.NegativeMode = m.NegativeModeSelector != nil && m.NegativeModeSelector.ShouldUseNegativeMode(operationCase.APIMethod)
+		m.FuzzStrategist.SetNegativeMode(operationCase.NegativeMode)
+		// Values are requested from resources scoped to the entity of the operation first, e.g., `Pet` for `petId` of `GET /pets/{petId}`.
+		m.FuzzStrategist.SetSchemaRefScope(utils.GetOperationEntitySchemaRef(operationCase.Operation))
 		// fill the request path and query params
 		requestParamsDef := operationCase.Operation.Parameters
-		requestPathParamResources, requestQueryParamResources, err := m.generateRequestParamResourcesFromSchema(requestParamsDef)
+		requestPathParamResources, requestQueryParamResources, lowConfidenceReasons, err := m.generateRequestParamResourcesFromSchema(operationCase.APIMethod, requestParamsDef)
 		if err != nil {
 			log.Err(err).Msg("[CaseManager.PopAndFillRequest] Failed to generate request param resources")
 			return nil, err
@@ -111,106 +216,202 @@ func (m *CaseManager) PopAndPopulate() (*TestScenario, error) {
 		operationCase.SetRequestPathParamsByResources(requestPathParamResources)
 		operationCase.SetRequestQueryParamsByResources(requestQueryParamResources)
 
-		// fill the request headers, including global extra headers and operation specific headers
-		requestHeaders := make(map[string]string)
-		// Add global extra headers
-		maps.Copy(requestHeaders, m.GlobalExtraHeaders)
-		// Add operation specific headers
-		operationCase.RequestHeaders = requestHeaders
+		// fill the request headers, including global extra headers, headers of operation header rules and operation specific headers
+		operationCase.RequestHeaders = m.buildRequestHeaders(testScenario, i, operationCase)
 
 		// fill the request body
 		requestBodySchema := operationCase.Operation.RequestBody
 		if requestBodySchema != nil {
-			requestBodyResrc, err := m.generateRequestBodyResourceFromSchema(requestBodySchema)
+			requestBodyResrc, bodyLowConfidenceReason, err := m.generateRequestBodyResourceFromSchema(operationCase.APIMethod, requestBodySchema)
 			if err != nil {
 				log.Err(err).Msgf("[CaseManager.PopAndFillRequest] Failed to generate request body resource, scenario UUID: %s", testScenario.UUID.String())
 				return nil, err
 			}
 			operationCase.SetRequestBodyByResource(requestBodyResrc)
+			if bodyLowConfidenceReason != "" {
+				lowConfidenceReasons = append(lowConfidenceReasons, bodyLowConfidenceReason)
+			}
+		}
+		operationCase.LowConfidenceReasons = lowConfidenceReasons
+		operationCase.WeightMapSelections = m.takeWeightMapSelections()
+		if operationCase.IsLowConfidence() {
+			log.Debug().Msgf("[CaseManager.PopAndPopulate] Request of operation %v is of low confidence: %v", operationCase.APIMethod, lowConfidenceReasons)
 		}
 	}
 	return testScenario, nil
 }
 
-// pushAndSort pushes a test scenario to the case manager and sorts the test scenarios by energy (if energy function is enabled in config).
-// It also culls the test scenarios if there are too many.
-func (m *CaseManager) pushAndSort(testcase *TestScenario) {
-	m.push(testcase)
-	m.sortAndCullByEnergy()
+// buildRequestHeaders builds the request headers of the i-th operation case of the test scenario,
+// including service mesh headers, configured headers (global extra headers and headers of matched operation header rules, with templates resolved), credential headers of the primary user session (whose name is set to the operation case) and operation specific headers.
+func (m *CaseManager) buildRequestHeaders(testScenario *TestScenario, i int, operationCase *OperationCase) map[string]string {
+	requestHeaders := make(map[string]string)
+	// Add service mesh headers, which can be overridden by configured headers
+	if m.MeshHeaderPropagator != nil {
+		maps.Copy(requestHeaders, m.MeshHeaderPropagator.BuildHeaders(testScenario, i))
+	}
+	// Add configured headers, whose templates are resolved at each population
+	maps.Copy(requestHeaders, m.buildConfiguredHeaders(operationCase.APIMethod))
+	// Add credential headers of primary user session
+	if primaryUserSession := m.GetPrimaryUserSession(); primaryUserSession != nil {
+		maps.Copy(requestHeaders, primaryUserSession.Headers)
+		operationCase.UserName = primaryUserSession.Name
+	}
+	// Add operation specific headers
+	// A fresh idempotency key is generated for each population, and kept across retries of the request.
+	if idempotencyKeyHeader := static.GetIdempotencyKeyHeader(operationCase.Operation); idempotencyKeyHeader != "" {
+		requestHeaders[idempotencyKeyHeader] = uuid.NewString()
+	}
+	return requestHeaders
 }
 
-// push adds a test case to the case manager.
-func (m *CaseManager) push(testcase *TestScenario) {
-	m.TestScenarios = append(m.TestScenarios, testcase)
+// NewDeletionOperationCase creates an operation case of the DELETE API method with the path parameters, to delete a resource outside test scenarios,
+// e.g., in the cleanup after fuzzing. Its request carries configured headers and credential headers of the primary user session, as those of test scenarios do.
+// It is not put into any queue.
+func (m *CaseManager) NewDeletionOperationCase(APIMethod static.SimpleAPIMethod, pathParams map[string]string) (*OperationCase, error) {
+	operation, exists := m.APIManager.GetOperationByMethod(APIMethod)
+	if !exists {
+		return nil, fmt.Errorf("operation %s %s not found", APIMethod.Method, APIMethod.Endpoint)
+	}
+	operationCase := NewOperationCase(APIMethod, operation)
+	requestHeaders := m.buildConfiguredHeaders(APIMethod)
+	if primaryUserSession := m.GetPrimaryUserSession(); primaryUserSession != nil {
+		maps.Copy(requestHeaders, primaryUserSession.Headers)
+		operationCase.UserName = primaryUserSession.Name
+	}
+	operationCase.RequestHeaders = requestHeaders
+	operationCase.RequestPathParams = maps.Clone(pathParams)
+	operationCase.RequestQueryParams = make(map[string]string)
+	operationCase.ResponseHeaders = make(map[string]string)
+	return operationCase, nil
 }
 
-// sortAndCullByEnergy sorts the test scenarios by energy and culls the test scenarios if there are too many.
-// If energy function is not enabled in config, it only culls the test scenarios.
-func (m *CaseManager) sortAndCullByEnergy() {
-	if config.GlobalConfig.EnableEnergyScenario {
-		sort.Slice(m.TestScenarios, func(i, j int) bool {
-			return m.TestScenarios[i].Energy > m.TestScenarios[j].Energy
-		})
+// takeWeightMapSelections takes keys selected from adaptive weight maps of the strategies since the last call, see [strategy.AdaptiveWeightMapStrategy].
+func (m *CaseManager) takeWeightMapSelections() []*strategy.WeightMapSelection {
+	weightMaps := make([]strategy.WeightMapStrategy, 0)
+	if m.FuzzStrategist != nil {
+		weightMaps = append(weightMaps, m.FuzzStrategist.SchemaToValueStrategy.ValueSourceWeightMap, m.FuzzStrategist.ResourceMutateStrategy.MutationPlanWeightMap)
 	}
-
-	if len(m.TestScenarios) > config.GlobalConfig.MaxAllowedScenarios {
-		m.TestScenarios = m.TestScenarios[:config.GlobalConfig.MaxAllowedScenarios]
+	if m.ResourceMutateStrategy != nil {
+		weightMaps = append(weightMaps, m.ResourceMutateStrategy.MutationPlanWeightMap)
+	}
+	selections := make([]*strategy.WeightMapSelection, 0)
+	for _, weightMap := range weightMaps {
+		adaptiveWeightMap, ok := weightMap.(*strategy.AdaptiveWeightMapStrategy)
+		if !ok {
+			continue
+		}
+		if selection := adaptiveWeightMap.TakeSelection(); selection != nil {
+			selections = append(selections, selection)
+		}
 	}
+	return selections
 }
 
-// pushAndSortOperationCase pushes a test operation case to the case manager and sorts the test operation cases by energy (if energy function is enabled in config).
-// It also culls the test operation cases if there are too many.
-func (m *CaseManager) pushAndSortOperationCase(operationCase *OperationCase) {
-	m.pushOperationCase(operationCase)
-	m.sortAndCullOperationCaseByEnergy()
+// pushAndCull pushes a test scenario to the case manager, in the order of energy (if energy function is enabled in config).
+// It also culls the test scenarios of lowest energy if there are too many.
+func (m *CaseManager) pushAndCull(testcase *TestScenario) {
+	m.TestScenarios.Push(testcase)
+	m.TestScenarios.Truncate(config.GlobalConfig.MaxAllowedScenarios)
 }
 
-// pushOperationCase adds a test operation case to the case manager.
-func (m *CaseManager) pushOperationCase(testcase *OperationCase) {
-	// Get the API method of the operation case.
-	apiMethod := testcase.APIMethod
+// pushAndCullOperationCase pushes a test operation case to the queue of its API method, in the order of energy (if energy function is enabled in config).
+// It also culls the test operation cases of lowest energy in the queue if there are too many.
+func (m *CaseManager) pushAndCullOperationCase(testcase *OperationCase) {
 	// Get the queue of the API method.
-	operationCaseQueue, exist := m.TestOperationCaseQueueMap[apiMethod]
+	operationCaseQueue, exist := m.TestOperationCaseQueueMap[testcase.APIMethod]
 	if !exist {
-		operationCaseQueue = make([]*OperationCase, 0)
+		operationCaseQueue = newOperationCaseQueue()
+		m.TestOperationCaseQueueMap[testcase.APIMethod] = operationCaseQueue
 	}
-	// Add the operation case to the queue.
-	// Check if the operation case is already in the queue before adding it.
-	for _, operationCase := range operationCaseQueue {
-		if operationCase.UUID == testcase.UUID {
-			return
-		}
+	// If the operation case is already in the queue, only its position is updated, as its energy may have changed.
+	if operationCaseQueue.FixFunc(func(operationCase *OperationCase) bool { return operationCase.UUID == testcase.UUID }) {
+		return
+	}
+	operationCaseQueue.Push(testcase)
+	operationCaseQueue.Truncate(config.GlobalConfig.MaxAllowedOperationCases)
+}
+
+// newOperationCaseQueue creates a queue of test operation cases, keyed by energy if energy function is enabled in config.
+func newOperationCaseQueue() *utils.PriorityQueue[*OperationCase] {
+	var energyOf func(*OperationCase) int
+	if config.GlobalConfig.EnableEnergyScenario {
+		energyOf = func(operationCase *OperationCase) int { return operationCase.Energy }
 	}
-	operationCaseQueue = append(operationCaseQueue, testcase)
-	// Update the queue in the map.
-	m.TestOperationCaseQueueMap[apiMethod] = operationCaseQueue
+	return utils.NewPriorityQueue(energyOf)
 }
 
-// sortAndCullOperationCaseByEnergy sorts the test operation cases by energy and culls the test operation cases if there are too many.
-func (m *CaseManager) sortAndCullOperationCaseByEnergy() {
+// GetOperationCaseQueues returns test operation cases in the queue of each API method, from the highest priority to the lowest.
+func (m *CaseManager) GetOperationCaseQueues() map[static.SimpleAPIMethod][]*OperationCase {
+	m.queueMu.Lock()
+	defer m.queueMu.Unlock()
+	operationCaseQueues := make(map[static.SimpleAPIMethod][]*OperationCase, len(m.TestOperationCaseQueueMap))
 	for apiMethod, operationCaseQueue := range m.TestOperationCaseQueueMap {
-		if config.GlobalConfig.EnableEnergyScenario {
-			sort.Slice(operationCaseQueue, func(i, j int) bool {
-				return operationCaseQueue[i].Energy > operationCaseQueue[j].Energy
-			})
-		}
-		if len(operationCaseQueue) > config.GlobalConfig.MaxAllowedOperationCases {
-			m.TestOperationCaseQueueMap[apiMethod] = operationCaseQueue[:config.GlobalConfig.MaxAllowedOperationCases]
-		}
+		operationCaseQueues[apiMethod] = operationCaseQueue.Values()
+	}
+	return operationCaseQueues
+}
+
+// EvictLowEnergyCases evicts the given ratio (0-1) of test scenarios and test operation cases (of each API method) with the lowest energy,
+// e.g., to reduce memory usage.
+// Energy is used even if energy function is not enabled in config, and the order of the remaining ones is kept.
+// It returns the number of evicted test scenarios and test operation cases.
+func (m *CaseManager) EvictLowEnergyCases(ratio float64) (int, int) {
+	m.queueMu.Lock()
+	defer m.queueMu.Unlock()
+	evictedScenarioCnt := evictLowEnergy(m.TestScenarios, ratio, func(ts *TestScenario) int { return ts.Energy })
+
+	evictedOperationCaseCnt := 0
+	for _, operationCaseQueue := range m.TestOperationCaseQueueMap {
+		evictedOperationCaseCnt += evictLowEnergy(operationCaseQueue, ratio, func(oc *OperationCase) int { return oc.Energy })
+	}
+	log.Info().Msgf("[CaseManager.EvictLowEnergyCases] Evicted %d test scenarios and %d test operation cases", evictedScenarioCnt, evictedOperationCaseCnt)
+	return evictedScenarioCnt, evictedOperationCaseCnt
+}
+
+// evictLowEnergy removes the given ratio of elements with the lowest energy from the queue, and returns the number of removed elements.
+// On ties of energy, later elements in the queue are removed first, and at least one element is kept if the queue is not empty.
+func evictLowEnergy[T comparable](queue *utils.PriorityQueue[T], ratio float64, energyOf func(T) int) int {
+	elems := queue.Values()
+	keepCnt := max(1, len(elems)-int(float64(len(elems))*ratio))
+	if len(elems) <= keepCnt {
+		return 0
+	}
+	slices.SortStableFunc(elems, func(a, b T) int {
+		return cmp.Compare(energyOf(b), energyOf(a))
+	})
+	evicted := make(map[T]struct{}, len(elems)-keepCnt)
+	for _, elem := range elems[keepCnt:] {
+		evicted[elem] = struct{}{}
 	}
+	return queue.DeleteFunc(func(elem T) bool {
+		_, exist := evicted[elem]
+		return exist
+	})
 }
 
-// GetScenarioSize returns the size of the test scenarios.
+// GetScenarioSize returns the size of the test scenarios, including injected ones not popped yet.
 func (m *CaseManager) GetScenarioSize() int {
-	return len(m.TestScenarios)
+	m.queueMu.Lock()
+	defer m.queueMu.Unlock()
+	return m.TestScenarios.Len() + len(m.injectedScenarios)
 }
 
 // EvaluateScenarioAndTryUpdate evaluates the given metrics for the given test scenario that has been executed,
 // determines whether to put the scenario back to the queue, and expand the scenario with an operation to a new scenario if needed.
 // It returns an error if any.
 func (m *CaseManager) EvaluateScenarioAndTryUpdate(hasAchieveNewCoverage bool, executedScenario *TestScenario) error {
+	m.queueMu.Lock()
+	defer m.queueMu.Unlock()
 	// Update the executed count and energy
 	executedScenario.ExecutedCount++
+	// Record execution results of the extensions, only for executed operation cases (see `execute_last_case_in_scenario_only`).
+	executedOperationCases := executedScenario.OperationCases
+	if config.GlobalConfig.ExecuteLastCaseInScenarioOnly && len(executedOperationCases) > 0 {
+		executedOperationCases = executedOperationCases[len(executedOperationCases)-1:]
+	}
+	for _, operationCase := range executedOperationCases {
+		m.RelationTracker.RecordExecution(operationCase.ExtensionRelations, operationCase.IsExecutedSuccessfully())
+	}
 	if hasAchieveNewCoverage {
 		executedScenario.IncreaseEnergyByRandom()
 	} else {
@@ -221,7 +422,7 @@ func (m *CaseManager) EvaluateScenarioAndTryUpdate(hasAchieveNewCoverage bool, e
 	// put it back to the queue.
 	if hasAchieveNewCoverage || executedScenario.ExecutedCount < config.GlobalConfig.MaxAllowedScenarioExecutedCount {
 		newScenario := executedScenario.Copy()
-		m.pushAndSort(newScenario)
+		m.pushAndCull(newScenario)
 	}
 
 	// Extend the scenario to generate a new one
@@ -230,7 +431,7 @@ func (m *CaseManager) EvaluateScenarioAndTryUpdate(hasAchieveNewCoverage bool, e
 		log.Err(err).Msg("[CaseManager.evaluateScenarioAndTryUpdate] Failed to process scenario")
 		// return err
 	} else if extendedScenario != nil {
-		m.pushAndSort(extendedScenario)
+		m.pushAndCull(extendedScenario)
 	}
 
 	return nil
@@ -240,6 +441,8 @@ func (m *CaseManager) EvaluateScenarioAndTryUpdate(hasAchieveNewCoverage bool, e
 // determines whether to put the operation to the queue.
 // It returns an error if any.
 func (m *CaseManager) EvaluateOperationCaseAndTryUpdate(hasAchieveNewCoverage bool, executedOperationCase *OperationCase) error {
+	m.queueMu.Lock()
+	defer m.queueMu.Unlock()
 	// Update the executed count and energy
 	executedOperationCase.ExecutedCount++
 	if hasAchieveNewCoverage {
@@ -247,11 +450,16 @@ func (m *CaseManager) EvaluateOperationCaseAndTryUpdate(hasAchieveNewCoverage bo
 	} else {
 		executedOperationCase.DecreaseEnergyByRandom()
 	}
+	// Reward the selections of adaptive weight maps used to populate the request, only once.
+	for _, selection := range executedOperationCase.WeightMapSelections {
+		selection.Reward(hasAchieveNewCoverage)
+	}
+	executedOperationCase.WeightMapSelections = nil
 
 	// If it has achieved new coverage or has not been executed for enough times,
 	// put it to the queue.
 	if hasAchieveNewCoverage || executedOperationCase.ExecutedCount < config.GlobalConfig.MaxAllowedOperationCaseExecutedCount {
-		m.pushAndSortOperationCase(executedOperationCase)
+		m.pushAndCullOperationCase(executedOperationCase)
 	}
 
 	return nil
@@ -279,11 +487,16 @@ func (m *CaseManager) extendScenarioIfExecSuccess(existingScenario *TestScenario
 
 	// Append a new operation.
 	// When generating a new operation case, we will try to get a operation from operation case queue (which is sorted by energy in advance).
-	candidateAPIMethods, err := m.resolveCandidateAPIMethods(newScenario)
+	candidateAPIMethods, candidateRelations, err := m.resolveCandidateAPIMethods(newScenario)
 	if err != nil {
 		log.Err(err).Msg("[CaseManager.extendScenarioIfExecSuccess] Failed to resolve candidate API methods")
 		return nil, err
 	}
+	// Preconditions (see [static.OperationPreconditionExtensionKey]) are hard constraints,
+	// so candidates whose preconditions are not in the scenario yet are excluded.
+	candidateAPIMethods = slices.DeleteFunc(candidateAPIMethods, func(apiMethod static.SimpleAPIMethod) bool {
+		return !arePreconditionsSatisfied(m.APIManager, newScenario, apiMethod)
+	})
 	if len(candidateAPIMethods) == 0 {
 		log.Warn().Msg("[CaseManager.extendScenarioIfExecSuccess] No candidates available for extending the scenario")
 		return nil, nil
@@ -295,10 +508,10 @@ func (m *CaseManager) extendScenarioIfExecSuccess(existingScenario *TestScenario
 		var operationCase *OperationCase
 		// First try to get the operation case from the queue.
 		operationCaseQueue, exist := m.TestOperationCaseQueueMap[apiMethod]
-		if exist && len(operationCaseQueue) > 0 {
+		if exist && operationCaseQueue.Len() > 0 {
 			// Get the first operation case (whose energy is the highest) from the queue.
 			// As it is picked from the queue only as a candidate, we do not remove it from the queue right now.
-			operationCase = operationCaseQueue[0]
+			operationCase, _ = operationCaseQueue.Peek()
 		} else {
 			// If the queue is empty, we need to create a new operation case.
 			operation, exist := m.APIManager.GetOperationByMethod(apiMethod)
@@ -318,7 +531,7 @@ func (m *CaseManager) extendScenarioIfExecSuccess(existingScenario *TestScenario
 	}
 	// Select the operation case with the highest energy from the candidate operation cases
 	// if energy function is enabled in config.
-	// Otherwise, we will randomly select one.
+	// Otherwise, we will randomly select one, weighted by the confidence of relations justifying it.
 	var newOperationCase *OperationCase
 	if config.GlobalConfig.EnableEnergyOperation {
 		sort.Slice(candidateOperationCases, func(i, j int) bool {
@@ -326,150 +539,167 @@ func (m *CaseManager) extendScenarioIfExecSuccess(existingScenario *TestScenario
 		})
 		newOperationCase = candidateOperationCases[0]
 	} else {
-		newOperationCase = candidateOperationCases[rand.IntN(len(candidateOperationCases))]
+		newOperationCase = m.selectCandidateByConfidence(candidateOperationCases, candidateRelations)
 	}
 
 	// If the operation is selected from the queue, we need to remove it from the queue (We can check it by checking its UUID).
 	// In addition, considering that the operations in queue have all been executed before, we should do some mutation.
 	selectedAPIMethod := newOperationCase.APIMethod
-	operationCaseQueue, exist := m.TestOperationCaseQueueMap[selectedAPIMethod]
-	if exist {
-		for i, operationCase := range operationCaseQueue {
-			if operationCase.UUID == newOperationCase.UUID {
-				// Remove the operation case from the queue.
-				// TestOperationCaseQueueMap must be updated immediately
-				// after the operation case is removed from the queue.
-				// Otherwise, if an error occurs in the mutation, the deletion may lost,
-				// leading to data inconsistency or even memory leak!
-				operationCaseQueue = slices.Delete(operationCaseQueue, i, i+1)
-				m.TestOperationCaseQueueMap[selectedAPIMethod] = operationCaseQueue
-
-				// **Note**: break as soon as we find the operation case
-				// so deleting while iterating the slice is safe.
-				// If you want to continue to iterate, please use a copy of the slice.
-				break
-			}
-		}
+	// The queue must be updated immediately after the operation case is selected.
+	// Otherwise, if an error occurs in the mutation, the deletion may lost, leading to data inconsistency or even memory leak!
+	if operationCaseQueue, exist := m.TestOperationCaseQueueMap[selectedAPIMethod]; exist {
+		operationCaseQueue.DeleteFunc(func(operationCase *OperationCase) bool { return operationCase.UUID == newOperationCase.UUID })
 	}
 
+	// Record the producer-consumer relations justifying the extension, whose usefulness is tracked by RelationTracker.
+	newOperationCase.ExtensionRelations = candidateRelations[selectedAPIMethod]
+	m.RelationTracker.RecordExtension(newOperationCase.ExtensionRelations)
+
 	newScenario.OperationCases = append(newScenario.OperationCases, newOperationCase)
 	return newScenario, nil
 }
 
-// resolveCandidateAPIMethods resolves the candidate API methods based on the test scenario.
-// We will try to get candidate API methods based on producer-consumer relationship.
-// The producer-consumer relationship includes two parts:
-//  1. producer-consumer relationship in system APIs
-//  2. producer-consumer relationship deduced from internal service APIs. For example, if system API A calls internal service API X1, system API B calls internal service API X2,
-//     and internal service API X1 has a producer-consumer relationship with X2, then we can guess that system API A and B may have a producer-consumer relationship.
-//
-// If there is no consumer, we will randomly select an API method.
-func (m *CaseManager) resolveCandidateAPIMethods(testScenario *TestScenario) ([]static.SimpleAPIMethod, error) {
-	// Our final candidate API methods.
-	candidateAPIMethods := make([]static.SimpleAPIMethod, 0)
-
-	// ------ Part 1: use external API dependency ------
-	// system-level producer-consumer relationship
-	producers := make([]static.SimpleAPIMethod, 0)
-	for _, operationCase := range testScenario.OperationCases {
-		producers = append(producers, operationCase.APIMethod)
-	}
-	consumers := m.APIManager.GetConsumerAPIMethodsByProducersForSystem(producers)
-	candidateAPIMethods = append(candidateAPIMethods, consumers...)
-
-	// ------ Part 2: enhance by internal service API dependency ------
-	// internal-service-level producer-consumer relationship
-	// 1. For each operation case in the existing scenario, get the internal service APIs it called.
-	// 2. For each internal service API (treat it as producer) we have in step 1, find its corresponding consumer (internal service) APIs.
-	// 3. For each internal service consumer API, find system APIs that call it.
-	// 4. Collect all system APIs in step 3, and add them to the candidate API methods.
-	internalServiceEndpoints := make([]static.InternalServiceEndpoint, 0)
-	for _, operationCase := range testScenario.OperationCases {
-		// Get the internal service APIs it called.
-		// Use high confidence map only (i.e., the map that is updated from traces), as the operation case is executed successfully, and there should exist corresponding traces.
-		currServiceInternalServiceEndpoints, err := m.RuntimeReachabilityMap.GetReachableInternalEndpointsByExternalAPI(operationCase.APIMethod, true)
-		if err != nil {
-			log.Err(err).Msgf("[CaseManager.resolveCandidateAPIMethods] Failed to get reachable internal endpoints by external API %v", operationCase.APIMethod)
-			return nil, err
-		}
-		internalServiceEndpoints = append(internalServiceEndpoints, currServiceInternalServiceEndpoints...)
-	}
-	// sort and remove duplicates
-	slices.SortFunc(internalServiceEndpoints, func(a, b static.InternalServiceEndpoint) int {
-		return static.CompareInternalServiceEndpoint(a, b)
-	})
-	internalServiceEndpoints = slices.Compact(internalServiceEndpoints)
-
-	// Get the internal service consumers for each internal service endpoint.
-	internalServiceConsumers := make([]static.InternalServiceEndpoint, 0)
-	if config.GlobalConfig.UseInternalServiceAPIDependency {
-		for _, internalServiceEndpoint := range internalServiceEndpoints {
-			currEndpointConsumers := m.APIManager.GetConsumerAPIMethodsByProducersForInternalService(
-				internalServiceEndpoint.ServiceName,
-				[]static.SimpleAPIMethod{internalServiceEndpoint.SimpleAPIMethod},
-			)
-			// consumer is SimpleAPIMethod, so we need to supplement the service name
-			for _, currEndpointConsumer := range currEndpointConsumers {
-				internalServiceConsumers = append(internalServiceConsumers, static.InternalServiceEndpoint{
-					ServiceName:     internalServiceEndpoint.ServiceName,
-					SimpleAPIMethod: currEndpointConsumer,
-				})
+// selectCandidateByConfidence randomly selects one of the candidate operation cases, by chance of its weight / sum(weights).
+// The weight of a candidate is the highest confidence of relations justifying it (see [ProducerConsumerRelation.GetConfidence]), or 1 if there is none,
+// so that candidates deduced from frequently and recently observed reachability are preferred over stale or guessed ones.
+// It is further multiplied by the distance weight of the candidate, if CallInfoGraph is set (see [CaseManager.getCandidateDistanceWeights]).
+// Weights are at least [minCandidateConfidenceWeight], so that no candidate is excluded.
+func (m *CaseManager) selectCandidateByConfidence(candidateOperationCases []*OperationCase, candidateRelations map[static.SimpleAPIMethod][]ProducerConsumerRelation) *OperationCase {
+	weights := make([]float64, len(candidateOperationCases))
+	distanceWeights := m.getCandidateDistanceWeights(candidateOperationCases)
+	totalWeight := 0.0
+	for i, operationCase := range candidateOperationCases {
+		weight := 1.0
+		if relations := candidateRelations[operationCase.APIMethod]; len(relations) > 0 {
+			weight = 0
+			for _, relation := range relations {
+				weight = max(weight, relation.GetConfidence(m.RuntimeReachabilityMap))
 			}
 		}
+		if distanceWeights != nil {
+			weight *= distanceWeights[i]
+		}
+		weights[i] = max(weight, minCandidateConfidenceWeight)
+		totalWeight += weights[i]
+	}
+	randomWeight := m.Rand.Float64() * totalWeight
+	for i, weight := range weights {
+		randomWeight -= weight
+		if randomWeight < 0 {
+			return candidateOperationCases[i]
+		}
 	}
+	// Only reachable by rounding errors.
+	return candidateOperationCases[len(candidateOperationCases)-1]
+}
 
-	// Find which system APIs call the internal service consumers, and collect them.
-	systemConsumers := make([]static.SimpleAPIMethod, 0)
-	internalServiceConsumersSet := make(map[static.InternalServiceEndpoint]struct{})
-	for _, internalServiceConsumer := range internalServiceConsumers {
-		internalServiceConsumersSet[internalServiceConsumer] = struct{}{}
-	}
-	// Iterate all system APIs, resolve their internal service endpoints, and check if they are in the internal service consumers.
-	// Deduplication is not needed here, as we iterate the API map in the order of the system APIs.
-	for systemAPIMethod := range m.APIManager.APIMap {
-		// We allow using low-confidence map here, as the system API might not have been executed yet.
-		reachableInternalServiceEndpoints, err := m.RuntimeReachabilityMap.GetReachableInternalEndpointsByExternalAPI(systemAPIMethod, true)
+// getCandidateDistanceWeights returns the distance weight of each candidate operation case, i.e., 1 / (1 + d),
+// where d is the shortest distance in CallInfoGraph from internal endpoints reachable by its API method to uncovered edges (see [fuzzruntime.CallInfoGraph.GetDistanceToUncoveredEdge]),
+// so that extensions are directed to candidates likely to cover new edges.
+// The weight is 0 if no uncovered edge is reachable from the candidate.
+// It returns nil if CallInfoGraph is not set, or no uncovered edge is reachable from any candidate, i.e., distance does not tell candidates apart.
+func (m *CaseManager) getCandidateDistanceWeights(candidateOperationCases []*OperationCase) []float64 {
+	if m.CallInfoGraph == nil || m.RuntimeReachabilityMap == nil {
+		return nil
+	}
+	distanceWeights := make([]float64, len(candidateOperationCases))
+	guided := false
+	for i, operationCase := range candidateOperationCases {
+		internalEndpoints, err := m.RuntimeReachabilityMap.GetReachableInternalEndpointsByExternalAPI(operationCase.APIMethod, false)
 		if err != nil {
-			log.Err(err).Msgf("[CaseManager.resolveCandidateAPIMethods] Failed to get reachable internal endpoints by external API %v", systemAPIMethod)
-			return nil, err
+			log.Err(err).Msgf("[CaseManager.getCandidateDistanceWeights] Failed to get reachable internal endpoints of %s %s", operationCase.APIMethod.Method, operationCase.APIMethod.Endpoint)
+			continue
 		}
-		for _, reachableInternalServiceEndpoint := range reachableInternalServiceEndpoints {
-			if _, exist := internalServiceConsumersSet[reachableInternalServiceEndpoint]; exist {
-				systemConsumers = append(systemConsumers, systemAPIMethod)
-				break
+		for _, internalEndpoint := range internalEndpoints {
+			distance, found := m.CallInfoGraph.GetDistanceToUncoveredEdge(internalEndpoint)
+			if found {
+				distanceWeights[i] = max(distanceWeights[i], 1/float64(1+distance))
+				guided = true
 			}
 		}
 	}
+	if !guided {
+		return nil
+	}
+	return distanceWeights
+}
 
-	// aggregate the system consumers
-	candidateAPIMethods = append(candidateAPIMethods, systemConsumers...)
+// resolveCandidateAPIMethods resolves the candidate API methods based on the test scenario, by the scenario extension policy (see [ScenarioExtensionPolicy]).
+// If there is no candidate, we will randomly select an API method.
+// It returns the sorted and deduplicated candidate API methods, and the map from each candidate to the sorted and deduplicated relations justifying it.
+func (m *CaseManager) resolveCandidateAPIMethods(testScenario *TestScenario) ([]static.SimpleAPIMethod, map[static.SimpleAPIMethod][]ProducerConsumerRelation, error) {
+	candidates, err := m.ExtensionPolicy.ResolveCandidates(testScenario)
+	if err != nil {
+		log.Err(err).Msgf("[CaseManager.resolveCandidateAPIMethods] Failed to resolve candidates by scenario extension policy %s", m.ExtensionPolicy.Name())
+		return nil, nil, err
+	}
 
-	// ------ Part 3: Post-process ------
 	// If there are no candidates until now, we can randomly select an API method.
-	if len(candidateAPIMethods) == 0 {
+	if len(candidates) == 0 {
 		log.Info().Msg("[CaseManager.resolveCandidateAPIMethods] No candidates available, randomly select an API method")
-		candidateAPIMethods = append(candidateAPIMethods, m.APIManager.GetRandomAPIMethod())
+		candidates, err = m.fallbackExtensionPolicy.ResolveCandidates(testScenario)
+		if err != nil {
+			log.Err(err).Msg("[CaseManager.resolveCandidateAPIMethods] Failed to randomly select an API method")
+			return nil, nil, err
+		}
 	}
 
-	// Deduplicate the candidate API methods by unique sort.
+	candidateAPIMethods := make([]static.SimpleAPIMethod, 0, len(candidates))
+	candidateRelations := make(map[static.SimpleAPIMethod][]ProducerConsumerRelation)
+	for _, candidate := range candidates {
+		candidateAPIMethods = append(candidateAPIMethods, candidate.APIMethod)
+		if candidate.Relation != nil {
+			candidateRelations[candidate.APIMethod] = append(candidateRelations[candidate.APIMethod], *candidate.Relation)
+		}
+	}
+	// Deduplicate the candidate API methods and their relations by unique sort.
 	slices.SortFunc(candidateAPIMethods, func(a, b static.SimpleAPIMethod) int {
 		return static.CompareSimpleAPIMethod(a, b)
 	})
 	candidateAPIMethods = slices.Compact(candidateAPIMethods)
-	return candidateAPIMethods, nil
+	for apiMethod, relations := range candidateRelations {
+		slices.SortFunc(relations, CompareProducerConsumerRelation)
+		candidateRelations[apiMethod] = slices.Compact(relations)
+	}
+	return candidateAPIMethods, candidateRelations, nil
 }
 
 // initTestcasesFromDoc initializes the test cases from the OpenAPI document.
 func (m *CaseManager) initTestcasesFromDoc() error {
-	// At the beginning, each testcase is a simple request to each API.
-	for method, operation := range m.APIManager.APIMap {
-		operationCase := NewOperationCase(method, operation)
-		testcase := NewTestScenario([]*OperationCase{operationCase})
-		m.pushAndSort(testcase)
+	// At the beginning, each testcase is a simple request to each API,
+	// preceded by its preconditions (see [static.OperationPreconditionExtensionKey]) if any.
+	// Sort methods, so that the initial queue is in a stable order (see [utils.SeedSharedRand]).
+	for _, method := range slices.SortedFunc(maps.Keys(m.APIManager.APIMap), static.CompareSimpleAPIMethod) {
+		operation := m.APIManager.APIMap[method]
+		operationCases := make([]*OperationCase, 0)
+		for _, precondition := range m.APIManager.GetOrderedOperationPreconditions(method) {
+			preconditionOperation, exist := m.APIManager.GetOperationByMethod(precondition)
+			if !exist {
+				log.Warn().Msgf("[CaseManager.initTestcasesFromDoc] The precondition %v of API method %v does not exist in the API manager", precondition, method)
+				continue
+			}
+			operationCases = append(operationCases, NewOperationCase(precondition, preconditionOperation))
+		}
+		operationCases = append(operationCases, NewOperationCase(method, operation))
+		testcase := NewTestScenario(operationCases)
+		m.pushAndCull(testcase)
 	}
 	return nil
 }
 
+// arePreconditionsSatisfied checks whether all preconditions of the API method (see [static.OperationPreconditionExtensionKey])
+// are in the test scenario, i.e., whether the API method can be appended to the scenario.
+func arePreconditionsSatisfied(APIManager *static.APIManager, testScenario *TestScenario, apiMethod static.SimpleAPIMethod) bool {
+	for _, precondition := range APIManager.GetOperationPreconditions(apiMethod) {
+		if !slices.ContainsFunc(testScenario.OperationCases, func(operationCase *OperationCase) bool {
+			return operationCase.APIMethod == precondition
+		}) {
+			return false
+		}
+	}
+	return true
+}
+
 // mutateScenario mutates the given test scenario and returns it.
 // Mutation would not reset the scenario, i.e., the executed count and energy will be inherited from the existing one. (This is different from extending)
 // Deprecated: this function is not used anymore, mutation in value generation strategy is used instead.
@@ -506,6 +736,11 @@ func (m *CaseManager) mutateOperationCase(operationCase *OperationCase) (*Operat
 			log.Err(err).Msgf("[CaseManager.mutateOperationCase] Failed to mutate request path param %s, resource: %s", key, resrc.String())
 			return nil, err
 		}
+		// Mutation must not break routing, so route-unsafe mutated values are dropped.
+		if !strategy.IsRouteSafePathParamResource(mutatedResrc) {
+			log.Debug().Msgf("[CaseManager.mutateOperationCase] Mutated request path param %s is not route-safe, keep it unmutated", key)
+			continue
+		}
 		requestPathParamResrc[key] = mutatedResrc
 	}
 	// mutate the request query params
@@ -536,34 +771,74 @@ func (m *CaseManager) mutateOperationCase(operationCase *OperationCase) (*Operat
 }
 
 // generateRequestBodyResourceFromSchema generates a request body resource from a schema.
-// It returns a json object as a resource and error if any.
-// If the schema is empty, it returns nil.
-func (m *CaseManager) generateRequestBodyResourceFromSchema(requestBodyRef *openapi3.RequestBodyRef) (resource.Resource, error) {
+// It returns a json object as a resource, the reason if the body is generated on a best-effort basis, and error if any.
+// If the request body is empty, it returns nil.
+// If the request body declares no schema, a best-effort value is generated (see [strategy.SchemaToValueStrategy.GenerateValueWithoutSchema]).
+// Documented values of the request body of the API method are used, see [resource.GetRequestBodySpecExamplePath].
+func (m *CaseManager) generateRequestBodyResourceFromSchema(APIMethod static.SimpleAPIMethod, requestBodyRef *openapi3.RequestBodyRef) (resource.Resource, string, error) {
 	if requestBodyRef == nil || requestBodyRef.Value == nil {
-		return nil, nil
+		return nil, "", nil
+	}
+	requestBodySchema := utils.GetRequestBodySchema(requestBodyRef)
+	if requestBodySchema == nil {
+		generatedValue, err := m.FuzzStrategist.GenerateValueWithoutSchema(requestBodyRef.Ref, strategy.SchemaLessBodyRawJSONValues)
+		if err != nil {
+			log.Err(err).Msgf("[CaseManager.generateRequestBodyResourceFromSchema] Failed to generate best-effort value for request body without schema")
+			return nil, "", err
+		}
+		return generatedValue, "request body declares no schema", nil
 	}
-	generatedValue, err := m.FuzzStrategist.GenerateValueForSchema(requestBodyRef.Ref, requestBodyRef.Value.Content.Get("application/json").Schema)
+	m.FuzzStrategist.SetSchemaPaths(resource.GetRequestBodySpecExamplePath(APIMethod))
+	generatedValue, err := m.FuzzStrategist.GenerateValueForSchema(requestBodyRef.Ref, requestBodySchema)
 	if err != nil {
-		log.Err(err).Msgf("[CaseManager.generateRequestBodyResourceFromSchema] Failed to generate object from schema %v", requestBodyRef.Value.Content.Get("application/json").Schema)
-		return nil, err
+		log.Err(err).Msgf("[CaseManager.generateRequestBodyResourceFromSchema] Failed to generate object from schema %v", requestBodySchema)
+		return nil, "", err
 	}
-	return generatedValue, nil
+	return generatedValue, "", nil
 }
 
 // generateRequestParamResourcesFromSchema generates request params resources (including path and query) from a schema.
-// It returns a map of request path params, a map of query params, and an error if any.
-func (m *CaseManager) generateRequestParamResourcesFromSchema(params []*openapi3.ParameterRef) (map[string]resource.Resource, map[string]resource.Resource, error) {
+// The schema of a param is taken from its `content` if it declares no `schema`.
+// Params declaring no schema at all get best-effort values (see [strategy.SchemaToValueStrategy.GenerateValueWithoutSchema]).
+// Path params are route-safe, see [strategy.SchemaToValueStrategy.GenerateValueForPathParam].
+// Documented values of the params of the API method are used, see [resource.GetParameterSpecExamplePath].
+// It returns a map of request path params, a map of query params, reasons of params generated on a best-effort basis, and an error if any.
+func (m *CaseManager) generateRequestParamResourcesFromSchema(APIMethod static.SimpleAPIMethod, params []*openapi3.ParameterRef) (map[string]resource.Resource, map[string]resource.Resource, []string, error) {
 	pathParams := make(map[string]resource.Resource)
 	queryParams := make(map[string]resource.Resource)
+	lowConfidenceReasons := make([]string, 0)
 	for _, param := range params {
 		if param == nil || param.Value == nil {
-			return nil, nil, fmt.Errorf("request param is nil")
+			return nil, nil, nil, fmt.Errorf("request param is nil")
 		}
 
-		generatedValue, err := m.FuzzStrategist.GenerateValueForSchema(param.Value.Name, param.Value.Schema)
-		if err != nil {
-			log.Err(err).Msgf("[CaseManager.generateRequestParamResourcesFromSchema] Failed to generate object from schema %v", param.Value.Schema)
-			return nil, nil, err
+		var generatedValue resource.Resource
+		var err error
+		paramSchema := utils.GetParameterSchema(param.Value)
+		m.FuzzStrategist.SetSchemaPaths(resource.GetParameterSpecExamplePath(APIMethod, param.Value))
+		if param.Value.In == "path" {
+			// Path params must be route-safe, unless hostile path values are generated deliberately.
+			generatedValue, err = m.FuzzStrategist.GenerateValueForPathParam(param.Value.Name, paramSchema)
+			if err != nil {
+				log.Err(err).Msgf("[CaseManager.generateRequestParamResourcesFromSchema] Failed to generate value for path param %s", param.Value.Name)
+				return nil, nil, nil, err
+			}
+			if paramSchema == nil {
+				lowConfidenceReasons = append(lowConfidenceReasons, fmt.Sprintf("%s param %s declares no schema", param.Value.In, param.Value.Name))
+			}
+		} else if paramSchema != nil {
+			generatedValue, err = m.FuzzStrategist.GenerateValueForSchema(param.Value.Name, paramSchema)
+			if err != nil {
+				log.Err(err).Msgf("[CaseManager.generateRequestParamResourcesFromSchema] Failed to generate object from schema %v", paramSchema)
+				return nil, nil, nil, err
+			}
+		} else {
+			generatedValue, err = m.FuzzStrategist.GenerateValueWithoutSchema(param.Value.Name, strategy.SchemaLessParamRawJSONValues)
+			if err != nil {
+				log.Err(err).Msgf("[CaseManager.generateRequestParamResourcesFromSchema] Failed to generate best-effort value for param %s without schema", param.Value.Name)
+				return nil, nil, nil, err
+			}
+			lowConfidenceReasons = append(lowConfidenceReasons, fmt.Sprintf("%s param %s declares no schema", param.Value.In, param.Value.Name))
 		}
 
 		if param.Value.In == "path" {
@@ -575,5 +850,5 @@ func (m *CaseManager) generateRequestParamResourcesFromSchema(params []*openapi3
 			log.Warn().Msgf("[CaseManager.generateRequestParamResourcesFromSchema] Unsupported param location %v", param.Value.In)
 		}
 	}
-	return pathParams, queryParams, nil
+	return pathParams, queryParams, lowConfidenceReasons, nil
 }
@@ -0,0 +1,190 @@
+package casemanager
+
+import (
+	"cmp"
+	"fmt"
+	"maps"
+	"resttracefuzzer/pkg/static"
+	"slices"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+)
+
+// GetTopScenarios returns copies of at most limit test scenarios of the highest energy in the queue, sorted by energy in descending order.
+// Scenarios of the same energy are in the order of the queue.
+// Injected scenarios not executed yet (see [CaseManager.InjectOperationCase]) are not included.
+// It is safe to call concurrently with fuzzing, e.g., from the control API.
+func (m *CaseManager) GetTopScenarios(limit int) []*TestScenario {
+	m.queueMu.Lock()
+	defer m.queueMu.Unlock()
+	testScenarios := m.TestScenarios.Values()
+	slices.SortStableFunc(testScenarios, func(a, b *TestScenario) int {
+		return cmp.Compare(b.Energy, a.Energy)
+	})
+	testScenarios = testScenarios[:min(max(limit, 0), len(testScenarios))]
+	res := make([]*TestScenario, 0, len(testScenarios))
+	for _, testScenario := range testScenarios {
+		res = append(res, testScenario.Copy())
+	}
+	return res
+}
+
+// TestScenarioSummary is the summary of a test scenario in the queue, exposed by the dashboard.
+// Unlike [TestScenario], it contains no request headers, parameters or bodies, which may carry credentials.
+type TestScenarioSummary struct {
+	// UUID is the unique identifier of the test scenario.
+	UUID uuid.UUID `json:"uuid"`
+
+	// Energy is the energy of the test scenario.
+	Energy int `json:"energy"`
+
+	// ExecutedCount is the number of times the test scenario is executed.
+	ExecutedCount int `json:"executedCount"`
+
+	// OperationCases are the summaries of operation cases of the test scenario, in order.
+	OperationCases []*OperationCaseSummary `json:"operationCases"`
+}
+
+// OperationCaseSummary is the summary of an operation case in a [TestScenarioSummary].
+type OperationCaseSummary struct {
+	// APIMethod is the API method of the operation case, i.e., method and endpoint.
+	APIMethod static.SimpleAPIMethod `json:"APIMethod"`
+}
+
+// GetTopScenarioSummaries returns summaries of at most limit test scenarios of the highest energy in the queue, see [CaseManager.GetTopScenarios].
+// It is safe to call concurrently with fuzzing, e.g., from the dashboard.
+func (m *CaseManager) GetTopScenarioSummaries(limit int) []*TestScenarioSummary {
+	testScenarios := m.GetTopScenarios(limit)
+	res := make([]*TestScenarioSummary, 0, len(testScenarios))
+	for _, testScenario := range testScenarios {
+		operationCaseSummaries := make([]*OperationCaseSummary, 0, len(testScenario.OperationCases))
+		for _, operationCase := range testScenario.OperationCases {
+			operationCaseSummaries = append(operationCaseSummaries, &OperationCaseSummary{APIMethod: operationCase.APIMethod})
+		}
+		res = append(res, &TestScenarioSummary{
+			UUID:           testScenario.UUID,
+			Energy:         testScenario.Energy,
+			ExecutedCount:  testScenario.ExecutedCount,
+			OperationCases: operationCaseSummaries,
+		})
+	}
+	return res
+}
+
+// GetTopOperationCases returns copies of at most limit operation cases of the highest energy in the queues of all API methods, sorted by energy in descending order.
+// Operation cases of the same energy are sorted by API method, and then in the order of their queue.
+// It is safe to call concurrently with fuzzing, e.g., from the control API.
+func (m *CaseManager) GetTopOperationCases(limit int) []*OperationCase {
+	m.queueMu.Lock()
+	defer m.queueMu.Unlock()
+	operationCases := make([]*OperationCase, 0)
+	for _, apiMethod := range slices.SortedFunc(maps.Keys(m.TestOperationCaseQueueMap), static.CompareSimpleAPIMethod) {
+		operationCases = append(operationCases, m.TestOperationCaseQueueMap[apiMethod].Values()...)
+	}
+	slices.SortStableFunc(operationCases, func(a, b *OperationCase) int {
+		return cmp.Compare(b.Energy, a.Energy)
+	})
+	operationCases = operationCases[:min(max(limit, 0), len(operationCases))]
+	res := make([]*OperationCase, 0, len(operationCases))
+	for _, operationCase := range operationCases {
+		res = append(res, operationCase.Copy())
+	}
+	return res
+}
+
+// InjectOperationCase injects a hand-crafted operation case as a new single-operation test scenario, which is popped before the queue,
+// e.g., to guide a long campaign towards an operation with known-interesting parameters.
+// Only the API method, request headers, path and query parameters, request body and energy of the operation case are used,
+// and the request is sent as crafted in its first execution, with global extra headers and credentials of the primary user added (the crafted headers take precedence).
+// After that, the scenario is evaluated, extended and populated again like others.
+// It returns a copy of the injected scenario (see [CaseManager.InjectTestScenario]), or an error if the API method is not defined in the API doc, or required path parameters are missing.
+// It is safe to call concurrently with fuzzing, e.g., from the control API.
+func (m *CaseManager) InjectOperationCase(operationCase *OperationCase) (*TestScenario, error) {
+	if operationCase == nil {
+		return nil, fmt.Errorf("operation case is nil")
+	}
+	return m.InjectTestScenario([]*OperationCase{operationCase}, operationCase.Energy)
+}
+
+// InjectTestScenario injects hand-crafted (or recorded) operation cases as a new test scenario with the given energy, like [CaseManager.InjectOperationCase].
+// It returns a copy of the injected scenario, which is safe to read while fuzzing, or an error if any operation case is invalid.
+// It is safe to call concurrently with fuzzing.
+func (m *CaseManager) InjectTestScenario(operationCases []*OperationCase, energy int) (*TestScenario, error) {
+	if len(operationCases) == 0 {
+		return nil, fmt.Errorf("test scenario is empty")
+	}
+	injectedOperationCases := make([]*OperationCase, 0, len(operationCases))
+	for _, operationCase := range operationCases {
+		injectedOperationCase, err := m.newInjectedOperationCase(operationCase)
+		if err != nil {
+			return nil, err
+		}
+		injectedOperationCase.Energy = min(max(energy, MinScenarioEnergy), MaxScenarioEnergy)
+		injectedOperationCases = append(injectedOperationCases, injectedOperationCase)
+	}
+	testScenario := NewTestScenario(injectedOperationCases)
+	testScenario.Energy = min(max(energy, MinScenarioEnergy), MaxScenarioEnergy)
+	testScenario.Injected = true
+	// The injected scenario is mutated by fuzzing once popped, so a copy taken before queueing it is returned.
+	injectedScenario := testScenario.Copy()
+	injectedScenario.Injected = true
+
+	m.queueMu.Lock()
+	defer m.queueMu.Unlock()
+	m.injectedScenarios = append(m.injectedScenarios, testScenario)
+	log.Info().Msgf("[CaseManager.InjectTestScenario] Injected %d operation cases as test scenario (UUID: %s)", len(injectedOperationCases), testScenario.UUID.String())
+	return injectedScenario, nil
+}
+
+// newInjectedOperationCase creates a new operation case of the requests of the crafted one, to be injected.
+// It returns an error if the API method is not defined in the API doc, or required path parameters are missing.
+func (m *CaseManager) newInjectedOperationCase(operationCase *OperationCase) (*OperationCase, error) {
+	if operationCase == nil {
+		return nil, fmt.Errorf("operation case is nil")
+	}
+	apiMethod := operationCase.APIMethod
+	if apiMethod.Typ == "" {
+		apiMethod.Typ = static.SimpleAPIMethodTypeHTTP
+	}
+	operation, exist := m.APIManager.GetOperationByMethod(apiMethod)
+	if !exist {
+		return nil, fmt.Errorf("API method %v is not defined in the API doc", apiMethod)
+	}
+	for _, param := range operation.Parameters {
+		if param == nil || param.Value == nil || param.Value.In != openapi3.ParameterInPath {
+			continue
+		}
+		if _, exist := operationCase.RequestPathParams[param.Value.Name]; !exist {
+			return nil, fmt.Errorf("path parameter %s of API method %v is missing", param.Value.Name, apiMethod)
+		}
+	}
+
+	injectedOperationCase := NewOperationCase(apiMethod, operation)
+	injectedOperationCase.RequestHeaders = cloneOrEmpty(operationCase.RequestHeaders)
+	injectedOperationCase.RequestPathParams = cloneOrEmpty(operationCase.RequestPathParams)
+	injectedOperationCase.RequestQueryParams = cloneOrEmpty(operationCase.RequestQueryParams)
+	injectedOperationCase.RequestBody = slices.Clone(operationCase.RequestBody)
+	return injectedOperationCase, nil
+}
+
+// popInjectedScenario pops the earliest injected test scenario, if any.
+// The caller must hold queueMu.
+func (m *CaseManager) popInjectedScenario() (*TestScenario, bool) {
+	if len(m.injectedScenarios) == 0 {
+		return nil, false
+	}
+	testScenario := m.injectedScenarios[0]
+	m.injectedScenarios[0] = nil
+	m.injectedScenarios = m.injectedScenarios[1:]
+	return testScenario, true
+}
+
+// cloneOrEmpty clones the map, or returns an empty map if it is nil.
+func cloneOrEmpty(m map[string]string) map[string]string {
+	if m == nil {
+		return make(map[string]string)
+	}
+	return maps.Clone(m)
+}
@@ -0,0 +1,67 @@
+package casemanager
+
+import (
+	"math"
+	"math/rand/v2"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// minEnergyDecayInterval is the min interval between two decays of energy, so that queues are not re-ordered after every test scenario.
+const minEnergyDecayInterval = time.Second
+
+// DecayEnergy decays the energy of queued test scenarios and operation cases exponentially with the time elapsed since the last decay,
+// i.e., energy is halved every EnergyDecayHalfLife, so that cases interesting early in the run do not dominate scheduling after the coverage frontier moves on.
+// As the decay is exponential, energy gained by a case is decayed by its age, no matter how often it is called.
+// Decayed energy is rounded stochastically, so that low energy decays as expected in average, rather than being stuck by rounding.
+// It does nothing if EnergyDecayHalfLife is not positive, and should be called periodically, e.g., after each test scenario.
+// It returns the number of cases whose energy is decreased.
+func (m *CaseManager) DecayEnergy() int {
+	if m.EnergyDecayHalfLife <= 0 {
+		return 0
+	}
+	m.queueMu.Lock()
+	defer m.queueMu.Unlock()
+	now := time.Now()
+	if m.lastEnergyDecayTime.IsZero() {
+		m.lastEnergyDecayTime = now
+		return 0
+	}
+	elapsed := now.Sub(m.lastEnergyDecayTime)
+	if elapsed < minEnergyDecayInterval {
+		return 0
+	}
+	m.lastEnergyDecayTime = now
+	factor := math.Pow(0.5, float64(elapsed)/float64(m.EnergyDecayHalfLife))
+
+	decayedCnt := 0
+	m.TestScenarios.FixAll(func(testScenario *TestScenario) {
+		decayedEnergy := decayEnergyByFactor(testScenario.Energy, factor, m.Rand)
+		if decayedEnergy < testScenario.Energy {
+			decayedCnt++
+		}
+		testScenario.Energy = max(decayedEnergy, MinScenarioEnergy)
+	})
+	for _, operationCaseQueue := range m.TestOperationCaseQueueMap {
+		operationCaseQueue.FixAll(func(operationCase *OperationCase) {
+			decayedEnergy := decayEnergyByFactor(operationCase.Energy, factor, m.Rand)
+			if decayedEnergy < operationCase.Energy {
+				decayedCnt++
+			}
+			operationCase.Energy = max(decayedEnergy, MinOperationCaseEnergy)
+		})
+	}
+	log.Debug().Msgf("[CaseManager.DecayEnergy] Decayed energy by factor %.3f after %v, energy of %d cases decreased", factor, elapsed, decayedCnt)
+	return decayedCnt
+}
+
+// decayEnergyByFactor multiplies the energy by the factor (0-1), where the fractional part is rounded up by chance of itself.
+func decayEnergyByFactor(energy int, factor float64, r *rand.Rand) int {
+	decayed := float64(energy) * factor
+	rounded := math.Floor(decayed)
+	if r.Float64() < decayed-rounded {
+		rounded++
+	}
+	return int(rounded)
+}
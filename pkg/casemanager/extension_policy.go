@@ -0,0 +1,442 @@
+package casemanager
+
+import (
+	"fmt"
+	"maps"
+	"resttracefuzzer/internal/config"
+	fuzzruntime "resttracefuzzer/pkg/runtime"
+	"resttracefuzzer/pkg/static"
+	"resttracefuzzer/pkg/utils"
+	"slices"
+
+	"github.com/bytedance/sonic"
+	"github.com/rs/zerolog/log"
+)
+
+// Names of scenario extension policies, used as keys of `scenario-extension-policy-weights` in config.
+const (
+	// ScenarioExtensionPolicyDependency is the name of [DependencyScenarioExtensionPolicy].
+	ScenarioExtensionPolicyDependency = "dependency"
+
+	// ScenarioExtensionPolicyTrace is the name of [TraceScenarioExtensionPolicy].
+	ScenarioExtensionPolicyTrace = "trace"
+
+	// ScenarioExtensionPolicyDataflow is the name of [DataflowScenarioExtensionPolicy].
+	ScenarioExtensionPolicyDataflow = "dataflow"
+
+	// ScenarioExtensionPolicyRandom is the name of [RandomScenarioExtensionPolicy].
+	ScenarioExtensionPolicyRandom = "random"
+)
+
+// ScenarioExtensionPolicy resolves candidate API methods to extend a test scenario with, i.e., to append to the scenario.
+type ScenarioExtensionPolicy interface {
+	// Name returns the name of the policy.
+	Name() string
+
+	// ResolveCandidates resolves candidate API methods to extend the test scenario with, with the relations justifying them.
+	// The candidates may contain duplicates (e.g., an API method justified by multiple relations), and may be empty if the policy knows no candidate.
+	ResolveCandidates(testScenario *TestScenario) ([]ScenarioExtensionCandidate, error)
+}
+
+// ScenarioExtensionCandidate is a candidate API method to extend a test scenario with, resolved by a [ScenarioExtensionPolicy].
+type ScenarioExtensionCandidate struct {
+	// APIMethod is the candidate API method.
+	APIMethod static.SimpleAPIMethod
+
+	// Relation is the producer-consumer relation justifying the candidate.
+	// It is nil if the candidate is not deduced from a producer-consumer relation, e.g., a random API method.
+	Relation *ProducerConsumerRelation
+}
+
+// NewScenarioExtensionPolicy creates a scenario extension policy from the weights of policies, in the format of stringified JSON map
+// from the policy name to its weight, e.g., `{"dependency": 1, "dataflow": 2}`.
+// For each extension, a policy is selected by chance of its weight / sum(weights), see [WeightedScenarioExtensionPolicy].
+// If policyWeightsStr is empty, the default policy is used, i.e., the union of dependency-file-driven and trace-driven policies.
+func NewScenarioExtensionPolicy(
+	policyWeightsStr string,
+	APIManager *static.APIManager,
+	runtimeReachabilityMap *fuzzruntime.RuntimeReachabilityMap,
+) (ScenarioExtensionPolicy, error) {
+	policies := map[string]ScenarioExtensionPolicy{
+		ScenarioExtensionPolicyDependency: NewDependencyScenarioExtensionPolicy(APIManager),
+		ScenarioExtensionPolicyTrace:      NewTraceScenarioExtensionPolicy(APIManager, runtimeReachabilityMap),
+		ScenarioExtensionPolicyDataflow:   NewDataflowScenarioExtensionPolicy(APIManager, runtimeReachabilityMap),
+		ScenarioExtensionPolicyRandom:     NewRandomScenarioExtensionPolicy(APIManager),
+	}
+	if policyWeightsStr == "" {
+		return NewUnionScenarioExtensionPolicy(
+			policies[ScenarioExtensionPolicyDependency],
+			policies[ScenarioExtensionPolicyTrace],
+		), nil
+	}
+
+	policyWeights := make(map[string]int)
+	err := sonic.UnmarshalString(policyWeightsStr, &policyWeights)
+	if err != nil {
+		log.Err(err).Msg("[NewScenarioExtensionPolicy] Failed to unmarshal scenario extension policy weights")
+		return nil, err
+	}
+	weightedPolicies := make([]ScenarioExtensionPolicy, 0)
+	weights := make([]int, 0)
+	// Sort the names, so that the policy selected only depends on the random source.
+	for _, name := range slices.Sorted(maps.Keys(policyWeights)) {
+		policy, exist := policies[name]
+		if !exist {
+			return nil, fmt.Errorf("unsupported scenario extension policy: %s", name)
+		}
+		weight := policyWeights[name]
+		if weight < 0 {
+			return nil, fmt.Errorf("weight of scenario extension policy %s is negative: %d", name, weight)
+		}
+		if weight == 0 {
+			continue
+		}
+		weightedPolicies = append(weightedPolicies, policy)
+		weights = append(weights, weight)
+	}
+	if len(weightedPolicies) == 0 {
+		return nil, fmt.Errorf("no scenario extension policy with positive weight")
+	}
+	return NewWeightedScenarioExtensionPolicy(weightedPolicies, weights), nil
+}
+
+// DependencyScenarioExtensionPolicy is a dependency-file-driven scenario extension policy.
+// Candidates are consumers of API methods in the scenario, by the system-level producer-consumer API dependency graph,
+// which is parsed from dependency files (e.g., generated by Restler), or inferred from the OpenAPI doc.
+type DependencyScenarioExtensionPolicy struct {
+	// APIManager is the API manager, holding the API dependency graph.
+	APIManager *static.APIManager
+}
+
+// NewDependencyScenarioExtensionPolicy creates a new DependencyScenarioExtensionPolicy.
+func NewDependencyScenarioExtensionPolicy(APIManager *static.APIManager) *DependencyScenarioExtensionPolicy {
+	return &DependencyScenarioExtensionPolicy{
+		APIManager: APIManager,
+	}
+}
+
+// Name implements [ScenarioExtensionPolicy].
+func (p *DependencyScenarioExtensionPolicy) Name() string {
+	return ScenarioExtensionPolicyDependency
+}
+
+// ResolveCandidates implements [ScenarioExtensionPolicy].
+func (p *DependencyScenarioExtensionPolicy) ResolveCandidates(testScenario *TestScenario) ([]ScenarioExtensionCandidate, error) {
+	if p.APIManager.APIDependencyGraph == nil {
+		return nil, nil
+	}
+	candidates := make([]ScenarioExtensionCandidate, 0)
+	for _, producer := range getScenarioAPIMethods(testScenario) {
+		for _, consumer := range p.APIManager.GetConsumerAPIMethodsByProducersForSystem([]static.SimpleAPIMethod{producer}) {
+			candidates = append(candidates, ScenarioExtensionCandidate{
+				APIMethod: consumer,
+				Relation: &ProducerConsumerRelation{
+					Policy:   p.Name(),
+					Producer: producer,
+					Consumer: consumer,
+				},
+			})
+		}
+	}
+	return candidates, nil
+}
+
+// TraceScenarioExtensionPolicy is a trace-driven scenario extension policy.
+// The producer-consumer relationship of system APIs is deduced from internal service API dependencies, by reachability learned from traces.
+// For example, if system API A calls internal service API X1, system API B calls internal service API X2,
+// and internal service API X1 has a producer-consumer relationship with X2, then we can guess that system API A and B may have a producer-consumer relationship.
+// Internal service API dependencies are used only if `use-internal-service-api-dependency` is enabled.
+type TraceScenarioExtensionPolicy struct {
+	// APIManager is the API manager, holding the internal service API dependency graphs.
+	APIManager *static.APIManager
+
+	// RuntimeReachabilityMap is the runtime reachability map, learned from traces.
+	RuntimeReachabilityMap *fuzzruntime.RuntimeReachabilityMap
+}
+
+// NewTraceScenarioExtensionPolicy creates a new TraceScenarioExtensionPolicy.
+func NewTraceScenarioExtensionPolicy(APIManager *static.APIManager, runtimeReachabilityMap *fuzzruntime.RuntimeReachabilityMap) *TraceScenarioExtensionPolicy {
+	return &TraceScenarioExtensionPolicy{
+		APIManager:             APIManager,
+		RuntimeReachabilityMap: runtimeReachabilityMap,
+	}
+}
+
+// Name implements [ScenarioExtensionPolicy].
+func (p *TraceScenarioExtensionPolicy) Name() string {
+	return ScenarioExtensionPolicyTrace
+}
+
+// ResolveCandidates implements [ScenarioExtensionPolicy].
+//  1. For each operation case in the existing scenario, get the internal service APIs it called.
+//  2. For each internal service API (treat it as producer) we have in step 1, find its corresponding consumer (internal service) APIs.
+//  3. For each internal service consumer API, find system APIs that call it.
+//  4. Collect all system APIs in step 3 as candidates.
+func (p *TraceScenarioExtensionPolicy) ResolveCandidates(testScenario *TestScenario) ([]ScenarioExtensionCandidate, error) {
+	if !config.GlobalConfig.UseInternalServiceAPIDependency {
+		return nil, nil
+	}
+	// Get the internal service consumers for each internal service endpoint.
+	getInternalConsumers := func(internalServiceEndpoint static.InternalServiceEndpoint) []static.InternalServiceEndpoint {
+		currEndpointConsumers := p.APIManager.GetConsumerAPIMethodsByProducersForInternalService(
+			internalServiceEndpoint.ServiceName,
+			[]static.SimpleAPIMethod{internalServiceEndpoint.SimpleAPIMethod},
+		)
+		// consumer is SimpleAPIMethod, so we need to supplement the service name
+		internalConsumers := make([]static.InternalServiceEndpoint, 0, len(currEndpointConsumers))
+		for _, currEndpointConsumer := range currEndpointConsumers {
+			internalConsumers = append(internalConsumers, static.InternalServiceEndpoint{
+				ServiceName:     internalServiceEndpoint.ServiceName,
+				SimpleAPIMethod: currEndpointConsumer,
+			})
+		}
+		return internalConsumers
+	}
+	return resolveCandidatesByInternalRelations(p.Name(), p.APIManager, p.RuntimeReachabilityMap, testScenario, getInternalConsumers)
+}
+
+// DataflowScenarioExtensionPolicy is a dataflow-graph-driven scenario extension policy.
+// Like [TraceScenarioExtensionPolicy], but the consumers of internal service APIs are targets of dataflow edges (see [static.APIDataflowGraph]) from them,
+// instead of consumers in internal service API dependency files.
+type DataflowScenarioExtensionPolicy struct {
+	// APIManager is the API manager, holding the dataflow graph.
+	APIManager *static.APIManager
+
+	// RuntimeReachabilityMap is the runtime reachability map, learned from traces.
+	RuntimeReachabilityMap *fuzzruntime.RuntimeReachabilityMap
+}
+
+// NewDataflowScenarioExtensionPolicy creates a new DataflowScenarioExtensionPolicy.
+func NewDataflowScenarioExtensionPolicy(APIManager *static.APIManager, runtimeReachabilityMap *fuzzruntime.RuntimeReachabilityMap) *DataflowScenarioExtensionPolicy {
+	return &DataflowScenarioExtensionPolicy{
+		APIManager:             APIManager,
+		RuntimeReachabilityMap: runtimeReachabilityMap,
+	}
+}
+
+// Name implements [ScenarioExtensionPolicy].
+func (p *DataflowScenarioExtensionPolicy) Name() string {
+	return ScenarioExtensionPolicyDataflow
+}
+
+// ResolveCandidates implements [ScenarioExtensionPolicy].
+func (p *DataflowScenarioExtensionPolicy) ResolveCandidates(testScenario *TestScenario) ([]ScenarioExtensionCandidate, error) {
+	if p.APIManager.APIDataflowGraph == nil {
+		return nil, nil
+	}
+	getDataflowTargets := func(internalServiceEndpoint static.InternalServiceEndpoint) []static.InternalServiceEndpoint {
+		dataflowTargets := make([]static.InternalServiceEndpoint, 0)
+		for _, edge := range p.APIManager.APIDataflowGraph.AdjacencyList[internalServiceEndpoint] {
+			dataflowTargets = append(dataflowTargets, edge.Target)
+		}
+		return dataflowTargets
+	}
+	return resolveCandidatesByInternalRelations(p.Name(), p.APIManager, p.RuntimeReachabilityMap, testScenario, getDataflowTargets)
+}
+
+// RandomScenarioExtensionPolicy is a uniform random scenario extension policy.
+// The candidate is a random API method not in the scenario yet, see [static.APIManager.GetRandomAPIMethod].
+type RandomScenarioExtensionPolicy struct {
+	// APIManager is the API manager.
+	APIManager *static.APIManager
+}
+
+// NewRandomScenarioExtensionPolicy creates a new RandomScenarioExtensionPolicy.
+func NewRandomScenarioExtensionPolicy(APIManager *static.APIManager) *RandomScenarioExtensionPolicy {
+	return &RandomScenarioExtensionPolicy{
+		APIManager: APIManager,
+	}
+}
+
+// Name implements [ScenarioExtensionPolicy].
+func (p *RandomScenarioExtensionPolicy) Name() string {
+	return ScenarioExtensionPolicyRandom
+}
+
+// ResolveCandidates implements [ScenarioExtensionPolicy].
+func (p *RandomScenarioExtensionPolicy) ResolveCandidates(testScenario *TestScenario) ([]ScenarioExtensionCandidate, error) {
+	// Exclude the operations already in the scenario, so that the scenario is not extended with the same operations repeatedly.
+	scenarioAPIMethods := make(map[static.SimpleAPIMethod]struct{})
+	for _, operationCase := range testScenario.OperationCases {
+		scenarioAPIMethods[operationCase.APIMethod] = struct{}{}
+	}
+	return []ScenarioExtensionCandidate{{APIMethod: p.APIManager.GetRandomAPIMethod(scenarioAPIMethods, nil)}}, nil
+}
+
+// UnionScenarioExtensionPolicy combines policies, by taking the union of their candidates.
+type UnionScenarioExtensionPolicy struct {
+	// Policies are the combined policies.
+	Policies []ScenarioExtensionPolicy
+}
+
+// NewUnionScenarioExtensionPolicy creates a new UnionScenarioExtensionPolicy.
+func NewUnionScenarioExtensionPolicy(policies ...ScenarioExtensionPolicy) *UnionScenarioExtensionPolicy {
+	return &UnionScenarioExtensionPolicy{
+		Policies: policies,
+	}
+}
+
+// Name implements [ScenarioExtensionPolicy].
+func (p *UnionScenarioExtensionPolicy) Name() string {
+	return "union"
+}
+
+// ResolveCandidates implements [ScenarioExtensionPolicy].
+func (p *UnionScenarioExtensionPolicy) ResolveCandidates(testScenario *TestScenario) ([]ScenarioExtensionCandidate, error) {
+	candidates := make([]ScenarioExtensionCandidate, 0)
+	for _, policy := range p.Policies {
+		policyCandidates, err := policy.ResolveCandidates(testScenario)
+		if err != nil {
+			log.Err(err).Msgf("[UnionScenarioExtensionPolicy.ResolveCandidates] Failed to resolve candidates by policy %s", policy.Name())
+			return nil, err
+		}
+		candidates = append(candidates, policyCandidates...)
+	}
+	return candidates, nil
+}
+
+// WeightedScenarioExtensionPolicy combines policies, by selecting one of them by chance of its weight / sum(weights) for each extension.
+type WeightedScenarioExtensionPolicy struct {
+	// Policies are the combined policies.
+	Policies []ScenarioExtensionPolicy
+
+	// Weights are the positive weights of policies, in the same order of Policies.
+	Weights []int
+}
+
+// NewWeightedScenarioExtensionPolicy creates a new WeightedScenarioExtensionPolicy.
+// Weights should be positive, and in the same order of policies.
+func NewWeightedScenarioExtensionPolicy(policies []ScenarioExtensionPolicy, weights []int) *WeightedScenarioExtensionPolicy {
+	return &WeightedScenarioExtensionPolicy{
+		Policies: policies,
+		Weights:  weights,
+	}
+}
+
+// Name implements [ScenarioExtensionPolicy].
+func (p *WeightedScenarioExtensionPolicy) Name() string {
+	return "weighted"
+}
+
+// ResolveCandidates implements [ScenarioExtensionPolicy].
+func (p *WeightedScenarioExtensionPolicy) ResolveCandidates(testScenario *TestScenario) ([]ScenarioExtensionCandidate, error) {
+	totalWeight := 0
+	for _, weight := range p.Weights {
+		totalWeight += weight
+	}
+	if totalWeight <= 0 {
+		return nil, fmt.Errorf("no scenario extension policy with positive weight")
+	}
+	randomWeight := utils.SharedRand.IntN(totalWeight)
+	for i, policy := range p.Policies {
+		randomWeight -= p.Weights[i]
+		if randomWeight < 0 {
+			log.Debug().Msgf("[WeightedScenarioExtensionPolicy.ResolveCandidates] Extend scenario (UUID: %s) by policy %s", testScenario.UUID.String(), policy.Name())
+			return policy.ResolveCandidates(testScenario)
+		}
+	}
+	// Should not reach here.
+	return nil, fmt.Errorf("failed to select a scenario extension policy")
+}
+
+// getScenarioAPIMethods returns the sorted and deduplicated API methods of operation cases in the scenario.
+func getScenarioAPIMethods(testScenario *TestScenario) []static.SimpleAPIMethod {
+	apiMethods := make([]static.SimpleAPIMethod, 0, len(testScenario.OperationCases))
+	for _, operationCase := range testScenario.OperationCases {
+		apiMethods = append(apiMethods, operationCase.APIMethod)
+	}
+	slices.SortFunc(apiMethods, static.CompareSimpleAPIMethod)
+	return slices.Compact(apiMethods)
+}
+
+// resolveCandidatesByInternalRelations resolves candidates by producer-consumer relations of internal service endpoints:
+//  1. For each operation case (producer) in the existing scenario, get the internal service endpoints it called.
+//  2. For each internal service endpoint in step 1, get its consumers by getInternalConsumers.
+//  3. For each internal service consumer, find system APIs (consumers) that call it, as candidates.
+//
+// Use high confidence map only to get the internal service endpoints called in step 1 (i.e., the map that is updated from traces),
+// as the operation cases are executed successfully, and there should exist corresponding traces.
+func resolveCandidatesByInternalRelations(
+	policyName string,
+	APIManager *static.APIManager,
+	runtimeReachabilityMap *fuzzruntime.RuntimeReachabilityMap,
+	testScenario *TestScenario,
+	getInternalConsumers func(internalServiceEndpoint static.InternalServiceEndpoint) []static.InternalServiceEndpoint,
+) ([]ScenarioExtensionCandidate, error) {
+	// Internal service producers called by each system API in the scenario, and their internal service consumers.
+	producer2InternalProducers := make(map[static.SimpleAPIMethod][]static.InternalServiceEndpoint)
+	internalProducer2Consumers := make(map[static.InternalServiceEndpoint][]static.InternalServiceEndpoint)
+	internalConsumersSet := make(map[static.InternalServiceEndpoint]struct{})
+	producers := getScenarioAPIMethods(testScenario)
+	for _, producer := range producers {
+		internalProducers, err := runtimeReachabilityMap.GetReachableInternalEndpointsByExternalAPI(producer, true)
+		if err != nil {
+			log.Err(err).Msgf("[resolveCandidatesByInternalRelations] Failed to get reachable internal endpoints by external API %v", producer)
+			return nil, err
+		}
+		producer2InternalProducers[producer] = internalProducers
+		for _, internalProducer := range internalProducers {
+			if _, exist := internalProducer2Consumers[internalProducer]; exist {
+				continue
+			}
+			internalConsumers := getInternalConsumers(internalProducer)
+			internalProducer2Consumers[internalProducer] = internalConsumers
+			for _, internalConsumer := range internalConsumers {
+				internalConsumersSet[internalConsumer] = struct{}{}
+			}
+		}
+	}
+	internalConsumer2SystemAPIMethods, err := getSystemAPIMethodsReaching(APIManager, runtimeReachabilityMap, internalConsumersSet)
+	if err != nil {
+		return nil, err
+	}
+
+	candidates := make([]ScenarioExtensionCandidate, 0)
+	for _, producer := range producers {
+		for _, internalProducer := range producer2InternalProducers[producer] {
+			for _, internalConsumer := range internalProducer2Consumers[internalProducer] {
+				for _, consumer := range internalConsumer2SystemAPIMethods[internalConsumer] {
+					candidates = append(candidates, ScenarioExtensionCandidate{
+						APIMethod: consumer,
+						Relation: &ProducerConsumerRelation{
+							Policy:           policyName,
+							Producer:         producer,
+							Consumer:         consumer,
+							InternalProducer: internalProducer,
+							InternalConsumer: internalConsumer,
+						},
+					})
+				}
+			}
+		}
+	}
+	return candidates, nil
+}
+
+// getSystemAPIMethodsReaching returns the map from each of the given internal service endpoints to the system API methods which reach it.
+// System API methods of each internal service endpoint are sorted.
+func getSystemAPIMethodsReaching(
+	APIManager *static.APIManager,
+	runtimeReachabilityMap *fuzzruntime.RuntimeReachabilityMap,
+	internalServiceEndpointsSet map[static.InternalServiceEndpoint]struct{},
+) (map[static.InternalServiceEndpoint][]static.SimpleAPIMethod, error) {
+	internalServiceEndpoint2SystemAPIMethods := make(map[static.InternalServiceEndpoint][]static.SimpleAPIMethod)
+	if len(internalServiceEndpointsSet) == 0 {
+		return internalServiceEndpoint2SystemAPIMethods, nil
+	}
+	// Iterate all system APIs in order, resolve their internal service endpoints, and check if they are in the given set.
+	for _, systemAPIMethod := range slices.SortedFunc(maps.Keys(APIManager.APIMap), static.CompareSimpleAPIMethod) {
+		// We allow using low-confidence map here, as the system API might not have been executed yet.
+		reachableInternalServiceEndpoints, err := runtimeReachabilityMap.GetReachableInternalEndpointsByExternalAPI(systemAPIMethod, true)
+		if err != nil {
+			log.Err(err).Msgf("[getSystemAPIMethodsReaching] Failed to get reachable internal endpoints by external API %v", systemAPIMethod)
+			return nil, err
+		}
+		for _, reachableInternalServiceEndpoint := range slices.Compact(slices.SortedFunc(slices.Values(reachableInternalServiceEndpoints), static.CompareInternalServiceEndpoint)) {
+			if _, exist := internalServiceEndpointsSet[reachableInternalServiceEndpoint]; exist {
+				internalServiceEndpoint2SystemAPIMethods[reachableInternalServiceEndpoint] = append(internalServiceEndpoint2SystemAPIMethods[reachableInternalServiceEndpoint], systemAPIMethod)
+			}
+		}
+	}
+	return internalServiceEndpoint2SystemAPIMethods, nil
+}
@@ -0,0 +1,182 @@
+package casemanager
+
+import (
+	"fmt"
+	"maps"
+	"math/rand/v2"
+	"resttracefuzzer/pkg/utils"
+	"resttracefuzzer/pkg/utils/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/bytedance/sonic"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	// MeshHeaderPresetB3 adds [B3 multi headers](https://github.com/openzipkin/b3-propagation), i.e., `x-b3-traceid`, `x-b3-spanid` and `x-b3-sampled`.
+	MeshHeaderPresetB3 = "b3"
+
+	// MeshHeaderPresetRequestID adds the `x-request-id` header, which Envoy uses to correlate logs and traces.
+	MeshHeaderPresetRequestID = "request-id"
+
+	// MeshHeaderPresetBaggage adds the [W3C baggage](https://www.w3.org/TR/baggage/) header, carrying the test scenario UUID and the index of the request in it.
+	MeshHeaderPresetBaggage = "baggage"
+
+	// MeshHeaderPresetIstio adds all headers Istio requires applications to propagate, i.e., [MeshHeaderPresetB3], [MeshHeaderPresetRequestID] and [MeshHeaderPresetBaggage].
+	MeshHeaderPresetIstio = "istio"
+
+	// B3TraceIDHeaderKey is the B3 header of the trace ID.
+	B3TraceIDHeaderKey = http.B3TraceIDHeaderKey
+
+	// B3SpanIDHeaderKey is the B3 header of the span ID.
+	B3SpanIDHeaderKey = http.B3SpanIDHeaderKey
+
+	// B3SampledHeaderKey is the B3 header of the sampling decision.
+	B3SampledHeaderKey = "x-b3-sampled"
+
+	// RequestIDHeaderKey is the header of the request ID.
+	RequestIDHeaderKey = "x-request-id"
+
+	// BaggageHeaderKey is the header of the W3C baggage.
+	BaggageHeaderKey = "baggage"
+
+	// TraceparentHeaderKey is the W3C trace context header of the trace ID, parent (span) ID and trace flags, e.g., `00-{trace ID}-{parent ID}-01`.
+	// It is added by clients to each request, see [http.InjectTraceContext].
+	TraceparentHeaderKey = http.TraceparentHeaderKey
+
+	// traceparentVersion is the version of traceparent headers added.
+	traceparentVersion = "00"
+
+	// traceparentSampledFlags are the trace flags of traceparent headers added, i.e., sampled.
+	traceparentSampledFlags = "01"
+)
+
+// MeshHeaderPropagator adds headers required by service meshes (e.g., Istio) to each request,
+// since trace stitching breaks in mesh-based systems if they are missing.
+// Each request starts a new trace (so its trace can be fetched alone), while the baggage is consistent across a test scenario,
+// so that all requests of the scenario can be correlated.
+type MeshHeaderPropagator struct {
+	// Presets are the enabled presets, e.g., [MeshHeaderPresetB3]. [MeshHeaderPresetIstio] is expanded into the presets it consists of.
+	Presets []string
+
+	// Rand is the random number generator of trace and span IDs, which is [utils.SharedRand] by default.
+	Rand *rand.Rand
+}
+
+// NewMeshHeaderPropagator creates a new MeshHeaderPropagator from a stringified JSON list of presets, e.g., `["istio"]`.
+// No header is added if presetsStr is empty. It returns an error if presetsStr is invalid or contains an unknown preset.
+func NewMeshHeaderPropagator(presetsStr string) (*MeshHeaderPropagator, error) {
+	presets := make([]string, 0)
+	if presetsStr != "" {
+		presetNames := make([]string, 0)
+		err := sonic.UnmarshalString(presetsStr, &presetNames)
+		if err != nil {
+			log.Err(err).Msg("[NewMeshHeaderPropagator] Failed to unmarshal mesh header presets")
+			return nil, err
+		}
+		for _, presetName := range presetNames {
+			var expandedPresets []string
+			switch presetName {
+			case MeshHeaderPresetIstio:
+				expandedPresets = []string{MeshHeaderPresetB3, MeshHeaderPresetRequestID, MeshHeaderPresetBaggage}
+			case MeshHeaderPresetB3, MeshHeaderPresetRequestID, MeshHeaderPresetBaggage:
+				expandedPresets = []string{presetName}
+			default:
+				return nil, fmt.Errorf("unknown mesh header preset: %s", presetName)
+			}
+			for _, preset := range expandedPresets {
+				if !slices.Contains(presets, preset) {
+					presets = append(presets, preset)
+				}
+			}
+		}
+	}
+	return &MeshHeaderPropagator{
+		Presets: presets,
+		Rand:    utils.SharedRand,
+	}, nil
+}
+
+// BuildHeaders builds mesh headers of the request at the index of the test scenario.
+// B3 trace and span IDs are replaced by those of the trace started by clients for each attempt of the request, if enabled, see [http.InjectTraceContext].
+// It returns an empty map if no preset is enabled.
+func (p *MeshHeaderPropagator) BuildHeaders(testScenario *TestScenario, index int) map[string]string {
+	headers := make(map[string]string)
+	var traceID, spanID string
+	if slices.Contains(p.Presets, MeshHeaderPresetB3) {
+		traceID = p.randomHexID(16)
+		spanID = p.randomHexID(8)
+	}
+	for _, preset := range p.Presets {
+		switch preset {
+		case MeshHeaderPresetB3:
+			headers[B3TraceIDHeaderKey] = traceID
+			headers[B3SpanIDHeaderKey] = spanID
+			headers[B3SampledHeaderKey] = "1"
+		case MeshHeaderPresetRequestID:
+			headers[RequestIDHeaderKey] = uuid.NewString()
+		case MeshHeaderPresetBaggage:
+			headers[BaggageHeaderKey] = "resttracefuzzer.scenario=" + testScenario.UUID.String() + ",resttracefuzzer.step=" + strconv.Itoa(index)
+		}
+	}
+	return headers
+}
+
+// RenewHeaders returns a copy of the request headers, with the mesh headers identifying the request (i.e., B3, traceparent and request ID headers) renewed,
+// so that the request resent (e.g., to another version of the system) starts a trace of its own. The baggage is kept, as the request is still of the same test scenario.
+// Headers not in requestHeaders are not added.
+func (p *MeshHeaderPropagator) RenewHeaders(requestHeaders map[string]string) map[string]string {
+	headers := maps.Clone(requestHeaders)
+	_, hasB3 := headers[B3TraceIDHeaderKey]
+	_, hasTraceparent := headers[TraceparentHeaderKey]
+	if hasB3 || hasTraceparent {
+		traceID := p.randomHexID(16)
+		spanID := p.randomHexID(8)
+		if hasB3 {
+			headers[B3TraceIDHeaderKey] = traceID
+			headers[B3SpanIDHeaderKey] = spanID
+		}
+		if hasTraceparent {
+			headers[TraceparentHeaderKey] = strings.Join([]string{traceparentVersion, traceID, spanID, traceparentSampledFlags}, "-")
+		}
+	}
+	if _, exist := headers[RequestIDHeaderKey]; exist {
+		headers[RequestIDHeaderKey] = uuid.NewString()
+	}
+	return headers
+}
+
+// randomHexID returns a random non-zero ID of byteCount bytes, in lowercase hex.
+func (p *MeshHeaderPropagator) randomHexID(byteCount int) string {
+	for {
+		id := make([]byte, byteCount)
+		allZero := true
+		for i := range id {
+			id[i] = byte(p.Rand.IntN(256))
+			allZero = allZero && id[i] == 0
+		}
+		// All-zero IDs are invalid in B3 and W3C trace context.
+		if !allZero {
+			return fmt.Sprintf("%x", id)
+		}
+	}
+}
+
+// GetClientTraceID returns the ID of the trace started by the request headers, i.e., by the traceparent or B3 header (see [MeshHeaderPropagator]).
+// Clients injecting trace context record the trace they start instead, see [http.ContextWithClientTraceID].
+// It returns false if the request does not start a trace.
+func GetClientTraceID(requestHeaders map[string]string) (string, bool) {
+	if traceparent, exist := requestHeaders[TraceparentHeaderKey]; exist {
+		// The traceparent is `{version}-{trace ID}-{parent ID}-{trace flags}`.
+		if fields := strings.Split(traceparent, "-"); len(fields) == 4 && len(fields[1]) == 32 {
+			return fields[1], true
+		}
+	}
+	if traceID, exist := requestHeaders[B3TraceIDHeaderKey]; exist && traceID != "" {
+		return traceID, true
+	}
+	return "", false
+}
@@ -0,0 +1,120 @@
+package casemanager
+
+import (
+	"fmt"
+	"maps"
+	"path"
+	"regexp"
+	"resttracefuzzer/pkg/static"
+	"resttracefuzzer/pkg/utils"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bytedance/sonic"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+)
+
+// headerTemplatePattern matches placeholders in header values, e.g., `{{uuid}}` or `{{ now }}`.
+var headerTemplatePattern = regexp.MustCompile(`\{\{\s*(\w+)\s*\}\}`)
+
+// OperationHeaderRule is a set of extra headers added to requests of the API methods it matches,
+// overriding global extra headers, e.g., an `X-Admin-Token` header only for `DELETE /users/*`.
+type OperationHeaderRule struct {
+	// Method is the HTTP method of matched API methods (case-insensitive). Empty or `*` matches all methods.
+	Method string `json:"method"`
+
+	// Path is a glob pattern of endpoints of matched API methods, in the syntax of [path.Match], e.g., `/pets/*` matches `/pets/{petId}`.
+	// Note that `*` does not match `/`. Empty matches all endpoints.
+	Path string `json:"path"`
+
+	// Headers are the headers added to requests of matched API methods. Values can contain templates, see [ResolveHeaderTemplates].
+	Headers map[string]string `json:"headers"`
+}
+
+// ParseOperationHeaderRules parses operation header rules from a stringified JSON list,
+// e.g., `[{"method": "DELETE", "path": "/users/*", "headers": {"X-Admin-Token": "secret"}}]`.
+// It returns an error if rulesStr is invalid, or a path pattern is malformed.
+func ParseOperationHeaderRules(rulesStr string) ([]*OperationHeaderRule, error) {
+	rules := make([]*OperationHeaderRule, 0)
+	if rulesStr == "" {
+		return rules, nil
+	}
+	err := sonic.UnmarshalString(rulesStr, &rules)
+	if err != nil {
+		log.Err(err).Msg("[ParseOperationHeaderRules] Failed to unmarshal operation header rules")
+		return nil, err
+	}
+	for _, rule := range rules {
+		if rule == nil {
+			return nil, fmt.Errorf("operation header rule is null")
+		}
+		if _, err := path.Match(rule.Path, ""); err != nil {
+			return nil, fmt.Errorf("invalid path pattern %s of operation header rule: %w", rule.Path, err)
+		}
+	}
+	return rules, nil
+}
+
+// Matches returns whether the rule matches the API method.
+func (r *OperationHeaderRule) Matches(APIMethod static.SimpleAPIMethod) bool {
+	if r.Method != "" && r.Method != "*" && !strings.EqualFold(r.Method, APIMethod.Method) {
+		return false
+	}
+	if r.Path == "" {
+		return true
+	}
+	matched, _ := path.Match(r.Path, APIMethod.Endpoint)
+	return matched
+}
+
+// ResolveHeaderTemplates returns a copy of the headers, with templates in values resolved. Supported templates are:
+//   - `{{uuid}}`: a random UUID, e.g., for request IDs.
+//   - `{{now}}`: the current time in RFC 3339 format.
+//   - `{{timestamp}}`: the current Unix timestamp in seconds.
+//   - `{{timestamp_ms}}`: the current Unix timestamp in milliseconds.
+//   - `{{random}}`: a random non-negative integer.
+//
+// Each occurrence of a template is resolved separately, and unknown templates are kept as is.
+func ResolveHeaderTemplates(headers map[string]string) map[string]string {
+	resolved := make(map[string]string, len(headers))
+	for key, value := range headers {
+		if !strings.Contains(value, "{{") {
+			resolved[key] = value
+			continue
+		}
+		resolved[key] = headerTemplatePattern.ReplaceAllStringFunc(value, func(placeholder string) string {
+			switch headerTemplatePattern.FindStringSubmatch(placeholder)[1] {
+			case "uuid":
+				return uuid.NewString()
+			case "now":
+				return time.Now().Format(time.RFC3339)
+			case "timestamp":
+				return strconv.FormatInt(time.Now().Unix(), 10)
+			case "timestamp_ms":
+				return strconv.FormatInt(time.Now().UnixMilli(), 10)
+			case "random":
+				return strconv.FormatInt(utils.SharedRand.Int64(), 10)
+			default:
+				return placeholder
+			}
+		})
+	}
+	return resolved
+}
+
+// buildConfiguredHeaders builds the headers configured for requests of the API method, i.e., global extra headers,
+// overridden by headers of matched operation header rules (in order), with templates resolved.
+func (m *CaseManager) buildConfiguredHeaders(APIMethod static.SimpleAPIMethod) map[string]string {
+	headers := maps.Clone(m.GlobalExtraHeaders)
+	if headers == nil {
+		headers = make(map[string]string)
+	}
+	for _, rule := range m.OperationHeaderRules {
+		if rule.Matches(APIMethod) {
+			maps.Copy(headers, rule.Headers)
+		}
+	}
+	return ResolveHeaderTemplates(headers)
+}
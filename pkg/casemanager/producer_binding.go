@@ -0,0 +1,112 @@
+package casemanager
+
+import (
+	"resttracefuzzer/pkg/resource"
+	"resttracefuzzer/pkg/static"
+	"resttracefuzzer/pkg/strategy"
+	"resttracefuzzer/pkg/utils/http"
+	"slices"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/rs/zerolog/log"
+)
+
+// bindablePropertyTypes are types of values bound from responses of producers.
+// Only primitive values (e.g., ids) are bound, as objects and arrays are mostly not identifiers.
+var bindablePropertyTypes = []static.SimpleAPIPropertyType{
+	static.SimpleAPIPropertyTypeString,
+	static.SimpleAPIPropertyTypeInteger,
+	static.SimpleAPIPropertyTypeFloat,
+	static.SimpleAPIPropertyTypeBoolean,
+}
+
+// BindProducerValues binds values in the responses of producers executed before the operation case in the test scenario
+// to its matching path, query and request body parameters, by bindings of the API dependency graph (see [static.APIDependencyBinding]),
+// rather than relying only on the name-based resource pool, e.g., the id of the product created by `POST /api/products`
+// is sent as `productId` of the following `GET /api/products/{productId}`.
+// The closest producer takes precedence, and producers not responding with success are skipped.
+// Operation cases of injected scenarios or in negative mode are kept as populated.
+// It should be called right before the operation case is executed, and returns the number of parameters bound.
+func (m *CaseManager) BindProducerValues(testScenario *TestScenario, operationCase *OperationCase) int {
+	if testScenario.Injected || operationCase.NegativeMode || operationCase.Operation == nil || m.APIManager.APIDependencyGraph == nil {
+		return 0
+	}
+	index := slices.Index(testScenario.OperationCases, operationCase)
+	if index <= 0 {
+		return 0
+	}
+
+	boundTargets := make(map[string]struct{})
+	for i := index - 1; i >= 0; i-- {
+		producer := testScenario.OperationCases[i]
+		bindings := m.APIManager.APIDependencyGraph.GetBindings(producer.APIMethod, operationCase.APIMethod)
+		if len(bindings) == 0 || !http.IsStatusCodeSuccess(producer.ResponseStatusCode) || len(producer.ResponseBody) == 0 {
+			continue
+		}
+		responseResource, err := resource.NewResourceFromRawBytes(producer.ResponseBody)
+		if err != nil {
+			log.Debug().Msgf("[CaseManager.BindProducerValues] Response of producer %v is not JSON, skip it: %v", producer.APIMethod, err)
+			continue
+		}
+		for _, binding := range bindings {
+			if _, bound := boundTargets[binding.TargetProperty.Name]; bound {
+				continue
+			}
+			owner := resource.FindPropertyOwner(responseResource, binding.SourceProperty.Name)
+			if owner == nil {
+				continue
+			}
+			value := owner.Value[binding.SourceProperty.Name]
+			if !slices.Contains(bindablePropertyTypes, value.Typ()) {
+				continue
+			}
+			if bindValueToOperationCase(operationCase, binding.TargetProperty.Name, value) {
+				boundTargets[binding.TargetProperty.Name] = struct{}{}
+				log.Debug().Msgf("[CaseManager.BindProducerValues] Bound %s of producer %v to %s of consumer %v, value: %s", binding.SourceProperty.Name, producer.APIMethod, binding.TargetProperty.Name, operationCase.APIMethod, value.String())
+			}
+		}
+	}
+	return len(boundTargets)
+}
+
+// bindValueToOperationCase sets the value to the path or query parameter of the given name declared by the operation,
+// or else to the shallowest request body property of the name, see [resource.FindPropertyOwner].
+// It returns false if the operation case has no such parameter.
+func bindValueToOperationCase(operationCase *OperationCase, name string, value resource.Resource) bool {
+	for _, param := range operationCase.Operation.Parameters {
+		if param == nil || param.Value == nil || param.Value.Name != name {
+			continue
+		}
+		switch param.Value.In {
+		case openapi3.ParameterInPath:
+			if operationCase.RequestPathParams == nil {
+				operationCase.RequestPathParams = make(map[string]string)
+			}
+			operationCase.RequestPathParams[name] = strategy.PathParamResourceString(value)
+			if operationCase.RequestPathParamResources != nil {
+				operationCase.RequestPathParamResources[name] = value.Copy()
+			}
+			return true
+		case openapi3.ParameterInQuery:
+			if operationCase.RequestQueryParams == nil {
+				operationCase.RequestQueryParams = make(map[string]string)
+			}
+			operationCase.RequestQueryParams[name] = value.String()
+			if operationCase.RequestQueryParamResources != nil {
+				operationCase.RequestQueryParamResources[name] = value.Copy()
+			}
+			return true
+		}
+	}
+
+	if operationCase.RequestBodyResource == nil {
+		return false
+	}
+	owner := resource.FindPropertyOwner(operationCase.RequestBodyResource, name)
+	if owner == nil {
+		return false
+	}
+	owner.Value[name] = value.Copy()
+	operationCase.SetRequestBodyByResource(operationCase.RequestBodyResource)
+	return true
+}
@@ -0,0 +1,120 @@
+package casemanager
+
+import (
+	"cmp"
+	"maps"
+	fuzzruntime "resttracefuzzer/pkg/runtime"
+	"resttracefuzzer/pkg/static"
+	"slices"
+	"strings"
+)
+
+// ProducerConsumerRelation is a producer-consumer relation between system API methods, deduced by a scenario extension policy (see [ScenarioExtensionPolicy]).
+// It justifies extending a test scenario containing the producer with the consumer.
+// It is used by value, and can be used as a map key.
+type ProducerConsumerRelation struct {
+	// Policy is the name of the scenario extension policy deducing the relation, e.g., `dependency`.
+	Policy string `json:"policy"`
+
+	// Producer is the system API method in the test scenario.
+	Producer static.SimpleAPIMethod `json:"producer"`
+
+	// Consumer is the system API method the test scenario is extended with.
+	Consumer static.SimpleAPIMethod `json:"consumer"`
+
+	// InternalProducer is the internal service endpoint called by the producer, from which the relation is deduced.
+	// It is empty for system-level relations, e.g., from the API dependency graph.
+	InternalProducer static.InternalServiceEndpoint `json:"internalProducer"`
+
+	// InternalConsumer is the internal service endpoint called by the consumer, which consumes the data of InternalProducer
+	// (by internal service API dependencies or dataflow edges).
+	// It is empty for system-level relations, e.g., from the API dependency graph.
+	InternalConsumer static.InternalServiceEndpoint `json:"internalConsumer"`
+}
+
+// CompareProducerConsumerRelation compares two ProducerConsumerRelation, by policy, producer, consumer, and internal service endpoints in order.
+func CompareProducerConsumerRelation(a, b ProducerConsumerRelation) int {
+	return cmp.Or(
+		strings.Compare(a.Policy, b.Policy),
+		static.CompareSimpleAPIMethod(a.Producer, b.Producer),
+		static.CompareSimpleAPIMethod(a.Consumer, b.Consumer),
+		static.CompareInternalServiceEndpoint(a.InternalProducer, b.InternalProducer),
+		static.CompareInternalServiceEndpoint(a.InternalConsumer, b.InternalConsumer),
+	)
+}
+
+// GetConfidence returns the confidence score (in [0, 1]) of the relation, by the confidence of reachability it is deduced from,
+// i.e., from the producer to InternalProducer and from the consumer to InternalConsumer (see [fuzzruntime.RuntimeReachabilityMap.GetConfidence]).
+// It is 1 for system-level relations, which are not deduced from reachability.
+func (r ProducerConsumerRelation) GetConfidence(runtimeReachabilityMap *fuzzruntime.RuntimeReachabilityMap) float64 {
+	if r.InternalProducer == (static.InternalServiceEndpoint{}) || runtimeReachabilityMap == nil {
+		return 1
+	}
+	return runtimeReachabilityMap.GetConfidence(r.Producer, r.InternalProducer) * runtimeReachabilityMap.GetConfidence(r.Consumer, r.InternalConsumer)
+}
+
+// ProducerConsumerRelationStat is the statistics of a producer-consumer relation actually used to extend test scenarios.
+// Relations with many executions but few successes are likely false (e.g., false dataflow edges), and can be pruned.
+type ProducerConsumerRelationStat struct {
+	ProducerConsumerRelation
+
+	// ExtensionCount is the number of test scenarios extended by the relation.
+	ExtensionCount int `json:"extensionCount"`
+
+	// ExecutionCount is the number of executions of operation cases appended to test scenarios by the relation.
+	ExecutionCount int `json:"executionCount"`
+
+	// SuccessCount is the number of successful (2xx) executions among ExecutionCount.
+	SuccessCount int `json:"successCount"`
+}
+
+// ProducerConsumerRelationTracker tracks the producer-consumer relations actually used to extend test scenarios, and the execution results of the extensions.
+type ProducerConsumerRelationTracker struct {
+	// Stats maps from the relation to its statistics.
+	Stats map[ProducerConsumerRelation]*ProducerConsumerRelationStat
+}
+
+// NewProducerConsumerRelationTracker creates a new ProducerConsumerRelationTracker.
+func NewProducerConsumerRelationTracker() *ProducerConsumerRelationTracker {
+	return &ProducerConsumerRelationTracker{
+		Stats: make(map[ProducerConsumerRelation]*ProducerConsumerRelationStat),
+	}
+}
+
+// RecordExtension records that a test scenario is extended, justified by the relations.
+func (t *ProducerConsumerRelationTracker) RecordExtension(relations []ProducerConsumerRelation) {
+	for _, relation := range relations {
+		t.getOrCreateStat(relation).ExtensionCount++
+	}
+}
+
+// RecordExecution records an execution of an operation case appended to a test scenario by the relations.
+func (t *ProducerConsumerRelationTracker) RecordExecution(relations []ProducerConsumerRelation, success bool) {
+	for _, relation := range relations {
+		stat := t.getOrCreateStat(relation)
+		stat.ExecutionCount++
+		if success {
+			stat.SuccessCount++
+		}
+	}
+}
+
+// GetStats returns statistics of all relations, sorted by the extension count in descending order, and then by the relation.
+func (t *ProducerConsumerRelationTracker) GetStats() []*ProducerConsumerRelationStat {
+	return slices.SortedFunc(maps.Values(t.Stats), func(a, b *ProducerConsumerRelationStat) int {
+		return cmp.Or(
+			cmp.Compare(b.ExtensionCount, a.ExtensionCount),
+			CompareProducerConsumerRelation(a.ProducerConsumerRelation, b.ProducerConsumerRelation),
+		)
+	})
+}
+
+// getOrCreateStat returns the statistics of the relation, and creates it if not exist.
+func (t *ProducerConsumerRelationTracker) getOrCreateStat(relation ProducerConsumerRelation) *ProducerConsumerRelationStat {
+	stat, exist := t.Stats[relation]
+	if !exist {
+		stat = &ProducerConsumerRelationStat{ProducerConsumerRelation: relation}
+		t.Stats[relation] = stat
+	}
+	return stat
+}
@@ -0,0 +1,84 @@
+package casemanager
+
+import (
+	"net/url"
+	"resttracefuzzer/pkg/parser"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+)
+
+// SeedScenarioEnergy is the energy of test scenarios injected from seed scenarios, see [CaseManager.InjectSeedScenarios].
+// Recorded traffic is realistic, so it is preferred to scenarios of random values.
+const SeedScenarioEnergy = MaxScenarioEnergy / 2
+
+// InjectSeedScenarios converts seed scenarios (recorded traffic, see [parser.SeedParser]) into test scenarios, and injects them (see [CaseManager.InjectTestScenario]),
+// so that fuzzing starts from realistic sequences of requests, besides single-operation test scenarios from the API doc.
+// Each recorded request is matched to the API method serving it (see [static.APIManager.MatchHTTPRequest]) by its path relative to the path of serverBaseURL,
+// and its path and query parameters, headers and body are kept. Requests matching no API method are skipped.
+// It returns the number of injected test scenarios.
+func (m *CaseManager) InjectSeedScenarios(seedScenarios []*parser.SeedScenario, serverBaseURL string) int {
+	basePath := ""
+	if u, err := url.Parse(serverBaseURL); err == nil {
+		basePath = strings.TrimSuffix(u.Path, "/")
+	}
+	injectedCount := 0
+	for _, seedScenario := range seedScenarios {
+		operationCases := make([]*OperationCase, 0, len(seedScenario.Requests))
+		for _, seedRequest := range seedScenario.Requests {
+			operationCase, ok := m.convertSeedRequest(seedRequest, basePath)
+			if !ok {
+				log.Debug().Msgf("[CaseManager.InjectSeedScenarios] Skip request %s %s of seed scenario %s, which matches no API method", seedRequest.Method, seedRequest.URL, seedScenario.Name)
+				continue
+			}
+			operationCases = append(operationCases, operationCase)
+		}
+		if len(operationCases) == 0 {
+			log.Warn().Msgf("[CaseManager.InjectSeedScenarios] No request of seed scenario %s matches any API method, skip it", seedScenario.Name)
+			continue
+		}
+		testScenario, err := m.InjectTestScenario(operationCases, SeedScenarioEnergy)
+		if err != nil {
+			log.Err(err).Msgf("[CaseManager.InjectSeedScenarios] Failed to inject seed scenario %s", seedScenario.Name)
+			continue
+		}
+		log.Info().Msgf("[CaseManager.InjectSeedScenarios] Injected seed scenario %s with %d of %d requests as test scenario (UUID: %s)", seedScenario.Name, len(operationCases), len(seedScenario.Requests), testScenario.UUID.String())
+		injectedCount++
+	}
+	return injectedCount
+}
+
+// convertSeedRequest converts a recorded request to an operation case of the API method serving it.
+// The path of the request is taken relative to basePath (if it is a prefix of it).
+// It returns false if the URL is invalid, no API method matches the request, or the request is excluded by the operation filter
+// (see [static.OperationFilter.IsRequestExcluded]).
+func (m *CaseManager) convertSeedRequest(seedRequest *parser.SeedRequest, basePath string) (*OperationCase, bool) {
+	u, err := url.Parse(seedRequest.URL)
+	if err != nil {
+		log.Err(err).Msgf("[CaseManager.convertSeedRequest] Invalid URL of recorded request: %s", seedRequest.URL)
+		return nil, false
+	}
+	requestPath := u.EscapedPath()
+	if basePath != "" && strings.HasPrefix(requestPath, basePath+"/") {
+		requestPath = strings.TrimPrefix(requestPath, basePath)
+	}
+	if m.APIManager.OperationFilter.IsRequestExcluded(seedRequest.Method, requestPath) {
+		log.Debug().Msgf("[CaseManager.convertSeedRequest] Request %s %s is excluded by operation filter", seedRequest.Method, seedRequest.URL)
+		return nil, false
+	}
+	apiMethod, pathParams, ok := m.APIManager.MatchHTTPRequest(seedRequest.Method, requestPath)
+	if !ok {
+		return nil, false
+	}
+	operationCase := NewOperationCase(apiMethod, nil)
+	operationCase.RequestHeaders = cloneOrEmpty(seedRequest.Headers)
+	operationCase.RequestPathParams = pathParams
+	operationCase.RequestQueryParams = make(map[string]string)
+	for key, values := range u.Query() {
+		if len(values) > 0 {
+			operationCase.RequestQueryParams[key] = values[0]
+		}
+	}
+	operationCase.RequestBody = seedRequest.Body
+	return operationCase, true
+}
@@ -0,0 +1,111 @@
+package casemanager
+
+import (
+	"fmt"
+	"maps"
+	"resttracefuzzer/pkg/static"
+	"strings"
+
+	"github.com/bytedance/sonic"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+)
+
+// UserSession is an identity (e.g., a user or a tenant) requests can be sent as.
+// Multiple user sessions can be configured to detect broken object level authorization (BOLA, also known as IDOR),
+// where a user can access resources created by another one.
+type UserSession struct {
+	// Name is the name of the user, used in logs and reports.
+	Name string `json:"name"`
+
+	// Headers are the credential headers of the user, e.g., `Authorization` or `X-Tenant-Id`.
+	// They are added to requests sent as the user, overriding global extra headers and headers of operation header rules.
+	Headers map[string]string `json:"headers"`
+}
+
+// ParseUserSessions parses user sessions from a stringified JSON list,
+// e.g., `[{"name": "alice", "headers": {"Authorization": "Bearer a"}}, {"name": "bob", "headers": {"Authorization": "Bearer b"}}]`.
+// Names of user sessions must be non-empty and unique.
+func ParseUserSessions(userSessionsStr string) ([]*UserSession, error) {
+	userSessions := make([]*UserSession, 0)
+	if userSessionsStr == "" {
+		return userSessions, nil
+	}
+	err := sonic.UnmarshalString(userSessionsStr, &userSessions)
+	if err != nil {
+		log.Err(err).Msg("[ParseUserSessions] Failed to unmarshal user sessions")
+		return nil, err
+	}
+	names := make(map[string]struct{})
+	for _, userSession := range userSessions {
+		if userSession == nil || userSession.Name == "" {
+			return nil, fmt.Errorf("user session name is empty")
+		}
+		if _, exists := names[userSession.Name]; exists {
+			return nil, fmt.Errorf("duplicate user session name: %s", userSession.Name)
+		}
+		names[userSession.Name] = struct{}{}
+	}
+	return userSessions, nil
+}
+
+// CheckUserSessionHeadersNotOverridden checks that no credential header of user sessions is in overriddenHeaders (case insensitive),
+// i.e., headers set on every request by HTTP client middlewares, e.g., `Authorization` by OAuth2.
+// Otherwise, replays as other users are sent with credentials of the primary user, and succeed as false positives of BOLA.
+// It returns nil if fewer than 2 user sessions are configured, as no request is replayed.
+func CheckUserSessionHeadersNotOverridden(userSessions []*UserSession, overriddenHeaders []string) error {
+	if len(userSessions) < 2 {
+		return nil
+	}
+	for _, userSession := range userSessions {
+		for key := range userSession.Headers {
+			for _, overriddenHeader := range overriddenHeaders {
+				if strings.EqualFold(key, overriddenHeader) {
+					return fmt.Errorf("header %s of user session %s is overridden by HTTP client middlewares", key, userSession.Name)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// GetPrimaryUserSession returns the primary user session, i.e., the first configured one, which all test scenarios are executed as.
+// It returns nil if no user session is configured.
+func (m *CaseManager) GetPrimaryUserSession() *UserSession {
+	if len(m.UserSessions) == 0 {
+		return nil
+	}
+	return m.UserSessions[0]
+}
+
+// DeriveOperationCaseForUser derives an operation case sending the same request as the given one, but as another user.
+// The response part of the derived operation case is empty, and it is not put into any queue.
+func (m *CaseManager) DeriveOperationCaseForUser(operationCase *OperationCase, userSession *UserSession) *OperationCase {
+	derivedOperationCase := operationCase.Copy()
+	// Keep other headers (e.g., header parameters) of the request, but replace credentials of the primary user.
+	requestHeaders := maps.Clone(operationCase.RequestHeaders)
+	if requestHeaders == nil {
+		requestHeaders = make(map[string]string)
+	}
+	if primaryUserSession := m.GetPrimaryUserSession(); primaryUserSession != nil {
+		configuredHeaders := m.buildConfiguredHeaders(operationCase.APIMethod)
+		for key := range primaryUserSession.Headers {
+			delete(requestHeaders, key)
+			if value, exists := configuredHeaders[key]; exists {
+				requestHeaders[key] = value
+			}
+		}
+	}
+	maps.Copy(requestHeaders, userSession.Headers)
+	// The idempotency key of the primary user should not be reused, otherwise the server may respond with the cached response.
+	if idempotencyKeyHeader := static.GetIdempotencyKeyHeader(operationCase.Operation); idempotencyKeyHeader != "" {
+		requestHeaders[idempotencyKeyHeader] = uuid.NewString()
+	}
+	derivedOperationCase.RequestHeaders = requestHeaders
+	derivedOperationCase.UserName = userSession.Name
+	derivedOperationCase.ResponseHeaders = make(map[string]string)
+	derivedOperationCase.ResponseStatusCode = 0
+	derivedOperationCase.ResponseBody = nil
+	derivedOperationCase.Reset()
+	return derivedOperationCase
+}
@@ -0,0 +1,141 @@
+package feedback
+
+import (
+	"resttracefuzzer/pkg/casemanager"
+	"resttracefuzzer/pkg/static"
+	"resttracefuzzer/pkg/utils/http"
+	"strings"
+
+	"github.com/cloudwego/hertz/pkg/protocol/consts"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+)
+
+// BOLAViolation records a broken object level authorization (BOLA, also known as IDOR) found in a test scenario,
+// i.e., a user succeeds in reading or mutating a resource created by another user.
+type BOLAViolation struct {
+	// TestScenarioUUID is the UUID of the test scenario in which the violation is found.
+	TestScenarioUUID uuid.UUID `json:"testScenarioUUID"`
+
+	// ResourcePath is the concrete path of the resource, e.g., `/users/42`.
+	ResourcePath string `json:"resourcePath"`
+
+	// Owner is the name of the user who created the resource.
+	Owner string `json:"owner"`
+
+	// Cause is the API method creating the resource.
+	Cause static.SimpleAPIMethod `json:"cause"`
+
+	// Attacker is the name of the user who accesses the resource created by the owner.
+	Attacker string `json:"attacker"`
+
+	// Witness is the API method the attacker succeeds in.
+	Witness static.SimpleAPIMethod `json:"witness"`
+
+	// WitnessStatusCode is the response status code of the witness.
+	WitnessStatusCode int `json:"witnessStatusCode"`
+}
+
+// bolaOwnedResource is a resource created in a test scenario, with its owner.
+type bolaOwnedResource struct {
+	// owner is the name of the user who created the resource.
+	owner string
+
+	// cause is the API method creating the resource.
+	cause static.SimpleAPIMethod
+}
+
+// BOLAOracle detects broken object level authorization, with multiple user sessions (see [casemanager.UserSession]).
+// It tracks resources created in a test scenario (POST on a collection path, see [CRUDOracle]) with their owners.
+// Before an operation case reads or mutates such a resource (or its sub-resources), the fuzzer replays the request as other users,
+// and the oracle flags successful replays.
+type BOLAOracle struct {
+	// Violations are all violations found so far.
+	Violations []*BOLAViolation
+
+	// collection2ItemPath maps from a collection path to its item path, e.g., from `/users` to `/users/{id}`.
+	collection2ItemPath map[string]string
+
+	// ownedResources maps from the concrete path of resources created in current test scenario to the resource.
+	ownedResources map[string]*bolaOwnedResource
+}
+
+// NewBOLAOracle creates a new BOLAOracle.
+func NewBOLAOracle(APIManager *static.APIManager) *BOLAOracle {
+	return &BOLAOracle{
+		Violations:          make([]*BOLAViolation, 0),
+		collection2ItemPath: getCollection2ItemPath(APIManager),
+		ownedResources:      make(map[string]*bolaOwnedResource),
+	}
+}
+
+// BeginScenario resets the resources tracked, as resources created in a test scenario are only tracked in the scenario.
+func (o *BOLAOracle) BeginScenario() {
+	o.ownedResources = make(map[string]*bolaOwnedResource)
+}
+
+// RecordOperationCase records the resource created (or deleted) by the executed operation case of current test scenario.
+func (o *BOLAOracle) RecordOperationCase(operationCase *casemanager.OperationCase) {
+	if !operationCase.IsExecutedSuccessfully() {
+		return
+	}
+	method := operationCase.APIMethod
+	switch method.Method {
+	case consts.MethodPost:
+		itemPath, exists := o.collection2ItemPath[method.Endpoint]
+		if !exists {
+			return
+		}
+		resourcePath, err := resolveCreatedResourcePath(itemPath, operationCase.RequestPathParams, operationCase.ResponseBody)
+		if err != nil {
+			log.Debug().Msgf("[BOLAOracle.RecordOperationCase] Failed to resolve created resource of %s %s: %s", method.Method, method.Endpoint, err)
+			return
+		}
+		o.ownedResources[resourcePath] = &bolaOwnedResource{owner: operationCase.UserName, cause: method}
+	case consts.MethodDelete:
+		delete(o.ownedResources, fillPathParams(method.Endpoint, operationCase.RequestPathParams))
+	}
+}
+
+// GetAccessedOwnedResource returns the concrete path and the owner of the resource the operation case (to be executed) reads or mutates,
+// if the resource (or its parent resource) was created in current test scenario.
+// Only GET, PUT, PATCH and DELETE are regarded as access to a resource.
+func (o *BOLAOracle) GetAccessedOwnedResource(operationCase *casemanager.OperationCase) (string, string, bool) {
+	switch operationCase.APIMethod.Method {
+	case consts.MethodGet, consts.MethodPut, consts.MethodPatch, consts.MethodDelete:
+	default:
+		return "", "", false
+	}
+	path := fillPathParams(operationCase.APIMethod.Endpoint, operationCase.RequestPathParams)
+	for resourcePath, ownedResource := range o.ownedResources {
+		if path == resourcePath || strings.HasPrefix(path, resourcePath+"/") {
+			return resourcePath, ownedResource.owner, true
+		}
+	}
+	return "", "", false
+}
+
+// CheckReplay checks the operation case replayed as another user (the attacker) on a resource created in current test scenario,
+// and returns the violation if the replay succeeds. The violation is also recorded in the oracle.
+func (o *BOLAOracle) CheckReplay(testScenarioUUID uuid.UUID, resourcePath string, replayedOperationCase *casemanager.OperationCase) *BOLAViolation {
+	ownedResource, exists := o.ownedResources[resourcePath]
+	if !exists || !http.IsStatusCodeSuccess(replayedOperationCase.ResponseStatusCode) {
+		return nil
+	}
+	violation := &BOLAViolation{
+		TestScenarioUUID:  testScenarioUUID,
+		ResourcePath:      resourcePath,
+		Owner:             ownedResource.owner,
+		Cause:             ownedResource.cause,
+		Attacker:          replayedOperationCase.UserName,
+		Witness:           replayedOperationCase.APIMethod,
+		WitnessStatusCode: replayedOperationCase.ResponseStatusCode,
+	}
+	log.Warn().Msgf("[BOLAOracle.CheckReplay] User %s accessed resource %s of user %s by %s %s, status code: %d, test scenario UUID: %s", violation.Attacker, resourcePath, violation.Owner, violation.Witness.Method, violation.Witness.Endpoint, violation.WitnessStatusCode, testScenarioUUID.String())
+	o.Violations = append(o.Violations, violation)
+	// A replayed DELETE removes the resource, and the owner cannot access it afterwards.
+	if replayedOperationCase.APIMethod.Method == consts.MethodDelete {
+		delete(o.ownedResources, resourcePath)
+	}
+	return violation
+}
@@ -0,0 +1,291 @@
+package feedback
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"resttracefuzzer/pkg/utils/http"
+	"strconv"
+	"strings"
+
+	"github.com/bytedance/sonic"
+	"github.com/cloudwego/hertz/pkg/protocol/consts"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	// CodeCoverageCollectorTypeJaCoCo collects coverage of Java services from an HTTP endpoint serving the JaCoCo XML report.
+	// The JaCoCo agent does not serve reports by itself, so a sidecar is needed, which dumps the execution data from the agent
+	// (`output=tcpserver`, by `jacococli dump`), generates the XML report with the class files (by `jacococli report --xml`) and serves it.
+	CodeCoverageCollectorTypeJaCoCo = "jacoco"
+
+	// CodeCoverageCollectorTypeGo collects coverage of Go services from an HTTP endpoint serving the Go cover profile
+	// (the text format of `go test -coverprofile`), e.g., the `/v1/cover/profile` endpoint of a goc server.
+	CodeCoverageCollectorTypeGo = "go"
+)
+
+// CodeCoverage is the line and branch coverage of services under test.
+// For Go services, statements are counted as lines, and branches are not available.
+type CodeCoverage struct {
+	// CoveredLines is the number of covered lines.
+	CoveredLines int `json:"coveredLines"`
+
+	// TotalLines is the number of all lines.
+	TotalLines int `json:"totalLines"`
+
+	// CoveredBranches is the number of covered branches.
+	CoveredBranches int `json:"coveredBranches"`
+
+	// TotalBranches is the number of all branches.
+	TotalBranches int `json:"totalBranches"`
+}
+
+// Add adds the other coverage to the coverage.
+func (c *CodeCoverage) Add(other *CodeCoverage) {
+	c.CoveredLines += other.CoveredLines
+	c.TotalLines += other.TotalLines
+	c.CoveredBranches += other.CoveredBranches
+	c.TotalBranches += other.TotalBranches
+}
+
+// CoverageCollector collects the code coverage of a service under test.
+type CoverageCollector interface {
+	// Name returns the name of the collector, used in logs and reports.
+	Name() string
+
+	// Collect collects the current (cumulative) code coverage of the service.
+	Collect(ctx context.Context) (*CodeCoverage, error)
+}
+
+// CoverageCollectorConfig is the configuration of a [CoverageCollector].
+type CoverageCollectorConfig struct {
+	// Name is the name of the collector, e.g., the name of the service. The URL is used if empty.
+	// Names should be unique, as coverage is tracked by collector names, see [CodeCoverageTracker.LatestCoverages].
+	Name string `json:"name"`
+
+	// Type is the type of the collector, [CodeCoverageCollectorTypeJaCoCo] or [CodeCoverageCollectorTypeGo].
+	Type string `json:"type"`
+
+	// URL is the URL of the coverage endpoint.
+	URL string `json:"url"`
+}
+
+// NewCoverageCollectors creates coverage collectors from a stringified JSON list of [CoverageCollectorConfig],
+// e.g., `[{"name": "order", "type": "jacoco", "url": "http://order:8081/jacoco/report.xml"}]`.
+// It returns an empty list if collectorsStr is empty, and an error if collectorsStr is invalid, or names of collectors are not unique.
+// Certificates of coverage endpoints are verified, with CA certificates in caFilePath (if not empty) in addition to system ones.
+func NewCoverageCollectors(collectorsStr string, caFilePath string) ([]CoverageCollector, error) {
+	collectors := make([]CoverageCollector, 0)
+	if collectorsStr == "" {
+		return collectors, nil
+	}
+	collectorConfigs := make([]*CoverageCollectorConfig, 0)
+	err := sonic.UnmarshalString(collectorsStr, &collectorConfigs)
+	if err != nil {
+		log.Err(err).Msg("[NewCoverageCollectors] Failed to unmarshal coverage collectors")
+		return nil, err
+	}
+	collectorNames := make(map[string]bool)
+	for _, collectorConfig := range collectorConfigs {
+		if collectorConfig == nil || collectorConfig.URL == "" {
+			return nil, fmt.Errorf("coverage collector URL is empty")
+		}
+		name := collectorConfig.Name
+		if name == "" {
+			name = collectorConfig.URL
+		}
+		if collectorNames[name] {
+			return nil, fmt.Errorf("duplicate coverage collector name: %s", name)
+		}
+		collectorNames[name] = true
+		// Unlike the system under test, coverage endpoints are verified, as the coverage report is trusted as feedback.
+		httpClient, err := http.NewHTTPClientWithTransport(collectorConfig.URL, []string{}, http.EmptyHTTPClientMiddlewareSlice(), http.TransportConfig{
+			CAFilePath:               caFilePath,
+			VerifyServerCertificates: true,
+		})
+		if err != nil {
+			log.Err(err).Msgf("[NewCoverageCollectors] Failed to create HTTP client of coverage collector %s", name)
+			return nil, err
+		}
+		switch collectorConfig.Type {
+		case CodeCoverageCollectorTypeJaCoCo:
+			collectors = append(collectors, &JaCoCoCoverageCollector{name: name, Client: httpClient})
+		case CodeCoverageCollectorTypeGo:
+			collectors = append(collectors, &GoCoverageCollector{name: name, Client: httpClient})
+		default:
+			return nil, fmt.Errorf("unsupported coverage collector type: %s", collectorConfig.Type)
+		}
+	}
+	return collectors, nil
+}
+
+// JaCoCoCoverageCollector collects coverage of a Java service from an HTTP endpoint serving the JaCoCo XML report.
+// Line and branch counters of the whole report are used.
+type JaCoCoCoverageCollector struct {
+	// name is the name of the collector.
+	name string
+
+	// Client is the HTTP client of the coverage endpoint, whose base URL is the URL of the endpoint.
+	Client *http.HTTPClient
+}
+
+// jacocoReport is the JaCoCo XML report, with report-level counters only.
+// See [JaCoCo report DTD](https://www.jacoco.org/jacoco/trunk/coverage/report.dtd).
+type jacocoReport struct {
+	Counters []struct {
+		Type    string `xml:"type,attr"`
+		Missed  int    `xml:"missed,attr"`
+		Covered int    `xml:"covered,attr"`
+	} `xml:"counter"`
+}
+
+// Name returns the name of the collector.
+func (c *JaCoCoCoverageCollector) Name() string {
+	return c.name
+}
+
+// Collect fetches the JaCoCo XML report, and returns its line and branch coverage.
+func (c *JaCoCoCoverageCollector) Collect(ctx context.Context) (*CodeCoverage, error) {
+	reportBytes, err := fetchCoverageReport(ctx, c.Client)
+	if err != nil {
+		return nil, err
+	}
+	var report jacocoReport
+	// The DOCTYPE of the report is not resolved.
+	err = xml.Unmarshal(reportBytes, &report)
+	if err != nil {
+		log.Err(err).Msgf("[JaCoCoCoverageCollector.Collect] Failed to parse JaCoCo XML report of %s", c.name)
+		return nil, err
+	}
+	coverage := &CodeCoverage{}
+	for _, counter := range report.Counters {
+		switch counter.Type {
+		case "LINE":
+			coverage.CoveredLines = counter.Covered
+			coverage.TotalLines = counter.Covered + counter.Missed
+		case "BRANCH":
+			coverage.CoveredBranches = counter.Covered
+			coverage.TotalBranches = counter.Covered + counter.Missed
+		}
+	}
+	return coverage, nil
+}
+
+// GoCoverageCollector collects coverage of a Go service from an HTTP endpoint serving the Go cover profile.
+// Statements are counted as lines, and branches are not available.
+type GoCoverageCollector struct {
+	// name is the name of the collector.
+	name string
+
+	// Client is the HTTP client of the coverage endpoint, whose base URL is the URL of the endpoint.
+	Client *http.HTTPClient
+}
+
+// Name returns the name of the collector.
+func (c *GoCoverageCollector) Name() string {
+	return c.name
+}
+
+// Collect fetches the Go cover profile, and returns its statement coverage.
+// A profile line is `file.go:startLine.startCol,endLine.endCol numStmt count`, and a block may appear in multiple lines,
+// e.g., profiles merged from multiple processes, where it is covered if covered in any of them.
+func (c *GoCoverageCollector) Collect(ctx context.Context) (*CodeCoverage, error) {
+	profileBytes, err := fetchCoverageReport(ctx, c.Client)
+	if err != nil {
+		return nil, err
+	}
+	blockStatements := make(map[string]int)
+	coveredBlocks := make(map[string]bool)
+	scanner := bufio.NewScanner(bytes.NewReader(profileBytes))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "mode:") {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) != 3 {
+			log.Warn().Msgf("[GoCoverageCollector.Collect] Invalid cover profile line of %s: %s", c.name, line)
+			continue
+		}
+		numStmt, err := strconv.Atoi(fields[1])
+		if err != nil {
+			log.Warn().Msgf("[GoCoverageCollector.Collect] Invalid statement count in cover profile line of %s: %s", c.name, line)
+			continue
+		}
+		count, err := strconv.Atoi(fields[2])
+		if err != nil {
+			log.Warn().Msgf("[GoCoverageCollector.Collect] Invalid hit count in cover profile line of %s: %s", c.name, line)
+			continue
+		}
+		blockStatements[fields[0]] = numStmt
+		coveredBlocks[fields[0]] = coveredBlocks[fields[0]] || count > 0
+	}
+	if err := scanner.Err(); err != nil {
+		log.Err(err).Msgf("[GoCoverageCollector.Collect] Failed to read cover profile of %s", c.name)
+		return nil, err
+	}
+	coverage := &CodeCoverage{}
+	for block, numStmt := range blockStatements {
+		coverage.TotalLines += numStmt
+		if coveredBlocks[block] {
+			coverage.CoveredLines += numStmt
+		}
+	}
+	return coverage, nil
+}
+
+// fetchCoverageReport fetches the coverage report from the coverage endpoint, i.e., the base URL of the client.
+func fetchCoverageReport(ctx context.Context, client *http.HTTPClient) ([]byte, error) {
+	statusCode, _, respBytes, err := client.PerformGet(ctx, "", map[string]string{}, nil, nil)
+	if err != nil {
+		log.Err(err).Msgf("[fetchCoverageReport] Failed to fetch coverage report")
+		return nil, err
+	}
+	if http.GetStatusCodeClass(statusCode) != consts.StatusOK {
+		log.Error().Msgf("[fetchCoverageReport] Failed to fetch coverage report, statusCode: %d", statusCode)
+		return nil, fmt.Errorf("failed to fetch coverage report, status code: %d", statusCode)
+	}
+	return respBytes, nil
+}
+
+// CodeCoverageTracker polls coverage collectors, and combines their code coverage.
+// If a collector fails, its last collected coverage is used.
+type CodeCoverageTracker struct {
+	// Collectors are the coverage collectors of services under test.
+	Collectors []CoverageCollector
+
+	// LatestCoverages maps from collector names to their last collected coverage.
+	LatestCoverages map[string]*CodeCoverage
+}
+
+// NewCodeCoverageTracker creates a new CodeCoverageTracker.
+func NewCodeCoverageTracker(collectors []CoverageCollector) *CodeCoverageTracker {
+	return &CodeCoverageTracker{
+		Collectors:      collectors,
+		LatestCoverages: make(map[string]*CodeCoverage),
+	}
+}
+
+// Collect polls all collectors, and returns the combined code coverage.
+func (t *CodeCoverageTracker) Collect(ctx context.Context) *CodeCoverage {
+	for _, collector := range t.Collectors {
+		coverage, err := collector.Collect(ctx)
+		if err != nil {
+			log.Err(err).Msgf("[CodeCoverageTracker.Collect] Failed to collect code coverage from %s, use the last collected one", collector.Name())
+			continue
+		}
+		t.LatestCoverages[collector.Name()] = coverage
+	}
+	return t.GetCombinedCoverage()
+}
+
+// GetCombinedCoverage returns the combined code coverage of the last collected ones.
+func (t *CodeCoverageTracker) GetCombinedCoverage() *CodeCoverage {
+	combinedCoverage := &CodeCoverage{}
+	for _, coverage := range t.LatestCoverages {
+		combinedCoverage.Add(coverage)
+	}
+	return combinedCoverage
+}
@@ -0,0 +1,205 @@
+package feedback
+
+import (
+	"cmp"
+	"maps"
+	"resttracefuzzer/pkg/casemanager"
+	"resttracefuzzer/pkg/feedback/trace"
+	"resttracefuzzer/pkg/static"
+	"slices"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	// SpanRequestHeaderAttributePrefix is the prefix of span attributes recording request headers, by OpenTelemetry semantic conventions,
+	// e.g., `http.request.header.x-tenant-id`. Older instrumentations replace `-` with `_` in header names.
+	SpanRequestHeaderAttributePrefix = "http.request.header."
+
+	// SpanBaggageAttributePrefix is the prefix of span attributes recording baggage entries, e.g., by baggage span processors.
+	SpanBaggageAttributePrefix = "baggage."
+)
+
+// ContextPropagationBreak records a call between services where a context header (e.g., tenant ID, user ID) sent with requests of an API method
+// reaches the caller but not the callee, as observed in span attributes.
+type ContextPropagationBreak struct {
+	// APIMethod is the API method whose requests carry the header.
+	APIMethod static.SimpleAPIMethod `json:"APIMethod"`
+
+	// Header is the context header not propagated.
+	Header string `json:"header"`
+
+	// SourceService is the service which receives the header, but does not propagate it.
+	SourceService string `json:"sourceService"`
+
+	// TargetService is the service called by SourceService, which does not receive the header.
+	TargetService string `json:"targetService"`
+
+	// TargetOperationName is the name of the span of TargetService not receiving the header.
+	TargetOperationName string `json:"targetOperationName"`
+
+	// Count is the number of traces where the header is not propagated from SourceService to TargetService.
+	Count int `json:"count"`
+
+	// TraceID is the ID of the first trace where the header is not propagated.
+	TraceID string `json:"traceID"`
+
+	// TestScenarioUUID is the UUID of the test scenario of TraceID.
+	TestScenarioUUID uuid.UUID `json:"testScenarioUUID"`
+}
+
+// contextPropagationBreakKey identifies a [ContextPropagationBreak].
+type contextPropagationBreakKey struct {
+	APIMethod     static.SimpleAPIMethod
+	Header        string
+	SourceService string
+	TargetService string
+}
+
+// ContextPropagationOracle checks, via span attributes, that configured context headers (e.g., tenant ID and user ID) sent with requests
+// actually reach downstream services, and reports calls where the propagation is broken, which response-based oracles cannot observe.
+// A service receives a header if its server (or consumer) span records it, as a request header attribute (see [SpanRequestHeaderAttributePrefix]),
+// a baggage attribute (see [SpanBaggageAttributePrefix]), or an attribute named by the header.
+// Only calls from services receiving the header are checked, so that a break is reported once at the first hop missing it, rather than at every service downstream.
+// Services must be instrumented to record the headers, e.g., by OTEL_INSTRUMENTATION_HTTP_SERVER_CAPTURE_REQUEST_HEADERS of OpenTelemetry agents.
+type ContextPropagationOracle struct {
+	// Headers are the context headers checked. The oracle is disabled if empty.
+	Headers []string
+
+	// breaks maps from break keys to breaks found so far.
+	breaks map[contextPropagationBreakKey]*ContextPropagationBreak
+}
+
+// NewContextPropagationOracle creates a new ContextPropagationOracle checking the comma-separated headers, e.g., `X-Tenant-ID,X-User-ID`.
+func NewContextPropagationOracle(headersStr string) *ContextPropagationOracle {
+	headers := make([]string, 0)
+	for header := range strings.SplitSeq(headersStr, ",") {
+		if header = strings.TrimSpace(header); header != "" {
+			headers = append(headers, header)
+		}
+	}
+	return &ContextPropagationOracle{
+		Headers: headers,
+		breaks:  make(map[contextPropagationBreakKey]*ContextPropagationBreak),
+	}
+}
+
+// EvaluateScenario implements [ScenarioEvaluator], checking propagation of context headers in traces of executed operation cases.
+// Operation cases without traces, or not sending the headers, are ignored.
+func (o *ContextPropagationOracle) EvaluateScenario(testScenario *casemanager.TestScenario, operationResults []*OperationResult) {
+	if len(o.Headers) == 0 {
+		return
+	}
+	for _, result := range operationResults {
+		if result.Trace == nil {
+			continue
+		}
+		for _, header := range o.Headers {
+			if !hasRequestHeader(result.OperationCase.RequestHeaders, header) {
+				continue
+			}
+			o.checkTrace(testScenario, result.OperationCase.APIMethod, result.Trace, header)
+		}
+	}
+}
+
+// GetBreaks returns the breaks found, sorted by API method, header, source service and target service.
+func (o *ContextPropagationOracle) GetBreaks() []*ContextPropagationBreak {
+	breaks := slices.Collect(maps.Values(o.breaks))
+	slices.SortFunc(breaks, func(a, b *ContextPropagationBreak) int {
+		return cmp.Or(
+			static.CompareSimpleAPIMethod(a.APIMethod, b.APIMethod),
+			cmp.Compare(a.Header, b.Header),
+			cmp.Compare(a.SourceService, b.SourceService),
+			cmp.Compare(a.TargetService, b.TargetService),
+		)
+	})
+	return breaks
+}
+
+// checkTrace checks propagation of the header along calls between services in the trace, and records breaks.
+func (o *ContextPropagationOracle) checkTrace(testScenario *casemanager.TestScenario, APIMethod static.SimpleAPIMethod, simplifiedTrace *trace.SimplifiedTrace, header string) {
+	// Sort span IDs, so that the first break of each key is stable.
+	for _, spanID := range slices.Sorted(maps.Keys(simplifiedTrace.SpanMap)) {
+		span := simplifiedTrace.SpanMap[spanID]
+		if !isReceivingSpan(span) || isSpanCarryingHeader(span, header) {
+			continue
+		}
+		source := getCallerReceivingSpan(simplifiedTrace, span)
+		if source == nil || !isSpanCarryingHeader(source, header) {
+			continue
+		}
+		key := contextPropagationBreakKey{
+			APIMethod:     APIMethod,
+			Header:        header,
+			SourceService: source.ServiceName,
+			TargetService: span.ServiceName,
+		}
+		if propagationBreak, exist := o.breaks[key]; exist {
+			propagationBreak.Count++
+			continue
+		}
+		o.breaks[key] = &ContextPropagationBreak{
+			APIMethod:           APIMethod,
+			Header:              header,
+			SourceService:       source.ServiceName,
+			TargetService:       span.ServiceName,
+			TargetOperationName: span.OperationName,
+			Count:               1,
+			TraceID:             simplifiedTrace.TraceID,
+			TestScenarioUUID:    testScenario.UUID,
+		}
+		log.Warn().Msgf("[ContextPropagationOracle.checkTrace] Header %s of %s %s is not propagated from service %s to %s, trace ID: %s", header, APIMethod.Method, APIMethod.Endpoint, source.ServiceName, span.ServiceName, simplifiedTrace.TraceID)
+	}
+}
+
+// getCallerReceivingSpan returns the nearest ancestor receiving span (see [isReceivingSpan]) of the span of another service, i.e., where the caller service receives the request.
+// It returns nil if the span is called by no service in the trace, e.g., it is the entry of the request.
+func getCallerReceivingSpan(simplifiedTrace *trace.SimplifiedTrace, span *trace.SimplifiedTraceSpan) *trace.SimplifiedTraceSpan {
+	// The visited set guards against malformed traces with cyclic parents.
+	visited := map[string]struct{}{span.SpanID: {}}
+	for parent := simplifiedTrace.SpanMap[span.ParentID]; parent != nil; parent = simplifiedTrace.SpanMap[parent.ParentID] {
+		if _, exist := visited[parent.SpanID]; exist {
+			return nil
+		}
+		visited[parent.SpanID] = struct{}{}
+		if isReceivingSpan(parent) && parent.ServiceName != span.ServiceName {
+			return parent
+		}
+	}
+	return nil
+}
+
+// isReceivingSpan checks whether the span is where a service receives a request or message, i.e., of kind server or consumer.
+func isReceivingSpan(span *trace.SimplifiedTraceSpan) bool {
+	return span != nil && (span.SpanKind == trace.SERVER || span.SpanKind == trace.CONSUMER)
+}
+
+// isSpanCarryingHeader checks whether the span records the header, see [ContextPropagationOracle].
+func isSpanCarryingHeader(span *trace.SimplifiedTraceSpan, header string) bool {
+	header = strings.ToLower(header)
+	candidateKeys := []string{
+		SpanRequestHeaderAttributePrefix + header,
+		SpanRequestHeaderAttributePrefix + strings.ReplaceAll(header, "-", "_"),
+		SpanBaggageAttributePrefix + header,
+		header,
+	}
+	for key := range span.AttributeMap {
+		if slices.Contains(candidateKeys, strings.ToLower(key)) {
+			return true
+		}
+	}
+	return false
+}
+
+// hasRequestHeader checks whether the request headers contain the header, case-insensitively.
+func hasRequestHeader(requestHeaders map[string]string, header string) bool {
+	for key, value := range requestHeaders {
+		if strings.EqualFold(key, header) && value != "" {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,233 @@
+package feedback
+
+import (
+	"fmt"
+	"maps"
+	"resttracefuzzer/pkg/casemanager"
+	"resttracefuzzer/pkg/static"
+	"resttracefuzzer/pkg/utils"
+	"resttracefuzzer/pkg/utils/http"
+	"strconv"
+	"strings"
+
+	"github.com/bytedance/sonic"
+	"github.com/cloudwego/hertz/pkg/protocol/consts"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+)
+
+// CRUDInvariantViolationType represents the type of a violated resource lifecycle invariant.
+type CRUDInvariantViolationType string
+
+const (
+	// CRUDInvariantViolationTypeGetAfterDelete means a resource can still be read successfully after it is deleted successfully.
+	CRUDInvariantViolationTypeGetAfterDelete CRUDInvariantViolationType = "getAfterDelete"
+
+	// CRUDInvariantViolationTypeNotFoundAfterCreate means a resource cannot be found (404) after it is created successfully, using the id returned by creation.
+	CRUDInvariantViolationTypeNotFoundAfterCreate CRUDInvariantViolationType = "notFoundAfterCreate"
+)
+
+// CRUDInvariantViolation records a violation of resource lifecycle invariant found in a test scenario.
+type CRUDInvariantViolation struct {
+	// Typ is the type of the violation.
+	Typ CRUDInvariantViolationType `json:"type"`
+
+	// TestScenarioUUID is the UUID of the test scenario in which the violation is found.
+	TestScenarioUUID uuid.UUID `json:"testScenarioUUID"`
+
+	// ResourcePath is the concrete path of the resource, e.g., `/users/42`.
+	ResourcePath string `json:"resourcePath"`
+
+	// Cause is the API method which changes the lifecycle of the resource (i.e., the creation or the deletion).
+	Cause static.SimpleAPIMethod `json:"cause"`
+
+	// Witness is the API method whose response violates the invariant (i.e., the read).
+	Witness static.SimpleAPIMethod `json:"witness"`
+
+	// WitnessStatusCode is the response status code of the witness.
+	WitnessStatusCode int `json:"witnessStatusCode"`
+}
+
+// crudResourceState is the lifecycle state of a concrete resource in a test scenario.
+type crudResourceState struct {
+	// deleted is true if the resource has been deleted successfully, and false if it has been created successfully.
+	deleted bool
+
+	// cause is the API method which sets the state.
+	cause static.SimpleAPIMethod
+}
+
+// CRUDOracle checks stateful invariants of resource lifecycles in test scenarios.
+// It derives resource lifecycles from the API doc:
+//   - POST on a collection path (e.g., `/users`) creates a resource of the item path (e.g., `/users/{id}`).
+//   - GET on an item path reads the resource.
+//   - DELETE on an item path removes the resource.
+//
+// And it flags violations like a successful GET after a successful DELETE,
+// or a 404 GET after a 201 POST with the returned id.
+type CRUDOracle struct {
+	// APIManager provides the API definitions.
+	APIManager *static.APIManager
+
+	// Violations are all violations found so far.
+	Violations []*CRUDInvariantViolation
+
+	// collection2ItemPath maps from a collection path to its item path, e.g., from `/users` to `/users/{id}`.
+	collection2ItemPath map[string]string
+}
+
+// NewCRUDOracle creates a new CRUDOracle.
+func NewCRUDOracle(APIManager *static.APIManager) *CRUDOracle {
+	return &CRUDOracle{
+		APIManager:          APIManager,
+		Violations:          make([]*CRUDInvariantViolation, 0),
+		collection2ItemPath: getCollection2ItemPath(APIManager),
+	}
+}
+
+// getCollection2ItemPath returns the map from collection paths to item paths derived from the API doc.
+// An item path is a path with a path parameter as its last segment,
+// and its collection path is the path without the last segment.
+// We only consider item paths which support GET or DELETE, and collection paths which support POST.
+func getCollection2ItemPath(APIManager *static.APIManager) map[string]string {
+	collection2ItemPath := make(map[string]string)
+	for method := range APIManager.APIMap {
+		if method.Method != consts.MethodGet && method.Method != consts.MethodDelete {
+			continue
+		}
+		segments := utils.SplitEndpointPath(method.Endpoint)
+		if len(segments) < 2 || !utils.IfPathSegmentIsPathParam(segments[len(segments)-1]) {
+			continue
+		}
+		collectionPath := "/" + strings.Join(segments[:len(segments)-1], "/")
+		// The creator may be a gRPC operation, see [static.APIManager.ResolveSystemAPIMethod].
+		creator := APIManager.ResolveSystemAPIMethod(consts.MethodPost, collectionPath)
+		if _, exists := APIManager.APIMap[creator]; exists {
+			collection2ItemPath[collectionPath] = method.Endpoint
+		}
+	}
+	return collection2ItemPath
+}
+
+// EvaluateScenario implements [ScenarioEvaluator], checking the executed test scenario by [CRUDOracle.CheckScenario].
+func (o *CRUDOracle) EvaluateScenario(testScenario *casemanager.TestScenario, operationResults []*OperationResult) {
+	o.CheckScenario(testScenario, operationResults)
+}
+
+// CheckScenario checks the executed test scenario by results of its executed operation cases in order, and returns the violations found in it.
+// The violations are also recorded in the oracle.
+// Operation cases not executed (e.g., cases after an aborted one) are not in the results, and failed requests (i.e., with no response status code) are ignored.
+func (o *CRUDOracle) CheckScenario(testScenario *casemanager.TestScenario, operationResults []*OperationResult) []*CRUDInvariantViolation {
+	violations := make([]*CRUDInvariantViolation, 0)
+	// resourceStates maps from the concrete path of a resource to its state.
+	resourceStates := make(map[string]*crudResourceState)
+	for _, operationResult := range operationResults {
+		operationCase := operationResult.OperationCase
+		statusCode := operationCase.ResponseStatusCode
+		if statusCode == 0 {
+			continue
+		}
+		method := operationCase.APIMethod
+		switch method.Method {
+		case consts.MethodPost:
+			itemPath, exists := o.collection2ItemPath[method.Endpoint]
+			if !exists || !http.IsStatusCodeSuccess(statusCode) {
+				continue
+			}
+			resourcePath, err := resolveCreatedResourcePath(itemPath, operationCase.RequestPathParams, operationCase.ResponseBody)
+			if err != nil {
+				log.Debug().Msgf("[CRUDOracle.CheckScenario] Failed to resolve created resource of %s %s: %s", method.Method, method.Endpoint, err)
+				continue
+			}
+			resourceStates[resourcePath] = &crudResourceState{deleted: false, cause: method}
+		case consts.MethodDelete:
+			if http.IsStatusCodeSuccess(statusCode) {
+				resourcePath := fillPathParams(method.Endpoint, operationCase.RequestPathParams)
+				resourceStates[resourcePath] = &crudResourceState{deleted: true, cause: method}
+			}
+		case consts.MethodGet:
+			resourcePath := fillPathParams(method.Endpoint, operationCase.RequestPathParams)
+			state, exists := resourceStates[resourcePath]
+			if !exists {
+				continue
+			}
+			var violationType CRUDInvariantViolationType
+			if state.deleted && http.IsStatusCodeSuccess(statusCode) {
+				violationType = CRUDInvariantViolationTypeGetAfterDelete
+			} else if !state.deleted && statusCode == consts.StatusNotFound {
+				violationType = CRUDInvariantViolationTypeNotFoundAfterCreate
+			} else {
+				continue
+			}
+			violation := &CRUDInvariantViolation{
+				Typ:               violationType,
+				TestScenarioUUID:  testScenario.UUID,
+				ResourcePath:      resourcePath,
+				Cause:             state.cause,
+				Witness:           method,
+				WitnessStatusCode: statusCode,
+			}
+			log.Warn().Msgf("[CRUDOracle.CheckScenario] Found violation %s on resource %s, test scenario UUID: %s", violationType, resourcePath, testScenario.UUID.String())
+			violations = append(violations, violation)
+		}
+	}
+	o.Violations = append(o.Violations, violations...)
+	return violations
+}
+
+// resolveCreatedResourcePath resolves the concrete item path of the resource created by a POST request, from the response body.
+// The id is the top-level field of the response body with the same name as the path parameter of the item path, or `id` as a fallback.
+// Path parameters of parent resources (e.g., `userId` of `/users/{userId}/posts`) are those of the creation, i.e., pathParams.
+// It returns an error if any path parameter of the item path is unknown.
+func resolveCreatedResourcePath(itemPath string, pathParams map[string]string, responseBody []byte) (string, error) {
+	paramName, id, err := resolveCreatedResourceID(itemPath, responseBody)
+	if err != nil {
+		return "", err
+	}
+	itemPathParams := maps.Clone(pathParams)
+	if itemPathParams == nil {
+		itemPathParams = make(map[string]string)
+	}
+	itemPathParams[paramName] = id
+	resourcePath := fillPathParams(itemPath, itemPathParams)
+	if strings.ContainsAny(resourcePath, "{}") {
+		return "", fmt.Errorf("unknown path parameter in item path %s", resourcePath)
+	}
+	return resourcePath, nil
+}
+
+// resolveCreatedResourceID resolves the id of the resource created by a POST request from the response body, see [resolveCreatedResourcePath].
+// It returns the name of the path parameter of the item path, and the id.
+// Numeric ids are formatted without exponent, as JSON numbers are decoded as float64, e.g., 1000000 is `1000000` instead of `1e+06`.
+func resolveCreatedResourceID(itemPath string, responseBody []byte) (string, string, error) {
+	segments := utils.SplitEndpointPath(itemPath)
+	paramName := strings.Trim(segments[len(segments)-1], "{}")
+	var body map[string]interface{}
+	err := sonic.Unmarshal(responseBody, &body)
+	if err != nil {
+		return "", "", err
+	}
+	id, exists := body[paramName]
+	if !exists {
+		id, exists = body["id"]
+	}
+	if !exists || id == nil {
+		return "", "", fmt.Errorf("no id found in response body")
+	}
+	switch id := id.(type) {
+	case float64:
+		return paramName, strconv.FormatFloat(id, 'f', -1, 64), nil
+	case string:
+		return paramName, id, nil
+	default:
+		return paramName, fmt.Sprint(id), nil
+	}
+}
+
+// fillPathParams replaces the path parameters in the path with the given values.
+func fillPathParams(path string, pathParams map[string]string) string {
+	for key, value := range pathParams {
+		path = strings.ReplaceAll(path, "{"+key+"}", value)
+	}
+	return path
+}
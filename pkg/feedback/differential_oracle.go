@@ -0,0 +1,256 @@
+package feedback
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"regexp"
+	"resttracefuzzer/pkg/casemanager"
+	"resttracefuzzer/pkg/static"
+	"slices"
+	"strings"
+
+	"github.com/bytedance/sonic"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+)
+
+// maxBehaviorDifferencesPerOperation is the maximum number of behavioral differences recorded for each API method.
+const maxBehaviorDifferencesPerOperation = 3
+
+// volatileValueRegex matches string values varying between runs of the same request, i.e., UUIDs and timestamps.
+var volatileValueRegex = regexp.MustCompile(`^([0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}|\d{4}-\d{2}-\d{2}[T ]\d{2}:\d{2}:\d{2}(\.\d+)?(Z|[+-]\d{2}:?\d{2})?)$`)
+
+// idFieldNameRegex matches names of JSON fields and parameters of IDs, e.g., `id`, `orderId` and `order_id`, whose values are generated by each version of the system on its own.
+var idFieldNameRegex = regexp.MustCompile(`^(?i:id|uuid)$|[a-z0-9](Id|ID)$|(?i:[_-]id)$`)
+
+// BehaviorDifference records a request to which the baseline and the candidate versions of the system respond differently.
+type BehaviorDifference struct {
+	// TestScenarioUUID is the UUID of the test scenario in which the difference is found.
+	TestScenarioUUID uuid.UUID `json:"testScenarioUUID"`
+
+	// APIMethod is the API method of the request.
+	APIMethod static.SimpleAPIMethod `json:"APIMethod"`
+
+	// BaselineStatusCode is the response status code of the baseline version.
+	BaselineStatusCode int `json:"baselineStatusCode"`
+
+	// CandidateStatusCode is the response status code of the candidate version.
+	CandidateStatusCode int `json:"candidateStatusCode"`
+
+	// DifferingFields are the JSON paths (e.g., `$.items[0].price`) of differing fields of response bodies, sorted.
+	// It is `$` if the bodies differ but are not both JSON.
+	DifferingFields []string `json:"differingFields"`
+}
+
+// DifferentialOracle compares responses of the baseline version (e.g., the current release) and the candidate version of the system to the same request,
+// and flags behavioral differences as potential regressions.
+// Status codes are compared, and bodies are compared as JSON values modulo volatile fields,
+// i.e., fields of IgnoredFields, UUID and timestamp strings, and values of ID fields (see [idFieldNameRegex]), which are only compared by type.
+// IDs differing in responses of a test scenario are mapped, so that later requests to the candidate refer to resources it created, see [DifferentialOracle.MapRequestToCandidate].
+// At most [maxBehaviorDifferencesPerOperation] differences are recorded for each API method.
+type DifferentialOracle struct {
+	// IgnoredFields are the names of JSON object fields not compared, e.g., `createdAt`.
+	IgnoredFields map[string]struct{}
+
+	// Differences are all differences found so far.
+	Differences []*BehaviorDifference
+
+	// differenceCounts maps from API methods to the number of their differences recorded.
+	differenceCounts map[static.SimpleAPIMethod]int
+
+	// candidateIDs maps from (stringified) values of ID fields in baseline responses of current test scenario to values at the same paths in candidate responses.
+	candidateIDs map[string]any
+}
+
+// NewDifferentialOracle creates a new DifferentialOracle.
+// ignoredFieldsStr is a stringified JSON list of names of volatile fields, e.g., `["createdAt", "requestId"]`, which can be empty.
+func NewDifferentialOracle(ignoredFieldsStr string) (*DifferentialOracle, error) {
+	ignoredFields := make(map[string]struct{})
+	if ignoredFieldsStr != "" {
+		fieldNames := make([]string, 0)
+		err := sonic.UnmarshalString(ignoredFieldsStr, &fieldNames)
+		if err != nil {
+			log.Err(err).Msg("[NewDifferentialOracle] Failed to unmarshal ignored fields")
+			return nil, err
+		}
+		for _, fieldName := range fieldNames {
+			ignoredFields[fieldName] = struct{}{}
+		}
+	}
+	return &DifferentialOracle{
+		IgnoredFields:    ignoredFields,
+		Differences:      make([]*BehaviorDifference, 0),
+		differenceCounts: make(map[static.SimpleAPIMethod]int),
+		candidateIDs:     make(map[string]any),
+	}, nil
+}
+
+// BeginScenario resets the IDs mapped, as resources created in a test scenario are only referred to in the scenario.
+func (o *DifferentialOracle) BeginScenario() {
+	o.candidateIDs = make(map[string]any)
+}
+
+// MapRequestToCandidate replaces IDs in the request of the operation case (to be sent to the candidate) with those the candidate responded at the same paths,
+// i.e., values of path parameters, and values of ID query parameters and body fields (see [idFieldNameRegex]), that the baseline responded before in current test scenario.
+func (o *DifferentialOracle) MapRequestToCandidate(operationCase *casemanager.OperationCase) {
+	if len(o.candidateIDs) == 0 {
+		return
+	}
+	for name, value := range operationCase.RequestPathParams {
+		if candidateID, exists := o.candidateIDs[value]; exists {
+			operationCase.RequestPathParams[name] = fmt.Sprint(candidateID)
+		}
+	}
+	for name, value := range operationCase.RequestQueryParams {
+		if candidateID, exists := o.candidateIDs[value]; exists && idFieldNameRegex.MatchString(name) {
+			operationCase.RequestQueryParams[name] = fmt.Sprint(candidateID)
+		}
+	}
+	var body any
+	if len(operationCase.RequestBody) == 0 || sonic.Unmarshal(operationCase.RequestBody, &body) != nil {
+		return
+	}
+	if !o.mapJSONIDs(body) {
+		return
+	}
+	if mappedBody, err := sonic.Marshal(body); err == nil {
+		operationCase.RequestBody = mappedBody
+	}
+}
+
+// mapJSONIDs replaces values of ID fields in the JSON value in place, see [DifferentialOracle.MapRequestToCandidate].
+// It returns whether any value is replaced.
+func (o *DifferentialOracle) mapJSONIDs(value any) bool {
+	mapped := false
+	switch v := value.(type) {
+	case map[string]any:
+		for key, fieldValue := range v {
+			if _, isObject := fieldValue.(map[string]any); !isObject && idFieldNameRegex.MatchString(key) {
+				if candidateID, exists := o.candidateIDs[fmt.Sprint(fieldValue)]; exists {
+					v[key] = candidateID
+					mapped = true
+					continue
+				}
+			}
+			mapped = o.mapJSONIDs(fieldValue) || mapped
+		}
+	case []any:
+		for _, item := range v {
+			mapped = o.mapJSONIDs(item) || mapped
+		}
+	}
+	return mapped
+}
+
+// CheckResponses compares the responses of the baseline and the candidate operation cases, which send the same request,
+// and returns the difference if they differ. The difference is also recorded in the oracle, unless enough differences of the API method are recorded.
+func (o *DifferentialOracle) CheckResponses(testScenarioUUID uuid.UUID, baselineOperationCase, candidateOperationCase *casemanager.OperationCase) *BehaviorDifference {
+	differingFields := o.diffResponseBodies(baselineOperationCase.ResponseBody, candidateOperationCase.ResponseBody)
+	if baselineOperationCase.ResponseStatusCode == candidateOperationCase.ResponseStatusCode && len(differingFields) == 0 {
+		return nil
+	}
+	difference := &BehaviorDifference{
+		TestScenarioUUID:    testScenarioUUID,
+		APIMethod:           baselineOperationCase.APIMethod,
+		BaselineStatusCode:  baselineOperationCase.ResponseStatusCode,
+		CandidateStatusCode: candidateOperationCase.ResponseStatusCode,
+		DifferingFields:     differingFields,
+	}
+	if o.differenceCounts[difference.APIMethod] >= maxBehaviorDifferencesPerOperation {
+		return difference
+	}
+	o.differenceCounts[difference.APIMethod]++
+	log.Warn().Msgf("[DifferentialOracle.CheckResponses] Behavior of %s %s differs, baseline status code: %d, candidate status code: %d, differing fields: %v, test scenario UUID: %s", difference.APIMethod.Method, difference.APIMethod.Endpoint, difference.BaselineStatusCode, difference.CandidateStatusCode, differingFields, testScenarioUUID.String())
+	o.Differences = append(o.Differences, difference)
+	return difference
+}
+
+// diffResponseBodies returns the sorted JSON paths of differing fields of the response bodies.
+// They are compared as JSON values if both are valid JSON, and as bytes otherwise.
+func (o *DifferentialOracle) diffResponseBodies(a, b []byte) []string {
+	var valueA, valueB any
+	if sonic.Unmarshal(a, &valueA) != nil || sonic.Unmarshal(b, &valueB) != nil {
+		if bytes.Equal(a, b) {
+			return nil
+		}
+		return []string{"$"}
+	}
+	differingFields := make([]string, 0)
+	o.diffJSONValues("$", valueA, valueB, &differingFields)
+	slices.Sort(differingFields)
+	return differingFields
+}
+
+// diffJSONValues appends the JSON paths of differing fields of the values (under the path) to differingFields.
+func (o *DifferentialOracle) diffJSONValues(path string, a, b any, differingFields *[]string) {
+	switch valueA := a.(type) {
+	case map[string]any:
+		valueB, ok := b.(map[string]any)
+		if !ok {
+			*differingFields = append(*differingFields, path)
+			return
+		}
+		keys := make(map[string]struct{})
+		for key := range valueA {
+			keys[key] = struct{}{}
+		}
+		for key := range valueB {
+			keys[key] = struct{}{}
+		}
+		for key := range keys {
+			if _, ignored := o.IgnoredFields[key]; ignored {
+				continue
+			}
+			if idFieldNameRegex.MatchString(key) && o.mapCandidateID(valueA[key], valueB[key]) {
+				continue
+			}
+			o.diffJSONValues(path+"."+key, valueA[key], valueB[key], differingFields)
+		}
+	case []any:
+		valueB, ok := b.([]any)
+		if !ok || len(valueA) != len(valueB) {
+			*differingFields = append(*differingFields, path)
+			return
+		}
+		for i := range valueA {
+			o.diffJSONValues(fmt.Sprintf("%s[%d]", path, i), valueA[i], valueB[i], differingFields)
+		}
+	case string:
+		valueB, ok := b.(string)
+		if ok && (valueA == valueB || isVolatileValue(valueA) && isVolatileValue(valueB)) {
+			return
+		}
+		*differingFields = append(*differingFields, path)
+	default:
+		if !reflect.DeepEqual(a, b) {
+			*differingFields = append(*differingFields, path)
+		}
+	}
+}
+
+// mapCandidateID records the candidate ID to which the baseline one maps, if both are strings or numbers.
+// It returns false if they are not, i.e., they should be compared as other values.
+func (o *DifferentialOracle) mapCandidateID(baselineID, candidateID any) bool {
+	switch baselineID.(type) {
+	case string:
+		if _, ok := candidateID.(string); !ok {
+			return false
+		}
+	case float64:
+		if _, ok := candidateID.(float64); !ok {
+			return false
+		}
+	default:
+		return false
+	}
+	if baselineID != candidateID {
+		o.candidateIDs[fmt.Sprint(baselineID)] = candidateID
+	}
+	return true
+}
+
+// isVolatileValue checks whether the string value varies between runs of the same request, i.e., a UUID or a timestamp.
+func isVolatileValue(value string) bool {
+	return volatileValueRegex.MatchString(strings.TrimSpace(value))
+}
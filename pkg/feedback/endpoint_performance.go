@@ -0,0 +1,143 @@
+package feedback
+
+import (
+	"maps"
+	"math/rand/v2"
+	"resttracefuzzer/pkg/static"
+	"resttracefuzzer/pkg/utils"
+	"resttracefuzzer/pkg/utils/http"
+	"slices"
+
+	"github.com/cloudwego/hertz/pkg/protocol/consts"
+)
+
+// maxEndpointLatencySamples is the max number of latencies sampled per API method, which latency percentiles are computed from.
+const maxEndpointLatencySamples = 1024
+
+// EndpointPerformance is the response time and availability of an API method observed during fuzzing.
+type EndpointPerformance struct {
+	// APIMethod is the API method.
+	APIMethod static.SimpleAPIMethod `json:"APIMethod"`
+
+	// RequestCount is the number of requests of the API method.
+	RequestCount int `json:"requestCount"`
+
+	// ServerErrorCount is the number of requests responded with 5xx status codes.
+	ServerErrorCount int `json:"serverErrorCount"`
+
+	// TimeoutCount is the number of requests failing without a response due to timeouts, see [http.IsTimeoutError].
+	TimeoutCount int `json:"timeoutCount"`
+
+	// ConnectionErrorCount is the number of requests failing without a response due to other errors, e.g., connection refused or reset.
+	ConnectionErrorCount int `json:"connectionErrorCount"`
+
+	// Availability is the ratio of requests responded without server errors, i.e., not failing with 5xx, timeouts or connection errors.
+	Availability float64 `json:"availability"`
+
+	// P50Latency, P95Latency, P99Latency and MaxLatency are latency percentiles of requests with responses, in milliseconds.
+	// Percentiles are estimated from a uniform sample of latencies (see [maxEndpointLatencySamples]), while MaxLatency is of all requests.
+	// They are 0 if no request gets a response.
+	P50Latency float64 `json:"p50LatencyMs"`
+	P95Latency float64 `json:"p95LatencyMs"`
+	P99Latency float64 `json:"p99LatencyMs"`
+	MaxLatency float64 `json:"maxLatencyMs"`
+}
+
+// endpointPerformanceSamples are the observations of requests of an API method.
+type endpointPerformanceSamples struct {
+	// latencies are a uniform sample (by reservoir sampling) of at most [maxEndpointLatencySamples] latencies of requests with responses, in milliseconds.
+	latencies []float64
+
+	// latencyCount is the number of requests with responses, including those not sampled.
+	latencyCount int
+
+	// maxLatency is the max latency of requests with responses, in milliseconds.
+	maxLatency float64
+
+	// requestCount, serverErrorCount, timeoutCount and connectionErrorCount are the counts of [EndpointPerformance].
+	requestCount         int
+	serverErrorCount     int
+	timeoutCount         int
+	connectionErrorCount int
+}
+
+// EndpointPerformanceTracker records response times, timeouts and connection errors of requests of each API method during fuzzing,
+// so that the fuzzer doubles as a lightweight load and robustness probe.
+// All requests sent are recorded, including replays (e.g., by BOLA and idempotency oracles) and re-executions of scenario minimization,
+// except those under chaos experiments, whose server errors and latencies are injected.
+type EndpointPerformanceTracker struct {
+	// samples maps from API methods to observations of their requests.
+	samples map[static.SimpleAPIMethod]*endpointPerformanceSamples
+
+	// rand is the random number generator of latency sampling, which is independent of [utils.SharedRand],
+	// as latencies depend on timing, and would break reproducibility of seeded runs.
+	rand *rand.Rand
+}
+
+// NewEndpointPerformanceTracker creates a new EndpointPerformanceTracker.
+func NewEndpointPerformanceTracker() *EndpointPerformanceTracker {
+	return &EndpointPerformanceTracker{
+		samples: make(map[static.SimpleAPIMethod]*endpointPerformanceSamples),
+		rand:    utils.NewIndependentRand(),
+	}
+}
+
+// RecordRequest records a request of the API method, with its response status code and latency in milliseconds, or the error if it fails without a response.
+func (t *EndpointPerformanceTracker) RecordRequest(method static.SimpleAPIMethod, statusCode int, latency float64, err error) {
+	samples, exist := t.samples[method]
+	if !exist {
+		samples = &endpointPerformanceSamples{latencies: make([]float64, 0, maxEndpointLatencySamples)}
+		t.samples[method] = samples
+	}
+	samples.requestCount++
+	switch {
+	case err != nil && http.IsTimeoutError(err):
+		samples.timeoutCount++
+	case err != nil || statusCode == 0:
+		samples.connectionErrorCount++
+	default:
+		if http.GetStatusCodeClass(statusCode) == consts.StatusInternalServerError {
+			samples.serverErrorCount++
+		}
+		samples.recordLatency(latency, t.rand)
+	}
+}
+
+// recordLatency records the latency of a request with response, which replaces a random sampled one (or none) once the sample is full.
+func (s *endpointPerformanceSamples) recordLatency(latency float64, r *rand.Rand) {
+	s.latencyCount++
+	s.maxLatency = max(s.maxLatency, latency)
+	if len(s.latencies) < maxEndpointLatencySamples {
+		s.latencies = append(s.latencies, latency)
+		return
+	}
+	if i := r.IntN(s.latencyCount); i < maxEndpointLatencySamples {
+		s.latencies[i] = latency
+	}
+}
+
+// GetPerformances returns the performances of API methods requested, sorted by API method.
+func (t *EndpointPerformanceTracker) GetPerformances() []*EndpointPerformance {
+	performances := make([]*EndpointPerformance, 0, len(t.samples))
+	for _, method := range slices.SortedFunc(maps.Keys(t.samples), static.CompareSimpleAPIMethod) {
+		samples := t.samples[method]
+		failedCount := samples.serverErrorCount + samples.timeoutCount + samples.connectionErrorCount
+		performance := &EndpointPerformance{
+			APIMethod:            method,
+			RequestCount:         samples.requestCount,
+			ServerErrorCount:     samples.serverErrorCount,
+			TimeoutCount:         samples.timeoutCount,
+			ConnectionErrorCount: samples.connectionErrorCount,
+			Availability:         float64(samples.requestCount-failedCount) / float64(samples.requestCount),
+		}
+		if len(samples.latencies) > 0 {
+			sortedLatencies := slices.Sorted(slices.Values(samples.latencies))
+			performance.P50Latency = utils.GetPercentile(sortedLatencies, 50)
+			performance.P95Latency = utils.GetPercentile(sortedLatencies, 95)
+			performance.P99Latency = utils.GetPercentile(sortedLatencies, 99)
+			performance.MaxLatency = samples.maxLatency
+		}
+		performances = append(performances, performance)
+	}
+	return performances
+}
@@ -0,0 +1,182 @@
+package feedback
+
+import (
+	"fmt"
+	"maps"
+	"resttracefuzzer/pkg/static"
+	"resttracefuzzer/pkg/utils"
+	"slices"
+	"strings"
+
+	"github.com/bytedance/sonic"
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/rs/zerolog/log"
+)
+
+// EnumParameterLocationBody is the location of top-level properties of JSON request body, in addition to those of OpenAPI parameters (path, query, ...).
+const EnumParameterLocationBody = "body"
+
+// EnumParameterCoverage records which enum members of a parameter have been exercised.
+type EnumParameterCoverage struct {
+	// APIMethod is the API method the parameter belongs to.
+	APIMethod static.SimpleAPIMethod `json:"APIMethod"`
+
+	// Name is the name of the parameter.
+	Name string `json:"name"`
+
+	// In is the location of the parameter, e.g., path, query, or [EnumParameterLocationBody].
+	In string `json:"in"`
+
+	// EnumValues are the declared enum members, formatted as strings.
+	EnumValues []string `json:"enumValues"`
+
+	// ExercisedValueCount maps from each enum member to the number of requests sending it.
+	ExercisedValueCount map[string]int `json:"exercisedValueCount"`
+
+	// InvalidValueCount is the number of requests sending a value out of the enum.
+	InvalidValueCount int `json:"invalidValueCount"`
+}
+
+// GetCoveredCount returns the number of exercised enum members.
+func (c *EnumParameterCoverage) GetCoveredCount() int {
+	cnt := 0
+	for _, count := range c.ExercisedValueCount {
+		if count > 0 {
+			cnt++
+		}
+	}
+	return cnt
+}
+
+// EnumCoverageTracker tracks which enum members of each parameter have actually been exercised in requests.
+// Parameters include path and query parameters, and top-level properties of JSON request body.
+type EnumCoverageTracker struct {
+	// ParameterCoverageMap maps from API method to parameter key (`{in}:{name}`) to its enum coverage.
+	ParameterCoverageMap map[static.SimpleAPIMethod]map[string]*EnumParameterCoverage
+}
+
+// NewEnumCoverageTracker creates a new EnumCoverageTracker.
+// Parameters declaring enum are collected from the API doc.
+func NewEnumCoverageTracker(APIManager *static.APIManager) *EnumCoverageTracker {
+	t := &EnumCoverageTracker{
+		ParameterCoverageMap: make(map[static.SimpleAPIMethod]map[string]*EnumParameterCoverage),
+	}
+	for method, operation := range APIManager.APIMap {
+		for _, param := range operation.Parameters {
+			if param == nil {
+				continue
+			}
+			paramSchema := utils.GetParameterSchema(param.Value)
+			if paramSchema == nil {
+				continue
+			}
+			t.addParameter(method, param.Value.Name, param.Value.In, paramSchema.Value.Enum)
+		}
+		requestBodySchema := utils.GetRequestBodySchema(operation.RequestBody)
+		if requestBodySchema == nil {
+			continue
+		}
+		for name, property := range requestBodySchema.Value.Properties {
+			if property == nil || property.Value == nil {
+				continue
+			}
+			t.addParameter(method, name, EnumParameterLocationBody, property.Value.Enum)
+		}
+	}
+	return t
+}
+
+// addParameter adds a parameter to track, if it declares enum.
+func (t *EnumCoverageTracker) addParameter(method static.SimpleAPIMethod, name, in string, enum []any) {
+	if len(enum) == 0 {
+		return
+	}
+	coverage := &EnumParameterCoverage{
+		APIMethod:           method,
+		Name:                name,
+		In:                  in,
+		EnumValues:          make([]string, 0, len(enum)),
+		ExercisedValueCount: make(map[string]int),
+	}
+	for _, value := range enum {
+		formattedValue := fmt.Sprint(value)
+		coverage.EnumValues = append(coverage.EnumValues, formattedValue)
+		coverage.ExercisedValueCount[formattedValue] = 0
+	}
+	if _, exists := t.ParameterCoverageMap[method]; !exists {
+		t.ParameterCoverageMap[method] = make(map[string]*EnumParameterCoverage)
+	}
+	t.ParameterCoverageMap[method][in+":"+name] = coverage
+}
+
+// RecordRequest records the enum values sent in a request.
+// Request body is parsed as a JSON object, and it is ignored if failed to parse.
+func (t *EnumCoverageTracker) RecordRequest(method static.SimpleAPIMethod, pathParams, queryParams map[string]string, requestBody []byte) {
+	parameterCoverages, exists := t.ParameterCoverageMap[method]
+	if !exists {
+		return
+	}
+	record := func(in, name string, value any) {
+		coverage, exists := parameterCoverages[in+":"+name]
+		if !exists {
+			return
+		}
+		formattedValue := fmt.Sprint(value)
+		if _, isMember := coverage.ExercisedValueCount[formattedValue]; isMember {
+			coverage.ExercisedValueCount[formattedValue]++
+		} else {
+			coverage.InvalidValueCount++
+		}
+	}
+	for name, value := range pathParams {
+		record(openapi3.ParameterInPath, name, value)
+	}
+	for name, value := range queryParams {
+		record(openapi3.ParameterInQuery, name, value)
+	}
+	if len(requestBody) > 0 {
+		var body map[string]any
+		err := sonic.Unmarshal(requestBody, &body)
+		if err != nil {
+			log.Debug().Msgf("[EnumCoverageTracker.RecordRequest] Request body is not a JSON object, ignored: %v", err)
+			return
+		}
+		for name, value := range body {
+			record(EnumParameterLocationBody, name, value)
+		}
+	}
+}
+
+// GetParameterCoverages returns the enum coverage of all tracked parameters, sorted by API method, location and name.
+func (t *EnumCoverageTracker) GetParameterCoverages() []*EnumParameterCoverage {
+	res := make([]*EnumParameterCoverage, 0)
+	for _, parameterCoverages := range t.ParameterCoverageMap {
+		res = append(res, slices.Collect(maps.Values(parameterCoverages))...)
+	}
+	slices.SortFunc(res, func(a, b *EnumParameterCoverage) int {
+		if c := static.CompareSimpleAPIMethod(a.APIMethod, b.APIMethod); c != 0 {
+			return c
+		}
+		if c := strings.Compare(a.In, b.In); c != 0 {
+			return c
+		}
+		return strings.Compare(a.Name, b.Name)
+	})
+	return res
+}
+
+// GetEnumCoverage returns the ratio of exercised enum members among all enum members of all tracked parameters.
+// It returns 0 if no parameter declares enum.
+func (t *EnumCoverageTracker) GetEnumCoverage() float64 {
+	totalCnt, coveredCnt := 0, 0
+	for _, parameterCoverages := range t.ParameterCoverageMap {
+		for _, coverage := range parameterCoverages {
+			totalCnt += len(coverage.ExercisedValueCount)
+			coveredCnt += coverage.GetCoveredCount()
+		}
+	}
+	if totalCnt == 0 {
+		return 0
+	}
+	return float64(coveredCnt) / float64(totalCnt)
+}
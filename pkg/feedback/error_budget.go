@@ -0,0 +1,152 @@
+package feedback
+
+import (
+	"fmt"
+	"maps"
+	"resttracefuzzer/pkg/utils/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/bytedance/sonic"
+	"github.com/rs/zerolog/log"
+)
+
+// ErrorBudgetTransportKey is the key of transport errors in error budgets, i.e., requests failing without a response (e.g., connection refused and timeouts).
+const ErrorBudgetTransportKey = "transport"
+
+// ErrorBudget aborts a run early if too many requests fail in the same way, e.g., more than 30% are transport errors, or 90% respond with 401,
+// as such runs indicate misconfiguration (e.g., wrong base URL or missing credentials), and their reports are worthless.
+// A budget is the max percentage (0-100) of requests of a kind, where the kind is transport errors (see [ErrorBudgetTransportKey]),
+// a status class (e.g., `4XX`), or a status code (e.g., `401`).
+// Budgets are checked only after at least MinRequests requests, so that a few unlucky requests at the beginning do not abort the run.
+type ErrorBudget struct {
+	// Budgets maps from the kinds of requests to their max percentages (0-100).
+	Budgets map[string]float64
+
+	// MinRequests is the minimum number of requests before budgets are checked.
+	MinRequests int
+
+	// RequestCount is the number of requests recorded.
+	RequestCount int
+
+	// KindCounts maps from the kinds of requests in budgets to their numbers.
+	KindCounts map[string]int
+}
+
+// NewErrorBudget creates a new ErrorBudget.
+// budgetsStr is a stringified JSON map from the kinds of requests to their max percentages, e.g., `{"transport": 30, "401": 90, "5XX": 80}`.
+// It returns nil if budgetsStr is empty, i.e., no budget is set, or an error if it is invalid.
+func NewErrorBudget(budgetsStr string, minRequests int) (*ErrorBudget, error) {
+	if budgetsStr == "" {
+		return nil, nil
+	}
+	budgets := make(map[string]float64)
+	err := sonic.UnmarshalString(budgetsStr, &budgets)
+	if err != nil {
+		log.Err(err).Msg("[NewErrorBudget] Failed to unmarshal error budgets")
+		return nil, err
+	}
+	normalizedBudgets := make(map[string]float64)
+	for key, percent := range budgets {
+		normalizedKey, err := normalizeErrorBudgetKey(key)
+		if err != nil {
+			return nil, err
+		}
+		if percent < 0 || percent > 100 {
+			return nil, fmt.Errorf("invalid percentage %v of error budget %s, it should be in [0, 100]", percent, key)
+		}
+		normalizedBudgets[normalizedKey] = percent
+	}
+	return &ErrorBudget{
+		Budgets:     normalizedBudgets,
+		MinRequests: max(minRequests, 1),
+		KindCounts:  make(map[string]int),
+	}, nil
+}
+
+// RecordResponse records the response of a request, where isTransportError tells whether it fails without a response.
+func (b *ErrorBudget) RecordResponse(statusCode int, isTransportError bool) {
+	b.RequestCount++
+	for key := range b.Budgets {
+		if matchErrorBudgetKey(key, statusCode, isTransportError) {
+			b.KindCounts[key]++
+		}
+	}
+}
+
+// CheckExceeded checks whether any budget is exceeded, and returns an error with a diagnostic of the first exceeded one (by key), or nil if none is.
+func (b *ErrorBudget) CheckExceeded() error {
+	if b.RequestCount < b.MinRequests {
+		return nil
+	}
+	for _, key := range slices.Sorted(maps.Keys(b.Budgets)) {
+		percent := float64(b.KindCounts[key]) * 100 / float64(b.RequestCount)
+		if percent > b.Budgets[key] {
+			return fmt.Errorf("error budget exceeded: %.1f%% of %d requests %s, above the budget %v%%, %s", percent, b.RequestCount, describeErrorBudgetKey(key), b.Budgets[key], getErrorBudgetHint(key))
+		}
+	}
+	return nil
+}
+
+// GetSortedKindCounts returns the numbers of requests of kinds in budgets, as `{kind}: {count}/{total}` sorted by kind, e.g., for logging.
+func (b *ErrorBudget) GetSortedKindCounts() []string {
+	res := make([]string, 0, len(b.Budgets))
+	for _, key := range slices.Sorted(maps.Keys(b.Budgets)) {
+		res = append(res, fmt.Sprintf("%s: %d/%d", key, b.KindCounts[key], b.RequestCount))
+	}
+	return res
+}
+
+// normalizeErrorBudgetKey validates the key of an error budget, and normalizes status classes to upper case, e.g., `4xx` to `4XX`.
+func normalizeErrorBudgetKey(key string) (string, error) {
+	if strings.EqualFold(key, ErrorBudgetTransportKey) {
+		return ErrorBudgetTransportKey, nil
+	}
+	upperKey := strings.ToUpper(key)
+	if len(upperKey) == 3 && upperKey[0] >= '1' && upperKey[0] <= '5' && upperKey[1:] == "XX" {
+		return upperKey, nil
+	}
+	if statusCode, err := strconv.Atoi(key); err == nil && statusCode >= 100 && statusCode < 600 {
+		return key, nil
+	}
+	return "", fmt.Errorf("invalid error budget key %s, it should be %s, a status class (e.g., 4XX) or a status code (e.g., 401)", key, ErrorBudgetTransportKey)
+}
+
+// matchErrorBudgetKey checks whether a response matches the (normalized) key of an error budget.
+// Transport errors match the transport key only, as they have no real status code.
+func matchErrorBudgetKey(key string, statusCode int, isTransportError bool) bool {
+	if key == ErrorBudgetTransportKey || isTransportError {
+		return key == ErrorBudgetTransportKey && isTransportError
+	}
+	if strings.HasSuffix(key, "XX") {
+		return http.GetStatusCodeClass(statusCode) == int(key[0]-'0')*100
+	}
+	return key == strconv.Itoa(statusCode)
+}
+
+// describeErrorBudgetKey describes the kind of requests of an error budget key, e.g., `are transport errors` or `responded with 401`.
+func describeErrorBudgetKey(key string) string {
+	if key == ErrorBudgetTransportKey {
+		return "are transport errors"
+	}
+	return "responded with " + key
+}
+
+// getErrorBudgetHint returns the likely misconfiguration of an exceeded error budget.
+func getErrorBudgetHint(key string) string {
+	switch key {
+	case ErrorBudgetTransportKey:
+		return "check that the server is up and reachable at server-base-url, and http-client-dial-timeout"
+	case "401", "403":
+		return "check credentials, e.g., extra-headers and user-sessions"
+	case "404":
+		return "check that server-base-url includes the base path of the API doc"
+	case "4XX":
+		return "check credentials and server-base-url, and that the API doc matches the server"
+	case "5XX":
+		return "check the health of the server and its dependencies"
+	default:
+		return "check the config of the run"
+	}
+}
@@ -0,0 +1,109 @@
+package feedback
+
+import (
+	"bytes"
+	"reflect"
+	"resttracefuzzer/pkg/casemanager"
+	"resttracefuzzer/pkg/static"
+
+	"github.com/bytedance/sonic"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+)
+
+// IdempotencyViolation records a request with an idempotency key which the server does not honor,
+// i.e., replaying the request with the same key gets a different response.
+type IdempotencyViolation struct {
+	// TestScenarioUUID is the UUID of the test scenario in which the violation is found.
+	TestScenarioUUID uuid.UUID `json:"testScenarioUUID"`
+
+	// APIMethod is the API method of the request.
+	APIMethod static.SimpleAPIMethod `json:"APIMethod"`
+
+	// Header is the header carrying the idempotency key, e.g., `Idempotency-Key`.
+	Header string `json:"header"`
+
+	// IdempotencyKey is the idempotency key of both the original request and the replay.
+	IdempotencyKey string `json:"idempotencyKey"`
+
+	// OriginalStatusCode is the response status code of the original request.
+	OriginalStatusCode int `json:"originalStatusCode"`
+
+	// ReplayStatusCode is the response status code of the replay.
+	ReplayStatusCode int `json:"replayStatusCode"`
+
+	// BodyDiffers is true if the response body of the replay differs from that of the original request.
+	BodyDiffers bool `json:"bodyDiffers"`
+}
+
+// IdempotencyOracle verifies that the server honors idempotency keys declared in the API doc (see [static.GetIdempotencyKeyHeader]).
+// After a request with an idempotency key succeeds, the fuzzer replays it with the same key,
+// and the oracle flags the replay if its status code or body (compared as JSON if possible) differs from the original response.
+// Each API method is replayed at most MaxReplaysPerOperation times.
+type IdempotencyOracle struct {
+	// MaxReplaysPerOperation is the maximum number of replays of each API method.
+	// Idempotency checking is disabled if it is not positive.
+	MaxReplaysPerOperation int
+
+	// Violations are all violations found so far.
+	Violations []*IdempotencyViolation
+
+	// replayCounts maps from API methods to the number of their replays.
+	replayCounts map[static.SimpleAPIMethod]int
+}
+
+// NewIdempotencyOracle creates a new IdempotencyOracle.
+// Idempotency checking is disabled if maxReplaysPerOperation is not positive.
+func NewIdempotencyOracle(maxReplaysPerOperation int) *IdempotencyOracle {
+	return &IdempotencyOracle{
+		MaxReplaysPerOperation: maxReplaysPerOperation,
+		Violations:             make([]*IdempotencyViolation, 0),
+		replayCounts:           make(map[static.SimpleAPIMethod]int),
+	}
+}
+
+// ShouldReplay returns whether the executed operation case should be replayed with its idempotency key,
+// i.e., it succeeds with an idempotency key, and its API method has not been replayed enough.
+func (o *IdempotencyOracle) ShouldReplay(operationCase *casemanager.OperationCase) bool {
+	if o.MaxReplaysPerOperation <= 0 || !operationCase.IsExecutedSuccessfully() {
+		return false
+	}
+	header := static.GetIdempotencyKeyHeader(operationCase.Operation)
+	if header == "" || operationCase.RequestHeaders[header] == "" {
+		return false
+	}
+	return o.replayCounts[operationCase.APIMethod] < o.MaxReplaysPerOperation
+}
+
+// CheckReplay compares the response of the replay with that of the original operation case,
+// and returns the violation if they differ. The violation is also recorded in the oracle.
+func (o *IdempotencyOracle) CheckReplay(testScenarioUUID uuid.UUID, originalOperationCase, replayedOperationCase *casemanager.OperationCase) *IdempotencyViolation {
+	o.replayCounts[originalOperationCase.APIMethod]++
+	bodyDiffers := !isResponseBodyEquivalent(originalOperationCase.ResponseBody, replayedOperationCase.ResponseBody)
+	if originalOperationCase.ResponseStatusCode == replayedOperationCase.ResponseStatusCode && !bodyDiffers {
+		return nil
+	}
+	header := static.GetIdempotencyKeyHeader(originalOperationCase.Operation)
+	violation := &IdempotencyViolation{
+		TestScenarioUUID:   testScenarioUUID,
+		APIMethod:          originalOperationCase.APIMethod,
+		Header:             header,
+		IdempotencyKey:     originalOperationCase.RequestHeaders[header],
+		OriginalStatusCode: originalOperationCase.ResponseStatusCode,
+		ReplayStatusCode:   replayedOperationCase.ResponseStatusCode,
+		BodyDiffers:        bodyDiffers,
+	}
+	log.Warn().Msgf("[IdempotencyOracle.CheckReplay] Idempotency key is not honored by %s %s, status code: %d, replay status code: %d, body differs: %t, test scenario UUID: %s", violation.APIMethod.Method, violation.APIMethod.Endpoint, violation.OriginalStatusCode, violation.ReplayStatusCode, bodyDiffers, testScenarioUUID.String())
+	o.Violations = append(o.Violations, violation)
+	return violation
+}
+
+// isResponseBodyEquivalent checks whether two response bodies are equivalent.
+// They are compared as JSON values if both are valid JSON, so that the order of object keys does not matter, and as bytes otherwise.
+func isResponseBodyEquivalent(a, b []byte) bool {
+	var valueA, valueB any
+	if sonic.Unmarshal(a, &valueA) == nil && sonic.Unmarshal(b, &valueB) == nil {
+		return reflect.DeepEqual(valueA, valueB)
+	}
+	return bytes.Equal(a, b)
+}
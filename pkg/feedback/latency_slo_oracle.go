@@ -0,0 +1,227 @@
+package feedback
+
+import (
+	"fmt"
+	"maps"
+	"resttracefuzzer/pkg/casemanager"
+	"resttracefuzzer/pkg/static"
+	"resttracefuzzer/pkg/utils"
+	"slices"
+	"strings"
+
+	"github.com/bytedance/sonic"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	// LatencySLOExtensionKey is the extension of an OpenAPI operation declaring its latency SLO in milliseconds, e.g., `x-slo-ms: 200`.
+	LatencySLOExtensionKey = "x-slo-ms"
+
+	// DefaultLatencySLOPercentile is the default percentile of latencies checked against SLOs.
+	DefaultLatencySLOPercentile = 95
+)
+
+// LatencySLOViolation records an API method whose latency percentile exceeds its SLO, with latency percentiles as evidence.
+type LatencySLOViolation struct {
+	// APIMethod is the API method violating the SLO.
+	APIMethod static.SimpleAPIMethod `json:"APIMethod"`
+
+	// SLO is the latency SLO of the API method, in milliseconds.
+	SLO float64 `json:"SLOMs"`
+
+	// Percentile is the percentile checked against the SLO, e.g., 95.
+	Percentile float64 `json:"percentile"`
+
+	// PercentileLatency is the latency of Percentile, in milliseconds, which exceeds SLO.
+	PercentileLatency float64 `json:"percentileLatencyMs"`
+
+	// SampleCount is the number of executions of the API method.
+	SampleCount int `json:"sampleCount"`
+
+	// ExceedingCount is the number of executions slower than SLO.
+	ExceedingCount int `json:"exceedingCount"`
+
+	// P50Latency, P95Latency, P99Latency and MaxLatency are latency percentiles of the API method, in milliseconds.
+	P50Latency float64 `json:"p50LatencyMs"`
+	P95Latency float64 `json:"p95LatencyMs"`
+	P99Latency float64 `json:"p99LatencyMs"`
+	MaxLatency float64 `json:"maxLatencyMs"`
+
+	// SlowestTestScenarioUUID is the UUID of the test scenario in which the slowest execution occurs.
+	SlowestTestScenarioUUID uuid.UUID `json:"slowestTestScenarioUUID"`
+}
+
+// latencySamples are the latencies of executions of an API method.
+type latencySamples struct {
+	// latencies are the latencies of executions, in milliseconds.
+	latencies []float64
+
+	// slowestLatency is the latency of the slowest execution, in milliseconds.
+	slowestLatency float64
+
+	// slowestTestScenarioUUID is the UUID of the test scenario of the slowest execution.
+	slowestTestScenarioUUID uuid.UUID
+}
+
+// LatencySLOOracle checks response times of API methods against their latency SLOs,
+// turning fuzzing into a lightweight performance regression check.
+// The SLO of an API method is, in order of precedence:
+//   - declared in config, by `METHOD path`;
+//   - declared by [LatencySLOExtensionKey] of the operation in the API doc;
+//   - the default SLO, if positive.
+//
+// An API method violates its SLO if the percentile (e.g., p95) of its latencies exceeds the SLO.
+// As percentiles need samples, violations are decided after fuzzing, see [LatencySLOOracle.GetViolations].
+type LatencySLOOracle struct {
+	// SLOs maps from API methods to their latency SLOs, in milliseconds.
+	// API methods without SLO are not checked.
+	SLOs map[static.SimpleAPIMethod]float64
+
+	// Percentile is the percentile of latencies checked against SLOs, in (0, 100].
+	Percentile float64
+
+	// samples maps from API methods to latencies of their executions.
+	samples map[static.SimpleAPIMethod]*latencySamples
+}
+
+// NewLatencySLOOracle creates a new LatencySLOOracle.
+// SLOsStr is a stringified JSON map from `METHOD path` (e.g., `GET /users/{id}`) to SLO in milliseconds, which can be empty.
+// defaultSLO applies to API methods without SLO declared, and is disabled if not positive.
+// If percentile is not in (0, 100], [DefaultLatencySLOPercentile] is used.
+// It returns an error if SLOsStr is invalid, or refers to an undefined API method.
+func NewLatencySLOOracle(APIManager *static.APIManager, SLOsStr string, defaultSLO float64, percentile float64) (*LatencySLOOracle, error) {
+	if percentile <= 0 || percentile > 100 {
+		log.Warn().Msgf("[NewLatencySLOOracle] Invalid latency SLO percentile %v, use %d instead", percentile, DefaultLatencySLOPercentile)
+		percentile = DefaultLatencySLOPercentile
+	}
+	SLOs := make(map[static.SimpleAPIMethod]float64)
+	for method, operation := range APIManager.APIMap {
+		if SLO, ok := getOperationLatencySLO(operation.Extensions[LatencySLOExtensionKey]); ok {
+			SLOs[method] = SLO
+		} else if defaultSLO > 0 {
+			SLOs[method] = defaultSLO
+		}
+	}
+	if SLOsStr != "" {
+		configuredSLOs := make(map[string]float64)
+		err := sonic.UnmarshalString(SLOsStr, &configuredSLOs)
+		if err != nil {
+			log.Err(err).Msg("[NewLatencySLOOracle] Failed to unmarshal latency SLOs")
+			return nil, err
+		}
+		for key, SLO := range configuredSLOs {
+			method, err := findAPIMethodByKey(APIManager, key)
+			if err != nil {
+				return nil, err
+			}
+			if SLO <= 0 {
+				return nil, fmt.Errorf("latency SLO of %s is not positive: %v", key, SLO)
+			}
+			SLOs[method] = SLO
+		}
+	}
+	return &LatencySLOOracle{
+		SLOs:       SLOs,
+		Percentile: percentile,
+		samples:    make(map[static.SimpleAPIMethod]*latencySamples),
+	}, nil
+}
+
+// EvaluateScenario implements [ScenarioEvaluator], recording latencies of executed operation cases with SLOs.
+// Operation cases failing to receive responses (i.e., with no status code) are ignored.
+func (o *LatencySLOOracle) EvaluateScenario(testScenario *casemanager.TestScenario, operationResults []*OperationResult) {
+	for _, result := range operationResults {
+		operationCase := result.OperationCase
+		if _, exist := o.SLOs[operationCase.APIMethod]; !exist || operationCase.ResponseStatusCode == 0 {
+			continue
+		}
+		samples, exist := o.samples[operationCase.APIMethod]
+		if !exist {
+			samples = &latencySamples{latencies: make([]float64, 0)}
+			o.samples[operationCase.APIMethod] = samples
+		}
+		if len(samples.latencies) == 0 || operationCase.ResponseTime > samples.slowestLatency {
+			samples.slowestLatency = operationCase.ResponseTime
+			samples.slowestTestScenarioUUID = testScenario.UUID
+		}
+		samples.latencies = append(samples.latencies, operationCase.ResponseTime)
+	}
+}
+
+// GetViolations returns the API methods whose latency percentile exceeds their SLOs, sorted by API method.
+func (o *LatencySLOOracle) GetViolations() []*LatencySLOViolation {
+	violations := make([]*LatencySLOViolation, 0)
+	for _, method := range slices.SortedFunc(maps.Keys(o.samples), static.CompareSimpleAPIMethod) {
+		samples := o.samples[method]
+		SLO := o.SLOs[method]
+		sortedLatencies := slices.Sorted(slices.Values(samples.latencies))
+		percentileLatency := utils.GetPercentile(sortedLatencies, o.Percentile)
+		if percentileLatency <= SLO {
+			continue
+		}
+		exceedingCount := 0
+		for _, latency := range sortedLatencies {
+			if latency > SLO {
+				exceedingCount++
+			}
+		}
+		violations = append(violations, &LatencySLOViolation{
+			APIMethod:               method,
+			SLO:                     SLO,
+			Percentile:              o.Percentile,
+			PercentileLatency:       percentileLatency,
+			SampleCount:             len(sortedLatencies),
+			ExceedingCount:          exceedingCount,
+			P50Latency:              utils.GetPercentile(sortedLatencies, 50),
+			P95Latency:              utils.GetPercentile(sortedLatencies, 95),
+			P99Latency:              utils.GetPercentile(sortedLatencies, 99),
+			MaxLatency:              samples.slowestLatency,
+			SlowestTestScenarioUUID: samples.slowestTestScenarioUUID,
+		})
+	}
+	return violations
+}
+
+// getOperationLatencySLO parses the value of [LatencySLOExtensionKey] of an operation.
+// It returns false if the value is absent or not a positive number.
+func getOperationLatencySLO(value any) (float64, bool) {
+	var SLO float64
+	switch v := value.(type) {
+	case nil:
+		return 0, false
+	case float64:
+		SLO = v
+	case float32:
+		SLO = float64(v)
+	case int:
+		SLO = float64(v)
+	case int64:
+		SLO = float64(v)
+	default:
+		log.Warn().Msgf("[getOperationLatencySLO] %s is not a number: %v", LatencySLOExtensionKey, value)
+		return 0, false
+	}
+	if SLO <= 0 {
+		log.Warn().Msgf("[getOperationLatencySLO] %s is not positive: %v", LatencySLOExtensionKey, value)
+		return 0, false
+	}
+	return SLO, true
+}
+
+// findAPIMethodByKey finds the API method of the key `METHOD path`, e.g., `GET /users/{id}`, in the API manager.
+func findAPIMethodByKey(APIManager *static.APIManager, key string) (static.SimpleAPIMethod, error) {
+	method, path, found := strings.Cut(strings.TrimSpace(key), " ")
+	if !found {
+		return static.SimpleAPIMethod{}, fmt.Errorf("invalid API method %q, expected `METHOD path`", key)
+	}
+	apiMethod := static.SimpleAPIMethod{
+		Method:   strings.ToUpper(method),
+		Endpoint: strings.TrimSpace(path),
+		Typ:      static.SimpleAPIMethodTypeHTTP,
+	}
+	if _, exist := APIManager.APIMap[apiMethod]; !exist {
+		return static.SimpleAPIMethod{}, fmt.Errorf("API method %q is not defined in the API doc", key)
+	}
+	return apiMethod, nil
+}
@@ -0,0 +1,229 @@
+package feedback
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"resttracefuzzer/pkg/static"
+	"resttracefuzzer/pkg/utils/http"
+	"slices"
+	"strings"
+
+	"github.com/cloudwego/hertz/pkg/protocol/consts"
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/rs/zerolog/log"
+)
+
+// MethodDiscrepancyType represents the type of a discrepancy between the methods a path handles and those documented.
+type MethodDiscrepancyType string
+
+const (
+	// MethodDiscrepancyTypeAllowedButUndocumented means a method is listed in `Allow` header of OPTIONS response, but not documented.
+	MethodDiscrepancyTypeAllowedButUndocumented MethodDiscrepancyType = "allowedButUndocumented"
+
+	// MethodDiscrepancyTypeDocumentedButNotAllowed means a method is documented, but not listed in `Allow` header of OPTIONS response.
+	MethodDiscrepancyTypeDocumentedButNotAllowed MethodDiscrepancyType = "documentedButNotAllowed"
+
+	// MethodDiscrepancyTypeUndocumentedMethodHandled means an undocumented method (HEAD without documented GET) is handled successfully.
+	MethodDiscrepancyTypeUndocumentedMethodHandled MethodDiscrepancyType = "undocumentedMethodHandled"
+
+	// MethodDiscrepancyTypeMethodOverrideHonored means a POST request with method override header is handled as the overriding method,
+	// while POST itself is not documented (and not handled).
+	MethodDiscrepancyTypeMethodOverrideHonored MethodDiscrepancyType = "methodOverrideHonored"
+)
+
+// AllowHeaderKey is the response header listing methods allowed on a resource.
+const AllowHeaderKey = "Allow"
+
+// defaultPathParamProbeValue is the value of path parameters in probing requests, if the parameter has no default or enum.
+const defaultPathParamProbeValue = "1"
+
+// MethodDiscrepancy records a discrepancy between the methods a path handles and those documented.
+type MethodDiscrepancy struct {
+	// Typ is the type of the discrepancy.
+	Typ MethodDiscrepancyType `json:"type"`
+
+	// Path is the path template in API doc, e.g., `/users/{id}`.
+	Path string `json:"path"`
+
+	// Method is the method in question.
+	Method string `json:"method"`
+
+	// DocumentedMethods are the methods documented for the path.
+	DocumentedMethods []string `json:"documentedMethods"`
+
+	// Detail describes the evidence, e.g., the `Allow` header or the response status code.
+	Detail string `json:"detail"`
+}
+
+// MethodProber probes undocumented method handling of each path in API doc, before fuzzing.
+// For each path, it sends:
+//   - OPTIONS, and compares methods in `Allow` header with documented ones.
+//   - HEAD, if neither HEAD nor GET is documented.
+//   - POST without and with method override headers (e.g., `X-HTTP-Method-Override: GET`), if POST is not documented but GET is,
+//     to check whether the server honors the override. It is sent only if OverrideHeaders is not empty.
+//
+// OPTIONS and HEAD requests are safe, while POST requests are not: a server handling the undocumented POST, or ignoring the override, may change its state.
+// So POST is only sent to paths without documented POST, and only if method override probing is opted in by OverrideHeaders.
+type MethodProber struct {
+	// APIManager provides the API definitions.
+	APIManager *static.APIManager
+
+	// OverrideHeaders are the header keys of method override probed, e.g., `X-HTTP-Method-Override`.
+	// POST requests are not sent if it is empty, see [MethodProber].
+	OverrideHeaders []string
+
+	// ExtraHeaders are headers attached to every probing request, e.g., tokens.
+	ExtraHeaders map[string]string
+
+	// Discrepancies are all discrepancies found so far.
+	Discrepancies []*MethodDiscrepancy
+
+	// ProbedPathCount is the number of paths probed.
+	ProbedPathCount int
+}
+
+// NewMethodProber creates a new MethodProber.
+// overrideHeaders is a comma-separated list of method override header keys.
+func NewMethodProber(APIManager *static.APIManager, overrideHeaders string, extraHeaders map[string]string) *MethodProber {
+	headerKeys := make([]string, 0)
+	for _, key := range strings.Split(overrideHeaders, ",") {
+		if key = strings.TrimSpace(key); key != "" {
+			headerKeys = append(headerKeys, key)
+		}
+	}
+	return &MethodProber{
+		APIManager:      APIManager,
+		OverrideHeaders: headerKeys,
+		ExtraHeaders:    extraHeaders,
+		Discrepancies:   make([]*MethodDiscrepancy, 0),
+	}
+}
+
+// Probe probes all paths of API doc with the HTTP client, and records discrepancies found.
+// The HTTP client should capture [AllowHeaderKey] header in responses.
+// It stops once ctx is done.
+func (p *MethodProber) Probe(ctx context.Context, httpClient *http.HTTPClient) {
+	if p.APIManager.APIDoc == nil || p.APIManager.APIDoc.Paths == nil {
+		log.Warn().Msg("[MethodProber.Probe] No API doc available")
+		return
+	}
+	for _, path := range slices.Sorted(maps.Keys(p.APIManager.APIDoc.Paths.Map())) {
+		if ctx.Err() != nil {
+			log.Warn().Msg("[MethodProber.Probe] Context done, stop probing")
+			return
+		}
+		p.probePath(ctx, httpClient, path, p.APIManager.APIDoc.Paths.Value(path))
+		p.ProbedPathCount++
+	}
+	log.Info().Msgf("[MethodProber.Probe] Probed %d paths, found %d method discrepancies", p.ProbedPathCount, len(p.Discrepancies))
+}
+
+// probePath probes a single path.
+func (p *MethodProber) probePath(ctx context.Context, httpClient *http.HTTPClient, path string, pathItem *openapi3.PathItem) {
+	operations := pathItem.Operations()
+	documentedMethods := slices.Sorted(maps.Keys(operations))
+	pathParams := getPathParamProbeValues(pathItem)
+	addDiscrepancy := func(typ MethodDiscrepancyType, method, detail string) {
+		log.Info().Msgf("[MethodProber.probePath] Method discrepancy %s on %s %s: %s", typ, method, path, detail)
+		p.Discrepancies = append(p.Discrepancies, &MethodDiscrepancy{
+			Typ:               typ,
+			Path:              path,
+			Method:            method,
+			DocumentedMethods: documentedMethods,
+			Detail:            detail,
+		})
+	}
+	request := func(method string, extraHeaders map[string]string) (int, map[string]string) {
+		headers := maps.Clone(p.ExtraHeaders)
+		if headers == nil {
+			headers = make(map[string]string)
+		}
+		maps.Copy(headers, extraHeaders)
+		statusCode, respHeaders, _, err := httpClient.PerformRequest(ctx, path, method, headers, maps.Clone(pathParams), nil, nil)
+		if err != nil {
+			log.Err(err).Msgf("[MethodProber.probePath] Failed to probe %s %s", method, path)
+			return 0, nil
+		}
+		return statusCode, respHeaders
+	}
+
+	// OPTIONS: compare allowed methods with documented ones.
+	// Methods implied by HTTP semantics (OPTIONS itself, and HEAD for GET) are not regarded as undocumented.
+	if _, documented := operations[consts.MethodOptions]; !documented {
+		statusCode, respHeaders := request(consts.MethodOptions, nil)
+		if allowHeader := respHeaders[AllowHeaderKey]; http.IsStatusCodeSuccess(statusCode) && allowHeader != "" {
+			allowedMethods := make([]string, 0)
+			for _, method := range strings.Split(allowHeader, ",") {
+				if method = strings.ToUpper(strings.TrimSpace(method)); method != "" {
+					allowedMethods = append(allowedMethods, method)
+				}
+			}
+			for _, method := range allowedMethods {
+				implied := method == consts.MethodOptions || (method == consts.MethodHead && slices.Contains(documentedMethods, consts.MethodGet))
+				if !implied && !slices.Contains(documentedMethods, method) {
+					addDiscrepancy(MethodDiscrepancyTypeAllowedButUndocumented, method, fmt.Sprintf("Allow: %s", allowHeader))
+				}
+			}
+			for _, method := range documentedMethods {
+				if !slices.Contains(allowedMethods, method) {
+					addDiscrepancy(MethodDiscrepancyTypeDocumentedButNotAllowed, method, fmt.Sprintf("Allow: %s", allowHeader))
+				}
+			}
+		}
+	}
+
+	// HEAD: it should not be handled if neither HEAD nor GET is documented.
+	_, headDocumented := operations[consts.MethodHead]
+	_, getDocumented := operations[consts.MethodGet]
+	if !headDocumented && !getDocumented {
+		if statusCode, _ := request(consts.MethodHead, nil); http.IsStatusCodeSuccess(statusCode) {
+			addDiscrepancy(MethodDiscrepancyTypeUndocumentedMethodHandled, consts.MethodHead, fmt.Sprintf("status code %d", statusCode))
+		}
+	}
+
+	// Method override: POST overridden to GET should not be handled as GET, if POST is not documented.
+	if _, postDocumented := operations[consts.MethodPost]; postDocumented || !getDocumented || len(p.OverrideHeaders) == 0 {
+		return
+	}
+	plainStatusCode, _ := request(consts.MethodPost, nil)
+	if http.IsStatusCodeSuccess(plainStatusCode) {
+		addDiscrepancy(MethodDiscrepancyTypeUndocumentedMethodHandled, consts.MethodPost, fmt.Sprintf("status code %d", plainStatusCode))
+		return
+	}
+	for _, headerKey := range p.OverrideHeaders {
+		statusCode, _ := request(consts.MethodPost, map[string]string{headerKey: consts.MethodGet})
+		if http.IsStatusCodeSuccess(statusCode) {
+			addDiscrepancy(MethodDiscrepancyTypeMethodOverrideHonored, consts.MethodPost, fmt.Sprintf("%s: %s, status code %d (%d without override)", headerKey, consts.MethodGet, statusCode, plainStatusCode))
+		}
+	}
+}
+
+// getPathParamProbeValues returns values of path parameters declared in the path item, for probing requests.
+// It uses the default value or the first enum member of the parameter if available, otherwise [defaultPathParamProbeValue].
+func getPathParamProbeValues(pathItem *openapi3.PathItem) map[string]string {
+	res := make(map[string]string)
+	parameters := slices.Clone(pathItem.Parameters)
+	for _, operation := range pathItem.Operations() {
+		parameters = append(parameters, operation.Parameters...)
+	}
+	for _, paramRef := range parameters {
+		if paramRef == nil || paramRef.Value == nil || paramRef.Value.In != openapi3.ParameterInPath {
+			continue
+		}
+		param := paramRef.Value
+		if _, exists := res[param.Name]; exists {
+			continue
+		}
+		value := defaultPathParamProbeValue
+		if param.Schema != nil && param.Schema.Value != nil {
+			if param.Schema.Value.Default != nil {
+				value = fmt.Sprint(param.Schema.Value.Default)
+			} else if len(param.Schema.Value.Enum) > 0 {
+				value = fmt.Sprint(param.Schema.Value.Enum[0])
+			}
+		}
+		res[param.Name] = value
+	}
+	return res
+}
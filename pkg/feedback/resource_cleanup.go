@@ -0,0 +1,273 @@
+package feedback
+
+import (
+	"maps"
+	"net/url"
+	"resttracefuzzer/pkg/casemanager"
+	"resttracefuzzer/pkg/static"
+	"resttracefuzzer/pkg/utils"
+	"resttracefuzzer/pkg/utils/http"
+	"slices"
+	"strings"
+
+	"github.com/cloudwego/hertz/pkg/protocol/consts"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+)
+
+// LocationHeaderKey is the key of the response header carrying the URL of the created resource, e.g., in 201 responses.
+const LocationHeaderKey = "Location"
+
+// CreatedResource is a resource created during fuzzing, which can be deleted by a DELETE operation.
+type CreatedResource struct {
+	// ResourcePath is the concrete path of the resource, e.g., `/users/42`.
+	ResourcePath string `json:"resourcePath"`
+
+	// Creator is the API method which creates the resource, e.g., `POST /users`.
+	Creator static.SimpleAPIMethod `json:"creator"`
+
+	// Deleter is the API method which deletes the resource, e.g., `DELETE /users/{userId}`.
+	Deleter static.SimpleAPIMethod `json:"deleter"`
+
+	// PathParams are the path parameters of the request of Deleter, e.g., `{"userId": "42"}`.
+	PathParams map[string]string `json:"pathParams"`
+
+	// TestScenarioUUID is the UUID of the test scenario in which the resource is created.
+	TestScenarioUUID uuid.UUID `json:"testScenarioUUID"`
+
+	// ServerBaseURL is the base URL of the server the resource is created on, see [CreatedResourceTracker.ServerBaseURLOf].
+	// The resource should be deleted on the same server, e.g., if servers are rotated. It is empty if unknown.
+	ServerBaseURL string `json:"serverBaseURL,omitempty"`
+}
+
+// ResourceCleanupResult is the result of deleting a created resource in the cleanup after fuzzing.
+type ResourceCleanupResult struct {
+	// Resource is the resource to delete.
+	Resource *CreatedResource `json:"resource"`
+
+	// StatusCode is the response status code of the deletion. It is 0 if the request failed.
+	StatusCode int `json:"statusCode"`
+
+	// Removed is true if the resource is gone after the deletion, i.e., the response is successful, or 404 or 410 as it was already gone.
+	Removed bool `json:"removed"`
+}
+
+// CreatedResourceTracker tracks resources created during fuzzing, so that they can be deleted after fuzzing to restore the system under test.
+// A resource is created by a successful request which:
+//   - responds 201 or with a `Location` header, to a POST on a collection path (e.g., `/users`) whose item path (e.g., `/users/{userId}`) supports DELETE.
+//     The resource is at the path in the `Location` header if any, or identified by the id in the response body otherwise, see [resolveCreatedResourcePath].
+//   - responds 201 to a PUT on an item path supporting DELETE, i.e., the requested path.
+//
+// Resources deleted by successful DELETE requests in test scenarios are no longer tracked.
+// Resources are identified by the server they are created on and their path, so that resources at the same path of different servers are tracked separately.
+type CreatedResourceTracker struct {
+	// APIManager provides the API definitions.
+	APIManager *static.APIManager
+
+	// ServerBaseURLOf returns the base URL of the server requests of the API method are currently sent to, e.g., with servers rotated.
+	// It is called when test scenarios are evaluated, i.e., right after they are executed. It can be nil, i.e., all resources are of the same server.
+	ServerBaseURLOf func(method static.SimpleAPIMethod) string
+
+	// CleanupResults are the results of deletions in the cleanup, see [CreatedResourceTracker.RecordCleanup].
+	CleanupResults []*ResourceCleanupResult
+
+	// createdResources are the resources created, in the order of creation. Untracked ones are nil, and are compacted lazily, see [CreatedResourceTracker.untrack].
+	createdResources []*CreatedResource
+
+	// createdResourceIndices maps from keys of tracked resources (see [getCreatedResourceKey]) to their indices in createdResources.
+	createdResourceIndices map[string]int
+
+	// collection2DeleteItemPath maps from a collection path supporting POST to its item path supporting DELETE, e.g., from `/users` to `/users/{userId}`.
+	collection2DeleteItemPath map[string]string
+}
+
+// NewCreatedResourceTracker creates a new CreatedResourceTracker.
+func NewCreatedResourceTracker(APIManager *static.APIManager) *CreatedResourceTracker {
+	return &CreatedResourceTracker{
+		APIManager:                APIManager,
+		CleanupResults:            make([]*ResourceCleanupResult, 0),
+		createdResources:          make([]*CreatedResource, 0),
+		createdResourceIndices:    make(map[string]int),
+		collection2DeleteItemPath: getCollection2DeleteItemPath(APIManager),
+	}
+}
+
+// getCollection2DeleteItemPath returns the map from collection paths supporting POST to item paths supporting DELETE, derived from the API doc.
+// An item path is a path with a path parameter as its last segment, and its collection path is the path without the last segment.
+func getCollection2DeleteItemPath(APIManager *static.APIManager) map[string]string {
+	collection2DeleteItemPath := make(map[string]string)
+	for method := range APIManager.APIMap {
+		if method.Method != consts.MethodDelete {
+			continue
+		}
+		segments := utils.SplitEndpointPath(method.Endpoint)
+		if len(segments) < 2 || !utils.IfPathSegmentIsPathParam(segments[len(segments)-1]) {
+			continue
+		}
+		collectionPath := "/" + strings.Join(segments[:len(segments)-1], "/")
+		creator := static.SimpleAPIMethod{
+			Endpoint: collectionPath,
+			Method:   consts.MethodPost,
+			Typ:      method.Typ,
+		}
+		if _, exists := APIManager.APIMap[creator]; exists {
+			collection2DeleteItemPath[collectionPath] = method.Endpoint
+		}
+	}
+	return collection2DeleteItemPath
+}
+
+// EvaluateScenario implements [ScenarioEvaluator], tracking resources created and deleted by the executed test scenario.
+func (t *CreatedResourceTracker) EvaluateScenario(testScenario *casemanager.TestScenario, operationResults []*OperationResult) {
+	for _, result := range operationResults {
+		operationCase := result.OperationCase
+		if !http.IsStatusCodeSuccess(operationCase.ResponseStatusCode) {
+			continue
+		}
+		serverBaseURL := t.getServerBaseURL(operationCase.APIMethod)
+		if operationCase.APIMethod.Method == consts.MethodDelete {
+			t.untrack(getCreatedResourceKey(serverBaseURL, fillPathParams(operationCase.APIMethod.Endpoint, operationCase.RequestPathParams)))
+			continue
+		}
+		createdResource, created := t.resolveCreatedResource(operationCase)
+		if !created {
+			continue
+		}
+		createdResource.TestScenarioUUID = testScenario.UUID
+		createdResource.ServerBaseURL = serverBaseURL
+		// A resource re-created at the same path (e.g., by PUT) is tracked once, at its latest creation.
+		key := getCreatedResourceKey(serverBaseURL, createdResource.ResourcePath)
+		t.untrack(key)
+		t.createdResourceIndices[key] = len(t.createdResources)
+		t.createdResources = append(t.createdResources, createdResource)
+		log.Debug().Msgf("[CreatedResourceTracker.EvaluateScenario] Resource %s created by %s %s", createdResource.ResourcePath, operationCase.APIMethod.Method, operationCase.APIMethod.Endpoint)
+	}
+}
+
+// resolveCreatedResource resolves the resource created by the executed operation case, if any.
+func (t *CreatedResourceTracker) resolveCreatedResource(operationCase *casemanager.OperationCase) (*CreatedResource, bool) {
+	method := operationCase.APIMethod
+	location := operationCase.ResponseHeaders[LocationHeaderKey]
+	var (
+		deleteItemPath string
+		pathParams     map[string]string
+	)
+	switch {
+	case method.Method == consts.MethodPost && (operationCase.ResponseStatusCode == consts.StatusCreated || location != ""):
+		itemPath, exists := t.collection2DeleteItemPath[method.Endpoint]
+		if !exists {
+			return nil, false
+		}
+		deleteItemPath = itemPath
+		if location != "" {
+			params, matched := matchLocationPath(itemPath, location)
+			if !matched {
+				log.Debug().Msgf("[CreatedResourceTracker.resolveCreatedResource] Location %s of response of %s %s does not match %s", location, method.Method, method.Endpoint, itemPath)
+				return nil, false
+			}
+			pathParams = params
+		} else {
+			paramName, id, err := resolveCreatedResourceID(itemPath, operationCase.ResponseBody)
+			if err != nil {
+				log.Debug().Msgf("[CreatedResourceTracker.resolveCreatedResource] Failed to resolve created resource of %s %s: %s", method.Method, method.Endpoint, err)
+				return nil, false
+			}
+			// Path parameters of parent resources (e.g., `userId` of `/users/{userId}/orders`) are those of the creation.
+			pathParams = maps.Clone(operationCase.RequestPathParams)
+			if pathParams == nil {
+				pathParams = make(map[string]string)
+			}
+			pathParams[paramName] = id
+		}
+	case method.Method == consts.MethodPut && operationCase.ResponseStatusCode == consts.StatusCreated:
+		deleter := static.SimpleAPIMethod{Endpoint: method.Endpoint, Method: consts.MethodDelete, Typ: method.Typ}
+		if _, exists := t.APIManager.APIMap[deleter]; !exists {
+			return nil, false
+		}
+		deleteItemPath = method.Endpoint
+		pathParams = maps.Clone(operationCase.RequestPathParams)
+	default:
+		return nil, false
+	}
+	resourcePath := fillPathParams(deleteItemPath, pathParams)
+	// The resource cannot be deleted if any path parameter is unknown.
+	if strings.ContainsAny(resourcePath, "{}") {
+		return nil, false
+	}
+	return &CreatedResource{
+		ResourcePath: resourcePath,
+		Creator:      method,
+		Deleter:      static.SimpleAPIMethod{Endpoint: deleteItemPath, Method: consts.MethodDelete, Typ: method.Typ},
+		PathParams:   pathParams,
+	}, true
+}
+
+// matchLocationPath matches the path of the URL in a `Location` header against the item path, and returns the path parameters.
+// The URL can be absolute or relative, and may contain the path of the server base URL (e.g., `/api/v1/users/42` for `/users/{userId}`),
+// so only its last segments, as many as those of the item path, are matched.
+func matchLocationPath(itemPath, location string) (map[string]string, bool) {
+	locationURL, err := url.Parse(location)
+	if err != nil {
+		return nil, false
+	}
+	locationSegments := utils.SplitEndpointPath(locationURL.EscapedPath())
+	itemSegmentCount := len(utils.SplitEndpointPath(itemPath))
+	if len(locationSegments) < itemSegmentCount {
+		return nil, false
+	}
+	return utils.MatchEndpointPath(itemPath, "/"+strings.Join(locationSegments[len(locationSegments)-itemSegmentCount:], "/"))
+}
+
+// getServerBaseURL returns the base URL of the server requests of the API method are sent to, see [CreatedResourceTracker.ServerBaseURLOf].
+func (t *CreatedResourceTracker) getServerBaseURL(method static.SimpleAPIMethod) string {
+	if t.ServerBaseURLOf == nil {
+		return ""
+	}
+	return t.ServerBaseURLOf(method)
+}
+
+// getCreatedResourceKey returns the key of a created resource, consisting of the base URL of its server and its path.
+func getCreatedResourceKey(serverBaseURL, resourcePath string) string {
+	return serverBaseURL + " " + resourcePath
+}
+
+// untrack stops tracking the resource of the key (see [getCreatedResourceKey]), e.g., as it is deleted.
+// The resource is set to nil in createdResources, which is compacted once more than half of it is nil, so that untracking is amortized O(1).
+func (t *CreatedResourceTracker) untrack(key string) {
+	index, tracked := t.createdResourceIndices[key]
+	if !tracked {
+		return
+	}
+	t.createdResources[index] = nil
+	delete(t.createdResourceIndices, key)
+	if len(t.createdResourceIndices) >= len(t.createdResources)/2 {
+		return
+	}
+	t.createdResources = slices.DeleteFunc(t.createdResources, func(resource *CreatedResource) bool { return resource == nil })
+	for i, resource := range t.createdResources {
+		t.createdResourceIndices[getCreatedResourceKey(resource.ServerBaseURL, resource.ResourcePath)] = i
+	}
+}
+
+// GetCreatedResources returns the resources created and not deleted yet, in the reverse order of creation,
+// so that resources depending on others (e.g., orders of a user) are deleted before them.
+func (t *CreatedResourceTracker) GetCreatedResources() []*CreatedResource {
+	createdResources := slices.DeleteFunc(slices.Clone(t.createdResources), func(resource *CreatedResource) bool { return resource == nil })
+	slices.Reverse(createdResources)
+	return createdResources
+}
+
+// RecordCleanup records the result of deleting the created resource in the cleanup.
+// The resource is no longer tracked if it is gone, see [ResourceCleanupResult.Removed].
+func (t *CreatedResourceTracker) RecordCleanup(resource *CreatedResource, statusCode int) *ResourceCleanupResult {
+	result := &ResourceCleanupResult{
+		Resource:   resource,
+		StatusCode: statusCode,
+		Removed:    http.IsStatusCodeSuccess(statusCode) || statusCode == consts.StatusNotFound || statusCode == consts.StatusGone,
+	}
+	if result.Removed {
+		t.untrack(getCreatedResourceKey(resource.ServerBaseURL, resource.ResourcePath))
+	}
+	t.CleanupResults = append(t.CleanupResults, result)
+	return result
+}
@@ -7,8 +7,10 @@ import (
 	"resttracefuzzer/pkg/utils"
 	"resttracefuzzer/pkg/utils/http"
 	"strconv"
+	"strings"
 
 	"github.com/cloudwego/hertz/pkg/protocol/consts"
+	"github.com/getkin/kin-openapi/openapi3"
 	"github.com/rs/zerolog/log"
 )
 
@@ -23,6 +25,13 @@ type ResponseProcesser struct {
 
 	// The Resource Manager. ResponseProcesser will extract resource from response, and store it in the resource manager.
 	ResourceManager *resource.ResourceManager
+
+	// StatusSequenceHitCount is the hit count of status sequences of test scenarios, i.e., scenario-level coverage.
+	// It maps the status sequence (e.g., `POST /users 201 -> GET /users/{id} 200`) to the hit count.
+	StatusSequenceHitCount map[string]int
+
+	// ScenarioEvaluators evaluate whole executed test scenarios, see [ResponseProcesser.ProcessScenario].
+	ScenarioEvaluators []ScenarioEvaluator
 }
 
 // NewResponseProcesser creates a new ResponseProcesser.
@@ -46,9 +55,11 @@ func NewResponseProcesser(APIManager *static.APIManager, resourceManager *resour
 		}
 	}
 	return &ResponseProcesser{
-		StatusHitCount:  counter,
-		APIManager:      APIManager,
-		ResourceManager: resourceManager,
+		StatusHitCount:         counter,
+		APIManager:             APIManager,
+		ResourceManager:        resourceManager,
+		StatusSequenceHitCount: make(map[string]int),
+		ScenarioEvaluators:     make([]ScenarioEvaluator, 0),
 	}
 }
 
@@ -88,7 +99,12 @@ func (rc *ResponseProcesser) ProcessResponse(method static.SimpleAPIMethod, stat
 			resourceName = endpointParts[len(endpointParts)-1]
 		}
 
-		err := rc.ResourceManager.StoreResourcesFromRawObjectBytes(responseBody, resourceName, true)
+		// Resources are scoped to component schemas of the response (e.g., `#/components/schemas/Pet`), so that they can be requested by the schema being filled.
+		var responseSchema *openapi3.SchemaRef
+		if operation, exist := rc.APIManager.GetOperationByMethod(method); exist {
+			responseSchema = utils.GetResponseSchema(operation, statusCode)
+		}
+		err := rc.ResourceManager.StoreResourcesFromRawObjectBytesWithSchema(responseBody, resourceName, true, responseSchema)
 		if err != nil {
 			log.Err(err).Msg("[ResponseProcesser.ProcessResponse] Failed to store resources")
 			return err
@@ -97,6 +113,48 @@ func (rc *ResponseProcesser) ProcessResponse(method static.SimpleAPIMethod, stat
 	return nil
 }
 
+// ProcessDeletion removes resources of the entity deleted by a successful DELETE request from the resource pool,
+// so that later requests do not keep using IDs known to be gone, which would only get 404 responses.
+// The entity is identified by the path parameter of the last path segment, e.g., `userId` of `DELETE /users/{userId}`,
+// and resources referring to its value are removed among those named by the path parameter, the collection (e.g., `users`) and its singular form (e.g., `user`),
+// i.e., primitives of them, and objects of them whose ID field (`id`, the path parameter or the singular form with `Id`, e.g., `userId`) refers to the value,
+// where the value is of the type declared by the path parameter, see [resource.ResourceManager.RemoveResourcesReferringTo].
+// It returns the number of removed resources.
+func (rc *ResponseProcesser) ProcessDeletion(method static.SimpleAPIMethod, statusCode int, pathParams map[string]string) int {
+	if method.Method != consts.MethodDelete || http.GetStatusCodeClass(statusCode) != consts.StatusOK {
+		return 0
+	}
+	endpointParts := utils.SplitEndpointPath(method.Endpoint)
+	if len(endpointParts) < 2 || !utils.IfPathSegmentIsPathParam(endpointParts[len(endpointParts)-1]) {
+		return 0
+	}
+	paramName := strings.Trim(endpointParts[len(endpointParts)-1], "{}")
+	value, exist := pathParams[paramName]
+	if !exist || value == "" {
+		return 0
+	}
+	resourceNames := []string{paramName}
+	fieldNames := []string{"id", paramName}
+	collectionName := endpointParts[len(endpointParts)-2]
+	if !utils.IfPathSegmentIsPathParam(collectionName) {
+		singularName := utils.GetSingularFormNameHeuristic(collectionName)
+		resourceNames = append(resourceNames, collectionName, singularName)
+		fieldNames = append(fieldNames, singularName+"Id")
+	}
+	// An ID of another type (e.g., a string "1" for an integer ID) does not refer to the entity.
+	valueType := static.SimpleAPIPropertyTypeUnknown
+	if operation, exist := rc.APIManager.GetOperationByMethod(method); exist {
+		if param := operation.Parameters.GetByInAndName(openapi3.ParameterInPath, paramName); param != nil && param.Schema != nil && param.Schema.Value != nil && param.Schema.Value.Type != nil {
+			valueType = static.OpenAPITypes2SimpleAPIPropertyType(param.Schema.Value.Type)
+		}
+	}
+	removedCount := rc.ResourceManager.RemoveResourcesReferringTo(resourceNames, fieldNames, value, valueType)
+	if removedCount > 0 {
+		log.Debug().Msgf("[ResponseProcesser.ProcessDeletion] Removed %d resources referring to %s deleted by %s %s", removedCount, value, method.Method, method.Endpoint)
+	}
+	return removedCount
+}
+
 // GetCoveredStatusCodeCount returns the covered status codes.
 func (rc *ResponseProcesser) GetCoveredStatusCodeCount() int {
 	count := 0
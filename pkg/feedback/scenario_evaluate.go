@@ -0,0 +1,71 @@
+package feedback
+
+import (
+	"fmt"
+	"resttracefuzzer/pkg/casemanager"
+	"resttracefuzzer/pkg/feedback/trace"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+)
+
+// OperationResult is the result of an executed operation case in a test scenario,
+// including the response (filled in the operation case) and the trace of the request, if fetched.
+type OperationResult struct {
+	// OperationCase is the executed operation case, with its response filled.
+	OperationCase *casemanager.OperationCase
+
+	// Trace is the trace of the request. It is nil if the trace is not available.
+	Trace *trace.SimplifiedTrace
+
+	// CallInfos are the calls between services in the trace. It is nil if the trace is not available.
+	CallInfos []*trace.CallInfo
+}
+
+// ScenarioEvaluator evaluates a whole executed test scenario at once,
+// e.g., oracles checking invariants across operations.
+type ScenarioEvaluator interface {
+	// EvaluateScenario evaluates the executed test scenario, with results of its executed operation cases in order.
+	EvaluateScenario(testScenario *casemanager.TestScenario, operationResults []*OperationResult)
+}
+
+// RegisterScenarioEvaluator registers a scenario evaluator, which would be called by [ResponseProcesser.ProcessScenario].
+func (rc *ResponseProcesser) RegisterScenarioEvaluator(evaluator ScenarioEvaluator) {
+	rc.ScenarioEvaluators = append(rc.ScenarioEvaluators, evaluator)
+}
+
+// ProcessScenario processes all operation results of a test scenario at once, after each of them has been processed by [ResponseProcesser.ProcessResponse].
+// It updates scenario-level coverage, i.e., the hit count of status sequences, and passes the results to registered scenario evaluators.
+// It returns true if the scenario covers a new status sequence.
+func (rc *ResponseProcesser) ProcessScenario(testScenario *casemanager.TestScenario, operationResults []*OperationResult) bool {
+	if len(operationResults) == 0 {
+		return false
+	}
+	statusSequence := getStatusSequence(operationResults)
+	hasNewStatusSequence := rc.StatusSequenceHitCount[statusSequence] == 0
+	rc.StatusSequenceHitCount[statusSequence]++
+	if hasNewStatusSequence {
+		log.Debug().Msgf("[ResponseProcesser.ProcessScenario] New status sequence covered: %s", statusSequence)
+	}
+
+	for _, evaluator := range rc.ScenarioEvaluators {
+		evaluator.EvaluateScenario(testScenario, operationResults)
+	}
+	return hasNewStatusSequence
+}
+
+// GetCoveredStatusSequenceCount returns the number of distinct status sequences covered by test scenarios.
+func (rc *ResponseProcesser) GetCoveredStatusSequenceCount() int {
+	return len(rc.StatusSequenceHitCount)
+}
+
+// getStatusSequence returns the status sequence of the operation results,
+// e.g., `POST /users 201 -> GET /users/{id} 200`.
+func getStatusSequence(operationResults []*OperationResult) string {
+	parts := make([]string, 0, len(operationResults))
+	for _, result := range operationResults {
+		apiMethod := result.OperationCase.APIMethod
+		parts = append(parts, fmt.Sprintf("%s %s %d", apiMethod.Method, apiMethod.Endpoint, result.OperationCase.ResponseStatusCode))
+	}
+	return strings.Join(parts, " -> ")
+}
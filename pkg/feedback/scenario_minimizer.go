@@ -0,0 +1,142 @@
+package feedback
+
+import (
+	"fmt"
+	"resttracefuzzer/pkg/casemanager"
+	"resttracefuzzer/pkg/static"
+	"resttracefuzzer/pkg/utils"
+	"slices"
+
+	"github.com/cloudwego/hertz/pkg/protocol/consts"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+)
+
+// MinimizedFailure records a server error (5xx) triggered by a multi-operation test scenario,
+// with the shortest scenario found to reproduce it, which is reported instead of the full chain.
+type MinimizedFailure struct {
+	// TestScenarioUUID is the UUID of the test scenario triggering the failure.
+	TestScenarioUUID uuid.UUID `json:"testScenarioUUID"`
+
+	// APIMethod is the API method responding with the server error.
+	APIMethod static.SimpleAPIMethod `json:"APIMethod"`
+
+	// StatusCode is the response status code of the failure.
+	StatusCode int `json:"statusCode"`
+
+	// OriginalLength is the number of operation cases of the original scenario, up to and including the failing one.
+	OriginalLength int `json:"originalLength"`
+
+	// ExecutionCount is the number of reduced scenarios re-executed during minimization.
+	ExecutionCount int `json:"executionCount"`
+
+	// OperationCases are the operation cases of the minimized scenario, with the failing one as the last.
+	// They are the original operation cases if the failure cannot be reproduced by any reduced scenario.
+	OperationCases []*casemanager.OperationCase `json:"-"`
+}
+
+// OperationCaseExecutor executes an operation case, and fills the response in it.
+type OperationCaseExecutor func(operationCase *casemanager.OperationCase) error
+
+// ScenarioMinimizer minimizes multi-operation test scenarios triggering server errors, by delta debugging (see [utils.DeltaDebugMinimize]).
+// Reduced subsequences of operation cases before the failing one are re-executed, and a subsequence reproduces the failure
+// if the failing operation case responds with the same status code.
+// Each failure (API method and status code) is minimized only once.
+type ScenarioMinimizer struct {
+	// MaxExecutions is the maximum number of reduced scenarios re-executed to minimize a failure.
+	// Minimization is disabled if it is not positive.
+	MaxExecutions int
+
+	// Failures are all minimized failures so far.
+	Failures []*MinimizedFailure
+
+	// minimizedFailureKeys are the keys of failures minimized, see [getMinimizedFailureKey].
+	minimizedFailureKeys map[string]struct{}
+}
+
+// NewScenarioMinimizer creates a new ScenarioMinimizer.
+// Minimization is disabled if maxExecutions is not positive.
+func NewScenarioMinimizer(maxExecutions int) *ScenarioMinimizer {
+	return &ScenarioMinimizer{
+		MaxExecutions:        maxExecutions,
+		Failures:             make([]*MinimizedFailure, 0),
+		minimizedFailureKeys: make(map[string]struct{}),
+	}
+}
+
+// FindFailureToMinimize returns the index of the first executed operation case in the test scenario
+// which responds with a server error not minimized yet and is not the first one.
+// It returns -1 if there is no such operation case, or minimization is disabled.
+func (m *ScenarioMinimizer) FindFailureToMinimize(testScenario *casemanager.TestScenario) int {
+	if m.MaxExecutions <= 0 {
+		return -1
+	}
+	for i, operationCase := range testScenario.OperationCases {
+		if i == 0 || operationCase.ResponseStatusCode < consts.StatusInternalServerError {
+			continue
+		}
+		if _, exist := m.minimizedFailureKeys[getMinimizedFailureKey(operationCase)]; !exist {
+			return i
+		}
+	}
+	return -1
+}
+
+// Minimize minimizes the operation cases before the failing one (at failingIndex) in the test scenario,
+// by re-executing copies of reduced subsequences and the failing operation case with the executor,
+// and records the minimized failure.
+// Operation cases of the test scenario are not modified.
+func (m *ScenarioMinimizer) Minimize(testScenario *casemanager.TestScenario, failingIndex int, execute OperationCaseExecutor) (*MinimizedFailure, error) {
+	if failingIndex <= 0 || failingIndex >= len(testScenario.OperationCases) {
+		return nil, fmt.Errorf("invalid failing operation case index %d for test scenario of length %d", failingIndex, len(testScenario.OperationCases))
+	}
+	failingOperationCase := testScenario.OperationCases[failingIndex]
+	m.minimizedFailureKeys[getMinimizedFailureKey(failingOperationCase)] = struct{}{}
+
+	// A reduced scenario reproduces the failure if the failing operation case responds with the same status code.
+	var minimizedOperationCases []*casemanager.OperationCase
+	reproduce := func(prefix []*casemanager.OperationCase) bool {
+		executedOperationCases := make([]*casemanager.OperationCase, 0, len(prefix)+1)
+		// Do not append to the prefix, which may share the underlying array with other subsequences.
+		for _, operationCase := range slices.Concat(prefix, []*casemanager.OperationCase{failingOperationCase}) {
+			executedOperationCase := operationCase.Copy()
+			if err := execute(executedOperationCase); err != nil {
+				log.Err(err).Msg("[ScenarioMinimizer.Minimize] Failed to execute operation case")
+				return false
+			}
+			executedOperationCases = append(executedOperationCases, executedOperationCase)
+		}
+		reproduced := executedOperationCases[len(executedOperationCases)-1].ResponseStatusCode == failingOperationCase.ResponseStatusCode
+		if reproduced {
+			minimizedOperationCases = executedOperationCases
+		}
+		return reproduced
+	}
+	prefix := testScenario.OperationCases[:failingIndex]
+	minimizedPrefix, executionCount := utils.DeltaDebugMinimize(prefix, reproduce, m.MaxExecutions)
+
+	// The failure may be flaky, or its reproduction may depend on states changed by the original scenario.
+	// In such case, or if the budget runs out before any reduction, the original operation cases are reported.
+	if minimizedOperationCases == nil || len(minimizedOperationCases) != len(minimizedPrefix)+1 {
+		minimizedOperationCases = make([]*casemanager.OperationCase, 0, failingIndex+1)
+		for _, operationCase := range testScenario.OperationCases[:failingIndex+1] {
+			minimizedOperationCases = append(minimizedOperationCases, operationCase.Copy())
+		}
+	}
+	failure := &MinimizedFailure{
+		TestScenarioUUID: testScenario.UUID,
+		APIMethod:        failingOperationCase.APIMethod,
+		StatusCode:       failingOperationCase.ResponseStatusCode,
+		OriginalLength:   failingIndex + 1,
+		ExecutionCount:   executionCount,
+		OperationCases:   minimizedOperationCases,
+	}
+	m.Failures = append(m.Failures, failure)
+	log.Info().Msgf("[ScenarioMinimizer.Minimize] Minimized failure %d of %s %s from %d to %d operation cases, with %d executions", failure.StatusCode, failure.APIMethod.Method, failure.APIMethod.Endpoint, failure.OriginalLength, len(failure.OperationCases), executionCount)
+	return failure, nil
+}
+
+// getMinimizedFailureKey returns the key of the failure of an operation case, consisting of the API method and the status code.
+func getMinimizedFailureKey(operationCase *casemanager.OperationCase) string {
+	return fmt.Sprintf("%s %s %d", operationCase.APIMethod.Method, operationCase.APIMethod.Endpoint, operationCase.ResponseStatusCode)
+}
@@ -0,0 +1,277 @@
+package feedback
+
+import (
+	"cmp"
+	"fmt"
+	"maps"
+	"resttracefuzzer/pkg/feedback/trace"
+	"resttracefuzzer/pkg/static"
+	"resttracefuzzer/pkg/utils"
+	"slices"
+	"strings"
+)
+
+// SchemaDriftType represents the type of a drift between the internal service doc and APIs observed in traces.
+type SchemaDriftType string
+
+const (
+	// SchemaDriftTypeUndocumentedService means a service serves APIs in traces, but none of its APIs is documented.
+	SchemaDriftTypeUndocumentedService SchemaDriftType = "undocumentedService"
+
+	// SchemaDriftTypeRenamedPathParam means an observed HTTP route matches a documented one except for names of path parameters,
+	// e.g., `/users/{userId}` is observed while `/users/{id}` is documented.
+	SchemaDriftTypeRenamedPathParam SchemaDriftType = "renamedPathParam"
+
+	// SchemaDriftTypeRenamedAPI means an undocumented API is observed, and a similar documented API of the service is not observed,
+	// i.e., the API is likely renamed.
+	SchemaDriftTypeRenamedAPI SchemaDriftType = "renamedAPI"
+
+	// SchemaDriftTypeUndocumentedAPI means an API is observed, but not documented, and no documented API is similar to it.
+	SchemaDriftTypeUndocumentedAPI SchemaDriftType = "undocumentedAPI"
+
+	// SchemaDriftTypeUnobservedAPI means a documented API of a service observed in traces is never observed, i.e., the API is possibly removed.
+	// It may also be an API the fuzzer fails to reach.
+	SchemaDriftTypeUnobservedAPI SchemaDriftType = "unobservedAPI"
+)
+
+// renamedAPISimilarityThreshold is the minimum similarity between an undocumented observed API and an unobserved documented API,
+// for the former to be reported as a renaming of the latter.
+const renamedAPISimilarityThreshold = 0.6
+
+// SchemaDrift records a likely drift between the internal service doc and APIs observed in traces.
+// Stale internal service docs silently degrade the dataflow graph, as dependencies are inferred from documented parameters.
+type SchemaDrift struct {
+	// Typ is the type of the drift.
+	Typ SchemaDriftType `json:"type"`
+
+	// ServiceName is the name of the service, in standard case.
+	ServiceName string `json:"serviceName"`
+
+	// DocumentedAPI is the documented API in question, i.e., `{method} {path}` of HTTP APIs or the method name of gRPC APIs.
+	// It is empty if no documented API is involved.
+	DocumentedAPI string `json:"documentedAPI,omitempty"`
+
+	// ObservedAPI is the observed API in question, i.e., `{method} {route}` of HTTP APIs or the method name of gRPC APIs.
+	// It is empty if no observed API is involved.
+	ObservedAPI string `json:"observedAPI,omitempty"`
+
+	// ObservedHitCount is the number of server spans of the observed API.
+	ObservedHitCount int `json:"observedHitCount,omitempty"`
+
+	// Detail describes the evidence, e.g., the renamed path parameters.
+	Detail string `json:"detail"`
+}
+
+// schemaDriftAPI is an API to compare, either documented or observed.
+type schemaDriftAPI struct {
+	// typ is the type of the API.
+	typ static.SimpleAPIMethodType
+
+	// method is the HTTP method of HTTP APIs, or the method name of gRPC APIs.
+	method string
+
+	// path is the path template of HTTP APIs. It is empty for gRPC APIs.
+	path string
+
+	// hitCount is the number of server spans of observed APIs. It is 0 for documented APIs.
+	hitCount int
+}
+
+// DetectSchemaDrift compares internal service APIs observed in traces (see [trace.ServiceDocSynthesizer]) against the internal service doc,
+// and returns likely drifts, sorted by service name, type and APIs.
+// For each service, observed APIs are matched to documented ones of the same type by method and path template.
+// Unmatched observed APIs are paired with the most similar unmatched documented ones as renamings, if similar enough.
+// Services observed only as clients are not compared, as their APIs are not observed.
+func DetectSchemaDrift(APIManager *static.APIManager, observedAPIs []*trace.ObservedServiceAPI) []*SchemaDrift {
+	documentedServiceAPIs := make(map[string][]*schemaDriftAPI)
+	for serviceName, methodMap := range APIManager.ServiceAPIMap {
+		formattedServiceName := utils.FormatServiceName(serviceName)
+		for method := range methodMap {
+			if method.Typ != static.SimpleAPIMethodTypeHTTP && method.Typ != static.SimpleAPIMethodTypeGRPC {
+				continue
+			}
+			api := &schemaDriftAPI{typ: method.Typ, method: method.Method}
+			if method.Typ == static.SimpleAPIMethodTypeHTTP {
+				api.path = method.Endpoint
+			}
+			documentedServiceAPIs[formattedServiceName] = append(documentedServiceAPIs[formattedServiceName], api)
+		}
+	}
+	observedServiceAPIs := make(map[string][]*schemaDriftAPI)
+	for _, observedAPI := range observedAPIs {
+		api := &schemaDriftAPI{typ: static.SimpleAPIMethodType(observedAPI.APIType), method: observedAPI.Method, hitCount: observedAPI.HitCount}
+		if api.typ == static.SimpleAPIMethodTypeHTTP {
+			api.path = observedAPI.Path
+		}
+		formattedServiceName := utils.FormatServiceName(observedAPI.ServiceName)
+		observedServiceAPIs[formattedServiceName] = append(observedServiceAPIs[formattedServiceName], api)
+	}
+
+	drifts := make([]*SchemaDrift, 0)
+	for _, serviceName := range slices.Sorted(maps.Keys(observedServiceAPIs)) {
+		drifts = append(drifts, detectServiceSchemaDrift(serviceName, documentedServiceAPIs[serviceName], observedServiceAPIs[serviceName])...)
+	}
+	slices.SortStableFunc(drifts, func(a, b *SchemaDrift) int {
+		return cmp.Or(
+			cmp.Compare(a.ServiceName, b.ServiceName),
+			cmp.Compare(a.Typ, b.Typ),
+			cmp.Compare(a.DocumentedAPI, b.DocumentedAPI),
+			cmp.Compare(a.ObservedAPI, b.ObservedAPI),
+		)
+	})
+	return drifts
+}
+
+// detectServiceSchemaDrift compares observed APIs of a service against its documented APIs.
+func detectServiceSchemaDrift(serviceName string, documentedAPIs, observedAPIs []*schemaDriftAPI) []*SchemaDrift {
+	drifts := make([]*SchemaDrift, 0)
+	if len(documentedAPIs) == 0 {
+		hitCount := 0
+		for _, api := range observedAPIs {
+			hitCount += api.hitCount
+		}
+		return append(drifts, &SchemaDrift{
+			Typ:              SchemaDriftTypeUndocumentedService,
+			ServiceName:      serviceName,
+			ObservedHitCount: hitCount,
+			Detail:           fmt.Sprintf("%d API(s) observed in traces, but the service is not in the internal service doc", len(observedAPIs)),
+		})
+	}
+	slices.SortFunc(documentedAPIs, compareSchemaDriftAPIs)
+	slices.SortFunc(observedAPIs, compareSchemaDriftAPIs)
+
+	// Match observed APIs exactly, or modulo names of path parameters.
+	matchedDocumentedAPIs := make(map[*schemaDriftAPI]struct{})
+	unmatchedObservedAPIs := make([]*schemaDriftAPI, 0)
+	for _, observedAPI := range observedAPIs {
+		var matchedAPI *schemaDriftAPI
+		for _, documentedAPI := range documentedAPIs {
+			if documentedAPI.typ != observedAPI.typ || documentedAPI.method != observedAPI.method {
+				continue
+			}
+			if documentedAPI.path == observedAPI.path {
+				matchedAPI = documentedAPI
+				break
+			}
+			if matchedAPI == nil && normalizeRouteTemplate(documentedAPI.path) == normalizeRouteTemplate(observedAPI.path) {
+				matchedAPI = documentedAPI
+			}
+		}
+		if matchedAPI == nil {
+			unmatchedObservedAPIs = append(unmatchedObservedAPIs, observedAPI)
+			continue
+		}
+		matchedDocumentedAPIs[matchedAPI] = struct{}{}
+		if !slices.Equal(getRouteParamNames(matchedAPI.path), getRouteParamNames(observedAPI.path)) {
+			drifts = append(drifts, &SchemaDrift{
+				Typ:              SchemaDriftTypeRenamedPathParam,
+				ServiceName:      serviceName,
+				DocumentedAPI:    matchedAPI.String(),
+				ObservedAPI:      observedAPI.String(),
+				ObservedHitCount: observedAPI.hitCount,
+				Detail:           fmt.Sprintf("path parameters %v are documented, but %v are observed", getRouteParamNames(matchedAPI.path), getRouteParamNames(observedAPI.path)),
+			})
+		}
+	}
+
+	// Pair unmatched observed APIs with the most similar unmatched documented APIs.
+	similarityCalculator := utils.NewLevenshteinSimilarityCalculator()
+	for _, observedAPI := range unmatchedObservedAPIs {
+		var renamedAPI *schemaDriftAPI
+		bestSimilarity := renamedAPISimilarityThreshold
+		for _, documentedAPI := range documentedAPIs {
+			if _, matched := matchedDocumentedAPIs[documentedAPI]; matched || documentedAPI.typ != observedAPI.typ {
+				continue
+			}
+			var similarity float64
+			if observedAPI.typ == static.SimpleAPIMethodTypeHTTP {
+				if documentedAPI.method != observedAPI.method {
+					continue
+				}
+				similarity = similarityCalculator.CalculateSimilarity(normalizeRouteTemplate(documentedAPI.path), normalizeRouteTemplate(observedAPI.path))
+			} else {
+				similarity = similarityCalculator.CalculateSimilarity(strings.ToLower(documentedAPI.method), strings.ToLower(observedAPI.method))
+			}
+			if similarity >= bestSimilarity {
+				renamedAPI = documentedAPI
+				bestSimilarity = similarity
+			}
+		}
+		if renamedAPI == nil {
+			drifts = append(drifts, &SchemaDrift{
+				Typ:              SchemaDriftTypeUndocumentedAPI,
+				ServiceName:      serviceName,
+				ObservedAPI:      observedAPI.String(),
+				ObservedHitCount: observedAPI.hitCount,
+				Detail:           "observed in traces, but not documented",
+			})
+			continue
+		}
+		matchedDocumentedAPIs[renamedAPI] = struct{}{}
+		drifts = append(drifts, &SchemaDrift{
+			Typ:              SchemaDriftTypeRenamedAPI,
+			ServiceName:      serviceName,
+			DocumentedAPI:    renamedAPI.String(),
+			ObservedAPI:      observedAPI.String(),
+			ObservedHitCount: observedAPI.hitCount,
+			Detail:           fmt.Sprintf("the documented API is never observed, and is %.0f%% similar to the observed one", bestSimilarity*100),
+		})
+	}
+
+	for _, documentedAPI := range documentedAPIs {
+		if _, matched := matchedDocumentedAPIs[documentedAPI]; matched {
+			continue
+		}
+		drifts = append(drifts, &SchemaDrift{
+			Typ:           SchemaDriftTypeUnobservedAPI,
+			ServiceName:   serviceName,
+			DocumentedAPI: documentedAPI.String(),
+			Detail:        "documented, but never observed in traces, while other APIs of the service are observed",
+		})
+	}
+	return drifts
+}
+
+// String returns `{method} {path}` of HTTP APIs, or the method name of gRPC APIs.
+func (a *schemaDriftAPI) String() string {
+	if a.typ == static.SimpleAPIMethodTypeHTTP {
+		return a.method + " " + a.path
+	}
+	return a.method
+}
+
+// compareSchemaDriftAPIs compares APIs by type, method and path.
+func compareSchemaDriftAPIs(a, b *schemaDriftAPI) int {
+	return cmp.Or(
+		cmp.Compare(a.typ, b.typ),
+		cmp.Compare(a.method, b.method),
+		cmp.Compare(a.path, b.path),
+	)
+}
+
+// isRouteParamSegment checks whether the segment of a route template is a path parameter, i.e., `{id}` or `:id` (used by some frameworks, e.g., Express and Gin).
+func isRouteParamSegment(segment string) bool {
+	return utils.IfPathSegmentIsPathParam(segment) || len(segment) > 1 && segment[0] == ':'
+}
+
+// normalizeRouteTemplate replaces path parameters of the route template with `{}`, so that templates differing only in parameter names are equal.
+func normalizeRouteTemplate(path string) string {
+	segments := utils.SplitEndpointPath(path)
+	for i, segment := range segments {
+		if isRouteParamSegment(segment) {
+			segments[i] = "{}"
+		}
+	}
+	return "/" + strings.Join(segments, "/")
+}
+
+// getRouteParamNames returns names of path parameters of the route template, in order.
+func getRouteParamNames(path string) []string {
+	names := make([]string, 0)
+	for _, segment := range utils.SplitEndpointPath(path) {
+		if isRouteParamSegment(segment) {
+			names = append(names, strings.Trim(segment, "{}:"))
+		}
+	}
+	return names
+}
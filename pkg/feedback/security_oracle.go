@@ -0,0 +1,210 @@
+package feedback
+
+import (
+	"fmt"
+	"regexp"
+	"resttracefuzzer/pkg/casemanager"
+	"resttracefuzzer/pkg/static"
+	"resttracefuzzer/pkg/strategy"
+	"strings"
+
+	"github.com/bytedance/sonic"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+)
+
+// SecurityFindingType represents the type of a security finding.
+type SecurityFindingType string
+
+const (
+	// SecurityFindingTypeErrorSignature means the response to a request with security payloads contains an error signature,
+	// e.g., an SQL error message, a stack trace or content of `/etc/passwd`.
+	SecurityFindingTypeErrorSignature SecurityFindingType = "errorSignature"
+
+	// SecurityFindingTypeReflectedPayload means a XSS payload in the request is reflected verbatim in the response.
+	SecurityFindingTypeReflectedPayload SecurityFindingType = "reflectedPayload"
+
+	// SecurityFindingTypeServerError means the server responds 5xx to a request with security payloads.
+	SecurityFindingTypeServerError SecurityFindingType = "serverError"
+)
+
+// maxSecurityFindingPayloadLength is the maximum length of payload recorded in a finding, as some payloads (e.g., oversized inputs) are very long.
+const maxSecurityFindingPayloadLength = 128
+
+// securityErrorSignature is a pattern of error messages in response, which indicates a possible vulnerability.
+type securityErrorSignature struct {
+	// name is the readable name of the signature.
+	name string
+
+	// pattern is the regular expression to match in response body.
+	pattern *regexp.Regexp
+}
+
+// securityErrorSignatures are the built-in error signatures.
+var securityErrorSignatures = []securityErrorSignature{
+	{name: "SQL error", pattern: regexp.MustCompile(`(?i)(you have an error in your sql syntax|sqlstate\[|unclosed quotation mark|unterminated quoted string|ORA-\d{5}|sqlite3?\.|PG::SyntaxError|syntax error at or near)`)},
+	{name: "stack trace", pattern: regexp.MustCompile(`(Traceback \(most recent call last\)|goroutine \d+ \[|Exception in thread|\tat [\w$.]+\(\w+\.java:\d+\))`)},
+	{name: "passwd file content", pattern: regexp.MustCompile(`root:[x*]?:0:0:`)},
+	{name: "win.ini content", pattern: regexp.MustCompile(`(?i)\[(fonts|extensions)\]`)},
+	{name: "command output", pattern: regexp.MustCompile(`uid=\d+\(\w+\) gid=\d+`)},
+	{name: "shell error", pattern: regexp.MustCompile(`(sh: \d+: |/bin/(ba)?sh: |command not found)`)},
+}
+
+// SecurityFinding records a suspicious response to a request carrying security payloads.
+type SecurityFinding struct {
+	// Typ is the type of the finding.
+	Typ SecurityFindingType `json:"type"`
+
+	// Category is the category of the security payload.
+	Category strategy.SecurityPayloadCategory `json:"category"`
+
+	// TestScenarioUUID is the UUID of the test scenario in which the finding is found.
+	TestScenarioUUID uuid.UUID `json:"testScenarioUUID"`
+
+	// APIMethod is the API method which receives the payload.
+	APIMethod static.SimpleAPIMethod `json:"APIMethod"`
+
+	// Payload is the security payload in the request, truncated if too long.
+	Payload string `json:"payload"`
+
+	// Evidence describes what is found in the response, e.g., the name of matched error signature.
+	Evidence string `json:"evidence"`
+
+	// StatusCode is the response status code.
+	StatusCode int `json:"statusCode"`
+}
+
+// SecurityOracle detects possible vulnerabilities from responses to requests carrying security payloads (see [strategy.SecurityPayloadDict]).
+// It uses the following heuristics:
+//   - Error signatures in response, e.g., SQL errors, stack traces, content of system files and command output.
+//   - XSS payloads reflected verbatim in response.
+//   - 5xx responses.
+//
+// Only requests containing payloads in the dictionary are checked, and findings of the same API method, type, category and evidence are reported once.
+type SecurityOracle struct {
+	// SecurityPayloadDict is the dictionary of security payloads used to generate requests.
+	SecurityPayloadDict *strategy.SecurityPayloadDict
+
+	// Findings are all findings found so far.
+	Findings []*SecurityFinding
+
+	// reportedFindingKeys are keys of reported findings, for deduplication.
+	reportedFindingKeys map[string]struct{}
+}
+
+// NewSecurityOracle creates a new SecurityOracle.
+func NewSecurityOracle(securityPayloadDict *strategy.SecurityPayloadDict) *SecurityOracle {
+	return &SecurityOracle{
+		SecurityPayloadDict: securityPayloadDict,
+		Findings:            make([]*SecurityFinding, 0),
+		reportedFindingKeys: make(map[string]struct{}),
+	}
+}
+
+// EvaluateScenario implements [ScenarioEvaluator], by checking each executed operation case of the scenario.
+func (o *SecurityOracle) EvaluateScenario(testScenario *casemanager.TestScenario, operationResults []*OperationResult) {
+	for _, result := range operationResults {
+		o.CheckOperationCase(testScenario.UUID, result.OperationCase)
+	}
+}
+
+// CheckOperationCase checks the executed operation case, and returns the new findings.
+// The findings are also recorded in the oracle.
+func (o *SecurityOracle) CheckOperationCase(testScenarioUUID uuid.UUID, operationCase *casemanager.OperationCase) []*SecurityFinding {
+	findings := make([]*SecurityFinding, 0)
+	if operationCase == nil || operationCase.ResponseStatusCode == 0 {
+		return findings
+	}
+	payloads := make(map[string]strategy.SecurityPayloadCategory)
+	for _, value := range collectRequestStrings(operationCase) {
+		for payload, category := range o.SecurityPayloadDict.FindPayloads(value) {
+			payloads[payload] = category
+		}
+	}
+	if len(payloads) == 0 {
+		return findings
+	}
+
+	responseBody := string(operationCase.ResponseBody)
+	for payload, category := range payloads {
+		newFinding := func(typ SecurityFindingType, evidence string) {
+			key := fmt.Sprintf("%s %s|%s|%s|%s", operationCase.APIMethod.Method, operationCase.APIMethod.Endpoint, typ, category, evidence)
+			if _, reported := o.reportedFindingKeys[key]; reported {
+				return
+			}
+			o.reportedFindingKeys[key] = struct{}{}
+			finding := &SecurityFinding{
+				Typ:              typ,
+				Category:         category,
+				TestScenarioUUID: testScenarioUUID,
+				APIMethod:        operationCase.APIMethod,
+				Payload:          truncatePayload(payload),
+				Evidence:         evidence,
+				StatusCode:       operationCase.ResponseStatusCode,
+			}
+			log.Warn().Msgf("[SecurityOracle.CheckOperationCase] Security finding %s (%s) on %s %s: %s", typ, category, operationCase.APIMethod.Method, operationCase.APIMethod.Endpoint, evidence)
+			findings = append(findings, finding)
+		}
+
+		for _, signature := range securityErrorSignatures {
+			// the signature must not come from the payload itself, e.g., a payload echoed in an error message
+			if signature.pattern.MatchString(responseBody) && !signature.pattern.MatchString(payload) {
+				newFinding(SecurityFindingTypeErrorSignature, signature.name)
+			}
+		}
+		if category == strategy.SecurityPayloadCategoryXSS && strings.Contains(responseBody, payload) {
+			newFinding(SecurityFindingTypeReflectedPayload, "payload reflected in response body")
+		}
+		if operationCase.ResponseStatusCode >= 500 {
+			newFinding(SecurityFindingTypeServerError, fmt.Sprintf("status code %d", operationCase.ResponseStatusCode))
+		}
+	}
+	o.Findings = append(o.Findings, findings...)
+	return findings
+}
+
+// collectRequestStrings collects string values sent in the request of the operation case,
+// i.e., path parameters, query parameters, headers and string values in JSON body.
+func collectRequestStrings(operationCase *casemanager.OperationCase) []string {
+	res := make([]string, 0)
+	for _, params := range []map[string]string{operationCase.RequestPathParams, operationCase.RequestQueryParams, operationCase.RequestHeaders} {
+		for _, value := range params {
+			res = append(res, value)
+		}
+	}
+	if len(operationCase.RequestBody) > 0 {
+		var body any
+		if err := sonic.Unmarshal(operationCase.RequestBody, &body); err != nil {
+			// not a JSON body, use it as is
+			res = append(res, string(operationCase.RequestBody))
+		} else {
+			res = appendJSONStrings(res, body)
+		}
+	}
+	return res
+}
+
+// appendJSONStrings appends all string values (including nested ones) in the unmarshalled JSON value to res.
+func appendJSONStrings(res []string, value any) []string {
+	switch v := value.(type) {
+	case string:
+		res = append(res, v)
+	case []any:
+		for _, item := range v {
+			res = appendJSONStrings(res, item)
+		}
+	case map[string]any:
+		for _, item := range v {
+			res = appendJSONStrings(res, item)
+		}
+	}
+	return res
+}
+
+// truncatePayload truncates the payload to [maxSecurityFindingPayloadLength].
+func truncatePayload(payload string) string {
+	if len(payload) <= maxSecurityFindingPayloadLength {
+		return payload
+	}
+	return fmt.Sprintf("%s...(%d bytes)", payload[:maxSecurityFindingPayloadLength], len(payload))
+}
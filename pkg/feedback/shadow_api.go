@@ -0,0 +1,108 @@
+package feedback
+
+import (
+	"cmp"
+	"net/url"
+	"resttracefuzzer/pkg/feedback/trace"
+	"resttracefuzzer/pkg/static"
+	"resttracefuzzer/pkg/utils"
+	"slices"
+	"strings"
+)
+
+// ShadowAPI is an API served in traces, but not declared in any API doc,
+// i.e., the API doc, docs of additional targets and the internal service doc.
+// Shadow APIs are likely forgotten or unintended attack surface, e.g., debug or admin routes.
+type ShadowAPI struct {
+	*trace.ObservedServiceAPI
+
+	// External tells whether the API is reached from outside the system, i.e., some of its server spans are at the entry of traces
+	// (see [trace.ObservedServiceAPI.EntryHitCount]), so it is likely exposed by a gateway.
+	// Otherwise, it is only called by other services.
+	External bool `json:"external"`
+}
+
+// DetectShadowAPIs compares APIs observed in server spans of traces (see [trace.ServiceDocSynthesizer]) against all documented APIs,
+// and returns those not documented, sorted by external first, then by service name, method and path.
+// Unlike [DetectSchemaDrift], an observed API is documented if any doc declares it, no matter which service serves it.
+// HTTP APIs are matched by method and route template (modulo names of path parameters),
+// where paths of serverBaseURLs (e.g., `/api/v1`) are stripped from observed routes, as documented paths are relative to them.
+// gRPC APIs are matched by method name.
+func DetectShadowAPIs(APIManager *static.APIManager, observedAPIs []*trace.ObservedServiceAPI, serverBaseURLs []string) []*ShadowAPI {
+	documentedMethods := make([]static.SimpleAPIMethod, 0, len(APIManager.APIMap)+len(APIManager.FilteredOperations))
+	for method := range APIManager.APIMap {
+		documentedMethods = append(documentedMethods, method)
+	}
+	// Operations excluded from fuzzing are still declared.
+	documentedMethods = append(documentedMethods, APIManager.FilteredOperations...)
+	for _, methodMap := range APIManager.ServiceAPIMap {
+		for method := range methodMap {
+			documentedMethods = append(documentedMethods, method)
+		}
+	}
+	// documentedHTTPRoutes records `{method} {normalized route template}` of documented HTTP APIs, see [normalizeRouteTemplate].
+	documentedHTTPRoutes := make(map[string]struct{})
+	// documentedGRPCMethods records method names of documented gRPC APIs.
+	documentedGRPCMethods := make(map[string]struct{})
+	for _, method := range documentedMethods {
+		switch method.Typ {
+		case static.SimpleAPIMethodTypeHTTP:
+			documentedHTTPRoutes[method.Method+" "+normalizeRouteTemplate(method.Endpoint)] = struct{}{}
+		case static.SimpleAPIMethodTypeGRPC:
+			documentedGRPCMethods[method.Method] = struct{}{}
+		}
+	}
+	basePaths := make([]string, 0, len(serverBaseURLs))
+	for _, serverBaseURL := range serverBaseURLs {
+		parsedURL, err := url.Parse(serverBaseURL)
+		if err != nil {
+			continue
+		}
+		if basePath := strings.TrimSuffix(parsedURL.Path, "/"); basePath != "" && !slices.Contains(basePaths, basePath) {
+			basePaths = append(basePaths, basePath)
+		}
+	}
+
+	shadowAPIs := make([]*ShadowAPI, 0)
+	for _, observedAPI := range observedAPIs {
+		documented := false
+		switch static.SimpleAPIMethodType(observedAPI.APIType) {
+		case static.SimpleAPIMethodTypeHTTP:
+			routes := []string{observedAPI.Path}
+			for _, basePath := range basePaths {
+				if route, found := strings.CutPrefix(observedAPI.Path, basePath); found && strings.HasPrefix(route, "/") {
+					routes = append(routes, route)
+				}
+			}
+			documented = slices.ContainsFunc(routes, func(route string) bool {
+				_, exists := documentedHTTPRoutes[observedAPI.Method+" "+normalizeRouteTemplate(route)]
+				return exists
+			})
+		case static.SimpleAPIMethodTypeGRPC:
+			_, documented = documentedGRPCMethods[observedAPI.Method]
+		default:
+			continue
+		}
+		if documented {
+			continue
+		}
+		shadowAPIs = append(shadowAPIs, &ShadowAPI{
+			ObservedServiceAPI: observedAPI,
+			External:           observedAPI.EntryHitCount > 0,
+		})
+	}
+	slices.SortStableFunc(shadowAPIs, func(a, b *ShadowAPI) int {
+		if a.External != b.External {
+			if a.External {
+				return -1
+			}
+			return 1
+		}
+		return cmp.Or(
+			cmp.Compare(utils.FormatServiceName(a.ServiceName), utils.FormatServiceName(b.ServiceName)),
+			cmp.Compare(a.Method, b.Method),
+			cmp.Compare(a.Path, b.Path),
+		)
+	})
+	return shadowAPIs
+}
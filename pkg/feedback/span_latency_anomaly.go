@@ -0,0 +1,177 @@
+package feedback
+
+import (
+	"resttracefuzzer/pkg/casemanager"
+	"resttracefuzzer/pkg/static"
+	"resttracefuzzer/pkg/utils"
+	"slices"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	// DefaultSpanLatencyAnomalyPercentile is the default percentile of span durations anomalies are detected against.
+	DefaultSpanLatencyAnomalyPercentile = 99
+
+	// DefaultSpanLatencyAnomalyMinSamples is the default number of samples of a span operation required before detecting anomalies.
+	DefaultSpanLatencyAnomalyMinSamples = 30
+
+	// maxSpanLatencySamples is the maximum number of duration samples kept for each span operation, to bound memory usage.
+	// Later samples are not kept, as the distribution is considered stable then.
+	maxSpanLatencySamples = 10000
+
+	// maxSpanLatencyAnomaliesPerOperation is the maximum number of anomalies recorded for each span operation.
+	maxSpanLatencyAnomaliesPerOperation = 3
+)
+
+// SpanOperation is an operation of an internal service, i.e., spans of the same service and name.
+type SpanOperation struct {
+	// ServiceName is the name of the service.
+	ServiceName string `json:"serviceName"`
+
+	// OperationName is the name of the operation (span name).
+	OperationName string `json:"operationName"`
+}
+
+// SpanLatencyAnomaly records a request whose internal span is abnormally slow, compared to earlier spans of the same operation.
+type SpanLatencyAnomaly struct {
+	// TestScenarioUUID is the UUID of the test scenario in which the anomaly is found.
+	TestScenarioUUID uuid.UUID `json:"testScenarioUUID"`
+
+	// APIMethod is the API method of the request.
+	APIMethod static.SimpleAPIMethod `json:"APIMethod"`
+
+	// SpanOperation is the operation of the slow span.
+	SpanOperation
+
+	// TraceID is the ID of the trace of the request.
+	TraceID string `json:"traceID"`
+
+	// SpanID is the ID of the slow span.
+	SpanID string `json:"spanID"`
+
+	// Duration is the duration of the slow span, in milliseconds.
+	Duration float64 `json:"durationMs"`
+
+	// Threshold is the duration threshold exceeded, i.e., the percentile duration times the multiplier, in milliseconds.
+	Threshold float64 `json:"thresholdMs"`
+
+	// PercentileDuration is the percentile of durations of earlier spans of the operation, in milliseconds.
+	PercentileDuration float64 `json:"percentileDurationMs"`
+
+	// SampleCount is the number of earlier spans of the operation.
+	SampleCount int `json:"sampleCount"`
+
+	// OperationCases are the operation cases of the test scenario, up to and including the one triggering the anomaly, for reproduction.
+	OperationCases []*casemanager.OperationCase `json:"-"`
+}
+
+// SpanLatencyAnomalyDetector tracks duration distributions of internal spans during fuzzing,
+// and flags requests whose spans exceed the percentile (e.g., p99) of earlier durations times the multiplier (e.g., 3) as performance anomalies.
+// Unlike [LatencySLOOracle], it needs no SLO, and locates the slow internal operation.
+type SpanLatencyAnomalyDetector struct {
+	// Percentile is the percentile of earlier durations, in (0, 100].
+	Percentile float64
+
+	// Multiplier is the multiplier of the percentile duration, above which a span is anomalous.
+	// Detection is disabled if it is not positive.
+	Multiplier float64
+
+	// MinSamples is the number of earlier spans of an operation required before detecting anomalies of it.
+	MinSamples int
+
+	// Anomalies are all anomalies found so far.
+	Anomalies []*SpanLatencyAnomaly
+
+	// sortedDurations maps from span operations to durations of their spans in ascending order, in milliseconds.
+	sortedDurations map[SpanOperation][]float64
+
+	// anomalyCounts maps from span operations to the number of their anomalies recorded.
+	anomalyCounts map[SpanOperation]int
+}
+
+// NewSpanLatencyAnomalyDetector creates a new SpanLatencyAnomalyDetector.
+// Detection is disabled if multiplier is not positive.
+// If percentile is not in (0, 100], or minSamples is not positive, defaults ([DefaultSpanLatencyAnomalyPercentile] and [DefaultSpanLatencyAnomalyMinSamples]) are used.
+func NewSpanLatencyAnomalyDetector(percentile float64, multiplier float64, minSamples int) *SpanLatencyAnomalyDetector {
+	if percentile <= 0 || percentile > 100 {
+		log.Warn().Msgf("[NewSpanLatencyAnomalyDetector] Invalid span latency anomaly percentile %v, use %d instead", percentile, DefaultSpanLatencyAnomalyPercentile)
+		percentile = DefaultSpanLatencyAnomalyPercentile
+	}
+	if minSamples <= 0 {
+		log.Warn().Msgf("[NewSpanLatencyAnomalyDetector] Invalid span latency anomaly min samples %d, use %d instead", minSamples, DefaultSpanLatencyAnomalyMinSamples)
+		minSamples = DefaultSpanLatencyAnomalyMinSamples
+	}
+	return &SpanLatencyAnomalyDetector{
+		Percentile:      percentile,
+		Multiplier:      multiplier,
+		MinSamples:      minSamples,
+		Anomalies:       make([]*SpanLatencyAnomaly, 0),
+		sortedDurations: make(map[SpanOperation][]float64),
+		anomalyCounts:   make(map[SpanOperation]int),
+	}
+}
+
+// EvaluateScenario implements [ScenarioEvaluator], checking spans of traces of executed operation cases against earlier durations, and recording their durations.
+// Operation cases without traces are ignored.
+func (d *SpanLatencyAnomalyDetector) EvaluateScenario(testScenario *casemanager.TestScenario, operationResults []*OperationResult) {
+	if d.Multiplier <= 0 {
+		return
+	}
+	for i, result := range operationResults {
+		if result.Trace == nil {
+			continue
+		}
+		for _, span := range result.Trace.SpanMap {
+			if span == nil {
+				continue
+			}
+			spanOperation := SpanOperation{
+				ServiceName:   span.ServiceName,
+				OperationName: span.OperationName,
+			}
+			// Span durations are in microseconds.
+			duration := float64(span.Duration) / 1000
+			sortedDurations := d.sortedDurations[spanOperation]
+			if len(sortedDurations) >= d.MinSamples && d.anomalyCounts[spanOperation] < maxSpanLatencyAnomaliesPerOperation {
+				percentileDuration := utils.GetPercentile(sortedDurations, d.Percentile)
+				threshold := percentileDuration * d.Multiplier
+				if duration > threshold {
+					d.recordAnomaly(&SpanLatencyAnomaly{
+						TestScenarioUUID:   testScenario.UUID,
+						APIMethod:          result.OperationCase.APIMethod,
+						SpanOperation:      spanOperation,
+						TraceID:            result.Trace.TraceID,
+						SpanID:             span.SpanID,
+						Duration:           duration,
+						Threshold:          threshold,
+						PercentileDuration: percentileDuration,
+						SampleCount:        len(sortedDurations),
+						OperationCases:     copyOperationCasesOfResults(operationResults[:i+1]),
+					})
+				}
+			}
+			if len(sortedDurations) < maxSpanLatencySamples {
+				index, _ := slices.BinarySearch(sortedDurations, duration)
+				d.sortedDurations[spanOperation] = slices.Insert(sortedDurations, index, duration)
+			}
+		}
+	}
+}
+
+// recordAnomaly records the anomaly.
+func (d *SpanLatencyAnomalyDetector) recordAnomaly(anomaly *SpanLatencyAnomaly) {
+	d.anomalyCounts[anomaly.SpanOperation]++
+	d.Anomalies = append(d.Anomalies, anomaly)
+	log.Warn().Msgf("[SpanLatencyAnomalyDetector.recordAnomaly] Span %s of %s (service %s) takes %.2f ms, exceeding threshold %.2f ms (p%v %.2f ms x %v), trace ID: %s, test scenario UUID: %s", anomaly.OperationName, anomaly.APIMethod.Endpoint, anomaly.ServiceName, anomaly.Duration, anomaly.Threshold, d.Percentile, anomaly.PercentileDuration, d.Multiplier, anomaly.TraceID, anomaly.TestScenarioUUID.String())
+}
+
+// copyOperationCasesOfResults returns copies of the operation cases of the results, in order.
+func copyOperationCasesOfResults(operationResults []*OperationResult) []*casemanager.OperationCase {
+	operationCases := make([]*casemanager.OperationCase, 0, len(operationResults))
+	for _, result := range operationResults {
+		operationCases = append(operationCases, result.OperationCase.Copy())
+	}
+	return operationCases
+}
@@ -0,0 +1,192 @@
+package feedback
+
+import (
+	"cmp"
+	"fmt"
+	"maps"
+	"math/rand/v2"
+	"resttracefuzzer/pkg/casemanager"
+	"resttracefuzzer/pkg/static"
+	"resttracefuzzer/pkg/utils"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/bytedance/sonic"
+	"github.com/rs/zerolog/log"
+)
+
+// StatusCodeTargetAllAPIMethods is the wildcard of API methods in status code targets, e.g., `* 4XX` for all documented 4xx responses.
+const StatusCodeTargetAllAPIMethods = "*"
+
+// StatusCodeTarget is an (API method, status code) pair the fuzzing campaign must cover.
+type StatusCodeTarget struct {
+	// APIMethod is the API method of the target.
+	APIMethod static.SimpleAPIMethod `json:"APIMethod"`
+
+	// StatusCode is the status code of the target.
+	StatusCode int `json:"statusCode"`
+}
+
+// StatusCodeTargetGap is the gap between status code targets and the status codes covered in fuzzing.
+type StatusCodeTargetGap struct {
+	// TargetCount is the number of status code targets.
+	TargetCount int `json:"targetCount"`
+
+	// CoveredCount is the number of covered status code targets.
+	CoveredCount int `json:"coveredCount"`
+
+	// UncoveredTargets are the status code targets not covered, sorted by API method and status code.
+	UncoveredTargets []StatusCodeTarget `json:"uncoveredTargets"`
+}
+
+// StatusCodeTargetTracker tracks the coverage of status code targets, and biases generation toward uncovered ones.
+// Operation cases of API methods with uncovered error (4xx and 5xx) targets are populated in negative mode by chance,
+// see [casemanager.NegativeModeSelector].
+type StatusCodeTargetTracker struct {
+	// TargetHitCount maps from status code targets to their hit counts.
+	TargetHitCount map[StatusCodeTarget]int
+
+	// NegativeModePercent is the percentage (0-100) of operation cases populated in negative mode,
+	// among those of API methods with uncovered error targets.
+	NegativeModePercent int
+
+	// Rand is the random number generator for negative mode selection, which is [utils.SharedRand] by default.
+	Rand *rand.Rand
+}
+
+// NewStatusCodeTargetTracker creates a new StatusCodeTargetTracker.
+// targetsStr is a stringified JSON list of targets in the format of `METHOD path status`, which can be empty, e.g.,
+// `["POST /users 409", "GET /users/{id} 4XX", "* 4XX"]`, where:
+//   - the API method can be [StatusCodeTargetAllAPIMethods] for all API methods;
+//   - the status can be a status code, or a status code class (e.g., `4XX`) for all status codes of the class documented in the API doc.
+//
+// If negativeModePercent is not in [0, 100], 0 is used, i.e., generation is not biased.
+// It returns an error if targetsStr is invalid, or refers to an undefined API method.
+func NewStatusCodeTargetTracker(APIManager *static.APIManager, targetsStr string, negativeModePercent int) (*StatusCodeTargetTracker, error) {
+	if negativeModePercent < 0 || negativeModePercent > 100 {
+		log.Warn().Msgf("[NewStatusCodeTargetTracker] Invalid negative mode percent %d, use 0 instead", negativeModePercent)
+		negativeModePercent = 0
+	}
+	targetHitCount := make(map[StatusCodeTarget]int)
+	if targetsStr != "" {
+		targetKeys := make([]string, 0)
+		err := sonic.UnmarshalString(targetsStr, &targetKeys)
+		if err != nil {
+			log.Err(err).Msg("[NewStatusCodeTargetTracker] Failed to unmarshal status code targets")
+			return nil, err
+		}
+		for _, key := range targetKeys {
+			targets, err := parseStatusCodeTarget(APIManager, key)
+			if err != nil {
+				return nil, err
+			}
+			for _, target := range targets {
+				targetHitCount[target] = 0
+			}
+		}
+	}
+	return &StatusCodeTargetTracker{
+		TargetHitCount:      targetHitCount,
+		NegativeModePercent: negativeModePercent,
+		Rand:                utils.SharedRand,
+	}, nil
+}
+
+// EvaluateScenario implements [ScenarioEvaluator], recording the status codes of executed operation cases.
+func (t *StatusCodeTargetTracker) EvaluateScenario(testScenario *casemanager.TestScenario, operationResults []*OperationResult) {
+	for _, result := range operationResults {
+		target := StatusCodeTarget{
+			APIMethod:  result.OperationCase.APIMethod,
+			StatusCode: result.OperationCase.ResponseStatusCode,
+		}
+		if _, exist := t.TargetHitCount[target]; !exist {
+			continue
+		}
+		if t.TargetHitCount[target] == 0 {
+			log.Info().Msgf("[StatusCodeTargetTracker.EvaluateScenario] Status code target %s %s %d is covered, test scenario UUID: %s", target.APIMethod.Method, target.APIMethod.Endpoint, target.StatusCode, testScenario.UUID.String())
+		}
+		t.TargetHitCount[target]++
+	}
+}
+
+// ShouldUseNegativeMode implements [casemanager.NegativeModeSelector].
+// It returns true by chance of NegativeModePercent, if the API method has uncovered error (4xx and 5xx) targets.
+func (t *StatusCodeTargetTracker) ShouldUseNegativeMode(APIMethod static.SimpleAPIMethod) bool {
+	if t.NegativeModePercent <= 0 {
+		return false
+	}
+	for target, hitCount := range t.TargetHitCount {
+		if hitCount == 0 && target.APIMethod == APIMethod && target.StatusCode >= 400 {
+			return t.Rand.IntN(100) < t.NegativeModePercent
+		}
+	}
+	return false
+}
+
+// GetGap returns the gap between status code targets and the status codes covered.
+func (t *StatusCodeTargetTracker) GetGap() *StatusCodeTargetGap {
+	gap := &StatusCodeTargetGap{
+		TargetCount:      len(t.TargetHitCount),
+		UncoveredTargets: make([]StatusCodeTarget, 0),
+	}
+	for target, hitCount := range t.TargetHitCount {
+		if hitCount > 0 {
+			gap.CoveredCount++
+		} else {
+			gap.UncoveredTargets = append(gap.UncoveredTargets, target)
+		}
+	}
+	slices.SortFunc(gap.UncoveredTargets, func(a, b StatusCodeTarget) int {
+		return cmp.Or(static.CompareSimpleAPIMethod(a.APIMethod, b.APIMethod), cmp.Compare(a.StatusCode, b.StatusCode))
+	})
+	return gap
+}
+
+// parseStatusCodeTarget parses the status code target key `METHOD path status` into targets, see [NewStatusCodeTargetTracker].
+func parseStatusCodeTarget(APIManager *static.APIManager, key string) ([]StatusCodeTarget, error) {
+	key = strings.TrimSpace(key)
+	separatorIndex := strings.LastIndex(key, " ")
+	if separatorIndex < 0 {
+		return nil, fmt.Errorf("invalid status code target %q, expected `METHOD path status`", key)
+	}
+	methodKey, statusStr := strings.TrimSpace(key[:separatorIndex]), strings.ToUpper(key[separatorIndex+1:])
+
+	var methods []static.SimpleAPIMethod
+	if methodKey == StatusCodeTargetAllAPIMethods {
+		methods = slices.SortedFunc(maps.Keys(APIManager.APIMap), static.CompareSimpleAPIMethod)
+	} else {
+		method, err := findAPIMethodByKey(APIManager, methodKey)
+		if err != nil {
+			return nil, err
+		}
+		methods = []static.SimpleAPIMethod{method}
+	}
+
+	targets := make([]StatusCodeTarget, 0)
+	// A status code class, e.g., `4XX`, is expanded to documented status codes of the class.
+	if len(statusStr) == 3 && strings.HasSuffix(statusStr, "XX") && statusStr[0] >= '1' && statusStr[0] <= '5' {
+		statusCodeClass := int(statusStr[0]-'0') * 100
+		for _, method := range methods {
+			for fieldKey := range APIManager.APIMap[method].Responses.Map() {
+				statusCode, err := strconv.Atoi(fieldKey)
+				if err != nil || statusCode/100*100 != statusCodeClass {
+					continue
+				}
+				targets = append(targets, StatusCodeTarget{APIMethod: method, StatusCode: statusCode})
+			}
+		}
+		if len(targets) == 0 {
+			log.Warn().Msgf("[parseStatusCodeTarget] No documented %s response for status code target %q", statusStr, key)
+		}
+		return targets, nil
+	}
+	statusCode, err := strconv.Atoi(statusStr)
+	if err != nil || statusCode < 100 || statusCode > 599 {
+		return nil, fmt.Errorf("invalid status %q of status code target %q, expected a status code or a status code class like `4XX`", statusStr, key)
+	}
+	for _, method := range methods {
+		targets = append(targets, StatusCodeTarget{APIMethod: method, StatusCode: statusCode})
+	}
+	return targets, nil
+}
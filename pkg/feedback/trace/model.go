@@ -1,8 +1,10 @@
 package trace
 
 import (
+	"fmt"
 	"regexp"
 	"resttracefuzzer/pkg/utils"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
@@ -35,6 +37,19 @@ type SimplifiedTraceSpan struct {
 	Duration      int64               `json:"duration"`      // Duration of the span, in microseconds
 	AttributeMap        map[string]AttributeEntry `json:"attributeMap"`       // Attributes associated with the span, map from tag key to attribute entry
 	ServiceName   string              `json:"serviceName"`   // Name of the service
+	StatusCode    SpanStatusCodeType  `json:"statusCode"`    // Status code of the span
+	StatusMessage string              `json:"statusMessage"` // Description of the status, usually set for errors only
+	Exceptions    []SpanException     `json:"exceptions"`    // Exceptions recorded as span events (logs in Jaeger)
+}
+
+// SpanException represents an exception recorded in a span, as an event named 'exception'.
+// See [OpenTelemetry specification](https://opentelemetry.io/docs/specs/semconv/exceptions/exceptions-spans/) for more details.
+type SpanException struct {
+	// Type is the type of the exception, i.e., attribute 'exception.type', e.g., 'java.net.ConnectException'.
+	Type string `json:"type"`
+
+	// Message is the message of the exception, i.e., attribute 'exception.message'.
+	Message string `json:"message"`
 }
 
 type AttributeEntry struct {
@@ -132,6 +147,54 @@ func (s *SemanticConventionType) UnmarshalJSON(data []byte) error {
 	return nil
 }
 
+// SpanStatusCodeType represents the status code of a span.
+// See [OpenTelemetry specification](https://opentelemetry.io/docs/specs/otel/trace/api/#set-status) for more details.
+type SpanStatusCodeType string
+
+// SpanStatusCodeType values.
+const (
+	// SpanStatusCodeUnset indicates that the status is not set, which is the default.
+	SpanStatusCodeUnset SpanStatusCodeType = "STATUS_CODE_UNSET"
+	// SpanStatusCodeOK indicates that the operation is validated to have completed successfully.
+	SpanStatusCodeOK SpanStatusCodeType = "STATUS_CODE_OK"
+	// SpanStatusCodeError indicates that the operation contains an error.
+	SpanStatusCodeError SpanStatusCodeType = "STATUS_CODE_ERROR"
+)
+
+// spanExceptionEventName is the name of span events (logs in Jaeger) recording exceptions.
+const spanExceptionEventName = "exception"
+
+// IsError checks whether the span indicates an error, i.e., its status code is error, or exceptions are recorded in it.
+func (s *SimplifiedTraceSpan) IsError() bool {
+	return s.StatusCode == SpanStatusCodeError || len(s.Exceptions) > 0
+}
+
+// GetErrorTypes returns the distinct error types of the span, in order of appearance.
+// Types of recorded exceptions are used if any. Otherwise, for spans with error status, attribute 'error.type' is used
+// if set, or the status code itself.
+// Messages are not used, as they usually contain request-specific values (e.g., IDs), which would make error types unbounded.
+func (s *SimplifiedTraceSpan) GetErrorTypes() []string {
+	errorTypes := make([]string, 0)
+	for _, exception := range s.Exceptions {
+		errorType := exception.Type
+		if errorType == "" {
+			errorType = spanExceptionEventName
+		}
+		if !slices.Contains(errorTypes, errorType) {
+			errorTypes = append(errorTypes, errorType)
+		}
+	}
+	if len(errorTypes) > 0 || s.StatusCode != SpanStatusCodeError {
+		return errorTypes
+	}
+	if errorType, exist := s.AttributeMap["error.type"]; exist {
+		if errorTypeStr, isString := errorType.Value.(string); isString && errorTypeStr != "" {
+			return []string{errorTypeStr}
+		}
+	}
+	return []string{string(SpanStatusCodeError)}
+}
+
 // RetrieveCalledMethod retrieves the called method that the span represents.
 // For example, if service A called method 'f' of service B, this method returns 'f'.
 // The second returned value indicates whether the method exists (or can be found).
@@ -177,6 +240,23 @@ func (s *SimplifiedTraceSpan) RetrieveCalledMethod() (string, bool) {
 		}
 		return operationNameParts[len(operationNameParts)-1], true
 
+	// For messaging, the called method is the destination (e.g., topic or queue) of the message, i.e., the channel in AsyncAPI doc.
+	// It is attribute 'messaging.destination.name' ('messaging.destination' in older versions), or the last part of the span name '{operation} {destination}'.
+	// See [OpenTelemetry specification](https://opentelemetry.io/docs/specs/semconv/messaging/messaging-spans/) for more details.
+	case SemanticConventionTypeMessaging:
+		for _, key := range []string{"messaging.destination.name", "messaging.destination"} {
+			if destination, exist := s.AttributeMap[key]; exist {
+				if destinationName, isString := destination.Value.(string); isString && destinationName != "" {
+					return destinationName, true
+				}
+			}
+		}
+		operationNameParts := strings.Split(s.OperationName, " ")
+		if len(operationNameParts) < 2 {
+			return "", false
+		}
+		return operationNameParts[len(operationNameParts)-1], true
+
 	// TODO: support more semantic conventions @xunzhou24
 	default:
 		log.Warn().Msgf("[SimplifiedTraceSpan.RetrieveCalledMethod] Unsupported semantic convention: %s", s.SemanticConvention)
@@ -184,6 +264,70 @@ func (s *SimplifiedTraceSpan) RetrieveCalledMethod() (string, bool) {
 	}
 }
 
+// dbStatementTablePattern is the pattern of a (possibly quoted and schema-qualified) table name in SQL statements.
+const dbStatementTablePattern = "([\\w.`\"\\[\\]]+)"
+
+var (
+	// dbStatementOperationRegex matches the operation (leading keyword) of a SQL statement.
+	dbStatementOperationRegex = regexp.MustCompile(`(?i)^\s*(SELECT|INSERT|UPDATE|DELETE|REPLACE|MERGE|UPSERT)\b`)
+
+	// dbStatementFromTableRegex, dbStatementIntoTableRegex and dbStatementUpdateTableRegex match the table in SQL statements,
+	// which may be quoted by backticks, double quotes or brackets.
+	dbStatementFromTableRegex   = regexp.MustCompile(`(?i)\bFROM\s+` + dbStatementTablePattern)
+	dbStatementIntoTableRegex   = regexp.MustCompile(`(?i)\bINTO\s+` + dbStatementTablePattern)
+	dbStatementUpdateTableRegex = regexp.MustCompile(`(?i)^\s*UPDATE\s+` + dbStatementTablePattern)
+
+	// dbTableQuoteReplacer removes quotes from table names, e.g., '`shop`.`orders`' to 'shop.orders'.
+	dbTableQuoteReplacer = strings.NewReplacer("`", "", `"`, "", "[", "", "]", "")
+
+	// dbStatementTableRegexMap maps from SQL operations to the regex matching the table in statements of the operation.
+	dbStatementTableRegexMap = map[string]*regexp.Regexp{
+		"SELECT":  dbStatementFromTableRegex,
+		"DELETE":  dbStatementFromTableRegex,
+		"INSERT":  dbStatementIntoTableRegex,
+		"REPLACE": dbStatementIntoTableRegex,
+		"MERGE":   dbStatementIntoTableRegex,
+		"UPSERT":  dbStatementIntoTableRegex,
+		"UPDATE":  dbStatementUpdateTableRegex,
+	}
+)
+
+// RetrieveDatabaseOperation retrieves the table and the operation (e.g., 'SELECT') of a database span.
+// They are taken from attributes 'db.sql.table' ('db.collection.name' in newer versions) and 'db.operation' ('db.operation.name' in newer versions),
+// or parsed from the SQL statement 'db.statement' ('db.query.text' in newer versions) if absent.
+// The second returned value indicates whether the span is a database span with the operation found. The table can be empty, e.g., for statements without table.
+// See [OpenTelemetry specification](https://opentelemetry.io/docs/specs/semconv/database/database-spans/) for more details.
+func (s *SimplifiedTraceSpan) RetrieveDatabaseOperation() (string, string, bool) {
+	if s.SemanticConvention != SemanticConventionTypeDatabase {
+		return "", "", false
+	}
+	getStringAttribute := func(keys ...string) string {
+		for _, key := range keys {
+			if attribute, exist := s.AttributeMap[key]; exist {
+				if value, isString := attribute.Value.(string); isString && value != "" {
+					return value
+				}
+			}
+		}
+		return ""
+	}
+	table := getStringAttribute("db.sql.table", "db.collection.name", "db.mongodb.collection")
+	operation := strings.ToUpper(getStringAttribute("db.operation", "db.operation.name"))
+	if statement := getStringAttribute("db.statement", "db.query.text"); statement != "" {
+		if operation == "" {
+			if matches := dbStatementOperationRegex.FindStringSubmatch(statement); matches != nil {
+				operation = strings.ToUpper(matches[1])
+			}
+		}
+		if tableRegex, exist := dbStatementTableRegexMap[operation]; exist && table == "" {
+			if matches := tableRegex.FindStringSubmatch(statement); matches != nil {
+				table = dbTableQuoteReplacer.Replace(matches[1])
+			}
+		}
+	}
+	return table, operation, operation != ""
+}
+
 // convertJaegerTraceTagValueToSpanKind converts a Jaeger trace tag value to a SpanKindType.
 // If the tag value is not recognized, it returns UNSPECIFIED.
 func convertJaegerTraceTagValueToSpanKind(tagValue string) SpanKindType {
@@ -219,7 +363,7 @@ type JaegerTraceSpan struct {
 	StartTime     int64                    `json:"startTime"`     // Start time of the span
 	Duration      int64                    `json:"duration"`      // Duration of the span
 	Tags          []JaegerTagEntry               `json:"tags"`          // Tags associated with the span
-	Logs          []map[string]interface{} `json:"logs"`          // Log entries associated with the span
+	Logs          []JaegerLogEntry         `json:"logs"`          // Log entries associated with the span
 	ProcessID     string                   `json:"processID"`     // Process ID
 	Warnings      interface{}              `json:"-"`             // Warnings associated with the span TODO: check here @xunzhou24
 }
@@ -231,6 +375,13 @@ type JaegerTagEntry struct {
 	Value interface{} `json:"value"`
 }
 
+// JaegerLogEntry represents a log entry of a span in a Jaeger trace.
+// Span events of OpenTelemetry are exported as logs, with the event name in field 'event'.
+type JaegerLogEntry struct {
+	Timestamp int64            `json:"timestamp"` // Timestamp of the log, in microseconds
+	Fields    []JaegerTagEntry `json:"fields"`    // Fields of the log
+}
+
 // ToAttributeEntry converts a JaegerTagEntry to an AttributeEntry.
 func (j *JaegerTagEntry) ToAttributeEntry() AttributeEntry {
 	return AttributeEntry{
@@ -303,9 +454,59 @@ func (j *JaegerTraceSpan) ToSimplifiedTraceSpan(processMap map[string]*JaegerPro
 	// parse semantic convention
 	span.SemanticConvention = j.InferSemanticConvention()
 
+	// parse status and exceptions
+	span.StatusCode, span.StatusMessage = j.ParseStatus()
+	span.Exceptions = j.ParseExceptions()
+
 	return span
 }
 
+// ParseStatus parses the status code and message of a JaegerTraceSpan from its tags.
+// OpenTelemetry exports the status as tags 'otel.status_code' ('OK' or 'ERROR') and 'otel.status_description',
+// and tag 'error' is set to true for errors, which is also used by OpenTracing.
+func (j *JaegerTraceSpan) ParseStatus() (SpanStatusCodeType, string) {
+	statusCode, statusMessage := SpanStatusCodeUnset, ""
+	for _, tag := range j.Tags {
+		switch tag.Key {
+		case "otel.status_code":
+			switch strings.ToUpper(fmt.Sprint(tag.Value)) {
+			case "ERROR":
+				statusCode = SpanStatusCodeError
+			case "OK":
+				if statusCode != SpanStatusCodeError {
+					statusCode = SpanStatusCodeOK
+				}
+			}
+		case "otel.status_description":
+			statusMessage = fmt.Sprint(tag.Value)
+		case "error":
+			if isError, _ := strconv.ParseBool(fmt.Sprint(tag.Value)); isError {
+				statusCode = SpanStatusCodeError
+			}
+		}
+	}
+	return statusCode, statusMessage
+}
+
+// ParseExceptions parses the exceptions recorded in logs of a JaegerTraceSpan, i.e., logs with field 'event' of 'exception'.
+func (j *JaegerTraceSpan) ParseExceptions() []SpanException {
+	exceptions := make([]SpanException, 0)
+	for _, logEntry := range j.Logs {
+		fields := make(map[string]string)
+		for _, field := range logEntry.Fields {
+			fields[field.Key] = fmt.Sprint(field.Value)
+		}
+		if fields["event"] != spanExceptionEventName {
+			continue
+		}
+		exceptions = append(exceptions, SpanException{
+			Type:    fields["exception.type"],
+			Message: fields["exception.message"],
+		})
+	}
+	return exceptions
+}
+
 // InferSemanticConvention infers the semantic convention of a JaegerTraceSpan.
 // Unsupported semantic conventions are returned as SemanticConventionTypeUnknown.
 // Note: the result may not be accurate, as it's based on the tags and name format.
@@ -434,7 +635,23 @@ type TempoTraceSpan struct {
 	StartTimeUnixNano  string `json:"startTimeUnixNano"`  // Start time in Unix nanoseconds
 	EndTimeUnixNano    string `json:"endTimeUnixNano"`    // End time in Unix nanoseconds
 	Attributes         []TempoAttributeEntry `json:"attributes"` // List of attributes
-	Status interface{} `json:"status"` // Status of the span
+	Events             []TempoSpanEvent      `json:"events"`     // List of events
+	Status             TempoSpanStatus       `json:"status"`     // Status of the span
+}
+
+// TempoSpanEvent represents an event of a span in a Tempo trace, e.g., an exception.
+type TempoSpanEvent struct {
+	TimeUnixNano string                `json:"timeUnixNano"` // Time of the event in Unix nanoseconds
+	Name         string                `json:"name"`         // Name of the event
+	Attributes   []TempoAttributeEntry `json:"attributes"`   // List of attributes
+}
+
+// TempoSpanStatus represents the status of a span in a Tempo trace.
+// It is empty if the status is unset.
+type TempoSpanStatus struct {
+	// Code is the status code, either the enum name (e.g., 'STATUS_CODE_ERROR') or the enum number (e.g., 2).
+	Code    interface{} `json:"code,omitempty"`
+	Message string      `json:"message,omitempty"` // Description of the status
 }
 
 type TempoAttributeEntry struct {
@@ -448,6 +665,21 @@ type TempoAttributeEntry struct {
 	} `json:"value"` // Value of the attribute
 }
 
+// TempoSearchResponse represents the response of Tempo search API (`/api/search`).
+type TempoSearchResponse struct {
+	Traces []TempoTraceSearchMetadata `json:"traces"` // Metadata of matched traces
+}
+
+// TempoTraceSearchMetadata represents the metadata of a trace matched by Tempo search API.
+// Spans are not included, and the trace should be fetched by its ID.
+type TempoTraceSearchMetadata struct {
+	TraceID           string `json:"traceID"`           // Trace ID in hex
+	RootServiceName   string `json:"rootServiceName"`   // Service name of the root span
+	RootTraceName     string `json:"rootTraceName"`     // Name of the root span
+	StartTimeUnixNano string `json:"startTimeUnixNano"` // Start time of the trace in Unix nanoseconds
+	DurationMs        int    `json:"durationMs"`        // Duration of the trace in milliseconds
+}
+
 // ToSimplifiedTrace converts a TempoTrace to a SimplifiedTrace.
 func (t *TempoTrace) ToSimplifiedTrace() *SimplifiedTrace {
 	spanMap := make(map[string]*SimplifiedTraceSpan)
@@ -562,9 +794,42 @@ func (t *TempoTraceSpan) ToSimplifiedTraceSpan(resourceAttributes []TempoAttribu
 	semanticConvention := t.InferSemanticConvention()
 	span.SemanticConvention = semanticConvention
 
+	// parse status and exceptions
+	span.StatusCode = convertTempoStatusCodeToSpanStatusCode(t.Status.Code)
+	span.StatusMessage = t.Status.Message
+	span.Exceptions = make([]SpanException, 0)
+	for _, event := range t.Events {
+		if event.Name != spanExceptionEventName {
+			continue
+		}
+		eventAttributes := convertTempoAttributesToAttributeEntries(event.Attributes)
+		exception := SpanException{}
+		if exceptionType, exist := eventAttributes["exception.type"]; exist {
+			exception.Type = fmt.Sprint(exceptionType.Value)
+		}
+		if exceptionMessage, exist := eventAttributes["exception.message"]; exist {
+			exception.Message = fmt.Sprint(exceptionMessage.Value)
+		}
+		span.Exceptions = append(span.Exceptions, exception)
+	}
+
 	return span
 }
 
+// convertTempoStatusCodeToSpanStatusCode converts a Tempo status code to a SpanStatusCodeType.
+// The code can be the enum name or the enum number, see [TempoSpanStatus].
+// If the code is not recognized, it returns SpanStatusCodeUnset.
+func convertTempoStatusCodeToSpanStatusCode(tempoStatusCode interface{}) SpanStatusCodeType {
+	switch fmt.Sprint(tempoStatusCode) {
+	case "STATUS_CODE_OK", "1":
+		return SpanStatusCodeOK
+	case "STATUS_CODE_ERROR", "2":
+		return SpanStatusCodeError
+	default:
+		return SpanStatusCodeUnset
+	}
+}
+
 
 // convertTempoAttributesToAttributeEntries converts a list of TempoAttributeEntry to a map of AttributeEntry.
 func convertTempoAttributesToAttributeEntries(tempoAttributes []TempoAttributeEntry) map[string]AttributeEntry {
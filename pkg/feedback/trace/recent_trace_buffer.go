@@ -0,0 +1,153 @@
+package trace
+
+import (
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+)
+
+// TraceSummary is a summary of a trace, e.g., to show recent traces on the dashboard without all spans.
+type TraceSummary struct {
+	// TraceID is the unique identifier for the trace.
+	TraceID string `json:"traceID"`
+
+	// StartTime is the start time of the trace.
+	StartTime time.Time `json:"startTime"`
+
+	// RootOperationName is the operation name of the root span (the one without parent in the trace), or empty if not found.
+	RootOperationName string `json:"rootOperationName"`
+
+	// Duration is the duration of the root span, in microseconds, or 0 if the root span is not found.
+	Duration int64 `json:"duration"`
+
+	// SpanCount is the number of spans in the trace.
+	SpanCount int `json:"spanCount"`
+
+	// ErrorSpanCount is the number of spans of error status, or with exceptions.
+	ErrorSpanCount int `json:"errorSpanCount"`
+
+	// ServiceNames are the names of services involved in the trace, sorted.
+	ServiceNames []string `json:"serviceNames"`
+}
+
+// NewTraceSummary summarizes the trace.
+func NewTraceSummary(trace *SimplifiedTrace) *TraceSummary {
+	summary := &TraceSummary{
+		TraceID:      trace.TraceID,
+		StartTime:    trace.StartTime,
+		SpanCount:    len(trace.SpanMap),
+		ServiceNames: make([]string, 0),
+	}
+	var rootSpan *SimplifiedTraceSpan
+	for _, span := range trace.SpanMap {
+		if span == nil {
+			continue
+		}
+		// The earliest one is taken if there are multiple spans without parent, e.g., the parent span is missing.
+		if _, hasParent := trace.SpanMap[span.ParentID]; !hasParent && (rootSpan == nil || span.StartTime.Before(rootSpan.StartTime)) {
+			rootSpan = span
+		}
+		if span.StatusCode == SpanStatusCodeError || len(span.Exceptions) > 0 {
+			summary.ErrorSpanCount++
+		}
+		if span.ServiceName != "" && !slices.Contains(summary.ServiceNames, span.ServiceName) {
+			summary.ServiceNames = append(summary.ServiceNames, span.ServiceName)
+		}
+	}
+	slices.Sort(summary.ServiceNames)
+	if rootSpan != nil {
+		summary.RootOperationName = rootSpan.OperationName
+		summary.Duration = rootSpan.Duration
+	}
+	return summary
+}
+
+// RecentTraceBuffer is a ring buffer keeping summaries (see [TraceSummary]) of the most recent traces fetched.
+// It is safe for concurrent use, as it may be read by the dashboard while the fuzzer is running.
+//
+// It implements [TraceDB], so that it observes every trace fetched by [TraceManager], but it does not store whole traces.
+type RecentTraceBuffer struct {
+	mu sync.Mutex
+
+	// summaries is the underlying ring.
+	summaries []*TraceSummary
+
+	// next is the index in summaries to write the next summary to.
+	next int
+
+	// full is true if the ring has been filled, i.e., oldest summaries are being overwritten.
+	full bool
+}
+
+// NewRecentTraceBuffer creates a new RecentTraceBuffer keeping at most `size` trace summaries.
+// It returns nil if size is not positive.
+func NewRecentTraceBuffer(size int) *RecentTraceBuffer {
+	if size <= 0 {
+		return nil
+	}
+	return &RecentTraceBuffer{
+		summaries: make([]*TraceSummary, size),
+	}
+}
+
+// SelectByIDs selects traces by IDs.
+// It is not implemented for RecentTraceBuffer, as traces are not stored.
+func (b *RecentTraceBuffer) SelectByIDs(ids []string) ([]*SimplifiedTrace, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+
+// Upsert records the summary of the trace.
+func (b *RecentTraceBuffer) Upsert(trace *SimplifiedTrace) error {
+	_, err := b.InsertAndReturn(trace)
+	return err
+}
+
+// BatchUpsert records summaries of the traces.
+func (b *RecentTraceBuffer) BatchUpsert(traces []*SimplifiedTrace) error {
+	_, err := b.BatchInsertAndReturn(traces)
+	return err
+}
+
+// InsertAndReturn records the summary of the trace, overwriting the oldest one if the buffer is full, and returns the trace.
+func (b *RecentTraceBuffer) InsertAndReturn(trace *SimplifiedTrace) (*SimplifiedTrace, error) {
+	if trace == nil {
+		return nil, fmt.Errorf("trace is nil")
+	}
+	summary := NewTraceSummary(trace)
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.summaries[b.next] = summary
+	b.next = (b.next + 1) % len(b.summaries)
+	if b.next == 0 {
+		b.full = true
+	}
+	return trace, nil
+}
+
+// BatchInsertAndReturn records summaries of the traces, and returns the traces.
+func (b *RecentTraceBuffer) BatchInsertAndReturn(traces []*SimplifiedTrace) ([]*SimplifiedTrace, error) {
+	res := make([]*SimplifiedTrace, 0, len(traces))
+	for _, trace := range traces {
+		if insertRes, err := b.InsertAndReturn(trace); insertRes != nil && err == nil {
+			res = append(res, insertRes)
+		}
+	}
+	return res, nil
+}
+
+// GetAll returns all trace summaries in the buffer, from the newest to the oldest.
+func (b *RecentTraceBuffer) GetAll() []*TraceSummary {
+	if b == nil {
+		return make([]*TraceSummary, 0)
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	res := make([]*TraceSummary, 0, len(b.summaries))
+	if b.full {
+		res = append(res, b.summaries[b.next:]...)
+	}
+	res = append(res, b.summaries[:b.next]...)
+	slices.Reverse(res)
+	return res
+}
@@ -0,0 +1,307 @@
+package trace
+
+import (
+	"fmt"
+	"maps"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/bytedance/sonic"
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/rs/zerolog/log"
+)
+
+// observedServiceAPITypeHTTP and observedServiceAPITypeGRPC are the API types of observed internal service APIs,
+// which are used as the tag `APIType_{APIType}` in synthesized doc, see [resttracefuzzer/pkg/static.APIManager].
+const (
+	observedServiceAPITypeHTTP = "HTTP"
+	observedServiceAPITypeGRPC = "gRPC"
+)
+
+// pathParamRegex matches path parameters in HTTP route template, e.g., `{id}` in `/users/{id}`.
+var pathParamRegex = regexp.MustCompile(`\{([^{}/]+)\}`)
+
+// nonAlphanumericRegex matches non-alphanumeric characters, which are removed from names used in operationId.
+var nonAlphanumericRegex = regexp.MustCompile(`[^a-zA-Z0-9]+`)
+
+// ObservedServiceAPI is an internal service API observed in traces.
+type ObservedServiceAPI struct {
+	// ServiceName is the name of the service serving the API, as reported in the span.
+	ServiceName string `json:"serviceName"`
+
+	// APIType is the type of the API, HTTP or gRPC.
+	APIType string `json:"APIType"`
+
+	// Method is the HTTP method of HTTP APIs, or the method name of gRPC APIs.
+	Method string `json:"method"`
+
+	// Path is the route template of HTTP APIs (e.g., `/users/{id}`), or `/{rpc.service}/{rpc.method}` of gRPC APIs.
+	Path string `json:"path"`
+
+	// HitCount is the number of server spans of the API observed.
+	HitCount int `json:"hitCount"`
+
+	// EntryHitCount is the number of server spans of the API at the entry of traces, i.e., without parent spans in the traces,
+	// which serve requests from outside the system (e.g., sent by the fuzzer).
+	EntryHitCount int `json:"entryHitCount"`
+}
+
+// ServiceDocSynthesizer synthesizes a skeleton OpenAPI document of internal services from server spans in collected traces,
+// for those without internal service docs.
+// The synthesized doc follows the conventions of the internal service doc we parse (operationId `{Service}_{Method}` and tag `APIType_{APIType}`),
+// so it can be passed by `--internal-service-openapi-spec` in subsequent runs. Request and response schemas are not available in traces, and are left empty.
+//
+// It implements [TraceDB], so that it observes every trace fetched by [TraceManager], but it does not store any trace.
+type ServiceDocSynthesizer struct {
+	// ObservedAPIs maps from the key of observed API (`{service} {method} {path}`) to the API.
+	ObservedAPIs map[string]*ObservedServiceAPI
+}
+
+// NewServiceDocSynthesizer creates a new ServiceDocSynthesizer.
+func NewServiceDocSynthesizer() *ServiceDocSynthesizer {
+	return &ServiceDocSynthesizer{
+		ObservedAPIs: make(map[string]*ObservedServiceAPI),
+	}
+}
+
+// SelectByIDs selects traces by IDs.
+// It is not implemented for ServiceDocSynthesizer, as traces are not stored.
+func (s *ServiceDocSynthesizer) SelectByIDs(ids []string) ([]*SimplifiedTrace, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+
+// Upsert records internal service APIs in the trace.
+func (s *ServiceDocSynthesizer) Upsert(trace *SimplifiedTrace) error {
+	_, err := s.InsertAndReturn(trace)
+	return err
+}
+
+// BatchUpsert records internal service APIs in the traces.
+func (s *ServiceDocSynthesizer) BatchUpsert(traces []*SimplifiedTrace) error {
+	_, err := s.BatchInsertAndReturn(traces)
+	return err
+}
+
+// InsertAndReturn records internal service APIs in the trace, and returns the trace.
+func (s *ServiceDocSynthesizer) InsertAndReturn(trace *SimplifiedTrace) (*SimplifiedTrace, error) {
+	if trace == nil {
+		return nil, fmt.Errorf("trace is nil")
+	}
+	for _, span := range trace.SpanMap {
+		_, hasParent := trace.SpanMap[span.ParentID]
+		s.recordSpan(span, !hasParent)
+	}
+	return trace, nil
+}
+
+// BatchInsertAndReturn records internal service APIs in the traces, and returns the traces.
+func (s *ServiceDocSynthesizer) BatchInsertAndReturn(traces []*SimplifiedTrace) ([]*SimplifiedTrace, error) {
+	res := make([]*SimplifiedTrace, 0, len(traces))
+	for _, trace := range traces {
+		if insertRes, err := s.InsertAndReturn(trace); insertRes != nil && err == nil {
+			res = append(res, insertRes)
+		}
+	}
+	return res, nil
+}
+
+// recordSpan records the API served by the span, if it is a server span of HTTP or RPC.
+// isEntry tells whether the span is at the entry of its trace, see [ObservedServiceAPI.EntryHitCount].
+func (s *ServiceDocSynthesizer) recordSpan(span *SimplifiedTraceSpan, isEntry bool) {
+	api := GetServedAPI(span)
+	if api == nil {
+		return
+	}
+	key := fmt.Sprintf("%s %s %s", api.ServiceName, api.Method, api.Path)
+	if existing, exists := s.ObservedAPIs[key]; exists {
+		api = existing
+	} else {
+		s.ObservedAPIs[key] = api
+		log.Debug().Msgf("[ServiceDocSynthesizer.recordSpan] New internal service API observed: %s", key)
+	}
+	api.HitCount++
+	if isEntry {
+		api.EntryHitCount++
+	}
+}
+
+// GetObservedAPIs returns all observed internal service APIs, sorted by service name, method and path.
+func (s *ServiceDocSynthesizer) GetObservedAPIs() []*ObservedServiceAPI {
+	observedAPIs := make([]*ObservedServiceAPI, 0, len(s.ObservedAPIs))
+	for _, key := range slices.Sorted(maps.Keys(s.ObservedAPIs)) {
+		observedAPIs = append(observedAPIs, s.ObservedAPIs[key])
+	}
+	return observedAPIs
+}
+
+// SynthesizeDoc synthesizes the OpenAPI document from observed internal service APIs.
+func (s *ServiceDocSynthesizer) SynthesizeDoc() *openapi3.T {
+	doc := &openapi3.T{
+		OpenAPI: "3.0.3",
+		Info: &openapi3.Info{
+			Title:       "Internal Services",
+			Description: "Skeleton of internal service APIs, synthesized from observed traces.",
+			Version:     "0.0.1",
+		},
+		Paths: openapi3.NewPaths(),
+	}
+	// operationIDs records used operationIds, to keep them unique.
+	operationIDs := make(map[string]struct{})
+	for _, key := range slices.Sorted(maps.Keys(s.ObservedAPIs)) {
+		api := s.ObservedAPIs[key]
+		serviceName := nonAlphanumericRegex.ReplaceAllString(api.ServiceName, "")
+		var methodName, httpMethod string
+		if api.APIType == observedServiceAPITypeGRPC {
+			methodName = nonAlphanumericRegex.ReplaceAllString(api.Method, "")
+			httpMethod = "POST"
+		} else {
+			methodName = getHTTPOperationName(api.Method, api.Path)
+			httpMethod = api.Method
+		}
+		if serviceName == "" || methodName == "" {
+			log.Warn().Msgf("[ServiceDocSynthesizer.SynthesizeDoc] Cannot name operation of API %s, skipped", key)
+			continue
+		}
+		operationID := fmt.Sprintf("%s_%s", serviceName, methodName)
+		for i := 2; ; i++ {
+			if _, used := operationIDs[operationID]; !used {
+				break
+			}
+			operationID = fmt.Sprintf("%s_%s%d", serviceName, methodName, i)
+		}
+		operationIDs[operationID] = struct{}{}
+
+		operation := &openapi3.Operation{
+			OperationID: operationID,
+			Tags:        []string{fmt.Sprintf("APIType_%s", api.APIType), api.ServiceName},
+			Summary:     fmt.Sprintf("Observed %d time(s) in traces", api.HitCount),
+			// responses are not available in traces, and a default one is required to make the doc valid
+			Responses: openapi3.NewResponses(openapi3.WithName("default", openapi3.NewResponse().WithDescription("Response not recorded in traces"))),
+		}
+		for _, match := range pathParamRegex.FindAllStringSubmatch(api.Path, -1) {
+			operation.AddParameter(openapi3.NewPathParameter(match[1]).WithSchema(openapi3.NewStringSchema()))
+		}
+
+		pathItem := doc.Paths.Value(api.Path)
+		if pathItem == nil {
+			pathItem = &openapi3.PathItem{}
+			doc.Paths.Set(api.Path, pathItem)
+		}
+		pathItem.SetOperation(httpMethod, operation)
+	}
+	return doc
+}
+
+// SaveDoc synthesizes the OpenAPI document and saves it to the given path, in JSON format.
+func (s *ServiceDocSynthesizer) SaveDoc(outputPath string) error {
+	docBytes, err := sonic.Marshal(s.SynthesizeDoc())
+	if err != nil {
+		log.Err(err).Msgf("[ServiceDocSynthesizer.SaveDoc] Failed to marshal the synthesized doc")
+		return err
+	}
+	err = os.WriteFile(outputPath, docBytes, 0644)
+	if err != nil {
+		log.Err(err).Msgf("[ServiceDocSynthesizer.SaveDoc] Failed to write the synthesized doc to file")
+		return err
+	}
+	log.Info().Msgf("[ServiceDocSynthesizer.SaveDoc] Synthesized doc of %d internal service APIs saved to %s", len(s.ObservedAPIs), outputPath)
+	return nil
+}
+
+// GetServedAPI returns the API served by the span, if it is a server span of HTTP or RPC, or nil otherwise.
+// The hit counts of the returned API are 0.
+func GetServedAPI(span *SimplifiedTraceSpan) *ObservedServiceAPI {
+	if span == nil || span.SpanKind != SERVER || span.ServiceName == "" {
+		return nil
+	}
+	switch span.SemanticConvention {
+	case SemanticConventionTypeHTTP:
+		// gRPC over HTTP/2 is recorded as gRPC API, see [SimplifiedTraceSpan.RetrieveCalledMethod]
+		if _, exist := span.AttributeMap["grpc.method"]; exist {
+			return newObservedGRPCAPI(span)
+		}
+		route, ok := span.RetrieveCalledMethod()
+		if !ok || !strings.HasPrefix(route, "/") {
+			return nil
+		}
+		httpMethod := getStringAttribute(span, "http.request.method", "http.method")
+		if httpMethod == "" {
+			httpMethod = strings.Split(span.OperationName, " ")[0]
+		}
+		httpMethod = strings.ToUpper(httpMethod)
+		if !slices.Contains(allHTTPMethods, httpMethod) {
+			return nil
+		}
+		return &ObservedServiceAPI{
+			ServiceName: span.ServiceName,
+			APIType:     observedServiceAPITypeHTTP,
+			Method:      httpMethod,
+			Path:        route,
+		}
+	case SemanticConventionTypeRPC:
+		return newObservedGRPCAPI(span)
+	default:
+		return nil
+	}
+}
+
+// allHTTPMethods are HTTP methods allowed in OpenAPI path items.
+var allHTTPMethods = []string{"GET", "PUT", "POST", "DELETE", "OPTIONS", "HEAD", "PATCH", "TRACE", "CONNECT"}
+
+// newObservedGRPCAPI creates an ObservedServiceAPI of gRPC from the span.
+// It returns nil if the method name cannot be retrieved.
+func newObservedGRPCAPI(span *SimplifiedTraceSpan) *ObservedServiceAPI {
+	methodName, ok := span.RetrieveCalledMethod()
+	if !ok || methodName == "" {
+		return nil
+	}
+	rpcService := getStringAttribute(span, "rpc.service")
+	if rpcService == "" {
+		rpcService = span.ServiceName
+	}
+	return &ObservedServiceAPI{
+		ServiceName: span.ServiceName,
+		APIType:     observedServiceAPITypeGRPC,
+		Method:      methodName,
+		Path:        fmt.Sprintf("/%s/%s", rpcService, methodName),
+	}
+}
+
+// getStringAttribute returns the value of the first existing string attribute of the given keys, or empty string if none exists.
+func getStringAttribute(span *SimplifiedTraceSpan, keys ...string) string {
+	for _, key := range keys {
+		if entry, exist := span.AttributeMap[key]; exist {
+			if value, ok := entry.Value.(string); ok && value != "" {
+				return value
+			}
+		}
+	}
+	return ""
+}
+
+// getHTTPOperationName returns the operation name of an HTTP API, used in operationId.
+// For example, `GET /users/{id}/orders` is named as `GetUsersByIdOrders`.
+func getHTTPOperationName(httpMethod, path string) string {
+	var builder strings.Builder
+	builder.WriteString(capitalize(strings.ToLower(httpMethod)))
+	for _, segment := range strings.Split(path, "/") {
+		if match := pathParamRegex.FindStringSubmatch(segment); match != nil {
+			builder.WriteString("By")
+			segment = match[1]
+		}
+		for _, word := range nonAlphanumericRegex.Split(segment, -1) {
+			builder.WriteString(capitalize(word))
+		}
+	}
+	return builder.String()
+}
+
+// capitalize returns the word with its first letter in upper case.
+func capitalize(word string) string {
+	if word == "" {
+		return word
+	}
+	return strings.ToUpper(word[:1]) + word[1:]
+}
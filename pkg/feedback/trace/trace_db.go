@@ -3,6 +3,7 @@ package trace
 import (
 	"fmt"
 	"os"
+	"resttracefuzzer/pkg/utils"
 
 	"github.com/bytedance/sonic"
 	"github.com/rs/zerolog/log"
@@ -120,23 +121,42 @@ func (db *InMemoryTraceDB) InsertAndReturn(trace *SimplifiedTrace) (*SimplifiedT
 	return trace, nil
 }
 
+// DropOldTraces drops the given ratio (0-1) of the oldest traces, i.e., those inserted earliest, e.g., to reduce memory usage.
+// It returns the number of dropped traces.
+func (db *InMemoryTraceDB) DropOldTraces(ratio float64) int {
+	dropCnt := int(float64(len(db.Traces)) * ratio)
+	// Copy the remaining ones, so that the dropped traces can be garbage collected.
+	db.Traces = append([]*SimplifiedTrace(nil), db.Traces[dropCnt:]...)
+	return dropCnt
+}
+
 // RawTraceFileSaver is a file-based implementation of TraceDB.
 // It saves traces to files in a specified directory.
 type RawTraceFileSaver struct {
 	// DirPath is the directory path where traces are saved.
 	DirPath string
+
+	// Compression is the compression of saved files, see [utils.WriteFileWithCompression].
+	// Files can be read by [utils.ReadFileWithDecompression], no matter whether they are compressed.
+	Compression string
 }
 
-// NewRawTraceFileSaver creates a new RawTraceFileSaver.
-func NewRawTraceFileSaver(dirPath string) *RawTraceFileSaver {
+// NewRawTraceFileSaver creates a new RawTraceFileSaver, which saves traces compressed by the compression.
+// Unsupported compression is replaced by [utils.CompressionNone].
+func NewRawTraceFileSaver(dirPath string, compression string) *RawTraceFileSaver {
 	// Create the directory if it does not exist.
 	if _, err := os.Stat(dirPath); os.IsNotExist(err) {
 		if err := os.MkdirAll(dirPath, 0755); err != nil {
 			log.Err(err).Msgf("[NewRawTraceFileSaver] Failed to create directory: %s", err)
 		}
 	}
+	if !utils.IsValidCompression(compression) {
+		log.Warn().Msgf("[NewRawTraceFileSaver] Unsupported compression %s, save traces uncompressed instead", compression)
+		compression = utils.CompressionNone
+	}
 	return &RawTraceFileSaver{
-		DirPath: dirPath,
+		DirPath:     dirPath,
+		Compression: compression,
 	}
 }
 
@@ -193,20 +213,13 @@ func (s *RawTraceFileSaver) BatchUpsert(traces []*SimplifiedTrace) error {
 }
 
 // saveToFile saves a trace to a file.
-// The file is named by the trace ID and is saved in the specified directory.
+// The file is named by the trace ID (with [utils.ZstdFileExtension] appended if compressed) and is saved in the specified directory.
 func (s *RawTraceFileSaver) saveToFile(trace *SimplifiedTrace) error {
 	if trace == nil {
 		return fmt.Errorf("trace is nil")
 	}
-	traceId := trace.TraceID
 	// Save the trace into a file named by traceId under the directory.
-	filePath := fmt.Sprintf("%s/%s.json", s.DirPath, traceId)
-	file, err := os.OpenFile(filePath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
-	if err != nil {
-		log.Err(err).Msgf("[RawTraceFileSaver.saveToFile] Failed to open file")
-		return fmt.Errorf("failed to open file: %w", err)
-	}
-	defer file.Close()
+	filePath := s.getTraceFilePath(trace.TraceID)
 
 	// Write the trace to the file.
 	traceBytes, err := sonic.Marshal(trace)
@@ -214,10 +227,24 @@ func (s *RawTraceFileSaver) saveToFile(trace *SimplifiedTrace) error {
 		log.Err(err).Msgf("[RawTraceFileSaver.saveToFile] Failed to marshal trace")
 		return fmt.Errorf("failed to marshal trace: %w", err)
 	}
-	if _, err := file.Write(traceBytes); err != nil {
+	if _, err := utils.WriteFileWithCompression(filePath, traceBytes, s.Compression); err != nil {
 		log.Err(err).Msgf("[RawTraceFileSaver.saveToFile] Failed to write trace to file")
 		return fmt.Errorf("failed to write trace to file: %w", err)
 	}
 
 	return nil
 }
+
+// getTraceFilePath returns the path of the file of the trace, before [utils.ZstdFileExtension] is appended if compressed.
+func (s *RawTraceFileSaver) getTraceFilePath(traceID string) string {
+	return fmt.Sprintf("%s/%s.json", s.DirPath, traceID)
+}
+
+// GetSavedTraceFilePath returns the path of the file of the trace (see [utils.GetCompressedFilePath]), and whether the trace has been saved.
+func (s *RawTraceFileSaver) GetSavedTraceFilePath(traceID string) (string, bool) {
+	filePath := utils.GetCompressedFilePath(s.getTraceFilePath(traceID), s.Compression)
+	if _, err := os.Stat(filePath); err != nil {
+		return "", false
+	}
+	return filePath, true
+}
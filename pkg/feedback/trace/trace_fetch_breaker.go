@@ -0,0 +1,129 @@
+package trace
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// ErrTraceFetchDegraded is returned when a trace fetch is skipped, as the trace backend is considered unavailable (i.e., degradation mode).
+var ErrTraceFetchDegraded = errors.New("trace backend is unavailable, trace fetch is skipped in degradation mode")
+
+// TraceFetchBreaker is a circuit breaker for fetching traces from the trace backend.
+// After sustained (consecutive) fetch failures, it switches to degradation mode, where trace fetches are skipped,
+// and coverage is collected from status codes only.
+// In degradation mode, it periodically allows a fetch as a probe, and switches back to normal mode once the probe succeeds.
+type TraceFetchBreaker struct {
+	// FailureThreshold is the number of consecutive fetch failures to switch to degradation mode.
+	// The breaker is disabled if it is not positive.
+	FailureThreshold int
+
+	// ProbeInterval is the interval between probes in degradation mode.
+	ProbeInterval time.Duration
+
+	// consecutiveFailures is the number of consecutive fetch failures.
+	consecutiveFailures int
+
+	// degraded indicates whether the breaker is in degradation mode.
+	degraded bool
+
+	// probing indicates whether a probe is in progress in degradation mode.
+	probing bool
+
+	// lastProbeTime is the time of the last probe, or the time of switching to degradation mode.
+	lastProbeTime time.Time
+
+	// degradedCount is the number of times the breaker switched to degradation mode.
+	degradedCount int
+
+	// mu protects the states above.
+	mu sync.Mutex
+}
+
+// NewTraceFetchBreaker creates a new TraceFetchBreaker.
+// The breaker is disabled if failureThreshold is not positive.
+func NewTraceFetchBreaker(failureThreshold int, probeInterval time.Duration) *TraceFetchBreaker {
+	if probeInterval < 0 {
+		log.Warn().Msgf("[NewTraceFetchBreaker] Invalid probe interval %v, use 0 instead", probeInterval)
+		probeInterval = 0
+	}
+	return &TraceFetchBreaker{
+		FailureThreshold: failureThreshold,
+		ProbeInterval:    probeInterval,
+	}
+}
+
+// Allow reports whether a trace fetch should be performed.
+// In normal mode, it always returns true.
+// In degradation mode, it returns true only if it is time to probe and no other probe is in progress.
+func (b *TraceFetchBreaker) Allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if !b.degraded {
+		return true
+	}
+	if b.probing || time.Since(b.lastProbeTime) < b.ProbeInterval {
+		return false
+	}
+	b.probing = true
+	b.lastProbeTime = time.Now()
+	log.Info().Msg("[TraceFetchBreaker.Allow] Probe the trace backend for recovery")
+	return true
+}
+
+// RecordSuccess records a successful trace fetch.
+// If the breaker is in degradation mode, it switches back to normal mode.
+func (b *TraceFetchBreaker) RecordSuccess() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.consecutiveFailures = 0
+	b.probing = false
+	if b.degraded {
+		b.degraded = false
+		log.Info().Msg("[TraceFetchBreaker.RecordSuccess] Trace backend recovered, switch back to normal mode")
+	}
+}
+
+// RecordFailure records a failed trace fetch.
+// It switches to degradation mode if consecutive failures reach the threshold.
+func (b *TraceFetchBreaker) RecordFailure() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.consecutiveFailures++
+	if b.degraded {
+		// The probe failed, wait for the next probe.
+		b.probing = false
+		b.lastProbeTime = time.Now()
+		log.Warn().Msgf("[TraceFetchBreaker.RecordFailure] Probe failed, trace backend is still unavailable, next probe in %v", b.ProbeInterval)
+		return
+	}
+	if b.FailureThreshold > 0 && b.consecutiveFailures >= b.FailureThreshold {
+		b.degraded = true
+		b.degradedCount++
+		b.lastProbeTime = time.Now()
+		log.Warn().Msgf("[TraceFetchBreaker.RecordFailure] %d consecutive trace fetch failures, switch to degradation mode (coverage from status codes only), next probe in %v", b.consecutiveFailures, b.ProbeInterval)
+	}
+}
+
+// ReleaseProbe releases the probe in progress without recording its result, e.g., if the fetch is cancelled.
+func (b *TraceFetchBreaker) ReleaseProbe() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.probing = false
+}
+
+// IsDegraded reports whether the breaker is in degradation mode.
+func (b *TraceFetchBreaker) IsDegraded() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.degraded
+}
+
+// GetDegradedCount returns the number of times the breaker switched to degradation mode.
+func (b *TraceFetchBreaker) GetDegradedCount() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.degradedCount
+}
@@ -0,0 +1,69 @@
+package trace
+
+import (
+	"context"
+	"sync"
+)
+
+// TraceFetchResult is the result of fetching a trace by ID asynchronously, see [TraceFetchPool].
+type TraceFetchResult struct {
+	// TraceID is the ID of the trace.
+	TraceID string
+
+	// Trace is the fetched trace. It is nil if Err is not nil.
+	Trace *SimplifiedTrace
+
+	// CallInfos are the call infos converted from Trace.
+	CallInfos []*CallInfo
+
+	// Err is the error of fetching or converting the trace, e.g., [ErrTraceFetchDegraded].
+	Err error
+}
+
+// TraceFetchPool fetches traces by ID concurrently, with a bounded number of workers,
+// so that waiting for traces to become queryable in slow trace backends does not block sending requests.
+// Fetched traces are converted to call infos by the workers as well, so only applying call infos is left to the caller.
+// Each trace is fetched by a request of its own, i.e., traces are not fetched in batches.
+type TraceFetchPool struct {
+	// Fetch fetches the trace by ID and converts it to call infos, e.g., [TraceManager.PullTraceByIDAndConvert].
+	Fetch func(ctx context.Context, traceID string) (*SimplifiedTrace, []*CallInfo, error)
+
+	// workerSlots bounds the number of traces being fetched at the same time.
+	workerSlots chan struct{}
+
+	// pendingWG waits for traces being fetched.
+	pendingWG sync.WaitGroup
+}
+
+// NewTraceFetchPool creates a new TraceFetchPool with the given number of workers, which should be positive.
+func NewTraceFetchPool(workerCount int, fetch func(ctx context.Context, traceID string) (*SimplifiedTrace, []*CallInfo, error)) *TraceFetchPool {
+	return &TraceFetchPool{
+		Fetch:       fetch,
+		workerSlots: make(chan struct{}, workerCount),
+	}
+}
+
+// Submit starts fetching the trace by ID, and returns a channel receiving the result once it is fetched.
+// It blocks until a worker is available, or returns a result with ctx.Err() if ctx is done before that.
+func (p *TraceFetchPool) Submit(ctx context.Context, traceID string) <-chan *TraceFetchResult {
+	resultChan := make(chan *TraceFetchResult, 1)
+	select {
+	case <-ctx.Done():
+		resultChan <- &TraceFetchResult{TraceID: traceID, Err: ctx.Err()}
+		return resultChan
+	case p.workerSlots <- struct{}{}:
+	}
+	p.pendingWG.Add(1)
+	go func() {
+		defer p.pendingWG.Done()
+		defer func() { <-p.workerSlots }()
+		trace, callInfos, err := p.Fetch(ctx, traceID)
+		resultChan <- &TraceFetchResult{TraceID: traceID, Trace: trace, CallInfos: callInfos, Err: err}
+	}()
+	return resultChan
+}
+
+// Wait waits until all submitted traces are fetched.
+func (p *TraceFetchPool) Wait() {
+	p.pendingWG.Wait()
+}
@@ -1,6 +1,8 @@
 package trace
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"io"
 	"os"
@@ -21,8 +23,14 @@ const (
 
 	// Maximum number of traces in a fetch request. (You can set it via query param "limit")
 	MAX_TRACE_FETCH_NUM = 100
+
+	// Maximum number of pages of a Tempo search, i.e., at most MAX_TEMPO_SEARCH_PAGE_NUM * MAX_TRACE_FETCH_NUM traces are fetched at a time.
+	MAX_TEMPO_SEARCH_PAGE_NUM = 10
 )
 
+// ErrTraceNotFound is returned when the trace backend has no trace of the given ID, e.g., the trace is not queryable yet, or it is dropped by sampling.
+var ErrTraceNotFound = errors.New("trace not found")
+
 // TraceFetcher fetches traces from trace backend and parses them into Jaeger-style spans.
 type TraceFetcher interface {
 	// FetchFromPath fetches traces from a local file.
@@ -31,10 +39,15 @@ type TraceFetcher interface {
 	FetchFromPath(path string) ([]*SimplifiedTraceSpan, error)
 
 	// FetchAllFromRemote fetches all traces from a remote source.
-	FetchAllFromRemote() ([]*SimplifiedTrace, error)
+	FetchAllFromRemote(ctx context.Context) ([]*SimplifiedTrace, error)
 
 	// FetchOneByIDFromRemote fetches a trace by its ID from a remote source.
-	FetchOneByIDFromRemote(traceID string) (*SimplifiedTrace, error)
+	// It returns an error wrapping [ErrTraceNotFound] if the trace is not found.
+	FetchOneByIDFromRemote(ctx context.Context, traceID string) (*SimplifiedTrace, error)
+
+	// FetchByQueryFromRemote fetches traces matching the query from a remote source, e.g., traces of requests sent by the fuzzer only.
+	// It returns an empty list if no trace matches.
+	FetchByQueryFromRemote(ctx context.Context, query *TraceQuery) ([]*SimplifiedTrace, error)
 }
 
 // JaegerTraceFetcher represents a fetcher for Jaeger traces.
@@ -83,8 +96,8 @@ func (p *JaegerTraceFetcher) FetchFromPath(filePath string) ([]*SimplifiedTraceS
 
 // FetchAllFromRemote fetches all Jaeger traces from remote source.
 // It returns a list of traces, or an error if failed.
-func (p *JaegerTraceFetcher) FetchAllFromRemote() ([]*SimplifiedTrace, error) {
-	serviceNames, err := p.fetchAllServicesFromRemote()
+func (p *JaegerTraceFetcher) FetchAllFromRemote(ctx context.Context) ([]*SimplifiedTrace, error) {
+	serviceNames, err := p.fetchAllServicesFromRemote(ctx)
 	if err != nil {
 		log.Err(err).Msg("[JaegerTraceFetcher.FetchFromRemote] Failed to fetch services")
 		return nil, err
@@ -95,7 +108,7 @@ func (p *JaegerTraceFetcher) FetchAllFromRemote() ([]*SimplifiedTrace, error) {
 	}
 	traces := make([]*SimplifiedTrace, 0)
 	for _, serviceName := range serviceNames {
-		serviceTraces, err := p.fetchServiceTracesFromRemote(serviceName)
+		serviceTraces, err := p.fetchServiceTracesFromRemote(ctx, serviceName)
 		if err != nil {
 			log.Err(err).Msg("[JaegerTraceFetcher.FetchFromRemote] Failed to fetch traces")
 			return nil, err
@@ -114,15 +127,68 @@ func (p *JaegerTraceFetcher) FetchAllFromRemote() ([]*SimplifiedTrace, error) {
 
 // FetchOneByIDFromRemote fetches a Jaeger trace by its ID from remote source.
 // It returns a SimplifiedTrace or an error if failed.
-func (p *JaegerTraceFetcher) FetchOneByIDFromRemote(traceID string) (*SimplifiedTrace, error) {
-	return p.fetchTraceByIDFromRemote(traceID)
+func (p *JaegerTraceFetcher) FetchOneByIDFromRemote(ctx context.Context, traceID string) (*SimplifiedTrace, error) {
+	return p.fetchTraceByIDFromRemote(ctx, traceID)
+}
+
+// FetchByQueryFromRemote fetches Jaeger traces matching the query from remote source.
+// As Jaeger API requires the service to search traces of, traces of all services are searched if the service of the query is empty.
+// Attributes of the query are matched as tags, see [TraceQuery.GetJaegerTags].
+// It returns a list of traces, or an error if failed.
+func (p *JaegerTraceFetcher) FetchByQueryFromRemote(ctx context.Context, query *TraceQuery) ([]*SimplifiedTrace, error) {
+	serviceNames := []string{query.ServiceName}
+	if query.ServiceName == "" {
+		var err error
+		serviceNames, err = p.fetchAllServicesFromRemote(ctx)
+		if err != nil {
+			log.Err(err).Msg("[JaegerTraceFetcher.FetchByQueryFromRemote] Failed to fetch services")
+			return nil, err
+		}
+	}
+	tagsBytes, err := sonic.Marshal(query.GetJaegerTags())
+	if err != nil {
+		log.Err(err).Msg("[JaegerTraceFetcher.FetchByQueryFromRemote] Failed to marshal tags")
+		return nil, err
+	}
+	startTime, endTime := query.GetTimeRange()
+	traces := make([]*SimplifiedTrace, 0)
+	// A trace may be found by multiple services.
+	seenTraceIDs := make(map[string]struct{})
+	for _, serviceName := range serviceNames {
+		queryParams := map[string]string{
+			"limit":   strconv.Itoa(MAX_TRACE_FETCH_NUM),
+			"service": serviceName,
+			"tags":    string(tagsBytes),
+			"start":   strconv.FormatInt(startTime.UnixMicro(), 10),
+			"end":     strconv.FormatInt(endTime.UnixMicro(), 10),
+		}
+		if query.SpanName != "" {
+			queryParams["operation"] = query.SpanName
+		}
+		serviceTraces, err := p.searchTracesFromRemote(ctx, queryParams)
+		if err != nil {
+			log.Err(err).Msg("[JaegerTraceFetcher.FetchByQueryFromRemote] Failed to fetch traces")
+			return nil, err
+		}
+		for _, trace := range serviceTraces {
+			if trace == nil {
+				continue
+			}
+			if _, seen := seenTraceIDs[trace.TraceID]; seen {
+				continue
+			}
+			seenTraceIDs[trace.TraceID] = struct{}{}
+			traces = append(traces, trace)
+		}
+	}
+	return traces, nil
 }
 
 // fetchAllServicesFromRemote fetches all services from remote source.
 // It returns a list of service names, or an error if failed.
-func (p *JaegerTraceFetcher) fetchAllServicesFromRemote() ([]string, error) {
+func (p *JaegerTraceFetcher) fetchAllServicesFromRemote(ctx context.Context) ([]string, error) {
 	headers := map[string]string{}
-	statusCode, _, respBytes, err := p.FetcherClient.PerformGet("/api/services", headers, nil, nil)
+	statusCode, _, respBytes, err := p.FetcherClient.PerformGet(ctx, "/api/services", headers, nil, nil)
 	if err != nil {
 		log.Err(err).Msgf("[JaegerTraceFetcher.FetchAllServicesFromRemote] Failed to fetch services")
 		return nil, err
@@ -143,20 +209,26 @@ func (p *JaegerTraceFetcher) fetchAllServicesFromRemote() ([]string, error) {
 
 // fetchServiceTracesFromRemote fetches traces of a service from remote source.
 // It returns a list of traces, or an error if failed.
-func (p *JaegerTraceFetcher) fetchServiceTracesFromRemote(serviceName string) ([]*SimplifiedTrace, error) {
-	path := "/api/traces"
-	headers := map[string]string{}
+func (p *JaegerTraceFetcher) fetchServiceTracesFromRemote(ctx context.Context, serviceName string) ([]*SimplifiedTrace, error) {
 	queryParams := map[string]string{
 		"limit":   strconv.Itoa(MAX_TRACE_FETCH_NUM),
 		"service": serviceName,
 	}
-	statusCode, _, respBytes, err := p.FetcherClient.PerformGet(path, headers, nil, queryParams)
+	return p.searchTracesFromRemote(ctx, queryParams)
+}
+
+// searchTracesFromRemote searches traces by the query params (e.g., service, operation and tags) via Jaeger traces API.
+// It returns a list of traces, or an error if failed.
+func (p *JaegerTraceFetcher) searchTracesFromRemote(ctx context.Context, queryParams map[string]string) ([]*SimplifiedTrace, error) {
+	path := "/api/traces"
+	headers := map[string]string{}
+	statusCode, _, respBytes, err := p.FetcherClient.PerformGet(ctx, path, headers, nil, queryParams)
 	if err != nil {
-		log.Err(err).Msgf("[JaegerTraceFetcher.FetchServiceTracesFromRemote] Failed to fetch traces, path: %s, query params: %v", path, queryParams)
+		log.Err(err).Msgf("[JaegerTraceFetcher.searchTracesFromRemote] Failed to fetch traces, path: %s, query params: %v", path, queryParams)
 		return nil, err
 	}
 	if http.GetStatusCodeClass(statusCode) != consts.StatusOK {
-		log.Err(err).Msgf("[JaegerTraceFetcher.FetchServiceTracesFromRemote] Failed to fetch traces, statusCode: %d, path: %s, query params: %v", statusCode, path, queryParams)
+		log.Err(err).Msgf("[JaegerTraceFetcher.searchTracesFromRemote] Failed to fetch traces, statusCode: %d, path: %s, query params: %v", statusCode, path, queryParams)
 		return nil, err
 	}
 
@@ -164,7 +236,7 @@ func (p *JaegerTraceFetcher) fetchServiceTracesFromRemote(serviceName string) ([
 		Data []JaegerTrace `json:"data"`
 	}
 	if err := sonic.Unmarshal(respBytes, &jaegerTraceListResp); err != nil {
-		log.Err(err).Msgf("[JaegerTraceFetcher.FetchServiceTracesFromRemote] Failed to unmarshal Jaeger traces response")
+		log.Err(err).Msgf("[JaegerTraceFetcher.searchTracesFromRemote] Failed to unmarshal Jaeger traces response")
 		return nil, err
 	}
 	traces := make([]*SimplifiedTrace, 0)
@@ -176,17 +248,20 @@ func (p *JaegerTraceFetcher) fetchServiceTracesFromRemote(serviceName string) ([
 
 // fetchTraceByIDFromRemote fetches a Jaeger trace by its ID from a remote source.
 // It returns a SimplifiedTrace or an error if the fetch operation fails.
-func (p *JaegerTraceFetcher) fetchTraceByIDFromRemote(traceID string) (*SimplifiedTrace, error) {
+func (p *JaegerTraceFetcher) fetchTraceByIDFromRemote(ctx context.Context, traceID string) (*SimplifiedTrace, error) {
 	path := fmt.Sprintf("/api/traces/%s", traceID)
 	headers := map[string]string{}
-	statusCode, _, respBytes, err := p.FetcherClient.PerformGet(path, headers, nil, nil)
+	statusCode, _, respBytes, err := p.FetcherClient.PerformGet(ctx, path, headers, nil, nil)
 	if err != nil {
 		log.Err(err).Msgf("[JaegerTraceFetcher.FetchTraceByIDFromRemote] Failed to fetch trace, path: %s", path)
 		return nil, err
 	}
+	if statusCode == consts.StatusNotFound {
+		return nil, fmt.Errorf("%w: %s", ErrTraceNotFound, traceID)
+	}
 	if http.GetStatusCodeClass(statusCode) != consts.StatusOK {
-		log.Err(err).Msgf("[JaegerTraceFetcher.FetchTraceByIDFromRemote] Failed to fetch trace, statusCode: %d, path: %s", statusCode, path)
-		return nil, err
+		log.Error().Msgf("[JaegerTraceFetcher.FetchTraceByIDFromRemote] Failed to fetch trace, statusCode: %d, path: %s", statusCode, path)
+		return nil, fmt.Errorf("failed to fetch trace, statusCode: %d", statusCode)
 	}
 
 	var jaegerTraceResp struct {
@@ -197,9 +272,7 @@ func (p *JaegerTraceFetcher) fetchTraceByIDFromRemote(traceID string) (*Simplifi
 		return nil, err
 	}
 	if len(jaegerTraceResp.Data) == 0 {
-		err := fmt.Errorf("trace not found: %s", traceID)
-		log.Err(err).Msgf("[JaegerTraceFetcher.FetchTraceByIDFromRemote] Failed to fetch trace")
-		return nil, err
+		return nil, fmt.Errorf("%w: %s", ErrTraceNotFound, traceID)
 	}
 	return jaegerTraceResp.Data[0].ToSimplifiedTrace(), nil
 }
@@ -228,21 +301,112 @@ func (p *TempoTraceFetcher) FetchFromPath(filePath string) ([]*SimplifiedTraceSp
 }
 
 // FetchAllFromRemote fetches all Tempo traces from remote source.
+// Like [JaegerTraceFetcher.FetchAllFromRemote], only traces started within TRACE_FILTER_OUT_AGE are fetched, see [TempoTraceFetcher.FetchByQueryFromRemote].
 // It returns a list of traces, or an error if failed.
-func (p *TempoTraceFetcher) FetchAllFromRemote() ([]*SimplifiedTrace, error) {
-	return nil, fmt.Errorf("TempoTraceFetcher.FetchAllFromRemote is not implemented")
+func (p *TempoTraceFetcher) FetchAllFromRemote(ctx context.Context) ([]*SimplifiedTrace, error) {
+	return p.FetchByQueryFromRemote(ctx, &TraceQuery{})
+}
+
+// FetchByQueryFromRemote fetches Tempo traces matching the query from remote source.
+// Traces are searched by TraceQL (see [TraceQuery.ToTraceQL]) via Tempo search API in the time range of the query, and then fetched by ID.
+// As Tempo search API does not support cursors, results are paginated by time:
+// if a page is full, the next page is searched with the end of the time range moved to the start of the oldest trace in the page.
+// It returns a list of traces, or an error if failed.
+func (p *TempoTraceFetcher) FetchByQueryFromRemote(ctx context.Context, query *TraceQuery) ([]*SimplifiedTrace, error) {
+	traceQL := query.ToTraceQL()
+	startTime, endTime := query.GetTimeRange()
+	start := startTime.Unix()
+	end := endTime.Unix()
+	traceIDs := make([]string, 0)
+	seenTraceIDs := make(map[string]struct{})
+	for pageNum := 0; pageNum < MAX_TEMPO_SEARCH_PAGE_NUM; pageNum++ {
+		searchedTraces, err := p.searchTracesFromRemote(ctx, traceQL, start, end)
+		if err != nil {
+			log.Err(err).Msgf("[TempoTraceFetcher.FetchByQueryFromRemote] Failed to search traces, query: %s", traceQL)
+			return nil, err
+		}
+		oldestStart := end
+		newTraceCnt := 0
+		for _, searchedTrace := range searchedTraces {
+			if _, seen := seenTraceIDs[searchedTrace.TraceID]; seen {
+				continue
+			}
+			seenTraceIDs[searchedTrace.TraceID] = struct{}{}
+			traceIDs = append(traceIDs, searchedTrace.TraceID)
+			newTraceCnt++
+			if startTimeUnixNano, err := strconv.ParseInt(searchedTrace.StartTimeUnixNano, 10, 64); err == nil {
+				oldestStart = min(oldestStart, time.Unix(0, startTimeUnixNano).Unix())
+			}
+		}
+		// The last page is not full, or it has no new trace (e.g., more traces than a page start in the same second).
+		if len(searchedTraces) < MAX_TRACE_FETCH_NUM || newTraceCnt == 0 {
+			break
+		}
+		end = oldestStart
+	}
+
+	traces := make([]*SimplifiedTrace, 0, len(traceIDs))
+	for _, traceID := range traceIDs {
+		trace, err := p.FetchOneByIDFromRemote(ctx, traceID)
+		// The trace may be dropped after it is searched, e.g., by retention.
+		if errors.Is(err, ErrTraceNotFound) {
+			continue
+		}
+		if err != nil {
+			log.Err(err).Msgf("[TempoTraceFetcher.FetchByQueryFromRemote] Failed to fetch trace, traceID: %s", traceID)
+			return nil, err
+		}
+		if trace == nil {
+			continue
+		}
+		traces = append(traces, trace)
+	}
+	return traces, nil
+}
+
+// searchTracesFromRemote searches traces matching the TraceQL query and started in the time range [start, end] (in Unix seconds), via Tempo search API.
+// At most MAX_TRACE_FETCH_NUM traces are returned.
+// It returns a list of metadata of traces, or an error if failed.
+func (p *TempoTraceFetcher) searchTracesFromRemote(ctx context.Context, query string, start, end int64) ([]TempoTraceSearchMetadata, error) {
+	path := "/api/search"
+	headers := map[string]string{}
+	queryParams := map[string]string{
+		"q":     query,
+		"start": strconv.FormatInt(start, 10),
+		"end":   strconv.FormatInt(end, 10),
+		"limit": strconv.Itoa(MAX_TRACE_FETCH_NUM),
+	}
+	statusCode, _, respBytes, err := p.FetcherClient.PerformGet(ctx, path, headers, nil, queryParams)
+	if err != nil {
+		log.Err(err).Msgf("[TempoTraceFetcher.searchTracesFromRemote] Failed to search traces, path: %s, query params: %v", path, queryParams)
+		return nil, err
+	}
+	if http.GetStatusCodeClass(statusCode) != consts.StatusOK {
+		log.Error().Msgf("[TempoTraceFetcher.searchTracesFromRemote] Failed to search traces, statusCode: %d, path: %s, query params: %v", statusCode, path, queryParams)
+		return nil, fmt.Errorf("failed to search traces, statusCode: %d", statusCode)
+	}
+
+	var searchResp TempoSearchResponse
+	if err := sonic.Unmarshal(respBytes, &searchResp); err != nil {
+		log.Err(err).Msgf("[TempoTraceFetcher.searchTracesFromRemote] Failed to unmarshal Tempo search response")
+		return nil, err
+	}
+	return searchResp.Traces, nil
 }
 
 // FetchOneByIDFromRemote fetches a Tempo trace by its ID from remote source.
 // It returns a SimplifiedTrace or an error if failed.
-func (p *TempoTraceFetcher) FetchOneByIDFromRemote(traceID string) (*SimplifiedTrace, error) {
+func (p *TempoTraceFetcher) FetchOneByIDFromRemote(ctx context.Context, traceID string) (*SimplifiedTrace, error) {
 	path := fmt.Sprintf("/api/traces/%s", traceID)
 	headers := map[string]string{}
-	statusCode, _, respBytes, err := p.FetcherClient.PerformGet(path, headers, nil, nil)
+	statusCode, _, respBytes, err := p.FetcherClient.PerformGet(ctx, path, headers, nil, nil)
 	if err != nil {
 		log.Err(err).Msgf("[TempoTraceFetcher.FetchOneByIDFromRemote] Failed to fetch trace, path: %s", path)
 		return nil, err
 	}
+	if statusCode == consts.StatusNotFound {
+		return nil, fmt.Errorf("%w: %s", ErrTraceNotFound, traceID)
+	}
 	if http.GetStatusCodeClass(statusCode) != consts.StatusOK {
 		log.Error().Msgf("[TempoTraceFetcher.FetchOneByIDFromRemote] Failed to fetch trace, statusCode: %d, path: %s", statusCode, path)
 		return nil, fmt.Errorf("failed to fetch trace, statusCode: %d", statusCode)
@@ -254,9 +418,7 @@ func (p *TempoTraceFetcher) FetchOneByIDFromRemote(traceID string) (*SimplifiedT
 		return nil, err
 	}
 	if len(tempoTrace.Batches) == 0 {
-		err := fmt.Errorf("trace not found: %s", traceID)
-		log.Err(err).Msgf("[TempoTraceFetcher.FetchOneByIDFromRemote] Failed to fetch trace")
-		return nil, err
+		return nil, fmt.Errorf("%w: %s", ErrTraceNotFound, traceID)
 	}
 	return tempoTrace.ToSimplifiedTrace(), nil
 }
@@ -1,7 +1,11 @@
 package trace
 
 import (
+	"context"
+	"errors"
 	"resttracefuzzer/internal/config"
+	"slices"
+	"sync"
 	"time"
 
 	"github.com/rs/zerolog/log"
@@ -17,6 +21,19 @@ type TraceManager struct {
 	// TraceDBs is the databases for traces.
 	// TraceDB is a interface, and the implementation can be decided based on your needs.
 	TraceDBs []TraceDB
+
+	// FetchBreaker is the circuit breaker for fetching traces by ID, which switches to degradation mode if the trace backend becomes unavailable.
+	FetchBreaker *TraceFetchBreaker
+
+	// WaitEstimator estimates how long to wait before a trace becomes queryable in the trace backend.
+	WaitEstimator *TraceWaitEstimator
+
+	// FetchPool fetches traces by ID concurrently, see [TraceFetchPool].
+	// It is nil if traces are fetched synchronously, i.e., the number of trace fetch workers is not positive.
+	FetchPool *TraceFetchPool
+
+	// traceDBMu serializes upserts to TraceDBs, as traces may be fetched concurrently by FetchPool.
+	traceDBMu sync.Mutex
 }
 
 // NewTraceManager creates a new TraceManager.
@@ -35,20 +52,42 @@ func NewTraceManager(
 		return nil
 	}
 	
-	return &TraceManager{
-		TraceFetcher: traceFetcher,
+	fetchBreaker := NewTraceFetchBreaker(
+		config.GlobalConfig.TraceFetchFailureThreshold,
+		time.Duration(config.GlobalConfig.TraceFetchProbeInterval)*time.Millisecond,
+	)
+
+	waitEstimator := NewTraceWaitEstimator(
+		config.GlobalConfig.TraceBackendType,
+		config.GlobalConfig.TraceFetchAdaptiveWait,
+		time.Duration(config.GlobalConfig.TraceFetchWaitTime)*time.Millisecond,
+		time.Duration(config.GlobalConfig.TraceFetchMaxWaitTime)*time.Millisecond,
+		float64(config.GlobalConfig.TraceFetchWaitPercentile),
+		config.GlobalConfig.TraceFetchRetryBudget,
+	)
+
+	m := &TraceManager{
+		TraceFetcher:  traceFetcher,
 		TraceDBs:      traceDBs,
+		FetchBreaker:  fetchBreaker,
+		WaitEstimator: waitEstimator,
 	}
+	if config.GlobalConfig.TraceFetchWorkers > 0 {
+		m.FetchPool = NewTraceFetchPool(config.GlobalConfig.TraceFetchWorkers, m.PullTraceByIDAndConvert)
+	}
+	return m
 }
 
 // PullTraces pulls traces from the trace source(e.g., Jaeger), and update local data.
-func (m *TraceManager) PullTraces() error {
+func (m *TraceManager) PullTraces(ctx context.Context) error {
 	// Fetch traces from the trace source.
-	traces, err := m.TraceFetcher.FetchAllFromRemote()
+	traces, err := m.TraceFetcher.FetchAllFromRemote(ctx)
 	if err != nil {
 		log.Err(err).Msg("[TraceManager.PullTraces] Failed to fetch traces from remote")
 		return err
 	}
+	m.traceDBMu.Lock()
+	defer m.traceDBMu.Unlock()
 	for _, traceDB := range m.TraceDBs {
 		err = traceDB.BatchUpsert(traces)
 		if err != nil {
@@ -60,14 +99,16 @@ func (m *TraceManager) PullTraces() error {
 }
 
 // PullTracesAndReturn pulls traces from the trace source(e.g., Jaeger), and return the traces.
-func (m *TraceManager) PullTracesAndReturn() ([]*SimplifiedTrace, error) {
+func (m *TraceManager) PullTracesAndReturn(ctx context.Context) ([]*SimplifiedTrace, error) {
 	// Fetch traces from the trace source.
-	traces, err := m.TraceFetcher.FetchAllFromRemote()
+	traces, err := m.TraceFetcher.FetchAllFromRemote(ctx)
 	if err != nil {
 		log.Err(err).Msg("[TraceManager.PullTracesAndReturn] Failed to fetch traces from remote")
 		return nil, err
 	}
 
+	m.traceDBMu.Lock()
+	defer m.traceDBMu.Unlock()
 	for _, traceDB := range m.TraceDBs {
 		err = traceDB.BatchUpsert(traces)
 		if err != nil {
@@ -78,18 +119,83 @@ func (m *TraceManager) PullTracesAndReturn() ([]*SimplifiedTrace, error) {
 	return traces, nil
 }
 
+// PullTracesByQueryAndReturn pulls traces matching the query from the trace source(e.g., Jaeger), and return the traces.
+// Unlike [TraceManager.PullTracesAndReturn], only targeted traces are pulled, e.g., traces of requests with the trace ID header of the fuzzer (see [NewRequestHeaderTraceQuery]).
+func (m *TraceManager) PullTracesByQueryAndReturn(ctx context.Context, query *TraceQuery) ([]*SimplifiedTrace, error) {
+	traces, err := m.TraceFetcher.FetchByQueryFromRemote(ctx, query)
+	if err != nil {
+		log.Err(err).Msg("[TraceManager.PullTracesByQueryAndReturn] Failed to fetch traces from remote")
+		return nil, err
+	}
+
+	m.traceDBMu.Lock()
+	defer m.traceDBMu.Unlock()
+	for _, traceDB := range m.TraceDBs {
+		err = traceDB.BatchUpsert(traces)
+		if err != nil {
+			log.Err(err).Msg("[TraceManager.PullTracesByQueryAndReturn] Failed to insert traces")
+			return nil, err
+		}
+	}
+	return traces, nil
+}
+
 // PullTraceByIDAndReturn pulls a trace by ID from the trace source(e.g., Jaeger), and return the trace.
-func (m *TraceManager) PullTraceByIDAndReturn(traceID string) (*SimplifiedTrace, error) {
-	// Wait a short time before fetching the trace, as the trace may not be
-	// available immediately after the request.
-	// TODO: a more sufficient way to wait for the trace to be available. @xunzhou24
-	time.Sleep(time.Duration(config.GlobalConfig.TraceFetchWaitTime) * time.Millisecond)
-	trace, err := m.TraceFetcher.FetchOneByIDFromRemote(traceID)
+// It should be called right after the request, as it waits for the trace to become queryable, see [TraceWaitEstimator];
+// a trace not found is fetched again with backoff until the max wait or the retry budget is exhausted, whether the wait is adaptive or not.
+// The wait before fetching is interrupted if ctx is done.
+// If the trace backend is considered unavailable by FetchBreaker, it returns [ErrTraceFetchDegraded] without fetching.
+func (m *TraceManager) PullTraceByIDAndReturn(ctx context.Context, traceID string) (*SimplifiedTrace, error) {
+	if !m.FetchBreaker.Allow() {
+		return nil, ErrTraceFetchDegraded
+	}
+	// Wait before fetching the trace, as the trace may not be available immediately after the request.
+	startTime := time.Now()
+	wait := m.WaitEstimator.GetWait()
+	var trace *SimplifiedTrace
+	var err error
+	for fetchCnt := 1; ; fetchCnt++ {
+		if !m.waitBeforeFetch(ctx, wait) {
+			log.Warn().Msgf("[TraceManager.PullTraceByIDAndReturn] Context done before fetching trace, traceID: %s", traceID)
+			m.FetchBreaker.ReleaseProbe()
+			return nil, ctx.Err()
+		}
+		trace, err = m.TraceFetcher.FetchOneByIDFromRemote(ctx, traceID)
+		if err == nil {
+			m.WaitEstimator.RecordQueryable(time.Since(startTime), fetchCnt == 1)
+			break
+		}
+		if !errors.Is(err, ErrTraceNotFound) {
+			break
+		}
+		wait = m.WaitEstimator.GetPollInterval(fetchCnt)
+		remainingWait := m.WaitEstimator.MaxWait - time.Since(startTime)
+		if remainingWait <= 0 {
+			m.WaitEstimator.RecordMissing()
+			break
+		}
+		if !m.WaitEstimator.TryAcquireRetry() {
+			log.Debug().Msgf("[TraceManager.PullTraceByIDAndReturn] Retry budget exhausted, consider trace missing, traceID: %s", traceID)
+			break
+		}
+		// The last fetch is at the max wait.
+		wait = min(wait, remainingWait)
+	}
+	// Failures caused by cancellation are not the trace backend's fault, and neither are traces not found, as the backend responds.
+	if err == nil || errors.Is(err, ErrTraceNotFound) {
+		m.FetchBreaker.RecordSuccess()
+	} else if ctx.Err() == nil {
+		m.FetchBreaker.RecordFailure()
+	} else {
+		m.FetchBreaker.ReleaseProbe()
+	}
 	if err != nil || trace == nil {
 		log.Err(err).Msgf("[TraceManager.PullTraceByIDAndReturn] Failed to fetch trace from remote, traceID: %s", traceID)
 		return nil, err
 	}
 
+	m.traceDBMu.Lock()
+	defer m.traceDBMu.Unlock()
 	for _, traceDB := range m.TraceDBs {
 		err = traceDB.Upsert(trace)
 		if err != nil {
@@ -100,6 +206,84 @@ func (m *TraceManager) PullTraceByIDAndReturn(traceID string) (*SimplifiedTrace,
 	return trace, nil
 }
 
+// PullTraceByIDAndConvert pulls a trace by ID like [TraceManager.PullTraceByIDAndReturn], and returns the trace with the call infos converted from it.
+// It is safe to call concurrently, e.g., by FetchPool.
+func (m *TraceManager) PullTraceByIDAndConvert(ctx context.Context, traceID string) (*SimplifiedTrace, []*CallInfo, error) {
+	trace, err := m.PullTraceByIDAndReturn(ctx, traceID)
+	if err != nil {
+		return nil, nil, err
+	}
+	// During the conversion, spans of kind 'internal' would be ignored, as we only care about the calls between services.
+	callInfos, err := m.BatchConvertTrace2CallInfos([]*SimplifiedTrace{trace})
+	if err != nil {
+		log.Err(err).Msgf("[TraceManager.PullTraceByIDAndConvert] Failed to convert trace to call infos, traceID: %s", traceID)
+		return nil, nil, err
+	}
+	return trace, callInfos, nil
+}
+
+// PullTraceByQueryAndConvert pulls the trace matching the query, e.g., of a request carrying a header unique to it (see [NewRequestHeaderTraceQuery]) whose trace ID is unknown,
+// and returns the trace with the call infos converted from it.
+// Like [TraceManager.PullTraceByIDAndReturn], it waits for the trace to become queryable before querying, and returns [ErrTraceFetchDegraded] if the trace backend is considered unavailable.
+// It returns [ErrTraceNotFound] if no trace matches, or the earliest trace if several do.
+func (m *TraceManager) PullTraceByQueryAndConvert(ctx context.Context, query *TraceQuery) (*SimplifiedTrace, []*CallInfo, error) {
+	if !m.FetchBreaker.Allow() {
+		return nil, nil, ErrTraceFetchDegraded
+	}
+	if !m.waitBeforeFetch(ctx, m.WaitEstimator.GetWait()) {
+		m.FetchBreaker.ReleaseProbe()
+		return nil, nil, ctx.Err()
+	}
+	traces, err := m.PullTracesByQueryAndReturn(ctx, query)
+	if err != nil {
+		if ctx.Err() == nil {
+			m.FetchBreaker.RecordFailure()
+		} else {
+			m.FetchBreaker.ReleaseProbe()
+		}
+		return nil, nil, err
+	}
+	m.FetchBreaker.RecordSuccess()
+	if len(traces) == 0 {
+		return nil, nil, ErrTraceNotFound
+	}
+	trace := slices.MinFunc(traces, func(a, b *SimplifiedTrace) int { return a.StartTime.Compare(b.StartTime) })
+	callInfos, err := m.BatchConvertTrace2CallInfos([]*SimplifiedTrace{trace})
+	if err != nil {
+		log.Err(err).Msgf("[TraceManager.PullTraceByQueryAndConvert] Failed to convert trace to call infos, traceID: %s", trace.TraceID)
+		return nil, nil, err
+	}
+	return trace, callInfos, nil
+}
+
+// waitBeforeFetch waits for the given duration, and returns false if ctx is done before that.
+func (m *TraceManager) waitBeforeFetch(ctx context.Context, wait time.Duration) bool {
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
+
+// DropOldTraces drops the given ratio (0-1) of the oldest traces in trace DBs holding traces in memory, e.g., to reduce memory usage.
+// Other trace DBs (e.g., RawTraceFileSaver) are not affected.
+// It returns the number of dropped traces.
+func (m *TraceManager) DropOldTraces(ratio float64) int {
+	m.traceDBMu.Lock()
+	defer m.traceDBMu.Unlock()
+	droppedCnt := 0
+	for _, traceDB := range m.TraceDBs {
+		if inMemoryTraceDB, ok := traceDB.(*InMemoryTraceDB); ok {
+			droppedCnt += inMemoryTraceDB.DropOldTraces(ratio)
+		}
+	}
+	log.Info().Msgf("[TraceManager.DropOldTraces] Dropped %d traces", droppedCnt)
+	return droppedCnt
+}
+
 // BatchConvertTrace2CallInfos returns the call information (list) between services.
 func (m *TraceManager) BatchConvertTrace2CallInfos(traces []*SimplifiedTrace) ([]*CallInfo, error) {
 	res := make([]*CallInfo, 0)
@@ -134,6 +318,14 @@ func (m *TraceManager) convertTrace2CallInfos(trace *SimplifiedTrace) ([]*CallIn
 		if span.ParentID != "" {
 			parentSpan = trace.SpanMap[span.ParentID]
 		}
+		// A consumer span without parent in the trace consumes a message published by the fuzzer (with trace context in message headers),
+		// so it is a call from outside of the system to the consumer service.
+		if parentSpan == nil && span.SpanKind == CONSUMER {
+			if methodTraceName, ok := span.RetrieveCalledMethod(); ok {
+				res = append(res, NewCallInfo("", span.ServiceName, methodTraceName))
+			}
+			continue
+		}
 		// Invalid parent span, or the parent span is of kind 'internal'.
 		if parentSpan == nil || parentSpan.SpanKind == INTERNAL {
 			continue
@@ -0,0 +1,115 @@
+package trace
+
+import (
+	"maps"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bytedance/sonic"
+)
+
+// TraceQLAttributeScopes are the scope prefixes of attributes in TraceQL, see [official TraceQL doc](https://grafana.com/docs/tempo/latest/traceql/).
+var TraceQLAttributeScopes = []string{"resource.", "span."}
+
+// TraceQuery is a query of traces in the trace backend, for targeted trace retrieval, see [TraceFetcher.FetchByQueryFromRemote].
+// Empty fields do not filter traces.
+type TraceQuery struct {
+	// ServiceName is the name of the service, which some span of the trace belongs to.
+	ServiceName string
+
+	// SpanName is the name (i.e., operation name in Jaeger) of some span of the trace.
+	SpanName string
+
+	// Attributes are the attributes (i.e., tags in Jaeger) of some span of the trace, which should all match (as strings).
+	// For Tempo, keys can be prefixed with the scope (see [TraceQLAttributeScopes]), e.g., `span.http.route`, or attributes of any scope are matched.
+	Attributes map[string]string
+
+	// ArrayAttributeKeys are keys of Attributes recorded as string arrays, e.g., `http.request.header.*` by OpenTelemetry semantic conventions,
+	// whose values match if any element equals the value of the query.
+	ArrayAttributeKeys []string
+
+	// StartTime and EndTime are the time range, in which the trace starts.
+	// The range is the last TRACE_FILTER_OUT_AGE if StartTime is zero, and ends now if EndTime is zero.
+	StartTime time.Time
+	EndTime   time.Time
+}
+
+// NewRequestHeaderTraceQuery creates a TraceQuery matching traces of requests with the given header, e.g., the trace ID header of the fuzzer.
+// The header should be captured as a span attribute by the instrumentation, i.e., `http.request.header.{key}` by OpenTelemetry semantic conventions,
+// which is an array of header values.
+func NewRequestHeaderTraceQuery(headerKey, headerValue string) *TraceQuery {
+	attributeKey := "span.http.request.header." + strings.ToLower(headerKey)
+	return &TraceQuery{
+		Attributes: map[string]string{
+			attributeKey: headerValue,
+		},
+		ArrayAttributeKeys: []string{attributeKey},
+	}
+}
+
+// GetTimeRange returns the time range of the query, with defaults resolved, see [TraceQuery.StartTime].
+func (q *TraceQuery) GetTimeRange() (time.Time, time.Time) {
+	endTime := q.EndTime
+	if endTime.IsZero() {
+		endTime = time.Now()
+	}
+	startTime := q.StartTime
+	if startTime.IsZero() {
+		startTime = endTime.Add(-TRACE_FILTER_OUT_AGE)
+	}
+	return startTime, endTime
+}
+
+// ToTraceQL converts the query (except the time range) to a TraceQL query, e.g., `{resource.service.name = "cart" && span.http.route = "/cart"}`.
+// All conditions are in a single spanset, i.e., they are matched by the same span.
+// It returns `{}`, i.e., matching all traces, if there is no condition.
+// Array attributes (see [TraceQuery.ArrayAttributeKeys]) are matched by regex, which matches any element of arrays,
+// as well as arrays stringified by the pipeline, e.g., `["value"]`.
+func (q *TraceQuery) ToTraceQL() string {
+	conditions := make([]string, 0, len(q.Attributes)+2)
+	if q.ServiceName != "" {
+		conditions = append(conditions, "resource.service.name = "+strconv.Quote(q.ServiceName))
+	}
+	if q.SpanName != "" {
+		conditions = append(conditions, "name = "+strconv.Quote(q.SpanName))
+	}
+	// Sort keys, so that the query is deterministic.
+	for _, key := range slices.Sorted(maps.Keys(q.Attributes)) {
+		attribute := key
+		if !slices.ContainsFunc(TraceQLAttributeScopes, func(scope string) bool { return strings.HasPrefix(key, scope) }) {
+			// Unscoped attributes match attributes of any scope.
+			attribute = "." + key
+		}
+		if slices.Contains(q.ArrayAttributeKeys, key) {
+			conditions = append(conditions, attribute+" =~ "+strconv.Quote(".*"+regexp.QuoteMeta(q.Attributes[key])+".*"))
+			continue
+		}
+		conditions = append(conditions, attribute+" = "+strconv.Quote(q.Attributes[key]))
+	}
+	return "{" + strings.Join(conditions, " && ") + "}"
+}
+
+// GetJaegerTags returns the attributes of the query as Jaeger tags, with scope prefixes (see [TraceQLAttributeScopes]) removed.
+// Values of array attributes (see [TraceQuery.ArrayAttributeKeys]) are single-element JSON arrays, e.g., `["value"]`,
+// as Jaeger stores array attributes as JSON strings, and matches tags exactly.
+func (q *TraceQuery) GetJaegerTags() map[string]string {
+	tags := make(map[string]string, len(q.Attributes))
+	for key, value := range q.Attributes {
+		if slices.Contains(q.ArrayAttributeKeys, key) {
+			if arrayValue, err := sonic.MarshalString([]string{value}); err == nil {
+				value = arrayValue
+			}
+		}
+		for _, scope := range TraceQLAttributeScopes {
+			if unscopedKey, found := strings.CutPrefix(key, scope); found {
+				key = unscopedKey
+				break
+			}
+		}
+		tags[key] = value
+	}
+	return tags
+}
@@ -0,0 +1,259 @@
+package trace
+
+import (
+	"math/rand/v2"
+	"resttracefuzzer/pkg/utils"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	// minTraceFetchWait is the lower bound of the adaptive wait before fetching a trace.
+	minTraceFetchWait = 50 * time.Millisecond
+
+	// minTraceFetchPollInterval is the lower bound of the interval between fetches of a trace not queryable yet.
+	minTraceFetchPollInterval = 50 * time.Millisecond
+
+	// traceWaitDecreaseRatio is the ratio the adaptive wait is multiplied by, once a trace is queryable at the first fetch.
+	traceWaitDecreaseRatio = 0.9
+
+	// traceWaitWindowSize is the number of the most recent delays kept, which the adaptive wait and reported percentiles are estimated from.
+	traceWaitWindowSize = 100
+
+	// maxTraceFetchPollIntervalMultiplier bounds the exponential backoff between fetches of a trace, i.e., the interval is at most this times the base one.
+	maxTraceFetchPollIntervalMultiplier = 8
+
+	// minTraceFetchRetryBudget is the number of retries allowed in addition to the retry budget, so that retries are allowed before traces are fetched.
+	minTraceFetchRetryBudget = 10
+)
+
+// TraceWaitEstimator estimates how long to wait after a request before its trace becomes queryable in the trace backend,
+// as the delay (e.g., batching of exporters and ingestion of backends) differs among backends and deployments.
+// A trace not queryable yet is fetched again until MaxWait, and if it is adaptive, the delay observed adjusts the wait:
+//   - if the trace is queryable at the first fetch, the wait is decreased by [traceWaitDecreaseRatio], to probe a shorter one;
+//   - otherwise, the wait is set to the Percentile of the most recent delays observed, so that most traces are queryable at the first fetch.
+//
+// Fetches of a trace not queryable yet are retried with bounded exponential backoff and jitter (see [TraceWaitEstimator.GetPollInterval]),
+// and limited by RetryBudget across traces, so that a slow trace backend is not flooded with retries.
+//
+// Retries are independent of whether it is adaptive: if it is not, the wait is always InitialWait, and traces are still fetched again until MaxWait.
+// They are disabled if MaxWait is InitialWait (by default), i.e., traces are fetched once.
+type TraceWaitEstimator struct {
+	// Backend is the type of the trace backend, e.g., Jaeger or Tempo.
+	Backend string
+
+	// Adaptive indicates whether the wait is adjusted by delays observed.
+	Adaptive bool
+
+	// InitialWait is the wait before any delay is observed.
+	InitialWait time.Duration
+
+	// MaxWait is the max time to wait for a trace to become queryable, after which it is considered missing.
+	MaxWait time.Duration
+
+	// Percentile is the percentile (0-100) of recent delays the wait is set to.
+	Percentile float64
+
+	// RetryBudget is the max average number of retries per trace, i.e., at most RetryBudget * (number of traces fetched) + [minTraceFetchRetryBudget] retries in total.
+	// A trace not queryable once the budget is exhausted is considered missing. Retries are unlimited if it is not positive.
+	RetryBudget int
+
+	// Rand is the random number generator of jitter, which is independent of [utils.SharedRand] by default (see [utils.NewIndependentRand]),
+	// as the number of retries depends on the timing of the trace backend, and would break reproducibility of seeded runs.
+	Rand *rand.Rand
+
+	// retryCount is the number of retries of fetches.
+	retryCount int
+
+	// retryBudgetExhaustedCount is the number of traces considered missing as the retry budget is exhausted.
+	retryBudgetExhaustedCount int
+
+	// currentWait is the current wait before fetching a trace.
+	currentWait time.Duration
+
+	// delays are the most recent delays observed (at most [traceWaitWindowSize]), i.e., the time until traces became queryable.
+	// Once full, it is a ring buffer, where nextDelayIndex is the index of the oldest delay, to be overwritten next.
+	delays []time.Duration
+
+	// nextDelayIndex is the index of delays the next delay observed is written to, once delays is full.
+	nextDelayIndex int
+
+	// observedCount is the number of all delays observed, including those no longer in delays.
+	observedCount int
+
+	// maxDelay is the max of all delays observed.
+	maxDelay time.Duration
+
+	// missingCount is the number of traces not queryable within MaxWait.
+	missingCount int
+
+	// mu protects the states above.
+	mu sync.Mutex
+}
+
+// TraceWaitStats is the distribution of delays observed until traces became queryable, for reporting.
+type TraceWaitStats struct {
+	// Backend is the type of the trace backend.
+	Backend string `json:"backend"`
+
+	// Adaptive indicates whether the wait is adjusted by delays observed.
+	Adaptive bool `json:"adaptive"`
+
+	// ObservedCount is the number of traces observed queryable.
+	ObservedCount int `json:"observedCount"`
+
+	// MissingCount is the number of traces not queryable within the max wait, or before the retry budget is exhausted.
+	MissingCount int `json:"missingCount"`
+
+	// RetryCount is the number of retries of fetches of traces not queryable yet.
+	RetryCount int `json:"retryCount"`
+
+	// RetryBudgetExhaustedCount is the number of traces considered missing as the retry budget is exhausted.
+	RetryBudgetExhaustedCount int `json:"retryBudgetExhaustedCount"`
+
+	// P50Ms is the median of recent delays (see [traceWaitWindowSize]), in milliseconds.
+	P50Ms float64 `json:"p50Ms"`
+
+	// P90Ms is the 90th percentile of recent delays, in milliseconds.
+	P90Ms float64 `json:"p90Ms"`
+
+	// P99Ms is the 99th percentile of recent delays, in milliseconds.
+	P99Ms float64 `json:"p99Ms"`
+
+	// MaxMs is the max of all delays, in milliseconds.
+	MaxMs float64 `json:"maxMs"`
+
+	// FinalWaitMs is the wait before fetching a trace at the end, in milliseconds.
+	FinalWaitMs float64 `json:"finalWaitMs"`
+}
+
+// NewTraceWaitEstimator creates a new TraceWaitEstimator.
+// Invalid values are replaced by valid ones: maxWait is at least initialWait (i.e., traces are not fetched again if it is 0), and percentile is 90 if not in (0, 100].
+func NewTraceWaitEstimator(backend string, adaptive bool, initialWait, maxWait time.Duration, percentile float64, retryBudget int) *TraceWaitEstimator {
+	if initialWait < 0 {
+		log.Warn().Msgf("[NewTraceWaitEstimator] Invalid initial wait %v, use 0 instead", initialWait)
+		initialWait = 0
+	}
+	if maxWait > 0 && maxWait < initialWait {
+		log.Warn().Msgf("[NewTraceWaitEstimator] Max wait %v is less than initial wait %v, use %v instead", maxWait, initialWait, initialWait)
+	}
+	maxWait = max(maxWait, initialWait)
+	if percentile <= 0 || percentile > 100 {
+		log.Warn().Msgf("[NewTraceWaitEstimator] Invalid percentile %v, use 90 instead", percentile)
+		percentile = 90
+	}
+	return &TraceWaitEstimator{
+		Backend:     backend,
+		Adaptive:    adaptive,
+		InitialWait: initialWait,
+		MaxWait:     maxWait,
+		Percentile:  percentile,
+		RetryBudget: retryBudget,
+		Rand:        utils.NewIndependentRand(),
+		currentWait: initialWait,
+		delays:      make([]time.Duration, 0, traceWaitWindowSize),
+	}
+}
+
+// GetWait returns the time to wait after a request before fetching its trace.
+func (e *TraceWaitEstimator) GetWait() time.Duration {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.currentWait
+}
+
+// GetPollInterval returns the interval before the retryCnt-th (from 1) retry of fetching a trace not queryable yet.
+// The interval backs off exponentially from a quarter of the current wait, i.e., it doubles per retry, up to [maxTraceFetchPollIntervalMultiplier] times that,
+// with equal jitter, i.e., it is uniformly random in [interval/2, interval), so that retries of traces of concurrent requests do not hit the backend at once.
+func (e *TraceWaitEstimator) GetPollInterval(retryCnt int) time.Duration {
+	baseInterval := max(e.GetWait()/4, minTraceFetchPollInterval)
+	maxInterval := baseInterval * maxTraceFetchPollIntervalMultiplier
+	interval := baseInterval
+	for i := 1; i < retryCnt && interval < maxInterval; i++ {
+		interval *= 2
+	}
+	interval = min(interval, maxInterval)
+	return interval/2 + time.Duration(e.Rand.Int64N(int64(interval/2)))
+}
+
+// TryAcquireRetry returns whether a fetch of a trace not queryable yet can be retried within RetryBudget, and counts the retry if so.
+// The trace is recorded missing if not.
+func (e *TraceWaitEstimator) TryAcquireRetry() bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	fetchedCount := e.observedCount + e.missingCount
+	if e.RetryBudget > 0 && e.retryCount >= e.RetryBudget*fetchedCount+minTraceFetchRetryBudget {
+		e.retryBudgetExhaustedCount++
+		e.missingCount++
+		return false
+	}
+	e.retryCount++
+	return true
+}
+
+// RecordQueryable records the delay until a trace became queryable, where isFirstFetch tells whether it is queryable at the first fetch.
+func (e *TraceWaitEstimator) RecordQueryable(delay time.Duration, isFirstFetch bool) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.observedCount++
+	e.maxDelay = max(e.maxDelay, delay)
+	if len(e.delays) < traceWaitWindowSize {
+		e.delays = append(e.delays, delay)
+	} else {
+		e.delays[e.nextDelayIndex] = delay
+		e.nextDelayIndex = (e.nextDelayIndex + 1) % traceWaitWindowSize
+	}
+	if !e.Adaptive {
+		return
+	}
+	if isFirstFetch {
+		e.currentWait = max(time.Duration(float64(e.currentWait)*traceWaitDecreaseRatio), minTraceFetchWait)
+		return
+	}
+	e.currentWait = min(max(getDurationPercentile(e.delays, e.Percentile), minTraceFetchWait), e.MaxWait)
+	log.Debug().Msgf("[TraceWaitEstimator.RecordQueryable] Trace became queryable after %v, adjust wait of %s to %v", delay, e.Backend, e.currentWait)
+}
+
+// RecordMissing records a trace not queryable within MaxWait, e.g., it is dropped by sampling.
+func (e *TraceWaitEstimator) RecordMissing() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.missingCount++
+}
+
+// GetStats returns the distribution of delays observed.
+func (e *TraceWaitEstimator) GetStats() *TraceWaitStats {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return &TraceWaitStats{
+		Backend:                   e.Backend,
+		Adaptive:                  e.Adaptive,
+		ObservedCount:             e.observedCount,
+		MissingCount:              e.missingCount,
+		RetryCount:                e.retryCount,
+		RetryBudgetExhaustedCount: e.retryBudgetExhaustedCount,
+		P50Ms:                     durationToMs(getDurationPercentile(e.delays, 50)),
+		P90Ms:                     durationToMs(getDurationPercentile(e.delays, 90)),
+		P99Ms:                     durationToMs(getDurationPercentile(e.delays, 99)),
+		MaxMs:                     durationToMs(e.maxDelay),
+		FinalWaitMs:               durationToMs(e.currentWait),
+	}
+}
+
+// getDurationPercentile returns the percentile (in (0, 100]) of durations, see [utils.GetPercentile].
+func getDurationPercentile(durations []time.Duration, percentile float64) time.Duration {
+	sorted := slices.Sorted(slices.Values(durations))
+	sortedValues := make([]float64, 0, len(sorted))
+	for _, duration := range sorted {
+		sortedValues = append(sortedValues, float64(duration))
+	}
+	return time.Duration(utils.GetPercentile(sortedValues, percentile))
+}
+
+// durationToMs converts the duration to milliseconds.
+func durationToMs(duration time.Duration) float64 {
+	return float64(duration) / float64(time.Millisecond)
+}
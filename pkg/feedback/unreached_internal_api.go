@@ -0,0 +1,130 @@
+package feedback
+
+import (
+	"cmp"
+	"resttracefuzzer/pkg/casemanager"
+	"resttracefuzzer/pkg/feedback/trace"
+	"resttracefuzzer/pkg/static"
+	"resttracefuzzer/pkg/utils"
+	"slices"
+)
+
+// UnreachedInternalAPI is an internal service API in the internal service doc, which never appears in any trace of the run.
+// It is possibly dead (e.g., removed from the implementation), or unreachable by external APIs.
+type UnreachedInternalAPI struct {
+	// ServiceName is the name of the service, in standard case.
+	ServiceName string `json:"serviceName"`
+
+	// APIMethod is the documented API method.
+	APIMethod static.SimpleAPIMethod `json:"APIMethod"`
+
+	// ServiceReached tells whether other APIs of the service appear in traces.
+	// If false, the whole service is never reached, e.g., it is not deployed or not instrumented.
+	ServiceReached bool `json:"serviceReached"`
+}
+
+// InternalAPIReachTracker tracks internal service APIs appearing in traces of executed test scenarios,
+// to find those in the internal service doc which never appear across the whole run, see [InternalAPIReachTracker.GetUnreachedAPIs].
+// An API appears in a trace if it is served by a server span, or called by a client (or producer) span per the call infos.
+type InternalAPIReachTracker struct {
+	// APIManager provides the internal service APIs.
+	APIManager *static.APIManager
+
+	// TraceCount is the number of traces tracked.
+	TraceCount int
+
+	// reachedRoutes maps from service names (in standard case) to the called methods appearing in traces (see [trace.CallInfo.Method]),
+	// i.e., route templates (normalized by [normalizeRouteTemplate]) of HTTP APIs and method names of gRPC APIs,
+	// to the set of HTTP methods of them. The set contains empty string if the HTTP method is unknown, i.e., from call infos.
+	reachedRoutes map[string]map[string]map[string]struct{}
+}
+
+// NewInternalAPIReachTracker creates a new InternalAPIReachTracker.
+func NewInternalAPIReachTracker(APIManager *static.APIManager) *InternalAPIReachTracker {
+	return &InternalAPIReachTracker{
+		APIManager:    APIManager,
+		reachedRoutes: make(map[string]map[string]map[string]struct{}),
+	}
+}
+
+// EvaluateScenario implements [ScenarioEvaluator], recording internal service APIs appearing in traces of the executed test scenario.
+func (t *InternalAPIReachTracker) EvaluateScenario(testScenario *casemanager.TestScenario, operationResults []*OperationResult) {
+	for _, result := range operationResults {
+		if result.Trace == nil {
+			continue
+		}
+		t.TraceCount++
+		for _, span := range result.Trace.SpanMap {
+			servedAPI := trace.GetServedAPI(span)
+			if servedAPI == nil {
+				continue
+			}
+			if static.SimpleAPIMethodType(servedAPI.APIType) == static.SimpleAPIMethodTypeHTTP {
+				t.recordReachedRoute(servedAPI.ServiceName, normalizeRouteTemplate(servedAPI.Path), servedAPI.Method)
+			} else {
+				t.recordReachedRoute(servedAPI.ServiceName, servedAPI.Method, "")
+			}
+		}
+		for _, callInfo := range result.CallInfos {
+			route := callInfo.Method
+			if len(route) > 0 && route[0] == '/' {
+				route = normalizeRouteTemplate(route)
+			}
+			t.recordReachedRoute(callInfo.TargetService, route, "")
+		}
+	}
+}
+
+// recordReachedRoute records the called method of the service appearing in traces, with the HTTP method if known.
+func (t *InternalAPIReachTracker) recordReachedRoute(serviceName, route, httpMethod string) {
+	serviceName = utils.FormatServiceName(serviceName)
+	routes, exists := t.reachedRoutes[serviceName]
+	if !exists {
+		routes = make(map[string]map[string]struct{})
+		t.reachedRoutes[serviceName] = routes
+	}
+	if _, exists := routes[route]; !exists {
+		routes[route] = make(map[string]struct{})
+	}
+	routes[route][httpMethod] = struct{}{}
+}
+
+// GetUnreachedAPIs returns HTTP and gRPC APIs of the internal service doc which never appear in traces tracked, sorted by service name and API method.
+// HTTP APIs are matched by route template (modulo names of path parameters) and HTTP method, if known.
+func (t *InternalAPIReachTracker) GetUnreachedAPIs() []*UnreachedInternalAPI {
+	unreachedAPIs := make([]*UnreachedInternalAPI, 0)
+	for serviceName, methodMap := range t.APIManager.ServiceAPIMap {
+		formattedServiceName := utils.FormatServiceName(serviceName)
+		routes, serviceReached := t.reachedRoutes[formattedServiceName]
+		for method := range methodMap {
+			var route string
+			switch method.Typ {
+			case static.SimpleAPIMethodTypeHTTP:
+				route = normalizeRouteTemplate(method.Endpoint)
+			case static.SimpleAPIMethodTypeGRPC:
+				route = method.Method
+			default:
+				continue
+			}
+			httpMethods := routes[route]
+			if _, reached := httpMethods[method.Method]; reached {
+				continue
+			}
+			if _, reached := httpMethods[""]; reached {
+				continue
+			}
+			unreachedAPIs = append(unreachedAPIs, &UnreachedInternalAPI{
+				ServiceName:    formattedServiceName,
+				APIMethod:      method,
+				ServiceReached: serviceReached,
+			})
+		}
+	}
+	slices.SortFunc(unreachedAPIs, func(a, b *UnreachedInternalAPI) int {
+		return cmp.Or(
+			cmp.Compare(a.ServiceName, b.ServiceName),
+			static.CompareSimpleAPIMethod(a.APIMethod, b.APIMethod),
+		)
+	})
+	return unreachedAPIs
+}
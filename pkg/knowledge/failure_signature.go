@@ -0,0 +1,133 @@
+package knowledge
+
+import (
+	"cmp"
+	"fmt"
+	"maps"
+	"regexp"
+	"resttracefuzzer/pkg/casemanager"
+	"resttracefuzzer/pkg/feedback"
+	"resttracefuzzer/pkg/static"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/rs/zerolog/log"
+)
+
+// maxBodySignatureLength is the max length of the body signature, in bytes.
+const maxBodySignatureLength = 128
+
+// volatileTokenRegex matches tokens varying among responses of the same failure, e.g., UUIDs, hex ids and numbers,
+// which are replaced in body signatures.
+var volatileTokenRegex = regexp.MustCompile(`[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}|\b[0-9a-fA-F]*[0-9][0-9a-fA-F]*\b`)
+
+// FailureSignature is a kind of server error (5xx) of an operation, identified by the status code and the normalized response body.
+type FailureSignature struct {
+	// APIMethod is the operation failed.
+	APIMethod static.SimpleAPIMethod `json:"APIMethod"`
+
+	// StatusCode is the response status code.
+	StatusCode int `json:"statusCode"`
+
+	// BodySignature is the first line of response body (truncated), with volatile tokens (e.g., ids and numbers) replaced by `#`.
+	BodySignature string `json:"bodySignature"`
+
+	// HitCount is the number of responses of the signature, in all runs.
+	HitCount int `json:"hitCount"`
+}
+
+// FailureSignatureTracker tracks failure signatures of executed operation cases, on top of those found in previous runs.
+// It implements [feedback.ScenarioEvaluator].
+// It is safe for concurrent use, as signatures may be read by the dashboard while the fuzzer is running.
+type FailureSignatureTracker struct {
+	mu sync.Mutex
+
+	// Signatures maps from the key of signatures to the signature.
+	Signatures map[string]*FailureSignature
+
+	// NewSignatures are signatures not found in previous runs, in the order they are found.
+	NewSignatures []*FailureSignature
+}
+
+// NewFailureSignatureTracker creates a new FailureSignatureTracker, with signatures found in previous runs.
+func NewFailureSignatureTracker(knownSignatures []*FailureSignature) *FailureSignatureTracker {
+	t := &FailureSignatureTracker{
+		Signatures:    make(map[string]*FailureSignature),
+		NewSignatures: make([]*FailureSignature, 0),
+	}
+	for _, signature := range knownSignatures {
+		t.Signatures[signature.key()] = signature
+	}
+	return t
+}
+
+// EvaluateScenario implements [feedback.ScenarioEvaluator], by recording failure signatures of executed operation cases.
+func (t *FailureSignatureTracker) EvaluateScenario(testScenario *casemanager.TestScenario, operationResults []*feedback.OperationResult) {
+	for _, result := range operationResults {
+		t.RecordOperationCase(result.OperationCase)
+	}
+}
+
+// RecordOperationCase records the failure signature of the executed operation case, if it is a server error.
+// It returns true if the signature is new, i.e., not found before.
+func (t *FailureSignatureTracker) RecordOperationCase(operationCase *casemanager.OperationCase) bool {
+	if operationCase == nil || operationCase.ResponseStatusCode < 500 {
+		return false
+	}
+	signature := &FailureSignature{
+		APIMethod:     operationCase.APIMethod,
+		StatusCode:    operationCase.ResponseStatusCode,
+		BodySignature: getBodySignature(operationCase.ResponseBody),
+	}
+	key := signature.key()
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if existing, exists := t.Signatures[key]; exists {
+		existing.HitCount++
+		return false
+	}
+	signature.HitCount = 1
+	t.Signatures[key] = signature
+	t.NewSignatures = append(t.NewSignatures, signature)
+	log.Info().Msgf("[FailureSignatureTracker.RecordOperationCase] New failure signature on %s %s: %d %s", signature.APIMethod.Method, signature.APIMethod.Endpoint, signature.StatusCode, signature.BodySignature)
+	return true
+}
+
+// GetSortedSignatures returns all signatures, sorted by API method, status code and body signature.
+func (t *FailureSignatureTracker) GetSortedSignatures() []*FailureSignature {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return slices.SortedFunc(maps.Values(t.Signatures), func(a, b *FailureSignature) int {
+		return cmp.Or(
+			static.CompareSimpleAPIMethod(a.APIMethod, b.APIMethod),
+			cmp.Compare(a.StatusCode, b.StatusCode),
+			strings.Compare(a.BodySignature, b.BodySignature),
+		)
+	})
+}
+
+// GetNewSignatures returns copies of signatures not found in previous runs, in the order they are found.
+func (t *FailureSignatureTracker) GetNewSignatures() []FailureSignature {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	res := make([]FailureSignature, 0, len(t.NewSignatures))
+	for _, signature := range t.NewSignatures {
+		res = append(res, *signature)
+	}
+	return res
+}
+
+// key returns the key identifying the signature.
+func (s *FailureSignature) key() string {
+	return fmt.Sprintf("%s %s|%d|%s", s.APIMethod.Method, s.APIMethod.Endpoint, s.StatusCode, s.BodySignature)
+}
+
+// getBodySignature returns the signature of response body, see [FailureSignature.BodySignature].
+func getBodySignature(body []byte) string {
+	firstLine, _, _ := strings.Cut(strings.TrimSpace(string(body)), "\n")
+	if len(firstLine) > maxBodySignatureLength {
+		firstLine = firstLine[:maxBodySignatureLength]
+	}
+	return volatileTokenRegex.ReplaceAllString(firstLine, "#")
+}
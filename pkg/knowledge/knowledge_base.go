@@ -0,0 +1,256 @@
+// Package knowledge provides an on-disk knowledge base, which keeps artifacts learned in fuzzing across runs.
+// Artifacts are stored per spec, i.e., looked up by the hash of API docs, so that they are reused only when docs are unchanged.
+package knowledge
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"resttracefuzzer/pkg/resource"
+	fuzzruntime "resttracefuzzer/pkg/runtime"
+	"resttracefuzzer/pkg/static"
+	"slices"
+	"time"
+
+	"github.com/bytedance/sonic"
+	"github.com/rs/zerolog/log"
+)
+
+// File names of artifacts in the directory of a spec.
+const (
+	metaFileName              = "meta.json"
+	dataflowGraphFileName     = "dataflow_graph.json"
+	reachabilityFileName      = "reachability.json"
+	resourcePoolFileName      = "resource_pool.json"
+	failureSignaturesFileName = "failure_signatures.json"
+)
+
+// specHashLength is the length of the (hex encoded) spec hash used as the directory name of a spec.
+const specHashLength = 16
+
+// KnowledgeMeta is the metadata of artifacts of a spec.
+type KnowledgeMeta struct {
+	// SpecHash is the hash of API docs.
+	SpecHash string `json:"specHash"`
+
+	// SpecPaths are the paths of API docs when the artifacts are saved, for reference only.
+	SpecPaths []string `json:"specPaths"`
+
+	// RunCount is the number of runs whose artifacts have been saved.
+	RunCount int `json:"runCount"`
+
+	// UpdatedAt is when the artifacts are saved last time.
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// reachabilityPair is a pair of an external API and an internal endpoint reachable from it.
+// [static.ReachabilityMap] cannot be marshalled directly, so it is stored as a list of pairs.
+type reachabilityPair struct {
+	External static.SimpleAPIMethod         `json:"external"`
+	Internal static.InternalServiceEndpoint `json:"internal"`
+}
+
+// KnowledgeBase is an on-disk knowledge base of artifacts learned in fuzzing, including:
+//   - the dataflow graph parsed from docs, which is costly to parse for large docs;
+//   - the high confidence reachability learned from traces;
+//   - the resource pool;
+//   - the failure signatures, see [FailureSignatureTracker].
+//
+// Artifacts of a spec are stored in directory `{baseDir}/{specHash}`, one JSON file for each.
+// Missing artifacts (e.g., in the first run) are skipped when loading.
+type KnowledgeBase struct {
+	// Dir is the directory of artifacts of current spec.
+	Dir string
+
+	// Meta is the metadata of artifacts of current spec.
+	Meta *KnowledgeMeta
+}
+
+// NewKnowledgeBase creates a KnowledgeBase under baseDir, for the spec consisting of docs at specPaths.
+// Empty paths (e.g., optional docs not provided) are ignored.
+// It creates the directory of the spec if it does not exist.
+func NewKnowledgeBase(baseDir string, specPaths ...string) (*KnowledgeBase, error) {
+	specPaths = slices.DeleteFunc(slices.Clone(specPaths), func(path string) bool { return path == "" })
+	specHash, err := hashSpecFiles(specPaths)
+	if err != nil {
+		log.Err(err).Msg("[NewKnowledgeBase] Failed to hash spec files")
+		return nil, err
+	}
+	dir := filepath.Join(baseDir, specHash)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		log.Err(err).Msgf("[NewKnowledgeBase] Failed to create knowledge base directory: %s", dir)
+		return nil, err
+	}
+	kb := &KnowledgeBase{
+		Dir: dir,
+		Meta: &KnowledgeMeta{
+			SpecHash:  specHash,
+			SpecPaths: specPaths,
+		},
+	}
+	if _, err := kb.loadArtifact(metaFileName, kb.Meta); err != nil {
+		log.Err(err).Msg("[NewKnowledgeBase] Failed to load metadata, a new one is used")
+	}
+	log.Info().Msgf("[NewKnowledgeBase] Knowledge base of spec %s: %s, %d run(s) saved", specHash, dir, kb.Meta.RunCount)
+	return kb, nil
+}
+
+// LoadDataflowGraph loads the dataflow graph.
+// It returns nil if the graph has not been saved.
+func (kb *KnowledgeBase) LoadDataflowGraph() (*static.APIDataflowGraph, error) {
+	var edges []*static.APIDataflowEdge
+	exists, err := kb.loadArtifact(dataflowGraphFileName, &edges)
+	if err != nil || !exists {
+		return nil, err
+	}
+	graph := static.NewAPIDataflowGraph()
+	for _, edge := range edges {
+		graph.AddEdge(edge)
+	}
+	log.Info().Msgf("[KnowledgeBase.LoadDataflowGraph] Loaded dataflow graph with %d edges", len(edges))
+	return graph, nil
+}
+
+// SaveDataflowGraph saves the dataflow graph.
+func (kb *KnowledgeBase) SaveDataflowGraph(graph *static.APIDataflowGraph) error {
+	return kb.saveArtifact(dataflowGraphFileName, graph.Edges)
+}
+
+// LoadReachability loads the high confidence reachability into the runtime reachability map.
+// Loaded reachability is removed from the low confidence map, like that learned from traces, see [fuzzruntime.RuntimeReachabilityMap.UpdateFromCallInfos].
+func (kb *KnowledgeBase) LoadReachability(reachabilityMap *fuzzruntime.RuntimeReachabilityMap) error {
+	var pairs []*reachabilityPair
+	exists, err := kb.loadArtifact(reachabilityFileName, &pairs)
+	if err != nil || !exists {
+		return err
+	}
+	for _, pair := range pairs {
+		reachabilityMap.AddReachabilityWithConfidenceLevel(pair.External, pair.Internal, 1)
+		reachabilityMap.RemoveReachabilityWithConfidenceLevel(pair.External, pair.Internal, 0)
+	}
+	log.Info().Msgf("[KnowledgeBase.LoadReachability] Loaded %d high confidence reachability pairs", len(pairs))
+	return nil
+}
+
+// SaveReachability saves the high confidence reachability of the runtime reachability map.
+func (kb *KnowledgeBase) SaveReachability(reachabilityMap *fuzzruntime.RuntimeReachabilityMap) error {
+	pairs := make([]*reachabilityPair, 0)
+	for _, external := range slices.SortedFunc(maps.Keys(reachabilityMap.HighConfidenceMap.External2Internal), static.CompareSimpleAPIMethod) {
+		for _, internal := range reachabilityMap.HighConfidenceMap.External2Internal[external] {
+			pairs = append(pairs, &reachabilityPair{External: external, Internal: internal})
+		}
+	}
+	return kb.saveArtifact(reachabilityFileName, pairs)
+}
+
+// LoadResourcePool loads resources into the resource manager.
+// The resource pool is saved in the format of external dictionary, see [resource.ResourceManager.LoadFromExternalDictFile].
+func (kb *KnowledgeBase) LoadResourcePool(resourceManager *resource.ResourceManager) error {
+	path := filepath.Join(kb.Dir, resourcePoolFileName)
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return nil
+	}
+	return resourceManager.LoadFromExternalDictFile(path)
+}
+
+// SaveResourcePool saves named resources of the resource manager.
+// Resources without names cannot be looked up by name, and are not saved.
+func (kb *KnowledgeBase) SaveResourcePool(resourceManager *resource.ResourceManager) error {
+	type dictValue struct {
+		Name  string `json:"name"`
+		Value any    `json:"value"`
+	}
+	dictValues := make([]*dictValue, 0)
+	for _, name := range slices.Sorted(maps.Keys(resourceManager.ResourceNameMap)) {
+		for _, resrc := range resourceManager.ResourceNameMap[name] {
+			dictValues = append(dictValues, &dictValue{Name: name, Value: resrc.ToJSONObject()})
+		}
+	}
+	return kb.saveArtifact(resourcePoolFileName, dictValues)
+}
+
+// LoadFailureSignatures loads failure signatures found in previous runs.
+// It returns an empty list if none has been saved.
+func (kb *KnowledgeBase) LoadFailureSignatures() ([]*FailureSignature, error) {
+	signatures := make([]*FailureSignature, 0)
+	if _, err := kb.loadArtifact(failureSignaturesFileName, &signatures); err != nil {
+		return make([]*FailureSignature, 0), err
+	}
+	return signatures, nil
+}
+
+// SaveFailureSignatures saves all failure signatures tracked, including those found in previous runs.
+func (kb *KnowledgeBase) SaveFailureSignatures(tracker *FailureSignatureTracker) error {
+	return kb.saveArtifact(failureSignaturesFileName, tracker.GetSortedSignatures())
+}
+
+// SaveMeta increases the run count, and saves the metadata.
+// It should be called once after all artifacts of a run are saved.
+func (kb *KnowledgeBase) SaveMeta() error {
+	kb.Meta.RunCount++
+	kb.Meta.UpdatedAt = time.Now()
+	return kb.saveArtifact(metaFileName, kb.Meta)
+}
+
+// loadArtifact loads the artifact of the given file name into v.
+// It returns false if the artifact does not exist.
+func (kb *KnowledgeBase) loadArtifact(fileName string, v any) (bool, error) {
+	path := filepath.Join(kb.Dir, fileName)
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		log.Debug().Msgf("[KnowledgeBase.loadArtifact] Artifact %s does not exist", path)
+		return false, nil
+	}
+	if err != nil {
+		log.Err(err).Msgf("[KnowledgeBase.loadArtifact] Failed to read artifact: %s", path)
+		return false, err
+	}
+	if err := sonic.Unmarshal(data, v); err != nil {
+		log.Err(err).Msgf("[KnowledgeBase.loadArtifact] Failed to unmarshal artifact: %s", path)
+		return false, err
+	}
+	return true, nil
+}
+
+// saveArtifact saves v as the artifact of the given file name.
+// It writes to a temporary file first and renames it, so that an interrupted save does not corrupt the existing artifact.
+func (kb *KnowledgeBase) saveArtifact(fileName string, v any) error {
+	path := filepath.Join(kb.Dir, fileName)
+	data, err := sonic.Marshal(v)
+	if err != nil {
+		log.Err(err).Msgf("[KnowledgeBase.saveArtifact] Failed to marshal artifact: %s", path)
+		return err
+	}
+	tmpPath := path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0644); err != nil {
+		log.Err(err).Msgf("[KnowledgeBase.saveArtifact] Failed to write artifact: %s", tmpPath)
+		return err
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		log.Err(err).Msgf("[KnowledgeBase.saveArtifact] Failed to rename artifact: %s", tmpPath)
+		return err
+	}
+	log.Debug().Msgf("[KnowledgeBase.saveArtifact] Artifact saved: %s", path)
+	return nil
+}
+
+// hashSpecFiles returns the hash of contents of the spec files, in the given order.
+func hashSpecFiles(specPaths []string) (string, error) {
+	if len(specPaths) == 0 {
+		return "", fmt.Errorf("no spec file to hash")
+	}
+	hash := sha256.New()
+	for _, path := range specPaths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return "", err
+		}
+		// The length prefix separates contents of files, so that moving bytes between files changes the hash.
+		fmt.Fprintf(hash, "%d:", len(data))
+		hash.Write(data)
+	}
+	return hex.EncodeToString(hash.Sum(nil))[:specHashLength], nil
+}
@@ -2,6 +2,7 @@ package llm
 
 import (
 	"context"
+	"fmt"
 
 	"github.com/openai/openai-go" // imported as openai
 	"github.com/openai/openai-go/option"
@@ -32,6 +33,7 @@ func NewOpenAIClient(
 	)
 	return &OpenAIClient{
 		client: &client,
+		model:  model,
 	}
 }
 
@@ -41,7 +43,7 @@ func NewOpenAIClient(
 // - prompt: The input prompt for generating the chat completion.
 // Returns the generated chat completion as a string, or an error if the request fails.
 func (c *OpenAIClient) ChatCompletion(ctx context.Context, prompt string) (string, error) {
-	chatCompletion, err := c.client.Chat.Completions.New(context.TODO(), openai.ChatCompletionNewParams{
+	chatCompletion, err := c.client.Chat.Completions.New(ctx, openai.ChatCompletionNewParams{
 		Messages: []openai.ChatCompletionMessageParamUnion{
 			openai.UserMessage(prompt),
 		},
@@ -51,6 +53,10 @@ func (c *OpenAIClient) ChatCompletion(ctx context.Context, prompt string) (strin
 		log.Err(err).Msg("[OpenAIClient.ChatCompletion] Error generating chat completion")
 		return "", err
 	}
-	log.Info().Msgf("[OpenAIClient.ChatCompletion] Generated chat completion: %s", chatCompletion.Choices[0].Message.Content)
+	if len(chatCompletion.Choices) == 0 {
+		log.Error().Msg("[OpenAIClient.ChatCompletion] No choice in chat completion")
+		return "", fmt.Errorf("no choice in chat completion")
+	}
+	log.Debug().Msgf("[OpenAIClient.ChatCompletion] Generated chat completion: %s", chatCompletion.Choices[0].Message.Content)
 	return chatCompletion.Choices[0].Message.Content, nil
 }
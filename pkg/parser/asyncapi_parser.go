@@ -0,0 +1,62 @@
+package parser
+
+import (
+	"fmt"
+	"os"
+	"resttracefuzzer/pkg/static"
+	"strings"
+
+	"github.com/bytedance/sonic"
+	"github.com/oasdiff/yaml"
+	"github.com/rs/zerolog/log"
+)
+
+// AsyncAPIParser is an AsyncAPI parser that parses AsyncAPI (2.x) spec files, in YAML or JSON.
+type AsyncAPIParser struct{}
+
+// NewAsyncAPIParser creates a new AsyncAPIParser.
+func NewAsyncAPIParser() *AsyncAPIParser {
+	return &AsyncAPIParser{}
+}
+
+// ParseSystemDocFromPath parses the AsyncAPI spec file of the system from the given path, with references resolved.
+// Channels with publish operation are those the system consumes messages from.
+// It returns the AsyncAPI doc and an error if any.
+func (p *AsyncAPIParser) ParseSystemDocFromPath(path string) (*static.AsyncAPIDoc, error) {
+	return p.parseDocFromPath(path)
+}
+
+// ParseServiceDocFromPath parses the AsyncAPI spec file of internal services from the given path, with references resolved.
+// Like OpenAPI spec of internal services, operationId of each operation should be in the format of `{Service}_{Method}`.
+// It returns the AsyncAPI doc and an error if any.
+func (p *AsyncAPIParser) ParseServiceDocFromPath(path string) (*static.AsyncAPIDoc, error) {
+	return p.parseDocFromPath(path)
+}
+
+// parseDocFromPath parses an AsyncAPI spec file from the given path, with references resolved.
+func (p *AsyncAPIParser) parseDocFromPath(path string) (*static.AsyncAPIDoc, error) {
+	docData, err := os.ReadFile(path)
+	if err != nil {
+		log.Err(err).Msgf("[AsyncAPIParser.parseDocFromPath] Failed to read file: %s", path)
+		return nil, err
+	}
+	// JSON is a subset of YAML, so both are converted to JSON first.
+	docJSON, err := yaml.YAMLToJSON(docData)
+	if err != nil {
+		log.Err(err).Msgf("[AsyncAPIParser.parseDocFromPath] Failed to parse file: %s", path)
+		return nil, err
+	}
+	var doc static.AsyncAPIDoc
+	if err := sonic.Unmarshal(docJSON, &doc); err != nil {
+		log.Err(err).Msgf("[AsyncAPIParser.parseDocFromPath] Failed to unmarshal AsyncAPI doc: %s", path)
+		return nil, err
+	}
+	if !strings.HasPrefix(doc.AsyncAPI, "2.") {
+		return nil, fmt.Errorf("unsupported AsyncAPI version: %s, only 2.x is supported", doc.AsyncAPI)
+	}
+	if err := doc.ResolveRefs(); err != nil {
+		log.Err(err).Msgf("[AsyncAPIParser.parseDocFromPath] Failed to resolve references in AsyncAPI doc: %s", path)
+		return nil, err
+	}
+	return &doc, nil
+}
@@ -0,0 +1,269 @@
+package parser
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"os"
+	"resttracefuzzer/pkg/static"
+	"slices"
+	"strings"
+
+	"github.com/bytedance/sonic"
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/rs/zerolog/log"
+)
+
+// EvoMaster exports the dependencies among resources derived by its resource-based sampling (option `--exportDependencies`)
+// to a CSV file (option `--dependencyFile`, `dependencies.csv` by default).
+// Resources are identified by their paths, and each row lists the resources (separated by semicolons) that a resource relates to,
+// i.e., the target resources should exist before the actions on the key resource.
+// Rows of the same key resource leave the key empty except for the first one,
+// and columns other than the key and the targets (e.g., the probability) are ignored.
+// Example CSV format:
+//
+//	key,targets,probability,info
+//	/api/products/{productId}/reviews,/api/products/{productId},1.0,
+//	,/api/users/{userId},0.5,
+//	/api/orders,/api/products/{productId};/api/users/{userId},1.0,
+const (
+	// evoMasterDependencyKeyColumn is the column header of the key resources.
+	evoMasterDependencyKeyColumn = "key"
+
+	// evoMasterDependencyTargetsColumn is the column header of the target resources.
+	evoMasterDependencyTargetsColumn = "targets"
+
+	// evoMasterResourceSeparator separates multiple resources in a cell.
+	evoMasterResourceSeparator = ";"
+)
+
+// evoMasterResourceDependency is a dependency of a key resource on target resources, by their paths.
+type evoMasterResourceDependency struct {
+	// Key is the path of the resource that depends on Targets.
+	Key string
+
+	// Targets are the paths of the resources that Key depends on.
+	Targets []string
+}
+
+// APIDependencyEvoMasterParser represents a parser for API dependencies from EvoMaster.
+// It implements the APIDependencyParser interface.
+//
+// As EvoMaster derives dependencies among resources rather than API methods, they are resolved by the API methods in the API manager:
+// all API methods on a key resource depend on the API methods creating its target resources,
+// i.e., POST and PUT on the target path, and POST on the collection of the target if its path ends with a path parameter.
+type APIDependencyEvoMasterParser struct {
+	// APIManager is the API manager, whose API methods resolve the resources.
+	APIManager *static.APIManager
+}
+
+// NewAPIDependencyEvoMasterParser creates a new APIDependencyEvoMasterParser.
+func NewAPIDependencyEvoMasterParser(APIManager *static.APIManager) *APIDependencyEvoMasterParser {
+	return &APIDependencyEvoMasterParser{
+		APIManager: APIManager,
+	}
+}
+
+// ParseFromFile parses API dependencies from a given file path.
+func (p *APIDependencyEvoMasterParser) ParseFromFile(path string) (*static.APIDependencyGraph, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		log.Err(err).Msgf("[APIDependencyEvoMasterParser.ParseFromFile] Error reading file")
+		return nil, err
+	}
+
+	// Parse the data from the file
+	dependencyGraph, err := p.ParseFromBytes(data)
+	if err != nil {
+		log.Err(err).Msgf("[APIDependencyEvoMasterParser.ParseFromFile] Error parsing data from file")
+		return nil, err
+	}
+	return dependencyGraph, nil
+}
+
+// ParseFromBytes parses the API dependency graph from the given byte slice, which is the CSV file exported by EvoMaster.
+// Resources are resolved by the system API methods.
+func (p *APIDependencyEvoMasterParser) ParseFromBytes(data []byte) (*static.APIDependencyGraph, error) {
+	dependencies, err := p.parseFromCSV(data)
+	if err != nil {
+		log.Err(err).Msgf("[APIDependencyEvoMasterParser.ParseFromBytes] Error parsing CSV")
+		return nil, err
+	}
+	var APIMap map[static.SimpleAPIMethod]*openapi3.Operation
+	if p.APIManager != nil {
+		APIMap = p.APIManager.APIMap
+	}
+	dependencyGraph := p.resolveDependencies(dependencies, APIMap)
+	log.Debug().Msgf("[APIDependencyEvoMasterParser.ParseFromBytes] Successfully parsed API dependency graph")
+	return dependencyGraph, nil
+}
+
+// ParseFromServiceMapFile parses the API dependency graph from the given service map file.
+// The file is a JSON file that contains a map of service names to the contents of their CSV files exported by EvoMaster.
+// Resources of each service are resolved by the API methods of the service.
+func (p *APIDependencyEvoMasterParser) ParseFromServiceMapFile(path string) (map[string]*static.APIDependencyGraph, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		log.Err(err).Msgf("[APIDependencyEvoMasterParser.ParseFromServiceMapFile] Error reading file")
+		return nil, err
+	}
+
+	serviceMap := make(map[string]string)
+	if err := sonic.Unmarshal(data, &serviceMap); err != nil {
+		log.Err(err).Msgf("[APIDependencyEvoMasterParser.ParseFromServiceMapFile] Error parsing JSON")
+		return nil, err
+	}
+
+	dependencyGraphs := make(map[string]*static.APIDependencyGraph)
+	for serviceName, content := range serviceMap {
+		dependencies, err := p.parseFromCSV([]byte(content))
+		if err != nil {
+			log.Err(err).Msgf("[APIDependencyEvoMasterParser.ParseFromServiceMapFile] Error parsing CSV for service %s", serviceName)
+			return nil, err
+		}
+		var serviceAPIMap map[static.SimpleAPIMethod]*openapi3.Operation
+		if p.APIManager != nil {
+			serviceAPIMap = p.APIManager.ServiceAPIMap[serviceName]
+		}
+		dependencyGraphs[serviceName] = p.resolveDependencies(dependencies, serviceAPIMap)
+	}
+	log.Debug().Msgf("[APIDependencyEvoMasterParser.ParseFromServiceMapFile] Successfully parsed API dependency graphs for all services")
+	return dependencyGraphs, nil
+}
+
+// parseFromCSV parses the resource dependencies from the CSV file exported by EvoMaster.
+// The key and targets columns are located by the header, so that the order of columns does not matter.
+// The key column is the one right before the targets column if it is also named key, as the grouping key may be repeated by the relation.
+func (p *APIDependencyEvoMasterParser) parseFromCSV(data []byte) ([]evoMasterResourceDependency, error) {
+	reader := csv.NewReader(bytes.NewReader(data))
+	// Rows may have trailing empty columns trimmed
+	reader.FieldsPerRecord = -1
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CSV: %w", err)
+	}
+	if len(records) == 0 {
+		return nil, nil
+	}
+
+	header := records[0]
+	for i := range header {
+		header[i] = strings.ToLower(strings.TrimSpace(header[i]))
+	}
+	targetsIdx := slices.Index(header, evoMasterDependencyTargetsColumn)
+	if targetsIdx < 0 {
+		return nil, fmt.Errorf("missing column %s in CSV header: %v", evoMasterDependencyTargetsColumn, records[0])
+	}
+	keyIdx := slices.Index(header, evoMasterDependencyKeyColumn)
+	if targetsIdx > 0 && header[targetsIdx-1] == evoMasterDependencyKeyColumn {
+		keyIdx = targetsIdx - 1
+	}
+	if keyIdx < 0 {
+		return nil, fmt.Errorf("missing column %s in CSV header: %v", evoMasterDependencyKeyColumn, records[0])
+	}
+
+	dependencies := make([]evoMasterResourceDependency, 0, len(records)-1)
+	lastKey := ""
+	for rowIdx, record := range records[1:] {
+		key := ""
+		if keyIdx < len(record) {
+			key = strings.TrimSpace(record[keyIdx])
+		}
+		// Rows of the same key resource leave the key empty, except for the first one
+		if key == "" && keyIdx != 0 && len(record) > 0 {
+			key = strings.TrimSpace(record[0])
+		}
+		if key == "" {
+			key = lastKey
+		}
+		if key == "" {
+			return nil, fmt.Errorf("missing key resource in CSV row %d", rowIdx+2)
+		}
+		lastKey = key
+		if targetsIdx >= len(record) {
+			continue
+		}
+		targets := splitEvoMasterResources(record[targetsIdx])
+		for _, keyResource := range splitEvoMasterResources(key) {
+			dependencies = append(dependencies, evoMasterResourceDependency{Key: keyResource, Targets: targets})
+		}
+	}
+	return dependencies, nil
+}
+
+// resolveDependencies resolves the resource dependencies to the API dependency graph by the given API methods.
+// Resources without API methods in APIMap are ignored.
+func (p *APIDependencyEvoMasterParser) resolveDependencies(dependencies []evoMasterResourceDependency, APIMap map[static.SimpleAPIMethod]*openapi3.Operation) *static.APIDependencyGraph {
+	// Sort API methods, so that the graph is deterministic
+	APIMethods := make([]static.SimpleAPIMethod, 0, len(APIMap))
+	for method := range APIMap {
+		APIMethods = append(APIMethods, method)
+	}
+	slices.SortFunc(APIMethods, static.CompareSimpleAPIMethod)
+
+	dependencyGraph := static.NewAPIDependencyGraph()
+	for _, dependency := range dependencies {
+		consumers := getEvoMasterResourceActions(APIMethods, dependency.Key)
+		if len(consumers) == 0 {
+			log.Warn().Msgf("[APIDependencyEvoMasterParser.resolveDependencies] No API method found on resource %s, it would be ignored", dependency.Key)
+			continue
+		}
+		for _, target := range dependency.Targets {
+			producers := getEvoMasterResourceCreators(APIMethods, target)
+			if len(producers) == 0 {
+				log.Warn().Msgf("[APIDependencyEvoMasterParser.resolveDependencies] No API method found creating resource %s, it would be ignored", target)
+				continue
+			}
+			for _, producer := range producers {
+				for _, consumer := range consumers {
+					if producer == consumer {
+						continue
+					}
+					log.Debug().Msgf("[APIDependencyEvoMasterParser.resolveDependencies] Adding dependency from %v to %v", producer, consumer)
+					dependencyGraph.AddDependency(producer, consumer)
+				}
+			}
+		}
+	}
+	return dependencyGraph
+}
+
+// splitEvoMasterResources splits the resource paths in a CSV cell, dropping empty ones.
+func splitEvoMasterResources(cell string) []string {
+	resources := make([]string, 0)
+	for _, resource := range strings.Split(cell, evoMasterResourceSeparator) {
+		if resource = strings.TrimSpace(resource); resource != "" {
+			resources = append(resources, resource)
+		}
+	}
+	return resources
+}
+
+// getEvoMasterResourceActions returns the API methods on the resource path.
+func getEvoMasterResourceActions(APIMethods []static.SimpleAPIMethod, resourcePath string) []static.SimpleAPIMethod {
+	actions := make([]static.SimpleAPIMethod, 0)
+	for _, method := range APIMethods {
+		if method.Endpoint == resourcePath {
+			actions = append(actions, method)
+		}
+	}
+	return actions
+}
+
+// getEvoMasterResourceCreators returns the API methods creating the resource,
+// i.e., POST and PUT on the resource path, and POST on its collection if the path ends with a path parameter (e.g., `/api/products/{productId}`).
+func getEvoMasterResourceCreators(APIMethods []static.SimpleAPIMethod, resourcePath string) []static.SimpleAPIMethod {
+	collectionPath := ""
+	if idx := strings.LastIndex(resourcePath, "/"); idx >= 0 && strings.HasPrefix(resourcePath[idx+1:], "{") {
+		collectionPath = resourcePath[:idx]
+	}
+	creators := make([]static.SimpleAPIMethod, 0)
+	for _, method := range APIMethods {
+		isCreator := (method.Endpoint == resourcePath && (method.Method == "POST" || method.Method == "PUT")) ||
+			(collectionPath != "" && method.Endpoint == collectionPath && method.Method == "POST")
+		if isCreator {
+			creators = append(creators, method)
+		}
+	}
+	return creators
+}
@@ -3,6 +3,7 @@ package parser
 import (
 	"fmt"
 	"resttracefuzzer/pkg/static"
+	"strings"
 )
 
 // APIDependencyParser is an interface for parsing API dependencies.
@@ -19,13 +20,40 @@ type APIDependencyParser interface {
 }
 
 // NewAPIDependencyParserByType creates a new APIDependencyParser instance based on the given parser type.
-func NewAPIDependencyParserByType(parserType string) (APIDependencyParser, error) {
-	// We support Restler parser for now
+// Parsers of dependency files not identifying API methods by method and path (i.e., EvoMaster and RestTestGen) resolve them by APIManager.
+func NewAPIDependencyParserByType(parserType string, APIManager *static.APIManager) (APIDependencyParser, error) {
+	// We support Restler, EvoMaster and RestTestGen parsers for now
 	// You can contact us if you want to add support for other parsers
 	switch parserType {
 	case "Restler":
 		return NewAPIDependencyRestlerParser(), nil
+	case "EvoMaster":
+		return NewAPIDependencyEvoMasterParser(APIManager), nil
+	case "RestTestGen":
+		return NewAPIDependencyRestTestGenParser(APIManager), nil
 	default:
 		return nil, fmt.Errorf("unsupported parser type: %s", parserType)
 	}
 }
+
+// parseHTTPAPIMethodString parses an HTTP API method from a string like "GET:/api/products/{productId}" (EvoMaster style)
+// or "GET /api/products/{productId}" (RestTestGen style).
+// The method is case-insensitive and would be converted to upper case.
+func parseHTTPAPIMethodString(s string) (static.SimpleAPIMethod, error) {
+	s = strings.TrimSpace(s)
+	idx := strings.IndexAny(s, ": ")
+	if idx <= 0 {
+		return static.SimpleAPIMethod{}, fmt.Errorf("invalid API method string: %s", s)
+	}
+	method := strings.ToUpper(strings.TrimSpace(s[:idx]))
+	endpoint := strings.TrimSpace(s[idx+1:])
+	if !strings.HasPrefix(endpoint, "/") {
+		return static.SimpleAPIMethod{}, fmt.Errorf("invalid endpoint in API method string: %s", s)
+	}
+	// We assume that all exposed APIs of the system are HTTP APIs
+	return static.SimpleAPIMethod{
+		Endpoint: endpoint,
+		Method:   method,
+		Typ:      static.SimpleAPIMethodTypeHTTP,
+	}, nil
+}
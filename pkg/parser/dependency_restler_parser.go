@@ -164,6 +164,14 @@ func (p *APIDependencyRestlerParser) ParseFromPathMap(jsonMap pathMap) (*static.
 					}
 					log.Debug().Msgf("[APIDependencyRestlerParser.ParseFromFileMap] Adding dependency from %v to %v", producer, consumer)
 					dependencyGraph.AddDependency(consumer, producer)
+					// The producer resource name is a path in the response, e.g., `[0]/id`, whose last segment is the property name.
+					sourcePropertyName := utils.ExtractLastSegment(producerConsumerDetail["producer_resource_name"], []string{"/"})
+					if sourcePropertyName != "" && producerConsumerDetail["consumer_param"] != "" {
+						dependencyGraph.AddBinding(producer, consumer, static.APIDependencyBinding{
+							SourceProperty: static.SimpleAPIProperty{Name: sourcePropertyName},
+							TargetProperty: static.SimpleAPIProperty{Name: producerConsumerDetail["consumer_param"]},
+						})
+					}
 				}
 			}
 		}
@@ -0,0 +1,231 @@
+package parser
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"resttracefuzzer/pkg/static"
+	"strings"
+
+	"github.com/bytedance/sonic"
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/rs/zerolog/log"
+)
+
+// RestTestGen exports its Operation Dependency Graph (ODG) in DOT format.
+// In the ODG, an edge from operation A to operation B means A depends on B, i.e., A consumes a field that B produces,
+// and the edge label is the name of the common field(s).
+// Nodes are either declared with a label, or identified by the label directly.
+// Labels are either "METHOD path", or operationIds resolved by the API methods in the API manager.
+// Quoted IDs and labels may contain escaped quotes, semicolons and newlines, which do not separate statements.
+// Example DOT format:
+//
+//	strict digraph G {
+//	  1 [ label="POST /api/products" ];
+//	  2 [ label="GET /api/products/{productId}" ];
+//	  3 [ label="updateProduct" ];
+//	  2 -> 1 [ label="productId" ];
+//	  3 -> 1 [ label="productId; name" ];
+//	  "DELETE /api/products/{productId}" -> "POST /api/products" [ label="productId" ];
+//	}
+var (
+	// restTestGenODGNodeRegex matches a node declaration with a label, e.g., `1 [ label="POST /api/products" ];`.
+	restTestGenODGNodeRegex = regexp.MustCompile(`^\s*("(?:[^"\\]|\\.)*"|[\w.]+)\s*\[(?:[^\]"]|"(?:[^"\\]|\\.)*")*?\blabel\s*=\s*"((?:[^"\\]|\\.)*)"`)
+
+	// restTestGenODGEdgeRegex matches an edge, e.g., `2 -> 1 [ label="productId" ];`.
+	restTestGenODGEdgeRegex = regexp.MustCompile(`^\s*("(?:[^"\\]|\\.)*"|[\w.]+)\s*->\s*("(?:[^"\\]|\\.)*"|[\w.]+)`)
+)
+
+// APIDependencyRestTestGenParser represents a parser for API dependencies from RestTestGen.
+// It implements the APIDependencyParser interface.
+type APIDependencyRestTestGenParser struct {
+	// APIManager is the API manager, whose API methods resolve nodes labeled by operationIds.
+	APIManager *static.APIManager
+}
+
+// NewAPIDependencyRestTestGenParser creates a new APIDependencyRestTestGenParser.
+func NewAPIDependencyRestTestGenParser(APIManager *static.APIManager) *APIDependencyRestTestGenParser {
+	return &APIDependencyRestTestGenParser{
+		APIManager: APIManager,
+	}
+}
+
+// ParseFromFile parses API dependencies from a given file path.
+func (p *APIDependencyRestTestGenParser) ParseFromFile(path string) (*static.APIDependencyGraph, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		log.Err(err).Msgf("[APIDependencyRestTestGenParser.ParseFromFile] Error reading file")
+		return nil, err
+	}
+
+	// Parse the data from the file
+	dependencyGraph, err := p.ParseFromBytes(data)
+	if err != nil {
+		log.Err(err).Msgf("[APIDependencyRestTestGenParser.ParseFromFile] Error parsing data from file")
+		return nil, err
+	}
+	return dependencyGraph, nil
+}
+
+// ParseFromBytes parses the API dependency graph from the given byte slice, which is an ODG in DOT format.
+// Nodes labeled by operationIds are resolved by the system API methods.
+func (p *APIDependencyRestTestGenParser) ParseFromBytes(data []byte) (*static.APIDependencyGraph, error) {
+	var APIMap map[static.SimpleAPIMethod]*openapi3.Operation
+	if p.APIManager != nil {
+		APIMap = p.APIManager.APIMap
+	}
+	dependencyGraph, err := p.parseFromDOT(string(data), APIMap)
+	if err != nil {
+		log.Err(err).Msgf("[APIDependencyRestTestGenParser.ParseFromBytes] Error parsing DOT")
+		return nil, err
+	}
+	log.Debug().Msgf("[APIDependencyRestTestGenParser.ParseFromBytes] Successfully parsed API dependency graph")
+	return dependencyGraph, nil
+}
+
+// ParseFromServiceMapFile parses the API dependency graph from the given service map file.
+// The file is a JSON file that contains a map of service names to their corresponding ODGs in DOT format.
+// Nodes labeled by operationIds are resolved by the API methods of the service.
+func (p *APIDependencyRestTestGenParser) ParseFromServiceMapFile(path string) (map[string]*static.APIDependencyGraph, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		log.Err(err).Msgf("[APIDependencyRestTestGenParser.ParseFromServiceMapFile] Error reading file")
+		return nil, err
+	}
+
+	serviceMap := make(map[string]string)
+	if err := sonic.Unmarshal(data, &serviceMap); err != nil {
+		log.Err(err).Msgf("[APIDependencyRestTestGenParser.ParseFromServiceMapFile] Error parsing JSON")
+		return nil, err
+	}
+
+	dependencyGraphs := make(map[string]*static.APIDependencyGraph)
+	for serviceName, dot := range serviceMap {
+		var serviceAPIMap map[static.SimpleAPIMethod]*openapi3.Operation
+		if p.APIManager != nil {
+			serviceAPIMap = p.APIManager.ServiceAPIMap[serviceName]
+		}
+		dependencyGraph, err := p.parseFromDOT(dot, serviceAPIMap)
+		if err != nil {
+			log.Err(err).Msgf("[APIDependencyRestTestGenParser.ParseFromServiceMapFile] Error parsing DOT for service %s", serviceName)
+			return nil, err
+		}
+		dependencyGraphs[serviceName] = dependencyGraph
+	}
+	log.Debug().Msgf("[APIDependencyRestTestGenParser.ParseFromServiceMapFile] Successfully parsed API dependency graphs for all services")
+	return dependencyGraphs, nil
+}
+
+// parseFromDOT parses the API dependency graph from a given ODG in DOT format.
+// Only node declarations and edges are parsed, and other statements (e.g., graph attributes) are ignored.
+// Nodes labeled by operationIds are resolved by APIMap.
+func (p *APIDependencyRestTestGenParser) parseFromDOT(dot string, APIMap map[static.SimpleAPIMethod]*openapi3.Operation) (*static.APIDependencyGraph, error) {
+	// Only the graph body between the braces contains statements.
+	if start, end := strings.Index(dot, "{"), strings.LastIndex(dot, "}"); start >= 0 && end > start {
+		dot = dot[start+1 : end]
+	}
+	statements := splitDOTStatements(dot)
+
+	// node ID -> label, i.e., "METHOD path"
+	nodeLabels := make(map[string]string)
+	// edges as pairs of (consumer node ID, producer node ID)
+	edges := make([][2]string, 0)
+	for _, statement := range statements {
+		if matches := restTestGenODGEdgeRegex.FindStringSubmatch(statement); matches != nil {
+			edges = append(edges, [2]string{unquoteDOTID(matches[1]), unquoteDOTID(matches[2])})
+			continue
+		}
+		if matches := restTestGenODGNodeRegex.FindStringSubmatch(statement); matches != nil {
+			nodeLabels[unquoteDOTID(matches[1])] = unescapeDOTString(matches[2])
+		}
+	}
+
+	// operationId -> API method
+	operationIDMethods := make(map[string]static.SimpleAPIMethod)
+	for method, operation := range APIMap {
+		if operation != nil && operation.OperationID != "" {
+			operationIDMethods[operation.OperationID] = method
+		}
+	}
+
+	resolveNode := func(nodeID string) (static.SimpleAPIMethod, error) {
+		label, exist := nodeLabels[nodeID]
+		if !exist {
+			label = nodeID
+		}
+		if method, exist := operationIDMethods[strings.TrimSpace(label)]; exist {
+			return method, nil
+		}
+		method, err := parseHTTPAPIMethodString(label)
+		if err != nil {
+			return static.SimpleAPIMethod{}, fmt.Errorf("failed to resolve ODG node %s: %w", nodeID, err)
+		}
+		return method, nil
+	}
+
+	dependencyGraph := static.NewAPIDependencyGraph()
+	for _, edge := range edges {
+		consumer, err := resolveNode(edge[0])
+		if err != nil {
+			log.Err(err).Msgf("[APIDependencyRestTestGenParser.parseFromDOT] Error resolving consumer")
+			return nil, err
+		}
+		producer, err := resolveNode(edge[1])
+		if err != nil {
+			log.Err(err).Msgf("[APIDependencyRestTestGenParser.parseFromDOT] Error resolving producer")
+			return nil, err
+		}
+		log.Debug().Msgf("[APIDependencyRestTestGenParser.parseFromDOT] Adding dependency from %v to %v", producer, consumer)
+		dependencyGraph.AddDependency(producer, consumer)
+	}
+	return dependencyGraph, nil
+}
+
+// splitDOTStatements splits the body of a DOT graph into statements, which are separated by newlines or semicolons.
+// Separators inside quoted strings (with backslash escapes) or attribute lists are not splitting, e.g., `label="a;b"`.
+func splitDOTStatements(body string) []string {
+	statements := make([]string, 0)
+	var statement strings.Builder
+	inQuotes, escaped, bracketDepth := false, false, 0
+	flush := func() {
+		if s := strings.TrimSpace(statement.String()); s != "" {
+			statements = append(statements, s)
+		}
+		statement.Reset()
+	}
+	for _, r := range body {
+		switch {
+		case escaped:
+			escaped = false
+		case inQuotes && r == '\\':
+			escaped = true
+		case r == '"':
+			inQuotes = !inQuotes
+		case inQuotes:
+		case r == '[':
+			bracketDepth++
+		case r == ']' && bracketDepth > 0:
+			bracketDepth--
+		case (r == '\n' || r == ';') && bracketDepth == 0:
+			flush()
+			continue
+		}
+		statement.WriteRune(r)
+	}
+	flush()
+	return statements
+}
+
+// unquoteDOTID returns the DOT ID without quotes and escapes, e.g., `"GET /api"` to `GET /api`.
+// Unquoted IDs are returned as is.
+func unquoteDOTID(id string) string {
+	if len(id) >= 2 && strings.HasPrefix(id, `"`) && strings.HasSuffix(id, `"`) {
+		return unescapeDOTString(id[1 : len(id)-1])
+	}
+	return id
+}
+
+// unescapeDOTString unescapes quotes in a quoted DOT string, the only escape of DOT.
+func unescapeDOTString(s string) string {
+	return strings.ReplaceAll(s, `\"`, `"`)
+}
@@ -0,0 +1,107 @@
+package parser
+
+import (
+	"os"
+	"strings"
+
+	"github.com/bytedance/sonic"
+	"github.com/rs/zerolog/log"
+)
+
+// Types used to parse HAR files, e.g., exported by browser devtools or recorded by `--save-har`.
+// Only fields used to rebuild requests are parsed.
+type harFile struct {
+	Log struct {
+		Pages []struct {
+			ID    string `json:"id"`
+			Title string `json:"title"`
+		} `json:"pages"`
+		Entries []struct {
+			PageRef string `json:"pageref"`
+			Request struct {
+				Method  string `json:"method"`
+				URL     string `json:"url"`
+				Headers []struct {
+					Name  string `json:"name"`
+					Value string `json:"value"`
+				} `json:"headers"`
+				PostData *struct {
+					Text string `json:"text"`
+				} `json:"postData"`
+			} `json:"request"`
+		} `json:"entries"`
+	} `json:"log"`
+}
+
+// harDefaultScenarioName is the name of the seed scenario of HAR entries not belonging to any page.
+const harDefaultScenarioName = "entries"
+
+// SeedHARParser represents a parser for seed scenarios from HAR files.
+// It implements the SeedParser interface.
+// Entries of each page become a seed scenario, and entries not belonging to any page become another one, in the order of entries.
+type SeedHARParser struct {
+}
+
+// NewSeedHARParser creates a new SeedHARParser.
+func NewSeedHARParser() *SeedHARParser {
+	return &SeedHARParser{}
+}
+
+// ParseFromFile parses seed scenarios from a given HAR file path.
+func (p *SeedHARParser) ParseFromFile(path string) ([]*SeedScenario, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		log.Err(err).Msgf("[SeedHARParser.ParseFromFile] Failed to read file: %s", path)
+		return nil, err
+	}
+	return p.ParseFromBytes(data)
+}
+
+// ParseFromBytes parses seed scenarios from the given HAR content.
+func (p *SeedHARParser) ParseFromBytes(data []byte) ([]*SeedScenario, error) {
+	var har harFile
+	err := sonic.Unmarshal(data, &har)
+	if err != nil {
+		log.Err(err).Msg("[SeedHARParser.ParseFromBytes] Failed to unmarshal HAR")
+		return nil, err
+	}
+	pageTitles := make(map[string]string)
+	for _, page := range har.Log.Pages {
+		pageTitles[page.ID] = page.Title
+	}
+	seedScenarios := make([]*SeedScenario, 0)
+	seedScenarioMap := make(map[string]*SeedScenario)
+	for _, entry := range har.Log.Entries {
+		seedScenario, exist := seedScenarioMap[entry.PageRef]
+		if !exist {
+			name := harDefaultScenarioName
+			if entry.PageRef != "" {
+				name = entry.PageRef
+				if title := pageTitles[entry.PageRef]; title != "" {
+					name = title
+				}
+			}
+			seedScenario = &SeedScenario{
+				Name:     name,
+				Requests: make([]*SeedRequest, 0),
+			}
+			seedScenarioMap[entry.PageRef] = seedScenario
+			seedScenarios = append(seedScenarios, seedScenario)
+		}
+		seedRequest := &SeedRequest{
+			Method:  strings.ToUpper(entry.Request.Method),
+			URL:     entry.Request.URL,
+			Headers: make(map[string]string),
+		}
+		for _, header := range entry.Request.Headers {
+			if !isSeedHeaderIgnored(header.Name) {
+				seedRequest.Headers[header.Name] = header.Value
+			}
+		}
+		if entry.Request.PostData != nil && entry.Request.PostData.Text != "" {
+			seedRequest.Body = []byte(entry.Request.PostData.Text)
+		}
+		seedScenario.Requests = append(seedScenario.Requests, seedRequest)
+	}
+	return seedScenarios, nil
+}
@@ -0,0 +1,76 @@
+package parser
+
+import (
+	"fmt"
+	"resttracefuzzer/pkg/utils/http"
+	"strings"
+)
+
+// SeedRequest is a recorded HTTP request, e.g., an entry of a HAR file or a request of a Postman collection.
+type SeedRequest struct {
+	// Method is the HTTP method of the request, in upper case.
+	Method string
+
+	// URL is the full URL of the request, including the query string.
+	URL string
+
+	// Headers are the headers of the request, except those managed by HTTP clients, credentials and trace propagation headers, see [isSeedHeaderIgnored].
+	Headers map[string]string
+
+	// Body is the body of the request, or nil if it has no body.
+	Body []byte
+}
+
+// SeedScenario is a sequence of recorded HTTP requests, which is converted to an initial test scenario.
+type SeedScenario struct {
+	// Name is the name of the scenario, e.g., the page of HAR entries or the folder of Postman requests, used in logs.
+	Name string
+
+	// Requests are the recorded requests, in order.
+	Requests []*SeedRequest
+}
+
+// SeedParser is an interface for parsing recorded traffic into seed scenarios.
+type SeedParser interface {
+	// ParseFromFile parses seed scenarios from the given file path.
+	ParseFromFile(path string) ([]*SeedScenario, error)
+
+	// ParseFromBytes parses seed scenarios from the given byte slice.
+	ParseFromBytes(data []byte) ([]*SeedScenario, error)
+}
+
+// NewSeedParserByType creates a new SeedParser instance based on the given file type.
+func NewSeedParserByType(fileType string) (SeedParser, error) {
+	switch fileType {
+	case "HAR":
+		return NewSeedHARParser(), nil
+	case "Postman":
+		return NewSeedPostmanParser(), nil
+	default:
+		return nil, fmt.Errorf("unsupported seed file type: %s", fileType)
+	}
+}
+
+// ignoredSeedHeaders are lower-cased headers of recorded requests which are managed by HTTP clients,
+// or tied to the recorded connection or session, so they are not replayed.
+var ignoredSeedHeaders = map[string]struct{}{
+	"host":              {},
+	"content-length":    {},
+	"connection":        {},
+	"keep-alive":        {},
+	"transfer-encoding": {},
+	"accept-encoding":   {},
+	"upgrade":           {},
+}
+
+// isSeedHeaderIgnored returns whether the header of a recorded request is not replayed, i.e.,
+// it is managed by HTTP clients (see [ignoredSeedHeaders]), or is an HTTP/2 pseudo header (e.g., `:authority`).
+// Credential (see [http.IsCredentialHeader]) and trace propagation (see [http.IsTracePropagationHeader]) headers are not replayed either,
+// as recorded ones are stale and would override credentials and mesh headers of the fuzzer, e.g., the trace of the replayed request would not be found.
+func isSeedHeaderIgnored(key string) bool {
+	if strings.HasPrefix(key, ":") || http.IsCredentialHeader(key) || http.IsTracePropagationHeader(key) {
+		return true
+	}
+	_, ignored := ignoredSeedHeaders[strings.ToLower(key)]
+	return ignored
+}
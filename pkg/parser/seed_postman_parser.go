@@ -0,0 +1,189 @@
+package parser
+
+import (
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/bytedance/sonic"
+	"github.com/rs/zerolog/log"
+)
+
+// Types used to parse Postman collections (v2.0 and v2.1).
+// Only fields used to rebuild requests are parsed.
+type postmanCollection struct {
+	Info struct {
+		Name string `json:"name"`
+	} `json:"info"`
+	Item     []*postmanItem    `json:"item"`
+	Variable []postmanKeyValue `json:"variable"`
+}
+
+// postmanItem is a request, or a folder of items if Request is nil.
+type postmanItem struct {
+	Name    string          `json:"name"`
+	Item    []*postmanItem  `json:"item"`
+	Request *postmanRequest `json:"request"`
+}
+
+// postmanRequest is a request of a Postman collection.
+type postmanRequest struct {
+	Method string            `json:"method"`
+	Header []postmanKeyValue `json:"header"`
+	Body   *struct {
+		Mode string `json:"mode"`
+		Raw  string `json:"raw"`
+	} `json:"body"`
+	// URL is either a string, or an object with the raw URL and path variables.
+	URL any `json:"url"`
+}
+
+// postmanKeyValue is a key-value pair of Postman collections, e.g., a header or a variable.
+type postmanKeyValue struct {
+	Key      string `json:"key"`
+	Value    string `json:"value"`
+	Disabled bool   `json:"disabled"`
+}
+
+// postmanVariablePattern matches variables in Postman collections, e.g., `{{baseUrl}}`.
+var postmanVariablePattern = regexp.MustCompile(`\{\{\s*([^{}\s]+)\s*\}\}`)
+
+// SeedPostmanParser represents a parser for seed scenarios from Postman collections.
+// It implements the SeedParser interface.
+// Requests directly in each folder become a seed scenario, and requests at the top level become another one, in the order of the collection.
+// Collection variables (e.g., `{{baseUrl}}`) and path variables (e.g., `:petId`) are resolved, and an unresolved variable at the start of the URL
+// is considered as the base URL and removed, so that requests are matched by their paths.
+type SeedPostmanParser struct {
+}
+
+// NewSeedPostmanParser creates a new SeedPostmanParser.
+func NewSeedPostmanParser() *SeedPostmanParser {
+	return &SeedPostmanParser{}
+}
+
+// ParseFromFile parses seed scenarios from a given Postman collection file path.
+func (p *SeedPostmanParser) ParseFromFile(path string) ([]*SeedScenario, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		log.Err(err).Msgf("[SeedPostmanParser.ParseFromFile] Failed to read file: %s", path)
+		return nil, err
+	}
+	return p.ParseFromBytes(data)
+}
+
+// ParseFromBytes parses seed scenarios from the given Postman collection content.
+func (p *SeedPostmanParser) ParseFromBytes(data []byte) ([]*SeedScenario, error) {
+	var collection postmanCollection
+	err := sonic.Unmarshal(data, &collection)
+	if err != nil {
+		log.Err(err).Msg("[SeedPostmanParser.ParseFromBytes] Failed to unmarshal Postman collection")
+		return nil, err
+	}
+	variables := make(map[string]string)
+	for _, variable := range collection.Variable {
+		if !variable.Disabled {
+			variables[variable.Key] = variable.Value
+		}
+	}
+	seedScenarios := make([]*SeedScenario, 0)
+	p.collectSeedScenarios(collection.Info.Name, collection.Item, variables, &seedScenarios)
+	return seedScenarios, nil
+}
+
+// collectSeedScenarios collects the seed scenario of requests directly in the items (named by name),
+// followed by those of folders in the items recursively. Folders without direct requests do not make scenarios.
+func (p *SeedPostmanParser) collectSeedScenarios(name string, items []*postmanItem, variables map[string]string, seedScenarios *[]*SeedScenario) {
+	seedRequests := make([]*SeedRequest, 0)
+	folders := make([]*postmanItem, 0)
+	for _, item := range items {
+		if item == nil {
+			continue
+		}
+		if item.Request == nil {
+			folders = append(folders, item)
+			continue
+		}
+		seedRequests = append(seedRequests, p.convertRequest(item.Request, variables))
+	}
+	if len(seedRequests) > 0 {
+		*seedScenarios = append(*seedScenarios, &SeedScenario{
+			Name:     name,
+			Requests: seedRequests,
+		})
+	}
+	for _, folder := range folders {
+		folderName := folder.Name
+		if name != "" {
+			folderName = name + "/" + folder.Name
+		}
+		p.collectSeedScenarios(folderName, folder.Item, variables, seedScenarios)
+	}
+}
+
+// convertRequest converts a Postman request to a seed request, resolving variables.
+func (p *SeedPostmanParser) convertRequest(request *postmanRequest, variables map[string]string) *SeedRequest {
+	var rawURL string
+	pathVariables := make(map[string]string)
+	switch u := request.URL.(type) {
+	case string:
+		rawURL = u
+	case map[string]any:
+		rawURL, _ = u["raw"].(string)
+		if urlVariables, ok := u["variable"].([]any); ok {
+			for _, urlVariable := range urlVariables {
+				if kv, ok := urlVariable.(map[string]any); ok {
+					key, _ := kv["key"].(string)
+					value, _ := kv["value"].(string)
+					pathVariables[key] = value
+				}
+			}
+		}
+	}
+	rawURL = resolvePostmanVariables(rawURL, variables)
+	// An unresolved base URL variable is removed, e.g., `{{baseUrl}}/pets` becomes `/pets`.
+	if strings.HasPrefix(rawURL, "{{") {
+		if end := strings.Index(rawURL, "}}"); end >= 0 {
+			rawURL = rawURL[end+2:]
+		}
+	}
+	// Path variables are in the form of `:name` segments.
+	if len(pathVariables) > 0 {
+		pathAndQuery := strings.SplitN(rawURL, "?", 2)
+		segments := strings.Split(pathAndQuery[0], "/")
+		for i, segment := range segments {
+			if value, exist := pathVariables[strings.TrimPrefix(segment, ":")]; exist && strings.HasPrefix(segment, ":") {
+				segments[i] = resolvePostmanVariables(value, variables)
+			}
+		}
+		pathAndQuery[0] = strings.Join(segments, "/")
+		rawURL = strings.Join(pathAndQuery, "?")
+	}
+
+	seedRequest := &SeedRequest{
+		Method:  strings.ToUpper(request.Method),
+		URL:     rawURL,
+		Headers: make(map[string]string),
+	}
+	if seedRequest.Method == "" {
+		seedRequest.Method = "GET"
+	}
+	for _, header := range request.Header {
+		if !header.Disabled && !isSeedHeaderIgnored(header.Key) {
+			seedRequest.Headers[header.Key] = resolvePostmanVariables(header.Value, variables)
+		}
+	}
+	if request.Body != nil && request.Body.Mode == "raw" && request.Body.Raw != "" {
+		seedRequest.Body = []byte(resolvePostmanVariables(request.Body.Raw, variables))
+	}
+	return seedRequest
+}
+
+// resolvePostmanVariables replaces variables in s with their values, and keeps unknown ones as is.
+func resolvePostmanVariables(s string, variables map[string]string) string {
+	return postmanVariablePattern.ReplaceAllStringFunc(s, func(placeholder string) string {
+		if value, exist := variables[postmanVariablePattern.FindStringSubmatch(placeholder)[1]]; exist {
+			return value
+		}
+		return placeholder
+	})
+}
@@ -0,0 +1,47 @@
+package report
+
+import (
+	"fmt"
+	"os"
+	"resttracefuzzer/pkg/feedback"
+
+	"github.com/bytedance/sonic"
+	"github.com/rs/zerolog/log"
+)
+
+// ConformanceReporter reports how the server under test conforms to its API doc,
+// e.g., discrepancies between methods the server handles and those documented.
+type ConformanceReporter struct {
+}
+
+// NewConformanceReporter creates a new ConformanceReporter.
+func NewConformanceReporter() *ConformanceReporter {
+	return &ConformanceReporter{}
+}
+
+// GenerateConformanceReport generates the conformance report.
+// At present, it includes the method discrepancies found by method probing.
+func (r *ConformanceReporter) GenerateConformanceReport(methodProber *feedback.MethodProber, outputPath string) error {
+	if methodProber == nil {
+		log.Error().Msg("[ConformanceReporter.GenerateConformanceReport] methodProber is nil.")
+		return fmt.Errorf("methodProber is nil")
+	}
+
+	conformanceReport := ConformanceReport{
+		ProbedPathCount:     methodProber.ProbedPathCount,
+		MethodDiscrepancies: methodProber.Discrepancies,
+	}
+	reportBytes, err := sonic.Marshal(conformanceReport)
+	if err != nil {
+		log.Err(err).Msg("[ConformanceReporter.GenerateConformanceReport] Failed to marshal the report")
+		return err
+	}
+
+	err = os.WriteFile(outputPath, reportBytes, 0644)
+	if err != nil {
+		log.Err(err).Msgf("[ConformanceReporter.GenerateConformanceReport] Failed to write the conformance report to file")
+		return err
+	}
+	log.Info().Msgf("[ConformanceReporter.GenerateConformanceReport] Conformance report has been written to %s", outputPath)
+	return nil
+}
@@ -2,8 +2,13 @@ package report
 
 import (
 	"fmt"
+	"maps"
 	"os"
+	"resttracefuzzer/pkg/casemanager"
 	"resttracefuzzer/pkg/resource"
+	fuzzruntime "resttracefuzzer/pkg/runtime"
+	"resttracefuzzer/pkg/static"
+	"slices"
 
 	"github.com/bytedance/sonic"
 	"github.com/rs/zerolog/log"
@@ -16,7 +21,16 @@ func NewFuzzerStateReporter() *FuzzerStateReporter {
 	return &FuzzerStateReporter{}
 }
 
-func (r *FuzzerStateReporter) GenerateFuzzerStateReport(resourceManager *resource.ResourceManager, outputPath string) error {
+// GenerateFuzzerStateReport generates the fuzzer state report, including the resource pool, summaries of queues,
+// statistics of producer-consumer relations used to extend scenarios, statistics of the reachability map, and the config snapshot.
+// caseManager and reachabilityMap are optional, and their summaries are omitted if nil.
+func (r *FuzzerStateReporter) GenerateFuzzerStateReport(
+	resourceManager *resource.ResourceManager,
+	caseManager *casemanager.CaseManager,
+	reachabilityMap *fuzzruntime.RuntimeReachabilityMap,
+	configSnapshot any,
+	outputPath string,
+) error {
 	if resourceManager == nil {
 		log.Error().Msg("[FuzzerStateReporter.GenerateFuzzerStateReport] resourceManager is nil.")
 		return fmt.Errorf("resourceManager is nil")
@@ -40,6 +54,18 @@ func (r *FuzzerStateReporter) GenerateFuzzerStateReport(resourceManager *resourc
 	fuzzerStateReport := FuzzerStateReport{
 		ResourceNameMap:            resourceManager.ResourceNameMap,
 		ResourceJSONObjectNameMap:   resourceJSONObjectNameMap,
+		Config:                      configSnapshot,
+	}
+	if caseManager != nil {
+		fuzzerStateReport.ScenarioQueue = summarizeScenarioQueue(caseManager.TestScenarios.Values())
+		fuzzerStateReport.OperationCaseQueues = summarizeOperationCaseQueues(caseManager.GetOperationCaseQueues())
+		fuzzerStateReport.ProducerConsumerRelations = caseManager.RelationTracker.GetStats()
+	}
+	if reachabilityMap != nil {
+		fuzzerStateReport.Reachability = &RuntimeReachabilityStats{
+			HighConfidence: getReachabilityMapStats(reachabilityMap.HighConfidenceMap),
+			LowConfidence:  getReachabilityMapStats(reachabilityMap.LowConfidenceMap),
+		}
 	}
 	reportBytes, err := sonic.Marshal(fuzzerStateReport)
 	if err != nil {
@@ -55,3 +81,60 @@ func (r *FuzzerStateReporter) GenerateFuzzerStateReport(resourceManager *resourc
 	log.Info().Msgf("[FuzzerStateReporter.GenerateFuzzerStateReport] Fuzzer state report has been written to %s", outputPath)
 	return nil
 }
+
+// summarizeScenarioQueue summarizes the test scenario queue.
+func summarizeScenarioQueue(testScenarios []*casemanager.TestScenario) *ScenarioQueueSummary {
+	summary := &ScenarioQueueSummary{
+		Size:                   len(testScenarios),
+		LengthHistogram:        make(map[int]int),
+		EnergyHistogram:        make(map[int]int),
+		ExecutedCountHistogram: make(map[int]int),
+	}
+	for _, testScenario := range testScenarios {
+		summary.TotalExecutedCount += testScenario.ExecutedCount
+		summary.LengthHistogram[len(testScenario.OperationCases)]++
+		summary.EnergyHistogram[testScenario.Energy]++
+		summary.ExecutedCountHistogram[testScenario.ExecutedCount]++
+	}
+	return summary
+}
+
+// summarizeOperationCaseQueues summarizes operation case queues, sorted by API method.
+func summarizeOperationCaseQueues(queueMap map[static.SimpleAPIMethod][]*casemanager.OperationCase) []*OperationCaseQueueSummary {
+	summaries := make([]*OperationCaseQueueSummary, 0, len(queueMap))
+	for _, method := range slices.SortedFunc(maps.Keys(queueMap), static.CompareSimpleAPIMethod) {
+		summary := &OperationCaseQueueSummary{
+			APIMethod:           method,
+			Size:                len(queueMap[method]),
+			EnergyHistogram:     make(map[int]int),
+			StatusCodeHistogram: make(map[int]int),
+		}
+		for _, operationCase := range queueMap[method] {
+			summary.TotalExecutedCount += operationCase.ExecutedCount
+			summary.EnergyHistogram[operationCase.Energy]++
+			summary.StatusCodeHistogram[operationCase.ResponseStatusCode]++
+		}
+		summaries = append(summaries, summary)
+	}
+	return summaries
+}
+
+// getReachabilityMapStats returns the statistics of the reachability map.
+func getReachabilityMapStats(reachabilityMap *static.ReachabilityMap) *ReachabilityMapStats {
+	stats := &ReachabilityMapStats{}
+	if reachabilityMap == nil {
+		return stats
+	}
+	for _, internals := range reachabilityMap.External2Internal {
+		if len(internals) > 0 {
+			stats.PairCount += len(internals)
+			stats.ExternalAPICount++
+		}
+	}
+	for _, externals := range reachabilityMap.Internal2External {
+		if len(externals) > 0 {
+			stats.InternalEndpointCount++
+		}
+	}
+	return stats
+}
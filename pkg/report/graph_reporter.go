@@ -0,0 +1,87 @@
+package report
+
+import (
+	"fmt"
+	"os"
+	fuzzruntime "resttracefuzzer/pkg/runtime"
+	"resttracefuzzer/pkg/static"
+	"resttracefuzzer/pkg/utils"
+
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	// graphCoveredEdgeColor is the color of edges hit at runtime in exported call info graphs.
+	graphCoveredEdgeColor = "green"
+
+	// graphUncoveredEdgeColor is the color of edges never hit at runtime in exported call info graphs.
+	graphUncoveredEdgeColor = "red"
+)
+
+// GraphReporter exports graphs of internal services as Graphviz DOT and Mermaid files, for visualization of microservice coverage.
+type GraphReporter struct {
+}
+
+// NewGraphReporter creates a new GraphReporter.
+func NewGraphReporter() *GraphReporter {
+	return &GraphReporter{}
+}
+
+// GenerateCallInfoGraphReport exports the runtime call info graph to `<outputPathPrefix>.dot` and `<outputPathPrefix>.mmd`.
+// Edges are labeled with hit counts, and colored by whether they are hit.
+func (r *GraphReporter) GenerateCallInfoGraphReport(callInfoGraph *fuzzruntime.CallInfoGraph, outputPathPrefix string) error {
+	edgeStyle := func(edge *fuzzruntime.CallInfoEdge) utils.GraphEdgeStyle {
+		color := graphUncoveredEdgeColor
+		if edge.HitCount > 0 {
+			color = graphCoveredEdgeColor
+		}
+		return utils.GraphEdgeStyle{
+			Label: fmt.Sprintf("hits: %d", edge.HitCount),
+			Color: color,
+		}
+	}
+	return r.writeGraph(
+		callInfoGraph.ExportDOT("CallInfoGraph", getInternalServiceEndpointGraphLabel, edgeStyle),
+		callInfoGraph.ExportMermaid(getInternalServiceEndpointGraphLabel, edgeStyle),
+		outputPathPrefix,
+	)
+}
+
+// GenerateAPIDataflowGraphReport exports the static dataflow graph to `<outputPathPrefix>.dot` and `<outputPathPrefix>.mmd`.
+// Edges are labeled with the source and target properties of the dataflow.
+func (r *GraphReporter) GenerateAPIDataflowGraphReport(APIDataflowGraph *static.APIDataflowGraph, outputPathPrefix string) error {
+	edgeStyle := func(edge *static.APIDataflowEdge) utils.GraphEdgeStyle {
+		return utils.GraphEdgeStyle{
+			Label: fmt.Sprintf("%s -> %s", edge.SourceProperty.Name, edge.TargetProperty.Name),
+		}
+	}
+	return r.writeGraph(
+		APIDataflowGraph.ExportDOT("APIDataflowGraph", getInternalServiceEndpointGraphLabel, edgeStyle),
+		APIDataflowGraph.ExportMermaid(getInternalServiceEndpointGraphLabel, edgeStyle),
+		outputPathPrefix,
+	)
+}
+
+// writeGraph writes the graph in DOT and Mermaid formats to files with the path prefix.
+func (r *GraphReporter) writeGraph(dot, mermaid, outputPathPrefix string) error {
+	for path, content := range map[string]string{
+		outputPathPrefix + ".dot": dot,
+		outputPathPrefix + ".mmd": mermaid,
+	} {
+		err := os.WriteFile(path, []byte(content), 0644)
+		if err != nil {
+			log.Err(err).Msgf("[GraphReporter.writeGraph] Failed to write graph to file %s", path)
+			return err
+		}
+		log.Info().Msgf("[GraphReporter.writeGraph] Graph written to %s", path)
+	}
+	return nil
+}
+
+// getInternalServiceEndpointGraphLabel returns the label of an internal service endpoint in exported graphs, with the service name in the first line.
+func getInternalServiceEndpointGraphLabel(endpoint static.InternalServiceEndpoint) string {
+	if endpoint.SimpleAPIMethod.Method == "" {
+		return endpoint.ServiceName + "\n" + endpoint.SimpleAPIMethod.Endpoint
+	}
+	return endpoint.ServiceName + "\n" + endpoint.SimpleAPIMethod.Method + " " + endpoint.SimpleAPIMethod.Endpoint
+}
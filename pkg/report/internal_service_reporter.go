@@ -2,6 +2,7 @@ package report
 
 import (
 	"os"
+	"resttracefuzzer/pkg/feedback"
 	fuzzruntime "resttracefuzzer/pkg/runtime"
 	"resttracefuzzer/pkg/static"
 	"slices"
@@ -20,10 +21,18 @@ func NewInternalServiceReporter() *InternalServiceReporter {
 }
 
 // GenerateInternalServiceReport generates the internal service report.
-// The report includes the edge coverage.
+// The report includes the edge coverage, span errors if spanErrorCoverage is not nil, database operations if databaseCoverage is not nil,
+// code coverage of services under test if codeCoverageTracker is not nil, schema drifts if schemaDrifts is not nil, shadow APIs if shadowAPIs is not nil,
+// and unreached internal service APIs if unreachedInternalAPIs is not nil.
 func (r *InternalServiceReporter) GenerateInternalServiceReport(
 	callInfoGraph *fuzzruntime.CallInfoGraph,
 	runtimeReachabilityMap *fuzzruntime.RuntimeReachabilityMap,
+	spanErrorCoverage *fuzzruntime.SpanErrorCoverage,
+	databaseCoverage *fuzzruntime.DatabaseCoverage,
+	codeCoverageTracker *feedback.CodeCoverageTracker,
+	schemaDrifts []*feedback.SchemaDrift,
+	shadowAPIs []*feedback.ShadowAPI,
+	unreachedInternalAPIs []*feedback.UnreachedInternalAPI,
 	outputPath string,
 ) error {
 	// At present, we only report the edge coverage.
@@ -34,7 +43,11 @@ func (r *InternalServiceReporter) GenerateInternalServiceReport(
 		}
 	}
 	// Calculate the coverage of the edges.
-	edgeCoverage := float64(coveredEdges) / float64(len(callInfoGraph.Edges))
+	// Without edges (e.g., no dependency file is provided), the coverage is 0 rather than NaN, which cannot be marshalled to JSON.
+	edgeCoverage := 0.0
+	if len(callInfoGraph.Edges) > 0 {
+		edgeCoverage = float64(coveredEdges) / float64(len(callInfoGraph.Edges))
+	}
 
 	slices.SortFunc(callInfoGraph.Edges, func(a, b *fuzzruntime.CallInfoEdge) int {
 		return static.CompareInternalServiceEndpoint(a.Source, b.Source)
@@ -45,7 +58,23 @@ func (r *InternalServiceReporter) GenerateInternalServiceReport(
 		EdgeCoverage:       edgeCoverage,
 		RuntimeHighConfidenceReachabilityMap: NewReachabilityMapForReport(runtimeReachabilityMap.HighConfidenceMap),
 		FinalCallInfoGraph: callInfoGraph,
+		SpanErrors:         make([]*fuzzruntime.SpanErrorEntry, 0),
+		DatabaseOperations: make([]*fuzzruntime.DatabaseOperationEntry, 0),
+	}
+	if spanErrorCoverage != nil {
+		report.SpanErrors = spanErrorCoverage.GetSpanErrorEntries()
+	}
+	if databaseCoverage != nil {
+		report.DatabaseOperationCoveredCount = databaseCoverage.GetCoveredCount()
+		report.DatabaseOperations = databaseCoverage.GetDatabaseOperationEntries()
+	}
+	if codeCoverageTracker != nil {
+		report.CodeCoverage = codeCoverageTracker.GetCombinedCoverage()
+		report.ServiceCodeCoverages = codeCoverageTracker.LatestCoverages
 	}
+	report.SchemaDrifts = schemaDrifts
+	report.ShadowAPIs = shadowAPIs
+	report.UnreachedInternalAPIs = unreachedInternalAPIs
 	reportJSON, err := sonic.Marshal(report)
 	if err != nil {
 		log.Err(err).Msgf("[InternalServiceReporter.GenerateInternalServiceReport] Failed to marshal the internal service report")
@@ -3,9 +3,12 @@ package report
 import (
 	"fmt"
 	"resttracefuzzer/pkg/casemanager"
+	"resttracefuzzer/pkg/feedback"
+	"resttracefuzzer/pkg/feedback/trace"
 	"resttracefuzzer/pkg/resource"
 	fuzzruntime "resttracefuzzer/pkg/runtime"
 	"resttracefuzzer/pkg/static"
+	"resttracefuzzer/pkg/strategy"
 	"slices"
 	"time"
 
@@ -36,6 +39,88 @@ type SystemTestReport struct {
 	// It is generated from statusHitCount.
 	// You should set statusHitCount using SetStatusHitCountReport.
 	APIMethodStatusHitCountReport []APIMethodStatusHitCountReport `json:"APIMethodStatusHitCountReport"`
+
+	// CoveredStatusSequenceCount is the number of distinct status sequences covered by test scenarios, i.e., scenario-level coverage.
+	CoveredStatusSequenceCount int `json:"coveredStatusSequenceCount"`
+
+	// OperationCollisions are duplicate operationIds and path collisions detected in API docs,
+	// along with the aliases used to disambiguate them.
+	OperationCollisions []*static.OperationCollision `json:"operationCollisions"`
+
+	// CRUDInvariantViolations are the violations of resource lifecycle invariants found during fuzzing.
+	CRUDInvariantViolations []*feedback.CRUDInvariantViolation `json:"CRUDInvariantViolations"`
+
+	// SecurityFindings are the suspicious responses to requests carrying security payloads, e.g., SQL errors and reflected XSS payloads.
+	SecurityFindings []*feedback.SecurityFinding `json:"securityFindings"`
+
+	// BOLAViolations are the cases where a user succeeds in reading or mutating resources created by another user.
+	BOLAViolations []*feedback.BOLAViolation `json:"BOLAViolations"`
+
+	// IdempotencyViolations are the requests with idempotency keys whose replays with the same keys get different responses.
+	IdempotencyViolations []*feedback.IdempotencyViolation `json:"idempotencyViolations"`
+
+	// BehaviorDifferences are the requests to which the baseline and the candidate versions respond differently in differential fuzzing.
+	BehaviorDifferences []*feedback.BehaviorDifference `json:"behaviorDifferences"`
+
+	// MinimizedFailures are the server errors triggered by multi-operation test scenarios, with the shortest reproducing scenarios.
+	MinimizedFailures []*MinimizedFailureReport `json:"minimizedFailures"`
+
+	// LatencySLOViolations are the API methods whose response time percentiles exceed their latency SLOs.
+	LatencySLOViolations []*feedback.LatencySLOViolation `json:"latencySLOViolations"`
+
+	// SpanLatencyAnomalies are the requests whose internal spans are abnormally slow, with the triggering scenarios.
+	SpanLatencyAnomalies []*SpanLatencyAnomalyReport `json:"spanLatencyAnomalies"`
+
+	// ContextPropagationBreaks are the calls between services where context headers reach the caller but not the callee.
+	ContextPropagationBreaks []*feedback.ContextPropagationBreak `json:"contextPropagationBreaks"`
+
+	// StatusCodeTargetGap is the gap between the configured status code targets and the status codes covered.
+	StatusCodeTargetGap *feedback.StatusCodeTargetGap `json:"statusCodeTargetGap"`
+
+	// EnumCoverage is the ratio of exercised enum members among all enum members declared by parameters.
+	EnumCoverage float64 `json:"enumCoverage"`
+
+	// EnumParameterCoverages are the exercised enum members of each parameter declaring enum.
+	EnumParameterCoverages []*feedback.EnumParameterCoverage `json:"enumParameterCoverages"`
+
+	// EndpointPerformances are the response time percentiles, timeout and connection error counts, and availability of each API method requested.
+	EndpointPerformances []*feedback.EndpointPerformance `json:"endpointPerformances"`
+
+	// ResourceCleanupResults are the results of deleting resources created during fuzzing, in the cleanup after fuzzing.
+	ResourceCleanupResults []*feedback.ResourceCleanupResult `json:"resourceCleanupResults"`
+
+	// LeftoverResources are the resources created during fuzzing and left in the system, i.e., neither deleted in fuzzing nor in the cleanup,
+	// in the reverse order of creation.
+	LeftoverResources []*feedback.CreatedResource `json:"leftoverResources"`
+
+	// SkippedOperations are the operations skipped in fuzzing, as they require features the fuzzer does not support yet.
+	// They are excluded from the coverage above.
+	SkippedOperations []*SkippedOperationReport `json:"skippedOperations"`
+
+	// CapabilityGaps are the parts of the API doc the fuzzer could not exercise, as it falls back on features it does not support,
+	// e.g., unknown schema types, unsupported parameter locations and media types.
+	CapabilityGaps []*strategy.CapabilityGap `json:"capabilityGaps"`
+
+	// TraceWaitStats is the distribution of delays until traces became queryable in the trace backend, and the wait before fetching traces at the end.
+	TraceWaitStats *trace.TraceWaitStats `json:"traceWaitStats"`
+}
+
+// SkippedOperationStatusUnsupported is the status of operations skipped for requiring unsupported features.
+const SkippedOperationStatusUnsupported = "skipped: unsupported"
+
+// SkippedOperationReport is the report of an operation skipped in fuzzing.
+type SkippedOperationReport struct {
+	// APIMethod is the API method of the skipped operation.
+	APIMethod static.SimpleAPIMethod `json:"APIMethod"`
+
+	// Status is why the operation is skipped, e.g., [SkippedOperationStatusUnsupported].
+	Status string `json:"status"`
+
+	// Reason is the unsupported feature category, see [resttracefuzzer/pkg/static.UnsupportedOperationReason].
+	Reason static.UnsupportedOperationReason `json:"reason"`
+
+	// Detail describes the unsupported feature.
+	Detail string `json:"detail"`
 }
 
 // SetStatusHitCountReport sets the status hit count report.
@@ -68,6 +153,44 @@ type InternalServiceTestReport struct {
 	// RuntimeHighConfidenceReachabilityMap is the runtime reachability map.
 	// By default, it only includes high confidence reachability map.
 	RuntimeHighConfidenceReachabilityMap *ReachabilityMapForReport `json:"runtimeHighConfidenceReachabilityMap"`
+
+	// SpanErrors are errors (error status and exceptions) of spans in traces, i.e., distinct (service, operation, error type) tuples.
+	SpanErrors []*fuzzruntime.SpanErrorEntry `json:"spanErrors"`
+
+	// DatabaseOperationCoveredCount is the number of distinct (service, table, operation) tuples from database spans in traces.
+	DatabaseOperationCoveredCount int `json:"databaseOperationCoveredCount"`
+
+	// DatabaseOperations are the database operations from database spans in traces, with hit counts.
+	DatabaseOperations []*fuzzruntime.DatabaseOperationEntry `json:"databaseOperations"`
+
+	// CodeCoverage is the combined line and branch coverage of services under test, collected by coverage collectors.
+	// It is nil if no coverage collector is configured.
+	CodeCoverage *feedback.CodeCoverage `json:"codeCoverage,omitempty"`
+
+	// ServiceCodeCoverages maps from coverage collector names to the code coverage they collected last.
+	ServiceCodeCoverages map[string]*feedback.CodeCoverage `json:"serviceCodeCoverages,omitempty"`
+
+	// SchemaDrifts are likely drifts between the internal service doc and APIs observed in traces.
+	// It is nil if schema drift detection is disabled.
+	SchemaDrifts []*feedback.SchemaDrift `json:"schemaDrifts,omitempty"`
+
+	// ShadowAPIs are APIs observed in traces but not declared in any API doc.
+	// It is nil if shadow API detection is disabled.
+	ShadowAPIs []*feedback.ShadowAPI `json:"shadowAPIs,omitempty"`
+
+	// UnreachedInternalAPIs are internal service APIs in the internal service doc which never appear in any trace of the run.
+	// It is nil if the internal service doc is not provided, or no trace is fetched.
+	UnreachedInternalAPIs []*feedback.UnreachedInternalAPI `json:"unreachedInternalAPIs,omitempty"`
+}
+
+// ConformanceReport is the report of how the server under test conforms to its API doc.
+type ConformanceReport struct {
+
+	// ProbedPathCount is the number of paths probed for method handling.
+	ProbedPathCount int `json:"probedPathCount"`
+
+	// MethodDiscrepancies are the discrepancies between methods the server handles and those documented.
+	MethodDiscrepancies []*feedback.MethodDiscrepancy `json:"methodDiscrepancies"`
 }
 
 // FuzzerStateReport is the report of the fuzzer state.
@@ -80,6 +203,82 @@ type FuzzerStateReport struct {
 
 	// ResourceJSONObjectNameMap is the jsonified version of ResourceNameMap.
 	ResourceJSONObjectNameMap map[string][]interface{} `json:"resourceNameMap"`
+
+	// ScenarioQueue is the summary of the test scenario queue at the end of fuzzing.
+	ScenarioQueue *ScenarioQueueSummary `json:"scenarioQueue"`
+
+	// OperationCaseQueues are the summaries of operation case queues of each API method at the end of fuzzing, sorted by API method.
+	OperationCaseQueues []*OperationCaseQueueSummary `json:"operationCaseQueues"`
+
+	// ProducerConsumerRelations are the statistics of producer-consumer relations actually used to extend test scenarios,
+	// sorted by the extension count in descending order.
+	// Relations with many executions but few successes are likely false, e.g., false dataflow edges.
+	ProducerConsumerRelations []*casemanager.ProducerConsumerRelationStat `json:"producerConsumerRelations"`
+
+	// Reachability is the statistics of the runtime reachability map at the end of fuzzing.
+	Reachability *RuntimeReachabilityStats `json:"reachability"`
+
+	// Config is the configuration of the run, with sensitive items redacted.
+	Config any `json:"config"`
+}
+
+// ScenarioQueueSummary is the summary of the test scenario queue.
+// Histograms map from a value (e.g., energy) to the number of scenarios with the value.
+type ScenarioQueueSummary struct {
+	// Size is the number of test scenarios in the queue.
+	Size int `json:"size"`
+
+	// TotalExecutedCount is the sum of executed counts of test scenarios in the queue.
+	TotalExecutedCount int `json:"totalExecutedCount"`
+
+	// LengthHistogram is the histogram of the number of operation cases in scenarios.
+	LengthHistogram map[int]int `json:"lengthHistogram"`
+
+	// EnergyHistogram is the histogram of energy of scenarios.
+	EnergyHistogram map[int]int `json:"energyHistogram"`
+
+	// ExecutedCountHistogram is the histogram of executed counts of scenarios.
+	ExecutedCountHistogram map[int]int `json:"executedCountHistogram"`
+}
+
+// OperationCaseQueueSummary is the summary of the operation case queue of an API method.
+// Histograms map from a value (e.g., energy) to the number of operation cases with the value.
+type OperationCaseQueueSummary struct {
+	// APIMethod is the API method of the queue.
+	APIMethod static.SimpleAPIMethod `json:"APIMethod"`
+
+	// Size is the number of operation cases in the queue.
+	Size int `json:"size"`
+
+	// TotalExecutedCount is the sum of executed counts of operation cases in the queue.
+	TotalExecutedCount int `json:"totalExecutedCount"`
+
+	// EnergyHistogram is the histogram of energy of operation cases.
+	EnergyHistogram map[int]int `json:"energyHistogram"`
+
+	// StatusCodeHistogram is the histogram of response status codes of the last execution of operation cases.
+	StatusCodeHistogram map[int]int `json:"statusCodeHistogram"`
+}
+
+// RuntimeReachabilityStats is the statistics of [resttracefuzzer/pkg/runtime.RuntimeReachabilityMap].
+type RuntimeReachabilityStats struct {
+	// HighConfidence is the statistics of the high confidence map, i.e., reachability learned from traces.
+	HighConfidence *ReachabilityMapStats `json:"highConfidence"`
+
+	// LowConfidence is the statistics of the low confidence map, i.e., reachability inferred from API docs but not observed yet.
+	LowConfidence *ReachabilityMapStats `json:"lowConfidence"`
+}
+
+// ReachabilityMapStats is the statistics of a [resttracefuzzer/pkg/static.ReachabilityMap].
+type ReachabilityMapStats struct {
+	// PairCount is the number of (external API, internal endpoint) pairs.
+	PairCount int `json:"pairCount"`
+
+	// ExternalAPICount is the number of external APIs reaching at least one internal endpoint.
+	ExternalAPICount int `json:"externalAPICount"`
+
+	// InternalEndpointCount is the number of internal endpoints reached by at least one external API.
+	InternalEndpointCount int `json:"internalEndpointCount"`
 }
 
 // OperationCaseForReport stores info of an operation tested during fuzzing.
@@ -103,17 +302,88 @@ type OperationCaseForReport struct {
 
 	// ResponseStatusCode is the expected status code of the response.
 	ResponseStatusCode int `json:"responseStatusCode"`
+
+	// LowConfidenceReasons tell why the request is generated on a best-effort basis, e.g., a parameter declares no schema.
+	LowConfidenceReasons []string `json:"lowConfidenceReasons,omitempty"`
+
+	// NegativeMode tells whether the request is populated in negative mode, i.e., biased toward error responses.
+	NegativeMode bool `json:"negativeMode,omitempty"`
+
+	// ExtensionRelations are the producer-consumer relations justifying extending the test scenario with the operation.
+	ExtensionRelations []casemanager.ProducerConsumerRelation `json:"extensionRelations,omitempty"`
+
+	// RequestURL is the fully resolved URL of the request, with path and query params.
+	// It is only set in dry run, where requests are not sent.
+	RequestURL string `json:"requestURL,omitempty"`
+
+	// TraceID is the ID of the trace produced by the request, if known.
+	TraceID string `json:"traceID,omitempty"`
+
+	// TraceFile is the path of the saved trace JSON of the request, relative to the run output directory.
+	// It is only set in the test log report, if raw traces are saved (see [TestLogReporter.TraceFileLocator]).
+	TraceFile string `json:"traceFile,omitempty"`
 }
 
 // NewReportFromOperationCase creates a new OperationCaseForReport from an OperationCase.
 func NewReportFromOperationCase(operationCase *casemanager.OperationCase) *OperationCaseForReport {
 	return &OperationCaseForReport{
-		APIMethod:          operationCase.APIMethod,
-		RequestHeaders:     operationCase.RequestHeaders,
-		RequestPathParams:  operationCase.RequestPathParams,
-		RequestQueryParams: operationCase.RequestQueryParams,
-		RequestBody:        string(operationCase.RequestBody),
-		ResponseStatusCode: operationCase.ResponseStatusCode,
+		APIMethod:            operationCase.APIMethod,
+		RequestHeaders:       operationCase.RequestHeaders,
+		RequestPathParams:    operationCase.RequestPathParams,
+		RequestQueryParams:   operationCase.RequestQueryParams,
+		RequestBody:          string(operationCase.RequestBody),
+		ResponseStatusCode:   operationCase.ResponseStatusCode,
+		LowConfidenceReasons: operationCase.LowConfidenceReasons,
+		NegativeMode:         operationCase.NegativeMode,
+		ExtensionRelations:   operationCase.ExtensionRelations,
+		TraceID:              operationCase.TraceID,
+	}
+}
+
+// MinimizedFailureReport is the report of a server error triggered by a multi-operation test scenario.
+// Simplified version of [resttracefuzzer/pkg/feedback.MinimizedFailure]
+type MinimizedFailureReport struct {
+	*feedback.MinimizedFailure
+
+	// OperationCases are the operation cases of the minimized scenario, with the failing one as the last.
+	OperationCases []*OperationCaseForReport `json:"operationCases"`
+
+	// MinimizedLength is the number of operation cases of the minimized scenario.
+	// It is used to improve the readability of the report.
+	MinimizedLength int `json:"minimizedLength"`
+}
+
+// NewReportFromMinimizedFailure creates a new MinimizedFailureReport from a MinimizedFailure.
+func NewReportFromMinimizedFailure(failure *feedback.MinimizedFailure) *MinimizedFailureReport {
+	operationCases := make([]*OperationCaseForReport, 0, len(failure.OperationCases))
+	for _, operationCase := range failure.OperationCases {
+		operationCases = append(operationCases, NewReportFromOperationCase(operationCase))
+	}
+	return &MinimizedFailureReport{
+		MinimizedFailure: failure,
+		OperationCases:   operationCases,
+		MinimizedLength:  len(operationCases),
+	}
+}
+
+// SpanLatencyAnomalyReport is the report of a request whose internal span is abnormally slow.
+// Simplified version of [resttracefuzzer/pkg/feedback.SpanLatencyAnomaly]
+type SpanLatencyAnomalyReport struct {
+	*feedback.SpanLatencyAnomaly
+
+	// OperationCases are the operation cases of the test scenario, with the one triggering the anomaly as the last.
+	OperationCases []*OperationCaseForReport `json:"operationCases"`
+}
+
+// NewReportFromSpanLatencyAnomaly creates a new SpanLatencyAnomalyReport from a SpanLatencyAnomaly.
+func NewReportFromSpanLatencyAnomaly(anomaly *feedback.SpanLatencyAnomaly) *SpanLatencyAnomalyReport {
+	operationCases := make([]*OperationCaseForReport, 0, len(anomaly.OperationCases))
+	for _, operationCase := range anomaly.OperationCases {
+		operationCases = append(operationCases, NewReportFromOperationCase(operationCase))
+	}
+	return &SpanLatencyAnomalyReport{
+		SpanLatencyAnomaly: anomaly,
+		OperationCases:     operationCases,
 	}
 }
 
@@ -133,6 +403,9 @@ type TestScenarioForReport struct {
 
 	// TestScenarioUUID is the UUID of the test scenario.
 	TestScenarioUUID uuid.UUID `json:"testScenarioUUID"`
+
+	// ChaosExperiment is the name of the chaos experiment the test scenario is run under, if any.
+	ChaosExperiment string `json:"chaosExperiment,omitempty"`
 }
 
 // NewReportFromTestScenario creates a new TestScenarioForReport from a TestScenario.
@@ -146,6 +419,7 @@ func NewReportFromTestScenario(testScenario *casemanager.TestScenario) *TestScen
 		OperationCaseLength: len(operationCases),
 		EndTime:             time.Now(),
 		TestScenarioUUID:    testScenario.UUID,
+		ChaosExperiment:     testScenario.ChaosExperiment,
 	}
 }
 
@@ -159,6 +433,38 @@ type TestLogReport struct {
 	// TestedScenariosLengthCount records the number of tested scenarios of each length.
 	// It maps from length of the tested scenarios to the number of tested scenarios.
 	TestedScenariosLengthCount map[int]int `json:"testedScenariosLengthCount"`
+
+	// LowConfidenceOperationCaseCount is the number of tested operation cases whose requests are generated on a best-effort basis,
+	// e.g., some of their parameters or request bodies declare no schema.
+	LowConfidenceOperationCaseCount int `json:"lowConfidenceOperationCaseCount"`
+
+	// DryRun indicates whether the scenarios are populated in dry run, i.e., requests are not sent, and there is no response.
+	DryRun bool `json:"dryRun"`
+
+	// FailingOperations link tested operation cases responded with server errors (i.e., 5xx) to their test scenarios and traces,
+	// in the order of testing.
+	FailingOperations []*FailingOperationLink `json:"failingOperations"`
+}
+
+// FailingOperationLink links a tested operation case responded with a server error to its test scenario and trace.
+type FailingOperationLink struct {
+	// TestScenarioUUID is the UUID of the test scenario, see [TestScenarioForReport.TestScenarioUUID].
+	TestScenarioUUID uuid.UUID `json:"testScenarioUUID"`
+
+	// OperationIndex is the index of the operation case in the test scenario, see [TestScenarioForReport.OperationCases].
+	OperationIndex int `json:"operationIndex"`
+
+	// APIMethod is the API method of the operation case.
+	APIMethod static.SimpleAPIMethod `json:"APIMethod"`
+
+	// ResponseStatusCode is the status code of the response.
+	ResponseStatusCode int `json:"responseStatusCode"`
+
+	// TraceID is the ID of the trace produced by the request, if known.
+	TraceID string `json:"traceID,omitempty"`
+
+	// TraceFile is the path of the saved trace JSON of the request, see [OperationCaseForReport.TraceFile].
+	TraceFile string `json:"traceFile,omitempty"`
 }
 
 // NewTestLogReport creates a new TestLogReport.
@@ -166,6 +472,7 @@ func NewTestLogReport() *TestLogReport {
 	return &TestLogReport{
 		TestedScenarios: make([]*TestScenarioForReport, 0),
 		TestedScenariosLengthCount: make(map[int]int),
+		FailingOperations: make([]*FailingOperationLink, 0),
 	}
 }
 
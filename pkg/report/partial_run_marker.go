@@ -0,0 +1,75 @@
+package report
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/bytedance/sonic"
+	"github.com/rs/zerolog/log"
+)
+
+// PartialRunMarkerFileName is the name of the partial-run marker file in the run output directory, see [PartialRunMarker].
+const PartialRunMarkerFileName = "partial_run.json"
+
+// Reasons of partial runs, see [PartialRunMarker.Reason].
+const (
+	// PartialRunReasonIntermediate means that reports are intermediate ones of a running fuzzer,
+	// or the fuzzer was killed (or aborted) before writing final reports.
+	PartialRunReasonIntermediate = "intermediate"
+
+	// PartialRunReasonInterrupted means that the fuzzer was interrupted (by SIGINT or SIGTERM) before the budget was exhausted,
+	// and reports cover the work done so far.
+	PartialRunReasonInterrupted = "interrupted"
+)
+
+// PartialRunMarker marks reports in the run output directory as reports of a partial run, i.e., not covering the whole budget.
+// It is removed once the final reports of a completed run are written.
+type PartialRunMarker struct {
+	// Reason tells why the run is partial, e.g., [PartialRunReasonInterrupted].
+	Reason string `json:"reason"`
+
+	// Signal is the signal interrupting the run, if any.
+	Signal string `json:"signal,omitempty"`
+
+	// WrittenAt is the time the marker (and reports) was written.
+	WrittenAt time.Time `json:"writtenAt"`
+
+	// ElapsedSeconds is the time elapsed since fuzzing started when the marker was written, in seconds.
+	ElapsedSeconds float64 `json:"elapsedSeconds"`
+
+	// BudgetSeconds is the time budget of fuzzing, in seconds.
+	BudgetSeconds float64 `json:"budgetSeconds"`
+}
+
+// WritePartialRunMarker writes the partial-run marker to the run output directory, overwriting the existing one.
+func WritePartialRunMarker(runOutputDir string, marker *PartialRunMarker) error {
+	if marker == nil {
+		return fmt.Errorf("marker is nil")
+	}
+	markerBytes, err := sonic.Marshal(marker)
+	if err != nil {
+		log.Err(err).Msg("[WritePartialRunMarker] Failed to marshal the partial-run marker")
+		return err
+	}
+	outputPath := filepath.Join(runOutputDir, PartialRunMarkerFileName)
+	err = os.WriteFile(outputPath, markerBytes, 0644)
+	if err != nil {
+		log.Err(err).Msgf("[WritePartialRunMarker] Failed to write the partial-run marker to file")
+		return err
+	}
+	log.Info().Msgf("[WritePartialRunMarker] Partial-run marker (reason: %s) has been written to %s", marker.Reason, outputPath)
+	return nil
+}
+
+// RemovePartialRunMarker removes the partial-run marker from the run output directory, if exists.
+func RemovePartialRunMarker(runOutputDir string) error {
+	err := os.Remove(filepath.Join(runOutputDir, PartialRunMarkerFileName))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		log.Err(err).Msgf("[RemovePartialRunMarker] Failed to remove the partial-run marker")
+		return err
+	}
+	return nil
+}
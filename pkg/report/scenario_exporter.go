@@ -0,0 +1,291 @@
+package report
+
+import (
+	"cmp"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"resttracefuzzer/pkg/feedback"
+	"resttracefuzzer/pkg/static"
+	"resttracefuzzer/pkg/utils/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	// ScenarioExportFormatGo exports failing scenarios as a Go test file, using net/http.
+	ScenarioExportFormatGo = "go"
+
+	// ScenarioExportFormatPython exports failing scenarios as a pytest file, using requests.
+	ScenarioExportFormatPython = "python"
+)
+
+// scenarioExportBaseURLEnv is the environment variable overriding the base URL of the service in exported test files.
+const scenarioExportBaseURLEnv = "REGRESSION_BASE_URL"
+
+// scenarioExportHeaderEnvPrefix is the prefix of environment variables providing values of credential and trace propagation headers in exported test files,
+// see [getExportedHeaderEnv].
+const scenarioExportHeaderEnvPrefix = "REGRESSION_HEADER_"
+
+// ScenarioExporter exports test scenarios failing with server errors (5xx) as standalone test files,
+// so that regressions can be added directly to the test suite of the service under test.
+type ScenarioExporter struct {
+	// Format is the format of exported test files, see [ScenarioExportFormatGo] and [ScenarioExportFormatPython].
+	Format string
+}
+
+// exportedScenario is a failing test scenario to export, ending with the failing operation case.
+type exportedScenario struct {
+	// testScenarioUUID is the UUID of the test scenario.
+	testScenarioUUID uuid.UUID
+
+	// minimized tells whether the scenario is minimized, see [feedback.ScenarioMinimizer].
+	minimized bool
+
+	// operationCases are the operation cases to send, with the failing one as the last.
+	operationCases []*OperationCaseForReport
+}
+
+// NewScenarioExporter creates a new ScenarioExporter.
+// It returns an error if the format is not supported.
+func NewScenarioExporter(format string) (*ScenarioExporter, error) {
+	if format != ScenarioExportFormatGo && format != ScenarioExportFormatPython {
+		return nil, fmt.Errorf("unsupported scenario export format: %s", format)
+	}
+	return &ScenarioExporter{
+		Format: format,
+	}, nil
+}
+
+// GetFileName returns the name of the exported test file, i.e., `regression_test.go` or `test_regression.py`.
+func (e *ScenarioExporter) GetFileName() string {
+	if e.Format == ScenarioExportFormatPython {
+		return "test_regression.py"
+	}
+	return "regression_test.go"
+}
+
+// ExportFailingScenarios exports one test for each failure (API method and status code of a server error) found in the tested scenarios,
+// to the test file (see [ScenarioExporter.GetFileName]) in the output directory.
+// The minimized scenario of the failure is exported if any (see [feedback.ScenarioMinimizer]), or else the shortest tested scenario up to the failure.
+// Each test sends the operation cases in order, asserts the recorded status codes of all but the last,
+// and asserts that the last (failing) one does not respond with a server error, so the test passes once the failure is fixed.
+// Scenarios with non-HTTP operations (e.g., gRPC) are not exported, as they cannot be reproduced by plain HTTP clients.
+// Requests are sent to the base URL, which can be overridden by env `REGRESSION_BASE_URL` when running the tests.
+// Recorded values of credential and trace propagation headers are not exported, and are read from env instead, see [getExportedHeaderEnv].
+func (e *ScenarioExporter) ExportFailingScenarios(testLogReport *TestLogReport, scenarioMinimizer *feedback.ScenarioMinimizer, baseURL, outputDir string) error {
+	if testLogReport == nil || scenarioMinimizer == nil {
+		log.Error().Msg("[ScenarioExporter.ExportFailingScenarios] testLogReport or scenarioMinimizer is nil.")
+		return fmt.Errorf("testLogReport or scenarioMinimizer is nil")
+	}
+
+	scenarios := make(map[string]*exportedScenario)
+	for _, failure := range scenarioMinimizer.Failures {
+		operationCases := make([]*OperationCaseForReport, 0, len(failure.OperationCases))
+		for _, operationCase := range failure.OperationCases {
+			operationCases = append(operationCases, NewReportFromOperationCase(operationCase))
+		}
+		if !isScenarioExportable(operationCases) {
+			continue
+		}
+		scenarios[getExportedFailureKey(failure.APIMethod, failure.StatusCode)] = &exportedScenario{
+			testScenarioUUID: failure.TestScenarioUUID,
+			minimized:        true,
+			operationCases:   operationCases,
+		}
+	}
+	for _, testScenario := range testLogReport.TestedScenarios {
+		failingIndex := slices.IndexFunc(testScenario.OperationCases, func(operationCase *OperationCaseForReport) bool {
+			return operationCase.ResponseStatusCode >= 500
+		})
+		if failingIndex < 0 {
+			continue
+		}
+		operationCases := testScenario.OperationCases[:failingIndex+1]
+		if !isScenarioExportable(operationCases) {
+			continue
+		}
+		failingOperationCase := operationCases[failingIndex]
+		key := getExportedFailureKey(failingOperationCase.APIMethod, failingOperationCase.ResponseStatusCode)
+		if existing, exist := scenarios[key]; exist && (existing.minimized || len(existing.operationCases) <= len(operationCases)) {
+			continue
+		}
+		scenarios[key] = &exportedScenario{
+			testScenarioUUID: testScenario.TestScenarioUUID,
+			operationCases:   operationCases,
+		}
+	}
+
+	// Tests are sorted by the failing API method and status code, so that exported files of runs are comparable.
+	sortedScenarios := slices.SortedFunc(maps.Values(scenarios), func(a, b *exportedScenario) int {
+		failingA, failingB := a.operationCases[len(a.operationCases)-1], b.operationCases[len(b.operationCases)-1]
+		return cmp.Or(
+			static.CompareSimpleAPIMethod(failingA.APIMethod, failingB.APIMethod),
+			cmp.Compare(failingA.ResponseStatusCode, failingB.ResponseStatusCode),
+		)
+	})
+	var content string
+	if e.Format == ScenarioExportFormatPython {
+		content = generatePytestFile(sortedScenarios, baseURL)
+	} else {
+		content = generateGoTestFile(sortedScenarios, baseURL)
+	}
+
+	outputPath := filepath.Join(outputDir, e.GetFileName())
+	err := os.WriteFile(outputPath, []byte(content), 0644)
+	if err != nil {
+		log.Err(err).Msgf("[ScenarioExporter.ExportFailingScenarios] Failed to write the exported test file")
+		return err
+	}
+	log.Info().Msgf("[ScenarioExporter.ExportFailingScenarios] %d failing scenarios have been exported to %s", len(sortedScenarios), outputPath)
+	return nil
+}
+
+// generateGoTestFile generates a Go test file of the scenarios, see [ScenarioExporter.ExportFailingScenarios].
+func generateGoTestFile(scenarios []*exportedScenario, baseURL string) string {
+	var b strings.Builder
+	b.WriteString("// Code generated by resttracefuzzer. Each test reproduces a test scenario failing with a server error (5xx).\n")
+	fmt.Fprintf(&b, "// Set env %s to the base URL of the service under test, or the one of the fuzzing run is used.\n", scenarioExportBaseURLEnv)
+	fmt.Fprintf(&b, "// Credential and trace propagation headers are read from env %s{HEADER} (e.g., %sAUTHORIZATION), and not sent if it is empty.\n\n", scenarioExportHeaderEnvPrefix, scenarioExportHeaderEnvPrefix)
+	b.WriteString("package regression\n\n")
+	b.WriteString("import (\n\t\"io\"\n\t\"net/http\"\n\t\"os\"\n\t\"strings\"\n\t\"testing\"\n)\n\n")
+	b.WriteString("// regressionBaseURL returns the base URL of the service under test.\n")
+	b.WriteString("func regressionBaseURL() string {\n")
+	fmt.Fprintf(&b, "\tif baseURL := os.Getenv(%q); baseURL != \"\" {\n\t\treturn baseURL\n\t}\n", scenarioExportBaseURLEnv)
+	fmt.Fprintf(&b, "\treturn %s\n}\n\n", strconv.Quote(baseURL))
+	b.WriteString("// sendRegressionRequest sends the request, and returns the status code of the response.\n")
+	b.WriteString("func sendRegressionRequest(t *testing.T, method, path string, headers map[string]string, body string) int {\n")
+	b.WriteString("\tt.Helper()\n")
+	b.WriteString("\tvar bodyReader io.Reader\n\tif body != \"\" {\n\t\tbodyReader = strings.NewReader(body)\n\t}\n")
+	b.WriteString("\treq, err := http.NewRequest(method, regressionBaseURL()+path, bodyReader)\n")
+	b.WriteString("\tif err != nil {\n\t\tt.Fatalf(\"failed to create request %s %s: %v\", method, path, err)\n\t}\n")
+	b.WriteString("\tfor k, v := range headers {\n\t\tif v != \"\" {\n\t\t\treq.Header.Set(k, v)\n\t\t}\n\t}\n")
+	b.WriteString("\tif body != \"\" && req.Header.Get(\"Content-Type\") == \"\" {\n\t\treq.Header.Set(\"Content-Type\", \"application/json\")\n\t}\n")
+	b.WriteString("\tresp, err := http.DefaultClient.Do(req)\n")
+	b.WriteString("\tif err != nil {\n\t\tt.Fatalf(\"failed to send request %s %s: %v\", method, path, err)\n\t}\n")
+	b.WriteString("\tdefer resp.Body.Close()\n\tio.Copy(io.Discard, resp.Body)\n\treturn resp.StatusCode\n}\n")
+
+	for i, scenario := range scenarios {
+		b.WriteString("\n")
+		fmt.Fprintf(&b, "// TestScenario%d %s.\n", i+1, describeExportedScenario(scenario))
+		fmt.Fprintf(&b, "func TestScenario%d(t *testing.T) {\n", i+1)
+		for j, operationCase := range scenario.operationCases {
+			headers := make([]string, 0, len(operationCase.RequestHeaders))
+			for _, k := range slices.Sorted(maps.Keys(operationCase.RequestHeaders)) {
+				value := strconv.Quote(operationCase.RequestHeaders[k])
+				if env := getExportedHeaderEnv(k); env != "" {
+					value = fmt.Sprintf("os.Getenv(%s)", strconv.Quote(env))
+				}
+				headers = append(headers, fmt.Sprintf("%s: %s", strconv.Quote(k), value))
+			}
+			step := fmt.Sprintf("step %d %s %s", j+1, operationCase.APIMethod.Method, operationCase.APIMethod.Endpoint)
+			fmt.Fprintf(&b, "\tif status := sendRegressionRequest(t, %s, %s, map[string]string{%s}, %s); ",
+				strconv.Quote(operationCase.APIMethod.Method), strconv.Quote(getExportedRequestPath(operationCase)), strings.Join(headers, ", "), strconv.Quote(operationCase.RequestBody))
+			if j == len(scenario.operationCases)-1 {
+				fmt.Fprintf(&b, "status >= 500 {\n\t\tt.Fatalf(%s, status)\n\t}\n", strconv.Quote(fmt.Sprintf("%s: expected no server error (%d in fuzzing), got %%d", step, operationCase.ResponseStatusCode)))
+			} else {
+				fmt.Fprintf(&b, "status != %d {\n\t\tt.Fatalf(%s, status)\n\t}\n", operationCase.ResponseStatusCode, strconv.Quote(fmt.Sprintf("%s: expected status %d, got %%d", step, operationCase.ResponseStatusCode)))
+			}
+		}
+		b.WriteString("}\n")
+	}
+	return b.String()
+}
+
+// generatePytestFile generates a pytest file of the scenarios, see [ScenarioExporter.ExportFailingScenarios].
+// Strings are quoted by [strconv.Quote], as its escapes are also valid in Python string literals.
+func generatePytestFile(scenarios []*exportedScenario, baseURL string) string {
+	var b strings.Builder
+	b.WriteString("# Generated by resttracefuzzer. Each test reproduces a test scenario failing with a server error (5xx).\n")
+	fmt.Fprintf(&b, "# Set env %s to the base URL of the service under test, or the one of the fuzzing run is used.\n", scenarioExportBaseURLEnv)
+	fmt.Fprintf(&b, "# Credential and trace propagation headers are read from env %s{HEADER} (e.g., %sAUTHORIZATION), and not sent if it is empty.\n\n", scenarioExportHeaderEnvPrefix, scenarioExportHeaderEnvPrefix)
+	b.WriteString("import os\n\nimport requests\n\n")
+	fmt.Fprintf(&b, "BASE_URL = os.environ.get(%s, %s)\n\n\n", strconv.Quote(scenarioExportBaseURLEnv), strconv.Quote(baseURL))
+	b.WriteString("def send_regression_request(method, path, headers, body):\n")
+	b.WriteString("    \"\"\"Sends the request, and returns the status code of the response.\"\"\"\n")
+	b.WriteString("    headers = {k: v for k, v in headers.items() if v}\n")
+	b.WriteString("    if body and not any(k.lower() == \"content-type\" for k in headers):\n")
+	b.WriteString("        headers = {**headers, \"Content-Type\": \"application/json\"}\n")
+	b.WriteString("    response = requests.request(method, BASE_URL + path, headers=headers, data=body.encode() if body else None)\n")
+	b.WriteString("    return response.status_code\n")
+
+	for i, scenario := range scenarios {
+		b.WriteString("\n\n")
+		fmt.Fprintf(&b, "def test_scenario_%d():\n", i+1)
+		fmt.Fprintf(&b, "    \"\"\"%s.\"\"\"\n", strings.ReplaceAll(describeExportedScenario(scenario), `"`, `'`))
+		for j, operationCase := range scenario.operationCases {
+			headers := make([]string, 0, len(operationCase.RequestHeaders))
+			for _, k := range slices.Sorted(maps.Keys(operationCase.RequestHeaders)) {
+				value := strconv.Quote(operationCase.RequestHeaders[k])
+				if env := getExportedHeaderEnv(k); env != "" {
+					value = fmt.Sprintf("os.environ.get(%s, \"\")", strconv.Quote(env))
+				}
+				headers = append(headers, fmt.Sprintf("%s: %s", strconv.Quote(k), value))
+			}
+			step := fmt.Sprintf("step %d %s %s", j+1, operationCase.APIMethod.Method, operationCase.APIMethod.Endpoint)
+			fmt.Fprintf(&b, "    status = send_regression_request(%s, %s, {%s}, %s)\n",
+				strconv.Quote(operationCase.APIMethod.Method), strconv.Quote(getExportedRequestPath(operationCase)), strings.Join(headers, ", "), strconv.Quote(operationCase.RequestBody))
+			if j == len(scenario.operationCases)-1 {
+				fmt.Fprintf(&b, "    assert status < 500, %s %% status\n", strconv.Quote(fmt.Sprintf("%s: expected no server error (%d in fuzzing), got %%d", step, operationCase.ResponseStatusCode)))
+			} else {
+				fmt.Fprintf(&b, "    assert status == %d, %s %% status\n", operationCase.ResponseStatusCode, strconv.Quote(fmt.Sprintf("%s: expected status %d, got %%d", step, operationCase.ResponseStatusCode)))
+			}
+		}
+	}
+	return b.String()
+}
+
+// describeExportedScenario describes the failure the scenario reproduces, e.g., `reproduces 500 of POST /orders (minimized from test scenario <UUID>)`.
+func describeExportedScenario(scenario *exportedScenario) string {
+	failingOperationCase := scenario.operationCases[len(scenario.operationCases)-1]
+	source := "test scenario"
+	if scenario.minimized {
+		source = "minimized from test scenario"
+	}
+	return fmt.Sprintf("reproduces %d of %s %s (%s %s)", failingOperationCase.ResponseStatusCode, failingOperationCase.APIMethod.Method, failingOperationCase.APIMethod.Endpoint, source, scenario.testScenarioUUID.String())
+}
+
+// getExportedHeaderEnv returns the environment variable providing the value of the header in exported test files, e.g., `REGRESSION_HEADER_AUTHORIZATION`,
+// so that credentials (see [http.IsCredentialHeader]) are not leaked into test files meant to be committed,
+// and trace propagation headers (see [http.IsTracePropagationHeader]) of the fuzzing run are not reused.
+// It returns empty if the recorded value of the header is exported as is.
+func getExportedHeaderEnv(key string) string {
+	if !http.IsCredentialHeader(key) && !http.IsTracePropagationHeader(key) {
+		return ""
+	}
+	env := strings.Map(func(r rune) rune {
+		if (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') {
+			return r
+		}
+		return '_'
+	}, strings.ToUpper(key))
+	return scenarioExportHeaderEnvPrefix + env
+}
+
+// getExportedRequestPath returns the path of the request relative to the base URL, with path and query params.
+func getExportedRequestPath(operationCase *OperationCaseForReport) string {
+	return http.BuildRequestURL("", operationCase.APIMethod.Endpoint, operationCase.RequestPathParams, operationCase.RequestQueryParams)
+}
+
+// isScenarioExportable tells whether the operation cases can be exported, i.e., there is at least one and all of them are HTTP ones.
+func isScenarioExportable(operationCases []*OperationCaseForReport) bool {
+	if len(operationCases) == 0 {
+		return false
+	}
+	for _, operationCase := range operationCases {
+		if operationCase.APIMethod.Typ == static.SimpleAPIMethodTypeGRPC || operationCase.APIMethod.Typ == static.SimpleAPIMethodTypeMessaging {
+			return false
+		}
+	}
+	return true
+}
+
+// getExportedFailureKey returns the key of a failure, consisting of the API method and the status code.
+func getExportedFailureKey(apiMethod static.SimpleAPIMethod, statusCode int) string {
+	return fmt.Sprintf("%s %s|%d", apiMethod.Method, apiMethod.Endpoint, statusCode)
+}
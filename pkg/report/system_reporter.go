@@ -4,7 +4,9 @@ import (
 	"fmt"
 	"os"
 	"resttracefuzzer/pkg/feedback"
+	"resttracefuzzer/pkg/feedback/trace"
 	"resttracefuzzer/pkg/static"
+	"resttracefuzzer/pkg/strategy"
 	"resttracefuzzer/pkg/utils/http"
 	"strconv"
 
@@ -15,7 +17,13 @@ import (
 // SystemReporter analyses and reports the results of system-level fuzzing.
 // It supports the following features:
 // 1. Report the coverage of the Endpoints, i.e., number of (path, method) pairs that have been visited.
-// 2. TODO: to implement the rest of the features. @xunzhou24
+// 2. Report the collisions of operationIds and paths detected in API docs.
+// 3. Report the violations of resource lifecycle (CRUD) invariants.
+// 4. Report the coverage of enum members of parameters.
+// 5. Report the operations skipped for requiring unsupported features.
+// 6. Report the security findings from responses to security payloads.
+// 7. Report the violations of object level authorization (BOLA) among users.
+// 8. TODO: to implement the rest of the features. @xunzhou24
 type SystemReporter struct {
 	APIManager *static.APIManager
 }
@@ -27,13 +35,67 @@ func NewSystemReporter(APIManager *static.APIManager) *SystemReporter {
 	}
 }
 
-// GenerateSystemReport generates the system-level report.
-// The report includes the coverage of the Endpoints and Status Codes.
-func (r *SystemReporter) GenerateSystemReport(responseProcesser *feedback.ResponseProcesser, outputPath string) error {
-	if responseProcesser == nil {
+// SystemReportSources are the sources of the system-level report, see [SystemReporter.GenerateSystemReport].
+// ResponseProcesser is required, and sections of the other sources are left empty in the report if they are nil.
+type SystemReportSources struct {
+	// ResponseProcesser provides the coverage of status codes and status sequences.
+	ResponseProcesser *feedback.ResponseProcesser
+
+	// CRUDOracle provides the violations of resource lifecycle (CRUD) invariants.
+	CRUDOracle *feedback.CRUDOracle
+
+	// SecurityOracle provides the security findings from responses to security payloads.
+	SecurityOracle *feedback.SecurityOracle
+
+	// BOLAOracle provides the violations of object level authorization among users.
+	BOLAOracle *feedback.BOLAOracle
+
+	// IdempotencyOracle provides the violations of idempotency keys by replayed requests.
+	IdempotencyOracle *feedback.IdempotencyOracle
+
+	// DifferentialOracle provides the behavioral differences found in differential fuzzing.
+	DifferentialOracle *feedback.DifferentialOracle
+
+	// ScenarioMinimizer provides the minimized scenarios of server errors.
+	ScenarioMinimizer *feedback.ScenarioMinimizer
+
+	// LatencySLOOracle provides the violations of latency SLOs of API methods.
+	LatencySLOOracle *feedback.LatencySLOOracle
+
+	// SpanLatencyAnomalyDetector provides the latency anomalies of spans.
+	SpanLatencyAnomalyDetector *feedback.SpanLatencyAnomalyDetector
+
+	// ContextPropagationOracle provides the breaks of propagation of context headers to downstream services.
+	ContextPropagationOracle *feedback.ContextPropagationOracle
+
+	// StatusCodeTargetTracker provides the gap of status code targets.
+	StatusCodeTargetTracker *feedback.StatusCodeTargetTracker
+
+	// EnumCoverageTracker provides the coverage of enum members of parameters.
+	EnumCoverageTracker *feedback.EnumCoverageTracker
+
+	// EndpointPerformanceTracker provides the response times and availability of endpoints.
+	EndpointPerformanceTracker *feedback.EndpointPerformanceTracker
+
+	// CreatedResourceTracker provides the cleanup results and leftovers of created resources.
+	CreatedResourceTracker *feedback.CreatedResourceTracker
+
+	// CapabilityGaps are the parts of the spec the fuzzer cannot exercise.
+	CapabilityGaps []*strategy.CapabilityGap
+
+	// TraceWaitStats are the statistics of delays until traces became queryable.
+	TraceWaitStats *trace.TraceWaitStats
+}
+
+// GenerateSystemReport generates the system-level report from the sources, and writes it to outputPath.
+// The report includes the coverage of the Endpoints, Status Codes and enum members, the violations (findings) found by CRUD, security and BOLA oracles, the behavioral differences found in differential fuzzing,
+// the minimized scenarios of server errors, the response times and availability of endpoints, the cleanup of created resources, and the delays until traces became queryable.
+func (r *SystemReporter) GenerateSystemReport(sources *SystemReportSources, outputPath string) error {
+	if sources == nil || sources.ResponseProcesser == nil {
 		log.Error().Msg("[SystemReporter.GenerateSystemReport] responseProcesser is nil.")
 		return fmt.Errorf("responseProcesser is nil")
 	}
+	responseProcesser := sources.ResponseProcesser
 
 	systemTestReport := SystemTestReport{}
 
@@ -76,6 +138,66 @@ func (r *SystemReporter) GenerateSystemReport(responseProcesser *feedback.Respon
 		systemTestReport.StatusCoverage[statusCodeClass] = float64(statusCodeClass2Cnt[statusCodeClass]) / float64(totalCnt)
 	}
 	systemTestReport.SetStatusHitCountReport(statusHitCount)
+	systemTestReport.CoveredStatusSequenceCount = responseProcesser.GetCoveredStatusSequenceCount()
+	systemTestReport.OperationCollisions = r.APIManager.OperationCollisions
+	if sources.CRUDOracle != nil {
+		systemTestReport.CRUDInvariantViolations = sources.CRUDOracle.Violations
+	}
+	if sources.SecurityOracle != nil {
+		systemTestReport.SecurityFindings = sources.SecurityOracle.Findings
+	}
+	if sources.BOLAOracle != nil {
+		systemTestReport.BOLAViolations = sources.BOLAOracle.Violations
+	}
+	if sources.IdempotencyOracle != nil {
+		systemTestReport.IdempotencyViolations = sources.IdempotencyOracle.Violations
+	}
+	if sources.DifferentialOracle != nil {
+		systemTestReport.BehaviorDifferences = sources.DifferentialOracle.Differences
+	}
+	if sources.ScenarioMinimizer != nil {
+		systemTestReport.MinimizedFailures = make([]*MinimizedFailureReport, 0, len(sources.ScenarioMinimizer.Failures))
+		for _, failure := range sources.ScenarioMinimizer.Failures {
+			systemTestReport.MinimizedFailures = append(systemTestReport.MinimizedFailures, NewReportFromMinimizedFailure(failure))
+		}
+	}
+	if sources.LatencySLOOracle != nil {
+		systemTestReport.LatencySLOViolations = sources.LatencySLOOracle.GetViolations()
+	}
+	if sources.SpanLatencyAnomalyDetector != nil {
+		systemTestReport.SpanLatencyAnomalies = make([]*SpanLatencyAnomalyReport, 0, len(sources.SpanLatencyAnomalyDetector.Anomalies))
+		for _, anomaly := range sources.SpanLatencyAnomalyDetector.Anomalies {
+			systemTestReport.SpanLatencyAnomalies = append(systemTestReport.SpanLatencyAnomalies, NewReportFromSpanLatencyAnomaly(anomaly))
+		}
+	}
+	if sources.ContextPropagationOracle != nil {
+		systemTestReport.ContextPropagationBreaks = sources.ContextPropagationOracle.GetBreaks()
+	}
+	if sources.StatusCodeTargetTracker != nil {
+		systemTestReport.StatusCodeTargetGap = sources.StatusCodeTargetTracker.GetGap()
+	}
+	if sources.EnumCoverageTracker != nil {
+		systemTestReport.EnumCoverage = sources.EnumCoverageTracker.GetEnumCoverage()
+		systemTestReport.EnumParameterCoverages = sources.EnumCoverageTracker.GetParameterCoverages()
+	}
+	if sources.EndpointPerformanceTracker != nil {
+		systemTestReport.EndpointPerformances = sources.EndpointPerformanceTracker.GetPerformances()
+	}
+	if sources.CreatedResourceTracker != nil {
+		systemTestReport.ResourceCleanupResults = sources.CreatedResourceTracker.CleanupResults
+		systemTestReport.LeftoverResources = sources.CreatedResourceTracker.GetCreatedResources()
+	}
+	systemTestReport.SkippedOperations = make([]*SkippedOperationReport, 0, len(r.APIManager.UnsupportedOperations))
+	for _, unsupportedOperation := range r.APIManager.UnsupportedOperations {
+		systemTestReport.SkippedOperations = append(systemTestReport.SkippedOperations, &SkippedOperationReport{
+			APIMethod: unsupportedOperation.APIMethod,
+			Status:    SkippedOperationStatusUnsupported,
+			Reason:    unsupportedOperation.Reason,
+			Detail:    unsupportedOperation.Detail,
+		})
+	}
+	systemTestReport.CapabilityGaps = sources.CapabilityGaps
+	systemTestReport.TraceWaitStats = sources.TraceWaitStats
 
 	// marshal the report to a JSON file.
 	reportBytes, err := sonic.Marshal(systemTestReport)
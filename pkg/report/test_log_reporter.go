@@ -1,10 +1,13 @@
 package report
 
 import (
-	"os"
 	"resttracefuzzer/pkg/casemanager"
+	"resttracefuzzer/pkg/static"
+	"resttracefuzzer/pkg/utils"
+	"resttracefuzzer/pkg/utils/http"
 
 	"github.com/bytedance/sonic"
+	"github.com/cloudwego/hertz/pkg/protocol/consts"
 	"github.com/rs/zerolog/log"
 )
 
@@ -12,23 +15,75 @@ import (
 // and generating a report after the fuzzing process.
 type TestLogReporter struct {
 	TestLogReport *TestLogReport
+
+	// Compression is the compression of the report file, see [utils.WriteFileWithCompression].
+	Compression string
+
+	// TraceFileLocator returns the path of the saved trace JSON of the trace ID, and whether the trace has been saved.
+	// It is nil if raw traces are not saved, and operation cases are not linked to saved traces then.
+	TraceFileLocator func(traceID string) (string, bool)
 }
 
-// NewTestLogReporter creates a new TestLogReporter.
-func NewTestLogReporter() *TestLogReporter {
+// NewTestLogReporter creates a new TestLogReporter, which writes the report compressed by the compression.
+// Unsupported compression is replaced by [utils.CompressionNone].
+func NewTestLogReporter(compression string) *TestLogReporter {
+	if !utils.IsValidCompression(compression) {
+		log.Warn().Msgf("[NewTestLogReporter] Unsupported compression %s, write the report uncompressed instead", compression)
+		compression = utils.CompressionNone
+	}
 	return &TestLogReporter{
 		TestLogReport: NewTestLogReport(),
+		Compression:   compression,
 	}
 }
 
 // LogTestScenario logs the tested test scenario.
 // To reduce the size of the report, it removes some info (such as response body) from origin tested operation, and uses a simplified version of the tested scenario in the report.
+// Operation cases are linked to their saved traces if any, and those responded with server errors are collected in [TestLogReport.FailingOperations].
 func (r *TestLogReporter) LogTestScenario(testScenario *casemanager.TestScenario) {
-	r.TestLogReport.TestedScenarios = append(r.TestLogReport.TestedScenarios, NewReportFromTestScenario(testScenario))
+	scenarioForReport := NewReportFromTestScenario(testScenario)
+	r.TestLogReport.TestedScenarios = append(r.TestLogReport.TestedScenarios, scenarioForReport)
 	r.TestLogReport.TestedScenariosLengthCount[len(testScenario.OperationCases)]++
+	for i, operationCase := range testScenario.OperationCases {
+		if operationCase.IsLowConfidence() {
+			r.TestLogReport.LowConfidenceOperationCaseCount++
+		}
+		operationCaseForReport := scenarioForReport.OperationCases[i]
+		if r.TraceFileLocator != nil && operationCase.TraceID != "" {
+			if traceFile, saved := r.TraceFileLocator(operationCase.TraceID); saved {
+				operationCaseForReport.TraceFile = traceFile
+			}
+		}
+		if http.GetStatusCodeClass(operationCase.ResponseStatusCode) == consts.StatusInternalServerError {
+			r.TestLogReport.FailingOperations = append(r.TestLogReport.FailingOperations, &FailingOperationLink{
+				TestScenarioUUID:   testScenario.UUID,
+				OperationIndex:     i,
+				APIMethod:          operationCase.APIMethod,
+				ResponseStatusCode: operationCase.ResponseStatusCode,
+				TraceID:            operationCaseForReport.TraceID,
+				TraceFile:          operationCaseForReport.TraceFile,
+			})
+		}
+	}
+}
+
+// LogDryRunScenario logs the test scenario populated in dry run, with the fully resolved URL of each HTTP request (see [http.BuildRequestURL]).
+// getBaseURL returns the server base URL of the operation, as operations may come from different targets.
+func (r *TestLogReporter) LogDryRunScenario(testScenario *casemanager.TestScenario, getBaseURL func(static.SimpleAPIMethod) string) {
+	r.LogTestScenario(testScenario)
+	r.TestLogReport.DryRun = true
+	scenarioForReport := r.TestLogReport.TestedScenarios[len(r.TestLogReport.TestedScenarios)-1]
+	for i, operationCase := range testScenario.OperationCases {
+		// gRPC and message-producing operations have no URL.
+		if operationCase.APIMethod.Typ == static.SimpleAPIMethodTypeGRPC || operationCase.APIMethod.Typ == static.SimpleAPIMethodTypeMessaging {
+			continue
+		}
+		scenarioForReport.OperationCases[i].RequestURL = http.BuildRequestURL(getBaseURL(operationCase.APIMethod), operationCase.APIMethod.Endpoint, operationCase.RequestPathParams, operationCase.RequestQueryParams)
+	}
 }
 
 // GenerateTestLogReport generates the test log report.
+// If it is compressed, [utils.ZstdFileExtension] is appended to the output path.
 func (r *TestLogReporter) GenerateTestLogReport(outputPath string) error {
 	// marshal the report to a JSON file.
 	reportBytes, err := sonic.Marshal(r.TestLogReport)
@@ -37,7 +92,7 @@ func (r *TestLogReporter) GenerateTestLogReport(outputPath string) error {
 	}
 
 	// Write the JSON string to the output file.
-	err = os.WriteFile(outputPath, reportBytes, 0644)
+	outputPath, err = utils.WriteFileWithCompression(outputPath, reportBytes, r.Compression)
 	if err != nil {
 		log.Err(err).Msgf("[TestLogReporter.GenerateTestLogReport] Failed to write the test log report")
 		return err
@@ -3,12 +3,15 @@ package resource
 import (
 	"fmt"
 	"hash/fnv"
+	"maps"
 	"math"
 	"resttracefuzzer/pkg/static"
 	"resttracefuzzer/pkg/utils"
+	"slices"
 	"strconv"
 
 	"github.com/bytedance/sonic"
+	"github.com/bytedance/sonic/decoder"
 	"github.com/rs/zerolog/log"
 )
 
@@ -464,3 +467,41 @@ func NewResourceFromValue(value any) (Resource, error) {
 		return nil, fmt.Errorf("unsupported property type %s", propertyType)
 	}
 }
+
+// NewResourceFromRawBytes creates a new resource from JSON bytes.
+// Integer values are parsed as int64, so that they are kept as integer resources.
+func NewResourceFromRawBytes(rawBytes []byte) (Resource, error) {
+	var value any
+	decoder := decoder.NewDecoder(string(rawBytes))
+	decoder.UseInt64()
+	err := decoder.Decode(&value)
+	if err != nil {
+		return nil, err
+	}
+	return NewResourceFromValue(value)
+}
+
+// FindPropertyOwner finds the shallowest object resource (the resource itself, or its sub-resources) which has the property of the given name.
+// Resources are visited breadth-first, and keys of objects in sorted order, so that the result is stable.
+// It returns nil if there is none.
+func FindPropertyOwner(root Resource, name string) *ResourceObject {
+	que := []Resource{root}
+	for len(que) > 0 {
+		newQue := make([]Resource, 0)
+		for _, r := range que {
+			switch r := r.(type) {
+			case *ResourceObject:
+				if _, exist := r.Value[name]; exist {
+					return r
+				}
+				for _, key := range slices.Sorted(maps.Keys(r.Value)) {
+					newQue = append(newQue, r.Value[key])
+				}
+			case *ResourceArray:
+				newQue = append(newQue, r.Value...)
+			}
+		}
+		que = newQue
+	}
+	return nil
+}
@@ -1,17 +1,23 @@
 package resource
 
 import (
+	"fmt"
 	"io"
-	"math/rand/v2"
+	"maps"
 	"os"
 	"resttracefuzzer/pkg/static"
 	"resttracefuzzer/pkg/utils"
+	"slices"
+	"strings"
 
 	"github.com/bytedance/sonic/decoder"
 	"github.com/getkin/kin-openapi/openapi3"
 	"github.com/rs/zerolog/log"
 )
 
+// defaultLocaleWeightKey is the key of locale weights for untagged resources and locales without a weight.
+const defaultLocaleWeightKey = "default"
+
 // Resource represents a resource in the resource pool, and ResourceManager manages the resource pool.
 // The resource pool is a set of resources, and several maps are used to index the resources, all of them having consistent data.
 // To improve readability, only ResourceNameMap would be serialized.
@@ -28,6 +34,28 @@ type ResourceManager struct {
 	// ResourceName2HashSet is used to store the hashcode of resources, preventing duplicate resources.
 	// It maps resource name to resource set, i.e., we do not allow duplicate resources with the same name.
 	ResourceName2HashSet map[string]map[uint64]struct{} `json:"-"`
+
+	// ResourceSchemaRefMap is an optional dimension of the pool, a map from component schema ref (e.g., `#/components/schemas/Pet`) to the resource name to the list of resources.
+	// A resource is scoped to the schema ref of its own schema, or that of the nearest enclosing object otherwise,
+	// so that values of the same name from unrelated entities (e.g., `id` of pets and owners) are not mixed.
+	// Only resources stored with a schema (see [ResourceManager.StoreResourcesFromRawObjectBytesWithSchema]) are scoped, and each of them is in the maps above as well.
+	ResourceSchemaRefMap map[string]map[string][]Resource `json:"-"`
+
+	// ResourceSchemaRef2HashSet is the hashcode set of resources of each schema ref and resource name, like ResourceName2HashSet.
+	ResourceSchemaRef2HashSet map[string]map[string]map[uint64]struct{} `json:"-"`
+
+	// SpecExampleMap is a map from schema paths (e.g., `#/components/schemas/Pet/properties/name` or `GET /pets#/parameters/query/limit`)
+	// to the values documented for them in the API doc, see [ResourceManager.StoreSpecExamples].
+	// Each of the values is in the maps above as well.
+	SpecExampleMap map[string][]Resource `json:"-"`
+
+	// ResourceLocaleMap is a map from the hashcode of a resource to its locale tag (e.g., `zh-CN` or `ar`), for locale-tagged values of external dictionaries.
+	// Resources not in the map are untagged.
+	ResourceLocaleMap map[uint64]string `json:"-"`
+
+	// LocaleWeights is a map from the locale tag to its weight, used to pick among resources of different locales, see [ResourceManager.SetLocaleWeights].
+	// Resources are picked uniformly if it is empty.
+	LocaleWeights map[string]int `json:"-"`
 }
 
 // NewResourceManager creates a new ResourceManager.
@@ -36,10 +64,29 @@ func NewResourceManager() *ResourceManager {
 	resourceNameMap := make(map[string][]Resource)
 	resourceHashSet := make(map[string]map[uint64]struct{})
 	return &ResourceManager{
-		ResourceTypeMap:      resourceTypeMap,
-		ResourceNameMap:      resourceNameMap,
-		ResourceName2HashSet: resourceHashSet,
+		ResourceTypeMap:           resourceTypeMap,
+		ResourceNameMap:           resourceNameMap,
+		ResourceName2HashSet:      resourceHashSet,
+		ResourceSchemaRefMap:      make(map[string]map[string][]Resource),
+		ResourceSchemaRef2HashSet: make(map[string]map[string]map[uint64]struct{}),
+		SpecExampleMap:            make(map[string][]Resource),
+		ResourceLocaleMap:         make(map[uint64]string),
+		LocaleWeights:             make(map[string]int),
+	}
+}
+
+// SetLocaleWeights sets the weights of locales to pick resources, so that values of some locales (e.g., CJK and RTL scripts) are exercised more often.
+// The weight of a locale tag is looked up by the full tag first (e.g., `zh-CN`), and then by its primary subtag (e.g., `zh`);
+// the key `default` sets the weight of untagged resources and locales without a weight, which is 1 if not set.
+// A locale of weight 0 is never picked, unless all candidates are of weight 0. It returns an error if any weight is negative.
+func (m *ResourceManager) SetLocaleWeights(localeWeights map[string]int) error {
+	for locale, weight := range localeWeights {
+		if weight < 0 {
+			return fmt.Errorf("invalid weight %d of locale %s, it should be non-negative", weight, locale)
+		}
 	}
+	m.LocaleWeights = localeWeights
+	return nil
 }
 
 // GetSingleResourceByType gets a resource from pool by the property type.
@@ -49,7 +96,7 @@ func (m *ResourceManager) GetSingleResourceByType(propertyType static.SimpleAPIP
 		log.Warn().Msgf("[ResourceManager.GetRandomResourceByType] No resource of type %s", propertyType)
 		return nil
 	}
-	return resources[rand.IntN(len(resources))]
+	return m.pickResource(resources)
 }
 
 // GetSingleResourceBySchemaTypes gets a resource from pool by the schema type(s).
@@ -82,7 +129,7 @@ func (m *ResourceManager) GetSingleResourceByName(resourceName string) Resource
 	// try to find a resource by full name
 	resources := m.ResourceNameMap[resourceName]
 	if len(resources) > 0 {
-		return resources[rand.IntN(len(resources))]
+		return m.pickResource(resources)
 	}
 
 	// try to find a resource that matches in the last part of the name
@@ -95,7 +142,76 @@ func (m *ResourceManager) GetSingleResourceByName(resourceName string) Resource
 		return nil
 	}
 
-	return resources[rand.IntN(len(resources))]
+	return m.pickResource(resources)
+}
+
+// GetSingleResourceBySchemaRefAndName gets a resource from pool by the resource name, among those scoped to the component schema ref (see ResourceSchemaRefMap).
+// Like [ResourceManager.GetSingleResourceByName], a resource matching the last part of the name is returned if none matches the full name.
+// It returns nil if the schema ref is empty, or no resource is found in the scope.
+func (m *ResourceManager) GetSingleResourceBySchemaRefAndName(schemaRef, resourceName string) Resource {
+	scopedResourceNameMap := m.ResourceSchemaRefMap[schemaRef]
+	if schemaRef == "" || resourceName == "" || len(scopedResourceNameMap) == 0 {
+		return nil
+	}
+	resources := scopedResourceNameMap[resourceName]
+	if len(resources) == 0 {
+		resourceNameParts := utils.SplitIntoWords(resourceName)
+		resources = scopedResourceNameMap[resourceNameParts[len(resourceNameParts)-1]]
+	}
+	if len(resources) == 0 {
+		return nil
+	}
+	return m.pickResource(resources)
+}
+
+// pickResource randomly picks one of the (non-empty) resources.
+// If locale weights are set and any resource is locale-tagged, a locale is picked by weight first, and then a resource of it uniformly,
+// so that the chance of a locale does not depend on how many values it has in the dictionary.
+func (m *ResourceManager) pickResource(resources []Resource) Resource {
+	if len(m.LocaleWeights) == 0 || len(m.ResourceLocaleMap) == 0 {
+		return resources[utils.SharedRand.IntN(len(resources))]
+	}
+	localeResources := make(map[string][]Resource)
+	for _, resource := range resources {
+		locale := m.ResourceLocaleMap[resource.Hashcode()]
+		localeResources[locale] = append(localeResources[locale], resource)
+	}
+	locales := slices.Sorted(maps.Keys(localeResources))
+	weights := make([]int, len(locales))
+	totalWeight := 0
+	for i, locale := range locales {
+		weights[i] = m.getLocaleWeight(locale)
+		totalWeight += weights[i]
+	}
+	if totalWeight == 0 {
+		return resources[utils.SharedRand.IntN(len(resources))]
+	}
+	roll := utils.SharedRand.IntN(totalWeight)
+	for i, locale := range locales {
+		if roll < weights[i] {
+			candidates := localeResources[locale]
+			return candidates[utils.SharedRand.IntN(len(candidates))]
+		}
+		roll -= weights[i]
+	}
+	return resources[utils.SharedRand.IntN(len(resources))]
+}
+
+// getLocaleWeight returns the weight of the locale tag, see [ResourceManager.SetLocaleWeights]. Untagged resources are of locale "".
+func (m *ResourceManager) getLocaleWeight(locale string) int {
+	if locale != "" {
+		if weight, ok := m.LocaleWeights[locale]; ok {
+			return weight
+		}
+		primarySubtag, _, _ := strings.Cut(locale, "-")
+		if weight, ok := m.LocaleWeights[primarySubtag]; ok {
+			return weight
+		}
+	}
+	if weight, ok := m.LocaleWeights[defaultLocaleWeightKey]; ok {
+		return weight
+	}
+	return 1
 }
 
 // LoadFromExternalDict loads resources from an external dictionary.
@@ -109,9 +225,15 @@ func (m *ResourceManager) GetSingleResourceByName(resourceName string) Resource
 //	    {
 //	        "name": "resource2",
 //	        "value": 1.0
+//	    },
+//	    {
+//	        "name": "resource3",
+//	        "value": "值",
+//	        "locale": "zh-CN"
 //	    }
 //	]
 //
+// The optional `locale` tags the value with a locale (e.g., `zh-CN`, `ar` or `he`), to pick values by locale weights, see [ResourceManager.SetLocaleWeights].
 // It returns an error if any.
 // Note: for resources loaded from external dictionary, we do not store sub-resources.
 func (m *ResourceManager) LoadFromExternalDictFile(filePath string) error {
@@ -135,8 +257,9 @@ func (m *ResourceManager) LoadFromExternalDictFile(filePath string) error {
 	// Parse JSON content
 	// To parse integer values as int64, we need to use the decoder, and set via decoder.UseInt64().
 	var dictValues []struct {
-		Name  string      `json:"name"`
-		Value interface{} `json:"value"`
+		Name   string      `json:"name"`
+		Value  interface{} `json:"value"`
+		Locale string      `json:"locale"`
 	}
 	decoder := decoder.NewDecoder(string(bytes))
 	decoder.UseInt64()
@@ -158,6 +281,9 @@ func (m *ResourceManager) LoadFromExternalDictFile(filePath string) error {
 			continue
 		}
 		m.storeResource(resource, resourceName, false) // For resources loaded from external dictionary, we do not store sub-resources.
+		if dictValue.Locale != "" {
+			m.ResourceLocaleMap[resource.Hashcode()] = dictValue.Locale
+		}
 		succCnt++
 	}
 	log.Info().Msgf("[ResourceManager.LoadFromExternalDictFile] Loaded %d resources", succCnt)
@@ -184,27 +310,239 @@ func (m *ResourceManager) LoadFromExternalDictFile(filePath string) error {
 //   - for object type, all values from the object key-value pairs will be stored;
 //   - for array type, all elements in the array will be stored.
 func (m *ResourceManager) StoreResourcesFromRawObjectBytes(rawObjectBytes []byte, rootResourceName string, shouldStoreSubResources bool) error {
-	// To parse integer values as int64, we need to use the decoder, and set via decoder.UseInt64().
-	var jsonObject interface{}
-	decoder := decoder.NewDecoder(string(rawObjectBytes))
-	decoder.UseInt64()
-	err := decoder.Decode(&jsonObject)
-	if err != nil {
-		log.Err(err).Msg("[ResourceManager.StoreResourcesFromRawObjectBytes] Failed to unmarshal JSON")
-		return err
-	}
+	return m.StoreResourcesFromRawObjectBytesWithSchema(rawObjectBytes, rootResourceName, shouldStoreSubResources, nil)
+}
+
+// StoreResourcesFromRawObjectBytesWithSchema stores resources from raw object bytes like [ResourceManager.StoreResourcesFromRawObjectBytes],
+// and scopes them to component schema refs found by walking the schema describing the object (e.g., the schema of the response), see ResourceSchemaRefMap.
+// Resources are not scoped if the schema is nil, or until a component schema is reached.
+func (m *ResourceManager) StoreResourcesFromRawObjectBytesWithSchema(rawObjectBytes []byte, rootResourceName string, shouldStoreSubResources bool, schema *openapi3.SchemaRef) error {
 	// Parse the object into a resource, for the convenience of post-processing.
-	rootResource, err := NewResourceFromValue(jsonObject)
+	rootResource, err := NewResourceFromRawBytes(rawObjectBytes)
 	if err != nil {
-		log.Err(err).Msg("[ResourceManager.StoreResourcesFromRawObjectBytes] Failed to create resource from JSON object")
+		log.Err(err).Msg("[ResourceManager.StoreResourcesFromRawObjectBytes] Failed to create resource from raw object bytes")
 		return err
 	}
 
 	// Store the root resource.
-	m.storeResource(rootResource, rootResourceName, shouldStoreSubResources)
+	m.storeScopedResource(rootResource, rootResourceName, shouldStoreSubResources, schema, "")
 	return nil
 }
 
+// Downsample randomly removes the given ratio (0-1) of resources from the pool, e.g., to reduce memory usage.
+// All indexes are kept consistent, and removed resources can be stored again later.
+// It returns the number of removed resources.
+func (m *ResourceManager) Downsample(ratio float64) int {
+	removedCnt := 0
+	retained := make(map[Resource]struct{})
+	for propertyType, resources := range m.ResourceTypeMap {
+		utils.SharedRand.Shuffle(len(resources), func(i, j int) {
+			resources[i], resources[j] = resources[j], resources[i]
+		})
+		keepCnt := len(resources) - int(float64(len(resources))*ratio)
+		removedCnt += len(resources) - keepCnt
+		m.ResourceTypeMap[propertyType] = resources[:keepCnt:keepCnt]
+		for _, resource := range resources[:keepCnt] {
+			retained[resource] = struct{}{}
+		}
+	}
+
+	// Every stored resource is in ResourceTypeMap, so other indexes are filtered by what is retained there.
+	m.ResourceName2HashSet = make(map[string]map[uint64]struct{})
+	for resourceName, resources := range m.ResourceNameMap {
+		remaining := make([]Resource, 0)
+		hashSet := make(map[uint64]struct{})
+		for _, resource := range resources {
+			if _, ok := retained[resource]; ok {
+				remaining = append(remaining, resource)
+				hashSet[resource.Hashcode()] = struct{}{}
+			}
+		}
+		if len(remaining) == 0 {
+			delete(m.ResourceNameMap, resourceName)
+			continue
+		}
+		m.ResourceNameMap[resourceName] = remaining
+		m.ResourceName2HashSet[resourceName] = hashSet
+	}
+	// Scoped resources may be other instances of the same values, so they are retained by hashcodes of their names.
+	m.filterScopedResources(func(resourceName string, resource Resource) bool {
+		_, ok := m.ResourceName2HashSet[resourceName][resource.Hashcode()]
+		return ok
+	})
+	log.Info().Msgf("[ResourceManager.Downsample] Removed %d resources from the pool", removedCnt)
+	return removedCnt
+}
+
+// RemoveResourcesReferringTo removes resources of a deleted entity from the pool, so that later requests do not use values known to be gone.
+// Among resources of the given names, a primitive resource is removed if it is of valueType and its string representation equals the value (e.g., the ID of the entity),
+// and an object resource is removed along with all its sub-resources if any of its fields named by fieldNames (e.g., `id` or `userId`) is such a primitive.
+// Field names are matched case-insensitively, ignoring underscores and hyphens, e.g., `user_id` matches `userId`.
+// Primitives of any type are matched if valueType is [static.SimpleAPIPropertyTypeUnknown], e.g., if the path parameter declares no schema type.
+// All indexes are kept consistent, and removed resources can be stored again later.
+// It returns the number of removed resources.
+func (m *ResourceManager) RemoveResourcesReferringTo(resourceNames []string, fieldNames []string, value string, valueType static.SimpleAPIPropertyType) int {
+	normalizedFieldNames := make(map[string]struct{}, len(fieldNames))
+	for _, fieldName := range fieldNames {
+		normalizedFieldNames[normalizeReferringFieldName(fieldName)] = struct{}{}
+	}
+	removed := make(map[Resource]struct{})
+	for _, resourceName := range resourceNames {
+		for _, resource := range m.ResourceNameMap[resourceName] {
+			if isResourceReferringTo(resource, normalizedFieldNames, value, valueType) {
+				collectResourceTree(resource, removed)
+			}
+		}
+	}
+	if len(removed) == 0 {
+		return 0
+	}
+
+	for propertyType, resources := range m.ResourceTypeMap {
+		m.ResourceTypeMap[propertyType] = slices.DeleteFunc(resources, func(resource Resource) bool {
+			_, ok := removed[resource]
+			return ok
+		})
+	}
+	for resourceName, resources := range m.ResourceNameMap {
+		remaining := make([]Resource, 0, len(resources))
+		for _, resource := range resources {
+			if _, ok := removed[resource]; ok {
+				delete(m.ResourceName2HashSet[resourceName], resource.Hashcode())
+				continue
+			}
+			remaining = append(remaining, resource)
+		}
+		if len(remaining) == 0 {
+			delete(m.ResourceNameMap, resourceName)
+			continue
+		}
+		m.ResourceNameMap[resourceName] = remaining
+	}
+	// Scoped resources may be other instances of the same values, so they are removed by hashcodes.
+	removedHashcodes := make(map[uint64]struct{}, len(removed))
+	for resource := range removed {
+		removedHashcodes[resource.Hashcode()] = struct{}{}
+	}
+	m.filterScopedResources(func(resourceName string, resource Resource) bool {
+		_, ok := removedHashcodes[resource.Hashcode()]
+		return !ok
+	})
+	log.Debug().Msgf("[ResourceManager.RemoveResourcesReferringTo] Removed %d resources referring to %s from the pool", len(removed), value)
+	return len(removed)
+}
+
+// filterScopedResources keeps only the resources satisfying keep (with their names) in ResourceSchemaRefMap, and rebuilds ResourceSchemaRef2HashSet.
+func (m *ResourceManager) filterScopedResources(keep func(resourceName string, resource Resource) bool) {
+	m.ResourceSchemaRef2HashSet = make(map[string]map[string]map[uint64]struct{})
+	for schemaRef, scopedResourceNameMap := range m.ResourceSchemaRefMap {
+		for resourceName, resources := range scopedResourceNameMap {
+			remaining := slices.DeleteFunc(resources, func(resource Resource) bool { return !keep(resourceName, resource) })
+			if len(remaining) == 0 {
+				delete(scopedResourceNameMap, resourceName)
+				continue
+			}
+			scopedResourceNameMap[resourceName] = remaining
+			for _, resource := range remaining {
+				m.addScopedHashcode(schemaRef, resourceName, resource.Hashcode())
+			}
+		}
+		if len(scopedResourceNameMap) == 0 {
+			delete(m.ResourceSchemaRefMap, schemaRef)
+		}
+	}
+}
+
+// addScopedHashcode adds the hashcode to ResourceSchemaRef2HashSet, and returns false if it already exists.
+func (m *ResourceManager) addScopedHashcode(schemaRef, resourceName string, hashcode uint64) bool {
+	scopedHashSetMap := m.ResourceSchemaRef2HashSet[schemaRef]
+	if scopedHashSetMap == nil {
+		scopedHashSetMap = make(map[string]map[uint64]struct{})
+		m.ResourceSchemaRef2HashSet[schemaRef] = scopedHashSetMap
+	}
+	hashSet := scopedHashSetMap[resourceName]
+	if hashSet == nil {
+		hashSet = make(map[uint64]struct{})
+		scopedHashSetMap[resourceName] = hashSet
+	}
+	if _, ok := hashSet[hashcode]; ok {
+		return false
+	}
+	hashSet[hashcode] = struct{}{}
+	return true
+}
+
+// isResourceReferringTo checks if the resource refers to the value of valueType, see [ResourceManager.RemoveResourcesReferringTo].
+// Field names should be normalized by [normalizeReferringFieldName].
+func isResourceReferringTo(resource Resource, normalizedFieldNames map[string]struct{}, value string, valueType static.SimpleAPIPropertyType) bool {
+	switch resource.Typ() {
+	case static.SimpleAPIPropertyTypeObject:
+		for fieldName, field := range resource.(*ResourceObject).Value {
+			if _, ok := normalizedFieldNames[normalizeReferringFieldName(fieldName)]; !ok || field == nil {
+				continue
+			}
+			if isPrimitiveResourceOf(field, value, valueType) {
+				return true
+			}
+		}
+		return false
+	case static.SimpleAPIPropertyTypeArray:
+		return false
+	default:
+		return isPrimitiveResourceOf(resource, value, valueType)
+	}
+}
+
+// isPrimitiveResourceOf checks if the resource is a primitive of valueType (of any primitive type if it is unknown), whose string representation equals the value.
+// Integers and floats are both numbers, as JSON numbers may be stored as either of them.
+func isPrimitiveResourceOf(resource Resource, value string, valueType static.SimpleAPIPropertyType) bool {
+	if !isPrimitiveResource(resource) {
+		return false
+	}
+	isNumber := func(typ static.SimpleAPIPropertyType) bool {
+		return typ == static.SimpleAPIPropertyTypeInteger || typ == static.SimpleAPIPropertyTypeFloat
+	}
+	isOfValueType := valueType == static.SimpleAPIPropertyTypeUnknown || resource.Typ() == valueType ||
+		(isNumber(valueType) && isNumber(resource.Typ()))
+	if !isOfValueType {
+		return false
+	}
+	return resource.String() == value
+}
+
+// normalizeReferringFieldName normalizes the field name to be matched, i.e., in lower case, without underscores and hyphens.
+func normalizeReferringFieldName(fieldName string) string {
+	return strings.NewReplacer("_", "", "-", "").Replace(strings.ToLower(fieldName))
+}
+
+// isPrimitiveResource checks if the resource is of a primitive type.
+func isPrimitiveResource(resource Resource) bool {
+	switch resource.Typ() {
+	case static.SimpleAPIPropertyTypeString, static.SimpleAPIPropertyTypeInteger, static.SimpleAPIPropertyTypeFloat, static.SimpleAPIPropertyTypeBoolean:
+		return true
+	default:
+		return false
+	}
+}
+
+// collectResourceTree adds the resource and all its sub-resources (recursively) to the set.
+func collectResourceTree(resource Resource, set map[Resource]struct{}) {
+	if resource == nil {
+		return
+	}
+	set[resource] = struct{}{}
+	switch resource.Typ() {
+	case static.SimpleAPIPropertyTypeObject:
+		for _, subResource := range resource.(*ResourceObject).Value {
+			collectResourceTree(subResource, set)
+		}
+	case static.SimpleAPIPropertyTypeArray:
+		for _, subResource := range resource.(*ResourceArray).Value {
+			collectResourceTree(subResource, set)
+		}
+	}
+}
+
 // storeResource stores a resource in the resource manager.
 // If the resource name is not empty, it will not be stored in the resource name map, i.e., we cannot get it by name.
 // Parameter `shouldStoreSubResources` indicates whether to store sub-resources.
@@ -222,10 +560,20 @@ func (m *ResourceManager) StoreResourcesFromRawObjectBytes(rawObjectBytes []byte
 //   - for object type, all values from the object key-value pairs will be stored (resource name is the key);
 //   - for array type, all elements in the array will be stored (heuristic rules are applied to current `resourceName` to get the name, e.g., "names" -> "name").
 func (m *ResourceManager) storeResource(resource Resource, resourceName string, shouldStoreSubResources bool) {
+	m.storeScopedResource(resource, resourceName, shouldStoreSubResources, nil, "")
+}
+
+// storeScopedResource stores a resource like [ResourceManager.storeResource], where schema describes the resource, and schemaRef is the scope of the enclosing object.
+// The resource is scoped to the ref of schema if any, or schemaRef otherwise, see ResourceSchemaRefMap.
+// A resource duplicate by name is still scoped, if it is new in the scope.
+func (m *ResourceManager) storeScopedResource(resource Resource, resourceName string, shouldStoreSubResources bool, schema *openapi3.SchemaRef, schemaRef string) {
 	if isResourceEmpty(resource) {
-		log.Warn().Msg("[ResourceManager.storeResource] Resource is empty")
+		log.Warn().Msg("[ResourceManager.storeScopedResource] Resource is empty")
 		return
 	}
+	if schema != nil && schema.Ref != "" {
+		schemaRef = schema.Ref
+	}
 
 	// Check if the resource is duplicate.
 	resourceSet := m.ResourceName2HashSet[resourceName]
@@ -234,15 +582,27 @@ func (m *ResourceManager) storeResource(resource Resource, resourceName string,
 		m.ResourceName2HashSet[resourceName] = resourceSet
 	}
 	hashcode := resource.Hashcode()
-	if _, ok := resourceSet[hashcode]; ok {
+	_, isDuplicate := resourceSet[hashcode]
+	isNewInScope := schemaRef != "" && resourceName != "" && m.addScopedHashcode(schemaRef, resourceName, hashcode)
+	if isDuplicate && !isNewInScope {
 		return
 	}
-	resourceSet[hashcode] = struct{}{}
 
 	// Store the resource in the resource manager.
-	m.ResourceTypeMap[resource.Typ()] = append(m.ResourceTypeMap[resource.Typ()], resource)
-	if resourceName != "" {
-		m.ResourceNameMap[resourceName] = append(m.ResourceNameMap[resourceName], resource)
+	if !isDuplicate {
+		resourceSet[hashcode] = struct{}{}
+		m.ResourceTypeMap[resource.Typ()] = append(m.ResourceTypeMap[resource.Typ()], resource)
+		if resourceName != "" {
+			m.ResourceNameMap[resourceName] = append(m.ResourceNameMap[resourceName], resource)
+		}
+	}
+	if isNewInScope {
+		scopedResourceNameMap := m.ResourceSchemaRefMap[schemaRef]
+		if scopedResourceNameMap == nil {
+			scopedResourceNameMap = make(map[string][]Resource)
+			m.ResourceSchemaRefMap[schemaRef] = scopedResourceNameMap
+		}
+		scopedResourceNameMap[resourceName] = append(scopedResourceNameMap[resourceName], resource)
 	}
 
 	if !shouldStoreSubResources {
@@ -251,19 +611,31 @@ func (m *ResourceManager) storeResource(resource Resource, resourceName string,
 	switch resource.Typ() {
 	case static.SimpleAPIPropertyTypeObject:
 		for field, subResource := range resource.(*ResourceObject).Value {
-			m.storeResource(subResource, field, shouldStoreSubResources)
+			m.storeScopedResource(subResource, field, shouldStoreSubResources, getPropertySchema(schema, field), schemaRef)
 		}
 	case static.SimpleAPIPropertyTypeArray:
 		// Heuristic rules to get the name of the array elements.
 		arrayElementName := utils.GetSingularFormNameHeuristic(resourceName)
+		var itemsSchema *openapi3.SchemaRef
+		if schema != nil && schema.Value != nil {
+			itemsSchema = schema.Value.Items
+		}
 		for _, subResource := range resource.(*ResourceArray).Value {
-			m.storeResource(subResource, arrayElementName, shouldStoreSubResources)
+			m.storeScopedResource(subResource, arrayElementName, shouldStoreSubResources, itemsSchema, schemaRef)
 		}
 	default:
 		// Do nothing for primitive types.
 	}
 }
 
+// getPropertySchema returns the schema of the property of an object schema, or nil if it is not declared.
+func getPropertySchema(schema *openapi3.SchemaRef, propertyName string) *openapi3.SchemaRef {
+	if schema == nil || schema.Value == nil {
+		return nil
+	}
+	return schema.Value.Properties[propertyName]
+}
+
 // isResourceEmpty checks if the resource is empty.
 func isResourceEmpty(resource Resource) bool {
 	if resource == nil {
@@ -0,0 +1,227 @@
+package resource
+
+import (
+	"fmt"
+	"maps"
+	"resttracefuzzer/pkg/static"
+	"resttracefuzzer/pkg/utils"
+	"slices"
+	"strings"
+
+	"github.com/bytedance/sonic"
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/rs/zerolog/log"
+)
+
+// StoreSpecExamples harvests documented values from the API doc, i.e., `example`, `examples` and `default` of parameters, request bodies and schemas
+// (including properties, items and subschemas of composition keywords, recursively), so that realistic values are used from the beginning of the campaign.
+// Values are indexed by the schema paths declaring them in SpecExampleMap (see [GetParameterSpecExamplePath] and [GetRequestBodySpecExamplePath]),
+// and stored in the resource pool by the names of parameters and properties as well.
+// Values documented by parameters and request bodies are indexed by the paths of the operations, so that they are not used for the component schemas they refer to.
+// Values harvested before are dropped, so that those of operations not in APIMap anymore are not used.
+// It returns the number of values harvested.
+func (m *ResourceManager) StoreSpecExamples(APIMap map[static.SimpleAPIMethod]*openapi3.Operation) int {
+	m.SpecExampleMap = make(map[string][]Resource)
+	storedCnt := 0
+	visited := make(map[string]struct{})
+	// Sort API methods, so that values are stored in a stable order.
+	for _, APIMethod := range slices.SortedFunc(maps.Keys(APIMap), static.CompareSimpleAPIMethod) {
+		operation := APIMap[APIMethod]
+		if operation == nil {
+			continue
+		}
+		for _, paramRef := range operation.Parameters {
+			if paramRef == nil || paramRef.Value == nil {
+				continue
+			}
+			param := paramRef.Value
+			paramSchema := utils.GetParameterSchema(param)
+			paramPath := GetParameterSpecExamplePath(APIMethod, param)
+			values := make([]any, 0)
+			if param.Example != nil {
+				values = append(values, param.Example)
+			}
+			values = append(values, getExampleRefValues(param.Examples)...)
+			storedCnt += m.storeSpecExampleValues(values, param.Name, []string{paramPath}, paramSchema)
+			storedCnt += m.storeSchemaSpecExamples(param.Name, getSpecExampleSchemaPaths(paramSchema, []string{paramPath}), paramSchema, visited)
+		}
+
+		bodySchema := utils.GetRequestBodySchema(operation.RequestBody)
+		if bodySchema == nil {
+			continue
+		}
+		bodyPath := GetRequestBodySpecExamplePath(APIMethod)
+		values := make([]any, 0)
+		for _, mediaType := range slices.Sorted(maps.Keys(operation.RequestBody.Value.Content)) {
+			mediaTypeDef := operation.RequestBody.Value.Content[mediaType]
+			if mediaTypeDef == nil || mediaTypeDef.Schema != bodySchema {
+				continue
+			}
+			if mediaTypeDef.Example != nil {
+				values = append(values, mediaTypeDef.Example)
+			}
+			values = append(values, getExampleRefValues(mediaTypeDef.Examples)...)
+		}
+		storedCnt += m.storeSpecExampleValues(values, "", []string{bodyPath}, bodySchema)
+		storedCnt += m.storeSchemaSpecExamples("", getSpecExampleSchemaPaths(bodySchema, []string{bodyPath}), bodySchema, visited)
+	}
+	log.Info().Msgf("[ResourceManager.StoreSpecExamples] Harvested %d documented values from the API doc", storedCnt)
+	return storedCnt
+}
+
+// GetSingleSpecExample gets a documented value of any of the schema paths (see [ResourceManager.StoreSpecExamples]), or nil if none of them documents any.
+// A schema may have more than one path, e.g., a parameter and the component schema it refers to.
+func (m *ResourceManager) GetSingleSpecExample(schemaPaths ...string) Resource {
+	examples := make([]Resource, 0)
+	for _, schemaPath := range schemaPaths {
+		examples = append(examples, m.SpecExampleMap[schemaPath]...)
+	}
+	if len(examples) == 0 {
+		return nil
+	}
+	return examples[utils.SharedRand.IntN(len(examples))]
+}
+
+// RemoveSpecExamplesOfOperation removes the documented values indexed by the paths of the API method, i.e., those of its parameters and request body (and their inline schemas).
+// Values of component schemas are kept, as they may be shared by other operations.
+// It returns the number of schema paths removed.
+func (m *ResourceManager) RemoveSpecExamplesOfOperation(APIMethod static.SimpleAPIMethod) int {
+	removedCnt := 0
+	operationPathPrefix := getOperationSpecExamplePath(APIMethod) + "/"
+	for schemaPath := range m.SpecExampleMap {
+		if strings.HasPrefix(schemaPath, operationPathPrefix) {
+			delete(m.SpecExampleMap, schemaPath)
+			removedCnt++
+		}
+	}
+	return removedCnt
+}
+
+// GetParameterSpecExamplePath returns the schema path of the parameter of the API method, e.g., `GET /pets#/parameters/query/limit`.
+func GetParameterSpecExamplePath(APIMethod static.SimpleAPIMethod, param *openapi3.Parameter) string {
+	return fmt.Sprintf("%s/parameters/%s/%s", getOperationSpecExamplePath(APIMethod), param.In, param.Name)
+}
+
+// GetRequestBodySpecExamplePath returns the schema path of the request body of the API method, e.g., `POST /pets#/requestBody`.
+func GetRequestBodySpecExamplePath(APIMethod static.SimpleAPIMethod) string {
+	return getOperationSpecExamplePath(APIMethod) + "/requestBody"
+}
+
+// GetPropertySpecExamplePaths returns the schema paths of the property of a schema of the paths.
+func GetPropertySpecExamplePaths(schemaPaths []string, propName string) []string {
+	return appendSpecExamplePathSuffix(schemaPaths, "/properties/"+propName)
+}
+
+// GetItemsSpecExamplePaths returns the schema paths of the items of an array schema of the paths.
+func GetItemsSpecExamplePaths(schemaPaths []string) []string {
+	return appendSpecExamplePathSuffix(schemaPaths, "/items")
+}
+
+// getOperationSpecExamplePath returns the path of the API method, under which the schema paths of its parameters and request body are.
+func getOperationSpecExamplePath(APIMethod static.SimpleAPIMethod) string {
+	return fmt.Sprintf("%s %s#", APIMethod.Method, APIMethod.Endpoint)
+}
+
+// appendSpecExamplePathSuffix returns the schema paths with the suffix appended.
+func appendSpecExamplePathSuffix(schemaPaths []string, suffix string) []string {
+	childPaths := make([]string, 0, len(schemaPaths))
+	for _, schemaPath := range schemaPaths {
+		childPaths = append(childPaths, schemaPath+suffix)
+	}
+	return childPaths
+}
+
+// getSpecExampleSchemaPaths returns the schema paths of the schema at the given paths,
+// i.e., the ref of the schema if it is a component schema, as values of component schemas are indexed by their refs only, or the given paths otherwise.
+func getSpecExampleSchemaPaths(schemaRef *openapi3.SchemaRef, schemaPaths []string) []string {
+	if schemaRef != nil && schemaRef.Ref != "" {
+		return []string{schemaRef.Ref}
+	}
+	return schemaPaths
+}
+
+// storeSchemaSpecExamples stores documented values of the schema and its properties, items and subschemas recursively,
+// where name is the name of the value of the schema, and schemaPaths are the paths of the schema.
+// Subschemas of composition keywords are merged into the schema in value generation, so their values are indexed by the paths of the schema as well.
+// Schemas visited at the same paths are skipped, as schemas can be recursive.
+func (m *ResourceManager) storeSchemaSpecExamples(name string, schemaPaths []string, schemaRef *openapi3.SchemaRef, visited map[string]struct{}) int {
+	if schemaRef == nil || schemaRef.Value == nil || len(schemaPaths) == 0 {
+		return 0
+	}
+	schema := schemaRef.Value
+	// The same schema is visited at different paths, e.g., a component schema is a subschema of different composite schemas.
+	visitedKey := fmt.Sprintf("%p %s", schema, strings.Join(schemaPaths, " "))
+	if _, exist := visited[visitedKey]; exist {
+		return 0
+	}
+	visited[visitedKey] = struct{}{}
+
+	values := make([]any, 0)
+	for _, value := range append([]any{schema.Example, schema.Default}, schema.Examples...) {
+		if value != nil {
+			values = append(values, value)
+		}
+	}
+	storedCnt := m.storeSpecExampleValues(values, name, schemaPaths, schemaRef)
+	for _, propName := range slices.Sorted(maps.Keys(schema.Properties)) {
+		propSchema := schema.Properties[propName]
+		storedCnt += m.storeSchemaSpecExamples(propName, getSpecExampleSchemaPaths(propSchema, GetPropertySpecExamplePaths(schemaPaths, propName)), propSchema, visited)
+	}
+	storedCnt += m.storeSchemaSpecExamples(utils.GetSingularFormNameHeuristic(name), getSpecExampleSchemaPaths(schema.Items, GetItemsSpecExamplePaths(schemaPaths)), schema.Items, visited)
+	for _, subschemas := range []openapi3.SchemaRefs{schema.AllOf, schema.OneOf, schema.AnyOf} {
+		for _, subschema := range subschemas {
+			subschemaPaths := slices.Clone(schemaPaths)
+			if subschema != nil && subschema.Ref != "" && !slices.Contains(subschemaPaths, subschema.Ref) {
+				subschemaPaths = append(subschemaPaths, subschema.Ref)
+			}
+			storedCnt += m.storeSchemaSpecExamples(name, subschemaPaths, subschema, visited)
+		}
+	}
+	return storedCnt
+}
+
+// storeSpecExampleValues indexes documented values by the schema paths in SpecExampleMap, and stores them (with sub-resources) in the resource pool by the name.
+// Values are converted via JSON, so that whole numbers are integer resources. It returns the number of values new to the schema paths.
+func (m *ResourceManager) storeSpecExampleValues(values []any, name string, schemaPaths []string, schemaRef *openapi3.SchemaRef) int {
+	if schemaRef == nil || schemaRef.Value == nil {
+		return 0
+	}
+	storedCnt := 0
+	for _, value := range values {
+		valueBytes, err := sonic.Marshal(value)
+		if err != nil {
+			log.Err(err).Msgf("[ResourceManager.storeSpecExampleValues] Failed to marshal documented value of %s", name)
+			continue
+		}
+		resource, err := NewResourceFromRawBytes(valueBytes)
+		if err != nil || isResourceEmpty(resource) {
+			continue
+		}
+		isNew := false
+		for _, schemaPath := range schemaPaths {
+			examples := m.SpecExampleMap[schemaPath]
+			if slices.ContainsFunc(examples, func(example Resource) bool { return example.Hashcode() == resource.Hashcode() }) {
+				continue
+			}
+			m.SpecExampleMap[schemaPath] = append(examples, resource)
+			isNew = true
+		}
+		if !isNew {
+			continue
+		}
+		m.storeScopedResource(resource, name, true, schemaRef, "")
+		storedCnt++
+	}
+	return storedCnt
+}
+
+// getExampleRefValues returns the values of the examples, sorted by example name.
+func getExampleRefValues(examples openapi3.Examples) []any {
+	values := make([]any, 0, len(examples))
+	for _, exampleName := range slices.Sorted(maps.Keys(examples)) {
+		if exampleRef := examples[exampleName]; exampleRef != nil && exampleRef.Value != nil && exampleRef.Value.Value != nil {
+			values = append(values, exampleRef.Value.Value)
+		}
+	}
+	return values
+}
@@ -83,8 +83,11 @@ func (g *CallInfoGraph) UpdateFromCallInfos(callInfos []*trace.CallInfo) error {
 	return nil
 }
 
-// GetEdgeCoverage returns the edge coverage of the runtime call info graph.
+// GetEdgeCoverage returns the edge coverage of the runtime call info graph, or 0 if there is no edge.
 func (g *CallInfoGraph) GetEdgeCoverage() float64 {
+	if len(g.Edges) == 0 {
+		return 0
+	}
 	return float64(g.GetEdgeCoveredCount()) / float64(len(g.Edges))
 }
 
@@ -98,3 +101,21 @@ func (g *CallInfoGraph) GetEdgeCoveredCount() int {
 	}
 	return coveredEdges
 }
+
+// GetDistanceToUncoveredEdge returns the shortest distance from the endpoint to the source of any uncovered edge (i.e., of zero hit count), by BFS on the graph.
+// It is 0 if an uncovered edge starts from the endpoint itself. It returns false if no uncovered edge is reachable from the endpoint.
+func (g *CallInfoGraph) GetDistanceToUncoveredEdge(endpoint static.InternalServiceEndpoint) (int, bool) {
+	endpoint.ServiceName = utils.FormatServiceName(endpoint.ServiceName)
+	distanceMap := g.GetDistanceMapBySource(endpoint)
+	minDistance, found := 0, false
+	for _, edge := range g.Edges {
+		if edge.HitCount > 0 {
+			continue
+		}
+		distance, reachable := distanceMap[edge.Source]
+		if reachable && (!found || distance < minDistance) {
+			minDistance, found = distance, true
+		}
+	}
+	return minDistance, found
+}
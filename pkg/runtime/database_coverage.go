@@ -0,0 +1,90 @@
+package runtime
+
+import (
+	"cmp"
+	"maps"
+	"resttracefuzzer/pkg/feedback/trace"
+	"resttracefuzzer/pkg/utils"
+	"slices"
+)
+
+// DatabaseOperation represents an operation on a database table by an internal service, i.e., a (service, table, operation) tuple.
+type DatabaseOperation struct {
+	// ServiceName is the name of the service, in standard case.
+	ServiceName string `json:"serviceName"`
+
+	// Table is the table (or collection) operated on. It is empty if unknown.
+	Table string `json:"table"`
+
+	// Operation is the database operation, e.g., 'SELECT' or 'INSERT'.
+	Operation string `json:"operation"`
+}
+
+// DatabaseOperationEntry is the runtime info of a DatabaseOperation.
+type DatabaseOperationEntry struct {
+	DatabaseOperation
+
+	// HitCount is the number of database spans of the operation.
+	HitCount int `json:"hitCount"`
+}
+
+// DatabaseCoverage records database operations of internal services from database spans (see [trace.SemanticConventionTypeDatabase]) in traces.
+// A new DatabaseOperation is considered as new coverage, so that the search is guided toward exercising more SQL operations.
+type DatabaseCoverage struct {
+	// DatabaseOperationMap maps from database operations to their runtime info.
+	DatabaseOperationMap map[DatabaseOperation]*DatabaseOperationEntry `json:"-"`
+}
+
+// NewDatabaseCoverage creates a new DatabaseCoverage.
+func NewDatabaseCoverage() *DatabaseCoverage {
+	return &DatabaseCoverage{
+		DatabaseOperationMap: make(map[DatabaseOperation]*DatabaseOperationEntry),
+	}
+}
+
+// UpdateFromTrace updates the database coverage from database spans of the trace.
+// It returns the number of new database operations.
+func (c *DatabaseCoverage) UpdateFromTrace(simplifiedTrace *trace.SimplifiedTrace) int {
+	if simplifiedTrace == nil {
+		return 0
+	}
+	newCount := 0
+	for _, span := range simplifiedTrace.SpanMap {
+		if span == nil {
+			continue
+		}
+		table, operation, ok := span.RetrieveDatabaseOperation()
+		if !ok {
+			continue
+		}
+		databaseOperation := DatabaseOperation{
+			ServiceName: utils.FormatServiceName(span.ServiceName),
+			Table:       table,
+			Operation:   operation,
+		}
+		entry, exist := c.DatabaseOperationMap[databaseOperation]
+		if !exist {
+			entry = &DatabaseOperationEntry{DatabaseOperation: databaseOperation}
+			c.DatabaseOperationMap[databaseOperation] = entry
+			newCount++
+		}
+		entry.HitCount++
+	}
+	return newCount
+}
+
+// GetCoveredCount returns the number of distinct database operations.
+func (c *DatabaseCoverage) GetCoveredCount() int {
+	return len(c.DatabaseOperationMap)
+}
+
+// GetDatabaseOperationEntries returns the runtime info of all database operations, sorted by service, table, and operation.
+func (c *DatabaseCoverage) GetDatabaseOperationEntries() []*DatabaseOperationEntry {
+	return slices.SortedFunc(maps.Values(c.DatabaseOperationMap), func(a, b *DatabaseOperationEntry) int {
+		return cmp.Or(
+			cmp.Compare(a.ServiceName, b.ServiceName),
+			cmp.Compare(a.Table, b.Table),
+			cmp.Compare(a.Operation, b.Operation),
+		)
+	})
+}
@@ -1,34 +1,73 @@
 package runtime
 
 import (
+	"math"
 	"resttracefuzzer/pkg/feedback/trace"
 	"resttracefuzzer/pkg/static"
 	"resttracefuzzer/pkg/utils"
+	"slices"
 
 	"github.com/rs/zerolog/log"
 )
 
+const (
+	// reachabilityLowConfidenceScore is the confidence score of reachability of low confidence level, e.g., from the API doc, see [RuntimeReachabilityMap.GetConfidence].
+	reachabilityLowConfidenceScore = 0.1
+
+	// reachabilityStaleHalfLives is the number of half-lives (see [RuntimeReachabilityMap.DecayHalfLife]) without observation
+	// after which reachability of high confidence level is stale, and demoted to low confidence level.
+	reachabilityStaleHalfLives = 3
+)
+
+// ReachabilityObservation records observations of reachability from an external API to an internal service endpoint in traces.
+type ReachabilityObservation struct {
+	// Count is the number of traces of the external API in which the internal service endpoint is called.
+	Count int `json:"count"`
+
+	// LastObservedTrace is the number of traces of the external API (see [RuntimeReachabilityMap.ExternalTraceCount]) when the reachability is last observed.
+	LastObservedTrace int `json:"lastObservedTrace"`
+}
+
 // RuntimeReachabilityMap is a map that tracks the reachability of functions at runtime.
 // The map would be updated during execution to reflect the current knowledge of the system components.
 // The map contains two ReachabilityMap, both of type [resttracefuzzer/pkg/static.ReachabilityMap]. One is of hign confidence level and the other is of low confidence level.
+// Besides the levels, observations of reachability in traces are tracked, to score the confidence of reachability, see [RuntimeReachabilityMap.GetConfidence].
 // Note: to handle various formats of service names, they would be processed when updating the map.
 type RuntimeReachabilityMap struct {
 	// High confidence reachability map
 	HighConfidenceMap *static.ReachabilityMap
 	// Low confidence reachability map
 	LowConfidenceMap *static.ReachabilityMap
+
+	// DecayHalfLife is the number of traces of an external API, after which the confidence of reachability from it not observed since halves.
+	// Reachability not observed for [reachabilityStaleHalfLives] half-lives is stale, and demoted to low confidence level until observed again.
+	// There is no decay if it is not positive.
+	DecayHalfLife int
+
+	// ExternalTraceCount maps from external APIs to the number of their traces, see [RuntimeReachabilityMap.UpdateFromCallInfos].
+	ExternalTraceCount map[static.SimpleAPIMethod]int
+
+	// Observations maps from external APIs to internal service endpoints to the observations of reachability between them.
+	Observations map[static.SimpleAPIMethod]map[static.InternalServiceEndpoint]*ReachabilityObservation
 }
 
-// NewRuntimeReachabilityMap creates a new RuntimeReachabilityMap with empty maps.
+// NewRuntimeReachabilityMap creates a new RuntimeReachabilityMap with empty maps, without decay.
 func NewRuntimeReachabilityMap() *RuntimeReachabilityMap {
 	highConfidenceMap := static.NewReachabilityMap()
 	lowConfidenceMap := static.NewReachabilityMap()
 	return &RuntimeReachabilityMap{
-		HighConfidenceMap: highConfidenceMap,
-		LowConfidenceMap:  lowConfidenceMap,
+		HighConfidenceMap:  highConfidenceMap,
+		LowConfidenceMap:   lowConfidenceMap,
+		ExternalTraceCount: make(map[static.SimpleAPIMethod]int),
+		Observations:       make(map[static.SimpleAPIMethod]map[static.InternalServiceEndpoint]*ReachabilityObservation),
 	}
 }
 
+// SetDecayHalfLife sets the half-life of the confidence of reachability, see [RuntimeReachabilityMap.DecayHalfLife].
+func (r *RuntimeReachabilityMap) SetDecayHalfLife(decayHalfLife int) {
+	r.DecayHalfLife = decayHalfLife
+}
+
 // NewRuntimeReachabilityMapFromStaticMap creates a new RuntimeReachabilityMap from static maps.
 // The static maps is generated from API Doc, and is of low confidence level.
 // service names in the static maps may not be formatted, so we need to format them when adding to the map.
@@ -75,14 +114,18 @@ func (r *RuntimeReachabilityMap) RemoveReachabilityWithConfidenceLevel(external
 	}
 }
 
-// UpdateFromCallInfos updates the reachability map from the call info.
+// UpdateFromCallInfos updates the reachability map from the call info of a trace of the external endpoint.
 // The call info is parsed from traces generated by services in the system.
 // Trace reflects the real info of the system, so we should update the high confidence map.
+// The trace is counted even if it has no call info, as it tells that the internal service endpoints reached before are not called this time,
+// and stale reachability from the external endpoint is demoted then, see [RuntimeReachabilityMap.DecayHalfLife].
 func (r *RuntimeReachabilityMap) UpdateFromCallInfos(externalEndpoint static.SimpleAPIMethod, callInfoList []*trace.CallInfo) error {
+	r.ExternalTraceCount[externalEndpoint]++
+	defer r.demoteStaleReachability(externalEndpoint)
 	if len(callInfoList) == 0 {
 		return nil
 	}
-	
+
 	// parse internal service endpoints from call info
 	internalServiceEndpoints := make([]static.InternalServiceEndpoint, 0)
 	for _, callInfo := range callInfoList {
@@ -98,9 +141,18 @@ func (r *RuntimeReachabilityMap) UpdateFromCallInfos(externalEndpoint static.Sim
 		internalServiceEndpoints = append(internalServiceEndpoints, internalServiceEndpoint)
 	}
 
-	// Update the high confidence map
+	// Update the high confidence map, and observations of each internal service endpoint once per trace.
+	observedEndpoints := make(map[static.InternalServiceEndpoint]struct{})
 	for _, internalServiceEndpoint := range internalServiceEndpoints {
-		r.AddReachabilityWithConfidenceLevel(externalEndpoint, internalServiceEndpoint, 1)
+		internalServiceEndpoint.ServiceName = utils.FormatServiceName(internalServiceEndpoint.ServiceName)
+		if _, observed := observedEndpoints[internalServiceEndpoint]; observed {
+			continue
+		}
+		observedEndpoints[internalServiceEndpoint] = struct{}{}
+		r.recordObservation(externalEndpoint, internalServiceEndpoint)
+		if !r.HighConfidenceMap.HasReachability(externalEndpoint, internalServiceEndpoint) {
+			r.AddReachabilityWithConfidenceLevel(externalEndpoint, internalServiceEndpoint, 1)
+		}
 	}
 	
 	// If the info exists in the low confidence map, we should remove it from the low confidence map.
@@ -113,6 +165,84 @@ func (r *RuntimeReachabilityMap) UpdateFromCallInfos(externalEndpoint static.Sim
 	return nil
 }
 
+// recordObservation records an observation of reachability from the external endpoint to the internal service endpoint, in the latest trace of the former.
+func (r *RuntimeReachabilityMap) recordObservation(external static.SimpleAPIMethod, internal static.InternalServiceEndpoint) {
+	observations, exists := r.Observations[external]
+	if !exists {
+		observations = make(map[static.InternalServiceEndpoint]*ReachabilityObservation)
+		r.Observations[external] = observations
+	}
+	observation, exists := observations[internal]
+	if !exists {
+		observation = &ReachabilityObservation{}
+		observations[internal] = observation
+	}
+	observation.Count++
+	observation.LastObservedTrace = r.ExternalTraceCount[external]
+}
+
+// getObservation returns the observations of reachability from the external endpoint to the internal service endpoint.
+// Reachability of high confidence level never observed in this run (e.g., loaded from the knowledge base) is regarded as observed once, before any trace.
+func (r *RuntimeReachabilityMap) getObservation(external static.SimpleAPIMethod, internal static.InternalServiceEndpoint) (*ReachabilityObservation, bool) {
+	if observation, exists := r.Observations[external][internal]; exists {
+		return observation, true
+	}
+	if r.HighConfidenceMap.HasReachability(external, internal) {
+		return &ReachabilityObservation{Count: 1}, true
+	}
+	return nil, false
+}
+
+// getDecay returns the decay factor of reachability observed as the observation, i.e., 0.5^(traces of the external endpoint since last observed / half-life).
+func (r *RuntimeReachabilityMap) getDecay(external static.SimpleAPIMethod, observation *ReachabilityObservation) float64 {
+	if r.DecayHalfLife <= 0 {
+		return 1
+	}
+	staleness := max(r.ExternalTraceCount[external]-observation.LastObservedTrace, 0)
+	return math.Pow(0.5, float64(staleness)/float64(r.DecayHalfLife))
+}
+
+// demoteStaleReachability demotes reachability from the external endpoint not observed for [reachabilityStaleHalfLives] half-lives to low confidence level.
+func (r *RuntimeReachabilityMap) demoteStaleReachability(external static.SimpleAPIMethod) {
+	if r.DecayHalfLife <= 0 {
+		return
+	}
+	internals, _ := r.HighConfidenceMap.GetInternalsByExternal(external)
+	for _, internal := range slices.Clone(internals) {
+		observation, _ := r.getObservation(external, internal)
+		if r.getDecay(external, observation) > math.Pow(0.5, reachabilityStaleHalfLives) {
+			continue
+		}
+		for r.HighConfidenceMap.HasReachability(external, internal) {
+			r.RemoveReachabilityWithConfidenceLevel(external, internal, 1)
+		}
+		if !r.LowConfidenceMap.HasReachability(external, internal) {
+			r.AddReachabilityWithConfidenceLevel(external, internal, 0)
+		}
+		log.Debug().Msgf("[RuntimeReachabilityMap.demoteStaleReachability] Reachability from %v to %v is not observed in %d traces, demoted to low confidence", external, internal, r.ExternalTraceCount[external]-observation.LastObservedTrace)
+	}
+}
+
+// GetConfidence returns the confidence score (in [0, 1]) of reachability from the external API to the internal service endpoint.
+// For reachability observed in traces (or of high confidence level), it is the ratio of traces of the external API calling the internal service endpoint,
+// weighted by the decay since last observed (see [RuntimeReachabilityMap.DecayHalfLife]), so frequent and recent reachability scores higher.
+// Otherwise, it is [reachabilityLowConfidenceScore] for reachability of low confidence level, or 0 if not reachable.
+func (r *RuntimeReachabilityMap) GetConfidence(external static.SimpleAPIMethod, internal static.InternalServiceEndpoint) float64 {
+	internal.ServiceName = utils.FormatServiceName(internal.ServiceName)
+	observation, observed := r.getObservation(external, internal)
+	if !observed {
+		if r.LowConfidenceMap.HasReachability(external, internal) {
+			return reachabilityLowConfidenceScore
+		}
+		return 0
+	}
+	ratio := 1.0
+	if traceCount := r.ExternalTraceCount[external]; traceCount > 0 {
+		ratio = min(float64(observation.Count)/float64(traceCount), 1)
+	}
+	return ratio * r.getDecay(external, observation)
+}
+
 // GetReachableInternalEndpointsByExternalAPI gets the reachable internal endpoints by external API.
 // The reachable internal endpoints are the ones that can be reached from the external API.
 // Parameter `useHighConfidenceOnly` indicates whether to use high confidence map only or not.
@@ -0,0 +1,99 @@
+package runtime
+
+import (
+	"cmp"
+	"maps"
+	"resttracefuzzer/pkg/feedback/trace"
+	"resttracefuzzer/pkg/utils"
+	"slices"
+)
+
+// SpanError represents an error type occurring in an operation of an internal service, i.e., a (service, operation, error type) tuple.
+type SpanError struct {
+	// ServiceName is the name of the service, in standard case.
+	ServiceName string `json:"serviceName"`
+
+	// OperationName is the name of the operation (span name), e.g., 'GET /users/{id}'.
+	OperationName string `json:"operationName"`
+
+	// ErrorType is the type of the error, see [trace.SimplifiedTraceSpan.GetErrorTypes].
+	ErrorType string `json:"errorType"`
+}
+
+// SpanErrorEntry is the runtime info of a SpanError.
+type SpanErrorEntry struct {
+	SpanError
+
+	// HitCount is the number of spans with the error.
+	HitCount int `json:"hitCount"`
+
+	// ExampleMessage is the exception (or status) message of the first span with the error.
+	ExampleMessage string `json:"exampleMessage"`
+}
+
+// SpanErrorCoverage records errors (error status and exceptions) of spans in traces.
+// A new SpanError is considered as new coverage, so that internal exceptions guide the search,
+// even if they are swallowed by upstream services, e.g., the HTTP response is 200.
+type SpanErrorCoverage struct {
+	// SpanErrorMap maps from span errors to their runtime info.
+	SpanErrorMap map[SpanError]*SpanErrorEntry `json:"-"`
+}
+
+// NewSpanErrorCoverage creates a new SpanErrorCoverage.
+func NewSpanErrorCoverage() *SpanErrorCoverage {
+	return &SpanErrorCoverage{
+		SpanErrorMap: make(map[SpanError]*SpanErrorEntry),
+	}
+}
+
+// UpdateFromTrace updates the span error coverage from spans of the trace.
+// It returns the number of new span errors.
+func (c *SpanErrorCoverage) UpdateFromTrace(simplifiedTrace *trace.SimplifiedTrace) int {
+	if simplifiedTrace == nil {
+		return 0
+	}
+	newCount := 0
+	for _, span := range simplifiedTrace.SpanMap {
+		if span == nil || !span.IsError() {
+			continue
+		}
+		message := span.StatusMessage
+		if len(span.Exceptions) > 0 && span.Exceptions[0].Message != "" {
+			message = span.Exceptions[0].Message
+		}
+		for _, errorType := range span.GetErrorTypes() {
+			spanError := SpanError{
+				ServiceName:   utils.FormatServiceName(span.ServiceName),
+				OperationName: span.OperationName,
+				ErrorType:     errorType,
+			}
+			entry, exist := c.SpanErrorMap[spanError]
+			if !exist {
+				entry = &SpanErrorEntry{
+					SpanError:      spanError,
+					ExampleMessage: message,
+				}
+				c.SpanErrorMap[spanError] = entry
+				newCount++
+			}
+			entry.HitCount++
+		}
+	}
+	return newCount
+}
+
+// GetCoveredCount returns the number of distinct span errors.
+func (c *SpanErrorCoverage) GetCoveredCount() int {
+	return len(c.SpanErrorMap)
+}
+
+// GetSpanErrorEntries returns the runtime info of all span errors, sorted by service, operation, and error type.
+func (c *SpanErrorCoverage) GetSpanErrorEntries() []*SpanErrorEntry {
+	return slices.SortedFunc(maps.Values(c.SpanErrorMap), func(a, b *SpanErrorEntry) int {
+		return cmp.Or(
+			cmp.Compare(a.ServiceName, b.ServiceName),
+			cmp.Compare(a.OperationName, b.OperationName),
+			cmp.Compare(a.ErrorType, b.ErrorType),
+		)
+	})
+}
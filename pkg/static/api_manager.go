@@ -1,6 +1,7 @@
 package static
 
 import (
+	"fmt"
 	"maps"
 	"resttracefuzzer/internal/config"
 	"resttracefuzzer/pkg/utils"
@@ -9,6 +10,19 @@ import (
 
 	"github.com/getkin/kin-openapi/openapi3"
 	"github.com/rs/zerolog/log"
+	"google.golang.org/protobuf/reflect/protoregistry"
+)
+
+// frontendServiceName is the name of the frontend 'service', to which external APIs belong.
+// TODO: make frontend 'service' name configurable @xunzhou24
+const frontendServiceName = "frontend"
+
+const (
+	// MessagingPublishMethod is the method of message-producing operations, see [SimpleAPIMethodTypeMessaging].
+	MessagingPublishMethod = "PUBLISH"
+
+	// MessagingConsumeMethod is the method of message-consuming operations of internal services, see [SimpleAPIMethodTypeMessaging].
+	MessagingConsumeMethod = "CONSUME"
 )
 
 // APIManager represents an API manager that manages the API definition,
@@ -20,6 +34,24 @@ type APIManager struct {
 	// The map from the simple API method to the OpenAPI operation.
 	APIMap map[SimpleAPIMethod]*openapi3.Operation
 
+	// ServerURLs are resolved URLs of servers of APIDoc to rotate among for test scenarios (see config `server-rotation`),
+	// or empty if servers are not rotated.
+	ServerURLs []string
+
+	// APITargets are additional externally exposed gateways of the system besides the primary one (of APIDoc),
+	// whose operations are added to APIMap as well, with [SimpleAPIMethod.Target] set, see [APIManager.SetAPITargets].
+	APITargets []*APITarget
+
+	// OperationFilter filters operations added to APIMap, see [APIManager.SetOperationFilter]. All operations are added if nil.
+	OperationFilter *OperationFilter
+
+	// FilteredOperations are operations in docs excluded by OperationFilter.
+	FilteredOperations []SimpleAPIMethod
+
+	// GRPCDescriptors are the descriptors of gRPC services of the system, see [APIManager.SetGRPCDescriptors].
+	// Operations of the system doc tagged `APIType_gRPC` are of type [SimpleAPIMethodTypeGRPC] only if it is set, and HTTP ones otherwise.
+	GRPCDescriptors *protoregistry.Files
+
 	// Internal APIs of the services in the system.
 	InternalServiceAPIDoc *openapi3.T
 
@@ -43,6 +75,31 @@ type APIManager struct {
 	// This map is initialized from API doc, and would not be updated in the process of fuzzing.
 	// We will keep updating the dynamic (runtime) version in runtime package
 	StaticReachabilityMap *ReachabilityMap
+
+	// OperationAliasMap maps from the operationId (or its alias if the operationId collides) to the operation endpoint.
+	// External APIs are registered under service `frontend`.
+	OperationAliasMap map[string]InternalServiceEndpoint
+
+	// OperationCollisions are duplicate operationIds and path collisions detected in docs.
+	// Colliding operations are disambiguated deterministically by prefixing with service name, see [OperationCollision].
+	OperationCollisions []*OperationCollision
+
+	// UnsupportedOperations are external operations requiring features the fuzzer does not support yet, see [UnsupportedOperation].
+	// If config `skip-unsupported-operations` is set to true, they are excluded from APIMap, and thus skipped in fuzzing and coverage.
+	UnsupportedOperations []*UnsupportedOperation
+
+	// OperationPreconditions maps from an external API method to those that must precede it in a test scenario,
+	// declared by vendor extension [OperationPreconditionExtensionKey] with operationIds.
+	OperationPreconditions map[SimpleAPIMethod][]SimpleAPIMethod
+
+	// AsyncAPIDoc is the AsyncAPI doc of channels the system consumes messages from. It is nil if not provided.
+	AsyncAPIDoc *AsyncAPIDoc
+
+	// MessagingChannelMap maps from message-producing operations (see [SimpleAPIMethodTypeMessaging]) to channels their messages are published to.
+	MessagingChannelMap map[SimpleAPIMethod]*MessagingChannel
+
+	// InternalServiceAsyncAPIDoc is the AsyncAPI doc of channels internal services publish messages to or consume messages from. It is nil if not provided.
+	InternalServiceAsyncAPIDoc *AsyncAPIDoc
 }
 
 // NewAPIManager creates a new APIManager.
@@ -50,23 +107,84 @@ func NewAPIManager() *APIManager {
 	return &APIManager{}
 }
 
+// SetAPITargets sets additional targets of the system, whose docs should have been parsed.
+// It should be called before initializing the API manager from docs, e.g., [APIManager.InitFromDocs].
+func (m *APIManager) SetAPITargets(targets []*APITarget) {
+	m.APITargets = targets
+}
+
+// SetOperationFilter sets the filter of operations to fuzz.
+// It should be called before initializing the API manager from docs, e.g., [APIManager.InitFromDocs].
+func (m *APIManager) SetOperationFilter(filter *OperationFilter) {
+	m.OperationFilter = filter
+}
+
+// SetGRPCDescriptors sets the loaded descriptors of gRPC services of the system, so that gRPC operations of the system doc can be executed natively.
+// It should be called before initializing the API manager from docs, e.g., [APIManager.InitFromDocs].
+func (m *APIManager) SetGRPCDescriptors(files *protoregistry.Files) {
+	m.GRPCDescriptors = files
+}
+
+// GetOperationTarget returns the additional target the operation comes from, see [SimpleAPIMethod.Target].
+// It returns false if the operation is of the primary target, or the target is unknown.
+func (m *APIManager) GetOperationTarget(method SimpleAPIMethod) (*APITarget, bool) {
+	if method.Target == "" {
+		return nil, false
+	}
+	for _, target := range m.APITargets {
+		if target.Name == method.Target {
+			return target, true
+		}
+	}
+	return nil, false
+}
+
 // InitFromDocs initializes the API manager from docs, including that of external APIs and of internal service interfaces.
 // It do some initilization work that needs both docs as well, such as reachability map.
 func (m *APIManager) InitFromDocs(externalDoc, internalDoc *openapi3.T) {
-	m.initFromSystemDoc(externalDoc)
-	m.initFromServiceDoc(internalDoc)
+	m.InitFromDocsWithDataflowGraph(externalDoc, internalDoc, nil)
+}
+
+// InitFromDocsWithDataflowGraph initializes the API manager like [APIManager.InitFromDocs],
+// but uses the given dataflow graph (e.g., cached from previous runs with the same docs) instead of parsing it from docs.
+// If dataflowGraph is nil, it is parsed from docs.
+func (m *APIManager) InitFromDocsWithDataflowGraph(externalDoc, internalDoc *openapi3.T, dataflowGraph *APIDataflowGraph) {
+	m.InitFromDocsWithAsyncAPIDocs(externalDoc, internalDoc, nil, nil, dataflowGraph)
+}
+
+// InitFromDocsWithAsyncAPIDocs initializes the API manager like [APIManager.InitFromDocsWithDataflowGraph], with AsyncAPI docs of message-driven APIs besides.
+// systemAsyncAPIDoc describes channels the system consumes messages from, which become message-producing operations of the system (see [APIManager.AsyncAPIDoc]),
+// and serviceAsyncAPIDoc describes channels internal services publish messages to or consume messages from, which contribute endpoints and dataflow edges.
+// Either of them can be nil. AsyncAPI docs should have references resolved, see [AsyncAPIDoc.ResolveRefs].
+func (m *APIManager) InitFromDocsWithAsyncAPIDocs(externalDoc, internalDoc *openapi3.T, systemAsyncAPIDoc, serviceAsyncAPIDoc *AsyncAPIDoc, dataflowGraph *APIDataflowGraph) {
+	m.OperationAliasMap = make(map[string]InternalServiceEndpoint)
+	m.OperationCollisions = make([]*OperationCollision, 0)
+	// operationIds of all docs share the same namespace, as they are all registered in OperationAliasMap.
+	collisionDetector := newOperationCollisionDetector()
+	m.initFromSystemDoc(externalDoc, collisionDetector)
+	m.initFromServiceDoc(internalDoc, collisionDetector)
+	m.initFromSystemAsyncAPIDoc(systemAsyncAPIDoc, collisionDetector)
+	m.initFromServiceAsyncAPIDoc(serviceAsyncAPIDoc, collisionDetector)
+	m.OperationCollisions = append(m.OperationCollisions, collisionDetector.getCollisions()...)
+	for _, collision := range m.OperationCollisions {
+		log.Warn().Msgf("[APIManager.InitFromDocs] Detected %s collision: %s, aliases: %v", collision.Typ, collision.Key, collision.Aliases)
+	}
+	m.initOperationPreconditions()
 
 	// add frontend APIs' info to ServiceAPIMap
-	// We hardcode `frontend` as the frontend 'service'
-	// TODO: make frontend 'service' name configurable @xunzhou24
-	frontendServiceName := "frontend"
 	frontendServiceAPIMap := make(map[SimpleAPIMethod]*openapi3.Operation)
 	maps.Copy(frontendServiceAPIMap, m.APIMap)
 	m.ServiceAPIMap[frontendServiceName] = frontendServiceAPIMap
 
-	// Generate the dataflow graph of the service APIs.
-	m.APIDataflowGraph = NewAPIDataflowGraph()
-	m.APIDataflowGraph.ParseFromServiceDocument(m.ServiceAPIMap)
+	// Generate the dataflow graph of the service APIs, if not given.
+	if dataflowGraph != nil {
+		log.Info().Msgf("[APIManager.InitFromDocs] Use given dataflow graph with %d edges", len(dataflowGraph.Edges))
+		m.APIDataflowGraph = dataflowGraph
+	} else {
+		m.APIDataflowGraph = NewAPIDataflowGraph()
+		m.APIDataflowGraph.ParseFromServiceDocument(m.ServiceAPIMap)
+		m.APIDataflowGraph.ParseFromMessagingChannels(m.ServiceAPIMap)
+	}
 
 	// Compute reachability map from the API doc.
 	// we only check reachability from external APIs to internal APIs
@@ -91,28 +209,201 @@ func (m *APIManager) InitFromDocs(externalDoc, internalDoc *openapi3.T) {
 
 // InitFromDoc initializes the API manager from an OpenAPI document.
 // The document is of interfaces of the whole system.
-func (m *APIManager) initFromSystemDoc(doc *openapi3.T) {
+// Operations of additional targets (see [APIManager.APITargets]) are added as well, keyed by their targets besides routes,
+// so that routes served by the primary target and additional ones are fuzzed on each of them.
+func (m *APIManager) initFromSystemDoc(doc *openapi3.T, collisionDetector *operationCollisionDetector) {
 	m.APIDoc = doc
 	m.APIMap = make(map[SimpleAPIMethod]*openapi3.Operation)
-	for path, pathItem := range doc.Paths.Map() {
-		for method, operation := range pathItem.Operations() {
+	m.UnsupportedOperations = make([]*UnsupportedOperation, 0)
+	m.FilteredOperations = make([]SimpleAPIMethod, 0)
+	m.addSystemDocOperations(doc, "", collisionDetector)
+	for _, target := range m.APITargets {
+		if target.Doc == nil {
+			log.Warn().Msgf("[APIManager.initFromSystemDoc] Doc of API target %s is not parsed, skip it", target.Name)
+			continue
+		}
+		m.addSystemDocOperations(target.Doc, target.Name, collisionDetector)
+	}
+}
+
+// addSystemDocOperations adds operations of the system doc to the API manager.
+// targetName is the name of the additional target of the doc, or empty for the primary one.
+func (m *APIManager) addSystemDocOperations(doc *openapi3.T, targetName string, collisionDetector *operationCollisionDetector) {
+	// Path collisions are checked within the doc only,
+	// since it is common for internal services to expose the same paths as the frontend does.
+	pathCollisionDetector := newOperationCollisionDetector()
+	// Iterate in sorted order, so that aliases of colliding operations are deterministic.
+	for _, path := range slices.Sorted(maps.Keys(doc.Paths.Map())) {
+		operations := doc.Paths.Value(path).Operations()
+		for _, method := range slices.Sorted(maps.Keys(operations)) {
+			operation := operations[method]
 			// By default, the type of the API is HTTP.
+			// gRPC operations (tagged `APIType_gRPC`, see [getAPITypeFromTags]) keep the HTTP path and method in the doc (e.g., transcoded by grpc-gateway),
+			// so that requests can be generated the same way, and the gRPC method is identified by operationId, see [ParseGRPCOperationID].
+			// Without descriptors, they cannot be called natively, and are HTTP ones (e.g., through grpc-gateway).
 			simpleAPIMethod := SimpleAPIMethod{
 				Method:   method,
 				Endpoint: path,
 				Typ:      SimpleAPIMethodTypeHTTP,
+				Target:   targetName,
+			}
+			if apiType, ok := getAPITypeFromTags(operation.Tags); ok && apiType == SimpleAPIMethodTypeGRPC && m.GRPCDescriptors != nil {
+				simpleAPIMethod.Typ = SimpleAPIMethodTypeGRPC
+			}
+			if !m.OperationFilter.IsOperationAllowed(simpleAPIMethod, operation) {
+				log.Info().Msgf("[APIManager.addSystemDocOperations] Skip operation %s %s excluded by operation filter", method, path)
+				m.FilteredOperations = append(m.FilteredOperations, simpleAPIMethod)
+				continue
+			}
+			if unsupportedOperation := checkOperationSupported(doc, simpleAPIMethod, operation); unsupportedOperation != nil && config.GlobalConfig.SkipUnsupportedOperations {
+				log.Info().Msgf("[APIManager.addSystemDocOperations] Skip unsupported operation %s %s, reason: %s, %s", method, path, unsupportedOperation.Reason, unsupportedOperation.Detail)
+				m.UnsupportedOperations = append(m.UnsupportedOperations, unsupportedOperation)
+				continue
 			}
 			m.APIMap[simpleAPIMethod] = operation
+
+			// Only operations actually added are checked for collisions and aliased, as skipped ones are never referred to.
+			endpoint := InternalServiceEndpoint{
+				ServiceName:     frontendServiceName,
+				SimpleAPIMethod: simpleAPIMethod,
+			}
+			pathCollisionDetector.record(OperationCollisionTypePath, fmt.Sprintf("%s %s", method, normalizePathTemplate(path)), endpoint)
+			if operation.OperationID != "" {
+				alias := collisionDetector.record(OperationCollisionTypeOperationID, operation.OperationID, endpoint)
+				m.OperationAliasMap[alias] = endpoint
+			}
+		}
+	}
+	m.OperationCollisions = append(m.OperationCollisions, pathCollisionDetector.getCollisions()...)
+}
+
+// initFromSystemAsyncAPIDoc adds message-producing operations from the AsyncAPI doc of the system,
+// so that test scenarios can include steps of publishing messages, besides calling HTTP APIs.
+// Each channel with publish operation (i.e., the system consumes messages from it) becomes an external API of type [SimpleAPIMethodTypeMessaging],
+// whose request body is the message payload. Nothing is added if doc is nil.
+func (m *APIManager) initFromSystemAsyncAPIDoc(doc *AsyncAPIDoc, collisionDetector *operationCollisionDetector) {
+	m.AsyncAPIDoc = doc
+	m.MessagingChannelMap = make(map[SimpleAPIMethod]*MessagingChannel)
+	if doc == nil {
+		return
+	}
+	for _, channelName := range slices.Sorted(maps.Keys(doc.Channels)) {
+		channel := doc.Channels[channelName]
+		if channel == nil || channel.Publish == nil {
+			continue
+		}
+		simpleAPIMethod := SimpleAPIMethod{
+			Method:   MessagingPublishMethod,
+			Endpoint: channelName,
+			Typ:      SimpleAPIMethodTypeMessaging,
+		}
+		operation := newOperationFromAsyncAPIOperation(channel.Publish)
+		if !m.OperationFilter.IsOperationAllowed(simpleAPIMethod, operation) {
+			log.Info().Msgf("[APIManager.initFromSystemAsyncAPIDoc] Skip channel %s excluded by operation filter", channelName)
+			m.FilteredOperations = append(m.FilteredOperations, simpleAPIMethod)
+			continue
+		}
+		m.APIMap[simpleAPIMethod] = operation
+		m.MessagingChannelMap[simpleAPIMethod] = NewMessagingChannel(channelName, channel)
+		if operation.OperationID != "" {
+			endpoint := InternalServiceEndpoint{
+				ServiceName:     frontendServiceName,
+				SimpleAPIMethod: simpleAPIMethod,
+			}
+			alias := collisionDetector.record(OperationCollisionTypeOperationID, operation.OperationID, endpoint)
+			m.OperationAliasMap[alias] = endpoint
+		}
+		log.Debug().Msgf("[APIManager.initFromSystemAsyncAPIDoc] Add message-producing operation of channel %s", channelName)
+	}
+	log.Info().Msgf("[APIManager.initFromSystemAsyncAPIDoc] Added %d message-producing operations", len(m.MessagingChannelMap))
+}
+
+// initFromServiceAsyncAPIDoc adds message-driven endpoints of internal services from their AsyncAPI doc to ServiceAPIMap.
+// Like OpenAPI docs of internal services, operationId of each operation is in the format of `{Service}_{Method}`, telling the service it belongs to.
+// From the perspective of the service, a subscribe operation publishes messages to the channel (method [MessagingPublishMethod]),
+// and a publish operation consumes messages from the channel (method [MessagingConsumeMethod]); the endpoint is the channel name in both cases.
+// Nothing is added if doc is nil.
+func (m *APIManager) initFromServiceAsyncAPIDoc(doc *AsyncAPIDoc, collisionDetector *operationCollisionDetector) {
+	m.InternalServiceAsyncAPIDoc = doc
+	if doc == nil {
+		return
+	}
+	endpointCount := 0
+	for _, channelName := range slices.Sorted(maps.Keys(doc.Channels)) {
+		channel := doc.Channels[channelName]
+		if channel == nil {
+			continue
+		}
+		for _, methodOperation := range []struct {
+			method    string
+			operation *AsyncAPIOperation
+		}{
+			{MessagingPublishMethod, channel.Subscribe},
+			{MessagingConsumeMethod, channel.Publish},
+		} {
+			method, asyncAPIOperation := methodOperation.method, methodOperation.operation
+			if asyncAPIOperation == nil {
+				continue
+			}
+			serviceName, _, ok := ParseGRPCOperationID(asyncAPIOperation.OperationID)
+			if !ok {
+				log.Warn().Msgf("[APIManager.initFromServiceAsyncAPIDoc] Invalid operationID of channel %s: %s", channelName, asyncAPIOperation.OperationID)
+				continue
+			}
+			simpleAPIMethod := SimpleAPIMethod{
+				Method:   method,
+				Endpoint: channelName,
+				Typ:      SimpleAPIMethodTypeMessaging,
+			}
+			endpoint := InternalServiceEndpoint{
+				ServiceName:     serviceName,
+				SimpleAPIMethod: simpleAPIMethod,
+			}
+			alias := collisionDetector.record(OperationCollisionTypeOperationID, asyncAPIOperation.OperationID, endpoint)
+			m.OperationAliasMap[alias] = endpoint
+			if _, exists := m.ServiceAPIMap[serviceName]; !exists {
+				m.ServiceAPIMap[serviceName] = make(map[SimpleAPIMethod]*openapi3.Operation)
+			}
+			operation := newOperationFromAsyncAPIOperation(asyncAPIOperation)
+			// Messages of internal services are not published by the fuzzer, so there is no response of the broker.
+			operation.Responses = nil
+			m.ServiceAPIMap[serviceName][simpleAPIMethod] = operation
+			endpointCount++
+		}
+	}
+	log.Info().Msgf("[APIManager.initFromServiceAsyncAPIDoc] Added %d message-driven endpoints of internal services", endpointCount)
+}
+
+// newOperationFromAsyncAPIOperation converts an operation of AsyncAPI doc to an OpenAPI operation,
+// whose request body is the message payload, and response is that of the message broker accepting the message.
+// If the message declares no payload schema, the request body declares no schema either, and best-effort payloads would be generated.
+func newOperationFromAsyncAPIOperation(asyncAPIOperation *AsyncAPIOperation) *openapi3.Operation {
+	operation := openapi3.NewOperation()
+	operation.OperationID = asyncAPIOperation.OperationID
+	operation.Summary = asyncAPIOperation.Summary
+	operation.Description = asyncAPIOperation.Description
+	operation.Responses = openapi3.NewResponses(
+		openapi3.WithStatus(200, &openapi3.ResponseRef{Value: openapi3.NewResponse().WithDescription("Message accepted by the broker")}),
+	)
+	if asyncAPIOperation.Message != nil {
+		operation.RequestBody = &openapi3.RequestBodyRef{
+			Value: openapi3.NewRequestBody().WithRequired(true).WithJSONSchemaRef(asyncAPIOperation.Message.Payload),
 		}
 	}
+	return operation
 }
 
 // initFromServiceDocs initializes the API manager from the OpenAPI document of the internal services.
-func (m *APIManager) initFromServiceDoc(doc *openapi3.T) {
+// Operations are visited in sorted order of path and method. If an operationId is declared more than once, later operations are registered in OperationAliasMap
+// with its alias (prefixed by service name), instead of silently overwriting the former one. Their API methods are kept, as they are identified by service name as well.
+// HTTP paths are not checked for collisions, as it is common for internal services to expose the same paths, separated by service name.
+func (m *APIManager) initFromServiceDoc(doc *openapi3.T, collisionDetector *operationCollisionDetector) {
 	m.InternalServiceAPIDoc = doc
 	m.ServiceAPIMap = make(map[string]map[SimpleAPIMethod]*openapi3.Operation)
-	for httpPath, pathItem := range doc.Paths.Map() {
-		for httpMethod, operation := range pathItem.Operations() {
+	for _, httpPath := range slices.Sorted(maps.Keys(doc.Paths.Map())) {
+		operations := doc.Paths.Value(httpPath).Operations()
+		for _, httpMethod := range slices.Sorted(maps.Keys(operations)) {
+			operation := operations[httpMethod]
 			// In OpenAPI generated by protoc-gen-openapi, operationID is in the format of `{Service}_{Method}`.
 			// We can use this format to extract the service name and method name.
 			operationID := operation.OperationID
@@ -158,6 +449,16 @@ func (m *APIManager) initFromServiceDoc(doc *openapi3.T) {
 				}
 			}
 
+			// Operations of unsupported API types are not aliased, as they are never referred to.
+			if simpleMethod.Typ != "" {
+				endpoint := InternalServiceEndpoint{
+					ServiceName:     serviceName,
+					SimpleAPIMethod: simpleMethod,
+				}
+				alias := collisionDetector.record(OperationCollisionTypeOperationID, operationID, endpoint)
+				m.OperationAliasMap[alias] = endpoint
+			}
+
 			if _, exists := m.ServiceAPIMap[serviceName]; !exists {
 				m.ServiceAPIMap[serviceName] = make(map[SimpleAPIMethod]*openapi3.Operation)
 			}
@@ -166,13 +467,42 @@ func (m *APIManager) initFromServiceDoc(doc *openapi3.T) {
 	}
 }
 
+// ParseGRPCOperationID parses the gRPC service name and method name from the operationId of a gRPC operation.
+// In OpenAPI generated by protoc-gen-openapi, operationID is in the format of `{Service}_{Method}`.
+// It returns false if the operationId is not in the format.
+func ParseGRPCOperationID(operationID string) (serviceName, methodName string, ok bool) {
+	operationIDParts := strings.Split(operationID, "_")
+	if len(operationIDParts) != 2 || operationIDParts[0] == "" || operationIDParts[1] == "" {
+		return "", "", false
+	}
+	return operationIDParts[0], operationIDParts[1], true
+}
+
+// getAPITypeFromTags returns the API type tagged in the format of `APIType_{APIType}`.
+// It returns false if no valid API type (HTTP or gRPC) is tagged.
+func getAPITypeFromTags(tags []string) (SimpleAPIMethodType, bool) {
+	for _, tag := range tags {
+		apiType, found := strings.CutPrefix(tag, "APIType_")
+		if !found {
+			continue
+		}
+		switch apiType {
+		case string(SimpleAPIMethodTypeHTTP):
+			return SimpleAPIMethodTypeHTTP, true
+		case string(SimpleAPIMethodTypeGRPC):
+			return SimpleAPIMethodTypeGRPC, true
+		}
+	}
+	return "", false
+}
+
 // InitDependencyGraph initializes the dependency graph of the API manager.
 // It accepts the graph of the external API dependency and the internal API dependency (a map from service to corresponding dependency graph).
 // If config `use-internal-service-api-dependency` is set to true, the internal API dependency will be used to enhance the fuzzing.
 // Otherwise, `internalAPIDependencyGraph` will be ignored (you can pass any value in such case).
 func (m *APIManager) InitDependencyGraph(externalAPIDependencyGraph *APIDependencyGraph, internalAPIDependencyGraphMap map[string]*APIDependencyGraph) {
-	// Just copy the external API dependency graph to the API manager.
-	m.APIDependencyGraph = externalAPIDependencyGraph
+	// Dependency files identify system APIs by method and path, assuming they are HTTP ones, so gRPC ones are resolved by them.
+	m.APIDependencyGraph = m.resolveDependencyGraphAPIMethods(externalAPIDependencyGraph)
 
 	// If the config `use-internal-service-api-dependency` is set to true, we will use the internal API dependency graph to enhance the fuzzing.
 	if config.GlobalConfig.UseInternalServiceAPIDependency {
@@ -190,6 +520,44 @@ func (m *APIManager) InitDependencyGraph(externalAPIDependencyGraph *APIDependen
 	}
 }
 
+// ResolveSystemAPIMethod returns the system API method of the HTTP method and path in the system doc, which is of type gRPC if it is executed natively (see [APIManager.GRPCDescriptors]).
+// It returns the HTTP API method if there is no such gRPC one, even if it is not in APIMap.
+func (m *APIManager) ResolveSystemAPIMethod(method, path string) SimpleAPIMethod {
+	grpcMethod := SimpleAPIMethod{Method: method, Endpoint: path, Typ: SimpleAPIMethodTypeGRPC}
+	if _, exists := m.APIMap[grpcMethod]; exists {
+		return grpcMethod
+	}
+	return SimpleAPIMethod{Method: method, Endpoint: path, Typ: SimpleAPIMethodTypeHTTP}
+}
+
+// resolveDependencyGraphAPIMethods returns the dependency graph with HTTP API methods resolved by [APIManager.ResolveSystemAPIMethod].
+// The graph is returned as is if it is nil, or no system API is of type gRPC.
+func (m *APIManager) resolveDependencyGraphAPIMethods(graph *APIDependencyGraph) *APIDependencyGraph {
+	if graph == nil || m.GRPCDescriptors == nil {
+		return graph
+	}
+	resolve := func(method SimpleAPIMethod) SimpleAPIMethod {
+		if method.Typ != SimpleAPIMethodTypeHTTP {
+			return method
+		}
+		return m.ResolveSystemAPIMethod(method.Method, method.Endpoint)
+	}
+	resolvedGraph := NewAPIDependencyGraph()
+	for producer, consumers := range graph.Graph {
+		for _, consumer := range consumers {
+			resolvedGraph.AddDependency(resolve(producer), resolve(consumer))
+		}
+	}
+	for producer, consumerBindings := range graph.Bindings {
+		for consumer, bindings := range consumerBindings {
+			for _, binding := range bindings {
+				resolvedGraph.AddBinding(resolve(producer), resolve(consumer), binding)
+			}
+		}
+	}
+	return resolvedGraph
+}
+
 // GetOperationByMethod returns the OpenAPI operation of the given API method.
 func (m *APIManager) GetOperationByMethod(method SimpleAPIMethod) (*openapi3.Operation, bool) {
 	if operation, ok := m.APIMap[method]; ok {
@@ -198,15 +566,91 @@ func (m *APIManager) GetOperationByMethod(method SimpleAPIMethod) (*openapi3.Ope
 	return nil, false
 }
 
+// MatchHTTPRequest returns the API method (except message-producing ones) serving a concrete HTTP request, e.g., `GET /pets/{petId}` for `GET /pets/42`,
+// and the path parameters of the request, see [utils.MatchEndpointPath]. The method is case-insensitive.
+// If multiple endpoints match, the one with most literal segments is returned, e.g., `/pets/mine` is preferred to `/pets/{petId}`.
+// It returns false if no API method matches.
+func (m *APIManager) MatchHTTPRequest(method, requestPath string) (SimpleAPIMethod, map[string]string, bool) {
+	method = strings.ToUpper(method)
+	var (
+		matchedMethod     SimpleAPIMethod
+		matchedPathParams map[string]string
+		found             bool
+	)
+	for _, apiMethod := range slices.SortedFunc(maps.Keys(m.APIMap), CompareSimpleAPIMethod) {
+		if apiMethod.Method != method || apiMethod.Typ == SimpleAPIMethodTypeMessaging {
+			continue
+		}
+		pathParams, ok := utils.MatchEndpointPath(apiMethod.Endpoint, requestPath)
+		if !ok {
+			continue
+		}
+		if !found || len(pathParams) < len(matchedPathParams) {
+			matchedMethod, matchedPathParams, found = apiMethod, pathParams, true
+		}
+	}
+	return matchedMethod, matchedPathParams, found
+}
+
+// ResolveOperationAlias returns the endpoint of the given operationId or alias.
+// Aliases are assigned to operations whose operationIds collide, see [OperationCollision].
+func (m *APIManager) ResolveOperationAlias(alias string) (InternalServiceEndpoint, bool) {
+	endpoint, ok := m.OperationAliasMap[alias]
+	return endpoint, ok
+}
+
 // GetRandomAPIMethod returns a random API method from the API manager.
-func (m *APIManager) GetRandomAPIMethod() SimpleAPIMethod {
-	// Golang map iteration order is random.
-	// See [official doc](https://go.dev/blog/maps#iteration-order).
-	for method := range m.APIMap {
-		return method
+// Methods in excluded (e.g., operations already in the scenario, or retired endpoints) are not selected, unless all methods are excluded.
+// If weights is not nil, a method is selected with a possibility of its weight / sum(weights of candidates).
+// Methods absent from weights have weight 1, and methods with non-positive weights are not selected.
+// Both excluded and weights can be nil.
+func (m *APIManager) GetRandomAPIMethod(excluded map[SimpleAPIMethod]struct{}, weights map[SimpleAPIMethod]int) SimpleAPIMethod {
+	if len(m.APIMap) == 0 {
+		log.Warn().Msg("[APIManager.GetRandomAPIMethod] APIMap is empty, return empty method")
+		return SimpleAPIMethod{}
+	}
+	// Sort the methods, so that the selection only depends on the random source, not on map iteration order.
+	allMethods := slices.SortedFunc(maps.Keys(m.APIMap), CompareSimpleAPIMethod)
+	candidates := make([]SimpleAPIMethod, 0, len(allMethods))
+	for _, method := range allMethods {
+		if _, exist := excluded[method]; !exist {
+			candidates = append(candidates, method)
+		}
+	}
+	if len(candidates) == 0 {
+		log.Warn().Msg("[APIManager.GetRandomAPIMethod] All API methods are excluded, select from all API methods instead")
+		candidates = allMethods
+	}
+	if weights == nil {
+		return candidates[utils.SharedRand.IntN(len(candidates))]
+	}
+
+	totalWeight := 0
+	for _, method := range candidates {
+		totalWeight += getAPIMethodWeight(method, weights)
 	}
-	log.Warn().Msg("[APIManager.GetRandomAPIMethod] APIMap is empty, return empty method")
-	return SimpleAPIMethod{}
+	if totalWeight <= 0 {
+		log.Warn().Msg("[APIManager.GetRandomAPIMethod] All candidate API methods have non-positive weights, select uniformly instead")
+		return candidates[utils.SharedRand.IntN(len(candidates))]
+	}
+	randomWeight := utils.SharedRand.IntN(totalWeight)
+	for _, method := range candidates {
+		randomWeight -= getAPIMethodWeight(method, weights)
+		if randomWeight < 0 {
+			return method
+		}
+	}
+	// Should not reach here.
+	return candidates[len(candidates)-1]
+}
+
+// getAPIMethodWeight returns the weight of the API method in weights, 1 if absent, and 0 if non-positive.
+func getAPIMethodWeight(method SimpleAPIMethod, weights map[SimpleAPIMethod]int) int {
+	weight, exist := weights[method]
+	if !exist {
+		return 1
+	}
+	return max(weight, 0)
 }
 
 // GetConsumerAPIMethodsByProducersForSystem returns the consumer API methods of the given producer API methods.
@@ -219,6 +663,10 @@ func (m *APIManager) GetConsumerAPIMethodsByProducersForSystem(producers []Simpl
 			res = append(res, consumers...)
 		}
 	}
+	// Dependency files may contain operations excluded by the operation filter.
+	res = slices.DeleteFunc(res, func(consumer SimpleAPIMethod) bool {
+		return slices.Contains(m.FilteredOperations, consumer)
+	})
 	// sort and remove duplicates
 	slices.SortFunc(res, func(a, b SimpleAPIMethod) int {
 		return CompareSimpleAPIMethod(a, b)
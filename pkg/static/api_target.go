@@ -0,0 +1,53 @@
+package static
+
+import (
+	"fmt"
+
+	"github.com/bytedance/sonic"
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+// APITarget is an additional externally exposed gateway of the system, besides the primary one (config `openapi-spec` and `server-base-url`).
+// Operations of its doc are fuzzed in the same campaign, and requests of them are sent to its server base URL.
+type APITarget struct {
+	// Name is the unique name of the target. It defaults to ServerBaseURL if not given.
+	Name string `json:"name"`
+
+	// OpenAPISpecPath is the path to the OpenAPI spec of the target.
+	OpenAPISpecPath string `json:"openAPISpec"`
+
+	// ServerBaseURL is the base URL of the target.
+	ServerBaseURL string `json:"serverBaseURL"`
+
+	// Doc is the parsed OpenAPI doc of the target, set before initializing [APIManager], see [APIManager.SetAPITargets].
+	Doc *openapi3.T `json:"-"`
+}
+
+// ParseAPITargets parses additional API targets from a stringified JSON list, e.g., config `additional-targets`.
+// It returns an empty list if the string is empty.
+func ParseAPITargets(targetsJSON string) ([]*APITarget, error) {
+	targets := make([]*APITarget, 0)
+	if targetsJSON == "" {
+		return targets, nil
+	}
+	if err := sonic.UnmarshalString(targetsJSON, &targets); err != nil {
+		return nil, fmt.Errorf("failed to parse API targets: %w", err)
+	}
+	names := make(map[string]struct{})
+	for i, target := range targets {
+		if target == nil {
+			return nil, fmt.Errorf("API target %d is null", i)
+		}
+		if target.OpenAPISpecPath == "" || target.ServerBaseURL == "" {
+			return nil, fmt.Errorf("API target %d requires both openAPISpec and serverBaseURL", i)
+		}
+		if target.Name == "" {
+			target.Name = target.ServerBaseURL
+		}
+		if _, exists := names[target.Name]; exists {
+			return nil, fmt.Errorf("duplicate API target name: %s", target.Name)
+		}
+		names[target.Name] = struct{}{}
+	}
+	return targets, nil
+}
@@ -0,0 +1,271 @@
+package static
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+const (
+	// asyncAPISchemaRefPrefix is the prefix of references to schemas in components of AsyncAPI doc.
+	asyncAPISchemaRefPrefix = "#/components/schemas/"
+
+	// asyncAPIMessageRefPrefix is the prefix of references to messages in components of AsyncAPI doc.
+	asyncAPIMessageRefPrefix = "#/components/messages/"
+)
+
+// AsyncAPIDoc is an AsyncAPI (2.x) document, which describes channels of a message-driven system,
+// see [AsyncAPI specification](https://www.asyncapi.com/docs/reference/specification/v2.6.0).
+// Only parts used by the fuzzer are modeled, and payload schemas are parsed as OpenAPI schemas,
+// so that values can be generated the same way as those of HTTP requests.
+type AsyncAPIDoc struct {
+	// AsyncAPI is the version of AsyncAPI specification, e.g., `2.6.0`.
+	AsyncAPI string `json:"asyncapi"`
+
+	// Channels maps from channel names to their definitions.
+	Channels map[string]*AsyncAPIChannel `json:"channels"`
+
+	// Components holds reusable schemas and messages.
+	Components *AsyncAPIComponents `json:"components,omitempty"`
+}
+
+// AsyncAPIChannel is a channel (e.g., a Kafka topic, or an AMQP queue or exchange) in AsyncAPI doc.
+type AsyncAPIChannel struct {
+	// Description is the description of the channel.
+	Description string `json:"description,omitempty"`
+
+	// Publish is the operation of clients publishing messages to the channel, i.e., the application consumes them.
+	// Channels with publish operation are where the fuzzer can send messages to.
+	Publish *AsyncAPIOperation `json:"publish,omitempty"`
+
+	// Subscribe is the operation of clients subscribing to messages of the channel, i.e., the application produces them.
+	Subscribe *AsyncAPIOperation `json:"subscribe,omitempty"`
+
+	// Bindings are protocol-specific definitions of the channel.
+	Bindings *AsyncAPIChannelBindings `json:"bindings,omitempty"`
+}
+
+// AsyncAPIChannelBindings are protocol-specific definitions of a channel.
+type AsyncAPIChannelBindings struct {
+	// Kafka is the Kafka binding of the channel.
+	Kafka *AsyncAPIKafkaChannelBinding `json:"kafka,omitempty"`
+
+	// AMQP is the AMQP 0-9-1 binding of the channel.
+	AMQP *AsyncAPIAMQPChannelBinding `json:"amqp,omitempty"`
+}
+
+// AsyncAPIKafkaChannelBinding is the Kafka binding of a channel.
+type AsyncAPIKafkaChannelBinding struct {
+	// Topic is the Kafka topic name, if different from the channel name.
+	Topic string `json:"topic,omitempty"`
+}
+
+// AsyncAPIAMQPChannelBinding is the AMQP 0-9-1 binding of a channel.
+type AsyncAPIAMQPChannelBinding struct {
+	// Is is what the channel is, `routingKey` (published to an exchange) or `queue`.
+	Is string `json:"is,omitempty"`
+
+	// Exchange is the exchange messages are published to, if the channel is a routing key.
+	Exchange *AsyncAPIAMQPDestination `json:"exchange,omitempty"`
+
+	// Queue is the queue of the channel, if the channel is a queue.
+	Queue *AsyncAPIAMQPDestination `json:"queue,omitempty"`
+}
+
+// AsyncAPIAMQPDestination is an AMQP exchange or queue.
+type AsyncAPIAMQPDestination struct {
+	// Name is the name of the exchange or queue.
+	Name string `json:"name,omitempty"`
+
+	// VirtualHost is the virtual host of the exchange or queue. By default, it is `/`.
+	VirtualHost string `json:"vhost,omitempty"`
+}
+
+// AsyncAPIOperation is a publish or subscribe operation of a channel.
+type AsyncAPIOperation struct {
+	// OperationID is the unique identifier of the operation.
+	OperationID string `json:"operationId,omitempty"`
+
+	// Summary is the short summary of the operation.
+	Summary string `json:"summary,omitempty"`
+
+	// Description is the description of the operation.
+	Description string `json:"description,omitempty"`
+
+	// Message is the message published or subscribed.
+	Message *AsyncAPIMessage `json:"message,omitempty"`
+
+	// Bindings are protocol-specific definitions of the operation.
+	Bindings *AsyncAPIOperationBindings `json:"bindings,omitempty"`
+}
+
+// AsyncAPIOperationBindings are protocol-specific definitions of an operation.
+type AsyncAPIOperationBindings struct {
+	// AMQP is the AMQP 0-9-1 binding of the operation.
+	AMQP *AsyncAPIAMQPOperationBinding `json:"amqp,omitempty"`
+}
+
+// AsyncAPIAMQPOperationBinding is the AMQP 0-9-1 binding of an operation.
+type AsyncAPIAMQPOperationBinding struct {
+	// CC are routing keys of messages. The first one is used when publishing.
+	CC []string `json:"cc,omitempty"`
+}
+
+// AsyncAPIMessage is a message in AsyncAPI doc.
+type AsyncAPIMessage struct {
+	// Ref is the reference to a message in components, e.g., `#/components/messages/UserSignedUp`.
+	Ref string `json:"$ref,omitempty"`
+
+	// Name is the name of the message.
+	Name string `json:"name,omitempty"`
+
+	// ContentType is the content type of the payload. Only JSON payloads are supported.
+	ContentType string `json:"contentType,omitempty"`
+
+	// Payload is the schema of the payload.
+	Payload *openapi3.SchemaRef `json:"payload,omitempty"`
+}
+
+// AsyncAPIComponents holds reusable objects of AsyncAPI doc.
+type AsyncAPIComponents struct {
+	// Schemas maps from names to reusable schemas.
+	Schemas map[string]*openapi3.SchemaRef `json:"schemas,omitempty"`
+
+	// Messages maps from names to reusable messages.
+	Messages map[string]*AsyncAPIMessage `json:"messages,omitempty"`
+}
+
+// MessagingChannel is where messages of a message-producing operation are published to, parsed from channel bindings of AsyncAPI doc.
+type MessagingChannel struct {
+	// Name is the name of the channel.
+	Name string `json:"name"`
+
+	// KafkaTopic is the Kafka topic. By default, it is the channel name.
+	KafkaTopic string `json:"kafkaTopic"`
+
+	// AMQPExchange is the AMQP exchange. It is empty if messages are published to the default exchange.
+	AMQPExchange string `json:"amqpExchange,omitempty"`
+
+	// AMQPRoutingKey is the AMQP routing key. By default, it is the channel name.
+	AMQPRoutingKey string `json:"amqpRoutingKey"`
+
+	// AMQPVirtualHost is the AMQP virtual host. By default, it is `/`.
+	AMQPVirtualHost string `json:"amqpVirtualHost"`
+}
+
+// NewMessagingChannel creates a new MessagingChannel from a channel (and its publish operation) of AsyncAPI doc.
+func NewMessagingChannel(name string, channel *AsyncAPIChannel) *MessagingChannel {
+	messagingChannel := &MessagingChannel{
+		Name:            name,
+		KafkaTopic:      name,
+		AMQPRoutingKey:  name,
+		AMQPVirtualHost: "/",
+	}
+	if channel.Bindings != nil && channel.Bindings.Kafka != nil && channel.Bindings.Kafka.Topic != "" {
+		messagingChannel.KafkaTopic = channel.Bindings.Kafka.Topic
+	}
+	if channel.Bindings != nil && channel.Bindings.AMQP != nil {
+		amqpBinding := channel.Bindings.AMQP
+		destination := amqpBinding.Queue
+		if amqpBinding.Is != "queue" && amqpBinding.Exchange != nil {
+			destination = amqpBinding.Exchange
+			messagingChannel.AMQPExchange = amqpBinding.Exchange.Name
+		} else if amqpBinding.Queue != nil && amqpBinding.Queue.Name != "" {
+			// Messages reach the queue through the default exchange, with the queue name as routing key.
+			messagingChannel.AMQPRoutingKey = amqpBinding.Queue.Name
+		}
+		if destination != nil && destination.VirtualHost != "" {
+			messagingChannel.AMQPVirtualHost = destination.VirtualHost
+		}
+	}
+	if channel.Publish != nil && channel.Publish.Bindings != nil && channel.Publish.Bindings.AMQP != nil && len(channel.Publish.Bindings.AMQP.CC) > 0 {
+		messagingChannel.AMQPRoutingKey = channel.Publish.Bindings.AMQP.CC[0]
+	}
+	return messagingChannel
+}
+
+// ResolveRefs resolves references to messages and schemas in components of the doc, in place.
+// Only local references (i.e., `#/components/...`) are supported.
+func (d *AsyncAPIDoc) ResolveRefs() error {
+	components := d.Components
+	if components == nil {
+		components = &AsyncAPIComponents{}
+	}
+	resolvedSchemas := make(map[*openapi3.SchemaRef]struct{})
+	for _, schemaRef := range components.Schemas {
+		if err := resolveAsyncAPISchemaRef(schemaRef, components.Schemas, resolvedSchemas); err != nil {
+			return err
+		}
+	}
+	for _, message := range components.Messages {
+		if message != nil {
+			if err := resolveAsyncAPISchemaRef(message.Payload, components.Schemas, resolvedSchemas); err != nil {
+				return err
+			}
+		}
+	}
+	for channelName, channel := range d.Channels {
+		if channel == nil {
+			continue
+		}
+		for _, operation := range []*AsyncAPIOperation{channel.Publish, channel.Subscribe} {
+			if operation == nil || operation.Message == nil {
+				continue
+			}
+			if operation.Message.Ref != "" {
+				messageName, found := strings.CutPrefix(operation.Message.Ref, asyncAPIMessageRefPrefix)
+				message, exists := components.Messages[messageName]
+				if !found || !exists || message == nil {
+					return fmt.Errorf("unresolved message reference %s in channel %s", operation.Message.Ref, channelName)
+				}
+				operation.Message = message
+			}
+			if err := resolveAsyncAPISchemaRef(operation.Message.Payload, components.Schemas, resolvedSchemas); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// resolveAsyncAPISchemaRef resolves references in the schema (and its sub-schemas) to schemas in components, in place.
+// Resolved schemas are recorded, so that recursive schemas are visited only once.
+func resolveAsyncAPISchemaRef(schemaRef *openapi3.SchemaRef, schemas map[string]*openapi3.SchemaRef, resolvedSchemas map[*openapi3.SchemaRef]struct{}) error {
+	if schemaRef == nil {
+		return nil
+	}
+	if _, resolved := resolvedSchemas[schemaRef]; resolved {
+		return nil
+	}
+	resolvedSchemas[schemaRef] = struct{}{}
+	if schemaRef.Value == nil && schemaRef.Ref != "" {
+		schemaName, found := strings.CutPrefix(schemaRef.Ref, asyncAPISchemaRefPrefix)
+		target, exists := schemas[schemaName]
+		if !found || !exists || target == nil {
+			return fmt.Errorf("unresolved schema reference %s", schemaRef.Ref)
+		}
+		if err := resolveAsyncAPISchemaRef(target, schemas, resolvedSchemas); err != nil {
+			return err
+		}
+		schemaRef.Value = target.Value
+		return nil
+	}
+	schema := schemaRef.Value
+	if schema == nil {
+		return nil
+	}
+	subSchemaRefs := []*openapi3.SchemaRef{schema.Items, schema.Not, schema.AdditionalProperties.Schema}
+	for _, propertySchemaRef := range schema.Properties {
+		subSchemaRefs = append(subSchemaRefs, propertySchemaRef)
+	}
+	for _, schemaRefs := range []openapi3.SchemaRefs{schema.AllOf, schema.AnyOf, schema.OneOf} {
+		subSchemaRefs = append(subSchemaRefs, schemaRefs...)
+	}
+	for _, subSchemaRef := range subSchemaRefs {
+		if err := resolveAsyncAPISchemaRef(subSchemaRef, schemas, resolvedSchemas); err != nil {
+			return err
+		}
+	}
+	return nil
+}
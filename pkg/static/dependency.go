@@ -1,16 +1,32 @@
 package static
 
+import "slices"
+
 // APIDependencyGraph represents the dependencies between different APIs.
 // It is a map from an API method to a list of API methods that depend on it, i.e., mapping from producer to consumer.
 // The graph is mainly used to choose a consumer API method when extending a test scenario (a request sequence).
 type APIDependencyGraph struct {
 	Graph map[SimpleAPIMethod][]SimpleAPIMethod
+
+	// Bindings maps from a producer to its consumer to the bindings between their properties, if known, see [APIDependencyBinding].
+	Bindings map[SimpleAPIMethod]map[SimpleAPIMethod][]APIDependencyBinding
+}
+
+// APIDependencyBinding binds a property in the success response of a producer to a parameter of its consumer,
+// e.g., `id` of `POST /api/products` to `productId` of `GET /api/products/{productId}`.
+type APIDependencyBinding struct {
+	// SourceProperty is the property in the response of the producer.
+	SourceProperty SimpleAPIProperty `json:"sourceProperty"`
+
+	// TargetProperty is the path, query or request body parameter of the consumer.
+	TargetProperty SimpleAPIProperty `json:"targetProperty"`
 }
 
 // NewAPIDependencyGraph creates a new APIDependencyGraph.
 func NewAPIDependencyGraph() *APIDependencyGraph {
 	return &APIDependencyGraph{
-		Graph: make(map[SimpleAPIMethod][]SimpleAPIMethod),
+		Graph:    make(map[SimpleAPIMethod][]SimpleAPIMethod),
+		Bindings: make(map[SimpleAPIMethod]map[SimpleAPIMethod][]APIDependencyBinding),
 	}
 }
 
@@ -21,3 +37,20 @@ func (g *APIDependencyGraph) AddDependency(producer, consumer SimpleAPIMethod) {
 	}
 	g.Graph[producer] = append(g.Graph[producer], consumer)
 }
+
+// AddBinding adds a binding between properties of a producer API method and a consumer API method.
+// Duplicated bindings are ignored.
+func (g *APIDependencyGraph) AddBinding(producer, consumer SimpleAPIMethod, binding APIDependencyBinding) {
+	if _, ok := g.Bindings[producer]; !ok {
+		g.Bindings[producer] = make(map[SimpleAPIMethod][]APIDependencyBinding)
+	}
+	if slices.Contains(g.Bindings[producer][consumer], binding) {
+		return
+	}
+	g.Bindings[producer][consumer] = append(g.Bindings[producer][consumer], binding)
+}
+
+// GetBindings returns the bindings between properties of a producer API method and a consumer API method, or nil if there is none.
+func (g *APIDependencyGraph) GetBindings(producer, consumer SimpleAPIMethod) []APIDependencyBinding {
+	return g.Bindings[producer][consumer]
+}
@@ -0,0 +1,180 @@
+package static
+
+import (
+	"maps"
+	"resttracefuzzer/pkg/utils"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/cloudwego/hertz/pkg/protocol/consts"
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/rs/zerolog/log"
+)
+
+// APIDependencyInferencer infers the system-level producer-consumer API dependency graph from the OpenAPI doc of the system,
+// as a built-in replacement for dependency files generated by other tools (e.g., Restler).
+// An API method (consumer) depends on another one (producer) if a property in the success response of the producer
+// matches a path, query or request body parameter of the consumer, by [utils.MatchVariableNames].
+// Matched properties are recorded as bindings of the dependency, see [APIDependencyBinding].
+type APIDependencyInferencer struct {
+	// SimilarityCalculator is used to calculate the similarity between words of property names.
+	SimilarityCalculator utils.SimilarityCalculator
+
+	// Threshold is the threshold above or equal to which the average similarity of property names is considered a match.
+	Threshold float64
+}
+
+// NewAPIDependencyInferencer creates a new APIDependencyInferencer.
+// It uses LevenshteinSimilarityCalculator with threshold 0.75, the same as the dataflow graph of internal services.
+func NewAPIDependencyInferencer() *APIDependencyInferencer {
+	return &APIDependencyInferencer{
+		SimilarityCalculator: utils.NewLevenshteinSimilarityCalculator(),
+		Threshold:            0.75,
+	}
+}
+
+// InferFromAPIMap infers the API dependency graph from the given map from the API method to the OpenAPI operation.
+func (i *APIDependencyInferencer) InferFromAPIMap(apiMap map[SimpleAPIMethod]*openapi3.Operation) *APIDependencyGraph {
+	dependencyGraph := NewAPIDependencyGraph()
+	// Sort the methods, so that the consumers of each producer are in a stable order.
+	methods := slices.SortedFunc(maps.Keys(apiMap), CompareSimpleAPIMethod)
+
+	producerPropertiesMap := make(map[SimpleAPIMethod][]inferenceProducerProperty)
+	consumerPropertiesMap := make(map[SimpleAPIMethod][]SimpleAPIProperty)
+	for _, method := range methods {
+		operation := apiMap[method]
+		if operation == nil {
+			continue
+		}
+		producerPropertiesMap[method] = getInferenceProducerProperties(method, operation)
+		consumerPropertiesMap[method] = getInferenceConsumerProperties(operation)
+	}
+
+	edgeCount := 0
+	for _, producer := range methods {
+		for _, consumer := range methods {
+			if producer == consumer {
+				continue
+			}
+			bindings := i.matchProperties(producerPropertiesMap[producer], consumerPropertiesMap[consumer])
+			if len(bindings) == 0 {
+				continue
+			}
+			log.Trace().Msgf("[APIDependencyInferencer.InferFromAPIMap] Adding dependency from %v to %v", producer, consumer)
+			dependencyGraph.AddDependency(producer, consumer)
+			for _, binding := range bindings {
+				dependencyGraph.AddBinding(producer, consumer, binding)
+			}
+			edgeCount++
+		}
+	}
+	log.Info().Msgf("[APIDependencyInferencer.InferFromAPIMap] Inferred %d API dependencies from %d API methods", edgeCount, len(methods))
+	return dependencyGraph
+}
+
+// matchProperties returns the bindings between producer properties and consumer properties matching each other.
+// Each consumer property is bound to the first producer property matching it only.
+func (i *APIDependencyInferencer) matchProperties(producerProperties []inferenceProducerProperty, consumerProperties []SimpleAPIProperty) []APIDependencyBinding {
+	var bindings []APIDependencyBinding
+	for _, consumerProp := range consumerProperties {
+		for _, producerProp := range producerProperties {
+			if utils.MatchVariableNames(producerProp.matchName, consumerProp.Name, i.SimilarityCalculator, i.Threshold) {
+				bindings = append(bindings, APIDependencyBinding{
+					SourceProperty: producerProp.property,
+					TargetProperty: consumerProp,
+				})
+				break
+			}
+		}
+	}
+	return bindings
+}
+
+// inferenceProducerProperty is a property in the success response of a producer.
+type inferenceProducerProperty struct {
+	// property is the property in the response.
+	property SimpleAPIProperty
+
+	// matchName is the name matched against consumer properties, which may be qualified, see [getInferenceProducerProperties].
+	matchName string
+}
+
+// getInferenceProducerProperties returns the properties in the success (200, 201 or 202) response of the operation.
+// Common field names (e.g., id) are qualified by the resource name of the endpoint, e.g., `id` of `/api/products` is also named `productId`,
+// as they are ignored by [utils.MatchVariableNames] otherwise.
+func getInferenceProducerProperties(method SimpleAPIMethod, operation *openapi3.Operation) []inferenceProducerProperty {
+	if operation.Responses == nil {
+		return nil
+	}
+	var properties []inferenceProducerProperty
+	for _, statusCode := range []int{consts.StatusOK, consts.StatusCreated, consts.StatusAccepted} {
+		responseRef, exist := operation.Responses.Map()[strconv.Itoa(statusCode)]
+		if !exist || responseRef.Value == nil || len(responseRef.Value.Content) == 0 {
+			continue
+		}
+		// Properties are sorted by name, so that bindings are stable.
+		responseProperties := extractPropertiesFromSchema(utils.GetContentSchema(responseRef.Value.Content))
+		slices.SortFunc(responseProperties, func(a, b SimpleAPIProperty) int {
+			return strings.Compare(a.Name, b.Name)
+		})
+		for _, property := range responseProperties {
+			properties = append(properties, inferenceProducerProperty{property: property, matchName: property.Name})
+		}
+		break
+	}
+
+	resourceName := getEndpointResourceName(method.Endpoint)
+	if resourceName == "" {
+		return properties
+	}
+	qualifiedProperties := make([]inferenceProducerProperty, 0)
+	for _, property := range properties {
+		if utils.IsCommonFieldName(property.matchName) {
+			qualifiedProperties = append(qualifiedProperties, inferenceProducerProperty{
+				property:  property.property,
+				matchName: resourceName + strings.ToUpper(property.matchName[:1]) + property.matchName[1:],
+			})
+		}
+	}
+	return append(properties, qualifiedProperties...)
+}
+
+// getInferenceConsumerProperties returns the path and query parameters, and the request body properties of the operation.
+// Header and cookie parameters are ignored, as they are mostly not resource-related (e.g., authentication tokens).
+func getInferenceConsumerProperties(operation *openapi3.Operation) []SimpleAPIProperty {
+	properties := make([]SimpleAPIProperty, 0)
+	for _, paramRef := range operation.Parameters {
+		param := paramRef.Value
+		if param == nil || (param.In != openapi3.ParameterInPath && param.In != openapi3.ParameterInQuery) {
+			continue
+		}
+		// Params declaring no schema are of unknown type, and cannot be matched.
+		paramSchema := utils.GetParameterSchema(param)
+		if paramSchema == nil {
+			continue
+		}
+		properties = append(properties, SimpleAPIProperty{
+			Name: param.Name,
+			Typ:  OpenAPITypes2SimpleAPIPropertyType(paramSchema.Value.Type),
+		})
+	}
+	if operation.RequestBody != nil {
+		properties = append(properties, extractPropertiesFromSchema(utils.GetRequestBodySchema(operation.RequestBody))...)
+	}
+	return properties
+}
+
+// getEndpointResourceName returns the singular name of the resource of an endpoint, i.e., the last segment which is not a path parameter.
+// For example, the resource name of `/api/products/{productId}` is `product`.
+// It returns an empty string if there is no such segment.
+func getEndpointResourceName(endpoint string) string {
+	segments := utils.SplitEndpointPath(endpoint)
+	for _, segment := range slices.Backward(segments) {
+		if segment == "" || strings.HasPrefix(segment, "{") {
+			continue
+		}
+		return utils.GetSingularFormNameHeuristic(segment)
+	}
+	return ""
+}
@@ -1,7 +1,9 @@
 package static
 
 import (
+	"maps"
 	"resttracefuzzer/pkg/utils"
+	"slices"
 	"strconv"
 
 	"github.com/bytedance/sonic"
@@ -65,7 +67,7 @@ func (g *APIDataflowGraph) ParseFromServiceDocument(serviceDocMap map[string]map
 	}
 
 	// log parsed dataflow graph, for debugging
-	dfgJson, err := sonic.MarshalString(g.Edges[0].Source.SimpleAPIMethod.Typ)
+	dfgJson, err := sonic.MarshalString(g.Edges)
 	if err != nil {
 		log.Err(err).Msg("[APIDataflowGraph.ParseFromServiceDocument] Failed to marshal dataflow graph")
 	} else {
@@ -94,9 +96,14 @@ func (g *APIDataflowGraph) parseServiceOperationPair(
 	sourceParameters := sourceOperation.Parameters
 	for _, sourceParamRef := range sourceParameters {
 		sourceParam := sourceParamRef.Value
+		// Params declaring no schema are of unknown type, and cannot be matched.
+		sourceParamSchema := utils.GetParameterSchema(sourceParam)
+		if sourceParamSchema == nil {
+			continue
+		}
 		simpleAPIProperty := SimpleAPIProperty{
 			Name: sourceParam.Name,
-			Typ:  OpenAPITypes2SimpleAPIPropertyType(sourceParam.Schema.Value.Type),
+			Typ:  OpenAPITypes2SimpleAPIPropertyType(sourceParamSchema.Value.Type),
 		}
 		sourceRequestProperties = append(sourceRequestProperties, simpleAPIProperty)
 	}
@@ -104,9 +111,14 @@ func (g *APIDataflowGraph) parseServiceOperationPair(
 	targetParameters := targetOperation.Parameters
 	for _, targetParamRef := range targetParameters {
 		targetParam := targetParamRef.Value
+		// Params declaring no schema are of unknown type, and cannot be matched.
+		targetParamSchema := utils.GetParameterSchema(targetParam)
+		if targetParamSchema == nil {
+			continue
+		}
 		simpleAPIProperty := SimpleAPIProperty{
 			Name: targetParam.Name,
-			Typ:  OpenAPITypes2SimpleAPIPropertyType(targetParam.Schema.Value.Type),
+			Typ:  OpenAPITypes2SimpleAPIPropertyType(targetParamSchema.Value.Type),
 		}
 		targetRequestProperties = append(targetRequestProperties, simpleAPIProperty)
 	}
@@ -115,14 +127,14 @@ func (g *APIDataflowGraph) parseServiceOperationPair(
 	if sourceOperation.RequestBody != nil {
 		sourceRequestProperties = append(
 			sourceRequestProperties,
-			extractPropertiesFromSchema(sourceOperation.RequestBody.Value.Content.Get("application/json").Schema)...,
+			extractPropertiesFromSchema(utils.GetRequestBodySchema(sourceOperation.RequestBody))...,
 		)
 	}
 
 	if targetOperation.RequestBody != nil {
 		targetRequestProperties = append(
 			targetRequestProperties,
-			extractPropertiesFromSchema(targetOperation.RequestBody.Value.Content.Get("application/json").Schema)...,
+			extractPropertiesFromSchema(utils.GetRequestBodySchema(targetOperation.RequestBody))...,
 		)
 	}
 
@@ -145,7 +157,7 @@ func (g *APIDataflowGraph) parseServiceOperationPair(
 			if len(contentMap) == 0 {
 				log.Debug().Msgf("[APIDataflowGraph.parseServiceOperationPair] No response content found, operation ID: %s", sourceOperation.OperationID)
 			} else {
-				sourceResponseProperties = extractPropertiesFromSchema(sourceResponse.Value.Content.Get("application/json").Schema)
+				sourceResponseProperties = extractPropertiesFromSchema(utils.GetContentSchema(contentMap))
 			}
 		}
 	}
@@ -166,7 +178,7 @@ func (g *APIDataflowGraph) parseServiceOperationPair(
 			if len(contentMap) == 0 {
 				log.Warn().Msgf("[APIDataflowGraph.parseServiceOperationPair] No response content found, operation ID: %s", targetOperation.OperationID)
 			} else {
-				targetResponseProperties = extractPropertiesFromSchema(targetResponse.Value.Content.Get("application/json").Schema)
+				targetResponseProperties = extractPropertiesFromSchema(utils.GetContentSchema(contentMap))
 			}
 		}
 	}
@@ -255,3 +267,66 @@ func (g *APIDataflowGraph) tryMatchPropertiesAndUpdateGraph(
 		}
 	}
 }
+
+// ParseFromMessagingChannels adds dataflow edges between message-driven endpoints (see [SimpleAPIMethodTypeMessaging]) of the same channel,
+// from each endpoint publishing messages to the channel to each endpoint consuming messages from it.
+// Such dataflow exists no matter whether property names match, as the message is passed as a whole.
+// Message-producing operations of the system (i.e., of service `frontend`) are producers as well.
+//
+// `serviceDocMap` is a map from the service name to the map from the method name to the OpenAPI operation.
+func (g *APIDataflowGraph) ParseFromMessagingChannels(serviceDocMap map[string]map[SimpleAPIMethod]*openapi3.Operation) {
+	producerMap := make(map[string][]InternalServiceEndpoint)
+	consumerMap := make(map[string][]InternalServiceEndpoint)
+	for _, serviceName := range slices.Sorted(maps.Keys(serviceDocMap)) {
+		for method := range serviceDocMap[serviceName] {
+			if method.Typ != SimpleAPIMethodTypeMessaging {
+				continue
+			}
+			endpoint := InternalServiceEndpoint{
+				ServiceName:     serviceName,
+				SimpleAPIMethod: method,
+			}
+			switch method.Method {
+			case MessagingPublishMethod:
+				producerMap[method.Endpoint] = append(producerMap[method.Endpoint], endpoint)
+			case MessagingConsumeMethod:
+				consumerMap[method.Endpoint] = append(consumerMap[method.Endpoint], endpoint)
+			}
+		}
+	}
+	edgeCount := 0
+	for _, channelName := range slices.Sorted(maps.Keys(producerMap)) {
+		for _, producer := range producerMap[channelName] {
+			for _, consumer := range consumerMap[channelName] {
+				// Like dataflow between operations, the dataflow within a service is ignored.
+				if producer.ServiceName == consumer.ServiceName {
+					continue
+				}
+				g.AddEdge(&APIDataflowEdge{
+					Source:         producer,
+					Target:         consumer,
+					SourceProperty: getMessagePayloadProperty(channelName, serviceDocMap[producer.ServiceName][producer.SimpleAPIMethod]),
+					TargetProperty: getMessagePayloadProperty(channelName, serviceDocMap[consumer.ServiceName][consumer.SimpleAPIMethod]),
+				})
+				edgeCount++
+			}
+		}
+	}
+	log.Debug().Msgf("[APIDataflowGraph.ParseFromMessagingChannels] Added %d dataflow edges of messaging channels", edgeCount)
+}
+
+// getMessagePayloadProperty returns the property of the message payload of a message-driven operation, named after the channel.
+// The payload is of type object if it declares no schema.
+func getMessagePayloadProperty(channelName string, operation *openapi3.Operation) SimpleAPIProperty {
+	property := SimpleAPIProperty{
+		Name: channelName,
+		Typ:  SimpleAPIPropertyTypeObject,
+	}
+	if operation == nil || operation.RequestBody == nil {
+		return property
+	}
+	if payloadSchema := utils.GetRequestBodySchema(operation.RequestBody); payloadSchema != nil && payloadSchema.Value != nil && payloadSchema.Value.Type != nil {
+		property.Typ = OpenAPITypes2SimpleAPIPropertyType(payloadSchema.Value.Type)
+	}
+	return property
+}
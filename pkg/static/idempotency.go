@@ -0,0 +1,28 @@
+package static
+
+import (
+	"regexp"
+
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+// idempotencyKeyHeaderRegex matches names of headers carrying idempotency keys, e.g., `Idempotency-Key`, `X-Idempotency-Key` and `Idempotency-Token`.
+var idempotencyKeyHeaderRegex = regexp.MustCompile(`(?i)^(x-)?idempotency[-_]?(key|token)$`)
+
+// GetIdempotencyKeyHeader returns the name of the header parameter declared by the operation to carry idempotency keys,
+// which makes retries of the request safe, e.g., `Idempotency-Key`.
+// It returns an empty string if there is no such header parameter.
+func GetIdempotencyKeyHeader(operation *openapi3.Operation) string {
+	if operation == nil {
+		return ""
+	}
+	for _, paramRef := range operation.Parameters {
+		if paramRef == nil || paramRef.Value == nil || paramRef.Value.In != openapi3.ParameterInHeader {
+			continue
+		}
+		if idempotencyKeyHeaderRegex.MatchString(paramRef.Value.Name) {
+			return paramRef.Value.Name
+		}
+	}
+	return ""
+}
@@ -0,0 +1,108 @@
+package static
+
+import (
+	"fmt"
+	"resttracefuzzer/pkg/utils"
+	"slices"
+	"strings"
+)
+
+// OperationCollisionType represents the type of a collision detected in API docs.
+type OperationCollisionType string
+
+const (
+	// OperationCollisionTypeOperationID means the same operationId is declared by more than one operation.
+	OperationCollisionTypeOperationID OperationCollisionType = "operationId"
+
+	// OperationCollisionTypePath means the same HTTP method and path template (ignoring path parameter names) is declared more than once,
+	// e.g., `GET /users/{id}` and `GET /users/{userId}`.
+	OperationCollisionTypePath OperationCollisionType = "path"
+)
+
+// OperationCollision records a collision detected when initializing the API manager from (merged) docs.
+// The first endpoint in Endpoints keeps its original name, and the rest are disambiguated by aliases.
+type OperationCollision struct {
+	// Typ is the type of the collision.
+	Typ OperationCollisionType `json:"type"`
+
+	// Key is the colliding key, i.e., the operationId, or the HTTP method and normalized path.
+	Key string `json:"key"`
+
+	// Endpoints are the endpoints involved in the collision, in deterministic (discovery) order.
+	Endpoints []InternalServiceEndpoint `json:"endpoints"`
+
+	// Aliases are the names the endpoints are registered with, in the same order as Endpoints.
+	// The first one is always the original name.
+	Aliases []string `json:"aliases"`
+}
+
+// operationCollisionDetector detects collisions of operationIds and paths, and assigns deterministic aliases.
+// It relies on the caller to feed operations in deterministic order.
+type operationCollisionDetector struct {
+	// collisions maps from collision type and key to the collision.
+	collisions map[OperationCollisionType]map[string]*OperationCollision
+}
+
+// newOperationCollisionDetector creates a new operationCollisionDetector.
+func newOperationCollisionDetector() *operationCollisionDetector {
+	return &operationCollisionDetector{
+		collisions: map[OperationCollisionType]map[string]*OperationCollision{
+			OperationCollisionTypeOperationID: make(map[string]*OperationCollision),
+			OperationCollisionTypePath:        make(map[string]*OperationCollision),
+		},
+	}
+}
+
+// record records an endpoint under the given key, and returns the alias assigned to it.
+// The first endpoint of a key keeps the original name, so the alias is the key itself;
+// later endpoints are prefixed by their service name, and suffixed by an index if the prefixed name still collides.
+func (d *operationCollisionDetector) record(typ OperationCollisionType, key string, endpoint InternalServiceEndpoint) string {
+	collision, exists := d.collisions[typ][key]
+	if !exists {
+		d.collisions[typ][key] = &OperationCollision{
+			Typ:       typ,
+			Key:       key,
+			Endpoints: []InternalServiceEndpoint{endpoint},
+			Aliases:   []string{key},
+		}
+		return key
+	}
+	alias := fmt.Sprintf("%s.%s", endpoint.ServiceName, key)
+	for i := 2; slices.Contains(collision.Aliases, alias); i++ {
+		alias = fmt.Sprintf("%s.%s#%d", endpoint.ServiceName, key, i)
+	}
+	collision.Endpoints = append(collision.Endpoints, endpoint)
+	collision.Aliases = append(collision.Aliases, alias)
+	return alias
+}
+
+// getCollisions returns all detected collisions, i.e., keys recorded more than once, sorted by type and key.
+func (d *operationCollisionDetector) getCollisions() []*OperationCollision {
+	res := make([]*OperationCollision, 0)
+	for _, collisionMap := range d.collisions {
+		for _, collision := range collisionMap {
+			if len(collision.Endpoints) > 1 {
+				res = append(res, collision)
+			}
+		}
+	}
+	slices.SortFunc(res, func(a, b *OperationCollision) int {
+		if a.Typ != b.Typ {
+			return strings.Compare(string(a.Typ), string(b.Typ))
+		}
+		return strings.Compare(a.Key, b.Key)
+	})
+	return res
+}
+
+// normalizePathTemplate replaces names of path parameters with empty braces,
+// so that `/users/{id}` and `/users/{userId}` are normalized to the same path.
+func normalizePathTemplate(path string) string {
+	segments := utils.SplitEndpointPath(path)
+	for i, segment := range segments {
+		if utils.IfPathSegmentIsPathParam(segment) {
+			segments[i] = "{}"
+		}
+	}
+	return "/" + strings.Join(segments, "/")
+}
@@ -0,0 +1,159 @@
+package static
+
+import (
+	"fmt"
+	"resttracefuzzer/pkg/utils"
+	"slices"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+const (
+	// operationFilterTagPrefix is the prefix of filter rules matching operations by tag, e.g., `tag:admin`.
+	operationFilterTagPrefix = "tag:"
+
+	// operationFilterOperationIDPrefix is the prefix of filter rules matching operations by operationId, e.g., `operationId:deleteUser`.
+	operationFilterOperationIDPrefix = "operationId:"
+)
+
+// OperationFilterRule is a rule matching operations, by method and path glob (see [utils.MatchPathGlob]), tag or operationId.
+// Empty fields match any operation.
+type OperationFilterRule struct {
+	// Method is the method of matched operations, e.g., `DELETE`.
+	Method string
+
+	// PathGlob is the glob pattern of paths of matched operations, e.g., `/admin/**`.
+	PathGlob string
+
+	// Tag is a tag of matched operations.
+	Tag string
+
+	// OperationID is the operationId of matched operations.
+	OperationID string
+}
+
+// ParseOperationFilterRule parses a filter rule from a string, in one of the forms:
+//   - `METHOD PATH_GLOB`, e.g., `DELETE /admin/**`, where METHOD can be `*` for any method;
+//   - `PATH_GLOB` or `METHOD` alone, e.g., `/admin/**` or `DELETE`;
+//   - `tag:TAG`, e.g., `tag:admin`;
+//   - `operationId:OPERATION_ID`, e.g., `operationId:deleteUser`.
+func ParseOperationFilterRule(ruleStr string) (*OperationFilterRule, error) {
+	ruleStr = strings.TrimSpace(ruleStr)
+	switch {
+	case ruleStr == "":
+		return nil, fmt.Errorf("empty operation filter rule")
+	case strings.HasPrefix(ruleStr, operationFilterTagPrefix):
+		return &OperationFilterRule{Tag: strings.TrimPrefix(ruleStr, operationFilterTagPrefix)}, nil
+	case strings.HasPrefix(ruleStr, operationFilterOperationIDPrefix):
+		return &OperationFilterRule{OperationID: strings.TrimPrefix(ruleStr, operationFilterOperationIDPrefix)}, nil
+	}
+	fields := strings.Fields(ruleStr)
+	rule := &OperationFilterRule{}
+	switch {
+	case len(fields) == 1 && strings.HasPrefix(fields[0], "/"):
+		rule.PathGlob = fields[0]
+	case len(fields) == 1:
+		rule.Method = strings.ToUpper(fields[0])
+	case len(fields) == 2 && strings.HasPrefix(fields[1], "/"):
+		rule.Method = strings.ToUpper(fields[0])
+		rule.PathGlob = fields[1]
+	default:
+		return nil, fmt.Errorf("invalid operation filter rule: %s", ruleStr)
+	}
+	if rule.Method == "*" {
+		rule.Method = ""
+	}
+	return rule, nil
+}
+
+// Match returns whether the rule matches the operation.
+func (r *OperationFilterRule) Match(method SimpleAPIMethod, operation *openapi3.Operation) bool {
+	if r.Tag != "" && (operation == nil || !slices.Contains(operation.Tags, r.Tag)) {
+		return false
+	}
+	if r.OperationID != "" && (operation == nil || operation.OperationID != r.OperationID) {
+		return false
+	}
+	return r.matchMethodAndPath(method.Method, method.Endpoint)
+}
+
+// MatchRequest returns whether the rule matches a request by method and path.
+// Rules of tag or operationId do not match any request, as requests carry neither.
+func (r *OperationFilterRule) MatchRequest(method, requestPath string) bool {
+	if r.Tag != "" || r.OperationID != "" {
+		return false
+	}
+	return r.matchMethodAndPath(method, requestPath)
+}
+
+// matchMethodAndPath returns whether the method and the path match those of the rule.
+func (r *OperationFilterRule) matchMethodAndPath(method, pathToMatch string) bool {
+	if r.Method != "" && !strings.EqualFold(r.Method, method) {
+		return false
+	}
+	return r.PathGlob == "" || utils.MatchPathGlob(r.PathGlob, pathToMatch)
+}
+
+// OperationFilter filters operations to fuzz by include and exclude rules, so that destructive or out-of-scope operations can be avoided.
+// An operation is allowed if it matches any include rule (or there is none), and matches no exclude rule.
+type OperationFilter struct {
+	// IncludeRules are rules of operations to include. All operations are included if empty.
+	IncludeRules []*OperationFilterRule
+
+	// ExcludeRules are rules of operations to exclude, taking precedence over IncludeRules.
+	ExcludeRules []*OperationFilterRule
+}
+
+// NewOperationFilter creates a new OperationFilter from comma-separated include and exclude rules, see [ParseOperationFilterRule].
+func NewOperationFilter(includeRulesStr, excludeRulesStr string) (*OperationFilter, error) {
+	includeRules, err := parseOperationFilterRules(includeRulesStr)
+	if err != nil {
+		return nil, err
+	}
+	excludeRules, err := parseOperationFilterRules(excludeRulesStr)
+	if err != nil {
+		return nil, err
+	}
+	return &OperationFilter{
+		IncludeRules: includeRules,
+		ExcludeRules: excludeRules,
+	}, nil
+}
+
+// IsOperationAllowed returns whether the operation is allowed to fuzz.
+// All operations are allowed if the filter is nil.
+func (f *OperationFilter) IsOperationAllowed(method SimpleAPIMethod, operation *openapi3.Operation) bool {
+	if f == nil {
+		return true
+	}
+	if len(f.IncludeRules) > 0 && !slices.ContainsFunc(f.IncludeRules, func(rule *OperationFilterRule) bool { return rule.Match(method, operation) }) {
+		return false
+	}
+	return !slices.ContainsFunc(f.ExcludeRules, func(rule *OperationFilterRule) bool { return rule.Match(method, operation) })
+}
+
+// IsRequestExcluded returns whether a concrete request (e.g., a recorded one) is excluded by method and path,
+// even if its path matches an allowed operation of a broader template.
+func (f *OperationFilter) IsRequestExcluded(method, requestPath string) bool {
+	if f == nil {
+		return false
+	}
+	return slices.ContainsFunc(f.ExcludeRules, func(rule *OperationFilterRule) bool { return rule.MatchRequest(method, requestPath) })
+}
+
+// parseOperationFilterRules parses comma-separated filter rules, ignoring empty ones.
+func parseOperationFilterRules(rulesStr string) ([]*OperationFilterRule, error) {
+	rules := make([]*OperationFilterRule, 0)
+	for _, ruleStr := range strings.Split(rulesStr, ",") {
+		if strings.TrimSpace(ruleStr) == "" {
+			continue
+		}
+		rule, err := ParseOperationFilterRule(ruleStr)
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
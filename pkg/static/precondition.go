@@ -0,0 +1,110 @@
+package static
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/rs/zerolog/log"
+)
+
+// OperationPreconditionExtensionKey is the vendor extension of an operation, listing operationIds of those that must precede it.
+// The value is a list of operationIds, or a single operationId, e.g.,
+//
+//	x-depends-on: [createUser, login]
+const OperationPreconditionExtensionKey = "x-depends-on"
+
+// initOperationPreconditions parses preconditions (see [OperationPreconditionExtensionKey]) of external operations.
+// It should be called after operationIds of the system doc are registered in OperationAliasMap.
+// Unknown operationIds, and those of operations not in APIMap (e.g., skipped unsupported operations), are ignored.
+func (m *APIManager) initOperationPreconditions() {
+	m.OperationPreconditions = make(map[SimpleAPIMethod][]SimpleAPIMethod)
+	for _, method := range slices.SortedFunc(maps.Keys(m.APIMap), CompareSimpleAPIMethod) {
+		operationIDs, err := parseOperationPreconditionExtension(m.APIMap[method])
+		if err != nil {
+			log.Err(err).Msgf("[APIManager.initOperationPreconditions] Invalid %s of %s %s, ignored", OperationPreconditionExtensionKey, method.Method, method.Endpoint)
+			continue
+		}
+		preconditions := make([]SimpleAPIMethod, 0, len(operationIDs))
+		for _, operationID := range operationIDs {
+			endpoint, ok := m.ResolveOperationAlias(operationID)
+			if !ok || endpoint.ServiceName != frontendServiceName {
+				log.Warn().Msgf("[APIManager.initOperationPreconditions] Unknown operationId %s in %s of %s %s, ignored", operationID, OperationPreconditionExtensionKey, method.Method, method.Endpoint)
+				continue
+			}
+			if _, exists := m.APIMap[endpoint.SimpleAPIMethod]; !exists || endpoint.SimpleAPIMethod == method {
+				log.Warn().Msgf("[APIManager.initOperationPreconditions] Operation %s in %s of %s %s is not available, ignored", operationID, OperationPreconditionExtensionKey, method.Method, method.Endpoint)
+				continue
+			}
+			if !slices.Contains(preconditions, endpoint.SimpleAPIMethod) {
+				preconditions = append(preconditions, endpoint.SimpleAPIMethod)
+			}
+		}
+		if len(preconditions) > 0 {
+			m.OperationPreconditions[method] = preconditions
+		}
+	}
+}
+
+// GetOperationPreconditions returns the API methods that must directly precede the given one, in declared order.
+func (m *APIManager) GetOperationPreconditions(method SimpleAPIMethod) []SimpleAPIMethod {
+	return m.OperationPreconditions[method]
+}
+
+// GetOrderedOperationPreconditions returns all API methods that must precede the given one, including transitive ones,
+// in an order satisfying all of their own preconditions (i.e., topological order). The given method itself is not included.
+// If preconditions form a cycle, the cycle is broken at the method visited again, and a warning is logged.
+func (m *APIManager) GetOrderedOperationPreconditions(method SimpleAPIMethod) []SimpleAPIMethod {
+	res := make([]SimpleAPIMethod, 0)
+	visited := make(map[SimpleAPIMethod]struct{})
+	visiting := make(map[SimpleAPIMethod]struct{})
+	var visit func(curr SimpleAPIMethod)
+	visit = func(curr SimpleAPIMethod) {
+		if _, ok := visited[curr]; ok {
+			return
+		}
+		if _, ok := visiting[curr]; ok {
+			log.Warn().Msgf("[APIManager.GetOrderedOperationPreconditions] Cyclic %s detected at %s %s", OperationPreconditionExtensionKey, curr.Method, curr.Endpoint)
+			return
+		}
+		visiting[curr] = struct{}{}
+		for _, precondition := range m.OperationPreconditions[curr] {
+			visit(precondition)
+		}
+		delete(visiting, curr)
+		visited[curr] = struct{}{}
+		if curr != method {
+			res = append(res, curr)
+		}
+	}
+	visit(method)
+	return res
+}
+
+// parseOperationPreconditionExtension parses operationIds in [OperationPreconditionExtensionKey] of the operation.
+// It returns an empty list if the extension does not exist.
+func parseOperationPreconditionExtension(operation *openapi3.Operation) ([]string, error) {
+	value, exists := operation.Extensions[OperationPreconditionExtensionKey]
+	if !exists || value == nil {
+		return []string{}, nil
+	}
+	switch v := value.(type) {
+	case string:
+		return []string{v}, nil
+	case []string:
+		return v, nil
+	case []any:
+		res := make([]string, 0, len(v))
+		for _, elem := range v {
+			operationID, ok := elem.(string)
+			if !ok {
+				return nil, fmt.Errorf("operationId is not a string: %v", elem)
+			}
+			res = append(res, operationID)
+		}
+		return res, nil
+	default:
+		return nil, fmt.Errorf("expected a list of operationIds, got %T", value)
+	}
+}
@@ -57,6 +57,11 @@ func (r *ReachabilityMap) RemoveReachability(external SimpleAPIMethod, internal
 	}
 }
 
+// HasReachability checks whether the internal API is reachable from the external API in the map.
+func (r *ReachabilityMap) HasReachability(external SimpleAPIMethod, internal InternalServiceEndpoint) bool {
+	return slices.Contains(r.External2Internal[external], internal)
+}
+
 // GetInternalsByExternal returns the list of internal APIs that are reachable from the given external API.
 // It returns the list of internal APIs and a boolean indicating whether the external API is found in the map.
 func (r *ReachabilityMap) GetInternalsByExternal(external SimpleAPIMethod) ([]InternalServiceEndpoint, bool) {
@@ -0,0 +1,59 @@
+package static
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"slices"
+
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+// serverVariableRegex matches templated server variables in server URLs, e.g., `{port}` in `https://example.com:{port}/v1`.
+var serverVariableRegex = regexp.MustCompile(`\{([^{}]+)\}`)
+
+// ResolveServerURLs resolves URLs of entries of the `servers` block of the doc, in order, by substituting templated server variables.
+// Values of variables are taken from variableValues (by variable name, shared by all servers) if given, or their defaults otherwise.
+// It returns an error if a value is not in the enum of its variable, or a variable in a URL is not defined by the server.
+// Relative server URLs (e.g., `/v1`) are kept, so that URLs are at the same indices as servers, see [IsAbsoluteServerURL].
+func ResolveServerURLs(doc *openapi3.T, variableValues map[string]string) ([]string, error) {
+	serverURLs := make([]string, 0)
+	if doc == nil {
+		return serverURLs, nil
+	}
+	for i, server := range doc.Servers {
+		if server == nil {
+			serverURLs = append(serverURLs, "")
+			continue
+		}
+		var resolveErr error
+		serverURL := serverVariableRegex.ReplaceAllStringFunc(server.URL, func(placeholder string) string {
+			name := placeholder[1 : len(placeholder)-1]
+			variable, exists := server.Variables[name]
+			if !exists || variable == nil {
+				resolveErr = fmt.Errorf("variable %s of server %d (%s) is not defined", name, i, server.URL)
+				return placeholder
+			}
+			value, overridden := variableValues[name]
+			if !overridden {
+				value = variable.Default
+			}
+			if len(variable.Enum) > 0 && !slices.Contains(variable.Enum, value) {
+				resolveErr = fmt.Errorf("value %s of variable %s of server %d (%s) is not one of %v", value, name, i, server.URL, variable.Enum)
+			}
+			return value
+		})
+		if resolveErr != nil {
+			return nil, resolveErr
+		}
+		serverURLs = append(serverURLs, serverURL)
+	}
+	return serverURLs, nil
+}
+
+// IsAbsoluteServerURL returns whether the server URL is absolute, i.e., with scheme and host.
+// Relative server URLs are relative to the location of the doc, which is not a server to send requests to.
+func IsAbsoluteServerURL(serverURL string) bool {
+	parsedURL, err := url.Parse(serverURL)
+	return err == nil && parsedURL.Scheme != "" && parsedURL.Host != ""
+}
@@ -1,7 +1,6 @@
 package static
 
 import (
-	"math/rand/v2"
 	"resttracefuzzer/pkg/utils"
 	"strings"
 
@@ -22,7 +21,11 @@ func (t SimpleAPIMethodType) MarshalJSON() ([]byte, error) {
 }
 
 func (t *SimpleAPIMethodType) UnmarshalJSON(data []byte) error {
-	*t = SimpleAPIMethodType(data)
+	var str string
+	if err := sonic.Unmarshal(data, &str); err != nil {
+		return err
+	}
+	*t = SimpleAPIMethodType(str)
 	return nil
 }
 
@@ -38,7 +41,11 @@ func (t SimpleAPIPropertyType) MarshalJSON() ([]byte, error) {
 }
 
 func (t *SimpleAPIPropertyType) UnmarshalJSON(data []byte) error {
-	*t = SimpleAPIPropertyType(data)
+	var str string
+	if err := sonic.Unmarshal(data, &str); err != nil {
+		return err
+	}
+	*t = SimpleAPIPropertyType(str)
 	return nil
 }
 
@@ -127,14 +134,14 @@ func DefaultValueForPrimitiveSimpleAPIPropertyType(typ SimpleAPIPropertyType) an
 func RandomValueForPrimitiveSimpleAPIPropertyType(typ SimpleAPIPropertyType) any {
 	switch typ {
 	case SimpleAPIPropertyTypeString:
-		randLength := rand.IntN(114) + 1
+		randLength := utils.SharedRand.IntN(114) + 1
 		return utils.RandStringBytes(randLength)
 	case SimpleAPIPropertyTypeInteger:
-		return rand.IntN(114514)
+		return utils.SharedRand.IntN(114514)
 	case SimpleAPIPropertyTypeFloat:
-		return rand.Float64() + float64(rand.IntN(114514))
+		return utils.SharedRand.Float64() + float64(utils.SharedRand.IntN(114514))
 	case SimpleAPIPropertyTypeBoolean:
-		return rand.IntN(2) == 1
+		return utils.SharedRand.IntN(2) == 1
 	default:
 		log.Warn().Msgf("[RandomValueForPrimitiveSimpleAPIPropertyType] Unknown type or non-primitive type: %v", typ)
 		return nil
@@ -156,9 +163,10 @@ const (
 	SimpleAPIMethodTypeHTTP SimpleAPIMethodType = "HTTP"
 	// SimpleAPIMethodTypeGRPC represents a gRPC API method.
 	SimpleAPIMethodTypeGRPC SimpleAPIMethodType = "gRPC"
+	// SimpleAPIMethodTypeMessaging represents a message-producing operation, i.e., publishing messages to a channel of a message broker.
+	SimpleAPIMethodTypeMessaging SimpleAPIMethodType = "Messaging"
 	// SimpleAPIMethodTypeUnknown represent an unknown type of API method
 	SimpleAPIMethodTypeUnknown SimpleAPIMethodType = "unknown"
-	// TODO: Add more types if needed, such as MessageQueue, etc. @xunzhou24
 
 	// SimpleAPIPropertyTypeFloat
 	SimpleAPIPropertyTypeFloat SimpleAPIPropertyType = "float"
@@ -187,14 +195,18 @@ const (
 // SimpleAPIMethod represents an API method on a specific endpoint.
 //   - If the API is an HTTP API, the method is the HTTP method, such as GET, POST, PUT, DELETE, and Endpoint is the URL path.
 //   - If the API is a gRPC API, the endpoint is the gRPC method name, and the method field is undefined
+//   - If the API is a message-producing operation, the method is [MessagingPublishMethod], and the endpoint is the channel name.
 //
-// Endpoint is the URL path or the gRPC method name.
+// Endpoint is the URL path, the gRPC method name or the channel name.
+// Target is the name of the additional target (see [APITarget]) the operation comes from, or empty for the primary one,
+// so that the same route served by several targets (e.g., gateways with the same route layout) is a different operation of each target.
 //
 // You should use the struct by value, not by pointer.
 type SimpleAPIMethod struct {
 	Endpoint string              `json:"endpoint"`
 	Method   string              `json:"method"`
 	Typ      SimpleAPIMethodType `json:"type"`
+	Target   string              `json:"target,omitempty"`
 }
 
 // CompareSimpleAPIMethod compares two SimpleAPIMethods.
@@ -207,7 +219,10 @@ func CompareSimpleAPIMethod(a, b SimpleAPIMethod) int {
 	if a.Method != b.Method {
 		return strings.Compare(a.Method, b.Method)
 	}
-	return strings.Compare(a.Typ.String(), b.Typ.String())
+	if a.Typ != b.Typ {
+		return strings.Compare(a.Typ.String(), b.Typ.String())
+	}
+	return strings.Compare(a.Target, b.Target)
 }
 
 // InternalServiceEndpoint represents an endpoint of an internal service.
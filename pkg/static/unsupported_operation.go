@@ -0,0 +1,147 @@
+package static
+
+import (
+	"fmt"
+	"resttracefuzzer/internal/config"
+	"slices"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+// UnsupportedOperationReason is the reason why an operation cannot be executed correctly by the fuzzer.
+type UnsupportedOperationReason string
+
+const (
+	// UnsupportedOperationReasonMediaType indicates that the request body has no JSON media type, e.g., multipart only.
+	UnsupportedOperationReasonMediaType UnsupportedOperationReason = "mediaType"
+
+	// UnsupportedOperationReasonWebSocket indicates that the operation is a WebSocket handshake.
+	UnsupportedOperationReasonWebSocket UnsupportedOperationReason = "websocket"
+
+	// UnsupportedOperationReasonAuth indicates that the operation requires an unsupported authentication, e.g., OAuth2.
+	UnsupportedOperationReasonAuth UnsupportedOperationReason = "auth"
+)
+
+// UnsupportedOperation is an operation requiring features the fuzzer does not support yet.
+// Such operations are skipped, rather than generating requests that are doomed to fail.
+type UnsupportedOperation struct {
+	// APIMethod is the API method of the operation.
+	APIMethod SimpleAPIMethod `json:"APIMethod"`
+
+	// Reason is the reason why the operation is unsupported.
+	Reason UnsupportedOperationReason `json:"reason"`
+
+	// Detail describes the unsupported feature, e.g., media types of the request body.
+	Detail string `json:"detail"`
+}
+
+// checkOperationSupported checks whether the fuzzer can execute the operation of the doc correctly.
+// It returns nil if the operation is supported.
+// At present, the following are unsupported:
+//  1. Request body without any JSON media type (e.g., multipart/form-data), as only JSON bodies are generated.
+//  2. WebSocket handshake, declared by extension `x-websocket` or by header `Upgrade` / `Sec-WebSocket-*`.
+//  3. Security requirements which cannot be satisfied by static headers or the OAuth2 auth manager (e.g., OpenID Connect, mutual TLS, API key in cookie or query, and OAuth2 if config `oauth2-grant-type` is not set).
+func checkOperationSupported(doc *openapi3.T, method SimpleAPIMethod, operation *openapi3.Operation) *UnsupportedOperation {
+	newUnsupportedOperation := func(reason UnsupportedOperationReason, detail string) *UnsupportedOperation {
+		return &UnsupportedOperation{
+			APIMethod: method,
+			Reason:    reason,
+			Detail:    detail,
+		}
+	}
+
+	if isWebSocketOperation(operation) {
+		return newUnsupportedOperation(UnsupportedOperationReasonWebSocket, "websocket handshake")
+	}
+
+	if operation.RequestBody != nil && operation.RequestBody.Value != nil && len(operation.RequestBody.Value.Content) > 0 {
+		mediaTypes := make([]string, 0, len(operation.RequestBody.Value.Content))
+		hasJSON := false
+		for mediaType := range operation.RequestBody.Value.Content {
+			mediaTypes = append(mediaTypes, mediaType)
+			if strings.Contains(strings.ToLower(mediaType), "json") {
+				hasJSON = true
+			}
+		}
+		if !hasJSON {
+			slices.Sort(mediaTypes)
+			return newUnsupportedOperation(UnsupportedOperationReasonMediaType, fmt.Sprintf("request body media types: %s", strings.Join(mediaTypes, ", ")))
+		}
+	}
+
+	// Security of operation overrides that of the doc.
+	securityRequirements := doc.Security
+	if operation.Security != nil {
+		securityRequirements = *operation.Security
+	}
+	if len(securityRequirements) > 0 {
+		var securitySchemes openapi3.SecuritySchemes
+		if doc.Components != nil {
+			securitySchemes = doc.Components.SecuritySchemes
+		}
+		// Requirements are alternatives, any satisfiable one is enough.
+		unsupportedSchemes := make([]string, 0)
+		satisfiable := false
+		for _, requirement := range securityRequirements {
+			unsupportedSchemesOfRequirement := getUnsupportedSecuritySchemes(requirement, securitySchemes)
+			if len(unsupportedSchemesOfRequirement) == 0 {
+				satisfiable = true
+				break
+			}
+			unsupportedSchemes = append(unsupportedSchemes, unsupportedSchemesOfRequirement...)
+		}
+		if !satisfiable {
+			slices.Sort(unsupportedSchemes)
+			unsupportedSchemes = slices.Compact(unsupportedSchemes)
+			return newUnsupportedOperation(UnsupportedOperationReasonAuth, fmt.Sprintf("security schemes: %s", strings.Join(unsupportedSchemes, ", ")))
+		}
+	}
+	return nil
+}
+
+// isWebSocketOperation checks whether the operation is a WebSocket handshake.
+func isWebSocketOperation(operation *openapi3.Operation) bool {
+	if isWebSocket, ok := operation.Extensions["x-websocket"].(bool); ok && isWebSocket {
+		return true
+	}
+	for _, param := range operation.Parameters {
+		if param == nil || param.Value == nil || param.Value.In != openapi3.ParameterInHeader {
+			continue
+		}
+		name := strings.ToLower(param.Value.Name)
+		if name == "upgrade" || strings.HasPrefix(name, "sec-websocket-") {
+			return true
+		}
+	}
+	return false
+}
+
+// getUnsupportedSecuritySchemes returns the names (with types) of schemes in the requirement that the fuzzer cannot satisfy.
+// Only schemes carried by headers are supported, i.e., HTTP authentication and API key in header, which can be set by extra headers or middlewares,
+// and OAuth2 if an OAuth2 grant type is configured, as tokens are then set by the OAuth2 auth manager.
+// Schemes not defined in the doc are regarded as unsupported.
+func getUnsupportedSecuritySchemes(requirement openapi3.SecurityRequirement, securitySchemes openapi3.SecuritySchemes) []string {
+	res := make([]string, 0)
+	for name := range requirement {
+		schemeRef, exists := securitySchemes[name]
+		if !exists || schemeRef == nil || schemeRef.Value == nil {
+			res = append(res, fmt.Sprintf("%s (undefined)", name))
+			continue
+		}
+		scheme := schemeRef.Value
+		switch {
+		case scheme.Type == "http":
+			continue
+		case scheme.Type == "apiKey" && scheme.In == openapi3.ParameterInHeader:
+			continue
+		case scheme.Type == "oauth2" && config.GlobalConfig.Oauth2GrantType != "":
+			continue
+		case scheme.Type == "apiKey":
+			res = append(res, fmt.Sprintf("%s (apiKey in %s)", name, scheme.In))
+		default:
+			res = append(res, fmt.Sprintf("%s (%s)", name, scheme.Type))
+		}
+	}
+	return res
+}
@@ -0,0 +1,177 @@
+package strategy
+
+import (
+	"maps"
+	"math"
+	"slices"
+	"sync"
+)
+
+const (
+	// WeightMapStrategyConstant is the weight map strategy whose weights are constant, see [ConstantWeightMapStrategy].
+	WeightMapStrategyConstant = "constant"
+
+	// WeightMapStrategyAdaptive is the weight map strategy whose weights adapt to coverage feedback, see [AdaptiveWeightMapStrategy].
+	WeightMapStrategyAdaptive = "adaptive"
+
+	// adaptiveWeightScale scales estimated reward rates to integer weights.
+	adaptiveWeightScale = 1000
+
+	// adaptiveMinRelativeRate is the minimum reward rate of a key relative to the best key, so that no key with positive initial weight is starved.
+	adaptiveMinRelativeRate = 0.05
+
+	// adaptiveFeedbackDecay discounts statistics of all keys on each feedback, so that recent feedback matters more,
+	// as the chance of new coverage changes during the run.
+	adaptiveFeedbackDecay = 0.99
+)
+
+// adaptiveArm is the statistics of a key of [AdaptiveWeightMapStrategy].
+type adaptiveArm struct {
+	// initialWeight is the configured weight of the key. A key of initial weight 0 is never selected.
+	initialWeight int
+
+	// pulls is the discounted number of feedbacks in which the key was selected.
+	pulls float64
+
+	// rewards is the discounted number of feedbacks in which the key was selected, and new coverage was achieved.
+	rewards float64
+}
+
+// AdaptiveWeightMapStrategy is a strategy for weight maps adapting to coverage feedback during the run, as a multi-armed bandit.
+// Each key is an arm, whose reward rate (i.e., the chance that a request using it achieves new coverage) is estimated from discounted feedback, with a uniform prior.
+// The weight of a key is its initial weight scaled by its estimated reward rate relative to the best key (at least [adaptiveMinRelativeRate]),
+// so that weights are the initial ones at the beginning, and keys producing coverage-increasing requests are selected more often later.
+//
+// Selected keys are recorded by [AdaptiveWeightMapStrategy.RecordSelection], taken for each request by [AdaptiveWeightMapStrategy.TakeSelection],
+// and rewarded by [WeightMapSelection.Reward] after the request is executed.
+// The parameter of weight maps is ignored.
+type AdaptiveWeightMapStrategy struct {
+	// arms maps from keys to their statistics.
+	arms map[string]*adaptiveArm
+
+	// pendingSelections is the set of keys selected since the last [AdaptiveWeightMapStrategy.TakeSelection].
+	pendingSelections map[string]struct{}
+
+	// mu protects arms and pendingSelections.
+	mu sync.Mutex
+}
+
+// WeightMapSelection is the keys of an [AdaptiveWeightMapStrategy] selected when populating a request, to be rewarded by feedback of the request.
+type WeightMapSelection struct {
+	// WeightMap is the weight map the keys are selected from.
+	WeightMap *AdaptiveWeightMapStrategy
+
+	// Keys are the selected keys, sorted.
+	Keys []string
+}
+
+// NewAdaptiveWeightMapStrategy creates a new AdaptiveWeightMapStrategy with initial weights.
+func NewAdaptiveWeightMapStrategy(initialWeights map[string]int) *AdaptiveWeightMapStrategy {
+	arms := make(map[string]*adaptiveArm, len(initialWeights))
+	for key, weight := range initialWeights {
+		arms[key] = &adaptiveArm{initialWeight: weight}
+	}
+	return &AdaptiveWeightMapStrategy{
+		arms:              arms,
+		pendingSelections: make(map[string]struct{}),
+	}
+}
+
+// newWeightMapStrategy creates a weight map strategy with initial weights, of the kind of config `weight-map-strategy`.
+// It returns false if the kind is unknown.
+func newWeightMapStrategy(kind string, weights map[string]int) (WeightMapStrategy, bool) {
+	switch kind {
+	case WeightMapStrategyConstant, "":
+		return NewConstantWeightMapStrategy(weights), true
+	case WeightMapStrategyAdaptive:
+		return NewAdaptiveWeightMapStrategy(weights), true
+	default:
+		return NewConstantWeightMapStrategy(weights), false
+	}
+}
+
+// GetWeight returns the current weight value for a given key.
+func (s *AdaptiveWeightMapStrategy) GetWeight(key string) int {
+	return s.GetMapWithParam(WEIGHT_MAP_STRATEGY_PARAM_PLACEHOLDER)[key]
+}
+
+// GetWeightWithParam returns the current weight value for a given key. The parameter is ignored.
+func (s *AdaptiveWeightMapStrategy) GetWeightWithParam(key string, param int) int {
+	return s.GetWeight(key)
+}
+
+// GetMapWithParam returns the current weight map. The parameter is ignored.
+func (s *AdaptiveWeightMapStrategy) GetMapWithParam(param int) map[string]int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	rates := make(map[string]float64, len(s.arms))
+	bestRate := 0.0
+	for key, arm := range s.arms {
+		if arm.initialWeight <= 0 {
+			continue
+		}
+		rates[key] = (arm.rewards + 1) / (arm.pulls + 2)
+		bestRate = max(bestRate, rates[key])
+	}
+	weightMap := make(map[string]int, len(s.arms))
+	for key, arm := range s.arms {
+		if arm.initialWeight <= 0 {
+			weightMap[key] = 0
+			continue
+		}
+		relativeRate := max(rates[key]/bestRate, adaptiveMinRelativeRate)
+		weightMap[key] = max(int(math.Round(float64(arm.initialWeight)*adaptiveWeightScale*relativeRate)), 1)
+	}
+	return weightMap
+}
+
+// RecordSelection records that the key is selected, until the selection is taken by [AdaptiveWeightMapStrategy.TakeSelection].
+func (s *AdaptiveWeightMapStrategy) RecordSelection(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.pendingSelections[key] = struct{}{}
+}
+
+// TakeSelection returns keys selected since the last call, and clears them.
+// It returns nil if no key is selected.
+func (s *AdaptiveWeightMapStrategy) TakeSelection() *WeightMapSelection {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.pendingSelections) == 0 {
+		return nil
+	}
+	keys := slices.Sorted(maps.Keys(s.pendingSelections))
+	clear(s.pendingSelections)
+	return &WeightMapSelection{
+		WeightMap: s,
+		Keys:      keys,
+	}
+}
+
+// Reward records the feedback of the request populated with the selected keys, i.e., whether it achieved new coverage.
+func (sel *WeightMapSelection) Reward(hasAchieveNewCoverage bool) {
+	s := sel.WeightMap
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, arm := range s.arms {
+		arm.pulls *= adaptiveFeedbackDecay
+		arm.rewards *= adaptiveFeedbackDecay
+	}
+	for _, key := range sel.Keys {
+		arm, exist := s.arms[key]
+		if !exist {
+			continue
+		}
+		arm.pulls++
+		if hasAchieveNewCoverage {
+			arm.rewards++
+		}
+	}
+}
+
+// recordWeightMapSelection records the selected key if the weight map adapts to feedback.
+func recordWeightMapSelection(weightMap WeightMapStrategy, key string) {
+	if adaptiveWeightMap, ok := weightMap.(*AdaptiveWeightMapStrategy); ok {
+		adaptiveWeightMap.RecordSelection(key)
+	}
+}
@@ -0,0 +1,222 @@
+package strategy
+
+import (
+	"cmp"
+	"fmt"
+	"maps"
+	"resttracefuzzer/pkg/static"
+	"resttracefuzzer/pkg/utils"
+	"slices"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+// CapabilityGapType represents the type of a spec feature the fuzzer cannot exercise.
+type CapabilityGapType string
+
+const (
+	// CapabilityGapTypeUnsupportedOperation means the whole operation is unsupported, see [static.UnsupportedOperation].
+	CapabilityGapTypeUnsupportedOperation CapabilityGapType = "unsupportedOperation"
+
+	// CapabilityGapTypeParamLocation means a parameter is in a location values are not generated for, e.g., header and cookie.
+	CapabilityGapTypeParamLocation CapabilityGapType = "paramLocation"
+
+	// CapabilityGapTypeMissingSchema means a parameter or request body declares no schema, and best-effort values are generated.
+	CapabilityGapTypeMissingSchema CapabilityGapType = "missingSchema"
+
+	// CapabilityGapTypeMediaType means media types of a request body other than the JSON one are never exercised.
+	CapabilityGapTypeMediaType CapabilityGapType = "mediaType"
+
+	// CapabilityGapTypeSchemaKeyword means a schema keyword is ignored in value generation, e.g., `oneOf` and `additionalProperties`.
+	CapabilityGapTypeSchemaKeyword CapabilityGapType = "schemaKeyword"
+
+	// CapabilityGapTypeSchemaType means a schema declares no known type (and no keyword values can be generated from), so a zero value is generated.
+	CapabilityGapTypeSchemaType CapabilityGapType = "schemaType"
+
+	// CapabilityGapTypeStringFormat means a string format has no value generator, see [FormatValueGenerator].
+	CapabilityGapTypeStringFormat CapabilityGapType = "stringFormat"
+)
+
+// CapabilityGap records a part of the spec the fuzzer cannot exercise, as the generator or the parser falls back on a feature it does not support.
+type CapabilityGap struct {
+	// Typ is the type of the gap.
+	Typ CapabilityGapType `json:"type"`
+
+	// Feature is the unsupported feature, e.g., `header`, `oneOf` or `ipv6`.
+	Feature string `json:"feature"`
+
+	// Location is where the feature is declared, e.g., `POST /orders request body $.items[].price`.
+	Location string `json:"location"`
+
+	// Fallback describes what the fuzzer does instead.
+	Fallback string `json:"fallback"`
+}
+
+// ignoredSchemaKeywordFallback is the fallback of schema keywords ignored in value generation.
+const ignoredSchemaKeywordFallback = "ignored, values are generated from type, properties and items only"
+
+// FindCapabilityGaps finds spec features of external operations that the fuzzer cannot exercise, e.g., unknown types,
+// unsupported parameter locations and missing media types, as the places the parser (see [static.APIManager.UnsupportedOperations])
+// and the generator would fall back.
+// Gaps are sorted by location, type and feature.
+func (s *SchemaToValueStrategy) FindCapabilityGaps(APIManager *static.APIManager) []*CapabilityGap {
+	finder := &capabilityGapFinder{
+		formatValueGenerator: s.FormatValueGenerator,
+		gaps:                 make(map[CapabilityGap]struct{}),
+	}
+	for _, unsupportedOperation := range APIManager.UnsupportedOperations {
+		finder.add(CapabilityGapTypeUnsupportedOperation, string(unsupportedOperation.Reason), formatCapabilityGapAPIMethod(unsupportedOperation.APIMethod), fmt.Sprintf("%s, the operation is skipped if config skip-unsupported-operations is true", unsupportedOperation.Detail))
+	}
+	for method, operation := range APIManager.APIMap {
+		finder.findInOperation(formatCapabilityGapAPIMethod(method), operation)
+	}
+	gaps := slices.SortedFunc(maps.Keys(finder.gaps), func(a, b CapabilityGap) int {
+		return cmp.Or(
+			cmp.Compare(a.Location, b.Location),
+			cmp.Compare(a.Typ, b.Typ),
+			cmp.Compare(a.Feature, b.Feature),
+		)
+	})
+	res := make([]*CapabilityGap, 0, len(gaps))
+	for i := range gaps {
+		res = append(res, &gaps[i])
+	}
+	return res
+}
+
+// capabilityGapFinder finds capability gaps in operations, deduplicated.
+type capabilityGapFinder struct {
+	// formatValueGenerator tells which string formats are supported.
+	formatValueGenerator *FormatValueGenerator
+
+	// gaps is the set of gaps found.
+	gaps map[CapabilityGap]struct{}
+}
+
+// add adds a gap.
+func (f *capabilityGapFinder) add(typ CapabilityGapType, feature, location, fallback string) {
+	f.gaps[CapabilityGap{Typ: typ, Feature: feature, Location: location, Fallback: fallback}] = struct{}{}
+}
+
+// findInOperation finds gaps in parameters and the request body of the operation, where location is that of the operation.
+func (f *capabilityGapFinder) findInOperation(location string, operation *openapi3.Operation) {
+	if operation == nil {
+		return
+	}
+	idempotencyKeyHeader := static.GetIdempotencyKeyHeader(operation)
+	for _, param := range operation.Parameters {
+		if param == nil || param.Value == nil {
+			continue
+		}
+		paramLocation := fmt.Sprintf("%s %s param %s", location, param.Value.In, param.Value.Name)
+		// Values are generated for path and query params only, see [resttracefuzzer/pkg/casemanager.CaseManager.PopAndPopulate].
+		if param.Value.In != openapi3.ParameterInPath && param.Value.In != openapi3.ParameterInQuery {
+			if param.Value.In == openapi3.ParameterInHeader && idempotencyKeyHeader != "" && strings.EqualFold(param.Value.Name, idempotencyKeyHeader) {
+				continue
+			}
+			f.add(CapabilityGapTypeParamLocation, param.Value.In, paramLocation, "not sent, unless set by extra headers")
+			continue
+		}
+		paramSchema := utils.GetParameterSchema(param.Value)
+		if paramSchema == nil {
+			f.add(CapabilityGapTypeMissingSchema, "schema", paramLocation, "best-effort values are generated")
+			continue
+		}
+		f.findInSchema(paramLocation+" ", "$", paramSchema.Value, make(map[*openapi3.Schema]struct{}))
+	}
+
+	if operation.RequestBody == nil || operation.RequestBody.Value == nil {
+		return
+	}
+	bodyLocation := location + " request body"
+	content := operation.RequestBody.Value.Content
+	if len(content) == 0 {
+		f.add(CapabilityGapTypeMissingSchema, "media type", bodyLocation, "no media type is declared, best-effort JSON values are generated")
+		return
+	}
+	hasJSON := false
+	for mediaType := range content {
+		if strings.Contains(strings.ToLower(mediaType), "json") {
+			hasJSON = true
+		}
+	}
+	if hasJSON {
+		for mediaType := range content {
+			if !strings.Contains(strings.ToLower(mediaType), "json") {
+				f.add(CapabilityGapTypeMediaType, mediaType, bodyLocation, "only JSON bodies are generated")
+			}
+		}
+	}
+	bodySchema := utils.GetRequestBodySchema(operation.RequestBody)
+	if bodySchema == nil {
+		f.add(CapabilityGapTypeMissingSchema, "schema", bodyLocation, "best-effort values are generated")
+		return
+	}
+	f.findInSchema(bodyLocation+" ", "$", bodySchema.Value, make(map[*openapi3.Schema]struct{}))
+}
+
+// findInSchema finds gaps in the schema and its properties and items recursively, where location is prefixed to the JSON path of the schema.
+// Visited schemas are skipped, as schemas can be recursive.
+func (f *capabilityGapFinder) findInSchema(location, path string, schema *openapi3.Schema, visited map[*openapi3.Schema]struct{}) {
+	if schema == nil {
+		return
+	}
+	if _, exist := visited[schema]; exist {
+		return
+	}
+	visited[schema] = struct{}{}
+	schemaLocation := location + path
+
+	// Composition keywords are resolved in value generation (see [resolveCompositeSchema]), so only gaps in their subschemas are found.
+	hasComposition := isCompositeSchema(schema)
+	for _, subschemas := range []openapi3.SchemaRefs{schema.AllOf, schema.OneOf, schema.AnyOf} {
+		for _, subschema := range subschemas {
+			if subschema != nil {
+				f.findInSchema(location, path, subschema.Value, visited)
+			}
+		}
+	}
+	if schema.Not != nil {
+		f.add(CapabilityGapTypeSchemaKeyword, "not", schemaLocation, ignoredSchemaKeywordFallback)
+	}
+	if schema.AdditionalProperties.Schema != nil {
+		f.add(CapabilityGapTypeSchemaKeyword, "additionalProperties", schemaLocation, "additional properties are never generated")
+	}
+
+	switch {
+	case schema.Type.Includes(openapi3.TypeObject):
+		for _, propName := range slices.Sorted(maps.Keys(schema.Properties)) {
+			if propSchema := schema.Properties[propName]; propSchema != nil {
+				f.findInSchema(location, path+"."+propName, propSchema.Value, visited)
+			}
+		}
+	case schema.Type.Includes(openapi3.TypeArray):
+		if schema.Items != nil {
+			f.findInSchema(location, path+"[]", schema.Items.Value, visited)
+		}
+	case utils.PrimitiveSchemaType2ReflectKind(schema.Type) == 0:
+		// An untyped schema with composition keywords is typed by its subschemas.
+		if !hasComposition {
+			f.add(CapabilityGapTypeSchemaType, formatCapabilityGapSchemaType(schema.Type), schemaLocation, "a null value is generated")
+		}
+	case schema.Type.Includes(openapi3.TypeString):
+		// Pattern and enum take precedence over format, see [SchemaToValueStrategy.constrainPrimitiveValue].
+		if schema.Format != "" && schema.Pattern == "" && len(schema.Enum) == 0 && !f.formatValueGenerator.IsSupported(schema.Format) {
+			f.add(CapabilityGapTypeStringFormat, schema.Format, schemaLocation, "random strings are generated, regardless of the format")
+		}
+	}
+}
+
+// formatCapabilityGapAPIMethod formats the API method as `{method} {endpoint}`.
+func formatCapabilityGapAPIMethod(method static.SimpleAPIMethod) string {
+	return method.Method + " " + method.Endpoint
+}
+
+// formatCapabilityGapSchemaType formats the types of a schema without a known type, e.g., `untyped` or `null`.
+func formatCapabilityGapSchemaType(types *openapi3.Types) string {
+	if types == nil || len(*types) == 0 {
+		return "untyped"
+	}
+	return strings.Join(*types, ", ")
+}
@@ -0,0 +1,308 @@
+package strategy
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"resttracefuzzer/pkg/utils"
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/rs/zerolog/log"
+)
+
+// constraintRangeSpan is the span of the generated range of a number, if only one of its bounds is declared.
+const constraintRangeSpan = 114514
+
+// applySchemaConstraints adjusts a primitive value to satisfy the constraints declared in the schema, including:
+//   - enum: pick one of the enum values.
+//   - minimum, maximum, exclusiveMinimum, exclusiveMaximum and multipleOf of numbers.
+//   - minLength, maxLength and pattern of strings.
+//
+// If the schema declares no constraints, or the value is not primitive, the value is returned unchanged.
+func applySchemaConstraints(schema *openapi3.Schema, value any) any {
+	if len(schema.Enum) > 0 {
+		enumValue := schema.Enum[utils.SharedRand.IntN(len(schema.Enum))]
+		// Numbers in API docs are parsed as float64
+		if f, ok := enumValue.(float64); ok && schema.Type.Includes(openapi3.TypeInteger) {
+			return clampFloat64ToInt64(f)
+		}
+		return enumValue
+	}
+	switch v := value.(type) {
+	case int64:
+		lo, hi, bounded := getIntegerBounds(schema)
+		if !bounded {
+			return v
+		}
+		if lo > hi {
+			log.Warn().Msgf("[applySchemaConstraints] Invalid integer bounds [%d, %d], ignored", lo, hi)
+			return v
+		}
+		res := randInt64InRange(lo, hi)
+		if schema.MultipleOf != nil && *schema.MultipleOf >= 1 {
+			multipleOf := math.Floor(*schema.MultipleOf)
+			// Round up to the nearest multiple, and fall back to rounding down if it is out of bounds.
+			// Rounding is done in float space, so that multiples near the int64 bounds do not overflow.
+			rounded := math.Ceil(float64(res)/multipleOf) * multipleOf
+			if rounded > float64(hi) {
+				rounded -= multipleOf
+			}
+			if rounded >= float64(lo) {
+				res = min(max(clampFloat64ToInt64(rounded), lo), hi)
+			}
+		}
+		return res
+	case float64:
+		lo, hi, bounded := getNumberBounds(schema)
+		if !bounded {
+			return v
+		}
+		if lo > hi {
+			log.Warn().Msgf("[applySchemaConstraints] Invalid number bounds [%f, %f], ignored", lo, hi)
+			return v
+		}
+		return lo + utils.SharedRand.Float64()*(hi-lo)
+	case string:
+		if schema.Pattern != "" {
+			generated, err := utils.GenerateStringForPattern(schema.Pattern)
+			if err != nil {
+				log.Warn().Err(err).Msgf("[applySchemaConstraints] Failed to generate string for pattern: %s", schema.Pattern)
+			} else {
+				v = generated
+			}
+		}
+		return fitStringLength(v, schema.MinLength, schema.MaxLength)
+	default:
+		return value
+	}
+}
+
+// generateConstraintViolatingValue generates a primitive value which deliberately violates one of the constraints declared in the schema, for negative testing.
+// The violated constraint is chosen randomly from the declared ones.
+// It returns false if the schema declares no constraints that can be violated.
+func generateConstraintViolatingValue(schema *openapi3.Schema) (any, bool) {
+	candidates := make([]any, 0)
+	isInteger := schema.Type.Includes(openapi3.TypeInteger)
+	isNumber := schema.Type.Includes(openapi3.TypeNumber)
+	isString := schema.Type.Includes(openapi3.TypeString)
+
+	if len(schema.Enum) > 0 {
+		if value, ok := generateValueOutOfEnum(schema.Enum); ok {
+			candidates = append(candidates, value)
+		}
+	}
+	if isInteger || isNumber {
+		// Bounds themselves are violations if they are exclusive.
+		if schema.Min != nil {
+			candidates = append(candidates, *schema.Min-1)
+		}
+		if schema.ExclusiveMin.Value != nil {
+			candidates = append(candidates, *schema.ExclusiveMin.Value)
+		}
+		if schema.Max != nil {
+			candidates = append(candidates, *schema.Max+1)
+		}
+		if schema.ExclusiveMax.Value != nil {
+			candidates = append(candidates, *schema.ExclusiveMax.Value)
+		}
+		if isInteger {
+			// Convert to integers, as floats would violate the type as well.
+			for i, candidate := range candidates {
+				if f, ok := candidate.(float64); ok {
+					candidates[i] = clampFloat64ToInt64(math.Round(f))
+				}
+			}
+		}
+		if isInteger && schema.MultipleOf != nil && *schema.MultipleOf > 1 {
+			candidates = append(candidates, int64(*schema.MultipleOf)+1)
+		}
+	}
+	if isString {
+		if schema.MinLength > 0 {
+			candidates = append(candidates, utils.RandStringBytes(int(min(schema.MinLength-1, uint64(MaxStringLength)))))
+		}
+		// Strings longer than MaxStringLength are not generated, so a larger maxLength cannot be violated.
+		if schema.MaxLength != nil && *schema.MaxLength < uint64(MaxStringLength) {
+			candidates = append(candidates, utils.RandStringBytes(int(*schema.MaxLength+1)))
+		}
+		if schema.Pattern != "" {
+			if value, ok := generateStringNotMatchingPattern(schema.Pattern); ok {
+				candidates = append(candidates, value)
+			}
+		}
+		// Formats like `password` and `binary` accept any string, so they cannot be violated.
+		if schema.Format != "" && schema.Format != "password" && schema.Format != "binary" {
+			candidates = append(candidates, "not-a-"+strings.ReplaceAll(schema.Format, "-", "_")+"!")
+		}
+	}
+	if len(candidates) == 0 {
+		return nil, false
+	}
+	return candidates[utils.SharedRand.IntN(len(candidates))], true
+}
+
+// getIntegerBounds returns the inclusive bounds of an integer schema, and whether any bound is declared.
+func getIntegerBounds(schema *openapi3.Schema) (int64, int64, bool) {
+	lo, hi, bounded := getNumberBounds(schema)
+	if !bounded {
+		return 0, 0, false
+	}
+	// Exclusive bounds of numbers are approximated by tiny offsets; for integers, they are rounded to integers inward.
+	return clampFloat64ToInt64(math.Ceil(lo)), clampFloat64ToInt64(math.Floor(hi)), true
+}
+
+// clampFloat64ToInt64 converts a float64 to int64, clamping it to the int64 range, as the conversion of out-of-range floats is implementation-defined.
+// NaN is converted to 0.
+func clampFloat64ToInt64(f float64) int64 {
+	switch {
+	case math.IsNaN(f):
+		return 0
+	// float64(math.MaxInt64) is 2^63, which is out of range itself.
+	case f >= float64(math.MaxInt64):
+		return math.MaxInt64
+	case f <= float64(math.MinInt64):
+		return math.MinInt64
+	default:
+		return int64(f)
+	}
+}
+
+// randInt64InRange returns a random integer in the inclusive range [lo, hi], which should not be empty.
+// The span is computed in uint64, so that ranges wider than math.MaxInt64 do not overflow.
+func randInt64InRange(lo, hi int64) int64 {
+	span := uint64(hi) - uint64(lo)
+	if span == math.MaxUint64 {
+		// The full int64 range, whose size (2^64) is not representable.
+		return int64(utils.SharedRand.Uint64())
+	}
+	return int64(uint64(lo) + utils.SharedRand.Uint64N(span+1))
+}
+
+// getNumberBounds returns the (approximately) inclusive bounds of a number schema, and whether any bound is declared.
+// If only one bound is declared, the other one is at a distance of [constraintRangeSpan].
+func getNumberBounds(schema *openapi3.Schema) (float64, float64, bool) {
+	var lo, hi *float64
+	if schema.Min != nil {
+		v := *schema.Min
+		if schema.ExclusiveMin.IsTrue() {
+			v = exclusiveBoundOffset(v, true, schema.Type.Includes(openapi3.TypeInteger))
+		}
+		lo = &v
+	}
+	if schema.ExclusiveMin.Value != nil {
+		v := exclusiveBoundOffset(*schema.ExclusiveMin.Value, true, schema.Type.Includes(openapi3.TypeInteger))
+		if lo == nil || v > *lo {
+			lo = &v
+		}
+	}
+	if schema.Max != nil {
+		v := *schema.Max
+		if schema.ExclusiveMax.IsTrue() {
+			v = exclusiveBoundOffset(v, false, schema.Type.Includes(openapi3.TypeInteger))
+		}
+		hi = &v
+	}
+	if schema.ExclusiveMax.Value != nil {
+		v := exclusiveBoundOffset(*schema.ExclusiveMax.Value, false, schema.Type.Includes(openapi3.TypeInteger))
+		if hi == nil || v < *hi {
+			hi = &v
+		}
+	}
+	switch {
+	case lo == nil && hi == nil:
+		return 0, 0, false
+	case lo == nil:
+		return *hi - constraintRangeSpan, *hi, true
+	case hi == nil:
+		return *lo, *lo + constraintRangeSpan, true
+	default:
+		return *lo, *hi, true
+	}
+}
+
+// exclusiveBoundOffset moves an exclusive bound inward, to make it inclusive.
+// Integers are moved by 1, and other numbers by a tiny relative offset.
+func exclusiveBoundOffset(bound float64, isLower, isInteger bool) float64 {
+	offset := 1.0
+	if !isInteger {
+		offset = max(math.Abs(bound)*1e-9, 1e-9)
+	}
+	if isLower {
+		return bound + offset
+	}
+	return bound - offset
+}
+
+// fitStringLength pads (with random letters) or truncates the string to fit the length constraints.
+// maxLength is nil if not declared. Lengths are in characters (i.e., runes), as in JSON schema,
+// and strings are padded to at most [MaxStringLength] characters.
+func fitStringLength(s string, minLength uint64, maxLength *uint64) string {
+	length := uint64(utf8.RuneCountInString(s))
+	if paddedLength := min(minLength, uint64(MaxStringLength)); length < paddedLength {
+		s += utils.RandStringBytes(int(paddedLength - length))
+		length = paddedLength
+	}
+	if maxLength != nil && length > *maxLength {
+		runeCnt := uint64(0)
+		for i := range s {
+			if runeCnt == *maxLength {
+				s = s[:i]
+				break
+			}
+			runeCnt++
+		}
+	}
+	return s
+}
+
+// generateValueOutOfEnum generates a value of the same type as the enum values, but not in the enum.
+func generateValueOutOfEnum(enum []any) (any, bool) {
+	switch sample := enum[utils.SharedRand.IntN(len(enum))].(type) {
+	case string:
+		for range 8 {
+			candidate := sample + "_" + utils.RandStringBytes(4)
+			if !slices.Contains(enum, any(candidate)) {
+				return candidate, true
+			}
+		}
+	case float64:
+		maxValue := sample
+		for _, v := range enum {
+			if f, ok := v.(float64); ok {
+				maxValue = max(maxValue, f)
+			}
+		}
+		return maxValue + 1, true
+	}
+	return nil, false
+}
+
+// generateStringNotMatchingPattern generates a random string which does not match the pattern.
+// It returns false if failed to do so after several attempts.
+func generateStringNotMatchingPattern(pattern string) (string, bool) {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return "", false
+	}
+	// Special characters are more likely to break patterns.
+	candidates := []string{"", " ", "!@#$%^&*()", strings.Repeat("~", 64), utils.RandStringBytes(16)}
+	for _, candidate := range candidates {
+		if !re.MatchString(candidate) {
+			return candidate, true
+		}
+	}
+	return "", false
+}
+
+// isValueSatisfyingSchemaConstraints checks whether a raw value satisfies the schema, for values from e.g. resource pool.
+func isValueSatisfyingSchemaConstraints(schema *openapi3.Schema, value any) bool {
+	err := schema.VisitJSON(value)
+	if err != nil {
+		log.Debug().Msgf("[isValueSatisfyingSchemaConstraints] Value %s does not satisfy schema: %s", fmt.Sprint(value), err)
+		return false
+	}
+	return true
+}
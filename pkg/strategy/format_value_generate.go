@@ -0,0 +1,122 @@
+package strategy
+
+import (
+	"encoding/base64"
+	"fmt"
+	"resttracefuzzer/pkg/utils"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// FormatValueGenerator generates syntactically valid string values for the `format` of string schemas,
+// e.g., uuid, email, date-time, uri and ipv4, so that requests are not trivially rejected by validation of the server.
+// You can register generators of custom formats by [FormatValueGenerator.Register].
+type FormatValueGenerator struct {
+	// generators maps from the format (in lowercase) to its generator.
+	generators map[string]func() string
+}
+
+// NewFormatValueGenerator creates a new FormatValueGenerator with generators of common formats registered.
+func NewFormatValueGenerator() *FormatValueGenerator {
+	g := &FormatValueGenerator{
+		generators: make(map[string]func() string),
+	}
+	g.Register("uuid", generateUUIDFormatValue)
+	g.Register("email", generateEmailFormatValue)
+	g.Register("idn-email", generateEmailFormatValue)
+	g.Register("date-time", generateDateTimeFormatValue)
+	g.Register("date", generateDateFormatValue)
+	g.Register("time", generateTimeFormatValue)
+	g.Register("uri", generateURIFormatValue)
+	g.Register("url", generateURIFormatValue)
+	g.Register("iri", generateURIFormatValue)
+	g.Register("hostname", generateHostnameFormatValue)
+	g.Register("ipv4", generateIPv4FormatValue)
+	g.Register("ipv6", generateIPv6FormatValue)
+	g.Register("byte", generateByteFormatValue)
+	return g
+}
+
+// Register registers a generator of the format, overriding the existing one if any.
+// Formats are case-insensitive.
+func (g *FormatValueGenerator) Register(format string, generator func() string) {
+	g.generators[strings.ToLower(format)] = generator
+}
+
+// Generate generates a value of the format.
+// It returns false if the format is not supported.
+func (g *FormatValueGenerator) Generate(format string) (string, bool) {
+	generator, exists := g.generators[strings.ToLower(format)]
+	if !exists {
+		return "", false
+	}
+	return generator(), true
+}
+
+// IsSupported checks whether the format is supported.
+func (g *FormatValueGenerator) IsSupported(format string) bool {
+	_, exists := g.generators[strings.ToLower(format)]
+	return exists
+}
+
+// generateUUIDFormatValue generates a random (version 4) UUID, e.g., `f47ac10b-58cc-4372-a567-0e02b2c3d479`.
+func generateUUIDFormatValue() string {
+	return uuid.NewString()
+}
+
+// generateEmailFormatValue generates a random email address, e.g., `abcdef@example.com`.
+func generateEmailFormatValue() string {
+	return fmt.Sprintf("%s@%s", strings.ToLower(utils.RandStringBytes(utils.SharedRand.IntN(8)+3)), generateHostnameFormatValue())
+}
+
+// randomTime returns a random time within about 10 years around now, in UTC and at second precision.
+func randomTime() time.Time {
+	offset := time.Duration(utils.SharedRand.Int64N(int64(10*365*24*time.Hour))) - 5*365*24*time.Hour
+	return time.Now().Add(offset).UTC().Truncate(time.Second)
+}
+
+// generateDateTimeFormatValue generates a random date-time in RFC 3339, e.g., `2024-01-02T15:04:05Z`.
+func generateDateTimeFormatValue() string {
+	return randomTime().Format(time.RFC3339)
+}
+
+// generateDateFormatValue generates a random full-date in RFC 3339, e.g., `2024-01-02`.
+func generateDateFormatValue() string {
+	return randomTime().Format(time.DateOnly)
+}
+
+// generateTimeFormatValue generates a random full-time in RFC 3339, e.g., `15:04:05Z`.
+func generateTimeFormatValue() string {
+	return randomTime().Format("15:04:05Z07:00")
+}
+
+// generateURIFormatValue generates a random absolute URI, e.g., `https://abc.example.com/def`.
+func generateURIFormatValue() string {
+	return fmt.Sprintf("https://%s/%s", generateHostnameFormatValue(), strings.ToLower(utils.RandStringBytes(utils.SharedRand.IntN(8)+1)))
+}
+
+// generateHostnameFormatValue generates a random hostname, e.g., `abc.example.com`.
+func generateHostnameFormatValue() string {
+	return fmt.Sprintf("%s.example.com", strings.ToLower(utils.RandStringBytes(utils.SharedRand.IntN(8)+1)))
+}
+
+// generateIPv4FormatValue generates a random IPv4 address in dotted-quad notation, e.g., `192.168.0.1`.
+func generateIPv4FormatValue() string {
+	return fmt.Sprintf("%d.%d.%d.%d", utils.SharedRand.IntN(223)+1, utils.SharedRand.IntN(256), utils.SharedRand.IntN(256), utils.SharedRand.IntN(254)+1)
+}
+
+// generateIPv6FormatValue generates a random IPv6 address in full notation, e.g., `2001:0db8:85a3:0000:0000:8a2e:0370:7334`.
+func generateIPv6FormatValue() string {
+	groups := make([]string, 8)
+	for i := range groups {
+		groups[i] = fmt.Sprintf("%04x", utils.SharedRand.IntN(0x10000))
+	}
+	return strings.Join(groups, ":")
+}
+
+// generateByteFormatValue generates random base64 encoded bytes.
+func generateByteFormatValue() string {
+	return base64.StdEncoding.EncodeToString([]byte(utils.RandStringBytes(utils.SharedRand.IntN(16) + 1)))
+}
@@ -16,15 +16,19 @@ type FuzzStrategist struct {
 }
 
 // NewFuzzStrategist creates a new FuzzStrategist.
+// It returns an error if the value generation strategy fails to be created, see [NewSchemaToValueStrategy].
 func NewFuzzStrategist(
 	resourceManager *resource.ResourceManager,
-) *FuzzStrategist {
-	schemaToValueStrategy := NewSchemaToValueStrategy(resourceManager)
+) (*FuzzStrategist, error) {
+	schemaToValueStrategy, err := NewSchemaToValueStrategy(resourceManager)
+	if err != nil {
+		return nil, err
+	}
 	resourceMutateStrategy := NewResourceMutateStrategy()
 	return &FuzzStrategist{
 		SchemaToValueStrategy: schemaToValueStrategy,
 		ResourceMutateStrategy: resourceMutateStrategy,
-	}
+	}, nil
 }
 
 // GenerateValueForSchema generates a value for a given schema.
@@ -33,6 +37,32 @@ func (s *FuzzStrategist) GenerateValueForSchema(name string, schema *openapi3.Sc
 	return s.SchemaToValueStrategy.GenerateValueForSchema(name, schema)
 }
 
+// GenerateValueWithoutSchema generates a best-effort value for a parameter or request body declaring no schema.
+func (s *FuzzStrategist) GenerateValueWithoutSchema(name string, rawJSONValues []string) (resource.Resource, error) {
+	return s.SchemaToValueStrategy.GenerateValueWithoutSchema(name, rawJSONValues)
+}
+
+// GenerateValueForPathParam generates a route-safe value (or a hostile one, in hostile path value mode) for a path parameter.
+// If schema is nil, the parameter declares no schema.
+func (s *FuzzStrategist) GenerateValueForPathParam(name string, schema *openapi3.SchemaRef) (resource.Resource, error) {
+	return s.SchemaToValueStrategy.GenerateValueForPathParam(name, schema)
+}
+
+// SetNegativeMode sets whether values are generated in negative mode, see [SchemaToValueStrategy.NegativeMode].
+func (s *FuzzStrategist) SetNegativeMode(negativeMode bool) {
+	s.SchemaToValueStrategy.NegativeMode = negativeMode
+}
+
+// SetSchemaRefScope sets the component schema ref values are generated for, see [SchemaToValueStrategy.SchemaRefScope].
+func (s *FuzzStrategist) SetSchemaRefScope(schemaRef string) {
+	s.SchemaToValueStrategy.SchemaRefScope = schemaRef
+}
+
+// SetSchemaPaths sets the schema paths values are generated for, see [SchemaToValueStrategy.SchemaPaths].
+func (s *FuzzStrategist) SetSchemaPaths(schemaPaths ...string) {
+	s.SchemaToValueStrategy.SchemaPaths = schemaPaths
+}
+
 // MutateResource mutates a resource.
 func (s *FuzzStrategist) MutateResource(resource resource.Resource) (resource.Resource, error) {
 	return s.ResourceMutateStrategy.MutateResource(resource)
@@ -0,0 +1,260 @@
+package strategy
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"resttracefuzzer/pkg/llm"
+	"resttracefuzzer/pkg/resource"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bytedance/sonic"
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	// LLMValueCountPerRequest is the number of values requested from the LLM at a time, for each parameter.
+	LLMValueCountPerRequest = 5
+
+	// LLMValueMaxConsecutiveFailures is the max number of consecutive failed requests to the LLM, after which the LLM is not requested any more in the run.
+	LLMValueMaxConsecutiveFailures = 3
+
+	// LLMValueMaxConcurrentRequests is the max number of requests to the LLM in flight at the same time.
+	LLMValueMaxConcurrentRequests = 2
+)
+
+// LLMValueGenerator synthesizes realistic values by an LLM from the name, schema and description of values, used by value source LLM.
+// Values are requested once for each distinct name and schema, and cached in a local file, so that later runs do not request them again.
+// As requests to the LLM are slow and may fail, they are sent in the background (at most [LLMValueMaxConcurrentRequests] at a time), so that value generation is not blocked by them,
+// they are not retried for the same name and schema in a run, and the LLM is not requested any more after [LLMValueMaxConsecutiveFailures] consecutive failures.
+type LLMValueGenerator struct {
+	// Client is the client of the LLM.
+	Client llm.LLMClient
+
+	// Timeout is the timeout of each request to the LLM.
+	Timeout time.Duration
+
+	// CacheFilePath is the path of the local file caching values, or empty if values are cached in memory only.
+	CacheFilePath string
+
+	// Cache is a map from cache keys (see [getLLMValueCacheKey]) to values synthesized, as raw JSON values.
+	Cache map[string][]string
+
+	// failedKeys are cache keys whose requests to the LLM failed in the run.
+	failedKeys map[string]struct{}
+
+	// pendingKeys are cache keys whose requests to the LLM are in flight.
+	pendingKeys map[string]struct{}
+
+	// consecutiveFailures is the number of consecutive failed requests to the LLM.
+	consecutiveFailures int
+
+	// mu protects the states above.
+	mu sync.Mutex
+
+	// requestSlots bounds the number of requests to the LLM in flight.
+	requestSlots chan struct{}
+
+	// pendingWG waits for requests to the LLM in flight.
+	pendingWG sync.WaitGroup
+
+	// saveMu serializes saves of the cache file.
+	saveMu sync.Mutex
+}
+
+// NewLLMValueGenerator creates a new LLMValueGenerator, and loads values cached in the file of cacheFilePath (if it exists).
+func NewLLMValueGenerator(client llm.LLMClient, timeout time.Duration, cacheFilePath string) *LLMValueGenerator {
+	g := &LLMValueGenerator{
+		Client:        client,
+		Timeout:       timeout,
+		CacheFilePath: cacheFilePath,
+		Cache:         make(map[string][]string),
+		failedKeys:    make(map[string]struct{}),
+		pendingKeys:   make(map[string]struct{}),
+		requestSlots:  make(chan struct{}, LLMValueMaxConcurrentRequests),
+	}
+	if cacheFilePath == "" {
+		return g
+	}
+	data, err := os.ReadFile(cacheFilePath)
+	if os.IsNotExist(err) {
+		return g
+	}
+	if err == nil {
+		err = sonic.Unmarshal(data, &g.Cache)
+	}
+	if err != nil {
+		log.Err(err).Msgf("[NewLLMValueGenerator] Failed to load LLM value cache from %s, start with an empty cache", cacheFilePath)
+		g.Cache = make(map[string][]string)
+		return g
+	}
+	log.Info().Msgf("[NewLLMValueGenerator] Loaded LLM values of %d parameters from %s", len(g.Cache), cacheFilePath)
+	return g
+}
+
+// GenerateValues returns the values synthesized by the LLM for the name and schema from the cache.
+// If they are not cached, they are requested from the LLM in the background, and nil is returned, so that other value sources are used until they are synthesized.
+// It returns nil as well if the LLM fails or is disabled after consecutive failures.
+func (g *LLMValueGenerator) GenerateValues(name string, schema *openapi3.Schema) []resource.Resource {
+	key, err := getLLMValueCacheKey(name, schema)
+	if err != nil {
+		log.Err(err).Msgf("[LLMValueGenerator.GenerateValues] Failed to get cache key of %s", name)
+		return nil
+	}
+	g.mu.Lock()
+	rawValues, cached := g.Cache[key]
+	if !cached {
+		g.requestValuesInBackground(key, name, schema)
+		g.mu.Unlock()
+		return nil
+	}
+	g.mu.Unlock()
+
+	values := make([]resource.Resource, 0, len(rawValues))
+	for _, rawValue := range rawValues {
+		value, err := resource.NewResourceFromRawBytes([]byte(rawValue))
+		if err != nil {
+			continue
+		}
+		values = append(values, value)
+	}
+	return values
+}
+
+// Wait waits until all requests to the LLM in flight are done, and their values are cached.
+func (g *LLMValueGenerator) Wait() {
+	g.pendingWG.Wait()
+}
+
+// requestValuesInBackground starts requesting values of the name and schema of the cache key from the LLM, unless they are being requested,
+// failed before, the LLM is disabled, or [LLMValueMaxConcurrentRequests] requests are in flight (they would be requested by later calls then).
+// The caller must hold mu.
+func (g *LLMValueGenerator) requestValuesInBackground(key, name string, schema *openapi3.Schema) {
+	_, pending := g.pendingKeys[key]
+	_, failed := g.failedKeys[key]
+	if pending || failed || g.consecutiveFailures >= LLMValueMaxConsecutiveFailures {
+		return
+	}
+	select {
+	case g.requestSlots <- struct{}{}:
+	default:
+		return
+	}
+	g.pendingKeys[key] = struct{}{}
+	g.pendingWG.Add(1)
+	go func() {
+		defer g.pendingWG.Done()
+		defer func() { <-g.requestSlots }()
+		rawValues, err := g.requestValues(name, schema)
+		g.mu.Lock()
+		delete(g.pendingKeys, key)
+		if err != nil {
+			log.Err(err).Msgf("[LLMValueGenerator.requestValuesInBackground] Failed to request values of %s from the LLM", name)
+			g.failedKeys[key] = struct{}{}
+			g.consecutiveFailures++
+			if g.consecutiveFailures == LLMValueMaxConsecutiveFailures {
+				log.Warn().Msgf("[LLMValueGenerator.requestValuesInBackground] %d consecutive requests to the LLM failed, the LLM is not requested any more", g.consecutiveFailures)
+			}
+			g.mu.Unlock()
+			return
+		}
+		g.consecutiveFailures = 0
+		g.Cache[key] = rawValues
+		g.mu.Unlock()
+		g.saveCache()
+	}()
+}
+
+// requestValues requests [LLMValueCountPerRequest] values for the name and schema from the LLM, and returns them as raw JSON values.
+func (g *LLMValueGenerator) requestValues(name string, schema *openapi3.Schema) ([]string, error) {
+	schemaBytes, err := sonic.Marshal(schema)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal schema: %w", err)
+	}
+	prompt := fmt.Sprintf(
+		"You are generating test inputs for a REST API. Generate %d distinct, realistic values for the value named %q, which conform to the JSON schema below.\n"+
+			"Schema: %s\nDescription: %s\n"+
+			"Respond with a JSON array of the values only, without any explanation.",
+		LLMValueCountPerRequest, name, string(schemaBytes), schema.Description,
+	)
+	ctx, cancel := context.WithTimeout(context.Background(), g.Timeout)
+	defer cancel()
+	completion, err := g.Client.ChatCompletion(ctx, prompt)
+	if err != nil {
+		return nil, err
+	}
+
+	// LLMs may wrap the array in code blocks or text, so only the outermost array is parsed.
+	start, end := strings.Index(completion, "["), strings.LastIndex(completion, "]")
+	if start < 0 || end < start {
+		return nil, fmt.Errorf("no JSON array in completion: %s", completion)
+	}
+	var values []any
+	if err := sonic.UnmarshalString(completion[start:end+1], &values); err != nil {
+		return nil, fmt.Errorf("failed to parse completion: %w", err)
+	}
+	rawValues := make([]string, 0, len(values))
+	for _, value := range values {
+		if value == nil {
+			continue
+		}
+		rawValue, err := sonic.MarshalString(value)
+		if err != nil {
+			continue
+		}
+		rawValues = append(rawValues, rawValue)
+	}
+	if len(rawValues) == 0 {
+		return nil, fmt.Errorf("no value in completion: %s", completion)
+	}
+	log.Debug().Msgf("[LLMValueGenerator.requestValues] Synthesized %d values of %s by the LLM", len(rawValues), name)
+	return rawValues, nil
+}
+
+// saveCache saves the cache to CacheFilePath, if it is set.
+// It writes to a temporary file first and renames it, so that an interrupted save does not corrupt the existing cache.
+func (g *LLMValueGenerator) saveCache() {
+	if g.CacheFilePath == "" {
+		return
+	}
+	g.saveMu.Lock()
+	defer g.saveMu.Unlock()
+	g.mu.Lock()
+	data, err := sonic.Marshal(g.Cache)
+	g.mu.Unlock()
+	if err != nil {
+		log.Err(err).Msgf("[LLMValueGenerator.saveCache] Failed to marshal LLM value cache")
+		return
+	}
+	if err := os.MkdirAll(filepath.Dir(g.CacheFilePath), os.ModePerm); err != nil {
+		log.Err(err).Msgf("[LLMValueGenerator.saveCache] Failed to create directory of %s", g.CacheFilePath)
+		return
+	}
+	tmpPath := g.CacheFilePath + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0644); err != nil {
+		log.Err(err).Msgf("[LLMValueGenerator.saveCache] Failed to write LLM value cache: %s", tmpPath)
+		return
+	}
+	if err := os.Rename(tmpPath, g.CacheFilePath); err != nil {
+		log.Err(err).Msgf("[LLMValueGenerator.saveCache] Failed to rename LLM value cache: %s", tmpPath)
+	}
+}
+
+// getLLMValueCacheKey returns the cache key of values of the name and schema, i.e., the hash of them.
+func getLLMValueCacheKey(name string, schema *openapi3.Schema) (string, error) {
+	schemaBytes, err := sonic.Marshal(schema)
+	if err != nil {
+		return "", err
+	}
+	hash := sha256.New()
+	hash.Write([]byte(name))
+	hash.Write([]byte{0})
+	hash.Write(schemaBytes)
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
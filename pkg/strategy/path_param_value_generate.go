@@ -0,0 +1,86 @@
+package strategy
+
+import (
+	"resttracefuzzer/pkg/resource"
+	"resttracefuzzer/pkg/static"
+	"resttracefuzzer/pkg/utils/http"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/rs/zerolog/log"
+)
+
+// pathParamRouteSafeMaxAttempts is the max number of attempts to generate a route-safe path parameter value, before sanitizing it.
+const pathParamRouteSafeMaxAttempts = 3
+
+// HostilePathValues are path parameter values which break routing even after escaping, used by hostile path value mode for negative testing.
+// They test whether the server handles the values explicitly, instead of routing the request to another endpoint or crashing.
+var HostilePathValues = []string{
+	"",
+	".",
+	"..",
+	"/",
+	"a/b",
+	"../",
+	"..%2F",
+	"%2F",
+	"%00",
+	"\\",
+	"?",
+	"#",
+	"\n",
+}
+
+// GenerateValueForPathParam generates a resource value for a path parameter.
+// Unlike [SchemaToValueStrategy.GenerateValueForSchema], generated values are always non-empty and route-safe (see [http.IsRouteSafePathParamValue]):
+// a value is regenerated if unsafe, and sanitized after several attempts.
+// By chance of HostilePathPercent, it returns a value of [HostilePathValues] instead, i.e., hostile path value mode.
+// If schema is nil, i.e., the parameter declares no schema, values are generated by [SchemaToValueStrategy.GenerateValueWithoutSchema].
+func (s *SchemaToValueStrategy) GenerateValueForPathParam(name string, schema *openapi3.SchemaRef) (resource.Resource, error) {
+	if s.Rand.IntN(100) < s.HostilePathPercent {
+		hostileValue := HostilePathValues[s.Rand.IntN(len(HostilePathValues))]
+		log.Debug().Msgf("[SchemaToValueStrategy.GenerateValueForPathParam] Use hostile path value %q for %s", hostileValue, name)
+		return resource.NewResourceString(hostileValue), nil
+	}
+	var (
+		value resource.Resource
+		err   error
+	)
+	for range pathParamRouteSafeMaxAttempts {
+		if schema != nil {
+			value, err = s.GenerateValueForSchema(name, schema)
+		} else {
+			value, err = s.GenerateValueWithoutSchema(name, SchemaLessParamRawJSONValues)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if IsRouteSafePathParamResource(value) {
+			return value, nil
+		}
+	}
+	sanitizedValue := http.SanitizePathParamValue(PathParamResourceString(value))
+	log.Debug().Msgf("[SchemaToValueStrategy.GenerateValueForPathParam] Generated path value %q for %s is not route-safe, sanitized to %q", PathParamResourceString(value), name, sanitizedValue)
+	return resource.NewResourceString(sanitizedValue), nil
+}
+
+// IsRouteSafePathParamResource checks whether a resource is route-safe as a path parameter value, see [http.IsRouteSafePathParamValue].
+func IsRouteSafePathParamResource(resrc resource.Resource) bool {
+	return resrc != nil && http.IsRouteSafePathParamValue(PathParamResourceString(resrc))
+}
+
+// PathParamResourceString returns the string of a resource as a path parameter value.
+// Arrays are joined by commas (e.g., "1,2,3") instead of JSON-style, i.e., `simple` style of OpenAPI.
+func PathParamResourceString(resrc resource.Resource) string {
+	if resrc == nil {
+		return ""
+	}
+	if resrc.Typ() == static.SimpleAPIPropertyTypeArray {
+		valueList := make([]string, 0)
+		for _, v := range resrc.(*resource.ResourceArray).Value {
+			valueList = append(valueList, v.String())
+		}
+		return strings.Join(valueList, ",")
+	}
+	return resrc.String()
+}
@@ -2,11 +2,17 @@ package strategy
 
 import (
 	"fmt"
+	"maps"
 	"math/rand/v2"
 	"reflect"
+	"resttracefuzzer/internal/config"
 	"resttracefuzzer/pkg/resource"
 	"resttracefuzzer/pkg/static"
 	"resttracefuzzer/pkg/utils"
+	"slices"
+	"strconv"
+
+	"github.com/rs/zerolog/log"
 )
 
 const (
@@ -28,43 +34,101 @@ const (
 
 	// NoMutationPlan is the key for no mutation plan, i.e., do not mutate.
 	NoMutationPlan = "NONE"
+
+	// StructureMutationRemoveField is the key for structure mutation which removes a field.
+	StructureMutationRemoveField = "REMOVE_FIELD"
+
+	// StructureMutationDuplicateField is the key for structure mutation which duplicates a field under a new name.
+	StructureMutationDuplicateField = "DUPLICATE_FIELD"
+
+	// StructureMutationInjectNull is the key for structure mutation which sets a field to null.
+	StructureMutationInjectNull = "INJECT_NULL"
+
+	// StructureMutationSwapType is the key for structure mutation which changes the type of a field, e.g., from integer to string.
+	StructureMutationSwapType = "SWAP_TYPE"
+
+	// StructureMutationAddExtraField is the key for structure mutation which adds a field not declared in schema.
+	StructureMutationAddExtraField = "ADD_EXTRA_FIELD"
 )
 
 // ResourceMutateStrategy is a strategy for mutating resources.
-// We plan to apply 2 types of mutation:
-//   - Mutation of resource value
-//   - Mutation of resource structure TODO @xunzhou24
+// We apply 2 types of mutation:
+//   - Mutation of resource value, for primitive resources.
+//   - Mutation of resource structure, for object resources, i.e., field removal, field duplication, null injection, type swapping and extra-field addition.
 type ResourceMutateStrategy struct {
 
 	// MutationPlanWeightMap is the weight map for different mutation plans.
 	// It determines whether to mutate, which type of mutation to apply.
 	// It must have 4 keys (RANDOM, STRUCTURE, EDGE_CASE, NONE) with non-negative integer weights.
 	MutationPlanWeightMap WeightMapStrategy
+
+	// StructureMutationWeightMap is the weight map for different structure mutations, used when structure mutation plan is chosen.
+	// It must have 5 keys (REMOVE_FIELD, DUPLICATE_FIELD, INJECT_NULL, SWAP_TYPE, ADD_EXTRA_FIELD) with non-negative integer weights.
+	StructureMutationWeightMap WeightMapStrategy
+
+	// Rand is the random number generator for mutation, which is [utils.SharedRand] by default.
+	Rand *rand.Rand
 }
 
 // NewResourceMutateStrategy creates a new ResourceMutateStrategy.
-// By default we use constant weight value, and the weight of random mutation, structure mutation, and no mutation are 1, 0, 3, respectively.
+// By default we use constant weight value, and the weight of random mutation, edge case mutation, and no mutation are 1, 1, 3, respectively.
+// The weight of structure mutation is set by config `mutation-plan-structure-weight` (1 by default).
 // If you do not want to apply structure mutation, you can set its weight to 0.
-// TODO: initialize the weight map from configuration. @xunzhou24
+// Config `mutation-plan-weights` overrides weights of mutation plans (see [resolveMutationPlanWeights]).
+// Weights of mutation plans adapt to coverage feedback if config `weight-map-strategy` is `adaptive` (see [AdaptiveWeightMapStrategy]).
+// All structure mutations are of equal weight.
 func NewResourceMutateStrategy() *ResourceMutateStrategy {
+	mutationPlanWeights, err := resolveMutationPlanWeights()
+	if err != nil {
+		log.Err(err).Msgf("[ResourceMutateStrategy.NewResourceMutateStrategy] Invalid mutation plan weight configuration, used default weights instead")
+		mutationPlanWeights = map[string]int{
+			MutationPlanRandom:    1,
+			MutationPlanEdgeCase:  1,
+			MutationPlanStructure: 1,
+			NoMutationPlan:        3,
+		}
+	}
+	mutationPlanWeightMap, ok := newWeightMapStrategy(config.GlobalConfig.WeightMapStrategy, mutationPlanWeights)
+	if !ok {
+		log.Error().Msgf("[ResourceMutateStrategy.NewResourceMutateStrategy] Unknown weight map strategy: %s, used constant weights instead", config.GlobalConfig.WeightMapStrategy)
+	}
 	return &ResourceMutateStrategy{
-		MutationPlanWeightMap: NewConstantWeightMapStrategy(
+		MutationPlanWeightMap: mutationPlanWeightMap,
+		StructureMutationWeightMap: NewConstantWeightMapStrategy(
 			map[string]int{
-				MutationPlanRandom:    1,
-				MutationPlanEdgeCase:  1,
-				MutationPlanStructure: 0,
-				NoMutationPlan:        3,
+				StructureMutationRemoveField:    1,
+				StructureMutationDuplicateField: 1,
+				StructureMutationInjectNull:     1,
+				StructureMutationSwapType:       1,
+				StructureMutationAddExtraField:  1,
 			},
 		),
+		Rand: utils.SharedRand,
 	}
 }
 
+// resolveMutationPlanWeights resolves weights of mutation plans from configuration.
+// The default weights (see [NewResourceMutateStrategy]) are overridden by config `mutation-plan-weights`, a stringified JSON object from mutation plans to weights,
+// e.g., `{"EDGE_CASE": 2, "NONE": 1}`.
+func resolveMutationPlanWeights() (map[string]int, error) {
+	return ParseWeightMapOverrides(config.GlobalConfig.MutationPlanWeights, map[string]int{
+		MutationPlanRandom:    1,
+		MutationPlanEdgeCase:  1,
+		MutationPlanStructure: config.GlobalConfig.MutationPlanStructureWeight,
+		NoMutationPlan:        3,
+	})
+}
+
 // MutateResource mutates a resource.
 // It is the entry of the mutation process.
 // We will apply different mutation strategies based applied strategies.
 // The method will return the mutated resource, and error if any.
 // Note that the parameter resource will be mutated in place (the returned resource is the same as the parameter).
 func (s *ResourceMutateStrategy) MutateResource(resrc resource.Resource) (resource.Resource, error) {
+	// Null (e.g., injected by structure mutation) is an empty resource of unknown type, and we do not mutate it.
+	if _, isEmpty := resrc.(*resource.ResourceEmpty); isEmpty {
+		return resrc, nil
+	}
 	switch resrc.Typ() {
 	case static.SimpleAPIPropertyTypeObject:
 		return s.mutateObjectResource(resrc)
@@ -198,12 +262,81 @@ func (s *ResourceMutateStrategy) mutatePrimitiveResourceByEdgeCase(resrc resourc
 }
 
 // mutateObjectResourceStructure mutates the structure of an object resource.
-// It will change the structure of the object resource, e.g., add or remove fields.
+// It applies one of the structure mutations, chosen by StructureMutationWeightMap:
+//   - REMOVE_FIELD: remove a random field.
+//   - DUPLICATE_FIELD: copy the value of a random field to a new field named `{field}_copy`, as JSON objects of the resource cannot hold duplicate keys.
+//   - INJECT_NULL: set a random field to null.
+//   - SWAP_TYPE: change the type of a random field, see [swapResourceType].
+//   - ADD_EXTRA_FIELD: add a field with random name and value.
+//
+// For an empty object, only ADD_EXTRA_FIELD is applicable.
+// The object resource is mutated in place.
 func (s *ResourceMutateStrategy) mutateObjectResourceStructure(resrc resource.Resource) (resource.Resource, error) {
-	// TODO: implement the method. @xunzhou24
-	return nil, fmt.Errorf("not implemented")
+	objectResrc, ok := resrc.(*resource.ResourceObject)
+	if !ok {
+		return nil, fmt.Errorf("invalid object resource")
+	}
+	object := objectResrc.Value
+
+	mutation := StructureMutationAddExtraField
+	if len(object) > 0 {
+		mutation = decideByWeightMap(s.StructureMutationWeightMap, StructureMutationAddExtraField)
+	}
+	// Sort keys, so that the chosen field is decided by random number only.
+	keys := slices.Sorted(maps.Keys(object))
+	var key string
+	if len(keys) > 0 {
+		key = keys[s.Rand.IntN(len(keys))]
+	}
+
+	switch mutation {
+	case StructureMutationRemoveField:
+		delete(object, key)
+	case StructureMutationDuplicateField:
+		object[key+"_copy"] = object[key].Copy()
+	case StructureMutationInjectNull:
+		object[key] = resource.NewResourceEmpty()
+	case StructureMutationSwapType:
+		object[key] = swapResourceType(object[key])
+	case StructureMutationAddExtraField:
+		object["extra_"+utils.RandStringBytes(6)] = resource.NewResourceString(utils.RandStringBytes(s.Rand.IntN(16) + 1))
+	default:
+		return nil, fmt.Errorf("unsupported structure mutation: %v", mutation)
+	}
+	log.Debug().Msgf("[ResourceMutateStrategy.mutateObjectResourceStructure] Applied structure mutation %s on field %s", mutation, key)
+	return objectResrc, nil
 }
 
+// swapResourceType returns a resource of a different type, converted from the given one where possible:
+//   - integer, float and boolean are converted to their string representations.
+//   - string is converted to an integer if it is numeric, otherwise to a boolean.
+//   - object is wrapped into an array.
+//   - array is converted to its first element, or an empty object if empty.
+//   - null is converted to an empty string.
+func swapResourceType(resrc resource.Resource) resource.Resource {
+	if _, isEmpty := resrc.(*resource.ResourceEmpty); isEmpty {
+		return resource.NewResourceString("")
+	}
+	switch resrc.Typ() {
+	case static.SimpleAPIPropertyTypeInteger, static.SimpleAPIPropertyTypeFloat, static.SimpleAPIPropertyTypeBoolean:
+		return resource.NewResourceString(resrc.String())
+	case static.SimpleAPIPropertyTypeString:
+		if i, err := strconv.ParseInt(resrc.String(), 10, 64); err == nil {
+			return resource.NewResourceInteger(i)
+		}
+		return resource.NewResourceBoolean(resrc.String() != "")
+	case static.SimpleAPIPropertyTypeObject:
+		return resource.NewResourceArray([]resource.Resource{resrc})
+	case static.SimpleAPIPropertyTypeArray:
+		array := resrc.(*resource.ResourceArray).Value
+		if len(array) > 0 {
+			return array[0]
+		}
+		return resource.NewResourceObject(make(map[string]resource.Resource))
+	default:
+		return resource.NewResourceEmpty()
+	}
+}
 
 // precheckAndTryApplyMutationPlan prechecks the resource and tries to apply the mutation plan.
 // It returns:
@@ -259,20 +392,32 @@ func (s *ResourceMutateStrategy) precheckAndTryApplyMutationPlan(resrc resource.
 
 // decideMutationPlan decides the mutation plan based on the weight map.
 func (s *ResourceMutateStrategy) decideMutationPlan() string {
+	return decideByWeightMap(s.MutationPlanWeightMap, NoMutationPlan)
+}
+
+// decideByWeightMap chooses a key from the weight map randomly, with probability proportional to its weight.
+// It returns the fallback if all weights are 0.
+func decideByWeightMap(weightMap WeightMapStrategy, fallback string) string {
+	weights := weightMap.GetMapWithParam(WEIGHT_MAP_STRATEGY_PARAM_PLACEHOLDER)
 	totalWeight := 0
-	for _, weight := range s.MutationPlanWeightMap.GetMapWithParam(WEIGHT_MAP_STRATEGY_PARAM_PLACEHOLDER) {
+	for _, weight := range weights {
 		totalWeight += weight
 	}
+	if totalWeight <= 0 {
+		return fallback
+	}
 
-	randomNumber := rand.IntN(totalWeight)
+	randomNumber := utils.SharedRand.IntN(totalWeight)
 	cumulativeWeight := 0
-	for source, weight := range s.MutationPlanWeightMap.GetMapWithParam(WEIGHT_MAP_STRATEGY_PARAM_PLACEHOLDER) {
-		cumulativeWeight += weight
+	// Sort keys, so that the chosen key is decided by random number only (see [utils.SeedSharedRand]).
+	for _, key := range slices.Sorted(maps.Keys(weights)) {
+		cumulativeWeight += weights[key]
 		if randomNumber < cumulativeWeight {
-			return source
+			recordWeightMapSelection(weightMap, key)
+			return key
 		}
 	}
 
-	// As a fallback, return no mutation plan. This line should normally never be reached.
-	return NoMutationPlan
+	// As a fallback, return the fallback key. This line should normally never be reached.
+	return fallback
 }
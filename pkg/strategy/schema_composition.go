@@ -0,0 +1,170 @@
+package strategy
+
+import (
+	"maps"
+	"math/rand/v2"
+	"slices"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+// maxSchemaCompositionDepth is the max depth of nested composition keywords resolved, as schemas can be recursive.
+const maxSchemaCompositionDepth = 8
+
+// isCompositeSchema checks whether the schema declares any of the composition keywords oneOf, anyOf and allOf.
+func isCompositeSchema(schema *openapi3.Schema) bool {
+	return schema != nil && (len(schema.OneOf) > 0 || len(schema.AnyOf) > 0 || len(schema.AllOf) > 0)
+}
+
+// isNullableSchema checks whether the schema allows null, i.e., it is `nullable` (OpenAPI 3.0), or its type includes `null` (OpenAPI 3.1).
+func isNullableSchema(schema *openapi3.Schema) bool {
+	return schema != nil && (schema.Nullable || schema.Type.IncludesNull())
+}
+
+// resolveCompositeSchema resolves the composition keywords of the schema into a plain schema values can be generated from:
+//   - subschemas of allOf are all merged into the schema;
+//   - one random branch of oneOf, and one of anyOf, is merged into the schema, and the discriminator property (if any) is set to the value of the branch.
+//
+// Branches of type `null` only are not selected, and make the resolved schema nullable instead.
+// Keywords of the schema itself take precedence over those merged, and the resolved schema is object (or array) typed, if it is untyped but declares properties (or items).
+// The ref of the resolved schema is that of the schema, or that of the selected branch if the schema is inline, so that values are scoped to it.
+// The schema is not modified, and it is returned as is if it is not composite.
+func resolveCompositeSchema(schema *openapi3.SchemaRef, r *rand.Rand) *openapi3.SchemaRef {
+	return resolveCompositeSchemaWithDepth(schema, r, 0)
+}
+
+// resolveCompositeSchemaWithDepth resolves the composition keywords like [resolveCompositeSchema], where depth is the depth of nested composition keywords.
+func resolveCompositeSchemaWithDepth(schema *openapi3.SchemaRef, r *rand.Rand, depth int) *openapi3.SchemaRef {
+	if schema == nil || !isCompositeSchema(schema.Value) || depth >= maxSchemaCompositionDepth {
+		return schema
+	}
+	resolved := *schema.Value
+	resolved.OneOf, resolved.AnyOf, resolved.AllOf = nil, nil, nil
+	resolved.Properties = maps.Clone(schema.Value.Properties)
+	resolved.Required = slices.Clone(schema.Value.Required)
+	resolvedRef := schema.Ref
+
+	for _, subschema := range schema.Value.AllOf {
+		if subschema == nil || subschema.Value == nil {
+			continue
+		}
+		mergeSchemaInto(&resolved, resolveCompositeSchemaWithDepth(subschema, r, depth+1).Value)
+	}
+	for _, branches := range []openapi3.SchemaRefs{schema.Value.OneOf, schema.Value.AnyOf} {
+		candidates := make(openapi3.SchemaRefs, 0, len(branches))
+		for _, branch := range branches {
+			if branch == nil || branch.Value == nil {
+				continue
+			}
+			if branch.Value.Type.Is(openapi3.TypeNull) {
+				resolved.Nullable = true
+				continue
+			}
+			candidates = append(candidates, branch)
+		}
+		if len(candidates) == 0 {
+			continue
+		}
+		branch := candidates[r.IntN(len(candidates))]
+		resolvedBranch := resolveCompositeSchemaWithDepth(branch, r, depth+1)
+		mergeSchemaInto(&resolved, resolvedBranch.Value)
+		resolved.Nullable = resolved.Nullable || isNullableSchema(resolvedBranch.Value)
+		setDiscriminatorValue(&resolved, branch.Ref)
+		if resolvedRef == "" {
+			resolvedRef = resolvedBranch.Ref
+		}
+	}
+
+	if resolved.Type == nil || len(*resolved.Type) == 0 {
+		switch {
+		case len(resolved.Properties) > 0:
+			resolved.Type = &openapi3.Types{openapi3.TypeObject}
+		case resolved.Items != nil:
+			resolved.Type = &openapi3.Types{openapi3.TypeArray}
+		}
+	}
+	return &openapi3.SchemaRef{Ref: resolvedRef, Value: &resolved}
+}
+
+// mergeSchemaInto merges the keywords used in value generation of src into dst, where keywords already declared by dst are kept.
+// Properties and required properties are merged as unions.
+func mergeSchemaInto(dst, src *openapi3.Schema) {
+	if src == nil {
+		return
+	}
+	if dst.Type == nil || len(*dst.Type) == 0 {
+		dst.Type = src.Type
+	}
+	if len(src.Properties) > 0 && dst.Properties == nil {
+		dst.Properties = make(openapi3.Schemas)
+	}
+	for propName, propSchema := range src.Properties {
+		if _, exist := dst.Properties[propName]; !exist {
+			dst.Properties[propName] = propSchema
+		}
+	}
+	for _, propName := range src.Required {
+		if !slices.Contains(dst.Required, propName) {
+			dst.Required = append(dst.Required, propName)
+		}
+	}
+	if dst.Items == nil {
+		dst.Items = src.Items
+	}
+	if dst.Format == "" {
+		dst.Format = src.Format
+	}
+	if dst.Pattern == "" {
+		dst.Pattern = src.Pattern
+	}
+	if len(dst.Enum) == 0 {
+		dst.Enum = src.Enum
+	}
+	if dst.Default == nil {
+		dst.Default = src.Default
+	}
+	if dst.Min == nil {
+		dst.Min = src.Min
+	}
+	if dst.Max == nil {
+		dst.Max = src.Max
+	}
+	if dst.MinLength == 0 {
+		dst.MinLength = src.MinLength
+	}
+	if dst.MaxLength == nil {
+		dst.MaxLength = src.MaxLength
+	}
+	if dst.MinItems == 0 {
+		dst.MinItems = src.MinItems
+	}
+	if dst.MaxItems == nil {
+		dst.MaxItems = src.MaxItems
+	}
+	if dst.Discriminator == nil {
+		dst.Discriminator = src.Discriminator
+	}
+}
+
+// setDiscriminatorValue sets the discriminator property of the schema to the value of the selected branch of the given ref,
+// i.e., the mapping key of the ref, or the name of the component schema if it is not mapped.
+func setDiscriminatorValue(schema *openapi3.Schema, branchRef string) {
+	if schema.Discriminator == nil || schema.Discriminator.PropertyName == "" || branchRef == "" {
+		return
+	}
+	value := branchRef[strings.LastIndex(branchRef, "/")+1:]
+	for _, mappingValue := range slices.Sorted(maps.Keys(schema.Discriminator.Mapping)) {
+		if schema.Discriminator.Mapping[mappingValue].Ref == branchRef {
+			value = mappingValue
+			break
+		}
+	}
+	if schema.Properties == nil {
+		schema.Properties = make(openapi3.Schemas)
+	}
+	schema.Properties[schema.Discriminator.PropertyName] = &openapi3.SchemaRef{Value: &openapi3.Schema{
+		Type: &openapi3.Types{openapi3.TypeString},
+		Enum: []any{value},
+	}}
+}
@@ -0,0 +1,97 @@
+package strategy
+
+import (
+	"fmt"
+	"os"
+	"resttracefuzzer/pkg/resource"
+
+	"github.com/bytedance/sonic"
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/rs/zerolog/log"
+	starlarkjson "go.starlark.net/lib/json"
+	"go.starlark.net/starlark"
+	"go.starlark.net/syntax"
+)
+
+// ScriptValueGeneratorFuncName is the name of the function defined by value generator scripts.
+const ScriptValueGeneratorFuncName = "generate"
+
+// ScriptValueGenerator generates values by a user-provided Starlark script, to codify domain-specific value logic (e.g., valid IBANs or tax IDs)
+// which built-in value sources cannot generate. It is consulted by [SchemaToValueStrategy] before value sources.
+// The script must define a function "generate", which takes the name of the value and its schema (as a JSON string),
+// and returns the value, or None to fall back on built-in value sources. Module "json" is predeclared, e.g., to decode the schema.
+// For example:
+//
+//	# Example Starlark script
+//	def generate(name, schema_json):
+//	    schema = json.decode(schema_json)
+//	    if name == "iban":
+//	        return "DE89370400440532013000"
+//	    if schema.get("format") == "tax-id":
+//	        return "123-45-6789"
+//	    return None
+//
+// Returned values are converted to JSON, so strings, numbers, booleans, lists and dicts are all supported.
+type ScriptValueGenerator struct {
+	// ScriptPath is the path of the Starlark script, used for logging.
+	ScriptPath string
+
+	// generateFunc is the function "generate" defined by the script.
+	generateFunc starlark.Callable
+}
+
+// NewScriptValueGenerator creates a new ScriptValueGenerator, executing the script at scriptPath once to load function "generate".
+// It returns an error if the script cannot be loaded, or does not define the function.
+func NewScriptValueGenerator(scriptPath string) (*ScriptValueGenerator, error) {
+	script, err := os.ReadFile(scriptPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read script %s: %w", scriptPath, err)
+	}
+	thread := &starlark.Thread{Name: "value_generator_script"}
+	predeclared := starlark.StringDict{"json": starlarkjson.Module}
+	globals, err := starlark.ExecFileOptions(syntax.LegacyFileOptions(), thread, scriptPath, script, predeclared)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute script %s: %w", scriptPath, err)
+	}
+	generateFunc, ok := globals[ScriptValueGeneratorFuncName].(starlark.Callable)
+	if !ok {
+		return nil, fmt.Errorf("script %s does not define function %s", scriptPath, ScriptValueGeneratorFuncName)
+	}
+	// Globals are frozen, so that calls of the function do not share mutable states.
+	globals.Freeze()
+	return &ScriptValueGenerator{
+		ScriptPath:   scriptPath,
+		generateFunc: generateFunc,
+	}, nil
+}
+
+// Generate calls function "generate" of the script with the name and schema, and returns the value returned.
+// It returns false if the script returns None or fails, so that built-in value sources are used instead.
+func (g *ScriptValueGenerator) Generate(name string, schema *openapi3.Schema) (resource.Resource, bool) {
+	schemaJSON, err := sonic.MarshalString(schema)
+	if err != nil {
+		log.Err(err).Msgf("[ScriptValueGenerator.Generate] Failed to marshal schema of %s", name)
+		return nil, false
+	}
+	thread := &starlark.Thread{Name: "value_generator_script"}
+	value, err := starlark.Call(thread, g.generateFunc, starlark.Tuple{starlark.String(name), starlark.String(schemaJSON)}, nil)
+	if err != nil {
+		log.Err(err).Msgf("[ScriptValueGenerator.Generate] Failed to call function %s of script %s for %s", ScriptValueGeneratorFuncName, g.ScriptPath, name)
+		return nil, false
+	}
+	if value == starlark.None {
+		return nil, false
+	}
+	encoded, err := starlark.Call(thread, starlarkjson.Module.Members["encode"], starlark.Tuple{value}, nil)
+	if err != nil {
+		log.Err(err).Msgf("[ScriptValueGenerator.Generate] Failed to encode value returned by script %s for %s", g.ScriptPath, name)
+		return nil, false
+	}
+	result, err := resource.NewResourceFromRawBytes([]byte(string(encoded.(starlark.String))))
+	if err != nil {
+		log.Err(err).Msgf("[ScriptValueGenerator.Generate] Invalid value returned by script %s for %s", g.ScriptPath, name)
+		return nil, false
+	}
+	log.Debug().Msgf("[ScriptValueGenerator.Generate] Use value %s generated by script for %s", string(encoded.(starlark.String)), name)
+	return result, true
+}
@@ -0,0 +1,139 @@
+package strategy
+
+import (
+	"fmt"
+	"maps"
+	"os"
+	"resttracefuzzer/pkg/utils"
+	"slices"
+	"strings"
+
+	"github.com/bytedance/sonic"
+	"github.com/rs/zerolog/log"
+)
+
+// SecurityPayloadCategory is the category of security payloads, i.e., the kind of vulnerability the payloads try to trigger.
+type SecurityPayloadCategory string
+
+const (
+	// SecurityPayloadCategorySQLInjection is the category of SQL injection payloads.
+	SecurityPayloadCategorySQLInjection SecurityPayloadCategory = "sqlInjection"
+
+	// SecurityPayloadCategoryXSS is the category of cross-site scripting payloads.
+	SecurityPayloadCategoryXSS SecurityPayloadCategory = "xss"
+
+	// SecurityPayloadCategoryPathTraversal is the category of path traversal payloads.
+	SecurityPayloadCategoryPathTraversal SecurityPayloadCategory = "pathTraversal"
+
+	// SecurityPayloadCategoryCommandInjection is the category of OS command injection payloads.
+	SecurityPayloadCategoryCommandInjection SecurityPayloadCategory = "commandInjection"
+
+	// SecurityPayloadCategoryOversized is the category of oversized inputs.
+	SecurityPayloadCategoryOversized SecurityPayloadCategory = "oversized"
+)
+
+// oversizedPayloadLength is the length of built-in oversized payloads.
+const oversizedPayloadLength = 65536
+
+// SecurityPayloadDict is a dictionary of security payloads for negative fuzzing, grouped by category.
+// It has built-in payloads of common categories, and you can add more by [SecurityPayloadDict.LoadFromFile].
+type SecurityPayloadDict struct {
+	// Payloads maps from category to payloads of the category.
+	Payloads map[SecurityPayloadCategory][]string
+}
+
+// NewSecurityPayloadDict creates a new SecurityPayloadDict with built-in payloads.
+func NewSecurityPayloadDict() *SecurityPayloadDict {
+	return &SecurityPayloadDict{
+		Payloads: map[SecurityPayloadCategory][]string{
+			SecurityPayloadCategorySQLInjection: {
+				"' OR '1'='1",
+				"' OR 1=1--",
+				"\" OR \"\"=\"",
+				"1; DROP TABLE users--",
+				"' UNION SELECT NULL--",
+				"1' AND SLEEP(5)--",
+			},
+			SecurityPayloadCategoryXSS: {
+				"<script>alert(1)</script>",
+				"\"><img src=x onerror=alert(1)>",
+				"<svg/onload=alert(1)>",
+				"javascript:alert(1)",
+			},
+			SecurityPayloadCategoryPathTraversal: {
+				"../../../../etc/passwd",
+				"..%2F..%2F..%2F..%2Fetc%2Fpasswd",
+				"..\\..\\..\\..\\windows\\win.ini",
+				"/etc/passwd%00",
+			},
+			SecurityPayloadCategoryCommandInjection: {
+				"; cat /etc/passwd",
+				"| id",
+				"`id`",
+				"$(id)",
+				"&& whoami",
+			},
+			SecurityPayloadCategoryOversized: {
+				strings.Repeat("A", oversizedPayloadLength),
+				strings.Repeat("%s", oversizedPayloadLength/2),
+			},
+		},
+	}
+}
+
+// LoadFromFile loads payloads from a JSON file, and adds them to the dictionary.
+// The file maps from category to a list of payloads, e.g., `{"sqlInjection": ["' OR 1=1--"], "myCategory": ["..."]}`.
+// Categories not built in are allowed.
+func (d *SecurityPayloadDict) LoadFromFile(filePath string) error {
+	content, err := os.ReadFile(filePath)
+	if err != nil {
+		log.Err(err).Msgf("[SecurityPayloadDict.LoadFromFile] Failed to read file: %s", filePath)
+		return err
+	}
+	var payloads map[SecurityPayloadCategory][]string
+	err = sonic.Unmarshal(content, &payloads)
+	if err != nil {
+		log.Err(err).Msgf("[SecurityPayloadDict.LoadFromFile] Failed to unmarshal file: %s", filePath)
+		return err
+	}
+	for category, categoryPayloads := range payloads {
+		for _, payload := range categoryPayloads {
+			if payload == "" || slices.Contains(d.Payloads[category], payload) {
+				continue
+			}
+			d.Payloads[category] = append(d.Payloads[category], payload)
+		}
+	}
+	log.Info().Msgf("[SecurityPayloadDict.LoadFromFile] Loaded security payloads of %d categories from %s", len(payloads), filePath)
+	return nil
+}
+
+// GetRandomPayload returns a random payload of a random category.
+// Each category is chosen with equal probability, regardless of the number of its payloads.
+func (d *SecurityPayloadDict) GetRandomPayload() (SecurityPayloadCategory, string, error) {
+	categories := make([]SecurityPayloadCategory, 0, len(d.Payloads))
+	for _, category := range slices.Sorted(maps.Keys(d.Payloads)) {
+		if len(d.Payloads[category]) > 0 {
+			categories = append(categories, category)
+		}
+	}
+	if len(categories) == 0 {
+		return "", "", fmt.Errorf("security payload dictionary is empty")
+	}
+	category := categories[utils.SharedRand.IntN(len(categories))]
+	payloads := d.Payloads[category]
+	return category, payloads[utils.SharedRand.IntN(len(payloads))], nil
+}
+
+// FindPayloads returns the payloads (with their categories) contained in the given text, e.g., a request body.
+func (d *SecurityPayloadDict) FindPayloads(text string) map[string]SecurityPayloadCategory {
+	res := make(map[string]SecurityPayloadCategory)
+	for category, payloads := range d.Payloads {
+		for _, payload := range payloads {
+			if strings.Contains(text, payload) {
+				res[payload] = category
+			}
+		}
+	}
+	return res
+}
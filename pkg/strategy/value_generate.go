@@ -2,11 +2,17 @@ package strategy
 
 import (
 	"fmt"
+	"maps"
 	"math/rand/v2"
+	"path/filepath"
 	"resttracefuzzer/internal/config"
+	"resttracefuzzer/pkg/llm"
 	"resttracefuzzer/pkg/resource"
 	"resttracefuzzer/pkg/utils"
+	"slices"
+	"time"
 
+	"github.com/bytedance/sonic"
 	"github.com/getkin/kin-openapi/openapi3"
 	"github.com/rs/zerolog/log"
 )
@@ -20,16 +26,75 @@ const (
 
 	// VALUE_SOURCE_MUTATION is the key for mutation of values.
 	VALUE_SOURCE_MUTATION = "MUTATION"
+
+	// VALUE_SOURCE_SECURITY is the key for security payloads (e.g., SQL injection and XSS), for negative fuzzing.
+	VALUE_SOURCE_SECURITY = "SECURITY"
+
+	// VALUE_SOURCE_SPEC_EXAMPLE is the key for values documented in the API doc, i.e., `example`, `examples` and `default`,
+	// see [resource.ResourceManager.StoreSpecExamples]. Schemas documenting no value fall back on the resource pool.
+	VALUE_SOURCE_SPEC_EXAMPLE = "SPEC_EXAMPLE"
+
+	// VALUE_SOURCE_LLM is the key for primitive values synthesized by an LLM from names, schemas and descriptions, see [LLMValueGenerator].
+	// If the LLM is disabled or fails, documented values and the resource pool are used instead.
+	VALUE_SOURCE_LLM = "LLM"
+
+	// VALUE_SOURCE_RAW_JSON is the key for raw JSON values, used for parameters and request bodies declaring no schema.
+	// It is not in the weight map, see [SchemaToValueStrategy.GenerateValueWithoutSchema].
+	VALUE_SOURCE_RAW_JSON = "RAW_JSON"
+)
+
+var (
+	// SchemaLessParamRawJSONValues are raw JSON values of parameters declaring no schema.
+	// Parameters are sent as strings, so only primitive values are used.
+	SchemaLessParamRawJSONValues = []string{`"string"`, `"1"`, `0`, `1`, `true`, `false`}
+
+	// NegativeModeConstraintViolationPercent is the minimum percentage of generated primitive values which violate schema constraints in negative mode.
+	NegativeModeConstraintViolationPercent = 50
+
+	// SchemaLessBodyRawJSONValues are raw JSON values of request bodies declaring no schema.
+	SchemaLessBodyRawJSONValues = []string{`{}`, `[]`}
+
+	// LargeArrayDefaultSize is the size of large arrays generated for array schemas declaring no maxItems, see [SchemaToValueStrategy.ArrayLargePercent].
+	LargeArrayDefaultSize = 100
+
+	// MaxArraySize is the maximum size of generated arrays, which caps minItems and maxItems declared in schema,
+	// so that huge declared sizes (e.g., `minItems: 1000000`) do not exhaust memory.
+	MaxArraySize = 1000
+
+	// MaxStringLength is the maximum length of generated strings, which caps minLength and maxLength declared in schema likewise.
+	MaxStringLength = 10000
 )
 
 // SchemaToValueStrategy is a strategy for generating values from schemas.
-// It uses 3 kinds of strategies:
+// It uses 6 kinds of strategies:
 //  1. Random value, only applicable to primitive types.
 //  2. Value from resource pool, including values from dictionary and test case response.
 //  3. Mutation of values from 1 and 2.
+//  4. Security payload from [SecurityPayloadDict], only applicable to string types.
+//  5. Value documented in the API doc for the schema, e.g., `example` and `default`.
+//  6. Value synthesized by an LLM (see [LLMValueGenerator]), only applicable to primitive types.
+//
+// Generated primitive values honor constraints declared in schema (e.g., minimum, maxLength, pattern and enum),
+// except for a configurable percentage of them, which deliberately violate the constraints for negative testing.
+// In negative mode (see [SchemaToValueStrategy.NegativeMode]), at least [NegativeModeConstraintViolationPercent] of them violate the constraints.
+// String values of well-known formats (e.g., uuid, email, date-time and uri) are generated by [FormatValueGenerator].
+//
+// Path parameter values are generated by [SchemaToValueStrategy.GenerateValueForPathParam], which guarantees them route-safe.
+//
+// Arrays honor minItems and maxItems declared in schema, except for configurable percentages of them,
+// which are empty, large or of duplicate elements, to exercise pagination and validation logic.
+//
+// Values returned by the value generator script (see [ScriptValueGenerator]), if configured, take precedence over all value sources.
+//
+// Composite schemas are resolved before generation (see [resolveCompositeSchema]): allOf subschemas are merged, and a random branch of oneOf and anyOf is selected.
+// Properties and array elements of nullable schemas are null by a configurable percentage.
 //
 // You can control the strategy by setting the configuration. At present you can set:
-//  1. The ratio of random value, value from resource pool, and mutation.
+//  1. The ratio of random value, value from resource pool, mutation, security payload, documented value and LLM-synthesized value.
+//  2. The percentage of values violating schema constraints.
+//  3. The percentage of path parameter values hostile to routing.
+//  4. The percentages of empty, large and duplicate-element arrays.
+//  5. The percentage of null values of nullable schemas.
 type SchemaToValueStrategy struct {
 
 	// ResourceManager is the resource manager for fetching resources.
@@ -37,8 +102,58 @@ type SchemaToValueStrategy struct {
 
 	// ValueSourceWeightMap is the weight map for different value sources.
 	// It can use different strategies to determine the weight of each value source.
-	// It must have 3 keys (RANDOM, RESOURCE_POOL, MUTATION) with non-negative integer weights.
+	// It must have 6 keys (RANDOM, RESOURCE_POOL, MUTATION, SECURITY, SPEC_EXAMPLE, LLM) with non-negative integer weights.
 	ValueSourceWeightMap WeightMapStrategy
+
+	// ConstraintViolationPercent is the percentage (0-100) of generated primitive values which violate schema constraints.
+	ConstraintViolationPercent int
+
+	// NegativeMode tells whether values are generated in negative mode, i.e., biased toward error responses.
+	// It is set per operation case by the case manager.
+	NegativeMode bool
+
+	// SchemaRefScope is the component schema ref (e.g., `#/components/schemas/Pet`) the value being generated belongs to.
+	// Resources scoped to it (see [resource.ResourceManager.ResourceSchemaRefMap]) are requested from the pool before unscoped ones.
+	// It is set to the ref of each component schema while generating its value, and is set per operation case by the case manager, e.g., for parameters.
+	SchemaRefScope string
+
+	// SchemaPaths are the schema paths (see [resource.ResourceManager.StoreSpecExamples]) of the value being generated, whose documented values are used by value source SPEC_EXAMPLE.
+	// They are extended by the refs of component schemas and the properties and items while generating values, and are set per parameter and request body by the case manager.
+	SchemaPaths []string
+
+	// HostilePathPercent is the percentage (0-100) of generated path parameter values which are hostile to routing, see [HostilePathValues].
+	HostilePathPercent int
+
+	// ArrayEmptyPercent is the percentage (0-100) of generated arrays which are empty, regardless of minItems.
+	ArrayEmptyPercent int
+
+	// ArrayLargePercent is the percentage (0-100) of generated arrays which are large,
+	// i.e., of maxItems elements (at most [MaxArraySize]), or [LargeArrayDefaultSize] elements if maxItems is not declared.
+	ArrayLargePercent int
+
+	// ArrayDuplicatePercent is the percentage (0-100) of generated arrays whose elements are all the same, regardless of uniqueItems.
+	ArrayDuplicatePercent int
+
+	// NullPercent is the percentage (0-100) of properties and array elements of nullable schemas which are null.
+	// Parameters and request bodies are never null, as they have no representation of null, or are omitted instead.
+	NullPercent int
+
+	// FormatValueGenerator generates string values for the `format` declared in schema.
+	FormatValueGenerator *FormatValueGenerator
+
+	// SecurityPayloadDict is the dictionary of security payloads, used by value source SECURITY.
+	SecurityPayloadDict *SecurityPayloadDict
+
+	// LLMValueGenerator synthesizes values by an LLM, used by value source LLM.
+	// It is nil if no LLM endpoint is configured, i.e., value source LLM is disabled.
+	LLMValueGenerator *LLMValueGenerator
+
+	// ScriptValueGenerator generates values by a user-provided Starlark script, consulted before value sources.
+	// It is nil if no script is configured.
+	ScriptValueGenerator *ScriptValueGenerator
+
+	// Rand is the random number generator for value generation, which is [utils.SharedRand] by default.
+	Rand *rand.Rand
 }
 
 // NewSchemaToValueStrategy creates a new SchemaToValueStrategy.
@@ -48,35 +163,140 @@ type SchemaToValueStrategy struct {
 //  1. RANDOM: 0
 //  2. RESOURCE_POOL: 1
 //  3. MUTATION: 0
+//  4. SECURITY: 0
+//  5. SPEC_EXAMPLE: 0
+//  6. LLM: 0
+//
 // That means only resource pool is used by default.
-func NewSchemaToValueStrategy(resourceManager *resource.ResourceManager) *SchemaToValueStrategy {
-	valueSourceRandomWeight := config.GlobalConfig.ValueGenerateRandomWeight
-	valueSourceResourcePoolWeight := config.GlobalConfig.ValueGenerateResourcePoolWeight
-	valueSourceMutationWeight := config.GlobalConfig.ValueGenerateMutationWeight
-	if valueSourceRandomWeight < 0 || valueSourceResourcePoolWeight < 0 || valueSourceMutationWeight < 0  || valueSourceRandomWeight+valueSourceResourcePoolWeight+valueSourceMutationWeight <= 0 {
-		log.Error().Msgf("[SchemaToValueStrategy.NewSchemaToValueStrategy] Invalid weight configuration: %d, %d, %d, used default weights (0, 1, 0) instead", valueSourceRandomWeight, valueSourceResourcePoolWeight, valueSourceMutationWeight)
-		valueSourceRandomWeight = 0
-		valueSourceResourcePoolWeight = 1
-		valueSourceMutationWeight = 0
+// Weights adapt to coverage feedback if config `weight-map-strategy` is `adaptive` (see [AdaptiveWeightMapStrategy]).
+// Weights are set by config `value-generate-*-weight`, and config `value-source-weights` overrides them (see [resolveValueSourceWeights]).
+// It returns an error if the value generator script is configured but fails to be loaded.
+func NewSchemaToValueStrategy(resourceManager *resource.ResourceManager) (*SchemaToValueStrategy, error) {
+	valueSourceWeights, err := resolveValueSourceWeights()
+	if err != nil {
+		log.Err(err).Msgf("[SchemaToValueStrategy.NewSchemaToValueStrategy] Invalid weight configuration, used default weights (0, 1, 0, 0, 0, 0) instead")
+		valueSourceWeights = map[string]int{
+			VALUE_SOURCE_RANDOM:        0,
+			VALUE_SOURCE_RESOURCE_POOL: 1,
+			VALUE_SOURCE_MUTATION:      0,
+			VALUE_SOURCE_SECURITY:      0,
+			VALUE_SOURCE_SPEC_EXAMPLE:  0,
+			VALUE_SOURCE_LLM:           0,
+		}
 	}
 	// Initialize the weight map with the weights from configuration.
-	valueSourceWeightMap := NewConstantWeightMapStrategy(
-		map[string]int{
-			VALUE_SOURCE_RANDOM:        valueSourceRandomWeight,
-			VALUE_SOURCE_RESOURCE_POOL: valueSourceResourcePoolWeight,
-			VALUE_SOURCE_MUTATION:      valueSourceMutationWeight,
-		},
-	)
-	return &SchemaToValueStrategy{
-		ResourceManager:      resourceManager,
-		ValueSourceWeightMap: valueSourceWeightMap,
+	valueSourceWeightMap, ok := newWeightMapStrategy(config.GlobalConfig.WeightMapStrategy, valueSourceWeights)
+	if !ok {
+		log.Error().Msgf("[SchemaToValueStrategy.NewSchemaToValueStrategy] Unknown weight map strategy: %s, used constant weights instead", config.GlobalConfig.WeightMapStrategy)
 	}
+	constraintViolationPercent := config.GlobalConfig.ValueGenerateConstraintViolationPercent
+	if constraintViolationPercent < 0 || constraintViolationPercent > 100 {
+		log.Error().Msgf("[SchemaToValueStrategy.NewSchemaToValueStrategy] Invalid constraint violation percent: %d, used default value 10 instead", constraintViolationPercent)
+		constraintViolationPercent = 10
+	}
+	hostilePathPercent := config.GlobalConfig.ValueGenerateHostilePathPercent
+	if hostilePathPercent < 0 || hostilePathPercent > 100 {
+		log.Error().Msgf("[SchemaToValueStrategy.NewSchemaToValueStrategy] Invalid hostile path percent: %d, used default value 0 instead", hostilePathPercent)
+		hostilePathPercent = 0
+	}
+	arrayPercents := []int{config.GlobalConfig.ValueGenerateArrayEmptyPercent, config.GlobalConfig.ValueGenerateArrayLargePercent, config.GlobalConfig.ValueGenerateArrayDuplicatePercent}
+	if slices.ContainsFunc(arrayPercents, func(percent int) bool { return percent < 0 }) || arrayPercents[0]+arrayPercents[1]+arrayPercents[2] > 100 {
+		log.Error().Msgf("[SchemaToValueStrategy.NewSchemaToValueStrategy] Invalid array percents (empty, large, duplicate): %v, they should be non-negative and sum to at most 100, used 0 instead", arrayPercents)
+		arrayPercents = []int{0, 0, 0}
+	}
+	nullPercent := config.GlobalConfig.ValueGenerateNullPercent
+	if nullPercent < 0 || nullPercent > 100 {
+		log.Error().Msgf("[SchemaToValueStrategy.NewSchemaToValueStrategy] Invalid null percent: %d, used default value 10 instead", nullPercent)
+		nullPercent = 10
+	}
+	securityPayloadDict := NewSecurityPayloadDict()
+	if filePath := config.GlobalConfig.SecurityPayloadDictFilePath; filePath != "" {
+		if err := securityPayloadDict.LoadFromFile(filePath); err != nil {
+			log.Err(err).Msgf("[SchemaToValueStrategy.NewSchemaToValueStrategy] Failed to load security payload dictionary, only built-in payloads are used")
+		}
+	}
+	// The LLM is only requested if an endpoint is configured, so that offline runs are unaffected.
+	var llmValueGenerator *LLMValueGenerator
+	if config.GlobalConfig.LlmBaseURL != "" {
+		cacheFilePath := config.GlobalConfig.LlmValueCacheFilePath
+		if cacheFilePath == "" {
+			cacheFilePath = filepath.Join(config.GlobalConfig.OutputDir, "llm_value_cache.json")
+		}
+		llmClient := llm.NewOpenAIClient(config.GlobalConfig.LlmBaseURL, config.GlobalConfig.LlmAPIKey, config.GlobalConfig.LlmModel)
+		llmValueGenerator = NewLLMValueGenerator(llmClient, time.Duration(config.GlobalConfig.LlmRequestTimeout)*time.Millisecond, cacheFilePath)
+	} else if config.GlobalConfig.ValueGenerateLlmWeight > 0 {
+		log.Warn().Msgf("[SchemaToValueStrategy.NewSchemaToValueStrategy] Value source LLM is weighted but no LLM endpoint is configured, documented values and resource pool are used instead")
+	}
+	var scriptValueGenerator *ScriptValueGenerator
+	if scriptPath := config.GlobalConfig.ValueGenerateScriptPath; scriptPath != "" {
+		scriptValueGenerator, err = NewScriptValueGenerator(scriptPath)
+		if err != nil {
+			log.Err(err).Msgf("[SchemaToValueStrategy.NewSchemaToValueStrategy] Failed to load value generator script")
+			return nil, err
+		}
+	}
+	return &SchemaToValueStrategy{
+		ResourceManager:            resourceManager,
+		ValueSourceWeightMap:       valueSourceWeightMap,
+		ConstraintViolationPercent: constraintViolationPercent,
+		HostilePathPercent:         hostilePathPercent,
+		ArrayEmptyPercent:          arrayPercents[0],
+		ArrayLargePercent:          arrayPercents[1],
+		ArrayDuplicatePercent:      arrayPercents[2],
+		NullPercent:                nullPercent,
+		FormatValueGenerator:       NewFormatValueGenerator(),
+		SecurityPayloadDict:        securityPayloadDict,
+		LLMValueGenerator:          llmValueGenerator,
+		ScriptValueGenerator:       scriptValueGenerator,
+		Rand:                       utils.SharedRand,
+	}, nil
+}
+
+// resolveValueSourceWeights resolves weights of value sources from configuration.
+// Weights of config `value-generate-*-weight` are overridden by config `value-source-weights`, a stringified JSON object from value sources to weights,
+// e.g., `{"RANDOM": 1, "RESOURCE_POOL": 3}`.
+func resolveValueSourceWeights() (map[string]int, error) {
+	return ParseWeightMapOverrides(config.GlobalConfig.ValueSourceWeights, map[string]int{
+		VALUE_SOURCE_RANDOM:        config.GlobalConfig.ValueGenerateRandomWeight,
+		VALUE_SOURCE_RESOURCE_POOL: config.GlobalConfig.ValueGenerateResourcePoolWeight,
+		VALUE_SOURCE_MUTATION:      config.GlobalConfig.ValueGenerateMutationWeight,
+		VALUE_SOURCE_SECURITY:      config.GlobalConfig.ValueGenerateSecurityWeight,
+		VALUE_SOURCE_SPEC_EXAMPLE:  config.GlobalConfig.ValueGenerateSpecExampleWeight,
+		VALUE_SOURCE_LLM:           config.GlobalConfig.ValueGenerateLlmWeight,
+	})
 }
 
 // GenerateValueForSchema generates a resource value for a given schema.
 // We want to find a value that can be used to generate a request.
 // name is the name, type or key etc. of the value, and schema is the schema of the value.
 func (s *SchemaToValueStrategy) GenerateValueForSchema(name string, schema *openapi3.SchemaRef) (resource.Resource, error) {
+	return s.generateValueForSchema(name, schema, false)
+}
+
+// generateValueForSchema generates a resource value for a given schema like [SchemaToValueStrategy.GenerateValueForSchema],
+// where allowNull tells whether the value can be null if the schema is nullable, i.e., it is a property or an array element.
+func (s *SchemaToValueStrategy) generateValueForSchema(name string, schema *openapi3.SchemaRef, allowNull bool) (resource.Resource, error) {
+	// Values of a component schema and its properties are scoped to the schema.
+	if schema != nil && schema.Ref != "" {
+		defer s.enterSchemaRefScope(schema.Ref)()
+		defer s.enterSchemaPaths(append([]string{schema.Ref}, s.SchemaPaths...))()
+	}
+
+	// Composite schemas are resolved into plain ones, whose values are scoped to the selected branch if the schema is inline.
+	if schema != nil && isCompositeSchema(schema.Value) {
+		resolvedSchema := resolveCompositeSchema(schema, s.Rand)
+		if resolvedSchema.Ref != schema.Ref {
+			defer s.enterSchemaRefScope(resolvedSchema.Ref)()
+			defer s.enterSchemaPaths(append([]string{resolvedSchema.Ref}, s.SchemaPaths...))()
+		}
+		schema = resolvedSchema
+	}
+
+	if allowNull && schema != nil && isNullableSchema(schema.Value) && s.Rand.IntN(100) < s.NullPercent {
+		log.Debug().Msgf("[SchemaToValueStrategy.generateValueForSchema] Generated null value for nullable schema of %s", name)
+		return resource.NewResourceEmpty(), nil
+	}
+
 	// Try to apply value source.
 	value, generated, err := s.preCheckAndTryApplyValueSource(name, schema)
 	if err != nil {
@@ -100,6 +320,31 @@ func (s *SchemaToValueStrategy) GenerateValueForSchema(name string, schema *open
 	}
 }
 
+// GenerateValueWithoutSchema generates a best-effort resource value for a parameter or request body declaring no schema.
+// It tries the resource pool by name first, and falls back to a random one of the raw JSON values (value source RAW_JSON),
+// e.g., [SchemaLessParamRawJSONValues] or [SchemaLessBodyRawJSONValues].
+func (s *SchemaToValueStrategy) GenerateValueWithoutSchema(name string, rawJSONValues []string) (resource.Resource, error) {
+	if name != "" {
+		if resrc := s.ResourceManager.GetSingleResourceBySchemaRefAndName(s.SchemaRefScope, name); resrc != nil {
+			return resrc, nil
+		}
+		if resrc := s.ResourceManager.GetSingleResourceByName(name); resrc != nil {
+			return resrc, nil
+		}
+	}
+	if len(rawJSONValues) == 0 {
+		return nil, fmt.Errorf("no raw JSON value to use for %s", name)
+	}
+	rawJSONValue := rawJSONValues[s.Rand.IntN(len(rawJSONValues))]
+	var value any
+	if err := sonic.UnmarshalString(rawJSONValue, &value); err != nil {
+		log.Err(err).Msgf("[SchemaToValueStrategy.GenerateValueWithoutSchema] Invalid raw JSON value: %s", rawJSONValue)
+		return nil, err
+	}
+	log.Debug().Msgf("[SchemaToValueStrategy.GenerateValueWithoutSchema] Use raw JSON value %s for %s, which declares no schema", rawJSONValue, name)
+	return resource.NewResourceFromValue(value)
+}
+
 // generateObjectValueForSchema generates a json object resource value from a schema.
 // It returns a json object resource, and error if any.
 // The returned object is of type ResourceObject.
@@ -119,8 +364,11 @@ func (s *SchemaToValueStrategy) generateObjectValueForSchema(name string, schema
 
 	result := resource.NewResourceObject(make(map[string]resource.Resource))
 
-	for propName, propSchema := range schema.Value.Properties {
-		propValue, err := s.GenerateValueForSchema(propName, propSchema)
+	// Sort properties, so that values are generated in a stable order (see [utils.SeedSharedRand]).
+	for _, propName := range slices.Sorted(maps.Keys(schema.Value.Properties)) {
+		restoreSchemaPaths := s.enterSchemaPaths(resource.GetPropertySpecExamplePaths(s.SchemaPaths, propName))
+		propValue, err := s.generateValueForSchema(propName, schema.Value.Properties[propName], true)
+		restoreSchemaPaths()
 		if err != nil {
 			return nil, err
 		}
@@ -140,16 +388,43 @@ func (s *SchemaToValueStrategy) generateArrayValueForSchema(name string, schema
 		return nil, fmt.Errorf("schema is nil")
 	}
 
-	result := resource.NewResourceArray(make([]resource.Resource, 0))
-
-	// TODO: control the array size @xunzhou24
-	// For now, we generate an array with one element.
-	elementValue, err := s.GenerateValueForSchema(name, schema.Value.Items)
-	if err != nil {
-		return nil, err
+	// By default, the array is of one element, or minItems elements if more are required.
+	// Declared sizes are capped by MaxArraySize before conversion, so that they do not overflow int.
+	size := max(int(min(schema.Value.MinItems, uint64(MaxArraySize))), 1)
+	if schema.Value.MaxItems != nil {
+		size = min(size, int(min(*schema.Value.MaxItems, uint64(MaxArraySize))))
+	}
+	duplicate := false
+	switch n := s.Rand.IntN(100); {
+	case n < s.ArrayEmptyPercent:
+		size = 0
+	case n < s.ArrayEmptyPercent+s.ArrayLargePercent:
+		size = LargeArrayDefaultSize
+		if schema.Value.MaxItems != nil {
+			size = int(min(*schema.Value.MaxItems, uint64(MaxArraySize)))
+		}
+	case n < s.ArrayEmptyPercent+s.ArrayLargePercent+s.ArrayDuplicatePercent:
+		// At least two elements are needed to duplicate, which is impossible if maxItems is less than 2.
+		if schema.Value.MaxItems == nil || *schema.Value.MaxItems >= 2 {
+			size = max(size, 2)
+			duplicate = true
+		}
 	}
-	result.Value = append(result.Value, elementValue)
+	size = min(size, MaxArraySize)
 
+	defer s.enterSchemaPaths(resource.GetItemsSpecExamplePaths(s.SchemaPaths))()
+	result := resource.NewResourceArray(make([]resource.Resource, 0, size))
+	for i := range size {
+		if duplicate && i > 0 {
+			result.Value = append(result.Value, result.Value[0].Copy())
+			continue
+		}
+		elementValue, err := s.generateValueForSchema(name, schema.Value.Items, true)
+		if err != nil {
+			return nil, err
+		}
+		result.Value = append(result.Value, elementValue)
+	}
 	return result, nil
 }
 
@@ -160,7 +435,7 @@ func (s *SchemaToValueStrategy) generatePrimitiveValueForSchema(name string, sch
 	if schema == nil || schema.Value == nil {
 		return nil, fmt.Errorf("schema is nil")
 	}
-	
+
 	// Try to apply value source.
 	value, generated, err := s.preCheckAndTryApplyValueSource(name, schema)
 	if err != nil {
@@ -172,13 +447,38 @@ func (s *SchemaToValueStrategy) generatePrimitiveValueForSchema(name string, sch
 
 	typeKind := utils.PrimitiveSchemaType2ReflectKind(schema.Value.Type)
 	defaultValue := utils.DefaultValueForPrimitiveTypeKind(typeKind)
-	result, err := resource.NewResourceFromValue(defaultValue)
+	result, err := resource.NewResourceFromValue(s.constrainPrimitiveValue(schema.Value, defaultValue))
 	if err != nil {
 		return nil, err
 	}
 	return result, nil
 }
 
+// constrainPrimitiveValue makes a generated primitive value satisfy the constraints declared in schema.
+// By chance of ConstraintViolationPercent (or [NegativeModeConstraintViolationPercent] in negative mode, whichever is higher),
+// it returns a value violating the constraints instead, if the schema declares any.
+func (s *SchemaToValueStrategy) constrainPrimitiveValue(schema *openapi3.Schema, value any) any {
+	constraintViolationPercent := s.ConstraintViolationPercent
+	if s.NegativeMode {
+		constraintViolationPercent = max(constraintViolationPercent, NegativeModeConstraintViolationPercent)
+	}
+	if s.Rand.IntN(100) < constraintViolationPercent {
+		if violatingValue, ok := generateConstraintViolatingValue(schema); ok {
+			log.Debug().Msgf("[SchemaToValueStrategy.constrainPrimitiveValue] Generated constraint violating value: %v", violatingValue)
+			return violatingValue
+		}
+	}
+	// Pattern and enum take precedence over format, and are handled in applySchemaConstraints.
+	if _, isString := value.(string); isString && schema.Format != "" && schema.Pattern == "" && len(schema.Enum) == 0 {
+		if formattedValue, ok := s.FormatValueGenerator.Generate(schema.Format); ok {
+			value = formattedValue
+		} else {
+			log.Debug().Msgf("[SchemaToValueStrategy.constrainPrimitiveValue] Unsupported string format: %s", schema.Format)
+		}
+	}
+	return applySchemaConstraints(schema, value)
+}
+
 // preCheckAndTryApplyValueSource checks the schema and applies the value source using name and type.
 // It returns:
 //  1. The generated value, if successful.
@@ -191,6 +491,13 @@ func (s *SchemaToValueStrategy) preCheckAndTryApplyValueSource(name string, sche
 		return nil, false, fmt.Errorf("schema is nil")
 	}
 
+	// Values of the user-provided script take precedence, as they codify domain knowledge.
+	if s.ScriptValueGenerator != nil {
+		if value, ok := s.ScriptValueGenerator.Generate(name, schema.Value); ok {
+			return value, true, nil
+		}
+	}
+
 	// Decide the value source based on weights.
 	valueSource := s.decideValueSource()
 	switch valueSource {
@@ -199,47 +506,114 @@ func (s *SchemaToValueStrategy) preCheckAndTryApplyValueSource(name string, sche
 		if !utils.IncludePrimitiveType(schema.Value.Type) {
 			return nil, false, nil
 		}
-		
+
 		typeKind := utils.PrimitiveSchemaType2ReflectKind(schema.Value.Type)
 		randomValue := utils.RandomValueForPrimitiveTypeKind(typeKind)
-		result, err := resource.NewResourceFromValue(randomValue)
+		result, err := resource.NewResourceFromValue(s.constrainPrimitiveValue(schema.Value, randomValue))
 		if err != nil {
 			return nil, false, err
 		}
 		return result, true, nil
+	case VALUE_SOURCE_LLM:
+		// LLMs synthesize primitive values only, as objects and arrays are composed of them.
+		if s.LLMValueGenerator != nil && utils.IncludePrimitiveType(schema.Value.Type) {
+			values := s.LLMValueGenerator.GenerateValues(name, schema.Value)
+			values = slices.DeleteFunc(values, func(value resource.Resource) bool {
+				return !s.isResourceSatisfyingSchemaConstraints(schema.Value, value)
+			})
+			if len(values) > 0 {
+				return values[s.Rand.IntN(len(values))], true, nil
+			}
+		}
+		// If the LLM is disabled, fails or is still synthesizing the values, documented values are used instead.
+		fallthrough
+	case VALUE_SOURCE_SPEC_EXAMPLE:
+		// Documented values are used as is, as they are realistic, even if violating constraints.
+		if example := s.ResourceManager.GetSingleSpecExample(s.SchemaPaths...); example != nil {
+			return example.Copy(), true, nil
+		}
+		// Schemas documenting no value fall back on the resource pool.
+		fallthrough
 	case VALUE_SOURCE_RESOURCE_POOL:
-		// First try to get a resource by name.
-		resource := s.ResourceManager.GetSingleResourceByName(name)
-		if resource != nil {
+		// First try to get a resource by name, scoped to the schema being filled first.
+		// Primitive resources violating schema constraints are skipped, and constrained values would be generated instead.
+		resource := s.ResourceManager.GetSingleResourceBySchemaRefAndName(s.SchemaRefScope, name)
+		if resource != nil && s.isResourceSatisfyingSchemaConstraints(schema.Value, resource) {
+			return resource, true, nil
+		}
+		resource = s.ResourceManager.GetSingleResourceByName(name)
+		if resource != nil && s.isResourceSatisfyingSchemaConstraints(schema.Value, resource) {
 			return resource, true, nil
 		}
 		// If failed, try to get a resource by type.
 		log.Debug().Msgf("[SchemaToValueStrategy.preCheckAndTryApplyValueSource] Cannot find resource by name: %s", name)
 		resource = s.ResourceManager.GetSingleResourceBySchemaTypes(schema.Value.Type)
-		if resource != nil {
+		if resource != nil && s.isResourceSatisfyingSchemaConstraints(schema.Value, resource) {
 			return resource, true, nil
 		}
 		// still cannot find a resource, return nil
 		return nil, false, nil
 	case VALUE_SOURCE_MUTATION: // TODO: implement mutation @xunzhou24
 		return nil, false, nil
+	case VALUE_SOURCE_SECURITY:
+		// security payloads are strings, so only apply to string types
+		if schema.Value.Type == nil || !schema.Value.Type.Includes(openapi3.TypeString) {
+			return nil, false, nil
+		}
+		category, payload, err := s.SecurityPayloadDict.GetRandomPayload()
+		if err != nil {
+			log.Err(err).Msgf("[SchemaToValueStrategy.preCheckAndTryApplyValueSource] Failed to get security payload")
+			return nil, false, nil
+		}
+		log.Debug().Msgf("[SchemaToValueStrategy.preCheckAndTryApplyValueSource] Use security payload of category %s for %s", category, name)
+		return resource.NewResourceString(payload), true, nil
 	default:
 		return nil, false, fmt.Errorf("unknown value source: %s", valueSource)
 	}
 }
 
+// enterSchemaRefScope sets SchemaRefScope to the schema ref, and returns the function restoring the previous scope.
+func (s *SchemaToValueStrategy) enterSchemaRefScope(schemaRef string) func() {
+	previousSchemaRefScope := s.SchemaRefScope
+	s.SchemaRefScope = schemaRef
+	return func() {
+		s.SchemaRefScope = previousSchemaRefScope
+	}
+}
+
+// enterSchemaPaths sets SchemaPaths to the schema paths, and returns the function restoring the previous ones.
+func (s *SchemaToValueStrategy) enterSchemaPaths(schemaPaths []string) func() {
+	previousSchemaPaths := s.SchemaPaths
+	s.SchemaPaths = schemaPaths
+	return func() {
+		s.SchemaPaths = previousSchemaPaths
+	}
+}
+
+// isResourceSatisfyingSchemaConstraints checks whether a resource from resource pool satisfies the schema constraints.
+// Only primitive resources are checked, as objects and arrays in resource pool are usually partial.
+func (s *SchemaToValueStrategy) isResourceSatisfyingSchemaConstraints(schema *openapi3.Schema, resrc resource.Resource) bool {
+	if !utils.IncludePrimitiveType(schema.Type) {
+		return true
+	}
+	return isValueSatisfyingSchemaConstraints(schema, resrc.GetRawValue())
+}
+
 // decideValueSource returns the selected value source based on weights.
 func (s *SchemaToValueStrategy) decideValueSource() string {
+	weights := s.ValueSourceWeightMap.GetMapWithParam(WEIGHT_MAP_STRATEGY_PARAM_PLACEHOLDER)
 	totalWeight := 0
-	for _, weight := range s.ValueSourceWeightMap.GetMapWithParam(WEIGHT_MAP_STRATEGY_PARAM_PLACEHOLDER) {
+	for _, weight := range weights {
 		totalWeight += weight
 	}
 
-	randomNumber := rand.IntN(totalWeight)
+	randomNumber := s.Rand.IntN(totalWeight)
 	cumulativeWeight := 0
-	for source, weight := range s.ValueSourceWeightMap.GetMapWithParam(WEIGHT_MAP_STRATEGY_PARAM_PLACEHOLDER) {
-		cumulativeWeight += weight
+	// Sort sources, so that the chosen source is decided by random number only (see [utils.SeedSharedRand]).
+	for _, source := range slices.Sorted(maps.Keys(weights)) {
+		cumulativeWeight += weights[source]
 		if randomNumber < cumulativeWeight {
+			recordWeightMapSelection(s.ValueSourceWeightMap, source)
 			return source
 		}
 	}
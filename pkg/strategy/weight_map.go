@@ -1,5 +1,14 @@
 package strategy
 
+import (
+	"fmt"
+	"maps"
+	"resttracefuzzer/internal/config"
+	"slices"
+
+	"github.com/bytedance/sonic"
+)
+
 const WEIGHT_MAP_STRATEGY_PARAM_PLACEHOLDER = -1
 
 // WeightMapStrategy defines the interface for different weight map strategies.
@@ -75,3 +84,49 @@ func (s *VariableWeightMapStrategy) GetMapWithParam(param int) map[string]int {
 	}
 	return weightMap
 }
+
+// ParseWeightMapOverrides overrides weights of defaultWeights by a stringified JSON object from keys to weights, e.g., `{"RANDOM": 1, "RESOURCE_POOL": 3}`.
+// Keys not in weightsStr keep their default weights, and defaultWeights is not modified.
+// It returns an error if weightsStr is invalid, contains a key not in defaultWeights or a negative weight, or the sum of the resulting weights is not positive.
+func ParseWeightMapOverrides(weightsStr string, defaultWeights map[string]int) (map[string]int, error) {
+	weights := maps.Clone(defaultWeights)
+	if weightsStr != "" {
+		overrides := make(map[string]int)
+		err := sonic.UnmarshalString(weightsStr, &overrides)
+		if err != nil {
+			return nil, err
+		}
+		for key, weight := range overrides {
+			if _, exist := defaultWeights[key]; !exist {
+				return nil, fmt.Errorf("unknown weight key %s, expected one of %v", key, slices.Sorted(maps.Keys(defaultWeights)))
+			}
+			weights[key] = weight
+		}
+	}
+	sum := 0
+	for key, weight := range weights {
+		if weight < 0 {
+			return nil, fmt.Errorf("invalid weight %d of %s, expected non-negative", weight, key)
+		}
+		sum += weight
+	}
+	if sum <= 0 {
+		return nil, fmt.Errorf("sum of weights must be positive")
+	}
+	return weights, nil
+}
+
+// ValidateWeightMapConfig validates weight maps of value generation and mutation, and the weight map strategy in the configuration,
+// so that an invalid configuration is reported at startup instead of being replaced by defaults silently.
+func ValidateWeightMapConfig() error {
+	if _, err := resolveValueSourceWeights(); err != nil {
+		return fmt.Errorf("invalid value source weights: %w", err)
+	}
+	if _, err := resolveMutationPlanWeights(); err != nil {
+		return fmt.Errorf("invalid mutation plan weights: %w", err)
+	}
+	if _, ok := newWeightMapStrategy(config.GlobalConfig.WeightMapStrategy, nil); !ok {
+		return fmt.Errorf("unknown weight map strategy: %s", config.GlobalConfig.WeightMapStrategy)
+	}
+	return nil
+}
@@ -10,7 +10,6 @@ import (
 	"encoding/base64"
 	"encoding/hex"
 	"math"
-	"math/rand/v2"
 	"reflect"
 
 	"github.com/rs/zerolog/log"
@@ -23,7 +22,7 @@ const letterBytes = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456
 func RandStringBytes(n int) string {
 	b := make([]byte, n)
 	for i := range b {
-		b[i] = letterBytes[rand.IntN(len(letterBytes))]
+		b[i] = letterBytes[SharedRand.IntN(len(letterBytes))]
 	}
 	return string(b)
 }
@@ -44,8 +43,8 @@ func mutateRandBytes(b []byte, mutationProbability float64, maxMutations int) {
 	}
 	mutations := 0
 	for i := range b {
-		if rand.Float64() < mutationProbability && mutations < maxMutations {
-			b[i] = letterBytes[rand.IntN(len(letterBytes))]
+		if SharedRand.Float64() < mutationProbability && mutations < maxMutations {
+			b[i] = letterBytes[SharedRand.IntN(len(letterBytes))]
 			mutations++
 		}
 	}
@@ -137,7 +136,7 @@ func ConvertFloatTo64BitType(num any) float64 {
 // - stdDev: the standard deviation of the distribution
 // The function returns a normally distributed random int64 value.
 func NormInt64(mean, stdDev int64) int64 {
-	return int64(math.Round(rand.NormFloat64()*float64(stdDev) + float64(mean)))
+	return int64(math.Round(SharedRand.NormFloat64()*float64(stdDev) + float64(mean)))
 }
 
 // DefaultValueForPrimitiveTypeKind returns the default value for a given primitive type kind.
@@ -165,13 +164,13 @@ func DefaultValueForPrimitiveTypeKind(kind reflect.Kind) any {
 func RandomValueForPrimitiveTypeKind(kind reflect.Kind) any {
 	switch kind {
 	case reflect.Int64:
-		return rand.Int64N(114514)
+		return SharedRand.Int64N(114514)
 	case reflect.Float64:
-		return rand.Float64() + float64(rand.IntN(114514))
+		return SharedRand.Float64() + float64(SharedRand.IntN(114514))
 	case reflect.Bool:
-		return rand.IntN(2) == 1
+		return SharedRand.IntN(2) == 1
 	case reflect.String:
-		randLength := rand.IntN(114) + 1
+		randLength := SharedRand.IntN(114) + 1
 		return RandStringBytes(randLength)
 	default:
 		log.Warn().Msgf("[RandomValueForPrimitiveTypeKind] Unsupported kind: %v", kind)
@@ -192,13 +191,13 @@ func EdgeCaseValueForPrimitiveTypeKind(kind reflect.Kind) any {
 	)
 	switch kind {
 	case reflect.Int64:
-		return intEdgeCase[rand.IntN(len(intEdgeCase))]
+		return intEdgeCase[SharedRand.IntN(len(intEdgeCase))]
 	case reflect.Float64:
-		return floatEdgeCase[rand.IntN(len(floatEdgeCase))]
+		return floatEdgeCase[SharedRand.IntN(len(floatEdgeCase))]
 	case reflect.Bool:
-		return boolEdgeCase[rand.IntN(len(boolEdgeCase))]
+		return boolEdgeCase[SharedRand.IntN(len(boolEdgeCase))]
 	case reflect.String:
-		return stringEdgeCase[rand.IntN(len(stringEdgeCase))]
+		return stringEdgeCase[SharedRand.IntN(len(stringEdgeCase))]
 	default:
 		log.Warn().Msgf("[EdgeCaseValueForPrimitiveTypeKind] Unsupported kind: %v", kind)
 		return nil
@@ -250,3 +249,18 @@ func Base64ToHex(base64Str string) (string, error) {
     hexStr := hex.EncodeToString(decoded)
 	return hexStr, nil
 }
+
+// GetPercentile returns the percentile (in (0, 100]) of sorted values by the nearest-rank method,
+// i.e., the smallest value such that at least percentile% of values are less than or equal to it.
+// It returns 0 if there is no value.
+//
+// Example:
+//   p := GetPercentile([]float64{1, 2, 3, 4}, 50)
+//   // p will be 2
+func GetPercentile(sortedValues []float64, percentile float64) float64 {
+	if len(sortedValues) == 0 {
+		return 0
+	}
+	index := min(int(math.Ceil(float64(len(sortedValues))*percentile/100))-1, len(sortedValues)-1)
+	return sortedValues[max(index, 0)]
+}
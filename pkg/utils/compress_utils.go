@@ -0,0 +1,151 @@
+package utils
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	// CompressionNone is the compression of output files written as is.
+	CompressionNone = ""
+
+	// CompressionZstd is the compression of output files compressed by Zstandard.
+	CompressionZstd = "zstd"
+
+	// ZstdFileExtension is the extension appended to the path of files compressed by Zstandard.
+	ZstdFileExtension = ".zst"
+)
+
+// zstdMagicNumber is the magic number at the beginning of each Zstandard frame.
+var zstdMagicNumber = []byte{0x28, 0xB5, 0x2F, 0xFD}
+
+// IsValidCompression checks whether the compression of output files is supported, i.e., [CompressionNone] or [CompressionZstd].
+func IsValidCompression(compression string) bool {
+	return compression == CompressionNone || compression == CompressionZstd
+}
+
+// GetCompressedFilePath returns the path of the file written with the compression, i.e., [ZstdFileExtension] is appended for [CompressionZstd].
+func GetCompressedFilePath(path, compression string) string {
+	if compression == CompressionZstd && !strings.HasSuffix(path, ZstdFileExtension) {
+		return path + ZstdFileExtension
+	}
+	return path
+}
+
+// WriteFileWithCompression writes data to the file of the path, compressed by the compression (see [GetCompressedFilePath] for the actual path).
+// It returns the path of the written file.
+func WriteFileWithCompression(path string, data []byte, compression string) (string, error) {
+	if !IsValidCompression(compression) {
+		return "", fmt.Errorf("unsupported compression: %s", compression)
+	}
+	path = GetCompressedFilePath(path, compression)
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		log.Err(err).Msgf("[WriteFileWithCompression] Failed to open file: %s", path)
+		return "", err
+	}
+	defer file.Close()
+
+	if compression == CompressionNone {
+		if _, err := file.Write(data); err != nil {
+			log.Err(err).Msgf("[WriteFileWithCompression] Failed to write file: %s", path)
+			return "", err
+		}
+		return path, nil
+	}
+	encoder, err := zstd.NewWriter(file)
+	if err != nil {
+		log.Err(err).Msgf("[WriteFileWithCompression] Failed to create zstd encoder")
+		return "", err
+	}
+	if _, err := encoder.Write(data); err != nil {
+		encoder.Close()
+		log.Err(err).Msgf("[WriteFileWithCompression] Failed to write compressed file: %s", path)
+		return "", err
+	}
+	// Closing the encoder flushes the remaining data, so its error must be checked.
+	if err := encoder.Close(); err != nil {
+		log.Err(err).Msgf("[WriteFileWithCompression] Failed to flush compressed file: %s", path)
+		return "", err
+	}
+	return path, nil
+}
+
+// ReadFileWithDecompression reads the file of the path, and decompresses it if it is compressed by Zstandard, detected by its magic number.
+// If the path does not exist but its compressed one (with [ZstdFileExtension]) does, the compressed one is read,
+// so that outputs can be read by their original paths, no matter whether they are compressed.
+func ReadFileWithDecompression(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) && !strings.HasSuffix(path, ZstdFileExtension) {
+		data, err = os.ReadFile(path + ZstdFileExtension)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.HasPrefix(data, zstdMagicNumber) {
+		return data, nil
+	}
+	decoder, err := zstd.NewReader(nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create zstd decoder: %w", err)
+	}
+	defer decoder.Close()
+	decompressed, err := decoder.DecodeAll(data, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress file %s: %w", path, err)
+	}
+	return decompressed, nil
+}
+
+// OpenFileWithDecompression opens the file of the path like [ReadFileWithDecompression], but returns a reader streaming the decompressed content,
+// e.g., for large NDJSON files read line by line. The caller must close the returned reader.
+func OpenFileWithDecompression(path string) (io.ReadCloser, error) {
+	file, err := os.Open(path)
+	if os.IsNotExist(err) && !strings.HasSuffix(path, ZstdFileExtension) {
+		file, err = os.Open(path + ZstdFileExtension)
+	}
+	if err != nil {
+		return nil, err
+	}
+	header := make([]byte, len(zstdMagicNumber))
+	n, err := io.ReadFull(file, header)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		file.Close()
+		return nil, err
+	}
+	content := io.MultiReader(bytes.NewReader(header[:n]), file)
+	if !bytes.Equal(header[:n], zstdMagicNumber) {
+		return &decompressedFileReader{Reader: content, file: file}, nil
+	}
+	decoder, err := zstd.NewReader(content)
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("failed to create zstd decoder: %w", err)
+	}
+	return &decompressedFileReader{Reader: decoder, file: file, decoder: decoder}, nil
+}
+
+// decompressedFileReader reads the decompressed content of a file, and closes the file (and the decoder if any) on close.
+type decompressedFileReader struct {
+	io.Reader
+
+	// file is the underlying file.
+	file *os.File
+
+	// decoder is the zstd decoder, or nil if the file is not compressed.
+	decoder *zstd.Decoder
+}
+
+// Close closes the decoder and the underlying file.
+func (r *decompressedFileReader) Close() error {
+	if r.decoder != nil {
+		r.decoder.Close()
+	}
+	return r.file.Close()
+}
@@ -0,0 +1,86 @@
+package utils
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	// RunOutputDirPrefix is the prefix of the name of each run's output directory.
+	RunOutputDirPrefix = "run_"
+
+	// LatestRunOutputDirLinkName is the name of the symlink pointing to the output directory of the latest run.
+	LatestRunOutputDirLinkName = "latest"
+)
+
+// CreateRunOutputDir creates the output directory of a run under baseDir, named `run_{runID}`,
+// and points the `latest` symlink under baseDir to it.
+// It returns the path of the created directory.
+// Failure to update the symlink (e.g., on file systems without symlink support) is logged but not returned.
+func CreateRunOutputDir(baseDir, runID string) (string, error) {
+	runDirName := RunOutputDirPrefix + runID
+	runDir := filepath.Join(baseDir, runDirName)
+	err := os.MkdirAll(runDir, os.ModePerm)
+	if err != nil {
+		log.Err(err).Msgf("[CreateRunOutputDir] Failed to create run output directory: %s", runDir)
+		return "", err
+	}
+
+	// The symlink target is relative, so that the output directory can be moved as a whole.
+	linkPath := filepath.Join(baseDir, LatestRunOutputDirLinkName)
+	if _, err := os.Lstat(linkPath); err == nil {
+		if err := os.Remove(linkPath); err != nil {
+			log.Warn().Err(err).Msgf("[CreateRunOutputDir] Failed to remove old symlink: %s", linkPath)
+			return runDir, nil
+		}
+	}
+	if err := os.Symlink(runDirName, linkPath); err != nil {
+		log.Warn().Err(err).Msgf("[CreateRunOutputDir] Failed to create symlink %s to %s", linkPath, runDirName)
+	}
+	return runDir, nil
+}
+
+// CleanupOldRunOutputDirs removes the oldest run output directories under baseDir, keeping the most recent `retention` ones.
+// Run output directories are those created by [CreateRunOutputDir], and are ordered by name, as run IDs are timestamps.
+// If retention is not positive, nothing is removed.
+// It returns the paths of the removed directories.
+func CleanupOldRunOutputDirs(baseDir string, retention int) ([]string, error) {
+	removed := make([]string, 0)
+	if retention <= 0 {
+		return removed, nil
+	}
+	entries, err := os.ReadDir(baseDir)
+	if err != nil {
+		log.Err(err).Msgf("[CleanupOldRunOutputDirs] Failed to read directory: %s", baseDir)
+		return removed, err
+	}
+	runDirNames := make([]string, 0)
+	for _, entry := range entries {
+		if entry.IsDir() && strings.HasPrefix(entry.Name(), RunOutputDirPrefix) {
+			runDirNames = append(runDirNames, entry.Name())
+		}
+	}
+	if len(runDirNames) <= retention {
+		return removed, nil
+	}
+	slices.Sort(runDirNames)
+	var errs []error
+	for _, name := range runDirNames[:len(runDirNames)-retention] {
+		runDir := filepath.Join(baseDir, name)
+		if err := os.RemoveAll(runDir); err != nil {
+			log.Err(err).Msgf("[CleanupOldRunOutputDirs] Failed to remove run output directory: %s", runDir)
+			errs = append(errs, err)
+			continue
+		}
+		removed = append(removed, runDir)
+	}
+	if len(errs) > 0 {
+		return removed, fmt.Errorf("failed to remove %d run output directories: %v", len(errs), errs)
+	}
+	return removed, nil
+}
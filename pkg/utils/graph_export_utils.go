@@ -0,0 +1,108 @@
+package utils
+
+import (
+	"fmt"
+	"strings"
+)
+
+// GraphEdgeStyle is the style of an edge in exported graphs.
+type GraphEdgeStyle struct {
+	// Label is the label of the edge. It is omitted if empty.
+	Label string
+
+	// Color is the color of the edge, e.g., `red` or `#ff0000`, which should be supported by both Graphviz and Mermaid.
+	// The default color is used if empty.
+	Color string
+}
+
+// ExportDOT exports the graph in Graphviz DOT format, with the name as graph ID.
+// Nodes are labeled by nodeLabel, and edges are styled by edgeStyle.
+// Nodes are numbered in order of their first appearance in Edges, so that the output is deterministic for the same edges.
+func (g *Graph[N, E]) ExportDOT(name string, nodeLabel func(N) string, edgeStyle func(E) GraphEdgeStyle) string {
+	nodeIDs, nodes := g.getExportNodeIDs()
+	var builder strings.Builder
+	fmt.Fprintf(&builder, "digraph %s {\n", escapeDOTString(name))
+	builder.WriteString("  rankdir=LR;\n")
+	builder.WriteString("  node [shape=box];\n")
+	for _, node := range nodes {
+		fmt.Fprintf(&builder, "  %s [label=%s];\n", nodeIDs[node], escapeDOTString(nodeLabel(node)))
+	}
+	for _, edge := range g.Edges {
+		style := edgeStyle(edge)
+		attrs := make([]string, 0, 2)
+		if style.Label != "" {
+			attrs = append(attrs, "label="+escapeDOTString(style.Label))
+		}
+		if style.Color != "" {
+			attrs = append(attrs, "color="+escapeDOTString(style.Color))
+		}
+		fmt.Fprintf(&builder, "  %s -> %s", nodeIDs[edge.GetSource()], nodeIDs[edge.GetTarget()])
+		if len(attrs) > 0 {
+			fmt.Fprintf(&builder, " [%s]", strings.Join(attrs, ", "))
+		}
+		builder.WriteString(";\n")
+	}
+	builder.WriteString("}\n")
+	return builder.String()
+}
+
+// ExportMermaid exports the graph as a Mermaid flowchart.
+// Nodes are labeled by nodeLabel, and edges are styled by edgeStyle. Line breaks in labels are kept.
+// Nodes are numbered as [Graph.ExportDOT].
+func (g *Graph[N, E]) ExportMermaid(nodeLabel func(N) string, edgeStyle func(E) GraphEdgeStyle) string {
+	nodeIDs, nodes := g.getExportNodeIDs()
+	var builder strings.Builder
+	builder.WriteString("flowchart LR\n")
+	for _, node := range nodes {
+		fmt.Fprintf(&builder, "  %s[%s]\n", nodeIDs[node], escapeMermaidString(nodeLabel(node)))
+	}
+	linkStyles := make([]string, 0)
+	for i, edge := range g.Edges {
+		style := edgeStyle(edge)
+		if style.Label != "" {
+			fmt.Fprintf(&builder, "  %s -->|%s| %s\n", nodeIDs[edge.GetSource()], escapeMermaidString(style.Label), nodeIDs[edge.GetTarget()])
+		} else {
+			fmt.Fprintf(&builder, "  %s --> %s\n", nodeIDs[edge.GetSource()], nodeIDs[edge.GetTarget()])
+		}
+		if style.Color != "" {
+			linkStyles = append(linkStyles, fmt.Sprintf("  linkStyle %d stroke:%s\n", i, style.Color))
+		}
+	}
+	for _, linkStyle := range linkStyles {
+		builder.WriteString(linkStyle)
+	}
+	return builder.String()
+}
+
+// getExportNodeIDs returns IDs (`n0`, `n1`, ...) of nodes in exported graphs, numbered in order of their first appearance in Edges,
+// and the nodes in that order.
+func (g *Graph[N, E]) getExportNodeIDs() (map[N]string, []N) {
+	nodeIDs := make(map[N]string)
+	nodes := make([]N, 0)
+	addNode := func(node N) {
+		if _, exist := nodeIDs[node]; !exist {
+			nodeIDs[node] = fmt.Sprintf("n%d", len(nodes))
+			nodes = append(nodes, node)
+		}
+	}
+	for _, edge := range g.Edges {
+		addNode(edge.GetSource())
+		addNode(edge.GetTarget())
+	}
+	return nodeIDs, nodes
+}
+
+// escapeDOTString quotes the string as a DOT ID, escaping `"` and `\`, and converting line breaks to `\n`.
+func escapeDOTString(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, `"`, `\"`)
+	s = strings.ReplaceAll(s, "\n", `\n`)
+	return `"` + s + `"`
+}
+
+// escapeMermaidString quotes the string as a Mermaid label, escaping `"` as entity code, and converting line breaks to `<br/>`.
+func escapeMermaidString(s string) string {
+	s = strings.ReplaceAll(s, `"`, "#quot;")
+	s = strings.ReplaceAll(s, "\n", "<br/>")
+	return `"` + s + `"`
+}
@@ -0,0 +1,507 @@
+// Package grpc provides a client of gRPC unary calls, whose messages are built dynamically from proto descriptors,
+// so that gRPC APIs can be fuzzed with JSON requests, like HTTP ones.
+package grpc
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"net"
+	gohttp "net/http"
+	"net/url"
+	"os"
+	"resttracefuzzer/pkg/utils/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bytedance/sonic"
+	"github.com/rs/zerolog/log"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+const (
+	// GRPCStatusHeaderKey is the trailer (or header, for trailers-only responses) carrying the gRPC status code.
+	GRPCStatusHeaderKey = "Grpc-Status"
+
+	// GRPCMessageHeaderKey is the trailer (or header) carrying the gRPC status message.
+	GRPCMessageHeaderKey = "Grpc-Message"
+
+	// grpcContentType is the content type of gRPC requests, whose messages are encoded in protobuf.
+	grpcContentType = "application/grpc+proto"
+
+	// messageFrameHeaderLength is the length of the header of a length-prefixed message: 1 byte of compressed flag and 4 bytes of length.
+	messageFrameHeaderLength = 5
+
+	// maxResponseMessageLength is the max length of a response message, in bytes.
+	maxResponseMessageLength = 16 << 20
+)
+
+// reservedHeaderKeys are request headers that must not be sent as gRPC metadata, as they are set by the client or the transport.
+var reservedHeaderKeys = map[string]struct{}{
+	"content-type":   {},
+	"content-length": {},
+	"host":           {},
+	"te":             {},
+	"user-agent":     {},
+	"connection":     {},
+}
+
+// GRPCClient is a client of gRPC unary calls.
+// Requests and responses are in JSON (see [protojson]), converted from/to protobuf messages by descriptors of services.
+// It speaks HTTP/2 with prior knowledge (h2c) to `http` servers, and over TLS to `https` servers.
+type GRPCClient struct {
+	// BaseURL is the base URL of the gRPC server, e.g., `http://localhost:50051`.
+	BaseURL string
+
+	// HeadersToCapture are the response headers (or trailers) to capture, e.g., the trace ID header.
+	// [GRPCStatusHeaderKey] and [GRPCMessageHeaderKey] are always captured.
+	HeadersToCapture []string
+
+	// Files is the registry of proto files, where services and messages are looked up.
+	Files *protoregistry.Files
+
+	// Client is the underlying HTTP/2 client.
+	Client *gohttp.Client
+
+	// Politeness throttles requests, like that of [http.HTTPClient].
+	// It is nil (i.e., no throttling) by default.
+	Politeness *http.HTTPClientPoliteness
+
+	// Middlewares process requests and responses in JSON, like those of [http.HTTPClient].
+	// The path is the full method (e.g., `/shop.v1.CartService/GetCart`) and the method is `POST`, and changes of them are ignored,
+	// as are path and query parameters, which are fields of the request message, see [BuildRequestMessageJSON].
+	Middlewares []http.HTTPClientMiddleware
+
+	// CaptureBuffer keeps the most recent requests and responses (in JSON), like that of [http.HTTPClient].
+	// It is nil (i.e., capture disabled) by default.
+	CaptureBuffer *http.HTTPCaptureBuffer
+
+	// HARRecorder records requests and responses (in JSON), like that of [http.HTTPClient].
+	// It is nil (i.e., recording disabled) by default.
+	HARRecorder *http.HARRecorder
+
+	// InjectTraceContext tells whether each request starts a new trace, like that of [http.HTTPClient].
+	InjectTraceContext bool
+}
+
+// NewGRPCClient creates a new GRPCClient, with services in the registry of proto files, e.g., loaded by [LoadDescriptorSetFile].
+func NewGRPCClient(baseURL string, files *protoregistry.Files, headersToCapture []string, transportConfig http.TransportConfig, dialTimeout time.Duration) (*GRPCClient, error) {
+	tlsConfig, err := transportConfig.NewTLSConfig()
+	if err != nil {
+		log.Err(err).Msgf("[NewGRPCClient] Failed to create TLS config")
+		return nil, err
+	}
+	transport := &gohttp.Transport{
+		DialContext:         (&net.Dialer{Timeout: dialTimeout}).DialContext,
+		TLSClientConfig:     tlsConfig,
+		TLSHandshakeTimeout: dialTimeout,
+		ForceAttemptHTTP2:   true,
+	}
+	// gRPC requires HTTP/2, so HTTP/1 is disabled.
+	transport.Protocols = new(gohttp.Protocols)
+	transport.Protocols.SetHTTP2(true)
+	transport.Protocols.SetUnencryptedHTTP2(true)
+	if transportConfig.ProxyURL != "" {
+		proxyURL, err := url.Parse(transportConfig.ProxyURL)
+		if err != nil {
+			log.Err(err).Msgf("[NewGRPCClient] Invalid proxy URL: %s", transportConfig.ProxyURL)
+			return nil, err
+		}
+		transport.Proxy = gohttp.ProxyURL(proxyURL)
+	}
+	return &GRPCClient{
+		BaseURL:          strings.TrimSuffix(baseURL, "/"),
+		HeadersToCapture: headersToCapture,
+		Files:            files,
+		Client:           &gohttp.Client{Transport: transport},
+	}, nil
+}
+
+// PerformRequest performs a unary call of the method of the service, with the JSON request body.
+// Request headers are sent as metadata.
+// It returns the HTTP status code corresponding to the gRPC status (see [HTTPStatusFromGRPCCode]),
+// captured response headers (and trailers), and the JSON response body.
+// Requests and responses go through Middlewares, and are recorded by CaptureBuffer and HARRecorder, like those of [http.HTTPClient].
+func (c *GRPCClient) PerformRequest(ctx context.Context, serviceName, methodName string, headers map[string]string, body []byte) (int, map[string]string, []byte, error) {
+	methodDesc, err := c.FindMethod(serviceName, methodName)
+	if err != nil {
+		log.Err(err).Msgf("[GRPCClient.PerformRequest] Failed to find method %s of service %s", methodName, serviceName)
+		return 0, nil, nil, err
+	}
+	fullMethod := fmt.Sprintf("/%s/%s", methodDesc.Parent().FullName(), methodDesc.Name())
+
+	headers = maps.Clone(headers)
+	if headers == nil {
+		headers = make(map[string]string)
+	}
+	for _, middleware := range c.Middlewares {
+		// errors are ignored here, like those of HTTP clients
+		_, _, headers, _, _, body, _ = middleware.HandleRequest(fullMethod, gohttp.MethodPost, headers, map[string]string{}, map[string]string{}, body)
+	}
+	if c.InjectTraceContext {
+		headers, _ = http.InjectTraceContext(ctx, headers)
+	}
+
+	reqMessage := dynamicpb.NewMessage(methodDesc.Input())
+	if len(bytes.TrimSpace(body)) > 0 {
+		if err := unmarshalMessageLeniently(body, reqMessage); err != nil {
+			log.Err(err).Msgf("[GRPCClient.PerformRequest] Failed to convert request body to %s: %s", methodDesc.Input().FullName(), string(body))
+			return 0, nil, nil, err
+		}
+	}
+	reqMessageBytes, err := proto.Marshal(reqMessage)
+	if err != nil {
+		log.Err(err).Msgf("[GRPCClient.PerformRequest] Failed to marshal request message")
+		return 0, nil, nil, err
+	}
+
+	req, err := gohttp.NewRequestWithContext(ctx, gohttp.MethodPost, c.BaseURL+fullMethod, bytes.NewReader(encodeMessageFrame(reqMessageBytes)))
+	if err != nil {
+		log.Err(err).Msgf("[GRPCClient.PerformRequest] Failed to create request of %s", fullMethod)
+		return 0, nil, nil, err
+	}
+	for key, value := range headers {
+		if _, reserved := reservedHeaderKeys[strings.ToLower(key)]; !reserved {
+			req.Header.Set(key, value)
+		}
+	}
+	req.Header.Set("Content-Type", grpcContentType)
+	req.Header.Set("TE", "trailers")
+
+	release, err := c.Politeness.Acquire(ctx)
+	if err != nil {
+		return 0, nil, nil, err
+	}
+	defer release()
+
+	// Requests and responses are captured in JSON, as they are processed by the fuzzer.
+	capture := http.NewHTTPCapture(gohttp.MethodPost, c.BaseURL+fullMethod, headers, nil, body)
+	defer c.CaptureBuffer.Add(capture)
+	var (
+		respHeaders map[string]string
+		respBody    []byte
+	)
+	defer func() {
+		c.HARRecorder.Record(capture, body, respHeaders, respBody)
+	}()
+	statusCode, respHeaders, respBody, err := c.doRequest(req, methodDesc)
+	capture.Duration = time.Since(capture.Time)
+	http.RecordResponseTime(ctx, capture.Duration)
+	if err != nil {
+		log.Err(err).Msgf("[GRPCClient.PerformRequest] Failed to perform request of %s", fullMethod)
+		capture.Error = err.Error()
+		return 0, nil, nil, err
+	}
+
+	// Apply middlewares on response, in reverse order of those on request, like those of HTTP clients.
+	for i := len(c.Middlewares) - 1; i >= 0; i-- {
+		statusCode, respHeaders, respBody, _ = c.Middlewares[i].HandleResponse(fullMethod, gohttp.MethodPost, statusCode, respHeaders, respBody)
+	}
+	capturedHeaders := c.retrieveHeaders(respHeaders)
+	capture.SetResponse(statusCode, capturedHeaders, respBody)
+	return statusCode, capturedHeaders, respBody, nil
+}
+
+// doRequest sends the request of a unary call of the method, and converts the response.
+// It returns the HTTP status code corresponding to the gRPC status, all response headers and trailers, and the JSON response body.
+func (c *GRPCClient) doRequest(req *gohttp.Request, methodDesc protoreflect.MethodDescriptor) (int, map[string]string, []byte, error) {
+	resp, err := c.Client.Do(req)
+	if err != nil {
+		return 0, nil, nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != gohttp.StatusOK {
+		// Not a gRPC response, e.g., from a proxy.
+		respBody, err := io.ReadAll(resp.Body)
+		return resp.StatusCode, mergeHeadersAndTrailers(resp.Header, resp.Trailer), respBody, err
+	}
+	respMessageBytes, err := readMessageFrame(resp.Body)
+	if err != nil {
+		return 0, mergeHeadersAndTrailers(resp.Header, resp.Trailer), nil, fmt.Errorf("failed to read response message: %w", err)
+	}
+	// Trailers are available only after the body is read to EOF.
+	if _, err := io.Copy(io.Discard, resp.Body); err != nil {
+		return 0, mergeHeadersAndTrailers(resp.Header, resp.Trailer), nil, fmt.Errorf("failed to read response: %w", err)
+	}
+	respHeaders := mergeHeadersAndTrailers(resp.Header, resp.Trailer)
+
+	grpcCode, err := strconv.Atoi(respHeaders[GRPCStatusHeaderKey])
+	if err != nil {
+		return 0, respHeaders, nil, fmt.Errorf("invalid %s: %s", GRPCStatusHeaderKey, respHeaders[GRPCStatusHeaderKey])
+	}
+	statusCode := HTTPStatusFromGRPCCode(grpcCode)
+	if grpcCode != 0 || respMessageBytes == nil {
+		// Error responses have no message, so the status is returned as body, like that of grpc-gateway.
+		respBody := fmt.Sprintf(`{"code":%d,"message":%s}`, grpcCode, strconv.Quote(respHeaders[GRPCMessageHeaderKey]))
+		return statusCode, respHeaders, []byte(respBody), nil
+	}
+
+	respMessage := dynamicpb.NewMessage(methodDesc.Output())
+	if err := proto.Unmarshal(respMessageBytes, respMessage); err != nil {
+		return 0, respHeaders, nil, fmt.Errorf("failed to unmarshal response message to %s: %w", methodDesc.Output().FullName(), err)
+	}
+	respBody, err := protojson.Marshal(respMessage)
+	if err != nil {
+		return 0, respHeaders, nil, fmt.Errorf("failed to convert response message to JSON: %w", err)
+	}
+	// Output of protojson is unstable (e.g., in whitespaces) on purpose, so it is compacted.
+	compactRespBody := new(bytes.Buffer)
+	if err := json.Compact(compactRespBody, respBody); err != nil {
+		return statusCode, respHeaders, respBody, nil
+	}
+	return statusCode, respHeaders, compactRespBody.Bytes(), nil
+}
+
+// unmarshalMessageLeniently converts the JSON body to the message, see [protojson].
+// Unknown fields are discarded, as the fuzzer may add extra fields on purpose.
+// If protojson rejects the body as a whole (e.g., fuzzed values of wrong types), fields are converted one by one:
+// string values are also tried as the JSON they contain (e.g., `"true"` as `true`, as parameters are strings), and fields still rejected are dropped,
+// so that the rest of the message is still sent.
+// It returns an error only if the body is not a JSON object.
+func unmarshalMessageLeniently(body []byte, message *dynamicpb.Message) error {
+	unmarshalOptions := protojson.UnmarshalOptions{DiscardUnknown: true}
+	err := unmarshalOptions.Unmarshal(body, message)
+	if err == nil {
+		return nil
+	}
+	var fields map[string]json.RawMessage
+	if json.Unmarshal(body, &fields) != nil {
+		return err
+	}
+	proto.Reset(message)
+	for _, name := range slices.Sorted(maps.Keys(fields)) {
+		converted := false
+		for _, value := range getLenientFieldValues(fields[name]) {
+			fieldJSON, err := json.Marshal(map[string]json.RawMessage{name: value})
+			if err != nil {
+				continue
+			}
+			fieldMessage := dynamicpb.NewMessage(message.Descriptor())
+			if unmarshalOptions.Unmarshal(fieldJSON, fieldMessage) == nil {
+				proto.Merge(message, fieldMessage)
+				converted = true
+				break
+			}
+		}
+		if !converted {
+			log.Debug().Msgf("[unmarshalMessageLeniently] Dropped field %s of %s, whose value is rejected: %s", name, message.Descriptor().FullName(), string(fields[name]))
+		}
+	}
+	return nil
+}
+
+// getLenientFieldValues returns the values to try converting a field from, see [unmarshalMessageLeniently].
+func getLenientFieldValues(value json.RawMessage) []json.RawMessage {
+	values := []json.RawMessage{value}
+	var s string
+	if json.Unmarshal(value, &s) == nil && json.Valid([]byte(s)) {
+		values = append(values, json.RawMessage(s))
+	}
+	return values
+}
+
+// FindMethod finds the method of the service in the registry.
+// The service name can be either a full name (e.g., `shop.v1.CartService`) or a short one (e.g., `CartService`).
+func (c *GRPCClient) FindMethod(serviceName, methodName string) (protoreflect.MethodDescriptor, error) {
+	var serviceDesc protoreflect.ServiceDescriptor
+	if desc, err := c.Files.FindDescriptorByName(protoreflect.FullName(serviceName)); err == nil {
+		serviceDesc, _ = desc.(protoreflect.ServiceDescriptor)
+	}
+	if serviceDesc == nil {
+		c.Files.RangeFiles(func(file protoreflect.FileDescriptor) bool {
+			if desc := file.Services().ByName(protoreflect.Name(serviceName)); desc != nil {
+				serviceDesc = desc
+				return false
+			}
+			return true
+		})
+	}
+	if serviceDesc == nil {
+		return nil, fmt.Errorf("service not found: %s", serviceName)
+	}
+	methodDesc := serviceDesc.Methods().ByName(protoreflect.Name(methodName))
+	if methodDesc == nil {
+		return nil, fmt.Errorf("method %s not found in service %s", methodName, serviceDesc.FullName())
+	}
+	if methodDesc.IsStreamingClient() || methodDesc.IsStreamingServer() {
+		return nil, fmt.Errorf("streaming method %s is not supported", methodDesc.FullName())
+	}
+	return methodDesc, nil
+}
+
+// mergeHeadersAndTrailers merges response headers and trailers into a map of (canonical) keys to the first values.
+// Trailers take precedence, as gRPC status is sent in trailers unless the response is trailers-only.
+func mergeHeadersAndTrailers(respHeaders, respTrailers gohttp.Header) map[string]string {
+	merged := make(map[string]string, len(respHeaders)+len(respTrailers))
+	for _, header := range []gohttp.Header{respHeaders, respTrailers} {
+		for key, values := range header {
+			if len(values) > 0 && values[0] != "" {
+				merged[gohttp.CanonicalHeaderKey(key)] = values[0]
+			}
+		}
+	}
+	// The message is percent-encoded.
+	if decoded, err := url.PathUnescape(merged[GRPCMessageHeaderKey]); err == nil {
+		merged[GRPCMessageHeaderKey] = decoded
+	}
+	return merged
+}
+
+// retrieveHeaders retrieves headers that we care about (i.e., HeadersToCapture and gRPC status) from all response headers, matching keys case-insensitively.
+func (c *GRPCClient) retrieveHeaders(respHeaders map[string]string) map[string]string {
+	retrievedHeaders := make(map[string]string)
+	for _, headerKey := range append([]string{GRPCStatusHeaderKey, GRPCMessageHeaderKey}, c.HeadersToCapture...) {
+		value, exists := respHeaders[headerKey]
+		if !exists {
+			for key, v := range respHeaders {
+				if strings.EqualFold(key, headerKey) {
+					value = v
+					break
+				}
+			}
+		}
+		retrievedHeaders[headerKey] = value
+	}
+	return retrievedHeaders
+}
+
+// HTTPStatusFromGRPCCode returns the HTTP status code corresponding to the gRPC status code,
+// following the mapping of grpc-gateway, so that gRPC responses are processed like HTTP ones.
+// Unknown codes are mapped to 500.
+func HTTPStatusFromGRPCCode(code int) int {
+	switch code {
+	case 0: // OK
+		return gohttp.StatusOK
+	case 1: // CANCELLED
+		return 499
+	case 2: // UNKNOWN
+		return gohttp.StatusInternalServerError
+	case 3: // INVALID_ARGUMENT
+		return gohttp.StatusBadRequest
+	case 4: // DEADLINE_EXCEEDED
+		return gohttp.StatusGatewayTimeout
+	case 5: // NOT_FOUND
+		return gohttp.StatusNotFound
+	case 6: // ALREADY_EXISTS
+		return gohttp.StatusConflict
+	case 7: // PERMISSION_DENIED
+		return gohttp.StatusForbidden
+	case 8: // RESOURCE_EXHAUSTED
+		return gohttp.StatusTooManyRequests
+	case 9: // FAILED_PRECONDITION
+		return gohttp.StatusBadRequest
+	case 10: // ABORTED
+		return gohttp.StatusConflict
+	case 11: // OUT_OF_RANGE
+		return gohttp.StatusBadRequest
+	case 12: // UNIMPLEMENTED
+		return gohttp.StatusNotImplemented
+	case 13: // INTERNAL
+		return gohttp.StatusInternalServerError
+	case 14: // UNAVAILABLE
+		return gohttp.StatusServiceUnavailable
+	case 15: // DATA_LOSS
+		return gohttp.StatusInternalServerError
+	case 16: // UNAUTHENTICATED
+		return gohttp.StatusUnauthorized
+	default:
+		return gohttp.StatusInternalServerError
+	}
+}
+
+// LoadDescriptorSetFile loads proto files in the descriptor set file into a registry.
+// The descriptor set file is a serialized [descriptorpb.FileDescriptorSet], e.g., generated by `protoc --include_imports --descriptor_set_out=...`.
+func LoadDescriptorSetFile(descriptorSetPath string) (*protoregistry.Files, error) {
+	data, err := os.ReadFile(descriptorSetPath)
+	if err != nil {
+		return nil, err
+	}
+	descriptorSet := &descriptorpb.FileDescriptorSet{}
+	if err := proto.Unmarshal(data, descriptorSet); err != nil {
+		return nil, err
+	}
+	return protodesc.NewFiles(descriptorSet)
+}
+
+// encodeMessageFrame encodes the message as a length-prefixed, uncompressed message.
+func encodeMessageFrame(message []byte) []byte {
+	frame := make([]byte, messageFrameHeaderLength, messageFrameHeaderLength+len(message))
+	binary.BigEndian.PutUint32(frame[1:], uint32(len(message)))
+	return append(frame, message...)
+}
+
+// readMessageFrame reads a length-prefixed message.
+// It returns nil if there is no message, e.g., in an error response.
+func readMessageFrame(r io.Reader) ([]byte, error) {
+	header := make([]byte, messageFrameHeaderLength)
+	if _, err := io.ReadFull(r, header); err != nil {
+		if err == io.EOF {
+			return nil, nil
+		}
+		return nil, err
+	}
+	if header[0] != 0 {
+		return nil, fmt.Errorf("compressed response message is not supported")
+	}
+	length := binary.BigEndian.Uint32(header[1:])
+	if length > maxResponseMessageLength {
+		return nil, fmt.Errorf("response message too large: %d bytes", length)
+	}
+	message := make([]byte, length)
+	if _, err := io.ReadFull(r, message); err != nil {
+		return nil, err
+	}
+	return message, nil
+}
+
+// BuildRequestMessageJSON builds the JSON of a request message, from parameters and body of a request generated from OpenAPI doc,
+// e.g., that of grpc-gateway, where path and query parameters are fields of the request message too.
+// Parameters are set as string fields (see [protojson] for accepted formats), nested by dots in names (e.g., `user.id`),
+// and those already set in the body take precedence.
+// The body should be a JSON object, or empty.
+func BuildRequestMessageJSON(pathParams, queryParams map[string]string, body []byte) ([]byte, error) {
+	message := make(map[string]any)
+	if len(bytes.TrimSpace(body)) > 0 {
+		if err := sonic.Unmarshal(body, &message); err != nil {
+			return nil, fmt.Errorf("request body is not a JSON object: %w", err)
+		}
+	}
+	for _, params := range []map[string]string{pathParams, queryParams} {
+		for _, name := range slices.Sorted(maps.Keys(params)) {
+			setNestedField(message, strings.Split(name, "."), params[name])
+		}
+	}
+	return sonic.Marshal(message)
+}
+
+// setNestedField sets the value of the field at path in the message, if it is not set yet.
+// Intermediate messages are created if they do not exist.
+func setNestedField(message map[string]any, path []string, value string) {
+	if len(path) == 1 {
+		if _, exists := message[path[0]]; !exists {
+			message[path[0]] = value
+		}
+		return
+	}
+	child, ok := message[path[0]].(map[string]any)
+	if !ok {
+		if _, exists := message[path[0]]; exists {
+			return
+		}
+		child = make(map[string]any)
+		message[path[0]] = child
+	}
+	setNestedField(child, path[1:], value)
+}
@@ -0,0 +1,283 @@
+package http
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"maps"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/bytedance/sonic"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	// OAuth2GrantTypeClientCredentials is the OAuth2 client credentials grant.
+	OAuth2GrantTypeClientCredentials = "client_credentials"
+
+	// OAuth2GrantTypePassword is the OAuth2 resource owner password credentials grant.
+	OAuth2GrantTypePassword = "password"
+
+	// oauth2GrantTypeRefreshToken is the grant to refresh access token with refresh token.
+	oauth2GrantTypeRefreshToken = "refresh_token"
+
+	// oauth2TokenExpirySkew is how much earlier than its expiry a token is regarded as expired,
+	// to avoid using a token which expires during the request.
+	oauth2TokenExpirySkew = 30 * time.Second
+
+	// oauth2TokenRequestTimeout is the timeout of requests to the token endpoint.
+	oauth2TokenRequestTimeout = 30 * time.Second
+)
+
+// HTTPClientUnauthorizedHandler is an optional interface of [HTTPClientMiddleware].
+// If a middleware implements it, [HTTPClient] calls it when a response is 401 (Unauthorized),
+// and retries the request once if any of such middlewares returns true.
+type HTTPClientUnauthorizedHandler interface {
+	// HandleUnauthorized is called when a response to the request sent at requestTime is 401 (Unauthorized).
+	// It returns true if the credentials are renewed (since requestTime), and the request should be retried.
+	HandleUnauthorized(requestTime time.Time) bool
+}
+
+// OAuth2Config is the configuration of OAuth2 authentication.
+type OAuth2Config struct {
+	// GrantType is the grant type, [OAuth2GrantTypeClientCredentials] or [OAuth2GrantTypePassword].
+	GrantType string
+
+	// TokenURL is the URL of the token endpoint.
+	TokenURL string
+
+	// ClientID is the client identifier.
+	ClientID string
+
+	// ClientSecret is the client secret. It can be empty for public clients.
+	ClientSecret string
+
+	// Username is the username of resource owner, used by password grant only.
+	Username string
+
+	// Password is the password of resource owner, used by password grant only.
+	Password string
+
+	// Scope is the space-delimited scopes requested. It can be empty.
+	Scope string
+}
+
+// oauth2TokenResponse is the successful response of the token endpoint.
+// See [RFC 6749](https://datatracker.ietf.org/doc/html/rfc6749#section-5.1).
+type oauth2TokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int64  `json:"expires_in"`
+	RefreshToken string `json:"refresh_token"`
+}
+
+// oauth2Token is an access token issued by the token endpoint.
+type oauth2Token struct {
+	// accessToken is the access token.
+	accessToken string
+
+	// tokenType is the type of the access token, e.g., `Bearer`.
+	tokenType string
+
+	// refreshToken is the refresh token of the access token. It is empty if not issued.
+	refreshToken string
+
+	// expiry is when the access token expires. It is zero if the token does not expire.
+	expiry time.Time
+
+	// issuedAt is when the access token is issued, i.e., received from the token endpoint.
+	issuedAt time.Time
+}
+
+// isUsable checks whether the token can be used for requests, i.e., it is available and not (about to be) expired.
+func (t *oauth2Token) isUsable() bool {
+	return t != nil && (t.expiry.IsZero() || time.Now().Before(t.expiry.Add(-oauth2TokenExpirySkew)))
+}
+
+// AuthManager manages OAuth2 access tokens for requests, as a built-in [HTTPClientMiddleware].
+// It fetches token from the token endpoint on demand, caches it, and sets it as `Authorization` header of requests.
+// The token is refreshed (with refresh token if available, otherwise by a new grant) when it expires,
+// or when the server responds 401 (Unauthorized) to a request sent with it, see [HTTPClientUnauthorizedHandler].
+// Renewals are serialized, and requests with a usable token are not blocked by them.
+type AuthManager struct {
+	// Config is the OAuth2 configuration.
+	Config OAuth2Config
+
+	// tokenClient is the HTTP client to request the token endpoint.
+	tokenClient *HTTPClient
+
+	// renewMu serializes renewals of the token, so that concurrent requests needing a new token renew it once.
+	// It is held across requests to the token endpoint, while mu is not.
+	renewMu sync.Mutex
+
+	// mu protects token.
+	mu sync.Mutex
+
+	// token is the cached token. It is nil if no token is available.
+	token *oauth2Token
+}
+
+// NewAuthManager creates a new AuthManager.
+// It returns an error if the configuration is invalid.
+func NewAuthManager(oauth2Config OAuth2Config) (*AuthManager, error) {
+	if oauth2Config.GrantType != OAuth2GrantTypeClientCredentials && oauth2Config.GrantType != OAuth2GrantTypePassword {
+		return nil, fmt.Errorf("unsupported OAuth2 grant type: %s", oauth2Config.GrantType)
+	}
+	if oauth2Config.TokenURL == "" {
+		return nil, fmt.Errorf("OAuth2 token URL is empty")
+	}
+	if oauth2Config.ClientID == "" {
+		return nil, fmt.Errorf("OAuth2 client id is empty")
+	}
+	return &AuthManager{
+		Config:      oauth2Config,
+		tokenClient: NewHTTPClient(oauth2Config.TokenURL, []string{}, EmptyHTTPClientMiddlewareSlice()),
+	}, nil
+}
+
+// HandleRequest sets the `Authorization` header of the request with cached access token, fetching a new one if needed.
+// If no token can be fetched, the request is sent without the header.
+// Headers of the caller are not changed, so that the token is not kept in, e.g., operation cases and reports.
+func (m *AuthManager) HandleRequest(path, method string, headers, pathParams, queryParams map[string]string, body []byte) (string, string, map[string]string, map[string]string, map[string]string, []byte, error) {
+	token := m.getToken()
+	if !token.isUsable() {
+		if err := m.renewTokenIfIssuedBefore(time.Now()); err != nil {
+			log.Err(err).Msg("[AuthManager.HandleRequest] Failed to get access token")
+			return path, method, headers, pathParams, queryParams, body, err
+		}
+		token = m.getToken()
+	}
+	headers = maps.Clone(headers)
+	if headers == nil {
+		headers = make(map[string]string)
+	}
+	headers["Authorization"] = fmt.Sprintf("%s %s", token.tokenType, token.accessToken)
+	return path, method, headers, pathParams, queryParams, body, nil
+}
+
+// HandleResponse keeps the response as is.
+func (m *AuthManager) HandleResponse(path, method string, statusCode int, headers map[string]string, body []byte) (int, map[string]string, []byte, error) {
+	return statusCode, headers, body, nil
+}
+
+// HandleUnauthorized renews the access token, as the server rejects the request sent at requestTime,
+// unless the token has been renewed since then, e.g., by another rejected request.
+// It returns true if a token issued after requestTime is available.
+func (m *AuthManager) HandleUnauthorized(requestTime time.Time) bool {
+	if err := m.renewTokenIfIssuedBefore(requestTime); err != nil {
+		log.Err(err).Msg("[AuthManager.HandleUnauthorized] Failed to renew access token")
+		return false
+	}
+	return true
+}
+
+// getToken returns the cached token, which is nil if no token is available.
+func (m *AuthManager) getToken() *oauth2Token {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.token
+}
+
+// renewTokenIfIssuedBefore renews the access token, unless the cached token is usable and issued after t,
+// i.e., it has been renewed since t by another caller.
+func (m *AuthManager) renewTokenIfIssuedBefore(t time.Time) error {
+	m.renewMu.Lock()
+	defer m.renewMu.Unlock()
+	token := m.getToken()
+	if token.isUsable() && token.issuedAt.After(t) {
+		return nil
+	}
+	refreshToken := ""
+	if token != nil {
+		refreshToken = token.refreshToken
+	}
+	newToken, err := m.renewToken(refreshToken)
+	if err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.token = newToken
+	log.Debug().Msg("[AuthManager.renewTokenIfIssuedBefore] Access token renewed")
+	return nil
+}
+
+// renewToken requests a new token, with the refresh token if available, otherwise by a new grant.
+func (m *AuthManager) renewToken(refreshToken string) (*oauth2Token, error) {
+	if refreshToken != "" {
+		form := url.Values{}
+		form.Set("grant_type", oauth2GrantTypeRefreshToken)
+		form.Set("refresh_token", refreshToken)
+		token, err := m.requestToken(form)
+		if err == nil {
+			// A new refresh token may be issued, otherwise keep using the current one.
+			if token.refreshToken == "" {
+				token.refreshToken = refreshToken
+			}
+			return token, nil
+		}
+		// The refresh token may also expire, so fallback to a new grant.
+		log.Warn().Msgf("[AuthManager.renewToken] Failed to refresh access token, request a new one instead: %s", err)
+	}
+
+	form := url.Values{}
+	form.Set("grant_type", m.Config.GrantType)
+	if m.Config.GrantType == OAuth2GrantTypePassword {
+		form.Set("username", m.Config.Username)
+		form.Set("password", m.Config.Password)
+	}
+	if m.Config.Scope != "" {
+		form.Set("scope", m.Config.Scope)
+	}
+	return m.requestToken(form)
+}
+
+// requestToken requests the token endpoint with the form, and returns the issued token.
+// Confidential clients are authenticated by HTTP Basic authentication, and public ones (without secret) send client id in the form.
+func (m *AuthManager) requestToken(form url.Values) (*oauth2Token, error) {
+	headers := map[string]string{
+		"Content-Type": "application/x-www-form-urlencoded",
+		"Accept":       "application/json",
+	}
+	if m.Config.ClientSecret != "" {
+		credentials := url.QueryEscape(m.Config.ClientID) + ":" + url.QueryEscape(m.Config.ClientSecret)
+		headers["Authorization"] = "Basic " + base64.StdEncoding.EncodeToString([]byte(credentials))
+	} else {
+		form.Set("client_id", m.Config.ClientID)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), oauth2TokenRequestTimeout)
+	defer cancel()
+	statusCode, _, respBody, err := m.tokenClient.PerformRequest(ctx, "", "POST", headers, nil, nil, []byte(form.Encode()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to request token endpoint: %w", err)
+	}
+	if !IsStatusCodeSuccess(statusCode) {
+		return nil, fmt.Errorf("token endpoint responds %d: %s", statusCode, string(respBody[:min(256, len(respBody))]))
+	}
+	var tokenResp oauth2TokenResponse
+	if err := sonic.Unmarshal(respBody, &tokenResp); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal token response: %w", err)
+	}
+	if tokenResp.AccessToken == "" {
+		return nil, fmt.Errorf("no access token in token response")
+	}
+
+	token := &oauth2Token{
+		accessToken:  tokenResp.AccessToken,
+		tokenType:    tokenResp.TokenType,
+		refreshToken: tokenResp.RefreshToken,
+		issuedAt:     time.Now(),
+	}
+	// Token type is case insensitive, and we use `Bearer` by default.
+	if token.tokenType == "" || token.tokenType == "bearer" {
+		token.tokenType = "Bearer"
+	}
+	if tokenResp.ExpiresIn > 0 {
+		token.expiry = token.issuedAt.Add(time.Duration(tokenResp.ExpiresIn) * time.Second)
+	}
+	log.Info().Msgf("[AuthManager.requestToken] Got access token by %s grant, expires in %d seconds", form.Get("grant_type"), tokenResp.ExpiresIn)
+	return token, nil
+}
@@ -0,0 +1,133 @@
+package http
+
+import (
+	"maps"
+	"sync"
+	"time"
+)
+
+// MaxCapturedBodySize is the maximal size of request or response body kept in a capture, in bytes.
+// Bodies larger than it are truncated, to keep memory usage of the capture buffer bounded.
+const MaxCapturedBodySize = 4096
+
+// HTTPCapture is a captured pair of HTTP request and response.
+// The request is captured after middlewares are applied, i.e., it is what is actually sent.
+type HTTPCapture struct {
+	// Time is the time when the request is sent.
+	Time time.Time `json:"time"`
+
+	// Duration is the time taken to receive the response.
+	Duration time.Duration `json:"duration"`
+
+	// Method is the HTTP method of the request.
+	Method string `json:"method"`
+
+	// URL is the request URL, with path parameters replaced, but without query string.
+	URL string `json:"URL"`
+
+	// RequestHeaders are the headers of the request, with values of credential headers masked, see [MaskCredentialHeaders].
+	RequestHeaders map[string]string `json:"requestHeaders"`
+
+	// RequestQueryParams are the query parameters of the request.
+	RequestQueryParams map[string]string `json:"requestQueryParams"`
+
+	// RequestBody is the (possibly truncated) body of the request.
+	RequestBody string `json:"requestBody"`
+
+	// ResponseStatusCode is the status code of the response, 0 if the request failed.
+	ResponseStatusCode int `json:"responseStatusCode"`
+
+	// ResponseHeaders are the captured headers of the response, see [HTTPClient.HeadersToCapture], masked as RequestHeaders.
+	ResponseHeaders map[string]string `json:"responseHeaders"`
+
+	// ResponseBody is the (possibly truncated) body of the response.
+	ResponseBody string `json:"responseBody"`
+
+	// Error is the error message if the request failed.
+	Error string `json:"error,omitempty"`
+}
+
+// HTTPCaptureBuffer is a ring buffer keeping the most recent HTTP captures.
+// It is safe for concurrent use, as it may be read by the control API while the fuzzer is running.
+type HTTPCaptureBuffer struct {
+	mu sync.Mutex
+
+	// captures is the underlying ring.
+	captures []*HTTPCapture
+
+	// next is the index in captures to write the next capture to.
+	next int
+
+	// full is true if the ring has been filled, i.e., oldest captures are being overwritten.
+	full bool
+}
+
+// NewHTTPCaptureBuffer creates a new HTTPCaptureBuffer keeping at most `size` captures.
+// It returns nil if size is not positive, and a nil buffer ignores all captures.
+func NewHTTPCaptureBuffer(size int) *HTTPCaptureBuffer {
+	if size <= 0 {
+		return nil
+	}
+	return &HTTPCaptureBuffer{
+		captures: make([]*HTTPCapture, size),
+	}
+}
+
+// Add adds a capture to the buffer, overwriting the oldest one if the buffer is full.
+func (b *HTTPCaptureBuffer) Add(capture *HTTPCapture) {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.captures[b.next] = capture
+	b.next = (b.next + 1) % len(b.captures)
+	if b.next == 0 {
+		b.full = true
+	}
+}
+
+// GetAll returns all captures in the buffer, from the oldest to the newest.
+func (b *HTTPCaptureBuffer) GetAll() []*HTTPCapture {
+	if b == nil {
+		return make([]*HTTPCapture, 0)
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if !b.full {
+		res := make([]*HTTPCapture, b.next)
+		copy(res, b.captures[:b.next])
+		return res
+	}
+	res := make([]*HTTPCapture, 0, len(b.captures))
+	res = append(res, b.captures[b.next:]...)
+	res = append(res, b.captures[:b.next]...)
+	return res
+}
+
+// NewHTTPCapture creates a capture of a request being sent now, e.g., by [HTTPClient] or clients of other protocols over HTTP.
+// Headers and query parameters are copied, where values of credential headers are masked (see [MaskCredentialHeaders]), as captures are served by the control API.
+// The body is truncated to [MaxCapturedBodySize].
+func NewHTTPCapture(method, requestURL string, headers, queryParams map[string]string, body []byte) *HTTPCapture {
+	return &HTTPCapture{
+		Time:               time.Now(),
+		Method:             method,
+		URL:                requestURL,
+		RequestHeaders:     MaskCredentialHeaders(headers),
+		RequestQueryParams: maps.Clone(queryParams),
+		RequestBody:        truncateCapturedBody(body),
+	}
+}
+
+// SetResponse fills the response of the capture, with the captured headers masked, see [HTTPCapture.ResponseHeaders].
+// The body is truncated to [MaxCapturedBodySize].
+func (capture *HTTPCapture) SetResponse(statusCode int, capturedHeaders map[string]string, body []byte) {
+	capture.ResponseStatusCode = statusCode
+	capture.ResponseHeaders = MaskCredentialHeaders(capturedHeaders)
+	capture.ResponseBody = truncateCapturedBody(body)
+}
+
+// truncateCapturedBody converts the body to string, truncated to [MaxCapturedBodySize].
+func truncateCapturedBody(body []byte) string {
+	return string(body[:min(MaxCapturedBodySize, len(body))])
+}
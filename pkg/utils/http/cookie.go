@@ -0,0 +1,96 @@
+package http
+
+import (
+	"context"
+	gohttp "net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"strings"
+
+	"github.com/cloudwego/hertz/pkg/protocol"
+	"github.com/rs/zerolog/log"
+)
+
+// CookieHeaderKey is the header of cookies sent with a request.
+const CookieHeaderKey = "Cookie"
+
+// cookieJarContextKey is the key of the cookie jar in the context, see [ContextWithCookieJar].
+type cookieJarContextKey struct{}
+
+// ContextWithCookieJar returns a copy of ctx carrying a new empty cookie jar.
+// Requests performed by [HTTPClient] with the context (or contexts derived from it) share the jar, i.e.,
+// cookies set by responses (`Set-Cookie`) are stored in the jar, and sent with later requests matching their domain and path.
+// For example, a cookie jar is created for each test scenario, so that sessions established by earlier operations are kept by later ones.
+func ContextWithCookieJar(ctx context.Context) context.Context {
+	// Public suffixes are not checked, as requests are sent to the server under test only.
+	jar, _ := cookiejar.New(nil)
+	return context.WithValue(ctx, cookieJarContextKey{}, jar)
+}
+
+// ContextWithoutCookieJar returns a copy of ctx without cookie jar, e.g., for requests which should not use cookies of the current session.
+func ContextWithoutCookieJar(ctx context.Context) context.Context {
+	return context.WithValue(ctx, cookieJarContextKey{}, (*cookiejar.Jar)(nil))
+}
+
+// getCookieJarFromContext returns the cookie jar of ctx, or nil if there is none.
+func getCookieJarFromContext(ctx context.Context) *cookiejar.Jar {
+	jar, _ := ctx.Value(cookieJarContextKey{}).(*cookiejar.Jar)
+	return jar
+}
+
+// addCookiesFromJar adds cookies of the jar matching the request URL to the Cookie header of the request.
+// Cookies already set in headers are kept, and take precedence over those of the jar with the same name.
+func addCookiesFromJar(jar *cookiejar.Jar, requestURL string, headers map[string]string) {
+	u, err := url.Parse(requestURL)
+	if err != nil {
+		log.Err(err).Msgf("[addCookiesFromJar] Failed to parse request URL: %s", requestURL)
+		return
+	}
+	cookieKey, existingCookies := CookieHeaderKey, ""
+	for key, value := range headers {
+		if strings.EqualFold(key, CookieHeaderKey) {
+			cookieKey, existingCookies = key, value
+			break
+		}
+	}
+	existingCookieNames := make(map[string]struct{})
+	if existingCookies != "" {
+		for _, cookie := range (&gohttp.Request{Header: gohttp.Header{CookieHeaderKey: {existingCookies}}}).Cookies() {
+			existingCookieNames[cookie.Name] = struct{}{}
+		}
+	}
+	cookiePairs := make([]string, 0)
+	if existingCookies != "" {
+		cookiePairs = append(cookiePairs, existingCookies)
+	}
+	for _, cookie := range jar.Cookies(u) {
+		if _, exists := existingCookieNames[cookie.Name]; !exists {
+			cookiePairs = append(cookiePairs, cookie.Name+"="+cookie.Value)
+		}
+	}
+	if len(cookiePairs) > 0 {
+		headers[cookieKey] = strings.Join(cookiePairs, "; ")
+	}
+}
+
+// storeCookiesToJar stores cookies set by the response (all `Set-Cookie` headers) to the jar.
+func storeCookiesToJar(jar *cookiejar.Jar, requestURL string, resp *protocol.Response) {
+	u, err := url.Parse(requestURL)
+	if err != nil {
+		log.Err(err).Msgf("[storeCookiesToJar] Failed to parse request URL: %s", requestURL)
+		return
+	}
+	cookies := make([]*gohttp.Cookie, 0)
+	resp.Header.VisitAllCookie(func(_, value []byte) {
+		cookie, err := gohttp.ParseSetCookie(string(value))
+		if err != nil {
+			log.Warn().Msgf("[storeCookiesToJar] Invalid Set-Cookie header: %s", string(value))
+			return
+		}
+		cookies = append(cookies, cookie)
+	})
+	if len(cookies) > 0 {
+		jar.SetCookies(u, cookies)
+		log.Debug().Msgf("[storeCookiesToJar] Stored %d cookies set by response of %s", len(cookies), requestURL)
+	}
+}
@@ -0,0 +1,256 @@
+package http
+
+import (
+	gohttp "net/http"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bytedance/sonic"
+	"github.com/rs/zerolog/log"
+)
+
+// MaxHARBodySize is the maximal size of request or response body kept in a HAR entry, in bytes.
+// Bodies larger than it are truncated, as entries are kept in memory until the HAR file is written.
+const MaxHARBodySize = 1 << 20
+
+// MaxHAREntries is the default maximal number of entries kept by a [HARRecorder], so that memory is bounded on long runs.
+const MaxHAREntries = 10000
+
+// harCreatorName is the name of the creator of HAR files.
+const harCreatorName = "rest_trace_fuzzer"
+
+// harNameValue is a name-value pair of HAR, e.g., a header or a query parameter.
+type harNameValue struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// harPostData is the body of a HAR request.
+type harPostData struct {
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text"`
+}
+
+// harRequest is a HAR request.
+type harRequest struct {
+	Method      string         `json:"method"`
+	URL         string         `json:"url"`
+	HTTPVersion string         `json:"httpVersion"`
+	Cookies     []harNameValue `json:"cookies"`
+	Headers     []harNameValue `json:"headers"`
+	QueryString []harNameValue `json:"queryString"`
+	PostData    *harPostData   `json:"postData,omitempty"`
+	HeadersSize int            `json:"headersSize"`
+	BodySize    int            `json:"bodySize"`
+}
+
+// harContent is the body of a HAR response.
+type harContent struct {
+	Size     int    `json:"size"`
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text"`
+}
+
+// harResponse is a HAR response.
+type harResponse struct {
+	Status      int            `json:"status"`
+	StatusText  string         `json:"statusText"`
+	HTTPVersion string         `json:"httpVersion"`
+	Cookies     []harNameValue `json:"cookies"`
+	Headers     []harNameValue `json:"headers"`
+	Content     harContent     `json:"content"`
+	RedirectURL string         `json:"redirectURL"`
+	HeadersSize int            `json:"headersSize"`
+	BodySize    int            `json:"bodySize"`
+}
+
+// harTimings are the timings of a HAR entry, in milliseconds.
+// Only the total time is measured, which is reported as waiting time.
+type harTimings struct {
+	Send    float64 `json:"send"`
+	Wait    float64 `json:"wait"`
+	Receive float64 `json:"receive"`
+}
+
+// harEntry is a HAR entry, i.e., a pair of request and response.
+type harEntry struct {
+	StartedDateTime string         `json:"startedDateTime"`
+	Time            float64        `json:"time"`
+	Request         harRequest     `json:"request"`
+	Response        harResponse    `json:"response"`
+	Cache           map[string]any `json:"cache"`
+	Timings         harTimings     `json:"timings"`
+
+	// Error is the error message if the request failed, as a custom field of HAR.
+	Error string `json:"_error,omitempty"`
+}
+
+// harLog is the root of a HAR file.
+type harLog struct {
+	Version string            `json:"version"`
+	Creator map[string]string `json:"creator"`
+	Entries []*harEntry       `json:"entries"`
+}
+
+// HARRecorder records the most recent requests and responses of HTTP clients, and writes them to a [HAR 1.2](http://www.softwareishard.com/blog/har-12-spec/) file,
+// so that a run can be inspected in browser devtools, or replayed by other tools.
+// Like [HTTPCaptureBuffer], requests are recorded after middlewares are applied into a ring, but all response headers are recorded.
+// Values of credential headers are masked (see [MaskCredentialHeaders]), so that the HAR file can be shared.
+// It is safe for concurrent use, and a nil recorder ignores all requests.
+type HARRecorder struct {
+	mu sync.Mutex
+
+	// entries is the underlying ring of recorded entries.
+	entries []*harEntry
+
+	// next is the index in entries to write the next entry to.
+	next int
+
+	// full is true if the ring has been filled, i.e., oldest entries are being overwritten.
+	full bool
+
+	// droppedCount is the number of oldest entries overwritten.
+	droppedCount int
+}
+
+// NewHARRecorder creates a new empty HARRecorder keeping at most `maxEntries` entries, e.g., [MaxHAREntries].
+// It returns nil if maxEntries is not positive, and a nil recorder ignores all requests.
+func NewHARRecorder(maxEntries int) *HARRecorder {
+	if maxEntries <= 0 {
+		return nil
+	}
+	return &HARRecorder{
+		entries: make([]*harEntry, maxEntries),
+	}
+}
+
+// Record records the captured request and its response. Bodies and response headers are passed separately,
+// as those of the capture are truncated and filtered, see [HTTPCapture].
+func (r *HARRecorder) Record(capture *HTTPCapture, requestBody []byte, responseHeaders map[string]string, responseBody []byte) {
+	if r == nil {
+		return
+	}
+	requestURL := capture.URL
+	if len(capture.RequestQueryParams) > 0 {
+		requestURL += "?" + paramDict2QueryStr(capture.RequestQueryParams)
+	}
+	durationMs := float64(capture.Duration.Microseconds()) / 1000
+	entry := &harEntry{
+		StartedDateTime: capture.Time.Format(time.RFC3339Nano),
+		Time:            durationMs,
+		Request: harRequest{
+			Method:      capture.Method,
+			URL:         requestURL,
+			HTTPVersion: "HTTP/1.1",
+			Cookies:     make([]harNameValue, 0),
+			Headers:     convertMapToHARNameValues(MaskCredentialHeaders(capture.RequestHeaders)),
+			QueryString: convertMapToHARNameValues(capture.RequestQueryParams),
+			HeadersSize: -1,
+			BodySize:    len(requestBody),
+		},
+		Response: harResponse{
+			Status:      capture.ResponseStatusCode,
+			StatusText:  gohttp.StatusText(capture.ResponseStatusCode),
+			HTTPVersion: "HTTP/1.1",
+			Cookies:     make([]harNameValue, 0),
+			Headers:     convertMapToHARNameValues(MaskCredentialHeaders(responseHeaders)),
+			Content: harContent{
+				Size:     len(responseBody),
+				MimeType: GetHeaderValue(responseHeaders, "Content-Type"),
+				Text:     string(responseBody[:min(MaxHARBodySize, len(responseBody))]),
+			},
+			HeadersSize: -1,
+			BodySize:    len(responseBody),
+		},
+		Cache:   make(map[string]any),
+		Timings: harTimings{Wait: durationMs},
+		Error:   capture.Error,
+	}
+	if len(requestBody) > 0 {
+		entry.Request.PostData = &harPostData{
+			MimeType: GetHeaderValue(capture.RequestHeaders, "Content-Type"),
+			Text:     string(requestBody[:min(MaxHARBodySize, len(requestBody))]),
+		}
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.full {
+		r.droppedCount++
+	}
+	r.entries[r.next] = entry
+	r.next = (r.next + 1) % len(r.entries)
+	if r.next == 0 {
+		r.full = true
+	}
+}
+
+// GetEntryCount returns the number of kept entries.
+func (r *HARRecorder) GetEntryCount() int {
+	if r == nil {
+		return 0
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.full {
+		return len(r.entries)
+	}
+	return r.next
+}
+
+// getEntries returns the kept entries, from the oldest to the newest, and the number of dropped entries.
+func (r *HARRecorder) getEntries() ([]*harEntry, int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if !r.full {
+		return slices.Clone(r.entries[:r.next]), r.droppedCount
+	}
+	entries := make([]*harEntry, 0, len(r.entries))
+	entries = append(entries, r.entries[r.next:]...)
+	entries = append(entries, r.entries[:r.next]...)
+	return entries, r.droppedCount
+}
+
+// WriteHARFile writes all kept entries to a HAR file at path, in order of recording.
+func (r *HARRecorder) WriteHARFile(path string) error {
+	if r == nil {
+		return nil
+	}
+	entries, droppedCount := r.getEntries()
+	if droppedCount > 0 {
+		log.Warn().Msgf("[HARRecorder.WriteHARFile] %d oldest entries were dropped, as at most %d entries are kept", droppedCount, len(entries))
+	}
+	har := map[string]*harLog{
+		"log": {
+			Version: "1.2",
+			Creator: map[string]string{"name": harCreatorName, "version": "1.0"},
+			Entries: entries,
+		},
+	}
+	harBytes, err := sonic.Marshal(har)
+	if err != nil {
+		log.Err(err).Msg("[HARRecorder.WriteHARFile] Failed to marshal HAR")
+		return err
+	}
+	err = os.WriteFile(path, harBytes, 0644)
+	if err != nil {
+		log.Err(err).Msgf("[HARRecorder.WriteHARFile] Failed to write HAR file: %s", path)
+		return err
+	}
+	log.Info().Msgf("[HARRecorder.WriteHARFile] Wrote %d entries to HAR file: %s", len(har["log"].Entries), path)
+	return nil
+}
+
+// convertMapToHARNameValues converts a map (e.g., headers) to HAR name-value pairs, sorted by name.
+func convertMapToHARNameValues(m map[string]string) []harNameValue {
+	nameValues := make([]harNameValue, 0, len(m))
+	for name, value := range m {
+		nameValues = append(nameValues, harNameValue{Name: name, Value: value})
+	}
+	slices.SortFunc(nameValues, func(a, b harNameValue) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+	return nameValues
+}
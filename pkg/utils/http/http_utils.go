@@ -7,11 +7,18 @@ package http
 import (
 	"context"
 	"crypto/tls"
+	"errors"
+	"maps"
+	"net"
 	"net/url"
+	"slices"
 	"strings"
+	"time"
+	"unicode"
 
 	"github.com/cloudwego/hertz/pkg/app/client"
 	hertzconfig "github.com/cloudwego/hertz/pkg/common/config"
+	errs "github.com/cloudwego/hertz/pkg/common/errors"
 	"github.com/cloudwego/hertz/pkg/network/standard"
 	"github.com/cloudwego/hertz/pkg/protocol"
 	"github.com/cloudwego/hertz/pkg/protocol/consts"
@@ -32,6 +39,22 @@ type HTTPClient struct {
 
 	// Middlewares are the middlewares used to process the request and response.
 	Middlewares []HTTPClientMiddleware
+
+	// CaptureBuffer keeps the most recent requests and responses, for live debugging.
+	// It is nil (i.e., capture disabled) by default.
+	CaptureBuffer *HTTPCaptureBuffer
+
+	// Politeness throttles requests, and backs off when the server is overloaded.
+	// It is nil (i.e., no throttling) by default.
+	Politeness *HTTPClientPoliteness
+
+	// HARRecorder records all requests and responses, to be written to a HAR file.
+	// It is nil (i.e., recording disabled) by default.
+	HARRecorder *HARRecorder
+
+	// InjectTraceContext tells whether each attempt of requests starts a new trace, by a traceparent header, see [InjectTraceContext].
+	// It is false by default, e.g., for clients of trace backends.
+	InjectTraceContext bool
 }
 
 // NewHTTPClient creates a new HTTPClient.
@@ -63,7 +86,8 @@ func NewHTTPClient(baseURL string, headersToCapture []string, middlewares []HTTP
 // It retries the request up to maxRetry times if a timeout error occurs.
 // If the request fails for any other reason, it returns the error immediately.
 // If all retry attempts fail due to timeout, it logs an error and returns the last error encountered.
-func (c *HTTPClient) PerformRequestWithRetry(path, method string, headers map[string]string, pathParams, queryParams map[string]string, body []byte, maxRetry int) (int, map[string]string, []byte, error) {
+// It stops retrying once ctx is done.
+func (c *HTTPClient) PerformRequestWithRetry(ctx context.Context, path, method string, headers map[string]string, pathParams, queryParams map[string]string, body []byte, maxRetry int) (int, map[string]string, []byte, error) {
 	// If maxRetry is invalid, fallback to 1
 	if maxRetry <= 0 {
 		log.Warn().Msgf("[HTTPClient.PerformRequestWithRetry] Invalid max retry: %d, fallback to 1", maxRetry)
@@ -73,7 +97,11 @@ func (c *HTTPClient) PerformRequestWithRetry(path, method string, headers map[st
 	// Retry only when timeout
 	var err error
 	for i := range maxRetry {
-		statusCode, headers, respBodyBytes, err := c.PerformRequest(path, method, headers, pathParams, queryParams, body)
+		if ctx.Err() != nil {
+			log.Warn().Msgf("[HTTPClient.PerformRequestWithRetry] Context done after %d retries, URL: %s, method: %s", i, c.BaseURL+path, method)
+			return 0, nil, nil, ctx.Err()
+		}
+		statusCode, headers, respBodyBytes, err := c.PerformRequest(ctx, path, method, headers, pathParams, queryParams, body)
 		if err != nil {
 			if strings.Contains(string(err.Error()), "timeout") {
 				log.Warn().Msgf("[HTTPClient.PerformRequestWithRetry] Retry %d times due to timeout, URL: %s, method: %s", i+1, c.BaseURL+path, method)
@@ -91,7 +119,51 @@ func (c *HTTPClient) PerformRequestWithRetry(path, method string, headers map[st
 // PerformRequest performs an HTTP request.
 // You do not have to encode the path params and query params, just pass them as a map. The function will do the encoding for you.
 // It returns the status code, headers that we care about, the response body in bytes, and an error if any.
-func (c *HTTPClient) PerformRequest(path, method string, headers map[string]string, pathParams, queryParams map[string]string, body []byte) (int, map[string]string, []byte, error) {
+// If ctx is already done, the request is not sent; if ctx has a deadline, the request would be aborted at the deadline.
+// If the response is 401 (Unauthorized) and any middleware renews credentials (see [HTTPClientUnauthorizedHandler]), the request is retried once.
+// If the server asks to back off (see [HTTPClientPoliteness]), the request is retried after waiting, and the last response is returned.
+// If ctx carries a cookie jar (see [ContextWithCookieJar]), cookies are sent from and stored to it.
+func (c *HTTPClient) PerformRequest(ctx context.Context, path, method string, headers map[string]string, pathParams, queryParams map[string]string, body []byte) (int, map[string]string, []byte, error) {
+	for retryCount := 0; ; retryCount++ {
+		statusCode, respHeaders, respBodyBytes, err := c.performRequestWithReauth(ctx, path, method, headers, pathParams, queryParams, body)
+		if err != nil {
+			return statusCode, nil, nil, err
+		}
+		wait, shouldRetry := c.Politeness.GetBackoff(statusCode, GetHeaderValue(respHeaders, RetryAfterHeaderKey), retryCount)
+		if shouldRetry {
+			log.Warn().Msgf("[HTTPClient.PerformRequest] Server responds %d, back off for %s before retry %d, URL: %s, method: %s", statusCode, wait, retryCount+1, c.BaseURL+path, method)
+			shouldRetry = sleepWithContext(ctx, wait) == nil
+		}
+		if !shouldRetry {
+			return statusCode, c.retrieveHeaders(respHeaders), respBodyBytes, nil
+		}
+	}
+}
+
+// performRequestWithReauth performs an HTTP request, and retries it once if credentials are renewed on 401 (Unauthorized).
+// It returns all response headers. See [HTTPClient.PerformRequest].
+func (c *HTTPClient) performRequestWithReauth(ctx context.Context, path, method string, headers map[string]string, pathParams, queryParams map[string]string, body []byte) (int, map[string]string, []byte, error) {
+	requestTime := time.Now()
+	statusCode, respHeaders, respBodyBytes, err := c.performRequestOnce(ctx, path, method, headers, pathParams, queryParams, body)
+	if err != nil || statusCode != consts.StatusUnauthorized {
+		return statusCode, respHeaders, respBodyBytes, err
+	}
+	credentialsRenewed := false
+	for _, middleware := range c.Middlewares {
+		if handler, ok := middleware.(HTTPClientUnauthorizedHandler); ok && handler.HandleUnauthorized(requestTime) {
+			credentialsRenewed = true
+		}
+	}
+	if !credentialsRenewed {
+		return statusCode, respHeaders, respBodyBytes, err
+	}
+	log.Debug().Msgf("[HTTPClient.performRequestWithReauth] Retry request with renewed credentials, URL: %s, method: %s", c.BaseURL+path, method)
+	return c.performRequestOnce(ctx, path, method, headers, pathParams, queryParams, body)
+}
+
+// performRequestOnce performs an HTTP request, without retry.
+// It returns all response headers (after middlewares), instead of those we care about only. See [HTTPClient.PerformRequest].
+func (c *HTTPClient) performRequestOnce(ctx context.Context, path, method string, headers map[string]string, pathParams, queryParams map[string]string, body []byte) (int, map[string]string, []byte, error) {
 	// In case of nil values, initialize them
 	if headers == nil {
 		headers = make(map[string]string)
@@ -115,16 +187,23 @@ func (c *HTTPClient) PerformRequest(path, method string, headers map[string]stri
 		protocol.ReleaseRequest(req)
 		protocol.ReleaseResponse(resp)
 	}()
-	requestURL := c.BaseURL + path
+	requestURL := BuildRequestURL(c.BaseURL, path, pathParams, nil)
 
-	// Set path params
-	if len(queryParams) > 0 {
-		req.SetQueryString(paramDict2QueryStr(queryParams))
+	// Send cookies of the cookie jar of ctx, if any, without changing headers of the caller
+	cookieJar := getCookieJarFromContext(ctx)
+	if cookieJar != nil {
+		headers = maps.Clone(headers)
+		addCookiesFromJar(cookieJar, requestURL, headers)
 	}
 
-	// Set path params, replacing the path params in the URL
-	for k, v := range pathParams {
-		requestURL = strings.ReplaceAll(requestURL, "{"+k+"}", url.PathEscape(v))
+	// Start a new trace for each attempt, so that retried and replayed requests do not share traces
+	if c.InjectTraceContext {
+		headers, _ = InjectTraceContext(ctx, headers)
+	}
+
+	// Set query params
+	if len(queryParams) > 0 {
+		req.SetQueryString(paramDict2QueryStr(queryParams))
 	}
 
 	req.SetRequestURI(requestURL)
@@ -132,31 +211,134 @@ func (c *HTTPClient) PerformRequest(path, method string, headers map[string]stri
 	req.SetMethod(method)
 	req.SetBody(body)
 
+	// Wait for the rate and concurrency limits, if any
+	release, err := c.Politeness.Acquire(ctx)
+	if err != nil {
+		log.Err(err).Msgf("[HTTPClient.PerformRequest] Context done while waiting to send request, URL: %s, method: %s", requestURL, method)
+		return 0, nil, nil, err
+	}
+	defer release()
+
 	log.Debug().Msgf("[HTTPClient.PerformRequest] Perform request, URL: %s, method: %s, headers: %v, query params: %v, body: %s", requestURL, method, headers, queryParams, string(body))
-	err := c.Client.Do(context.Background(), req, resp)
+	capture := NewHTTPCapture(method, requestURL, headers, queryParams, body)
+	defer c.CaptureBuffer.Add(capture)
+	// Response headers and body are recorded in HAR entirely, while they are filtered and truncated in the capture.
+	var (
+		respHeaders   map[string]string
+		respBodyBytes []byte
+	)
+	defer func() {
+		c.HARRecorder.Record(capture, body, respHeaders, respBodyBytes)
+	}()
+	err = ctx.Err()
+	if err == nil {
+		// Hertz client does not watch ctx itself, so we pass the deadline explicitly.
+		if deadline, ok := ctx.Deadline(); ok {
+			err = c.Client.DoDeadline(ctx, req, resp, deadline)
+		} else {
+			err = c.Client.Do(ctx, req, resp)
+		}
+	}
+	capture.Duration = time.Since(capture.Time)
+	RecordResponseTime(ctx, capture.Duration)
 	if err != nil {
 		log.Err(err).Msgf("[HTTPClient.PerformRequest] Failed to perform request, URL: %s, method: %s", requestURL, method)
+		capture.Error = err.Error()
 		return 0, nil, nil, err
 	}
-	respBodyBytes, err := resp.BodyE()
+	respBodyBytes, err = resp.BodyE()
 	if err != nil {
 		log.Err(err).Msgf("[HTTPClient.PerformRequest] Failed to get response body, URL: %s, method: %s", requestURL, method)
+		capture.Error = err.Error()
+		respBodyBytes = nil
 		return 0, nil, nil, err
 	}
+	if cookieJar != nil {
+		storeCookiesToJar(cookieJar, requestURL, resp)
+	}
 	// we do not log whole response body, for some responses may be too large
 	statusCode := resp.StatusCode()
 	log.Debug().Msgf("[HTTPClient.PerformRequest] Response, status code: %d, response body (64 bytes at most): %s", statusCode, string(respBodyBytes[:min(64, len(respBodyBytes))]))
-	// retrieve headers that we care about
+
+	// Apply middlewares on response, in reverse order of those on request.
+	// Middlewares see all response headers, while only headers that we care about are returned.
+	respHeaders = make(map[string]string)
+	resp.Header.VisitAll(func(key, value []byte) {
+		respHeaders[string(key)] = string(value)
+	})
+	// The response body is owned by the response, which would be released, so we copy it before passing it to middlewares.
+	respBodyBytes = slices.Clone(respBodyBytes)
+	for i := len(c.Middlewares) - 1; i >= 0; i-- {
+		// errors are ignored here, as on request
+		statusCode, respHeaders, respBodyBytes, _ = c.Middlewares[i].HandleResponse(path, method, statusCode, respHeaders, respBodyBytes)
+	}
+
+	capture.SetResponse(statusCode, c.retrieveHeaders(respHeaders), respBodyBytes)
+	return statusCode, respHeaders, respBodyBytes, nil
+}
+
+// responseTimeContextKey is the key of the response time in the context, see [ContextWithResponseTime].
+type responseTimeContextKey struct{}
+
+// ContextWithResponseTime returns a copy of ctx carrying a response time, and the pointer to it.
+// Requests performed with the context (or contexts derived from it) set the response time to the time taken by their last attempt, see [RecordResponseTime],
+// i.e., earlier attempts retried (e.g., after 429 or 401), backoff waits and waits for rate limits are excluded.
+// It stays 0 if no attempt is sent.
+func ContextWithResponseTime(ctx context.Context) (context.Context, *time.Duration) {
+	responseTime := new(time.Duration)
+	return context.WithValue(ctx, responseTimeContextKey{}, responseTime), responseTime
+}
+
+// RecordResponseTime sets the response time of ctx (see [ContextWithResponseTime]) to the time taken by an attempt of a request.
+// It does nothing if ctx carries no response time.
+func RecordResponseTime(ctx context.Context, duration time.Duration) {
+	if responseTime, ok := ctx.Value(responseTimeContextKey{}).(*time.Duration); ok {
+		*responseTime = duration
+	}
+}
+
+// retrieveHeaders retrieves headers that we care about (i.e., HeadersToCapture) from all response headers.
+func (c *HTTPClient) retrieveHeaders(respHeaders map[string]string) map[string]string {
 	retrievedHeaders := make(map[string]string)
 	for _, headerKey := range c.HeadersToCapture {
-		retrievedHeaders[headerKey] = resp.Header.Get(headerKey)
+		retrievedHeaders[headerKey] = GetHeaderValue(respHeaders, headerKey)
 	}
-	return statusCode, retrievedHeaders, respBodyBytes, nil
+	return retrievedHeaders
 }
 
 // PerformGet performs an HTTP GET request.
-func (c *HTTPClient) PerformGet(path string, headers map[string]string, pathParams, queryParams map[string]string) (int, map[string]string, []byte, error) {
-	return c.PerformRequest(path, "GET", headers, pathParams, queryParams, nil)
+func (c *HTTPClient) PerformGet(ctx context.Context, path string, headers map[string]string, pathParams, queryParams map[string]string) (int, map[string]string, []byte, error) {
+	return c.PerformRequest(ctx, path, "GET", headers, pathParams, queryParams, nil)
+}
+
+// GetHeaderValue returns the value of the header in headers, matching the key case-insensitively.
+// It returns empty string if the header does not exist.
+func GetHeaderValue(headers map[string]string, key string) string {
+	if value, exists := headers[key]; exists {
+		return value
+	}
+	for k, v := range headers {
+		if strings.EqualFold(k, key) {
+			return v
+		}
+	}
+	return ""
+}
+
+// BuildRequestURL builds the URL of a request, by replacing path params (escaped) in the path, and appending the (encoded) query string.
+// The query string is omitted if there is no query param.
+//
+// For example, if the base URL is `http://localhost:8080`, the path is `/users/{id}`, path params are {"id": "1"}, and query params are {"a": "1"},
+// the output is `http://localhost:8080/users/1?a=1`.
+func BuildRequestURL(baseURL, path string, pathParams, queryParams map[string]string) string {
+	requestURL := baseURL + path
+	for k, v := range pathParams {
+		requestURL = strings.ReplaceAll(requestURL, "{"+k+"}", url.PathEscape(v))
+	}
+	if len(queryParams) > 0 {
+		requestURL += "?" + paramDict2QueryStr(queryParams)
+	}
+	return requestURL
 }
 
 // paramDict2QueryStr converts a map of parameters to a query string.
@@ -205,6 +387,19 @@ func IsStatusCodeSuccess(statusCode int) bool {
 	return GetStatusCodeClass(statusCode) == consts.StatusOK
 }
 
+// IsTimeoutError checks whether an error of a request is a timeout, e.g., of dialing, reading the response, or the deadline of the request.
+// Other errors of requests without a response are connection errors, e.g., connection refused or reset.
+func IsTimeoutError(err error) bool {
+	if err == nil {
+		return false
+	}
+	if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, errs.ErrTimeout) || errors.Is(err, errs.ErrIdleTimeout) {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
 // GetAllStatusCodeClasses returns all status code classes.
 func GetAllStatusCodeClasses() []int {
 	return []int{
@@ -215,3 +410,42 @@ func GetAllStatusCodeClasses() []int {
 		consts.StatusInternalServerError,
 	}
 }
+
+// DefaultRouteSafePathParamValue is the value of path parameters that cannot be made route-safe by [SanitizePathParamValue].
+// It is valid for both numeric and string identifiers.
+const DefaultRouteSafePathParamValue = "1"
+
+// routeUnsafePathParamChars are characters of path parameter values that break routing even when escaped,
+// as servers and proxies may decode them before routing (e.g., `%2F` to `/`).
+const routeUnsafePathParamChars = "/\\?#%"
+
+// IsRouteSafePathParamValue checks whether a path parameter value keeps the request routed to the endpoint of the parameter.
+// A route-safe value is non-empty, not a dot segment (`.` or `..`), and contains no slash, backslash, `?`, `#`, `%` or control characters.
+func IsRouteSafePathParamValue(value string) bool {
+	if value == "" || value == "." || value == ".." {
+		return false
+	}
+	for _, r := range value {
+		if unicode.IsControl(r) || strings.ContainsRune(routeUnsafePathParamChars, r) {
+			return false
+		}
+	}
+	return true
+}
+
+// SanitizePathParamValue makes a path parameter value route-safe (see [IsRouteSafePathParamValue]),
+// by replacing unsafe characters with `_`. If the value is empty or a dot segment, [DefaultRouteSafePathParamValue] is returned.
+//
+// For example, "a/b" is sanitized to "a_b", and "" to "1".
+func SanitizePathParamValue(value string) string {
+	sanitized := strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) || strings.ContainsRune(routeUnsafePathParamChars, r) {
+			return '_'
+		}
+		return r
+	}, value)
+	if sanitized == "" || sanitized == "." || sanitized == ".." {
+		return DefaultRouteSafePathParamValue
+	}
+	return sanitized
+}
@@ -10,6 +10,7 @@ import (
 	"os"
 
 	"maps"
+	"sync"
 
 	"github.com/rs/zerolog/log"
 	"go.starlark.net/starlark"
@@ -24,6 +25,11 @@ type HTTPClientMiddleware interface {
 	// It takes the request path, method, headers, path parameters, query parameters, and body as input.
 	// It returns the modified request path, method, headers, path parameters, query parameters, body, and an error if any.
 	HandleRequest(path, method string, headers map[string]string, pathParams, queryParams map[string]string, body []byte) (resPath, resMethod string, resHeaders map[string]string, resPathParams, resQueryParams map[string]string, resBody []byte, err error)
+
+	// HandleResponse processes the HTTP response.
+	// It takes the request path and method (after all middlewares on request), and the response status code, headers, and body as input.
+	// It returns the modified status code, headers, body, and an error if any.
+	HandleResponse(path, method string, statusCode int, headers map[string]string, body []byte) (resStatusCode int, resHeaders map[string]string, resBody []byte, err error)
 }
 
 // EmptyHTTPClientMiddlewareSlice returns an empty slice of HTTPClientMiddleware.
@@ -44,6 +50,20 @@ func EmptyHTTPClientMiddlewareSlice() []HTTPClientMiddleware {
 //	queryParams = {"search": "new_query"}
 //	body = "[1, 2, 3]"
 //
+// To handle responses, the script can define a function "handleResponse", which takes path, method, statusCode, headers and body of the response,
+// and returns None to keep the response, or a Dict with (any of) keys "statusCode", "headers" and "body" to replace those of the response.
+// A global Dict "state" is shared among all executions of the script, e.g., to pass a token extracted from a response to subsequent requests.
+// For example:
+//
+//	# Example Starlark script
+//	headers = {"Authorization": "Bearer " + state.get("token", "")}
+//
+//	def handleResponse(path, method, statusCode, headers, body):
+//	    if path == "/login" and "X-Auth-Token" in headers:
+//	        state["token"] = headers["X-Auth-Token"]
+//	    headers.pop("Set-Cookie", None)
+//	    return {"headers": headers}
+//
 // It can be used for logging, authentication, modifying headers, etc.
 // For how to write Starlark scripts, see: https://github.com/google/starlark-go/blob/master/doc/spec.md
 type HTTPClientScriptMiddleware struct {
@@ -55,6 +75,12 @@ type HTTPClientScriptMiddleware struct {
 
 	// Script is the content of the Starlark script.
 	Script []byte `json:"script"`
+
+	// state is the global "state" Dict of the script, shared among executions.
+	state *starlark.Dict
+
+	// mu serializes executions of the script, as they share the state.
+	mu sync.Mutex
 }
 
 // NewHTTPClientMiddleware creates a new HTTPClientScriptMiddleware.
@@ -77,19 +103,29 @@ func NewHTTPClientScriptMiddleware(scriptPath string) *HTTPClientScriptMiddlewar
 	return &HTTPClientScriptMiddleware{
 		ScriptPath: scriptPath,
 		Script:     script,
+		state:      starlark.NewDict(0),
 	}
 }
 
+// execScript executes the script, with the shared state predeclared, and returns the globals.
+// The caller must hold the lock.
+func (m *HTTPClientScriptMiddleware) execScript() (starlark.StringDict, error) {
+	thread := &starlark.Thread{Name: "http_middleware_script"}
+	fileOptions := syntax.LegacyFileOptions()
+	predeclared := starlark.StringDict{"state": m.state}
+	return starlark.ExecFileOptions(fileOptions, thread, m.ScriptPath, m.Script, predeclared)
+}
+
 // HandleRequest runs the Starlark script to handle the request.
 // The script can modify the request by returning modified values for headers, path parameters, query parameters, and body.
 // The script should define global variables "headers", "pathParams", "queryParams", and "body" to return the modified values.
 // It returns the modified request path, method, headers, path parameters, query parameters, body, and an error if any.
 // Note: The parameters headers, pathParams, and queryParams are passed by reference and would be modified directly.
 func (m *HTTPClientScriptMiddleware) HandleRequest(path, method string, headers, pathParams, queryParams map[string]string, body []byte) (string, string, map[string]string, map[string]string, map[string]string, []byte, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
 	// Try to run the script
-	thread := &starlark.Thread{Name: "http_middleware_script"}
-	fileOptions := syntax.LegacyFileOptions()
-	globals, err := starlark.ExecFileOptions(fileOptions, thread, m.ScriptPath, m.Script, nil)
+	globals, err := m.execScript()
 	if err != nil {
 		log.Err(err).Msg("[HTTPClientScriptMiddleware.HandleRequest] Failed to execute script")
 		return path, method, headers, pathParams, queryParams, body, err
@@ -152,6 +188,74 @@ func (m *HTTPClientScriptMiddleware) HandleRequest(path, method string, headers,
 	return path, method, headers, pathParams, queryParams, body, nil
 }
 
+// HandleResponse runs the function "handleResponse" defined in the Starlark script to handle the response.
+// If the script does not define the function, the response is kept.
+// It returns the modified status code, headers, body, and an error if any.
+func (m *HTTPClientScriptMiddleware) HandleResponse(path, method string, statusCode int, headers map[string]string, body []byte) (int, map[string]string, []byte, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	globals, err := m.execScript()
+	if err != nil {
+		log.Err(err).Msg("[HTTPClientScriptMiddleware.HandleResponse] Failed to execute script")
+		return statusCode, headers, body, err
+	}
+	handler, ok := globals["handleResponse"]
+	if !ok {
+		return statusCode, headers, body, nil
+	}
+	headersDict := starlark.NewDict(len(headers))
+	for k, v := range headers {
+		headersDict.SetKey(starlark.String(k), starlark.String(v))
+	}
+	args := starlark.Tuple{starlark.String(path), starlark.String(method), starlark.MakeInt(statusCode), headersDict, starlark.String(body)}
+	thread := &starlark.Thread{Name: "http_middleware_script"}
+	res, err := starlark.Call(thread, handler, args, nil)
+	if err != nil {
+		log.Err(err).Msg("[HTTPClientScriptMiddleware.HandleResponse] Failed to call handleResponse")
+		return statusCode, headers, body, err
+	}
+	if res == starlark.None {
+		return statusCode, headers, body, nil
+	}
+	resDict, isDict := res.(*starlark.Dict)
+	if !isDict {
+		log.Warn().Msgf("[HTTPClientScriptMiddleware.HandleResponse] handleResponse returns neither None nor a dict: %s", res.Type())
+		return statusCode, headers, body, fmt.Errorf("handleResponse returns %s, expected None or dict", res.Type())
+	}
+
+	// Extract the results
+	if value, found, _ := resDict.Get(starlark.String("statusCode")); found {
+		var newStatusCode int
+		if err := starlark.AsInt(value, &newStatusCode); err != nil {
+			log.Err(err).Msg("[HTTPClientScriptMiddleware.HandleResponse] statusCode is not an int")
+			return statusCode, headers, body, err
+		}
+		statusCode = newStatusCode
+	}
+	if value, found, _ := resDict.Get(starlark.String("headers")); found {
+		if headersMap, isMap := value.(*starlark.Dict); isMap {
+			newHeaders, err := convertStarlarkMapToStringMap(headersMap)
+			if err != nil {
+				log.Err(err).Msg("[HTTPClientScriptMiddleware.HandleResponse] Failed to convert headers map")
+				return statusCode, headers, body, err
+			}
+			log.Debug().Msgf("[HTTPClientScriptMiddleware.HandleResponse] Got headers: %v", newHeaders)
+			headers = newHeaders
+		} else {
+			log.Warn().Msg("[HTTPClientScriptMiddleware.HandleResponse] headers is not a map")
+		}
+	}
+	if value, found, _ := resDict.Get(starlark.String("body")); found {
+		if str, isStr := value.(starlark.String); isStr {
+			body = []byte(str.GoString())
+		} else {
+			log.Warn().Msgf("[HTTPClientScriptMiddleware.HandleResponse] Body is not a string: %s", value.String())
+			body = []byte(value.String())
+		}
+	}
+	return statusCode, headers, body, nil
+}
+
 // Helper function to convert a Starlark map to a Go map[string]string
 func convertStarlarkMapToStringMap(starlarkMap *starlark.Dict) (map[string]string, error) {
 	goMap := make(map[string]string)
@@ -0,0 +1,163 @@
+package http
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cloudwego/hertz/pkg/protocol/consts"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	// RetryAfterHeaderKey is the response header telling how long to wait before the next request.
+	RetryAfterHeaderKey = "Retry-After"
+
+	// retryAfterDateFormat is the format of HTTP-date in `Retry-After` header, see [RFC 9110](https://www.rfc-editor.org/rfc/rfc9110#section-5.6.7).
+	retryAfterDateFormat = "Mon, 02 Jan 2006 15:04:05 GMT"
+
+	// backoffBaseWait is the wait before the first retry, if the response has no `Retry-After` header.
+	// It doubles on each retry.
+	backoffBaseWait = time.Second
+)
+
+// PolitenessConfig is the configuration of politeness controls of [HTTPClient].
+// Zero values disable the corresponding controls.
+type PolitenessConfig struct {
+	// MaxRequestsPerSecond is the max number of requests sent per second.
+	MaxRequestsPerSecond int
+
+	// MaxConcurrentRequests is the max number of requests in flight at the same time, i.e., connections in use.
+	MaxConcurrentRequests int
+
+	// BackoffMaxRetries is the max number of retries of a request responded 429 (Too Many Requests),
+	// or 503 (Service Unavailable) with `Retry-After` header.
+	BackoffMaxRetries int
+
+	// BackoffMaxWait is the max wait before a retry. Longer `Retry-After` is capped to it.
+	BackoffMaxWait time.Duration
+}
+
+// HTTPClientPoliteness throttles requests of [HTTPClient], so that the fuzzer can run against shared environments safely.
+// It limits the request rate and concurrency, and backs off when the server asks so (429, or 503 with `Retry-After`).
+// A 503 without `Retry-After` is not retried, as it is more likely a failure of the server than overload.
+type HTTPClientPoliteness struct {
+	// Config is the politeness configuration.
+	Config PolitenessConfig
+
+	// interval is the minimal interval between two requests. It is zero if the rate is not limited.
+	interval time.Duration
+
+	// mu protects nextSendTime.
+	mu sync.Mutex
+
+	// nextSendTime is the earliest time the next request can be sent.
+	nextSendTime time.Time
+
+	// inFlight is a semaphore of requests in flight. It is nil if the concurrency is not limited.
+	inFlight chan struct{}
+}
+
+// NewHTTPClientPoliteness creates a new HTTPClientPoliteness.
+func NewHTTPClientPoliteness(politenessConfig PolitenessConfig) *HTTPClientPoliteness {
+	p := &HTTPClientPoliteness{
+		Config: politenessConfig,
+	}
+	if politenessConfig.MaxRequestsPerSecond > 0 {
+		p.interval = time.Second / time.Duration(politenessConfig.MaxRequestsPerSecond)
+	}
+	if politenessConfig.MaxConcurrentRequests > 0 {
+		p.inFlight = make(chan struct{}, politenessConfig.MaxConcurrentRequests)
+	}
+	return p
+}
+
+// Acquire waits until a request can be sent, under the rate and concurrency limits.
+// It returns a function to release the request once it is done, or an error if ctx is done while waiting.
+// It is safe to call on a nil HTTPClientPoliteness, where no request is throttled.
+func (p *HTTPClientPoliteness) Acquire(ctx context.Context) (func(), error) {
+	if p == nil {
+		return func() {}, nil
+	}
+	if p.interval > 0 {
+		p.mu.Lock()
+		now := time.Now()
+		sendTime := p.nextSendTime
+		if sendTime.Before(now) {
+			sendTime = now
+		}
+		p.nextSendTime = sendTime.Add(p.interval)
+		p.mu.Unlock()
+		if err := sleepWithContext(ctx, time.Until(sendTime)); err != nil {
+			return nil, err
+		}
+	}
+	if p.inFlight == nil {
+		return func() {}, nil
+	}
+	select {
+	case p.inFlight <- struct{}{}:
+		return func() { <-p.inFlight }, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// GetBackoff returns how long to wait before retrying a request, given the response and the number of retries so far.
+// It returns false if the request should not be retried.
+// It is safe to call on a nil HTTPClientPoliteness, where no request is retried.
+func (p *HTTPClientPoliteness) GetBackoff(statusCode int, retryAfter string, retryCount int) (time.Duration, bool) {
+	if p == nil || retryCount >= p.Config.BackoffMaxRetries {
+		return 0, false
+	}
+	wait, hasRetryAfter := parseRetryAfter(retryAfter, time.Now())
+	switch {
+	case statusCode == consts.StatusTooManyRequests && !hasRetryAfter:
+		wait = backoffBaseWait << retryCount
+	case statusCode == consts.StatusTooManyRequests, statusCode == consts.StatusServiceUnavailable && hasRetryAfter:
+	default:
+		return 0, false
+	}
+	if p.Config.BackoffMaxWait > 0 && wait > p.Config.BackoffMaxWait {
+		wait = p.Config.BackoffMaxWait
+	}
+	return wait, true
+}
+
+// parseRetryAfter parses the value of `Retry-After` header, which is either delay seconds or an HTTP-date.
+// It returns false if the value is empty or invalid.
+func parseRetryAfter(value string, now time.Time) (time.Duration, bool) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+		return time.Duration(seconds) * time.Second, true
+	}
+	date, err := time.Parse(retryAfterDateFormat, value)
+	if err != nil {
+		log.Warn().Msgf("[parseRetryAfter] Invalid Retry-After: %s", value)
+		return 0, false
+	}
+	return max(date.Sub(now), 0), true
+}
+
+// sleepWithContext sleeps for the duration, or until ctx is done, in which case it returns the error of ctx.
+func sleepWithContext(ctx context.Context, duration time.Duration) error {
+	if duration <= 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(duration)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
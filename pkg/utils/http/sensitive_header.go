@@ -0,0 +1,64 @@
+package http
+
+import (
+	"slices"
+	"strings"
+)
+
+// MaskedHeaderValue replaces values of credential headers, see [MaskCredentialHeaders].
+const MaskedHeaderValue = "<masked>"
+
+// credentialHeaderKeys are the (lowercase) keys of headers which usually carry credentials, see [IsCredentialHeader].
+var credentialHeaderKeys = []string{
+	"authorization",
+	"cookie",
+	"proxy-authorization",
+	"set-cookie",
+	"x-amz-security-token",
+	"x-api-key",
+	"x-auth-token",
+	"x-csrf-token",
+}
+
+// tracePropagationHeaderKeys are the (lowercase) keys of headers propagating trace context or request IDs through a service mesh, see [IsTracePropagationHeader].
+var tracePropagationHeaderKeys = []string{
+	"b3",
+	"baggage",
+	"traceparent",
+	"tracestate",
+	"x-request-id",
+}
+
+// tracePropagationHeaderPrefixes are the (lowercase) prefixes of trace propagation header keys, e.g., `x-b3-traceid`.
+var tracePropagationHeaderPrefixes = []string{
+	"x-b3-",
+	"x-ot-",
+}
+
+// IsCredentialHeader tells whether the header usually carries credentials, e.g., `Authorization` and `Cookie`. The key is case-insensitive.
+func IsCredentialHeader(key string) bool {
+	return slices.Contains(credentialHeaderKeys, strings.ToLower(key))
+}
+
+// IsTracePropagationHeader tells whether the header propagates trace context or request IDs, e.g., `traceparent` and `X-B3-TraceId`,
+// whose values identify a single request and should not be reused. The key is case-insensitive.
+func IsTracePropagationHeader(key string) bool {
+	lowerKey := strings.ToLower(key)
+	if slices.Contains(tracePropagationHeaderKeys, lowerKey) {
+		return true
+	}
+	return slices.ContainsFunc(tracePropagationHeaderPrefixes, func(prefix string) bool { return strings.HasPrefix(lowerKey, prefix) })
+}
+
+// MaskCredentialHeaders returns a copy of the headers, with values of credential headers (see [IsCredentialHeader]) replaced by [MaskedHeaderValue],
+// e.g., to log or save requests.
+func MaskCredentialHeaders(headers map[string]string) map[string]string {
+	masked := make(map[string]string, len(headers))
+	for key, value := range headers {
+		if IsCredentialHeader(key) {
+			value = MaskedHeaderValue
+		}
+		masked[key] = value
+	}
+	return masked
+}
@@ -0,0 +1,279 @@
+package http
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"maps"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	// RequestSigningTypeSigV4 is AWS Signature Version 4, e.g., for services behind AWS API Gateway.
+	// See [AWS SigV4](https://docs.aws.amazon.com/IAM/latest/UserGuide/reference_sigv-create-signed-request.html).
+	RequestSigningTypeSigV4 = "sigv4"
+
+	// RequestSigningTypeHMAC is generic HMAC-SHA256 signing of the request, see [RequestSigner.signHMAC].
+	RequestSigningTypeHMAC = "hmac"
+
+	// sigV4Algorithm is the signing algorithm identifier of SigV4.
+	sigV4Algorithm = "AWS4-HMAC-SHA256"
+
+	// sigV4TimeFormat is the format of the signing time of SigV4, in ISO 8601 basic format.
+	sigV4TimeFormat = "20060102T150405Z"
+
+	// HMACTimestampHeaderKey is the header of the signing time (Unix seconds) of HMAC signing.
+	HMACTimestampHeaderKey = "X-Signature-Timestamp"
+
+	// HMACKeyIDHeaderKey is the header of the access key id of HMAC signing.
+	HMACKeyIDHeaderKey = "X-Signature-Key-Id"
+)
+
+// RequestSigningConfig is the configuration of request signing.
+type RequestSigningConfig struct {
+	// Type is the signing type, [RequestSigningTypeSigV4] or [RequestSigningTypeHMAC].
+	Type string
+
+	// BaseURL is the base URL of requests, the same as that of the HTTP client.
+	// The host and the path of it are signed.
+	BaseURL string
+
+	// AccessKeyID is the access key id of credentials.
+	AccessKeyID string
+
+	// SecretAccessKey is the secret key of credentials.
+	SecretAccessKey string
+
+	// SessionToken is the session token of temporary credentials, used by SigV4 only. It can be empty.
+	SessionToken string
+
+	// Region is the region of requests, e.g., `us-east-1`, used by SigV4 only.
+	Region string
+
+	// Service is the service name of requests, e.g., `execute-api`, used by SigV4 only.
+	Service string
+
+	// HMACHeader is the header to set the signature in, used by HMAC only.
+	HMACHeader string
+}
+
+// RequestSigner signs requests, as a built-in [HTTPClientMiddleware].
+// It should be the last middleware on request, so that the signature covers all mutations made by other middlewares.
+// Headers are modified directly, like other middlewares.
+type RequestSigner struct {
+	// Config is the signing configuration.
+	Config RequestSigningConfig
+
+	// Now returns the signing time. It is time.Now by default, and can be replaced, e.g., for testing.
+	Now func() time.Time
+
+	// host is the host of the base URL, without default port, which is signed as `Host` header.
+	host string
+
+	// mu protects the cached signing key below.
+	mu sync.Mutex
+
+	// sigV4KeyDate is the date (in `20060102` format) the cached SigV4 signing key is derived for.
+	sigV4KeyDate string
+
+	// sigV4Key is the cached SigV4 signing key, which only changes by date.
+	sigV4Key []byte
+}
+
+// NewRequestSigner creates a new RequestSigner.
+// It returns an error if the configuration is invalid.
+func NewRequestSigner(signingConfig RequestSigningConfig) (*RequestSigner, error) {
+	switch signingConfig.Type {
+	case RequestSigningTypeSigV4:
+		if signingConfig.Region == "" || signingConfig.Service == "" {
+			return nil, fmt.Errorf("region and service are required by SigV4 request signing")
+		}
+	case RequestSigningTypeHMAC:
+		if signingConfig.HMACHeader == "" {
+			return nil, fmt.Errorf("signature header is required by HMAC request signing")
+		}
+	default:
+		return nil, fmt.Errorf("unsupported request signing type: %s", signingConfig.Type)
+	}
+	if signingConfig.AccessKeyID == "" || signingConfig.SecretAccessKey == "" {
+		return nil, fmt.Errorf("access key id and secret access key are required by request signing")
+	}
+	baseURL, err := url.Parse(signingConfig.BaseURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse base URL %s: %w", signingConfig.BaseURL, err)
+	}
+	host := baseURL.Host
+	if (baseURL.Scheme == "http" && baseURL.Port() == "80") || (baseURL.Scheme == "https" && baseURL.Port() == "443") {
+		host = baseURL.Hostname()
+	}
+	return &RequestSigner{
+		Config: signingConfig,
+		Now:    time.Now,
+		host:   host,
+	}, nil
+}
+
+// HandleRequest signs the request, and sets the signature in headers.
+// If the request cannot be signed, it is sent unsigned.
+func (s *RequestSigner) HandleRequest(path, method string, headers, pathParams, queryParams map[string]string, body []byte) (string, string, map[string]string, map[string]string, map[string]string, []byte, error) {
+	escapedPath, err := s.getEscapedPath(path, pathParams)
+	if err != nil {
+		log.Err(err).Msg("[RequestSigner.HandleRequest] Failed to resolve request path, request is sent unsigned")
+		return path, method, headers, pathParams, queryParams, body, err
+	}
+	signingTime := s.Now().UTC()
+	// Headers of the caller are not changed, so that credentials are not kept in, e.g., operation cases and reports.
+	headers = maps.Clone(headers)
+	if headers == nil {
+		headers = make(map[string]string)
+	}
+	switch s.Config.Type {
+	case RequestSigningTypeSigV4:
+		s.signSigV4(method, escapedPath, headers, queryParams, body, signingTime)
+	case RequestSigningTypeHMAC:
+		s.signHMAC(method, escapedPath, headers, queryParams, body, signingTime)
+	}
+	return path, method, headers, pathParams, queryParams, body, nil
+}
+
+// HandleResponse keeps the response as is.
+func (s *RequestSigner) HandleResponse(path, method string, statusCode int, headers map[string]string, body []byte) (int, map[string]string, []byte, error) {
+	return statusCode, headers, body, nil
+}
+
+// getEscapedPath returns the escaped path of the request URL, with the base path and path params resolved, as sent by [HTTPClient].
+func (s *RequestSigner) getEscapedPath(path string, pathParams map[string]string) (string, error) {
+	requestURL, err := url.Parse(BuildRequestURL(s.Config.BaseURL, path, pathParams, nil))
+	if err != nil {
+		return "", err
+	}
+	escapedPath := requestURL.EscapedPath()
+	if escapedPath == "" {
+		escapedPath = "/"
+	}
+	return escapedPath, nil
+}
+
+// signSigV4 signs the request by SigV4, and sets `X-Amz-Date`, `X-Amz-Security-Token` (if session token is set) and `Authorization` headers.
+// `Host`, `X-Amz-Date` and `X-Amz-Security-Token` headers are signed, as other headers may be changed by proxies.
+func (s *RequestSigner) signSigV4(method, escapedPath string, headers, queryParams map[string]string, body []byte, signingTime time.Time) {
+	amzDate := signingTime.Format(sigV4TimeFormat)
+	date := amzDate[:8]
+	headers["X-Amz-Date"] = amzDate
+	signedHeaders := map[string]string{
+		"host":       s.host,
+		"x-amz-date": amzDate,
+	}
+	if s.Config.SessionToken != "" {
+		headers["X-Amz-Security-Token"] = s.Config.SessionToken
+		signedHeaders["x-amz-security-token"] = s.Config.SessionToken
+	}
+	signedHeaderKeys := slices.Sorted(maps.Keys(signedHeaders))
+	var canonicalHeaders strings.Builder
+	for _, key := range signedHeaderKeys {
+		canonicalHeaders.WriteString(key + ":" + strings.TrimSpace(signedHeaders[key]) + "\n")
+	}
+	signedHeadersStr := strings.Join(signedHeaderKeys, ";")
+
+	// Paths are escaped once more for services other than S3, see [AWS SigV4].
+	canonicalRequest := strings.Join([]string{
+		method,
+		sigV4URIEncode(escapedPath, false),
+		getSigV4CanonicalQueryString(queryParams),
+		canonicalHeaders.String(),
+		signedHeadersStr,
+		hashSHA256Hex(body),
+	}, "\n")
+	scope := strings.Join([]string{date, s.Config.Region, s.Config.Service, "aws4_request"}, "/")
+	stringToSign := strings.Join([]string{
+		sigV4Algorithm,
+		amzDate,
+		scope,
+		hashSHA256Hex([]byte(canonicalRequest)),
+	}, "\n")
+	signature := hex.EncodeToString(hmacSHA256(s.getSigV4Key(date), []byte(stringToSign)))
+	headers["Authorization"] = fmt.Sprintf("%s Credential=%s/%s, SignedHeaders=%s, Signature=%s", sigV4Algorithm, s.Config.AccessKeyID, scope, signedHeadersStr, signature)
+	log.Debug().Msgf("[RequestSigner.signSigV4] Signed request, canonical request: %q", canonicalRequest)
+}
+
+// getSigV4Key returns the SigV4 signing key of the date, which is cached.
+func (s *RequestSigner) getSigV4Key(date string) []byte {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.sigV4KeyDate != date {
+		key := []byte("AWS4" + s.Config.SecretAccessKey)
+		for _, data := range []string{date, s.Config.Region, s.Config.Service, "aws4_request"} {
+			key = hmacSHA256(key, []byte(data))
+		}
+		s.sigV4KeyDate = date
+		s.sigV4Key = key
+	}
+	return s.sigV4Key
+}
+
+// signHMAC signs the request by HMAC-SHA256 with the secret key, and sets the hex-encoded signature in HMACHeader,
+// the signing time (Unix seconds) in [HMACTimestampHeaderKey] and the access key id in [HMACKeyIDHeaderKey].
+// The signed string consists of the following lines:
+//
+//	METHOD
+//	/escaped/path?canonical=query
+//	timestamp
+//	hex-encoded SHA256 of body
+//
+// where the query is canonicalized as SigV4, and `?` is omitted if there is no query param.
+func (s *RequestSigner) signHMAC(method, escapedPath string, headers, queryParams map[string]string, body []byte, signingTime time.Time) {
+	timestamp := fmt.Sprintf("%d", signingTime.Unix())
+	target := escapedPath
+	if len(queryParams) > 0 {
+		target += "?" + getSigV4CanonicalQueryString(queryParams)
+	}
+	stringToSign := strings.Join([]string{method, target, timestamp, hashSHA256Hex(body)}, "\n")
+	headers[s.Config.HMACHeader] = hex.EncodeToString(hmacSHA256([]byte(s.Config.SecretAccessKey), []byte(stringToSign)))
+	headers[HMACTimestampHeaderKey] = timestamp
+	headers[HMACKeyIDHeaderKey] = s.Config.AccessKeyID
+}
+
+// getSigV4CanonicalQueryString returns the canonical query string of SigV4,
+// i.e., URI-encoded params sorted by key, joined by `&`.
+func getSigV4CanonicalQueryString(queryParams map[string]string) string {
+	params := make([]string, 0, len(queryParams))
+	for _, key := range slices.Sorted(maps.Keys(queryParams)) {
+		params = append(params, sigV4URIEncode(key, true)+"="+sigV4URIEncode(queryParams[key], true))
+	}
+	return strings.Join(params, "&")
+}
+
+// sigV4URIEncode encodes every byte except unreserved characters (`A-Z`, `a-z`, `0-9`, `-`, `_`, `.`, `~`) as `%XY` in upper case, as required by SigV4.
+// `/` is kept if encodeSlash is false, e.g., for paths.
+func sigV4URIEncode(s string, encodeSlash bool) string {
+	var encoded strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if ('A' <= c && c <= 'Z') || ('a' <= c && c <= 'z') || ('0' <= c && c <= '9') || c == '-' || c == '_' || c == '.' || c == '~' || (c == '/' && !encodeSlash) {
+			encoded.WriteByte(c)
+		} else {
+			fmt.Fprintf(&encoded, "%%%02X", c)
+		}
+	}
+	return encoded.String()
+}
+
+// hashSHA256Hex returns the hex-encoded SHA256 hash of data.
+func hashSHA256Hex(data []byte) string {
+	hash := sha256.Sum256(data)
+	return hex.EncodeToString(hash[:])
+}
+
+// hmacSHA256 returns the HMAC-SHA256 of data with the key.
+func hmacSHA256(key, data []byte) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write(data)
+	return mac.Sum(nil)
+}
@@ -0,0 +1,92 @@
+package http
+
+import (
+	"context"
+	"fmt"
+	"math/rand/v2"
+	"strings"
+)
+
+const (
+	// TraceparentHeaderKey is the [W3C trace context](https://www.w3.org/TR/trace-context/) header of the trace ID, parent (span) ID and trace flags,
+	// e.g., `00-{trace ID}-{parent ID}-01`.
+	TraceparentHeaderKey = "traceparent"
+
+	// TracestateHeaderKey is the W3C trace context header of vendor-specific trace data, which belongs to the trace of the traceparent.
+	TracestateHeaderKey = "tracestate"
+
+	// B3TraceIDHeaderKey is the [B3](https://github.com/openzipkin/b3-propagation) header of the trace ID.
+	B3TraceIDHeaderKey = "x-b3-traceid"
+
+	// B3SpanIDHeaderKey is the B3 header of the span ID.
+	B3SpanIDHeaderKey = "x-b3-spanid"
+
+	// traceparentVersion is the version of traceparent headers injected.
+	traceparentVersion = "00"
+
+	// traceparentSampledFlags are the trace flags of traceparent headers injected, i.e., sampled.
+	traceparentSampledFlags = "01"
+)
+
+// InjectTraceContext returns a copy of headers, with a traceparent header starting a new sampled trace, and the ID of the trace.
+// Trace context headers already in headers (in any case) are replaced, e.g., those recorded with seeds, or sent by an earlier attempt.
+// B3 trace and span IDs, if present, are replaced by those of the new trace, so that both headers start the same trace.
+// The trace ID is also recorded in ctx, see [ContextWithClientTraceID].
+//
+// IDs are drawn from the global random source of math/rand/v2, instead of the seeded `utils.SharedRand`,
+// as the number of requests (e.g., retries) depends on timing, which should not change values generated with the same seed.
+func InjectTraceContext(ctx context.Context, headers map[string]string) (map[string]string, string) {
+	traceID := randomHexID(16)
+	spanID := randomHexID(8)
+	injectedHeaders := make(map[string]string, len(headers)+1)
+	for key, value := range headers {
+		switch strings.ToLower(key) {
+		case TraceparentHeaderKey, TracestateHeaderKey:
+			continue
+		case B3TraceIDHeaderKey:
+			value = traceID
+		case B3SpanIDHeaderKey:
+			value = spanID
+		}
+		injectedHeaders[key] = value
+	}
+	injectedHeaders[TraceparentHeaderKey] = strings.Join([]string{traceparentVersion, traceID, spanID, traceparentSampledFlags}, "-")
+	recordClientTraceID(ctx, traceID)
+	return injectedHeaders, traceID
+}
+
+// randomHexID returns a random non-zero ID of byteCount bytes, in lowercase hex.
+func randomHexID(byteCount int) string {
+	for {
+		id := make([]byte, byteCount)
+		allZero := true
+		for i := range id {
+			id[i] = byte(rand.IntN(256))
+			allZero = allZero && id[i] == 0
+		}
+		// All-zero IDs are invalid in B3 and W3C trace context.
+		if !allZero {
+			return fmt.Sprintf("%x", id)
+		}
+	}
+}
+
+// clientTraceIDContextKey is the key of the client trace ID in the context, see [ContextWithClientTraceID].
+type clientTraceIDContextKey struct{}
+
+// ContextWithClientTraceID returns a copy of ctx carrying a client trace ID, and the pointer to it.
+// Requests performed with the context (or contexts derived from it) by clients injecting trace context (see [InjectTraceContext])
+// set it to the ID of the trace started by their last attempt, like [ContextWithResponseTime].
+// It stays empty if no trace is started.
+func ContextWithClientTraceID(ctx context.Context) (context.Context, *string) {
+	clientTraceID := new(string)
+	return context.WithValue(ctx, clientTraceIDContextKey{}, clientTraceID), clientTraceID
+}
+
+// recordClientTraceID sets the client trace ID of ctx (see [ContextWithClientTraceID]).
+// It does nothing if ctx carries no client trace ID.
+func recordClientTraceID(ctx context.Context, traceID string) {
+	if clientTraceID, ok := ctx.Value(clientTraceIDContextKey{}).(*string); ok {
+		*clientTraceID = traceID
+	}
+}
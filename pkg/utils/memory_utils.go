@@ -0,0 +1,31 @@
+package utils
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+// GetProcessRSS returns the resident set size (RSS) of current process, in bytes.
+// It reads `/proc/self/statm` on Linux; on other platforms (or if the file is unavailable),
+// it falls back to the memory obtained from OS by Go runtime, which is an upper bound of the heap part of RSS.
+func GetProcessRSS() (uint64, error) {
+	statm, err := os.ReadFile("/proc/self/statm")
+	if err != nil {
+		var memStats runtime.MemStats
+		runtime.ReadMemStats(&memStats)
+		return memStats.Sys, nil
+	}
+	// The second field is the number of resident pages.
+	fields := strings.Fields(string(statm))
+	if len(fields) < 2 {
+		return 0, fmt.Errorf("unexpected format of /proc/self/statm: %s", string(statm))
+	}
+	residentPages, err := strconv.ParseUint(fields[1], 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse resident pages: %w", err)
+	}
+	return residentPages * uint64(os.Getpagesize()), nil
+}
@@ -0,0 +1,129 @@
+package messaging
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"resttracefuzzer/pkg/utils/http"
+	"slices"
+	"sync"
+
+	"github.com/bytedance/sonic"
+	"github.com/cloudwego/hertz/pkg/protocol/consts"
+	"github.com/rs/zerolog/log"
+)
+
+// KafkaRESTPublisher publishes messages to Kafka through a REST proxy supporting v3 API,
+// see [Confluent REST Proxy API v3](https://docs.confluent.io/platform/current/kafka-rest/api.html#records-v3).
+// Messages are produced as records with JSON values, and headers of messages are record headers.
+type KafkaRESTPublisher struct {
+	// HTTPClient is the client of the REST proxy.
+	HTTPClient *http.HTTPClient
+
+	// Authorization is the value of `Authorization` header of requests to the REST proxy. It is empty if no authentication is required.
+	Authorization string
+
+	// clusterID is the ID of the Kafka cluster, fetched from the REST proxy on first publish.
+	clusterID string
+
+	// mu protects clusterID.
+	mu sync.Mutex
+}
+
+// kafkaRESTClusterListResponse is the response of listing clusters of the REST proxy.
+type kafkaRESTClusterListResponse struct {
+	Data []struct {
+		ClusterID string `json:"cluster_id"`
+	} `json:"data"`
+}
+
+// kafkaRESTRecordHeader is a header of a record to produce. Its value is base64 encoded.
+type kafkaRESTRecordHeader struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// kafkaRESTRecordData is the key or value of a record to produce.
+type kafkaRESTRecordData struct {
+	Type string `json:"type"`
+	Data any    `json:"data"`
+}
+
+// kafkaRESTProduceRequest is the request of producing a record.
+type kafkaRESTProduceRequest struct {
+	Headers []kafkaRESTRecordHeader `json:"headers,omitempty"`
+	Value   *kafkaRESTRecordData    `json:"value,omitempty"`
+}
+
+// NewKafkaRESTPublisher creates a new KafkaRESTPublisher.
+func NewKafkaRESTPublisher(httpClient *http.HTTPClient, authorization string) *KafkaRESTPublisher {
+	return &KafkaRESTPublisher{
+		HTTPClient:    httpClient,
+		Authorization: authorization,
+	}
+}
+
+// Publish produces a record to the Kafka topic of the destination.
+// The payload is produced as a JSON value if it is valid JSON, or a string value otherwise.
+func (p *KafkaRESTPublisher) Publish(ctx context.Context, destination Destination, headers map[string]string, payload []byte) (int, map[string]string, []byte, error) {
+	clusterID, err := p.getClusterID(ctx)
+	if err != nil {
+		log.Err(err).Msgf("[KafkaRESTPublisher.Publish] Failed to get cluster ID from REST proxy")
+		return 0, nil, nil, err
+	}
+	produceRequest := kafkaRESTProduceRequest{
+		Headers: make([]kafkaRESTRecordHeader, 0, len(headers)),
+	}
+	for _, name := range slices.Sorted(maps.Keys(headers)) {
+		produceRequest.Headers = append(produceRequest.Headers, kafkaRESTRecordHeader{
+			Name:  name,
+			Value: base64.StdEncoding.EncodeToString([]byte(headers[name])),
+		})
+	}
+	if len(payload) > 0 {
+		if json.Valid(payload) {
+			produceRequest.Value = &kafkaRESTRecordData{Type: "JSON", Data: json.RawMessage(payload)}
+		} else {
+			produceRequest.Value = &kafkaRESTRecordData{Type: "STRING", Data: string(payload)}
+		}
+	}
+	body, err := sonic.Marshal(produceRequest)
+	if err != nil {
+		log.Err(err).Msgf("[KafkaRESTPublisher.Publish] Failed to marshal produce request")
+		return 0, nil, nil, err
+	}
+	pathParams := map[string]string{
+		"cluster_id": clusterID,
+		"topic_name": destination.KafkaTopic,
+	}
+	return p.HTTPClient.PerformRequest(ctx, "/v3/clusters/{cluster_id}/topics/{topic_name}/records", consts.MethodPost, newBrokerRequestHeaders("application/json", p.Authorization), pathParams, nil, body)
+}
+
+// getClusterID returns the ID of the Kafka cluster, which is fetched from the REST proxy if not yet.
+// The first cluster listed is used, as a REST proxy usually serves a single cluster.
+func (p *KafkaRESTPublisher) getClusterID(ctx context.Context) (string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.clusterID != "" {
+		return p.clusterID, nil
+	}
+	statusCode, _, respBody, err := p.HTTPClient.PerformGet(ctx, "/v3/clusters", newBrokerRequestHeaders("application/json", p.Authorization), nil, nil)
+	if err != nil {
+		return "", err
+	}
+	if !http.IsStatusCodeSuccess(statusCode) {
+		return "", fmt.Errorf("failed to list clusters, status code: %d, body: %s", statusCode, string(respBody))
+	}
+	var clusterList kafkaRESTClusterListResponse
+	if err := sonic.Unmarshal(respBody, &clusterList); err != nil {
+		return "", err
+	}
+	if len(clusterList.Data) == 0 || clusterList.Data[0].ClusterID == "" {
+		return "", fmt.Errorf("no cluster found")
+	}
+	p.clusterID = clusterList.Data[0].ClusterID
+	log.Info().Msgf("[KafkaRESTPublisher.getClusterID] Publish messages to Kafka cluster %s", p.clusterID)
+	return p.clusterID, nil
+}
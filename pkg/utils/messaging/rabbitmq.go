@@ -0,0 +1,101 @@
+package messaging
+
+import (
+	"context"
+	"resttracefuzzer/pkg/utils/http"
+
+	"github.com/bytedance/sonic"
+	"github.com/cloudwego/hertz/pkg/protocol/consts"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	// rabbitMQDefaultExchange is the name of the default exchange in RabbitMQ management API.
+	rabbitMQDefaultExchange = "amq.default"
+
+	// rabbitMQPersistentDeliveryMode is the delivery mode of persistent messages.
+	rabbitMQPersistentDeliveryMode = 2
+)
+
+// RabbitMQPublisher publishes messages to RabbitMQ through the management HTTP API,
+// see [RabbitMQ HTTP API](https://www.rabbitmq.com/docs/http-api-reference).
+// The management API is meant for testing instead of high throughput, which suits fuzzing.
+type RabbitMQPublisher struct {
+	// HTTPClient is the client of the management API.
+	HTTPClient *http.HTTPClient
+
+	// Authorization is the value of `Authorization` header of requests to the management API.
+	Authorization string
+}
+
+// rabbitMQPublishProperties are properties of a message to publish.
+type rabbitMQPublishProperties struct {
+	Headers      map[string]string `json:"headers,omitempty"`
+	ContentType  string            `json:"content_type"`
+	DeliveryMode int               `json:"delivery_mode"`
+}
+
+// rabbitMQPublishRequest is the request of publishing a message to an exchange.
+type rabbitMQPublishRequest struct {
+	Properties      rabbitMQPublishProperties `json:"properties"`
+	RoutingKey      string                    `json:"routing_key"`
+	Payload         string                    `json:"payload"`
+	PayloadEncoding string                    `json:"payload_encoding"`
+}
+
+// rabbitMQPublishResponse is the response of publishing a message to an exchange.
+type rabbitMQPublishResponse struct {
+	// Routed is whether the message was routed to at least one queue.
+	Routed bool `json:"routed"`
+}
+
+// NewRabbitMQPublisher creates a new RabbitMQPublisher.
+func NewRabbitMQPublisher(httpClient *http.HTTPClient, authorization string) *RabbitMQPublisher {
+	return &RabbitMQPublisher{
+		HTTPClient:    httpClient,
+		Authorization: authorization,
+	}
+}
+
+// Publish publishes a message to the AMQP exchange of the destination, with the routing key of the destination.
+// Unroutable messages are accepted by RabbitMQ (and dropped), so they are only logged as warnings.
+func (p *RabbitMQPublisher) Publish(ctx context.Context, destination Destination, headers map[string]string, payload []byte) (int, map[string]string, []byte, error) {
+	exchange := destination.AMQPExchange
+	if exchange == "" {
+		exchange = rabbitMQDefaultExchange
+	}
+	virtualHost := destination.AMQPVirtualHost
+	if virtualHost == "" {
+		virtualHost = "/"
+	}
+	publishRequest := rabbitMQPublishRequest{
+		Properties: rabbitMQPublishProperties{
+			Headers:      headers,
+			ContentType:  "application/json",
+			DeliveryMode: rabbitMQPersistentDeliveryMode,
+		},
+		RoutingKey:      destination.AMQPRoutingKey,
+		Payload:         string(payload),
+		PayloadEncoding: "string",
+	}
+	body, err := sonic.Marshal(publishRequest)
+	if err != nil {
+		log.Err(err).Msgf("[RabbitMQPublisher.Publish] Failed to marshal publish request")
+		return 0, nil, nil, err
+	}
+	pathParams := map[string]string{
+		"vhost":    virtualHost,
+		"exchange": exchange,
+	}
+	statusCode, respHeaders, respBody, err := p.HTTPClient.PerformRequest(ctx, "/api/exchanges/{vhost}/{exchange}/publish", consts.MethodPost, newBrokerRequestHeaders("application/json", p.Authorization), pathParams, nil, body)
+	if err != nil {
+		return statusCode, respHeaders, respBody, err
+	}
+	if http.IsStatusCodeSuccess(statusCode) {
+		var publishResponse rabbitMQPublishResponse
+		if err := sonic.Unmarshal(respBody, &publishResponse); err == nil && !publishResponse.Routed {
+			log.Warn().Msgf("[RabbitMQPublisher.Publish] Message to exchange %s with routing key %s is not routed to any queue", exchange, destination.AMQPRoutingKey)
+		}
+	}
+	return statusCode, respHeaders, respBody, nil
+}
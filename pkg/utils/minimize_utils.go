@@ -0,0 +1,104 @@
+package utils
+
+// DeltaDebugMinimize reduces items to a smaller subsequence which still passes the test, by delta debugging (ddmin).
+// The test reports whether a subsequence reproduces the interesting behavior, e.g., a failure.
+// The whole items are assumed to pass the test, and the order of items is preserved in subsequences.
+// At most maxTests tests are performed, and the smallest passing subsequence found so far is returned when the budget runs out.
+// It accepts the following parameters:
+// - items: the items to be minimized
+// - test: the function to check whether a subsequence passes the test
+// - maxTests: the maximum number of tests to perform, no limit if it is not positive
+// The function returns the minimized subsequence, and the number of tests performed.
+//
+// See Zeller and Hildebrandt, "Simplifying and Isolating Failure-Inducing Input", TSE 2002.
+func DeltaDebugMinimize[T any](items []T, test func([]T) bool, maxTests int) ([]T, int) {
+	current := make([]T, len(items))
+	copy(current, items)
+	testCount := 0
+	runTest := func(candidate []T) (passed bool, exhausted bool) {
+		if maxTests > 0 && testCount >= maxTests {
+			return false, true
+		}
+		testCount++
+		return test(candidate), false
+	}
+
+	// The empty subsequence is checked first, as it is common that the target does not depend on other items at all.
+	if len(current) > 0 {
+		passed, exhausted := runTest([]T{})
+		if exhausted {
+			return current, testCount
+		}
+		if passed {
+			return []T{}, testCount
+		}
+	}
+
+	granularity := 2
+	for len(current) >= 2 {
+		chunks := splitIntoChunks(current, granularity)
+		reduced := false
+
+		// Try each chunk as the subsequence.
+		for _, chunk := range chunks {
+			passed, exhausted := runTest(chunk)
+			if exhausted {
+				return current, testCount
+			}
+			if passed {
+				current = chunk
+				granularity = 2
+				reduced = true
+				break
+			}
+		}
+		if reduced {
+			continue
+		}
+
+		// Try each complement of a chunk as the subsequence.
+		// If there are only 2 chunks, complements are the same as chunks, which have been tested.
+		if granularity > 2 {
+			for i := range chunks {
+				complement := make([]T, 0, len(current)-len(chunks[i]))
+				for j, chunk := range chunks {
+					if j != i {
+						complement = append(complement, chunk...)
+					}
+				}
+				passed, exhausted := runTest(complement)
+				if exhausted {
+					return current, testCount
+				}
+				if passed {
+					current = complement
+					granularity = max(granularity-1, 2)
+					reduced = true
+					break
+				}
+			}
+		}
+		if reduced {
+			continue
+		}
+
+		// Increase the granularity, or stop if each chunk has only one item.
+		if granularity >= len(current) {
+			break
+		}
+		granularity = min(granularity*2, len(current))
+	}
+	return current, testCount
+}
+
+// splitIntoChunks splits items into n chunks of (almost) equal sizes, preserving the order.
+func splitIntoChunks[T any](items []T, n int) [][]T {
+	chunks := make([][]T, 0, n)
+	start := 0
+	for i := range n {
+		end := start + (len(items)-start)/(n-i)
+		chunks = append(chunks, items[start:end])
+		start = end
+	}
+	return chunks
+}
@@ -8,7 +8,12 @@
 package utils
 
 import (
+	"fmt"
+	"maps"
+	"net/url"
+	"path"
 	"reflect"
+	"strconv"
 	"strings"
 
 	"github.com/getkin/kin-openapi/openapi3"
@@ -82,6 +87,107 @@ func PrimitiveSchemaType2ReflectKind(schemaType *openapi3.Types) reflect.Kind {
 	}
 }
 
+// GetParameterSchema returns the schema of a parameter.
+// A parameter declares either `schema`, or `content` with a single media type in place of it,
+// in which case the schema of the media type is returned.
+// It returns nil if the parameter declares no schema at all.
+func GetParameterSchema(param *openapi3.Parameter) *openapi3.SchemaRef {
+	if param == nil {
+		return nil
+	}
+	if param.Schema != nil && param.Schema.Value != nil {
+		return param.Schema
+	}
+	return GetContentSchema(param.Content)
+}
+
+// GetRequestBodySchema returns the schema of a request body, see [GetContentSchema].
+// It returns nil if the request body declares no schema at all.
+func GetRequestBodySchema(requestBodyRef *openapi3.RequestBodyRef) *openapi3.SchemaRef {
+	if requestBodyRef == nil || requestBodyRef.Value == nil {
+		return nil
+	}
+	return GetContentSchema(requestBodyRef.Value.Content)
+}
+
+// GetResponseSchema returns the schema of the response of the operation with the status code, see [GetContentSchema].
+// The response of the exact status code is preferred, then that of its class (e.g., `2XX`), then the default response.
+// It returns nil if the response declares no schema at all.
+func GetResponseSchema(operation *openapi3.Operation, statusCode int) *openapi3.SchemaRef {
+	if operation == nil || operation.Responses == nil {
+		return nil
+	}
+	responses := operation.Responses.Map()
+	for _, key := range []string{strconv.Itoa(statusCode), fmt.Sprintf("%dXX", statusCode/100), "default"} {
+		responseRef, exist := responses[key]
+		if !exist || responseRef == nil || responseRef.Value == nil {
+			continue
+		}
+		return GetContentSchema(responseRef.Value.Content)
+	}
+	return nil
+}
+
+// GetOperationEntitySchemaRef returns the component schema ref (e.g., `#/components/schemas/Pet`) of the entity the operation is about,
+// i.e., that of the request body, or that of the first success (200, 201 or 202) response otherwise.
+// For an array schema, the ref of its items is returned, e.g., the entity of `GET /pets` returning `Pet[]` is `Pet`.
+// It returns an empty string if neither is a component schema.
+func GetOperationEntitySchemaRef(operation *openapi3.Operation) string {
+	if operation == nil {
+		return ""
+	}
+	if schemaRef := getEntitySchemaRef(GetRequestBodySchema(operation.RequestBody)); schemaRef != "" {
+		return schemaRef
+	}
+	for _, statusCode := range []int{200, 201, 202} {
+		if schemaRef := getEntitySchemaRef(GetResponseSchema(operation, statusCode)); schemaRef != "" {
+			return schemaRef
+		}
+	}
+	return ""
+}
+
+// getEntitySchemaRef returns the ref of the schema, or that of its items if it is an array schema.
+func getEntitySchemaRef(schema *openapi3.SchemaRef) string {
+	if schema == nil {
+		return ""
+	}
+	if schema.Ref == "" && schema.Value != nil && schema.Value.Type.Includes(openapi3.TypeArray) && schema.Value.Items != nil {
+		return schema.Value.Items.Ref
+	}
+	return schema.Ref
+}
+
+// GetContentSchema returns the schema of a content, i.e., a map from media types to their definitions.
+// Media type `application/json` is preferred, then other JSON media types (e.g., `application/problem+json`), then the others, in alphabetical order.
+// It returns nil if no media type declares a schema.
+func GetContentSchema(content openapi3.Content) *openapi3.SchemaRef {
+	mediaTypes := slices.Sorted(maps.Keys(content))
+	slices.SortStableFunc(mediaTypes, func(a, b string) int {
+		return getMediaTypePriority(a) - getMediaTypePriority(b)
+	})
+	for _, mediaType := range mediaTypes {
+		mediaTypeDef := content[mediaType]
+		if mediaTypeDef != nil && mediaTypeDef.Schema != nil && mediaTypeDef.Schema.Value != nil {
+			return mediaTypeDef.Schema
+		}
+	}
+	return nil
+}
+
+// getMediaTypePriority returns the priority of a media type when choosing the schema of a content. The lower, the more preferred.
+func getMediaTypePriority(mediaType string) int {
+	mediaType = strings.ToLower(mediaType)
+	switch {
+	case strings.HasPrefix(mediaType, "application/json"):
+		return 0
+	case strings.Contains(mediaType, "json"):
+		return 1
+	default:
+		return 2
+	}
+}
+
 // SplitEndpointPath splits the endpoint path into segments by slash.
 // For example, "/api/v1/user/{id}" will be split into ["api", "v1", "user", "{id}"].
 func SplitEndpointPath(endpoint string) []string {
@@ -106,6 +212,64 @@ func IfPathSegmentIsPathParam(segment string) bool {
 }
 
 
+// MatchEndpointPath matches a concrete request path against an endpoint template, segment by segment,
+// e.g., "/pets/42" matches "/pets/{petId}" with path parameters {"petId": "42"}.
+// Only path parameters taking whole segments are supported, and their values are unescaped.
+// It returns the path parameters, and whether the path matches the endpoint.
+func MatchEndpointPath(endpoint, requestPath string) (map[string]string, bool) {
+	endpointSegments := SplitEndpointPath(endpoint)
+	requestSegments := SplitEndpointPath(requestPath)
+	if len(endpointSegments) != len(requestSegments) {
+		return nil, false
+	}
+	pathParams := make(map[string]string)
+	for i, endpointSegment := range endpointSegments {
+		if IfPathSegmentIsPathParam(endpointSegment) {
+			value, err := url.PathUnescape(requestSegments[i])
+			if err != nil {
+				value = requestSegments[i]
+			}
+			pathParams[endpointSegment[1:len(endpointSegment)-1]] = value
+			continue
+		}
+		if endpointSegment != requestSegments[i] {
+			return nil, false
+		}
+	}
+	return pathParams, true
+}
+
+// MatchPathGlob matches a path (an endpoint template or a concrete request path) against a glob pattern, segment by segment,
+// e.g., "/admin/users/{userId}" matches "/admin/**" and "/admin/*/{userId}".
+// A segment `**` matches zero or more segments, and other segments are matched as [path.Match] patterns (e.g., `*` and `?`).
+// Malformed segment patterns match nothing.
+func MatchPathGlob(pattern, pathToMatch string) bool {
+	return matchPathGlobSegments(SplitEndpointPath(pattern), SplitEndpointPath(pathToMatch))
+}
+
+// matchPathGlobSegments matches path segments against glob pattern segments, see [MatchPathGlob].
+func matchPathGlobSegments(patternSegments, pathSegments []string) bool {
+	if len(patternSegments) == 0 {
+		return len(pathSegments) == 0
+	}
+	if patternSegments[0] == "**" {
+		for i := 0; i <= len(pathSegments); i++ {
+			if matchPathGlobSegments(patternSegments[1:], pathSegments[i:]) {
+				return true
+			}
+		}
+		return false
+	}
+	if len(pathSegments) == 0 {
+		return false
+	}
+	matched, err := path.Match(patternSegments[0], pathSegments[0])
+	if err != nil || !matched {
+		return false
+	}
+	return matchPathGlobSegments(patternSegments[1:], pathSegments[1:])
+}
+
 // IsCommonFieldName checks if the given field name is a common field name.
 // Common field names are typically used for metadata or identifiers in schemas.
 // The function converts the input name to lowercase before performing the check
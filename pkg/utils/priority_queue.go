@@ -0,0 +1,176 @@
+package utils
+
+import (
+	"cmp"
+	"container/heap"
+	"slices"
+)
+
+// PriorityQueue is a heap-based max-priority queue. Elements of the same priority are popped in insertion order (FIFO).
+// The priority of an element is computed when it is pushed (or fixed by [PriorityQueue.FixFunc]),
+// so changes to the element after that do not affect its position.
+// It is not safe for concurrent use.
+type PriorityQueue[T any] struct {
+	// entries is the heap of entries.
+	entries priorityQueueHeap[T]
+
+	// priorityOf computes the priority of an element. If it is nil, all elements are of the same priority, i.e., the queue is FIFO.
+	priorityOf func(T) int
+
+	// nextSequence is the insertion sequence of the next pushed element.
+	nextSequence uint64
+}
+
+// priorityQueueEntry is an element of a PriorityQueue, with its priority and insertion sequence.
+type priorityQueueEntry[T any] struct {
+	value    T
+	priority int
+	sequence uint64
+}
+
+// priorityQueueHeap implements [heap.Interface], where the entry of higher priority (or earlier insertion on ties) is less.
+type priorityQueueHeap[T any] []*priorityQueueEntry[T]
+
+func (h priorityQueueHeap[T]) Len() int { return len(h) }
+
+func (h priorityQueueHeap[T]) Less(i, j int) bool {
+	return comparePriorityQueueEntries(h[i], h[j]) < 0
+}
+
+func (h priorityQueueHeap[T]) Swap(i, j int) { h[i], h[j] = h[j], h[i] }
+
+func (h *priorityQueueHeap[T]) Push(x any) { *h = append(*h, x.(*priorityQueueEntry[T])) }
+
+func (h *priorityQueueHeap[T]) Pop() any {
+	old := *h
+	n := len(old)
+	entry := old[n-1]
+	old[n-1] = nil
+	*h = old[:n-1]
+	return entry
+}
+
+// comparePriorityQueueEntries compares entries in popping order, i.e., the entry of higher priority (or earlier insertion on ties) is less.
+func comparePriorityQueueEntries[T any](a, b *priorityQueueEntry[T]) int {
+	return cmp.Or(cmp.Compare(b.priority, a.priority), cmp.Compare(a.sequence, b.sequence))
+}
+
+// NewPriorityQueue creates a new PriorityQueue, ordered by priorityOf.
+// If priorityOf is nil, the queue is FIFO.
+func NewPriorityQueue[T any](priorityOf func(T) int) *PriorityQueue[T] {
+	return &PriorityQueue[T]{
+		entries:    make(priorityQueueHeap[T], 0),
+		priorityOf: priorityOf,
+	}
+}
+
+// Len returns the number of elements in the queue.
+func (q *PriorityQueue[T]) Len() int {
+	return len(q.entries)
+}
+
+// Push pushes the element to the queue, in O(log n).
+func (q *PriorityQueue[T]) Push(value T) {
+	heap.Push(&q.entries, &priorityQueueEntry[T]{
+		value:    value,
+		priority: q.computePriority(value),
+		sequence: q.nextSequence,
+	})
+	q.nextSequence++
+}
+
+// Pop removes and returns the element of the highest priority, in O(log n).
+// It returns false if the queue is empty.
+func (q *PriorityQueue[T]) Pop() (T, bool) {
+	if len(q.entries) == 0 {
+		var zero T
+		return zero, false
+	}
+	return heap.Pop(&q.entries).(*priorityQueueEntry[T]).value, true
+}
+
+// Peek returns the element of the highest priority without removing it, in O(1).
+// It returns false if the queue is empty.
+func (q *PriorityQueue[T]) Peek() (T, bool) {
+	if len(q.entries) == 0 {
+		var zero T
+		return zero, false
+	}
+	return q.entries[0].value, true
+}
+
+// Truncate removes elements of the lowest priority (or latest insertion on ties) until at most maxLen elements are left.
+// It returns the number of removed elements.
+// Each removal is in O(n), as the lowest element is searched among leaves of the heap, and it is cheap if few elements are removed, e.g., after a push.
+func (q *PriorityQueue[T]) Truncate(maxLen int) int {
+	removedCount := 0
+	for len(q.entries) > max(maxLen, 0) {
+		// In a heap, the lowest element must be a leaf.
+		lowestIndex := len(q.entries) / 2
+		for i := lowestIndex + 1; i < len(q.entries); i++ {
+			if comparePriorityQueueEntries(q.entries[i], q.entries[lowestIndex]) > 0 {
+				lowestIndex = i
+			}
+		}
+		heap.Remove(&q.entries, lowestIndex)
+		removedCount++
+	}
+	return removedCount
+}
+
+// DeleteFunc removes all elements for which del returns true, in O(n).
+// It returns the number of removed elements.
+func (q *PriorityQueue[T]) DeleteFunc(del func(T) bool) int {
+	oldLen := len(q.entries)
+	q.entries = slices.DeleteFunc(q.entries, func(entry *priorityQueueEntry[T]) bool {
+		return del(entry.value)
+	})
+	if len(q.entries) != oldLen {
+		heap.Init(&q.entries)
+	}
+	return oldLen - len(q.entries)
+}
+
+// FixFunc recomputes the priority of the first element for which match returns true, and restores the heap order, in O(n).
+// Its insertion order is kept. It returns false if no element matches.
+func (q *PriorityQueue[T]) FixFunc(match func(T) bool) bool {
+	index := slices.IndexFunc(q.entries, func(entry *priorityQueueEntry[T]) bool {
+		return match(entry.value)
+	})
+	if index < 0 {
+		return false
+	}
+	q.entries[index].priority = q.computePriority(q.entries[index].value)
+	heap.Fix(&q.entries, index)
+	return true
+}
+
+// FixAll applies update to each element, recomputes the priorities of all elements, and restores the heap order, in O(n).
+// Insertion orders are kept.
+func (q *PriorityQueue[T]) FixAll(update func(T)) {
+	for _, entry := range q.entries {
+		update(entry.value)
+		entry.priority = q.computePriority(entry.value)
+	}
+	heap.Init(&q.entries)
+}
+
+// Values returns all elements in popping order, i.e., from the highest priority to the lowest, in O(n log n).
+// The queue is not modified.
+func (q *PriorityQueue[T]) Values() []T {
+	sortedEntries := slices.Clone(q.entries)
+	slices.SortFunc(sortedEntries, comparePriorityQueueEntries[T])
+	values := make([]T, 0, len(sortedEntries))
+	for _, entry := range sortedEntries {
+		values = append(values, entry.value)
+	}
+	return values
+}
+
+// computePriority computes the priority of the element, which is 0 if priorityOf is nil.
+func (q *PriorityQueue[T]) computePriority(value T) int {
+	if q.priorityOf == nil {
+		return 0
+	}
+	return q.priorityOf(value)
+}
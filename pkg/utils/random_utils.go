@@ -0,0 +1,70 @@
+package utils
+
+import (
+	"math/rand/v2"
+	"sync"
+
+	"github.com/google/uuid"
+)
+
+// lockedPCGSource is a PCG random source, which is safe for concurrent use and can be reseeded in place.
+type lockedPCGSource struct {
+	// pcg is the underlying PCG source.
+	pcg *rand.PCG
+
+	// mu protects pcg.
+	mu sync.Mutex
+}
+
+// Uint64 returns a pseudo-random 64-bit value.
+func (s *lockedPCGSource) Uint64() uint64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.pcg.Uint64()
+}
+
+// seed resets the source to the state determined by the seed.
+func (s *lockedPCGSource) seed(seed uint64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	// The second word of PCG state is derived from the seed, so that a single number determines the whole state.
+	s.pcg.Seed(seed, seed^0x9e3779b97f4a7c15)
+}
+
+// sharedRandSource is the source of SharedRand, randomly seeded by default.
+var sharedRandSource = &lockedPCGSource{pcg: rand.NewPCG(rand.Uint64(), rand.Uint64())}
+
+// SharedRand is the random number generator shared by the fuzzer, which is safe for concurrent use.
+// All randomness of fuzzing (case selection, value generation, mutation, etc.) should come from it instead of global math/rand,
+// so that a run can be reproduced by seeding it with [SeedSharedRand].
+// Components holding their own generator (e.g., CaseManager) are injected with it by default.
+var SharedRand = rand.New(sharedRandSource)
+
+// NewIndependentRand creates a random number generator which is safe for concurrent use, randomly seeded and independent of [SharedRand],
+// for randomness which should not affect fuzzing, e.g., jitter of retries whose number depends on timing.
+// Draws from it do not advance SharedRand, so runs seeded by [SeedSharedRand] stay reproducible.
+func NewIndependentRand() *rand.Rand {
+	return rand.New(&lockedPCGSource{pcg: rand.NewPCG(rand.Uint64(), rand.Uint64())})
+}
+
+// sharedRandReader reads random bytes from SharedRand.
+type sharedRandReader struct{}
+
+// Read fills p with random bytes from SharedRand. It never returns an error.
+func (sharedRandReader) Read(p []byte) (int, error) {
+	for i := 0; i < len(p); i += 8 {
+		v := SharedRand.Uint64()
+		for j := i; j < min(i+8, len(p)); j++ {
+			p[j] = byte(v)
+			v >>= 8
+		}
+	}
+	return len(p), nil
+}
+
+// SeedSharedRand seeds SharedRand, so that the random sequence is reproducible.
+// UUIDs (e.g., of test scenarios) are also generated from SharedRand after seeding.
+func SeedSharedRand(seed uint64) {
+	sharedRandSource.seed(seed)
+	uuid.SetRand(sharedRandReader{})
+}
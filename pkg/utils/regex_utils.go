@@ -0,0 +1,88 @@
+package utils
+
+import (
+	"regexp/syntax"
+	"strings"
+)
+
+// maxPatternRepeat is the maximal number of repetitions generated for unbounded repeat operators (e.g., `*`, `+`, `{2,}`) in patterns.
+const maxPatternRepeat = 8
+
+// GenerateStringForPattern generates a random string matching the given regular expression pattern, e.g., `pattern` of an OpenAPI string schema.
+// Anchors and word boundaries are ignored, and for characters classes, printable ASCII characters are preferred.
+// It returns an error if the pattern cannot be parsed.
+func GenerateStringForPattern(pattern string) (string, error) {
+	re, err := syntax.Parse(pattern, syntax.Perl)
+	if err != nil {
+		return "", err
+	}
+	var sb strings.Builder
+	generateStringForRegexp(&sb, re.Simplify())
+	return sb.String(), nil
+}
+
+// generateStringForRegexp writes a random string matching the (simplified) regular expression to the builder.
+func generateStringForRegexp(sb *strings.Builder, re *syntax.Regexp) {
+	switch re.Op {
+	case syntax.OpLiteral:
+		for _, r := range re.Rune {
+			sb.WriteRune(r)
+		}
+	case syntax.OpCharClass:
+		sb.WriteRune(randomRuneInCharClass(re.Rune))
+	case syntax.OpAnyChar, syntax.OpAnyCharNotNL:
+		sb.WriteByte(letterBytes[SharedRand.IntN(len(letterBytes))])
+	case syntax.OpCapture:
+		generateStringForRegexp(sb, re.Sub[0])
+	case syntax.OpStar:
+		generateRepeatedStringForRegexp(sb, re.Sub[0], 0, maxPatternRepeat)
+	case syntax.OpPlus:
+		generateRepeatedStringForRegexp(sb, re.Sub[0], 1, maxPatternRepeat)
+	case syntax.OpQuest:
+		generateRepeatedStringForRegexp(sb, re.Sub[0], 0, 1)
+	case syntax.OpRepeat:
+		maxRepeat := re.Max
+		if maxRepeat < 0 {
+			maxRepeat = re.Min + maxPatternRepeat
+		}
+		generateRepeatedStringForRegexp(sb, re.Sub[0], re.Min, maxRepeat)
+	case syntax.OpConcat:
+		for _, sub := range re.Sub {
+			generateStringForRegexp(sb, sub)
+		}
+	case syntax.OpAlternate:
+		generateStringForRegexp(sb, re.Sub[SharedRand.IntN(len(re.Sub))])
+	default:
+		// Empty-width operators (e.g., anchors, word boundaries) and no-match produce nothing.
+	}
+}
+
+// generateRepeatedStringForRegexp repeats the regular expression a random number of times in [minRepeat, maxRepeat].
+func generateRepeatedStringForRegexp(sb *strings.Builder, re *syntax.Regexp, minRepeat, maxRepeat int) {
+	n := minRepeat + SharedRand.IntN(maxRepeat-minRepeat+1)
+	for range n {
+		generateStringForRegexp(sb, re)
+	}
+}
+
+// randomRuneInCharClass returns a random rune in the character class, which is a list of [lo, hi] rune ranges.
+// Ranges are intersected with printable ASCII characters if possible, since negated classes (e.g., `[^a]`) cover all unicode.
+func randomRuneInCharClass(ranges []rune) rune {
+	if len(ranges) < 2 {
+		return 'a'
+	}
+	const printableLo, printableHi = ' ', '~'
+	printableRanges := make([]rune, 0, len(ranges))
+	for i := 0; i+1 < len(ranges); i += 2 {
+		lo, hi := max(ranges[i], printableLo), min(ranges[i+1], printableHi)
+		if lo <= hi {
+			printableRanges = append(printableRanges, lo, hi)
+		}
+	}
+	if len(printableRanges) > 0 {
+		ranges = printableRanges
+	}
+	i := SharedRand.IntN(len(ranges)/2) * 2
+	lo, hi := ranges[i], ranges[i+1]
+	return lo + SharedRand.Int32N(hi-lo+1)
+}
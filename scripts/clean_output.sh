@@ -1,4 +1,5 @@
 #!/bin/bash
 
-# Remove all files ending with ...report...json and .log under /output
-rm -rf ./output/*report*.json ./output/*.log
\ No newline at end of file
+# Remove run output directories and the `latest` symlink under /output,
+# as well as files ending with ...report...json and .log left by older versions
+rm -rf ./output/run_* ./output/latest ./output/*report*.json ./output/*.log
@@ -0,0 +1,161 @@
+package test
+
+import (
+	"context"
+	nethttp "net/http"
+	"net/http/httptest"
+	"testing"
+
+	"resttracefuzzer/internal/config"
+	"resttracefuzzer/internal/fuzzer"
+	"resttracefuzzer/pkg/casemanager"
+	"resttracefuzzer/pkg/feedback"
+	"resttracefuzzer/pkg/static"
+	"resttracefuzzer/pkg/utils/http"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestParseAPITargets tests parsing additional API targets.
+func TestParseAPITargets(t *testing.T) {
+	tests := []struct {
+		name        string
+		targetsJSON string
+		wantNames   []string
+		wantErr     bool
+	}{
+		{"empty", "", []string{}, false},
+		{"empty list", "[]", []string{}, false},
+		{
+			"named",
+			`[{"name": "admin", "openAPISpec": "./admin.json", "serverBaseURL": "http://admin:8080"}]`,
+			[]string{"admin"},
+			false,
+		},
+		{
+			"name defaults to server base URL",
+			`[{"openAPISpec": "./admin.json", "serverBaseURL": "http://admin:8080"}]`,
+			[]string{"http://admin:8080"},
+			false,
+		},
+		{"invalid JSON", `[{`, nil, true},
+		{"null target", `[null]`, nil, true},
+		{"missing spec", `[{"serverBaseURL": "http://admin:8080"}]`, nil, true},
+		{"missing server base URL", `[{"openAPISpec": "./admin.json"}]`, nil, true},
+		{
+			"duplicate names",
+			`[{"openAPISpec": "./a.json", "serverBaseURL": "http://admin:8080"}, {"openAPISpec": "./b.json", "serverBaseURL": "http://admin:8080"}]`,
+			nil,
+			true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			targets, err := static.ParseAPITargets(tt.targetsJSON)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			names := make([]string, 0, len(targets))
+			for _, target := range targets {
+				names = append(names, target.Name)
+			}
+			assert.Equal(t, tt.wantNames, names)
+		})
+	}
+}
+
+// newSingleGetOperationDoc creates an OpenAPI doc with a single GET operation of the path.
+func newSingleGetOperationDoc(path, operationID string) *openapi3.T {
+	paths := openapi3.NewPaths()
+	paths.Set(path, &openapi3.PathItem{
+		Get: &openapi3.Operation{
+			OperationID: operationID,
+			Responses:   openapi3.NewResponses(),
+		},
+	})
+	return &openapi3.T{Paths: paths}
+}
+
+// TestAPIManagerAPITargetsSameRoute tests that the same route served by the primary target and additional ones are different operations.
+func TestAPIManagerAPITargetsSameRoute(t *testing.T) {
+	if config.GlobalConfig == nil {
+		config.InitConfig()
+	}
+	targets := []*static.APITarget{
+		{Name: "admin", Doc: newSingleGetOperationDoc("/items", "listAdminItems")},
+		{Name: "mobile", Doc: newSingleGetOperationDoc("/items", "listMobileItems")},
+	}
+	apiManager := static.NewAPIManager()
+	apiManager.SetAPITargets(targets)
+	apiManager.InitFromDocs(newSingleGetOperationDoc("/items", "listItems"), &openapi3.T{Paths: openapi3.NewPaths()})
+
+	for _, targetName := range []string{"", "admin", "mobile"} {
+		method := static.SimpleAPIMethod{Endpoint: "/items", Method: "GET", Typ: static.SimpleAPIMethodTypeHTTP, Target: targetName}
+		_, exists := apiManager.APIMap[method]
+		assert.True(t, exists, targetName)
+		target, ok := apiManager.GetOperationTarget(method)
+		assert.Equal(t, targetName != "", ok)
+		if ok {
+			assert.Equal(t, targetName, target.Name)
+		}
+	}
+	assert.Len(t, apiManager.APIMap, 3)
+	_, ok := apiManager.GetOperationTarget(static.SimpleAPIMethod{Endpoint: "/items", Method: "GET", Typ: static.SimpleAPIMethodTypeHTTP, Target: "unknown"})
+	assert.False(t, ok)
+}
+
+// TestBasicFuzzerAPITargetRouting tests that requests of operations are sent to the servers of their targets.
+func TestBasicFuzzerAPITargetRouting(t *testing.T) {
+	if config.GlobalConfig == nil {
+		config.InitConfig()
+	}
+	newNamedServer := func(name string) *httptest.Server {
+		return httptest.NewServer(nethttp.HandlerFunc(func(w nethttp.ResponseWriter, r *nethttp.Request) {
+			w.Write([]byte(name))
+		}))
+	}
+	primaryServer, adminServer := newNamedServer("primary"), newNamedServer("admin")
+	defer primaryServer.Close()
+	defer adminServer.Close()
+
+	apiManager := static.NewAPIManager()
+	apiManager.SetAPITargets([]*static.APITarget{{Name: "admin", Doc: newSingleGetOperationDoc("/items", "listAdminItems")}})
+	apiManager.InitFromDocs(newSingleGetOperationDoc("/items", "listItems"), &openapi3.T{Paths: openapi3.NewPaths()})
+	basicFuzzer := &fuzzer.BasicFuzzer{
+		APIManager: apiManager,
+		HTTPClient: http.NewHTTPClient(primaryServer.URL, []string{}, http.EmptyHTTPClientMiddlewareSlice()),
+		TargetHTTPClients: map[string]*http.HTTPClient{
+			"admin": http.NewHTTPClient(adminServer.URL, []string{}, http.EmptyHTTPClientMiddlewareSlice()),
+		},
+		EnumCoverageTracker:        feedback.NewEnumCoverageTracker(apiManager),
+		EndpointPerformanceTracker: feedback.NewEndpointPerformanceTracker(),
+		Progress:                   fuzzer.NewProgressTracker(),
+	}
+
+	tests := []struct {
+		targetName string
+		wantBody   string
+	}{
+		{"", "primary"},
+		{"admin", "admin"},
+		// Operations of unknown targets are sent to the primary one.
+		{"unknown", "primary"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.wantBody+tt.targetName, func(t *testing.T) {
+			operationCase := &casemanager.OperationCase{
+				APIMethod:          static.SimpleAPIMethod{Endpoint: "/items", Method: "GET", Typ: static.SimpleAPIMethodTypeHTTP, Target: tt.targetName},
+				RequestHeaders:     map[string]string{},
+				RequestPathParams:  map[string]string{},
+				RequestQueryParams: map[string]string{},
+			}
+			err := basicFuzzer.ExecuteCaseOperation(context.Background(), operationCase)
+			assert.NoError(t, err)
+			assert.Equal(t, nethttp.StatusOK, operationCase.ResponseStatusCode)
+			assert.Equal(t, tt.wantBody, string(operationCase.ResponseBody))
+		})
+	}
+}
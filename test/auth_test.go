@@ -0,0 +1,105 @@
+package test
+
+import (
+	"context"
+	nethttp "net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"resttracefuzzer/pkg/casemanager"
+	"resttracefuzzer/pkg/static"
+	"resttracefuzzer/pkg/utils/http"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// newTestAuthManager creates an AuthManager of a token endpoint issuing tokens `token-{n}`, and returns the number of issued tokens.
+func newTestAuthManager(t *testing.T) (*http.AuthManager, *atomic.Int32) {
+	issuedCount := &atomic.Int32{}
+	server := httptest.NewServer(nethttp.HandlerFunc(func(w nethttp.ResponseWriter, r *nethttp.Request) {
+		n := issuedCount.Add(1)
+		// Slow token endpoints make concurrent renewals overlap.
+		time.Sleep(20 * time.Millisecond)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"access_token": "token-` + strconv.Itoa(int(n)) + `", "token_type": "bearer", "expires_in": 3600}`))
+	}))
+	t.Cleanup(server.Close)
+	authManager, err := http.NewAuthManager(http.OAuth2Config{
+		GrantType: http.OAuth2GrantTypeClientCredentials,
+		TokenURL:  server.URL,
+		ClientID:  "fuzzer",
+	})
+	assert.NoError(t, err)
+	return authManager, issuedCount
+}
+
+// getAuthorizationForTest returns the `Authorization` header set by the auth manager.
+func getAuthorizationForTest(t *testing.T, authManager *http.AuthManager) string {
+	_, _, headers, _, _, _, err := authManager.HandleRequest("/pets", "GET", map[string]string{}, nil, nil, nil)
+	assert.NoError(t, err)
+	return headers["Authorization"]
+}
+
+// TestAuthManagerCachesToken tests that the token is fetched once, and reused by later requests.
+func TestAuthManagerCachesToken(t *testing.T) {
+	authManager, issuedCount := newTestAuthManager(t)
+	assert.Equal(t, "Bearer token-1", getAuthorizationForTest(t, authManager))
+	assert.Equal(t, "Bearer token-1", getAuthorizationForTest(t, authManager))
+	assert.Equal(t, int32(1), issuedCount.Load())
+}
+
+// TestAuthManagerHandleUnauthorized tests that the token is renewed only if it is issued before the rejected request.
+func TestAuthManagerHandleUnauthorized(t *testing.T) {
+	authManager, issuedCount := newTestAuthManager(t)
+	requestTime := time.Now()
+	assert.Equal(t, "Bearer token-1", getAuthorizationForTest(t, authManager))
+
+	// The token is issued after the request, i.e., the request was sent with an older one.
+	assert.True(t, authManager.HandleUnauthorized(requestTime))
+	assert.Equal(t, int32(1), issuedCount.Load())
+
+	// The token is rejected, so it is renewed.
+	assert.True(t, authManager.HandleUnauthorized(time.Now()))
+	assert.Equal(t, int32(2), issuedCount.Load())
+	assert.Equal(t, "Bearer token-2", getAuthorizationForTest(t, authManager))
+}
+
+// TestAuthManagerConcurrentUnauthorized tests that concurrent rejections of the same token renew it once.
+func TestAuthManagerConcurrentUnauthorized(t *testing.T) {
+	authManager, issuedCount := newTestAuthManager(t)
+	getAuthorizationForTest(t, authManager)
+	requestTime := time.Now()
+	var wg sync.WaitGroup
+	for range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			assert.True(t, authManager.HandleUnauthorized(requestTime))
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, int32(2), issuedCount.Load())
+	assert.Equal(t, "Bearer token-2", getAuthorizationForTest(t, authManager))
+}
+
+// TestAuthManagerKeepsCallerHeaders tests that the token is sent with requests, but not set in headers of the caller,
+// e.g., request headers of an operation case, which are kept in reports.
+func TestAuthManagerKeepsCallerHeaders(t *testing.T) {
+	authManager, _ := newTestAuthManager(t)
+	server := httptest.NewServer(nethttp.HandlerFunc(func(w nethttp.ResponseWriter, r *nethttp.Request) {
+		w.Write([]byte(r.Header.Get("Authorization")))
+	}))
+	t.Cleanup(server.Close)
+	client := http.NewHTTPClient(server.URL, []string{}, []http.HTTPClientMiddleware{authManager})
+
+	operationCase := casemanager.NewOperationCase(static.SimpleAPIMethod{Method: "GET", Endpoint: "/pets", Typ: static.SimpleAPIMethodTypeHTTP}, nil)
+	operationCase.RequestHeaders = map[string]string{"X-Request-Id": "1"}
+	_, _, respBody, err := client.PerformRequest(context.Background(), operationCase.APIMethod.Endpoint, operationCase.APIMethod.Method, operationCase.RequestHeaders, operationCase.RequestPathParams, operationCase.RequestQueryParams, operationCase.RequestBody)
+	assert.NoError(t, err)
+	assert.Equal(t, "Bearer token-1", string(respBody))
+	assert.Equal(t, map[string]string{"X-Request-Id": "1"}, operationCase.RequestHeaders)
+}
@@ -0,0 +1,184 @@
+package test
+
+import (
+	"testing"
+
+	"resttracefuzzer/pkg/casemanager"
+	"resttracefuzzer/pkg/feedback"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+// newBOLAOperationCase creates an executed operation case sent as the user.
+func newBOLAOperationCase(userName, method, endpoint string, pathParams map[string]string, statusCode int, body string) *casemanager.OperationCase {
+	operationCase := newExecutedOperationResult(method, endpoint, pathParams, statusCode, "", body).OperationCase
+	operationCase.UserName = userName
+	return operationCase
+}
+
+// TestBOLAOracle tests that resources created in a test scenario are tracked with their owners,
+// including those of nested collections, and that successful replays on them as other users are violations.
+func TestBOLAOracle(t *testing.T) {
+	apiManager := newCRUDFixtureAPIManager(t)
+	userParams := map[string]string{"userId": "1"}
+	postParams := map[string]string{"userId": "1", "postId": "5"}
+
+	tests := []struct {
+		name string
+		// recorded are the operation cases executed (as the owner) so far in the test scenario.
+		recorded []*casemanager.OperationCase
+		// accessing is the operation case to be executed, which is replayed as bob.
+		accessing        *casemanager.OperationCase
+		replayStatusCode int
+		wantAccessed     bool
+		wantPath         string
+		wantViolation    bool
+	}{
+		{
+			name:             "replay succeeds",
+			recorded:         []*casemanager.OperationCase{newBOLAOperationCase("alice", "POST", "/users", nil, 201, `{"id": 1}`)},
+			accessing:        newBOLAOperationCase("alice", "GET", "/users/{userId}", userParams, 0, ""),
+			replayStatusCode: 200,
+			wantAccessed:     true,
+			wantPath:         "/users/1",
+			wantViolation:    true,
+		},
+		{
+			name:             "replay forbidden",
+			recorded:         []*casemanager.OperationCase{newBOLAOperationCase("alice", "POST", "/users", nil, 201, `{"id": 1}`)},
+			accessing:        newBOLAOperationCase("alice", "DELETE", "/users/{userId}", userParams, 0, ""),
+			replayStatusCode: 403,
+			wantAccessed:     true,
+			wantPath:         "/users/1",
+		},
+		{
+			name:             "sub-resource of owned resource",
+			recorded:         []*casemanager.OperationCase{newBOLAOperationCase("alice", "POST", "/users", nil, 201, `{"id": 1}`)},
+			accessing:        newBOLAOperationCase("alice", "GET", "/users/{userId}/posts/{postId}", postParams, 0, ""),
+			replayStatusCode: 200,
+			wantAccessed:     true,
+			wantPath:         "/users/1",
+			wantViolation:    true,
+		},
+		{
+			name:             "nested collection",
+			recorded:         []*casemanager.OperationCase{newBOLAOperationCase("alice", "POST", "/users/{userId}/posts", userParams, 201, `{"postId": 5}`)},
+			accessing:        newBOLAOperationCase("alice", "PATCH", "/users/{userId}/posts/{postId}", postParams, 0, ""),
+			replayStatusCode: 200,
+			wantAccessed:     true,
+			wantPath:         "/users/1/posts/5",
+			wantViolation:    true,
+		},
+		{
+			name:         "nested collection of other parent",
+			recorded:     []*casemanager.OperationCase{newBOLAOperationCase("alice", "POST", "/users/{userId}/posts", map[string]string{"userId": "2"}, 201, `{"postId": 5}`)},
+			accessing:    newBOLAOperationCase("alice", "GET", "/users/{userId}/posts/{postId}", postParams, 0, ""),
+			wantAccessed: false,
+		},
+		{
+			name:         "creation failed",
+			recorded:     []*casemanager.OperationCase{newBOLAOperationCase("alice", "POST", "/users", nil, 400, `{"id": 1}`)},
+			accessing:    newBOLAOperationCase("alice", "GET", "/users/{userId}", userParams, 0, ""),
+			wantAccessed: false,
+		},
+		{
+			name: "deleted by owner",
+			recorded: []*casemanager.OperationCase{
+				newBOLAOperationCase("alice", "POST", "/users", nil, 201, `{"id": 1}`),
+				newBOLAOperationCase("alice", "DELETE", "/users/{userId}", userParams, 204, ""),
+			},
+			accessing:    newBOLAOperationCase("alice", "GET", "/users/{userId}", userParams, 0, ""),
+			wantAccessed: false,
+		},
+		{
+			name:         "creation is not access",
+			recorded:     []*casemanager.OperationCase{newBOLAOperationCase("alice", "POST", "/users", nil, 201, `{"id": 1}`)},
+			accessing:    newBOLAOperationCase("alice", "POST", "/users/{userId}/posts", userParams, 0, ""),
+			wantAccessed: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			oracle := feedback.NewBOLAOracle(apiManager)
+			oracle.BeginScenario()
+			for _, operationCase := range tt.recorded {
+				oracle.RecordOperationCase(operationCase)
+			}
+			resourcePath, owner, accessed := oracle.GetAccessedOwnedResource(tt.accessing)
+			assert.Equal(t, tt.wantAccessed, accessed)
+			if !tt.wantAccessed {
+				return
+			}
+			assert.Equal(t, tt.wantPath, resourcePath)
+			assert.Equal(t, "alice", owner)
+
+			replayedOperationCase := tt.accessing.Copy()
+			replayedOperationCase.UserName = "bob"
+			replayedOperationCase.ResponseStatusCode = tt.replayStatusCode
+			violation := oracle.CheckReplay(uuid.New(), resourcePath, replayedOperationCase)
+			if !tt.wantViolation {
+				assert.Nil(t, violation)
+				assert.Empty(t, oracle.Violations)
+				return
+			}
+			assert.NotNil(t, violation)
+			assert.Equal(t, "alice", violation.Owner)
+			assert.Equal(t, "bob", violation.Attacker)
+			assert.Equal(t, tt.wantPath, violation.ResourcePath)
+			assert.Equal(t, tt.accessing.APIMethod, violation.Witness)
+			assert.Len(t, oracle.Violations, 1)
+		})
+	}
+}
+
+// TestBOLAOracleBeginScenario tests that resources created in a test scenario are not tracked in later ones,
+// and that resources deleted by replays are not tracked any more.
+func TestBOLAOracleBeginScenario(t *testing.T) {
+	oracle := feedback.NewBOLAOracle(newCRUDFixtureAPIManager(t))
+	accessing := newBOLAOperationCase("alice", "DELETE", "/users/{userId}", map[string]string{"userId": "1"}, 0, "")
+
+	oracle.RecordOperationCase(newBOLAOperationCase("alice", "POST", "/users", nil, 201, `{"id": 1}`))
+	oracle.BeginScenario()
+	_, _, accessed := oracle.GetAccessedOwnedResource(accessing)
+	assert.False(t, accessed)
+
+	oracle.RecordOperationCase(newBOLAOperationCase("alice", "POST", "/users", nil, 201, `{"id": 1}`))
+	replayedOperationCase := accessing.Copy()
+	replayedOperationCase.UserName = "bob"
+	replayedOperationCase.ResponseStatusCode = 204
+	assert.NotNil(t, oracle.CheckReplay(uuid.New(), "/users/1", replayedOperationCase))
+	_, _, accessed = oracle.GetAccessedOwnedResource(accessing)
+	assert.False(t, accessed)
+}
+
+// TestCheckUserSessionHeadersNotOverridden tests rejecting user sessions whose credential headers are overridden by HTTP client middlewares,
+// e.g., `Authorization` by OAuth2, as replays as other users would be sent as the primary user.
+func TestCheckUserSessionHeadersNotOverridden(t *testing.T) {
+	alice := &casemanager.UserSession{Name: "alice", Headers: map[string]string{"Authorization": "Bearer a"}}
+	bob := &casemanager.UserSession{Name: "bob", Headers: map[string]string{"authorization": "Bearer b"}}
+	tenant := &casemanager.UserSession{Name: "tenant", Headers: map[string]string{"X-Tenant-Id": "t"}}
+
+	tests := []struct {
+		name              string
+		userSessions      []*casemanager.UserSession
+		overriddenHeaders []string
+		wantErr           bool
+	}{
+		{"no middleware", []*casemanager.UserSession{alice, bob}, []string{}, false},
+		{"overridden", []*casemanager.UserSession{alice, bob}, []string{"Authorization"}, true},
+		{"overridden of other user", []*casemanager.UserSession{tenant, bob}, []string{"Authorization"}, true},
+		{"other headers", []*casemanager.UserSession{tenant, tenant}, []string{"Authorization", "X-Signature"}, false},
+		{"single user", []*casemanager.UserSession{alice}, []string{"Authorization"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := casemanager.CheckUserSessionHeadersNotOverridden(tt.userSessions, tt.overriddenHeaders)
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,50 @@
+package test
+
+import (
+	"testing"
+
+	"resttracefuzzer/pkg/casemanager"
+	"resttracefuzzer/pkg/static"
+	"resttracefuzzer/pkg/utils"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestInjectOperationCase tests that the injected scenario is queued, and a copy of it, not shared with fuzzing, is returned.
+func TestInjectOperationCase(t *testing.T) {
+	apiManager := static.NewAPIManager()
+	apiManager.InitFromDocs(newSingleGetOperationDoc("/items", "listItems"), &openapi3.T{Paths: openapi3.NewPaths()})
+	caseManager := &casemanager.CaseManager{
+		APIManager:    apiManager,
+		TestScenarios: utils.NewPriorityQueue[*casemanager.TestScenario](nil),
+	}
+	apiMethod := static.SimpleAPIMethod{Endpoint: "/items", Method: "GET", Typ: static.SimpleAPIMethodTypeHTTP}
+
+	returnedScenario, err := caseManager.InjectOperationCase(&casemanager.OperationCase{
+		APIMethod:          apiMethod,
+		RequestHeaders:     map[string]string{"X-Tenant": "acme"},
+		RequestPathParams:  map[string]string{},
+		RequestQueryParams: map[string]string{"limit": "10"},
+		Energy:             20,
+	})
+	assert.NoError(t, err)
+	assert.True(t, returnedScenario.Injected)
+
+	queuedScenario, err := caseManager.Pop()
+	assert.NoError(t, err)
+	assert.NotSame(t, queuedScenario, returnedScenario)
+	assert.Equal(t, queuedScenario.UUID, returnedScenario.UUID)
+	assert.Equal(t, queuedScenario.Energy, returnedScenario.Energy)
+	// Fuzzing mutates the queued scenario, which the returned one does not share.
+	queuedScenario.OperationCases[0].RequestHeaders["X-Tenant"] = "other"
+	queuedScenario.OperationCases[0].RequestQueryParams["limit"] = "20"
+	assert.Equal(t, "acme", returnedScenario.OperationCases[0].RequestHeaders["X-Tenant"])
+	assert.Equal(t, "10", returnedScenario.OperationCases[0].RequestQueryParams["limit"])
+
+	// Undefined API methods are rejected.
+	_, err = caseManager.InjectOperationCase(&casemanager.OperationCase{
+		APIMethod: static.SimpleAPIMethod{Endpoint: "/unknown", Method: "GET", Typ: static.SimpleAPIMethodTypeHTTP},
+	})
+	assert.Error(t, err)
+}
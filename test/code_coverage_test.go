@@ -0,0 +1,121 @@
+package test
+
+import (
+	"context"
+	nethttp "net/http"
+	"net/http/httptest"
+	"testing"
+
+	"resttracefuzzer/pkg/feedback"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// jacocoReportFixture is a JaCoCo XML report, with counters of a package and the whole report.
+const jacocoReportFixture = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<!DOCTYPE report PUBLIC "-//JACOCO//DTD Report 1.1//EN" "report.dtd">
+<report name="order-service">
+	<sessioninfo id="order-1" start="1700000000000" dump="1700000001000"/>
+	<package name="com/example/order">
+		<counter type="LINE" missed="1" covered="1"/>
+	</package>
+	<counter type="INSTRUCTION" missed="40" covered="60"/>
+	<counter type="BRANCH" missed="3" covered="5"/>
+	<counter type="LINE" missed="10" covered="30"/>
+	<counter type="METHOD" missed="2" covered="8"/>
+</report>`
+
+// goCoverProfileFixture is a Go cover profile, merged from two processes, with an invalid line.
+const goCoverProfileFixture = `mode: count
+example.com/order/handler.go:10.30,12.2 2 0
+example.com/order/handler.go:14.30,18.2 3 1
+example.com/order/handler.go:10.30,12.2 2 4
+example.com/order/store.go:5.20,7.2 5 0
+invalid line
+`
+
+// newCoverageServer starts a server serving the coverage report at any path.
+func newCoverageServer(t *testing.T, report string) *httptest.Server {
+	server := httptest.NewServer(nethttp.HandlerFunc(func(w nethttp.ResponseWriter, r *nethttp.Request) {
+		w.Write([]byte(report))
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+// TestCoverageCollectorCollect tests parsing JaCoCo XML reports and Go cover profiles.
+func TestCoverageCollectorCollect(t *testing.T) {
+	tests := []struct {
+		name   string
+		typ    string
+		report string
+		want   *feedback.CodeCoverage
+	}{
+		{"jacoco", feedback.CodeCoverageCollectorTypeJaCoCo, jacocoReportFixture, &feedback.CodeCoverage{CoveredLines: 30, TotalLines: 40, CoveredBranches: 5, TotalBranches: 8}},
+		{"jacoco without counters", feedback.CodeCoverageCollectorTypeJaCoCo, `<report name="empty"/>`, &feedback.CodeCoverage{}},
+		{"go", feedback.CodeCoverageCollectorTypeGo, goCoverProfileFixture, &feedback.CodeCoverage{CoveredLines: 5, TotalLines: 10}},
+		{"go mode only", feedback.CodeCoverageCollectorTypeGo, "mode: set\n", &feedback.CodeCoverage{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newCoverageServer(t, tt.report)
+			collectors, err := feedback.NewCoverageCollectors(`[{"name": "order", "type": "`+tt.typ+`", "url": "`+server.URL+`/report"}]`, "")
+			assert.NoError(t, err)
+			assert.Len(t, collectors, 1)
+			coverage, err := collectors[0].Collect(context.Background())
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, coverage)
+		})
+	}
+
+	server := newCoverageServer(t, "not xml <")
+	collectors, err := feedback.NewCoverageCollectors(`[{"type": "jacoco", "url": "`+server.URL+`"}]`, "")
+	assert.NoError(t, err)
+	assert.Equal(t, server.URL, collectors[0].Name())
+	_, err = collectors[0].Collect(context.Background())
+	assert.Error(t, err)
+}
+
+// TestNewCoverageCollectorsInvalid tests that invalid collector configurations are rejected, including duplicate names.
+func TestNewCoverageCollectorsInvalid(t *testing.T) {
+	tests := []struct {
+		name          string
+		collectorsStr string
+		wantCount     int
+		wantErr       bool
+	}{
+		{"empty", "", 0, false},
+		{"two collectors", `[{"name": "order", "type": "jacoco", "url": "http://order:8081/report.xml"}, {"name": "cart", "type": "go", "url": "http://cart:7777/v1/cover/profile"}]`, 2, false},
+		{"same URL of different names", `[{"name": "order-1", "type": "jacoco", "url": "http://order:8081/report.xml"}, {"name": "order-2", "type": "jacoco", "url": "http://order:8081/report.xml"}]`, 2, false},
+		{"invalid JSON", `[{"name": "order"`, 0, true},
+		{"missing URL", `[{"name": "order", "type": "jacoco"}]`, 0, true},
+		{"unsupported type", `[{"name": "order", "type": "istanbul", "url": "http://order:8081/report"}]`, 0, true},
+		{"duplicate names", `[{"name": "order", "type": "jacoco", "url": "http://order-1:8081/report.xml"}, {"name": "order", "type": "go", "url": "http://order-2:7777/v1/cover/profile"}]`, 0, true},
+		{"duplicate default names", `[{"type": "jacoco", "url": "http://order:8081/report.xml"}, {"type": "jacoco", "url": "http://order:8081/report.xml"}]`, 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			collectors, err := feedback.NewCoverageCollectors(tt.collectorsStr, "")
+			if tt.wantErr {
+				assert.Error(t, err)
+				assert.Nil(t, collectors)
+			} else {
+				assert.NoError(t, err)
+				assert.Len(t, collectors, tt.wantCount)
+			}
+		})
+	}
+}
+
+// TestCoverageCollectorVerifiesCertificate tests that certificates of coverage endpoints are verified.
+func TestCoverageCollectorVerifiesCertificate(t *testing.T) {
+	server := httptest.NewTLSServer(nethttp.HandlerFunc(func(w nethttp.ResponseWriter, r *nethttp.Request) {
+		w.Write([]byte(goCoverProfileFixture))
+	}))
+	defer server.Close()
+
+	collectors, err := feedback.NewCoverageCollectors(`[{"name": "cart", "type": "go", "url": "`+server.URL+`"}]`, "")
+	assert.NoError(t, err)
+	_, err = collectors[0].Collect(context.Background())
+	assert.ErrorContains(t, err, "certificate")
+}
@@ -450,3 +450,26 @@ func TestBase64ToHex(t *testing.T) {
 		})
 	}
 }
+
+func TestGetPercentile(t *testing.T) {
+	values := []float64{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}
+	testCases := []struct {
+		percentile float64
+		expected   float64
+	}{
+		{50, 5},
+		{95, 10},
+		{90, 9},
+		{10, 1},
+		{1, 1},
+		{100, 10},
+	}
+	for _, tc := range testCases {
+		if got := utils.GetPercentile(values, tc.percentile); got != tc.expected {
+			t.Errorf("GetPercentile(%v, %v) = %v; want %v", values, tc.percentile, got, tc.expected)
+		}
+	}
+	if got := utils.GetPercentile([]float64{}, 95); got != 0 {
+		t.Errorf("GetPercentile of empty values = %v; want 0", got)
+	}
+}
@@ -0,0 +1,55 @@
+package test
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"resttracefuzzer/pkg/utils"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWriteAndReadFileWithCompression(t *testing.T) {
+	dir := t.TempDir()
+	data := []byte(strings.Repeat(`{"traceID":"abc","spans":[]}`+"\n", 1000))
+
+	// Compressed files are read by their original paths
+	path := filepath.Join(dir, "trace.json")
+	writtenPath, err := utils.WriteFileWithCompression(path, data, utils.CompressionZstd)
+	assert.NoError(t, err)
+	assert.Equal(t, path+utils.ZstdFileExtension, writtenPath)
+	compressed, err := os.ReadFile(writtenPath)
+	assert.NoError(t, err)
+	assert.Less(t, len(compressed), len(data))
+	read, err := utils.ReadFileWithDecompression(path)
+	assert.NoError(t, err)
+	assert.Equal(t, data, read)
+
+	reader, err := utils.OpenFileWithDecompression(writtenPath)
+	assert.NoError(t, err)
+	read, err = io.ReadAll(reader)
+	assert.NoError(t, err)
+	assert.NoError(t, reader.Close())
+	assert.Equal(t, data, read)
+
+	// Uncompressed files are read as is
+	plainPath := filepath.Join(dir, "report.json")
+	writtenPath, err = utils.WriteFileWithCompression(plainPath, []byte("{}"), utils.CompressionNone)
+	assert.NoError(t, err)
+	assert.Equal(t, plainPath, writtenPath)
+	read, err = utils.ReadFileWithDecompression(plainPath)
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("{}"), read)
+
+	reader, err = utils.OpenFileWithDecompression(plainPath)
+	assert.NoError(t, err)
+	read, err = io.ReadAll(reader)
+	assert.NoError(t, err)
+	assert.NoError(t, reader.Close())
+	assert.Equal(t, []byte("{}"), read)
+
+	_, err = utils.WriteFileWithCompression(plainPath, data, "gzip")
+	assert.Error(t, err)
+}
@@ -0,0 +1,108 @@
+package test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"resttracefuzzer/internal/config"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// writeConfigFile writes the content to a config file of the name in a temp directory, and returns its path.
+func writeConfigFile(t *testing.T, name, content string) string {
+	configFilePath := filepath.Join(t.TempDir(), name)
+	assert.NoError(t, os.WriteFile(configFilePath, []byte(content), 0o644))
+	return configFilePath
+}
+
+// TestLoadConfigFile tests loading config files in JSON and YAML, with profiles.
+func TestLoadConfigFile(t *testing.T) {
+	tests := []struct {
+		name        string
+		fileName    string
+		content     string
+		cliProfile  string
+		wantBudget  int
+		wantBaseURL string
+	}{
+		{"json", "config.json", `{"fuzzerBudget": 30, "serverBaseURL": "http://a"}`, "", 30, "http://a"},
+		{"yaml", "config.yaml", "fuzzerBudget: 30\nserverBaseURL: http://a\n", "", 30, "http://a"},
+		{"keys case-insensitive", "config.json", `{"FuzzerBudget": 30, "serverbaseurl": "http://a"}`, "", 30, "http://a"},
+		{"profile of file", "config.yaml", "fuzzerBudget: 30\nprofile: smoke\nprofiles:\n  smoke:\n    fuzzerBudget: 5\n", "", 5, ""},
+		{"profile of command line takes precedence", "config.yaml", "fuzzerBudget: 30\nprofile: smoke\nprofiles:\n  smoke:\n    fuzzerBudget: 5\n  nightly:\n    fuzzerBudget: 3600\n", "nightly", 3600, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.RuntimeConfig{Profile: tt.cliProfile}
+			err := config.LoadConfigFile(writeConfigFile(t, tt.fileName, tt.content), cfg)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantBudget, cfg.FuzzerBudget)
+			assert.Equal(t, tt.wantBaseURL, cfg.ServerBaseURL)
+		})
+	}
+}
+
+// TestLoadConfigFileInvalid tests that invalid config files are rejected with the problems found.
+func TestLoadConfigFileInvalid(t *testing.T) {
+	tests := []struct {
+		name        string
+		content     string
+		profile     string
+		wantErrSubs []string
+	}{
+		{"unknown key with suggestion", `{"serverBaseUrll": "http://a"}`, "", []string{`unknown key "serverBaseUrll"`, `did you mean "serverBaseURL"?`}},
+		{"unknown key without suggestion", `{"zzz": 1}`, "", []string{`unknown key "zzz"`}},
+		{"wrong type", `{"fuzzerBudget": "30", "enableCookieJar": 1}`, "", []string{`key "enableCookieJar" expects a boolean value`, `key "fuzzerBudget" expects a number value`}},
+		{"problem in profile", `{"profiles": {"smoke": {"fuzzerBudgett": 5}}}`, "", []string{`profile "smoke": unknown key "fuzzerBudgett"`}},
+		{"invalid profiles", `{"profiles": []}`, "", []string{`key "profiles" expects an object`}},
+		{"profile not found", `{"profiles": {"smoke": {}}}`, "nightly", []string{`profile "nightly" is not found`, "[smoke]"}},
+		{"not an object", `[1]`, "", []string{"a JSON or YAML object is expected"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.RuntimeConfig{Profile: tt.profile}
+			err := config.LoadConfigFile(writeConfigFile(t, "config.json", tt.content), cfg)
+			assert.Error(t, err)
+			for _, sub := range tt.wantErrSubs {
+				assert.Contains(t, err.Error(), sub)
+			}
+		})
+	}
+
+	// null is accepted for all types, and leaves the value unchanged.
+	cfg := &config.RuntimeConfig{FuzzerBudget: 30}
+	assert.NoError(t, config.LoadConfigFile(writeConfigFile(t, "config.json", `{"fuzzerBudget": null}`), cfg))
+	assert.Equal(t, 30, cfg.FuzzerBudget)
+}
+
+// TestLoadConfigFileActionKeys tests that action keys in the config file do not override the command line.
+func TestLoadConfigFileActionKeys(t *testing.T) {
+	cfg := &config.RuntimeConfig{PrintDefaultConfig: true}
+	err := config.LoadConfigFile(writeConfigFile(t, "config.json", `{"printDefaultConfig": false, "fuzzerBudget": 30, "profile": "smoke", "profiles": {"smoke": {"PrintDefaultConfig": false}}}`), cfg)
+	assert.NoError(t, err)
+	assert.True(t, cfg.PrintDefaultConfig)
+	assert.Equal(t, 30, cfg.FuzzerBudget)
+
+	cfg = &config.RuntimeConfig{}
+	err = config.LoadConfigFile(writeConfigFile(t, "config.json", `{"printDefaultConfig": true}`), cfg)
+	assert.NoError(t, err)
+	assert.False(t, cfg.PrintDefaultConfig)
+}
+
+// TestGenerateDefaultConfigTemplate tests that the generated template is a valid config file of default values, commented.
+func TestGenerateDefaultConfigTemplate(t *testing.T) {
+	template := config.GenerateDefaultConfigTemplate()
+	assert.Contains(t, template, "# Flag: --server-base-url, environment variable: SERVER_BASE_URL, type: string.\n")
+	assert.Contains(t, template, "\nfuzzerBudget: ")
+	assert.NotContains(t, template, "printDefaultConfig")
+
+	cfg := &config.RuntimeConfig{}
+	assert.NoError(t, config.LoadConfigFile(writeConfigFile(t, "config.yaml", template), cfg))
+	for _, spec := range config.ConfigKeySpecs {
+		if spec.Key == "fuzzerBudget" {
+			assert.EqualValues(t, spec.Default, cfg.FuzzerBudget)
+		}
+	}
+}
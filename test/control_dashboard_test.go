@@ -0,0 +1,102 @@
+package test
+
+import (
+	"io"
+	nethttp "net/http"
+	"net/http/httptest"
+	"testing"
+
+	"resttracefuzzer/internal/control"
+	"resttracefuzzer/pkg/casemanager"
+	"resttracefuzzer/pkg/static"
+	"resttracefuzzer/pkg/utils"
+
+	"github.com/bytedance/sonic"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestDashboardQueueScenarios tests that the dashboard lists queued scenarios as summaries, without request headers.
+func TestDashboardQueueScenarios(t *testing.T) {
+	caseManager := &casemanager.CaseManager{
+		TestScenarios: utils.NewPriorityQueue(func(testScenario *casemanager.TestScenario) int { return testScenario.Energy }),
+	}
+	getUser := static.SimpleAPIMethod{Method: "GET", Endpoint: "/users/{id}", Typ: static.SimpleAPIMethodTypeHTTP}
+	deleteUser := static.SimpleAPIMethod{Method: "DELETE", Endpoint: "/users/{id}", Typ: static.SimpleAPIMethodTypeHTTP}
+	lowScenario := casemanager.NewTestScenario([]*casemanager.OperationCase{
+		{APIMethod: getUser, RequestHeaders: map[string]string{"Authorization": "Bearer secret-token"}},
+	})
+	lowScenario.Energy = 1
+	highScenario := casemanager.NewTestScenario([]*casemanager.OperationCase{
+		{APIMethod: getUser, RequestHeaders: map[string]string{"Authorization": "Bearer secret-token"}},
+		{APIMethod: deleteUser, RequestHeaders: map[string]string{"Cookie": "session=secret-cookie"}},
+	})
+	highScenario.Energy = 5
+	highScenario.ExecutedCount = 3
+	caseManager.TestScenarios.Push(lowScenario)
+	caseManager.TestScenarios.Push(highScenario)
+
+	dashboardServer := control.NewDashboardServer("127.0.0.1:0", nil, caseManager, nil, nil)
+	server := httptest.NewServer(dashboardServer.Handler())
+	defer server.Close()
+
+	tests := []struct {
+		name           string
+		query          string
+		wantStatusCode int
+		wantUUIDs      []string
+	}{
+		{"default limit", "", nethttp.StatusOK, []string{highScenario.UUID.String(), lowScenario.UUID.String()}},
+		{"limit", "?limit=1", nethttp.StatusOK, []string{highScenario.UUID.String()}},
+		{"invalid limit", "?limit=-1", nethttp.StatusBadRequest, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := nethttp.Get(server.URL + "/api/queue/scenarios" + tt.query)
+			assert.NoError(t, err)
+			defer resp.Body.Close()
+			body, err := io.ReadAll(resp.Body)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantStatusCode, resp.StatusCode)
+			if tt.wantStatusCode != nethttp.StatusOK {
+				return
+			}
+			assert.NotContains(t, string(body), "secret")
+			assert.NotContains(t, string(body), "requestHeaders")
+
+			var summaries []*casemanager.TestScenarioSummary
+			assert.NoError(t, sonic.Unmarshal(body, &summaries))
+			uuids := make([]string, 0, len(summaries))
+			for _, summary := range summaries {
+				uuids = append(uuids, summary.UUID.String())
+			}
+			assert.Equal(t, tt.wantUUIDs, uuids)
+			assert.Equal(t, 5, summaries[0].Energy)
+			assert.Equal(t, 3, summaries[0].ExecutedCount)
+			assert.Len(t, summaries[0].OperationCases, 2)
+			assert.Equal(t, getUser, summaries[0].OperationCases[0].APIMethod)
+			assert.Equal(t, deleteUser, summaries[0].OperationCases[1].APIMethod)
+		})
+	}
+}
+
+// TestIsLoopbackAddress tests telling whether the control API only accepts connections from the local host.
+func TestIsLoopbackAddress(t *testing.T) {
+	tests := []struct {
+		address string
+		want    bool
+	}{
+		{"127.0.0.1:8089", true},
+		{"localhost:8089", true},
+		{"[::1]:8089", true},
+		{":8089", false},
+		{"0.0.0.0:8089", false},
+		{"192.168.1.2:8089", false},
+		{"example.com:8089", false},
+		{"127.0.0.1", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.address, func(t *testing.T) {
+			assert.Equal(t, tt.want, control.IsLoopbackAddress(tt.address))
+		})
+	}
+}
@@ -0,0 +1,91 @@
+package test
+
+import (
+	"testing"
+
+	"resttracefuzzer/pkg/casemanager"
+	"resttracefuzzer/pkg/feedback"
+	"resttracefuzzer/pkg/static"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/stretchr/testify/assert"
+)
+
+// newCRUDFixtureAPIManager creates an API manager of users and their posts, i.e., a flat and a nested collection.
+func newCRUDFixtureAPIManager(t *testing.T) *static.APIManager {
+	doc, err := openapi3.NewLoader().LoadFromData([]byte(`{
+		"openapi": "3.0.0",
+		"info": {"title": "users", "version": "1.0"},
+		"paths": {
+			"/users": {"post": {"responses": {"201": {"description": "created"}}}},
+			"/users/{userId}": {
+				"get": {"responses": {"200": {"description": "ok"}}},
+				"delete": {"responses": {"204": {"description": "deleted"}}}
+			},
+			"/users/{userId}/posts": {"post": {"responses": {"201": {"description": "created"}}}},
+			"/users/{userId}/posts/{postId}": {
+				"get": {"responses": {"200": {"description": "ok"}}},
+				"delete": {"responses": {"204": {"description": "deleted"}}}
+			}
+		}
+	}`))
+	assert.NoError(t, err)
+	apiManager := static.NewAPIManager()
+	apiManager.InitFromDocs(doc, &openapi3.T{Paths: openapi3.NewPaths()})
+	return apiManager
+}
+
+// TestCRUDOracleCheckScenario tests that only executed operation cases are checked, in order of execution,
+// where resources of nested collections are identified by path parameters of their parents as well.
+func TestCRUDOracleCheckScenario(t *testing.T) {
+	apiManager := newCRUDFixtureAPIManager(t)
+
+	userParams := map[string]string{"userId": "1000000"}
+	create := newExecutedOperationResult("POST", "/users", nil, 201, "", `{"id": 1000000}`)
+	remove := newExecutedOperationResult("DELETE", "/users/{userId}", userParams, 204, "", "")
+	read := newExecutedOperationResult("GET", "/users/{userId}", userParams, 200, "", `{"id": 1000000}`)
+	readNotFound := newExecutedOperationResult("GET", "/users/{userId}", userParams, 404, "", "")
+
+	postParams := map[string]string{"userId": "1", "postId": "5"}
+	createPost := newExecutedOperationResult("POST", "/users/{userId}/posts", map[string]string{"userId": "1"}, 201, "", `{"postId": 5}`)
+	createPostOfUnknownUser := newExecutedOperationResult("POST", "/users/{userId}/posts", nil, 201, "", `{"postId": 5}`)
+	removePost := newExecutedOperationResult("DELETE", "/users/{userId}/posts/{postId}", postParams, 204, "", "")
+	readPost := newExecutedOperationResult("GET", "/users/{userId}/posts/{postId}", postParams, 200, "", `{"postId": 5}`)
+	readPostNotFound := newExecutedOperationResult("GET", "/users/{userId}/posts/{postId}", postParams, 404, "", "")
+	readPostOfOtherUser := newExecutedOperationResult("GET", "/users/{userId}/posts/{postId}", map[string]string{"userId": "2", "postId": "5"}, 404, "", "")
+
+	tests := []struct {
+		name             string
+		operationResults []*feedback.OperationResult
+		wantTypes        []feedback.CRUDInvariantViolationType
+		wantPath         string
+	}{
+		{"get after delete", []*feedback.OperationResult{create, remove, read}, []feedback.CRUDInvariantViolationType{feedback.CRUDInvariantViolationTypeGetAfterDelete}, "/users/1000000"},
+		{"get before delete", []*feedback.OperationResult{create, read, remove}, []feedback.CRUDInvariantViolationType{}, ""},
+		// The read is in the scenario with a stale response, but not executed, e.g., the scenario is aborted after the deletion.
+		{"get not executed", []*feedback.OperationResult{create, remove}, []feedback.CRUDInvariantViolationType{}, ""},
+		{"not found after create", []*feedback.OperationResult{create, readNotFound}, []feedback.CRUDInvariantViolationType{feedback.CRUDInvariantViolationTypeNotFoundAfterCreate}, "/users/1000000"},
+		{"not found after delete", []*feedback.OperationResult{create, remove, readNotFound}, []feedback.CRUDInvariantViolationType{}, ""},
+		{"nested get after delete", []*feedback.OperationResult{createPost, removePost, readPost}, []feedback.CRUDInvariantViolationType{feedback.CRUDInvariantViolationTypeGetAfterDelete}, "/users/1/posts/5"},
+		{"nested not found after create", []*feedback.OperationResult{createPost, readPostNotFound}, []feedback.CRUDInvariantViolationType{feedback.CRUDInvariantViolationTypeNotFoundAfterCreate}, "/users/1/posts/5"},
+		{"nested not found of other parent", []*feedback.OperationResult{createPost, readPostOfOtherUser}, []feedback.CRUDInvariantViolationType{}, ""},
+		{"nested of unknown parent", []*feedback.OperationResult{createPostOfUnknownUser, readPostNotFound}, []feedback.CRUDInvariantViolationType{}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			operationCases := make([]*casemanager.OperationCase, 0, len(tt.operationResults))
+			for _, operationResult := range tt.operationResults {
+				operationCases = append(operationCases, operationResult.OperationCase)
+			}
+			testScenario := casemanager.NewTestScenario(operationCases)
+			oracle := feedback.NewCRUDOracle(apiManager)
+			violations := oracle.CheckScenario(testScenario, tt.operationResults)
+			gotTypes := make([]feedback.CRUDInvariantViolationType, 0, len(violations))
+			for _, violation := range violations {
+				gotTypes = append(gotTypes, violation.Typ)
+				assert.Equal(t, tt.wantPath, violation.ResourcePath)
+			}
+			assert.Equal(t, tt.wantTypes, gotTypes)
+		})
+	}
+}
@@ -0,0 +1,90 @@
+package test
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"resttracefuzzer/internal/demo"
+	"testing"
+	"time"
+
+	"github.com/bytedance/sonic"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestDemoFuzzingLoop runs the whole fuzzing loop against the demo target, i.e., sending requests, fetching traces and reporting,
+// and checks that internal services are observed in traces and the seeded propagation bug is found.
+func TestDemoFuzzingLoop(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skip integration test in short mode")
+	}
+	dir := t.TempDir()
+	fuzzerPath := filepath.Join(dir, "api-fuzzer")
+	build := exec.Command("go", "build", "-o", fuzzerPath, "../cmd/api-fuzzer")
+	output, err := build.CombinedOutput()
+	if !assert.NoError(t, err, string(output)) {
+		return
+	}
+
+	d, err := demo.Start()
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer d.Stop(context.Background())
+	specPath := filepath.Join(dir, "openapi.json")
+	assert.NoError(t, demo.WriteOpenAPISpec(specPath))
+
+	outputDir := filepath.Join(dir, "output")
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+	fuzz := exec.CommandContext(ctx, fuzzerPath,
+		"--openapi-spec", specPath,
+		"--server-base-url", d.ServerBaseURL,
+		"--trace-backend-type", "Jaeger",
+		"--trace-backend-url", d.TraceBackendURL,
+		"--fuzzer-budget", "10",
+		"--trace-fetch-wait-time", "100",
+		"--output-dir", outputDir,
+		"--synthesize-internal-service-openapi=true",
+		"--context-propagation-headers", demo.TenantIDHeader,
+		"--extra-headers", `{"`+demo.TenantIDHeader+`":"tenant-1"}`,
+	)
+	output, err = fuzz.CombinedOutput()
+	if !assert.NoError(t, err, string(output[max(0, len(output)-4096):])) {
+		return
+	}
+	assert.Positive(t, d.JaegerStub.GetTraceCount())
+
+	// Internal services are observed in traces
+	synthesizedDocBytes, err := os.ReadFile(filepath.Join(outputDir, "latest", "synthesized_internal_service_oas.json"))
+	if !assert.NoError(t, err) {
+		return
+	}
+	var synthesizedDoc struct {
+		Paths map[string]any `json:"paths"`
+	}
+	assert.NoError(t, sonic.Unmarshal(synthesizedDocBytes, &synthesizedDoc))
+	assert.Contains(t, synthesizedDoc.Paths, "/items/{itemId}/reservations")
+	assert.Contains(t, synthesizedDoc.Paths, "/payments")
+
+	// The seeded propagation bug is found
+	systemReportBytes, err := os.ReadFile(filepath.Join(outputDir, "latest", "system_report.json"))
+	if !assert.NoError(t, err) {
+		return
+	}
+	var systemReport struct {
+		ContextPropagationBreaks []struct {
+			Header        string `json:"header"`
+			SourceService string `json:"sourceService"`
+			TargetService string `json:"targetService"`
+		} `json:"contextPropagationBreaks"`
+	}
+	assert.NoError(t, sonic.Unmarshal(systemReportBytes, &systemReport))
+	if assert.NotEmpty(t, systemReport.ContextPropagationBreaks) {
+		propagationBreak := systemReport.ContextPropagationBreaks[0]
+		assert.Equal(t, demo.TenantIDHeader, propagationBreak.Header)
+		assert.Equal(t, "order", propagationBreak.SourceService)
+		assert.Equal(t, "payment", propagationBreak.TargetService)
+	}
+}
@@ -0,0 +1,120 @@
+package test
+
+import (
+	"testing"
+
+	"resttracefuzzer/pkg/parser"
+	"resttracefuzzer/pkg/static"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/stretchr/testify/assert"
+)
+
+// newDependencyFixtureAPIManager creates an API manager with the API methods of the dependency file fixtures in testdata/dependency.
+func newDependencyFixtureAPIManager() (*static.APIManager, map[string]static.SimpleAPIMethod) {
+	operations := map[string]static.SimpleAPIMethod{
+		"createProduct": {Method: "POST", Endpoint: "/api/products", Typ: static.SimpleAPIMethodTypeHTTP},
+		"getProduct":    {Method: "GET", Endpoint: "/api/products/{productId}", Typ: static.SimpleAPIMethodTypeHTTP},
+		"updateProduct": {Method: "PUT", Endpoint: "/api/products/{productId}", Typ: static.SimpleAPIMethodTypeHTTP},
+		"deleteProduct": {Method: "DELETE", Endpoint: "/api/products/{productId}", Typ: static.SimpleAPIMethodTypeHTTP},
+		"createReview":  {Method: "POST", Endpoint: "/api/products/{productId}/reviews", Typ: static.SimpleAPIMethodTypeHTTP},
+		"listReviews":   {Method: "GET", Endpoint: "/api/products/{productId}/reviews", Typ: static.SimpleAPIMethodTypeHTTP},
+		"createUser":    {Method: "POST", Endpoint: "/api/users", Typ: static.SimpleAPIMethodTypeHTTP},
+		"createOrder":   {Method: "POST", Endpoint: "/api/orders", Typ: static.SimpleAPIMethodTypeHTTP},
+		"listOrders":    {Method: "GET", Endpoint: "/api/orders", Typ: static.SimpleAPIMethodTypeHTTP},
+	}
+	apiManager := static.NewAPIManager()
+	apiManager.APIMap = make(map[static.SimpleAPIMethod]*openapi3.Operation)
+	for operationID, method := range operations {
+		apiManager.APIMap[method] = &openapi3.Operation{OperationID: operationID}
+	}
+	return apiManager, operations
+}
+
+// TestAPIDependencyEvoMasterParser tests resolving the resource dependencies exported by EvoMaster to the API methods creating and using the resources.
+func TestAPIDependencyEvoMasterParser(t *testing.T) {
+	apiManager, operations := newDependencyFixtureAPIManager()
+	dependencyGraph, err := parser.NewAPIDependencyEvoMasterParser(apiManager).ParseFromFile("testdata/dependency/evomaster_dependencies.csv")
+	assert.NoError(t, err)
+
+	consumers := []static.SimpleAPIMethod{operations["createReview"], operations["listReviews"], operations["createOrder"], operations["listOrders"]}
+	tests := []struct {
+		producer  string
+		consumers []static.SimpleAPIMethod
+	}{
+		{"createProduct", consumers},
+		{"updateProduct", consumers},
+		{"createUser", consumers},
+		{"getProduct", nil},
+		{"deleteProduct", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.producer, func(t *testing.T) {
+			assert.ElementsMatch(t, tt.consumers, dependencyGraph.Graph[operations[tt.producer]])
+		})
+	}
+	assert.Len(t, dependencyGraph.Graph, 3)
+}
+
+// TestAPIDependencyEvoMasterParserInvalid tests that CSV files without the key or targets column are rejected.
+func TestAPIDependencyEvoMasterParserInvalid(t *testing.T) {
+	apiManager, _ := newDependencyFixtureAPIManager()
+	tests := []struct {
+		name    string
+		content string
+		wantErr bool
+	}{
+		{"empty", "", false},
+		{"repeated key column", "key,key,targets,probability\n/api/orders,/api/orders,/api/users/{userId},1.0\n", false},
+		{"missing targets column", "key,probability\n/api/orders,1.0\n", true},
+		{"missing key column", "targets,probability\n/api/users/{userId},1.0\n", true},
+		{"missing first key", "key,targets\n,/api/users/{userId}\n", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parser.NewAPIDependencyEvoMasterParser(apiManager).ParseFromBytes([]byte(tt.content))
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+// TestAPIDependencyRestTestGenParser tests parsing the ODG exported by RestTestGen, with labels of methods and paths or operationIds,
+// and semicolons and escaped quotes inside quoted labels.
+func TestAPIDependencyRestTestGenParser(t *testing.T) {
+	apiManager, operations := newDependencyFixtureAPIManager()
+	dependencyGraph, err := parser.NewAPIDependencyRestTestGenParser(apiManager).ParseFromFile("testdata/dependency/resttestgen_odg.dot")
+	assert.NoError(t, err)
+
+	assert.ElementsMatch(t, []static.SimpleAPIMethod{operations["getProduct"], operations["updateProduct"], operations["deleteProduct"]}, dependencyGraph.Graph[operations["createProduct"]])
+	assert.ElementsMatch(t, []static.SimpleAPIMethod{operations["createReview"]}, dependencyGraph.Graph[operations["getProduct"]])
+	assert.Len(t, dependencyGraph.Graph, 2)
+}
+
+// TestAPIDependencyRestTestGenParserInvalid tests that edges between unresolvable nodes are rejected.
+func TestAPIDependencyRestTestGenParserInvalid(t *testing.T) {
+	apiManager, _ := newDependencyFixtureAPIManager()
+	tests := []struct {
+		name    string
+		dot     string
+		wantErr bool
+	}{
+		{"no edges", `digraph G { 1 [ label="unknownOperation" ]; }`, false},
+		{"quoted semicolon", `digraph G { 1 [ label="createProduct" ]; 2 [ label="a;b" ]; 1 -> 1 [ label="x;y" ]; }`, false},
+		{"unknown operationId", `digraph G { 1 [ label="unknownOperation" ]; 1 -> 1; }`, true},
+		{"invalid endpoint", `digraph G { "GET api" -> "POST /api/products"; }`, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parser.NewAPIDependencyRestTestGenParser(apiManager).ParseFromBytes([]byte(tt.dot))
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
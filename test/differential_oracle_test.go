@@ -0,0 +1,91 @@
+package test
+
+import (
+	"testing"
+
+	"resttracefuzzer/pkg/casemanager"
+	"resttracefuzzer/pkg/feedback"
+	"resttracefuzzer/pkg/static"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+// newDifferentialOperationCase creates an executed operation case of the API method, with the response.
+func newDifferentialOperationCase(method, endpoint string, statusCode int, responseBody string) *casemanager.OperationCase {
+	return &casemanager.OperationCase{
+		APIMethod:          static.SimpleAPIMethod{Method: method, Endpoint: endpoint, Typ: static.SimpleAPIMethodTypeHTTP},
+		RequestHeaders:     map[string]string{},
+		RequestPathParams:  map[string]string{},
+		RequestQueryParams: map[string]string{},
+		ResponseStatusCode: statusCode,
+		ResponseBody:       []byte(responseBody),
+	}
+}
+
+// TestDifferentialOracleCheckResponses tests comparing responses modulo volatile fields and IDs.
+func TestDifferentialOracleCheckResponses(t *testing.T) {
+	tests := []struct {
+		name              string
+		baselineStatus    int
+		baselineBody      string
+		candidateStatus   int
+		candidateBody     string
+		wantDifferingPath []string
+	}{
+		{"same", 200, `{"name": "a"}`, 200, `{"name": "a"}`, nil},
+		{"status differs", 200, `{}`, 500, `{}`, []string{}},
+		{"field differs", 200, `{"name": "a", "price": 1}`, 200, `{"name": "a", "price": 2}`, []string{"$.price"}},
+		{"ignored field", 200, `{"requestId": "x"}`, 200, `{"requestId": "y"}`, nil},
+		{"volatile values", 200, `{"at": "2024-01-01T00:00:00Z"}`, 200, `{"at": "2024-06-01T12:00:00Z"}`, nil},
+		{"server-generated IDs", 200, `{"id": 1, "items": [{"itemId": "a1"}], "order_id": "o1"}`, 200, `{"id": 7, "items": [{"itemId": "b2"}], "order_id": "o9"}`, nil},
+		{"ID of another type", 200, `{"id": 1}`, 200, `{"id": "1"}`, []string{"$.id"}},
+		{"not an ID field", 200, `{"valid": true}`, 200, `{"valid": false}`, []string{"$.valid"}},
+		{"not JSON", 200, `a`, 200, `b`, []string{"$"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			oracle, err := feedback.NewDifferentialOracle(`["requestId"]`)
+			assert.NoError(t, err)
+			baseline := newDifferentialOperationCase("GET", "/orders", tt.baselineStatus, tt.baselineBody)
+			candidate := newDifferentialOperationCase("GET", "/orders", tt.candidateStatus, tt.candidateBody)
+			difference := oracle.CheckResponses(uuid.New(), baseline, candidate)
+			if tt.wantDifferingPath == nil {
+				assert.Nil(t, difference)
+				assert.Empty(t, oracle.Differences)
+				return
+			}
+			assert.NotNil(t, difference)
+			assert.ElementsMatch(t, tt.wantDifferingPath, difference.DifferingFields)
+			assert.Len(t, oracle.Differences, 1)
+		})
+	}
+}
+
+// TestDifferentialOracleMapRequestToCandidate tests that IDs responded by the candidate replace those of the baseline in later requests of the scenario.
+func TestDifferentialOracleMapRequestToCandidate(t *testing.T) {
+	oracle, err := feedback.NewDifferentialOracle("")
+	assert.NoError(t, err)
+	oracle.BeginScenario()
+	baseline := newDifferentialOperationCase("POST", "/orders", 201, `{"id": 1, "code": "o-1"}`)
+	candidate := newDifferentialOperationCase("POST", "/orders", 201, `{"id": 7, "code": "o-1"}`)
+	assert.Nil(t, oracle.CheckResponses(uuid.New(), baseline, candidate))
+
+	operationCase := newDifferentialOperationCase("PUT", "/orders/{orderId}", 0, "")
+	operationCase.RequestPathParams["orderId"] = "1"
+	operationCase.RequestQueryParams["orderId"] = "1"
+	operationCase.RequestQueryParams["page"] = "1"
+	operationCase.RequestBody = []byte(`{"orderId": 1, "quantity": 1, "lines": [{"id": 1}]}`)
+	oracle.MapRequestToCandidate(operationCase)
+	assert.Equal(t, "7", operationCase.RequestPathParams["orderId"])
+	assert.Equal(t, "7", operationCase.RequestQueryParams["orderId"])
+	// Values of fields other than IDs are kept.
+	assert.Equal(t, "1", operationCase.RequestQueryParams["page"])
+	assert.JSONEq(t, `{"orderId": 7, "quantity": 1, "lines": [{"id": 7}]}`, string(operationCase.RequestBody))
+
+	// IDs are mapped only in the scenario they are responded.
+	oracle.BeginScenario()
+	operationCase.RequestPathParams["orderId"] = "1"
+	oracle.MapRequestToCandidate(operationCase)
+	assert.Equal(t, "1", operationCase.RequestPathParams["orderId"])
+}
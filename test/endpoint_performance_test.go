@@ -0,0 +1,37 @@
+package test
+
+import (
+	"errors"
+	"testing"
+
+	"resttracefuzzer/pkg/feedback"
+	"resttracefuzzer/pkg/static"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestEndpointPerformanceTracker tests counting failures of requests, and estimating latency percentiles from a bounded sample.
+func TestEndpointPerformanceTracker(t *testing.T) {
+	tracker := feedback.NewEndpointPerformanceTracker()
+	method := static.SimpleAPIMethod{Endpoint: "/items", Method: "GET", Typ: static.SimpleAPIMethodTypeHTTP}
+	// Latencies are uniform in [1, 10000], with a single outlier.
+	for i := 1; i <= 10000; i++ {
+		tracker.RecordRequest(method, 200, float64(i), nil)
+	}
+	tracker.RecordRequest(method, 200, 1e6, nil)
+	tracker.RecordRequest(method, 500, 1, nil)
+	tracker.RecordRequest(method, 0, 0, errors.New("connection refused"))
+
+	performances := tracker.GetPerformances()
+	assert.Len(t, performances, 1)
+	performance := performances[0]
+	assert.Equal(t, method, performance.APIMethod)
+	assert.Equal(t, 10003, performance.RequestCount)
+	assert.Equal(t, 1, performance.ServerErrorCount)
+	assert.Equal(t, 1, performance.ConnectionErrorCount)
+	assert.InDelta(t, 10001.0/10003, performance.Availability, 1e-9)
+	// The max is of all latencies, while percentiles are estimated from the sample.
+	assert.Equal(t, 1e6, performance.MaxLatency)
+	assert.InDelta(t, 5000, performance.P50Latency, 1000)
+	assert.InDelta(t, 9500, performance.P95Latency, 500)
+}
@@ -0,0 +1,46 @@
+package test
+
+import (
+	"os"
+	"path/filepath"
+	"resttracefuzzer/pkg/utils"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCreateRunOutputDir(t *testing.T) {
+	baseDir := t.TempDir()
+
+	runDir, err := utils.CreateRunOutputDir(baseDir, "20240101000000")
+	assert.NoError(t, err)
+	assert.DirExists(t, runDir)
+
+	// `latest` should follow the most recent run
+	runDir, err = utils.CreateRunOutputDir(baseDir, "20240101000001")
+	assert.NoError(t, err)
+	target, err := os.Readlink(filepath.Join(baseDir, utils.LatestRunOutputDirLinkName))
+	assert.NoError(t, err)
+	assert.Equal(t, filepath.Base(runDir), target)
+}
+
+func TestCleanupOldRunOutputDirs(t *testing.T) {
+	baseDir := t.TempDir()
+	runIDs := []string{"20240101000002", "20240101000000", "20240101000001"}
+	for _, runID := range runIDs {
+		_, err := utils.CreateRunOutputDir(baseDir, runID)
+		assert.NoError(t, err)
+	}
+
+	// Non-positive retention keeps everything
+	removed, err := utils.CleanupOldRunOutputDirs(baseDir, 0)
+	assert.NoError(t, err)
+	assert.Empty(t, removed)
+
+	removed, err = utils.CleanupOldRunOutputDirs(baseDir, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{filepath.Join(baseDir, "run_20240101000000")}, removed)
+	assert.NoDirExists(t, filepath.Join(baseDir, "run_20240101000000"))
+	assert.DirExists(t, filepath.Join(baseDir, "run_20240101000001"))
+	assert.DirExists(t, filepath.Join(baseDir, "run_20240101000002"))
+}
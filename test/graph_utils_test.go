@@ -68,3 +68,53 @@ func TestGraph_CanReach(t *testing.T) {
 		}
 	}
 }
+
+func TestGraph_ExportDOT(t *testing.T) {
+	g := utils.NewGraph[TestNode, TestEdge]()
+	g.AddEdge(TestEdge{From: "A", To: "B"})
+	g.AddEdge(TestEdge{From: "B", To: "C\"1\""})
+
+	nodeLabel := func(node TestNode) string { return "node\n" + string(node) }
+	edgeStyle := func(edge TestEdge) utils.GraphEdgeStyle {
+		if edge.From == "A" {
+			return utils.GraphEdgeStyle{Label: "hits: 1", Color: "green"}
+		}
+		return utils.GraphEdgeStyle{}
+	}
+	expected := "digraph \"G\" {\n" +
+		"  rankdir=LR;\n" +
+		"  node [shape=box];\n" +
+		"  n0 [label=\"node\\nA\"];\n" +
+		"  n1 [label=\"node\\nB\"];\n" +
+		"  n2 [label=\"node\\nC\\\"1\\\"\"];\n" +
+		"  n0 -> n1 [label=\"hits: 1\", color=\"green\"];\n" +
+		"  n1 -> n2;\n" +
+		"}\n"
+	if dot := g.ExportDOT("G", nodeLabel, edgeStyle); dot != expected {
+		t.Errorf("Unexpected DOT output:\n%s", dot)
+	}
+}
+
+func TestGraph_ExportMermaid(t *testing.T) {
+	g := utils.NewGraph[TestNode, TestEdge]()
+	g.AddEdge(TestEdge{From: "A", To: "B"})
+	g.AddEdge(TestEdge{From: "B", To: "C\"1\""})
+
+	nodeLabel := func(node TestNode) string { return "node\n" + string(node) }
+	edgeStyle := func(edge TestEdge) utils.GraphEdgeStyle {
+		if edge.From == "B" {
+			return utils.GraphEdgeStyle{Label: "hits: 0", Color: "red"}
+		}
+		return utils.GraphEdgeStyle{}
+	}
+	expected := "flowchart LR\n" +
+		"  n0[\"node<br/>A\"]\n" +
+		"  n1[\"node<br/>B\"]\n" +
+		"  n2[\"node<br/>C#quot;1#quot;\"]\n" +
+		"  n0 --> n1\n" +
+		"  n1 -->|\"hits: 0\"| n2\n" +
+		"  linkStyle 1 stroke:red\n"
+	if mermaid := g.ExportMermaid(nodeLabel, edgeStyle); mermaid != expected {
+		t.Errorf("Unexpected Mermaid output:\n%s", mermaid)
+	}
+}
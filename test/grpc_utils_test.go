@@ -0,0 +1,205 @@
+package test
+
+import (
+	"context"
+	"io"
+	nethttp "net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"resttracefuzzer/internal/config"
+	"resttracefuzzer/pkg/static"
+	"resttracefuzzer/pkg/utils/grpc"
+	"resttracefuzzer/pkg/utils/http"
+
+	"github.com/bytedance/sonic"
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// TestHTTPStatusFromGRPCCode tests mapping gRPC status codes to HTTP status codes.
+func TestHTTPStatusFromGRPCCode(t *testing.T) {
+	assert.Equal(t, 200, grpc.HTTPStatusFromGRPCCode(0))
+	assert.Equal(t, 400, grpc.HTTPStatusFromGRPCCode(3))
+	assert.Equal(t, 404, grpc.HTTPStatusFromGRPCCode(5))
+	assert.Equal(t, 429, grpc.HTTPStatusFromGRPCCode(8))
+	assert.Equal(t, 401, grpc.HTTPStatusFromGRPCCode(16))
+	assert.Equal(t, 500, grpc.HTTPStatusFromGRPCCode(100))
+}
+
+// TestBuildRequestMessageJSON tests building request messages from parameters and body.
+func TestBuildRequestMessageJSON(t *testing.T) {
+	pathParams := map[string]string{"id": "1", "user.name": "alice"}
+	queryParams := map[string]string{"page": "2", "count": "3"}
+	body := []byte(`{"count": 10, "user": {"age": 20}}`)
+	messageJSON, err := grpc.BuildRequestMessageJSON(pathParams, queryParams, body)
+	assert.NoError(t, err)
+
+	var message map[string]any
+	assert.NoError(t, sonic.Unmarshal(messageJSON, &message))
+	assert.Equal(t, "1", message["id"])
+	assert.Equal(t, "2", message["page"])
+	// Fields set in body take precedence.
+	assert.EqualValues(t, 10, message["count"])
+	user := message["user"].(map[string]any)
+	assert.Equal(t, "alice", user["name"])
+	assert.EqualValues(t, 20, user["age"])
+
+	// Empty body
+	messageJSON, err = grpc.BuildRequestMessageJSON(nil, map[string]string{"q": "x"}, nil)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"q": "x"}`, string(messageJSON))
+
+	// Body not an object
+	_, err = grpc.BuildRequestMessageJSON(nil, nil, []byte(`[1, 2]`))
+	assert.Error(t, err)
+}
+
+// newItemServiceFiles returns the registry of a proto file defining `test.v1.ItemService`, whose method `Echo` takes and returns `test.v1.Item`.
+func newItemServiceFiles(t *testing.T) *protoregistry.Files {
+	fileDesc := &descriptorpb.FileDescriptorProto{
+		Name:    proto.String("item.proto"),
+		Package: proto.String("test.v1"),
+		Syntax:  proto.String("proto3"),
+		MessageType: []*descriptorpb.DescriptorProto{{
+			Name: proto.String("Item"),
+			Field: []*descriptorpb.FieldDescriptorProto{
+				{Name: proto.String("name"), JsonName: proto.String("name"), Number: proto.Int32(1), Label: descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(), Type: descriptorpb.FieldDescriptorProto_TYPE_STRING.Enum()},
+				{Name: proto.String("count"), JsonName: proto.String("count"), Number: proto.Int32(2), Label: descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(), Type: descriptorpb.FieldDescriptorProto_TYPE_INT32.Enum()},
+				{Name: proto.String("enabled"), JsonName: proto.String("enabled"), Number: proto.Int32(3), Label: descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(), Type: descriptorpb.FieldDescriptorProto_TYPE_BOOL.Enum()},
+			},
+		}},
+		Service: []*descriptorpb.ServiceDescriptorProto{{
+			Name: proto.String("ItemService"),
+			Method: []*descriptorpb.MethodDescriptorProto{
+				{Name: proto.String("Echo"), InputType: proto.String(".test.v1.Item"), OutputType: proto.String(".test.v1.Item")},
+			},
+		}},
+	}
+	files, err := protodesc.NewFiles(&descriptorpb.FileDescriptorSet{File: []*descriptorpb.FileDescriptorProto{fileDesc}})
+	assert.NoError(t, err)
+	return files
+}
+
+// startFakeGRPCEchoServer starts an h2c server on localhost, which echoes the request message of any unary call, and the `X-Echo` metadata as a trailer.
+func startFakeGRPCEchoServer(t *testing.T) *httptest.Server {
+	server := httptest.NewUnstartedServer(nethttp.HandlerFunc(func(w nethttp.ResponseWriter, r *nethttp.Request) {
+		frame, _ := io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "application/grpc")
+		w.Header().Set("Trailer", "Grpc-Status, Grpc-Message, X-Echo")
+		w.Write(frame)
+		w.Header().Set("Grpc-Status", "0")
+		w.Header().Set("Grpc-Message", "")
+		w.Header().Set("X-Echo", r.Header.Get("X-Echo"))
+	}))
+	server.Config.Protocols = new(nethttp.Protocols)
+	server.Config.Protocols.SetUnencryptedHTTP2(true)
+	server.Start()
+	t.Cleanup(server.Close)
+	return server
+}
+
+// echoHeaderMiddleware sets the `X-Echo` request header, and tags the response body with it, so that tests can tell middlewares are applied.
+type echoHeaderMiddleware struct {
+	value string
+}
+
+func (m *echoHeaderMiddleware) HandleRequest(path, method string, headers map[string]string, pathParams, queryParams map[string]string, body []byte) (string, string, map[string]string, map[string]string, map[string]string, []byte, error) {
+	headers["X-Echo"] = m.value
+	return path, method, headers, pathParams, queryParams, body, nil
+}
+
+func (m *echoHeaderMiddleware) HandleResponse(path, method string, statusCode int, headers map[string]string, body []byte) (int, map[string]string, []byte, error) {
+	return statusCode, headers, append(body, []byte(" "+path)...), nil
+}
+
+// TestGRPCClientPerformRequest tests that gRPC calls convert fuzzed bodies leniently, go through middlewares, and are captured.
+func TestGRPCClientPerformRequest(t *testing.T) {
+	server := startFakeGRPCEchoServer(t)
+	client, err := grpc.NewGRPCClient(server.URL, newItemServiceFiles(t), []string{"X-Echo"}, http.TransportConfig{}, time.Second)
+	assert.NoError(t, err)
+	client.Middlewares = []http.HTTPClientMiddleware{&echoHeaderMiddleware{value: "mw"}}
+	client.CaptureBuffer = http.NewHTTPCaptureBuffer(10)
+	client.HARRecorder = http.NewHARRecorder(10)
+
+	tests := []struct {
+		name     string
+		body     string
+		wantBody string
+	}{
+		{"valid body", `{"name": "a", "count": 1}`, `{"name":"a","count":1}`},
+		{"unknown field discarded", `{"name": "a", "extra": true}`, `{"name":"a"}`},
+		{"string value parsed as JSON", `{"count": "42", "enabled": "true"}`, `{"count":42,"enabled":true}`},
+		{"rejected field dropped", `{"name": "a", "count": "many", "enabled": 1}`, `{"name":"a"}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			statusCode, headers, body, err := client.PerformRequest(context.Background(), "ItemService", "Echo", map[string]string{}, []byte(tt.body))
+			assert.NoError(t, err)
+			assert.Equal(t, nethttp.StatusOK, statusCode)
+			assert.Equal(t, "mw", headers["X-Echo"])
+			assert.Equal(t, tt.wantBody+" /test.v1.ItemService/Echo", string(body))
+		})
+	}
+
+	// Body not an object
+	_, _, _, err = client.PerformRequest(context.Background(), "ItemService", "Echo", map[string]string{}, []byte(`[1]`))
+	assert.Error(t, err)
+
+	captures := client.CaptureBuffer.GetAll()
+	assert.Len(t, captures, len(tests))
+	assert.Equal(t, nethttp.MethodPost, captures[0].Method)
+	assert.Equal(t, server.URL+"/test.v1.ItemService/Echo", captures[0].URL)
+	assert.Equal(t, "mw", captures[0].RequestHeaders["X-Echo"])
+	assert.Equal(t, nethttp.StatusOK, captures[0].ResponseStatusCode)
+	assert.Equal(t, len(tests), client.HARRecorder.GetEntryCount())
+}
+
+// TestAPIManagerGRPCOperationTyping tests that operations tagged `APIType_gRPC` are of type gRPC only if descriptors are set.
+func TestAPIManagerGRPCOperationTyping(t *testing.T) {
+	if config.GlobalConfig == nil {
+		config.InitConfig()
+	}
+	newDoc := func() *openapi3.T {
+		return &openapi3.T{
+			Paths: openapi3.NewPaths(
+				openapi3.WithPath("/test.v1.ItemService/Echo", &openapi3.PathItem{
+					Post: &openapi3.Operation{OperationID: "ItemService_Echo", Tags: []string{"APIType_gRPC"}, Responses: openapi3.NewResponses()},
+				}),
+				openapi3.WithPath("/items", &openapi3.PathItem{
+					Post: &openapi3.Operation{OperationID: "createItem", Responses: openapi3.NewResponses()},
+				}),
+			),
+		}
+	}
+	grpcMethod := static.SimpleAPIMethod{Method: "POST", Endpoint: "/test.v1.ItemService/Echo", Typ: static.SimpleAPIMethodTypeGRPC}
+	httpMethod := static.SimpleAPIMethod{Method: "POST", Endpoint: "/test.v1.ItemService/Echo", Typ: static.SimpleAPIMethodTypeHTTP}
+
+	tests := []struct {
+		name           string
+		setDescriptors bool
+		want           static.SimpleAPIMethod
+	}{
+		{"without descriptors", false, httpMethod},
+		{"with descriptors", true, grpcMethod},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			apiManager := static.NewAPIManager()
+			if tt.setDescriptors {
+				apiManager.SetGRPCDescriptors(newItemServiceFiles(t))
+			}
+			apiManager.InitFromDocs(newDoc(), &openapi3.T{Paths: openapi3.NewPaths()})
+			_, exists := apiManager.APIMap[tt.want]
+			assert.True(t, exists)
+			assert.Equal(t, tt.want, apiManager.ResolveSystemAPIMethod("POST", "/test.v1.ItemService/Echo"))
+			// Plain HTTP operations are not affected.
+			assert.Equal(t, static.SimpleAPIMethod{Method: "POST", Endpoint: "/items", Typ: static.SimpleAPIMethodTypeHTTP}, apiManager.ResolveSystemAPIMethod("POST", "/items"))
+		})
+	}
+}
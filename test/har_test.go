@@ -0,0 +1,113 @@
+package test
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"resttracefuzzer/pkg/utils/http"
+
+	"github.com/bytedance/sonic"
+	"github.com/stretchr/testify/assert"
+)
+
+// harFileForTest is the part of a HAR file checked by tests.
+type harFileForTest struct {
+	Log struct {
+		Entries []struct {
+			Request struct {
+				URL     string `json:"url"`
+				Headers []struct {
+					Name  string `json:"name"`
+					Value string `json:"value"`
+				} `json:"headers"`
+			} `json:"request"`
+			Response struct {
+				Headers []struct {
+					Name  string `json:"name"`
+					Value string `json:"value"`
+				} `json:"headers"`
+			} `json:"response"`
+		} `json:"entries"`
+	} `json:"log"`
+}
+
+// recordHARForTest records requests to the given paths, and returns the written HAR file.
+func recordHARForTest(t *testing.T, recorder *http.HARRecorder, paths []string) harFileForTest {
+	for _, path := range paths {
+		capture := &http.HTTPCapture{
+			Time:               time.Now(),
+			Method:             "GET",
+			URL:                "http://example.com" + path,
+			RequestHeaders:     map[string]string{"Authorization": "Bearer secret", "Accept": "application/json"},
+			ResponseStatusCode: 200,
+		}
+		recorder.Record(capture, nil, map[string]string{"Set-Cookie": "session=secret", "Content-Type": "application/json"}, []byte(`{}`))
+	}
+	harPath := filepath.Join(t.TempDir(), "requests.har")
+	assert.NoError(t, recorder.WriteHARFile(harPath))
+	harBytes, err := os.ReadFile(harPath)
+	assert.NoError(t, err)
+	var har harFileForTest
+	assert.NoError(t, sonic.Unmarshal(harBytes, &har))
+	return har
+}
+
+// TestHARRecorderKeepsMostRecentEntries tests that the recorder keeps at most the given number of entries, from the oldest to the newest.
+func TestHARRecorderKeepsMostRecentEntries(t *testing.T) {
+	tests := []struct {
+		name         string
+		maxEntries   int
+		requestCount int
+		wantPaths    []string
+	}{
+		{"below capacity", 3, 2, []string{"/0", "/1"}},
+		{"at capacity", 3, 3, []string{"/0", "/1", "/2"}},
+		{"over capacity", 3, 5, []string{"/2", "/3", "/4"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := http.NewHARRecorder(tt.maxEntries)
+			paths := make([]string, 0, tt.requestCount)
+			for i := range tt.requestCount {
+				paths = append(paths, "/"+strconv.Itoa(i))
+			}
+			har := recordHARForTest(t, recorder, paths)
+			assert.Equal(t, len(tt.wantPaths), recorder.GetEntryCount())
+			gotPaths := make([]string, 0, len(har.Log.Entries))
+			for _, entry := range har.Log.Entries {
+				gotPaths = append(gotPaths, entry.Request.URL[len("http://example.com"):])
+			}
+			assert.Equal(t, tt.wantPaths, gotPaths)
+		})
+	}
+}
+
+// TestHARRecorderMasksCredentialHeaders tests that values of credential headers are masked in the HAR file, and other headers are kept.
+func TestHARRecorderMasksCredentialHeaders(t *testing.T) {
+	har := recordHARForTest(t, http.NewHARRecorder(http.MaxHAREntries), []string{"/pets"})
+	if !assert.Len(t, har.Log.Entries, 1) {
+		return
+	}
+	requestHeaders := make(map[string]string)
+	for _, header := range har.Log.Entries[0].Request.Headers {
+		requestHeaders[header.Name] = header.Value
+	}
+	assert.Equal(t, map[string]string{"Authorization": http.MaskedHeaderValue, "Accept": "application/json"}, requestHeaders)
+	responseHeaders := make(map[string]string)
+	for _, header := range har.Log.Entries[0].Response.Headers {
+		responseHeaders[header.Name] = header.Value
+	}
+	assert.Equal(t, map[string]string{"Set-Cookie": http.MaskedHeaderValue, "Content-Type": "application/json"}, responseHeaders)
+}
+
+// TestHARRecorderNil tests that a nil recorder, e.g., of non-positive max entries, ignores all requests.
+func TestHARRecorderNil(t *testing.T) {
+	recorder := http.NewHARRecorder(0)
+	assert.Nil(t, recorder)
+	recorder.Record(&http.HTTPCapture{}, nil, nil, nil)
+	assert.Equal(t, 0, recorder.GetEntryCount())
+	assert.NoError(t, recorder.WriteHARFile(filepath.Join(t.TempDir(), "requests.har")))
+}
@@ -0,0 +1,39 @@
+package test
+
+import (
+	"resttracefuzzer/pkg/utils/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHTTPCaptureBuffer(t *testing.T) {
+	// Disabled buffer ignores captures
+	disabled := http.NewHTTPCaptureBuffer(0)
+	disabled.Add(&http.HTTPCapture{Method: "GET"})
+	assert.Empty(t, disabled.GetAll())
+
+	buffer := http.NewHTTPCaptureBuffer(2)
+	assert.Empty(t, buffer.GetAll())
+
+	buffer.Add(&http.HTTPCapture{URL: "/a"})
+	buffer.Add(&http.HTTPCapture{URL: "/b"})
+	buffer.Add(&http.HTTPCapture{URL: "/c"})
+
+	// Oldest capture is overwritten, and captures are returned from the oldest to the newest
+	captures := buffer.GetAll()
+	assert.Len(t, captures, 2)
+	assert.Equal(t, "/b", captures[0].URL)
+	assert.Equal(t, "/c", captures[1].URL)
+}
+
+// TestNewHTTPCaptureMasksCredentials tests that values of credential headers are masked in captures, and headers of the caller are not changed.
+func TestNewHTTPCaptureMasksCredentials(t *testing.T) {
+	headers := map[string]string{"Authorization": "Bearer secret", "x-api-key": "key", "Accept": "application/json"}
+	capture := http.NewHTTPCapture("GET", "http://localhost/pets", headers, map[string]string{"limit": "1"}, nil)
+	assert.Equal(t, map[string]string{"Authorization": http.MaskedHeaderValue, "x-api-key": http.MaskedHeaderValue, "Accept": "application/json"}, capture.RequestHeaders)
+	assert.Equal(t, "Bearer secret", headers["Authorization"])
+
+	capture.SetResponse(200, map[string]string{"Set-Cookie": "session=abc", "Content-Type": "application/json"}, nil)
+	assert.Equal(t, map[string]string{"Set-Cookie": http.MaskedHeaderValue, "Content-Type": "application/json"}, capture.ResponseHeaders)
+}
@@ -0,0 +1,67 @@
+package test
+
+import (
+	"context"
+	"maps"
+	nethttp "net/http"
+	"net/http/httptest"
+	"testing"
+
+	"resttracefuzzer/pkg/utils/http"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// newCookieServer starts a server setting cookies at `/login` (including one scoped to `/admin` and an invalid one),
+// and echoing the Cookie header received at any other path.
+func newCookieServer(t *testing.T) *httptest.Server {
+	server := httptest.NewServer(nethttp.HandlerFunc(func(w nethttp.ResponseWriter, r *nethttp.Request) {
+		if r.URL.Path == "/login" {
+			w.Header().Add("Set-Cookie", "session=abc; Path=/")
+			w.Header().Add("Set-Cookie", "role=admin; Path=/admin")
+			w.Header().Add("Set-Cookie", "=invalid")
+			return
+		}
+		w.Write([]byte(r.Header.Get(http.CookieHeaderKey)))
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+// TestHTTPClientCookieJar tests that cookies set by responses are sent with later requests of contexts sharing the cookie jar,
+// by their paths, where cookies in headers take precedence over those of the jar.
+func TestHTTPClientCookieJar(t *testing.T) {
+	server := newCookieServer(t)
+	client := http.NewHTTPClient(server.URL, []string{}, http.EmptyHTTPClientMiddlewareSlice())
+
+	tests := []struct {
+		name       string
+		getContext func(jarCtx context.Context) context.Context
+		path       string
+		headers    map[string]string
+		want       string
+	}{
+		{"jar", func(jarCtx context.Context) context.Context { return jarCtx }, "/orders", map[string]string{}, "session=abc"},
+		{"jar of path", func(jarCtx context.Context) context.Context { return jarCtx }, "/admin/users", map[string]string{}, "role=admin; session=abc"},
+		{"jar with header cookies", func(jarCtx context.Context) context.Context { return jarCtx }, "/orders", map[string]string{"Cookie": "theme=dark"}, "theme=dark; session=abc"},
+		{"header cookie of same name", func(jarCtx context.Context) context.Context { return jarCtx }, "/orders", map[string]string{"cookie": "session=mine"}, "session=mine"},
+		{"derived context", func(jarCtx context.Context) context.Context { return context.WithValue(jarCtx, struct{}{}, "derived") }, "/orders", map[string]string{}, "session=abc"},
+		{"new jar", func(jarCtx context.Context) context.Context { return http.ContextWithCookieJar(jarCtx) }, "/orders", map[string]string{}, ""},
+		{"without jar", func(jarCtx context.Context) context.Context { return http.ContextWithoutCookieJar(jarCtx) }, "/orders", map[string]string{}, ""},
+		{"no jar", func(jarCtx context.Context) context.Context { return context.Background() }, "/orders", map[string]string{"Cookie": "theme=dark"}, "theme=dark"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			jarCtx := http.ContextWithCookieJar(context.Background())
+			_, _, _, err := client.PerformGet(jarCtx, "/login", map[string]string{}, nil, nil)
+			assert.NoError(t, err)
+
+			headers := maps.Clone(tt.headers)
+			_, _, respBody, err := client.PerformGet(tt.getContext(jarCtx), tt.path, headers, nil, nil)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, string(respBody))
+			// Headers of the caller are not changed.
+			assert.Equal(t, tt.headers, headers)
+		})
+	}
+}
@@ -0,0 +1,91 @@
+package test
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"testing"
+	"time"
+
+	"resttracefuzzer/pkg/utils/http"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// newTestRequestSigner creates a RequestSigner with credentials and time of the AWS SigV4 test suite.
+func newTestRequestSigner(t *testing.T, signingType string) *http.RequestSigner {
+	signer, err := http.NewRequestSigner(http.RequestSigningConfig{
+		Type:            signingType,
+		BaseURL:         "https://example.amazonaws.com",
+		AccessKeyID:     "AKIDEXAMPLE",
+		SecretAccessKey: "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY",
+		Region:          "us-east-1",
+		Service:         "service",
+		HMACHeader:      "X-Signature",
+	})
+	assert.NoError(t, err)
+	signer.Now = func() time.Time {
+		return time.Date(2015, 8, 30, 12, 36, 0, 0, time.UTC)
+	}
+	return signer
+}
+
+// TestNewRequestSigner tests validating request signing config.
+func TestNewRequestSigner(t *testing.T) {
+	_, err := http.NewRequestSigner(http.RequestSigningConfig{Type: "unknown", AccessKeyID: "a", SecretAccessKey: "b"})
+	assert.Error(t, err)
+	_, err = http.NewRequestSigner(http.RequestSigningConfig{Type: http.RequestSigningTypeSigV4, AccessKeyID: "a", SecretAccessKey: "b"})
+	assert.Error(t, err)
+	_, err = http.NewRequestSigner(http.RequestSigningConfig{Type: http.RequestSigningTypeHMAC, HMACHeader: "X-Signature"})
+	assert.Error(t, err)
+}
+
+// TestRequestSignerSigV4 tests signing requests by SigV4, with cases from the AWS SigV4 test suite.
+func TestRequestSignerSigV4(t *testing.T) {
+	signer := newTestRequestSigner(t, http.RequestSigningTypeSigV4)
+
+	// get-vanilla
+	headers := map[string]string{}
+	_, _, headers, _, _, _, err := signer.HandleRequest("/", "GET", headers, map[string]string{}, map[string]string{}, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "20150830T123600Z", headers["X-Amz-Date"])
+	assert.Equal(t, "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/service/aws4_request, SignedHeaders=host;x-amz-date, Signature=5fa00fa31553b73ebf1942676e86291e8372ff2a2260956d9b8aae1d763fbf31", headers["Authorization"])
+
+	// get-vanilla-query-order-key-case
+	headers = map[string]string{}
+	_, _, headers, _, _, _, err = signer.HandleRequest("/", "GET", headers, map[string]string{}, map[string]string{"Param2": "value2", "Param1": "value1"}, nil)
+	assert.NoError(t, err)
+	assert.Contains(t, headers["Authorization"], "Signature=b97d918cfa904a5beff61c982a1b6f458b799221646efd99d3219ec94cdf2500")
+}
+
+// TestRequestSignerHMAC tests signing requests by HMAC.
+func TestRequestSignerHMAC(t *testing.T) {
+	signer := newTestRequestSigner(t, http.RequestSigningTypeHMAC)
+	headers := map[string]string{}
+	body := []byte(`{"name":"a"}`)
+	_, _, headers, _, _, _, err := signer.HandleRequest("/users/{id}", "PUT", headers, map[string]string{"id": "a b"}, map[string]string{"b": "2", "a": "1"}, body)
+	assert.NoError(t, err)
+	assert.Equal(t, "1440938160", headers[http.HMACTimestampHeaderKey])
+	assert.Equal(t, "AKIDEXAMPLE", headers[http.HMACKeyIDHeaderKey])
+
+	bodyHash := sha256.Sum256(body)
+	mac := hmac.New(sha256.New, []byte("wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY"))
+	mac.Write([]byte("PUT\n/users/a%20b?a=1&b=2\n1440938160\n" + hex.EncodeToString(bodyHash[:])))
+	assert.Equal(t, hex.EncodeToString(mac.Sum(nil)), headers["X-Signature"])
+}
+
+// TestRequestSignerKeepsCallerHeaders tests that signatures and credentials are set in headers of the request, but not in those of the caller.
+func TestRequestSignerKeepsCallerHeaders(t *testing.T) {
+	for _, signingType := range []string{http.RequestSigningTypeSigV4, http.RequestSigningTypeHMAC} {
+		t.Run(signingType, func(t *testing.T) {
+			signer := newTestRequestSigner(t, signingType)
+			signer.Config.SessionToken = "session-token"
+			callerHeaders := map[string]string{"X-Request-Id": "1"}
+			_, _, headers, _, _, _, err := signer.HandleRequest("/", "GET", callerHeaders, map[string]string{}, map[string]string{}, nil)
+			assert.NoError(t, err)
+			assert.Greater(t, len(headers), 1)
+			assert.Equal(t, "1", headers["X-Request-Id"])
+			assert.Equal(t, map[string]string{"X-Request-Id": "1"}, callerHeaders)
+		})
+	}
+}
@@ -1,11 +1,21 @@
 package test
 
 import (
+	"context"
+	"fmt"
+	"net"
+	nethttp "net/http"
+	"net/http/httptest"
+	"os"
+	"sync/atomic"
+	"syscall"
 	"testing"
+	"time"
 
 	"resttracefuzzer/pkg/utils/http"
 
 	"github.com/bytedance/sonic"
+	errs "github.com/cloudwego/hertz/pkg/common/errors"
 	"github.com/cloudwego/hertz/pkg/protocol/consts"
 	"github.com/stretchr/testify/assert"
 )
@@ -35,7 +45,7 @@ func TestPerformRequest(t *testing.T) {
 	bodyBytes, err := sonic.Marshal(body)
 	assert.NoError(t, err)
 
-	statusCode, headers, respBody, err := client.PerformRequest("/test", "POST", headers, pathParams, queryParams, bodyBytes)
+	statusCode, headers, respBody, err := client.PerformRequest(context.Background(), "/test", "POST", headers, pathParams, queryParams, bodyBytes)
 	assert.NoError(t, err)
 	assert.Equal(t, consts.StatusOK, statusCode)
 	assert.NotNil(t, headers)
@@ -56,7 +66,7 @@ func TestPerformHTTPSRequest(t *testing.T) {
 	bodyBytes, err := sonic.Marshal(body)
 	assert.NoError(t, err)
 
-	statusCode, headers, respBody, err := client.PerformRequest("/test", "POST", headers, pathParams, queryParams, bodyBytes)
+	statusCode, headers, respBody, err := client.PerformRequest(context.Background(), "/test", "POST", headers, pathParams, queryParams, bodyBytes)
 	assert.NoError(t, err)
 	assert.Equal(t, consts.StatusOK, statusCode)
 	assert.NotNil(t, headers)
@@ -76,7 +86,7 @@ func TestPerformRequestWithRetry(t *testing.T) {
 	bodyBytes, err := sonic.Marshal(body)
 	assert.NoError(t, err)
 
-	statusCode, headers, respBody, err := client.PerformRequestWithRetry("/test", "POST", headers, pathParams, queryParams, bodyBytes, 3)
+	statusCode, headers, respBody, err := client.PerformRequestWithRetry(context.Background(), "/test", "POST", headers, pathParams, queryParams, bodyBytes, 3)
 	assert.NoError(t, err)
 	assert.Equal(t, consts.StatusOK, statusCode)
 	assert.NotNil(t, headers)
@@ -92,7 +102,7 @@ func TestPerformGet(t *testing.T) {
 	pathParams := map[string]string{}
 	queryParams := map[string]string{}
 
-	statusCode, headers, respBody, err := client.PerformGet("/test", headers, pathParams, queryParams)
+	statusCode, headers, respBody, err := client.PerformGet(context.Background(), "/test", headers, pathParams, queryParams)
 	assert.NoError(t, err)
 	assert.Equal(t, consts.StatusOK, statusCode)
 	assert.NotNil(t, headers)
@@ -114,3 +124,176 @@ func TestIsStatusCodeSuccess(t *testing.T) {
 	assert.True(t, http.IsStatusCodeSuccess(consts.StatusOK))
 	assert.False(t, http.IsStatusCodeSuccess(consts.StatusBadRequest))
 }
+
+// TestIsTimeoutError tests the classification of timeouts among errors of requests.
+func TestIsTimeoutError(t *testing.T) {
+	assert.True(t, http.IsTimeoutError(context.DeadlineExceeded))
+	assert.True(t, http.IsTimeoutError(fmt.Errorf("read response: %w", errs.ErrTimeout)))
+	assert.True(t, http.IsTimeoutError(&net.OpError{Op: "dial", Err: os.ErrDeadlineExceeded}))
+
+	assert.False(t, http.IsTimeoutError(nil))
+	assert.False(t, http.IsTimeoutError(&net.OpError{Op: "dial", Err: syscall.ECONNREFUSED}))
+	assert.False(t, http.IsTimeoutError(errs.ErrConnectionClosed))
+}
+
+// TestHTTPClientPolitenessGetBackoff tests the backoff on 429 and 503 responses.
+func TestHTTPClientPolitenessGetBackoff(t *testing.T) {
+	politeness := http.NewHTTPClientPoliteness(http.PolitenessConfig{
+		BackoffMaxRetries: 2,
+		BackoffMaxWait:    10 * time.Second,
+	})
+
+	// 429 without Retry-After backs off exponentially
+	wait, shouldRetry := politeness.GetBackoff(consts.StatusTooManyRequests, "", 0)
+	assert.True(t, shouldRetry)
+	assert.Equal(t, time.Second, wait)
+	wait, shouldRetry = politeness.GetBackoff(consts.StatusTooManyRequests, "", 1)
+	assert.True(t, shouldRetry)
+	assert.Equal(t, 2*time.Second, wait)
+
+	// Retry-After is respected, and capped by max wait
+	wait, shouldRetry = politeness.GetBackoff(consts.StatusTooManyRequests, "5", 0)
+	assert.True(t, shouldRetry)
+	assert.Equal(t, 5*time.Second, wait)
+	wait, shouldRetry = politeness.GetBackoff(consts.StatusServiceUnavailable, "120", 0)
+	assert.True(t, shouldRetry)
+	assert.Equal(t, 10*time.Second, wait)
+	wait, shouldRetry = politeness.GetBackoff(consts.StatusServiceUnavailable, "Wed, 21 Oct 2015 07:28:00 GMT", 0)
+	assert.True(t, shouldRetry)
+	assert.Equal(t, time.Duration(0), wait)
+
+	// 503 without Retry-After, other status codes, and exhausted retries are not retried
+	_, shouldRetry = politeness.GetBackoff(consts.StatusServiceUnavailable, "", 0)
+	assert.False(t, shouldRetry)
+	_, shouldRetry = politeness.GetBackoff(consts.StatusInternalServerError, "5", 0)
+	assert.False(t, shouldRetry)
+	_, shouldRetry = politeness.GetBackoff(consts.StatusTooManyRequests, "", 2)
+	assert.False(t, shouldRetry)
+
+	// nil politeness never retries
+	var nilPoliteness *http.HTTPClientPoliteness
+	_, shouldRetry = nilPoliteness.GetBackoff(consts.StatusTooManyRequests, "", 0)
+	assert.False(t, shouldRetry)
+}
+
+// TestContextWithResponseTime tests that the response time is of the last attempt of a request retried after backoff.
+func TestContextWithResponseTime(t *testing.T) {
+	var requestCount atomic.Int32
+	server := httptest.NewServer(nethttp.HandlerFunc(func(w nethttp.ResponseWriter, r *nethttp.Request) {
+		if requestCount.Add(1) == 1 {
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(nethttp.StatusTooManyRequests)
+			return
+		}
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+	client := http.NewHTTPClient(server.URL, []string{}, http.EmptyHTTPClientMiddlewareSlice())
+	client.Politeness = http.NewHTTPClientPoliteness(http.PolitenessConfig{
+		BackoffMaxRetries: 1,
+		BackoffMaxWait:    10 * time.Second,
+	})
+
+	ctx, responseTime := http.ContextWithResponseTime(context.Background())
+	start := time.Now()
+	statusCode, _, _, err := client.PerformGet(ctx, "/", map[string]string{}, nil, nil)
+	elapsed := time.Since(start)
+	assert.NoError(t, err)
+	assert.Equal(t, consts.StatusOK, statusCode)
+	assert.EqualValues(t, 2, requestCount.Load())
+	assert.GreaterOrEqual(t, elapsed, time.Second)
+	assert.Greater(t, *responseTime, time.Duration(0))
+	assert.Less(t, *responseTime, elapsed-time.Second)
+
+	// Contexts without response time are not affected.
+	http.RecordResponseTime(context.Background(), time.Second)
+}
+
+// TestInjectTraceContext tests that injected trace context replaces recorded headers, and B3 headers carry the same trace.
+func TestInjectTraceContext(t *testing.T) {
+	headers := map[string]string{
+		"Traceparent":  "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01",
+		"tracestate":   "vendor=value",
+		"X-B3-TraceId": "463ac35c9f6413ad",
+		"X-B3-SpanId":  "a2fb4a1d1a96d312",
+		"Accept":       "application/json",
+	}
+	ctx, clientTraceID := http.ContextWithClientTraceID(context.Background())
+	injectedHeaders, traceID := http.InjectTraceContext(ctx, headers)
+	assert.Len(t, traceID, 32)
+	assert.Equal(t, traceID, *clientTraceID)
+	assert.Regexp(t, "^00-"+traceID+"-[0-9a-f]{16}-01$", injectedHeaders[http.TraceparentHeaderKey])
+	assert.NotContains(t, injectedHeaders, "Traceparent")
+	assert.NotContains(t, injectedHeaders, "tracestate")
+	assert.Equal(t, traceID, injectedHeaders["X-B3-TraceId"])
+	assert.Equal(t, injectedHeaders[http.TraceparentHeaderKey][36:52], injectedHeaders["X-B3-SpanId"])
+	assert.Equal(t, "application/json", injectedHeaders["Accept"])
+	// Headers of the caller are not changed.
+	assert.Equal(t, "463ac35c9f6413ad", headers["X-B3-TraceId"])
+}
+
+// TestHTTPClientInjectTraceContext tests that each attempt of a request starts a new trace, and the trace of the last attempt is recorded.
+func TestHTTPClientInjectTraceContext(t *testing.T) {
+	receivedTraceparents := make(chan string, 4)
+	var requestCount atomic.Int32
+	server := httptest.NewServer(nethttp.HandlerFunc(func(w nethttp.ResponseWriter, r *nethttp.Request) {
+		receivedTraceparents <- r.Header.Get(http.TraceparentHeaderKey)
+		if requestCount.Add(1) == 1 {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(nethttp.StatusTooManyRequests)
+			return
+		}
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+	client := http.NewHTTPClient(server.URL, []string{}, http.EmptyHTTPClientMiddlewareSlice())
+	client.Politeness = http.NewHTTPClientPoliteness(http.PolitenessConfig{
+		BackoffMaxRetries: 1,
+		BackoffMaxWait:    time.Second,
+	})
+	client.InjectTraceContext = true
+
+	ctx, clientTraceID := http.ContextWithClientTraceID(context.Background())
+	statusCode, _, _, err := client.PerformGet(ctx, "/", map[string]string{}, nil, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, consts.StatusOK, statusCode)
+	firstTraceparent, lastTraceparent := <-receivedTraceparents, <-receivedTraceparents
+	assert.NotEmpty(t, firstTraceparent)
+	assert.NotEqual(t, firstTraceparent, lastTraceparent)
+	assert.Equal(t, "00-"+*clientTraceID, lastTraceparent[:35])
+
+	// Clients not injecting trace context send no traceparent.
+	client.InjectTraceContext = false
+	_, _, _, err = client.PerformGet(context.Background(), "/", map[string]string{}, nil, nil)
+	assert.NoError(t, err)
+	assert.Empty(t, <-receivedTraceparents)
+}
+
+// TestIsRouteSafePathParamValue tests checking whether path parameter values keep requests routed to their endpoints.
+func TestIsRouteSafePathParamValue(t *testing.T) {
+	for _, value := range []string{"1", "abc", "a-b_c.d", "hello world", "..a", "中文"} {
+		assert.True(t, http.IsRouteSafePathParamValue(value), value)
+	}
+	for _, value := range []string{"", ".", "..", "/", "a/b", "a\\b", "a?b", "a#b", "%2F", "a\nb"} {
+		assert.False(t, http.IsRouteSafePathParamValue(value), value)
+	}
+}
+
+// TestSanitizePathParamValue tests sanitizing path parameter values to route-safe ones.
+func TestSanitizePathParamValue(t *testing.T) {
+	assert.Equal(t, "abc", http.SanitizePathParamValue("abc"))
+	assert.Equal(t, "a_b", http.SanitizePathParamValue("a/b"))
+	assert.Equal(t, "__etc_passwd", http.SanitizePathParamValue("/\\etc/passwd"))
+	assert.Equal(t, "_2F", http.SanitizePathParamValue("%2F"))
+	assert.Equal(t, http.DefaultRouteSafePathParamValue, http.SanitizePathParamValue(""))
+	assert.Equal(t, http.DefaultRouteSafePathParamValue, http.SanitizePathParamValue(".."))
+	assert.True(t, http.IsRouteSafePathParamValue(http.SanitizePathParamValue("../a?b#c")))
+}
+
+// TestBuildRequestURL tests building request URLs from path and query params.
+func TestBuildRequestURL(t *testing.T) {
+	assert.Equal(t, "http://localhost:8080/users", http.BuildRequestURL("http://localhost:8080", "/users", nil, nil))
+	assert.Equal(t, "http://localhost:8080/users/1/posts/a%20b", http.BuildRequestURL("http://localhost:8080", "/users/{id}/posts/{postId}", map[string]string{"id": "1", "postId": "a b"}, nil))
+	assert.Equal(t, "http://localhost:8080/users?a=1&b=x%26y", http.BuildRequestURL("http://localhost:8080", "/users", nil, map[string]string{"b": "x&y", "a": "1"}))
+	assert.Equal(t, "http://localhost:8080/users/{id}", http.BuildRequestURL("http://localhost:8080", "/users/{id}", map[string]string{}, map[string]string{}))
+}
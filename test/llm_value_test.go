@@ -0,0 +1,144 @@
+package test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"resttracefuzzer/pkg/resource"
+	"resttracefuzzer/pkg/strategy"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/stretchr/testify/assert"
+)
+
+// mockLLMClient is an LLM client responding with a fixed completion or error, which counts the requests.
+type mockLLMClient struct {
+	// completion is the completion of each request.
+	completion string
+
+	// err is the error of each request, if not nil.
+	err error
+
+	// release blocks requests until it is closed, if not nil.
+	release chan struct{}
+
+	// requestCnt is the number of requests.
+	requestCnt atomic.Int32
+}
+
+// ChatCompletion implements [llm.LLMClient].
+func (c *mockLLMClient) ChatCompletion(ctx context.Context, prompt string) (string, error) {
+	c.requestCnt.Add(1)
+	if c.release != nil {
+		<-c.release
+	}
+	return c.completion, c.err
+}
+
+// getResourceValueJSONs returns the JSON strings of the resources.
+func getResourceValueJSONs(t *testing.T, resources []resource.Resource) []string {
+	valueJSONs := make([]string, 0, len(resources))
+	for _, r := range resources {
+		valueJSON, err := json.Marshal(r.ToJSONObject())
+		assert.NoError(t, err)
+		valueJSONs = append(valueJSONs, string(valueJSON))
+	}
+	return valueJSONs
+}
+
+// TestLLMValueGeneratorGenerateValues tests parsing values from completions of the LLM, which are requested once for each name and schema.
+func TestLLMValueGeneratorGenerateValues(t *testing.T) {
+	tests := []struct {
+		name       string
+		completion string
+		err        error
+		want       []string
+	}{
+		{"array", `["alice", "bob"]`, nil, []string{`"alice"`, `"bob"`}},
+		{"array in code block", "Here you are:\n```json\n[1, 2.5, null]\n```", nil, []string{`1`, `2.5`}},
+		{"no array", "I cannot help with that.", nil, nil},
+		{"invalid array", `["alice", ]`, nil, nil},
+		{"null values only", `[null]`, nil, nil},
+		{"error", "", errors.New("service unavailable"), nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := &mockLLMClient{completion: tt.completion, err: tt.err}
+			generator := strategy.NewLLMValueGenerator(client, time.Second, "")
+			schema := openapi3.NewStringSchema()
+
+			// Values are requested in the background, so none is returned at first.
+			assert.Nil(t, generator.GenerateValues("userName", schema))
+			generator.Wait()
+			for range 3 {
+				values := generator.GenerateValues("userName", schema)
+				generator.Wait()
+				if tt.want == nil {
+					assert.Nil(t, values)
+				} else {
+					assert.Equal(t, tt.want, getResourceValueJSONs(t, values))
+				}
+			}
+			assert.Equal(t, int32(1), client.requestCnt.Load())
+		})
+	}
+}
+
+// TestLLMValueGeneratorNonBlocking tests that value generation is not blocked by requests to the LLM in flight, and that they are bounded.
+func TestLLMValueGeneratorNonBlocking(t *testing.T) {
+	client := &mockLLMClient{completion: `["alice"]`, release: make(chan struct{})}
+	generator := strategy.NewLLMValueGenerator(client, time.Second, "")
+	schema := openapi3.NewStringSchema()
+
+	for _, name := range []string{"userName", "userName", "petName", "shopName"} {
+		assert.Nil(t, generator.GenerateValues(name, schema))
+	}
+	close(client.release)
+	generator.Wait()
+	assert.Equal(t, int32(strategy.LLMValueMaxConcurrentRequests), client.requestCnt.Load())
+	assert.Equal(t, []string{`"alice"`}, getResourceValueJSONs(t, generator.GenerateValues("userName", schema)))
+
+	// Values not requested for lack of request slots are requested by later calls.
+	assert.Nil(t, generator.GenerateValues("shopName", schema))
+	generator.Wait()
+	assert.Equal(t, []string{`"alice"`}, getResourceValueJSONs(t, generator.GenerateValues("shopName", schema)))
+}
+
+// TestLLMValueGeneratorConsecutiveFailures tests that the LLM is not requested any more after consecutive failures.
+func TestLLMValueGeneratorConsecutiveFailures(t *testing.T) {
+	client := &mockLLMClient{err: errors.New("service unavailable")}
+	generator := strategy.NewLLMValueGenerator(client, time.Second, "")
+	schema := openapi3.NewStringSchema()
+
+	for _, name := range []string{"a", "b", "c", "d", "e"} {
+		assert.Nil(t, generator.GenerateValues(name, schema))
+		generator.Wait()
+	}
+	assert.Equal(t, int32(strategy.LLMValueMaxConsecutiveFailures), client.requestCnt.Load())
+}
+
+// TestLLMValueGeneratorCacheFile tests that values are saved to the cache file, and loaded by later runs without requesting the LLM.
+func TestLLMValueGeneratorCacheFile(t *testing.T) {
+	cacheFilePath := filepath.Join(t.TempDir(), "llm", "llm_value_cache.json")
+	schema := openapi3.NewIntegerSchema()
+
+	client := &mockLLMClient{completion: `[18, 30]`}
+	generator := strategy.NewLLMValueGenerator(client, time.Second, cacheFilePath)
+	generator.GenerateValues("age", schema)
+	generator.Wait()
+	assert.FileExists(t, cacheFilePath)
+
+	laterClient := &mockLLMClient{completion: `[99]`}
+	laterGenerator := strategy.NewLLMValueGenerator(laterClient, time.Second, cacheFilePath)
+	assert.Equal(t, []string{`18`, `30`}, getResourceValueJSONs(t, laterGenerator.GenerateValues("age", schema)))
+	// Values are cached by name and schema.
+	assert.Nil(t, laterGenerator.GenerateValues("age", openapi3.NewStringSchema()))
+	laterGenerator.Wait()
+	assert.Equal(t, int32(1), client.requestCnt.Load())
+	assert.Equal(t, int32(1), laterClient.requestCnt.Load())
+}
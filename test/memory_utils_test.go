@@ -0,0 +1,24 @@
+package test
+
+import (
+	"resttracefuzzer/pkg/utils"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetProcessRSS(t *testing.T) {
+	rss, err := utils.GetProcessRSS()
+	assert.NoError(t, err)
+	assert.Greater(t, rss, uint64(0))
+
+	// RSS should grow after touching a large allocation
+	buf := make([]byte, 64<<20)
+	for i := range buf {
+		buf[i] = byte(i)
+	}
+	grown, err := utils.GetProcessRSS()
+	assert.NoError(t, err)
+	assert.Greater(t, grown, rss)
+	assert.NotZero(t, buf[len(buf)-1])
+}
@@ -0,0 +1,76 @@
+package test
+
+import (
+	"testing"
+
+	"resttracefuzzer/pkg/casemanager"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestMeshHeaderPropagatorRenewHeaders tests that renewed headers start another trace, but are of the same test scenario.
+func TestMeshHeaderPropagatorRenewHeaders(t *testing.T) {
+	propagator, err := casemanager.NewMeshHeaderPropagator(`["istio"]`)
+	assert.NoError(t, err)
+	testScenario := &casemanager.TestScenario{UUID: uuid.New()}
+	headers := propagator.BuildHeaders(testScenario, 0)
+	headers["Authorization"] = "Bearer token"
+	headers[casemanager.TraceparentHeaderKey] = "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01"
+
+	renewedHeaders := propagator.RenewHeaders(headers)
+	assert.NotEqual(t, headers[casemanager.B3TraceIDHeaderKey], renewedHeaders[casemanager.B3TraceIDHeaderKey])
+	assert.NotEqual(t, headers[casemanager.B3SpanIDHeaderKey], renewedHeaders[casemanager.B3SpanIDHeaderKey])
+	assert.NotEqual(t, headers[casemanager.RequestIDHeaderKey], renewedHeaders[casemanager.RequestIDHeaderKey])
+	assert.Equal(t, headers[casemanager.BaggageHeaderKey], renewedHeaders[casemanager.BaggageHeaderKey])
+	assert.Equal(t, "Bearer token", renewedHeaders["Authorization"])
+	// B3 and traceparent headers still start the same trace.
+	traceID, ok := casemanager.GetClientTraceID(renewedHeaders)
+	assert.True(t, ok)
+	assert.Equal(t, renewedHeaders[casemanager.B3TraceIDHeaderKey], traceID)
+
+	// Headers are not added if missing.
+	renewedHeaders = propagator.RenewHeaders(map[string]string{"Authorization": "Bearer token"})
+	assert.Equal(t, map[string]string{"Authorization": "Bearer token"}, renewedHeaders)
+}
+
+// TestNewMeshHeaderPropagatorUnknownPreset tests that unknown presets, e.g., the former tracecontext preset, are rejected.
+func TestNewMeshHeaderPropagatorUnknownPreset(t *testing.T) {
+	_, err := casemanager.NewMeshHeaderPropagator(`["tracecontext"]`)
+	assert.Error(t, err)
+}
+
+// TestGetClientTraceID tests getting the trace ID started by request headers.
+func TestGetClientTraceID(t *testing.T) {
+	tests := []struct {
+		name        string
+		headers     map[string]string
+		wantTraceID string
+		wantOK      bool
+	}{
+		{"no header", map[string]string{}, "", false},
+		{"traceparent", map[string]string{"traceparent": "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01"}, "0af7651916cd43dd8448eb211c80319c", true},
+		{"b3", map[string]string{"x-b3-traceid": "463ac35c9f6413ad"}, "463ac35c9f6413ad", true},
+		{
+			"traceparent preferred over b3",
+			map[string]string{"traceparent": "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01", "x-b3-traceid": "463ac35c9f6413ad"},
+			"0af7651916cd43dd8448eb211c80319c",
+			true,
+		},
+		{
+			"malformed traceparent falls back to b3",
+			map[string]string{"traceparent": "00-0af7-01", "x-b3-traceid": "463ac35c9f6413ad"},
+			"463ac35c9f6413ad",
+			true,
+		},
+		{"malformed traceparent", map[string]string{"traceparent": "invalid"}, "", false},
+		{"empty b3", map[string]string{"x-b3-traceid": ""}, "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			traceID, ok := casemanager.GetClientTraceID(tt.headers)
+			assert.Equal(t, tt.wantOK, ok)
+			assert.Equal(t, tt.wantTraceID, traceID)
+		})
+	}
+}
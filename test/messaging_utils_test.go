@@ -0,0 +1,22 @@
+package test
+
+import (
+	"regexp"
+	"testing"
+
+	"resttracefuzzer/pkg/utils/messaging"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestNewTraceParent tests the format of trace contexts propagated in message headers.
+func TestNewTraceParent(t *testing.T) {
+	traceParent, traceID := messaging.NewTraceParent()
+	assert.Regexp(t, regexp.MustCompile(`^00-[0-9a-f]{32}-[0-9a-f]{16}-01$`), traceParent)
+	assert.Len(t, traceID, 32)
+	assert.Contains(t, traceParent, traceID)
+
+	anotherTraceParent, anotherTraceID := messaging.NewTraceParent()
+	assert.NotEqual(t, traceParent, anotherTraceParent)
+	assert.NotEqual(t, traceID, anotherTraceID)
+}
@@ -0,0 +1,79 @@
+package test
+
+import (
+	"context"
+	nethttp "net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"resttracefuzzer/internal/config"
+	"resttracefuzzer/pkg/feedback"
+	"resttracefuzzer/pkg/static"
+	"resttracefuzzer/pkg/utils/http"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestMethodProberProbe tests that method override is probed by POST requests only if override headers are given.
+func TestMethodProberProbe(t *testing.T) {
+	if config.GlobalConfig == nil {
+		config.InitConfig()
+	}
+	var (
+		mu              sync.Mutex
+		receivedMethods []string
+	)
+	server := httptest.NewServer(nethttp.HandlerFunc(func(w nethttp.ResponseWriter, r *nethttp.Request) {
+		mu.Lock()
+		receivedMethods = append(receivedMethods, r.Method)
+		mu.Unlock()
+		switch {
+		case r.Method == nethttp.MethodOptions:
+			w.Header().Set(feedback.AllowHeaderKey, "GET, DELETE")
+		case r.Method == nethttp.MethodPost && r.Header.Get("X-HTTP-Method-Override") == nethttp.MethodGet:
+			// The override is honored.
+		case r.Method != nethttp.MethodGet:
+			w.WriteHeader(nethttp.StatusMethodNotAllowed)
+		}
+	}))
+	defer server.Close()
+	apiManager := static.NewAPIManager()
+	apiManager.InitFromDocs(newSingleGetOperationDoc("/items", "listItems"), &openapi3.T{Paths: openapi3.NewPaths()})
+
+	tests := []struct {
+		name            string
+		overrideHeaders string
+		wantMethods     []string
+		wantTypes       []feedback.MethodDiscrepancyType
+	}{
+		{
+			"no override headers",
+			"",
+			[]string{nethttp.MethodOptions},
+			[]feedback.MethodDiscrepancyType{feedback.MethodDiscrepancyTypeAllowedButUndocumented},
+		},
+		{
+			"override headers",
+			"X-HTTP-Method-Override, X-Method-Override",
+			[]string{nethttp.MethodOptions, nethttp.MethodPost, nethttp.MethodPost, nethttp.MethodPost},
+			[]feedback.MethodDiscrepancyType{feedback.MethodDiscrepancyTypeAllowedButUndocumented, feedback.MethodDiscrepancyTypeMethodOverrideHonored},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			receivedMethods = nil
+			httpClient := http.NewHTTPClient(server.URL, []string{feedback.AllowHeaderKey}, http.EmptyHTTPClientMiddlewareSlice())
+			prober := feedback.NewMethodProber(apiManager, tt.overrideHeaders, map[string]string{})
+			prober.Probe(context.Background(), httpClient)
+			assert.Equal(t, 1, prober.ProbedPathCount)
+			assert.Equal(t, tt.wantMethods, receivedMethods)
+			discrepancyTypes := make([]feedback.MethodDiscrepancyType, 0, len(prober.Discrepancies))
+			for _, discrepancy := range prober.Discrepancies {
+				discrepancyTypes = append(discrepancyTypes, discrepancy.Typ)
+			}
+			assert.Equal(t, tt.wantTypes, discrepancyTypes)
+		})
+	}
+}
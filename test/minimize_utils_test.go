@@ -0,0 +1,65 @@
+package test
+
+import (
+	"resttracefuzzer/pkg/utils"
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// containsAll returns a test function passing if the subsequence contains all the required items.
+func containsAll(required ...int) func([]int) bool {
+	return func(subsequence []int) bool {
+		for _, item := range required {
+			if !slices.Contains(subsequence, item) {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+func TestDeltaDebugMinimize(t *testing.T) {
+	testCases := []struct {
+		name     string
+		items    []int
+		required []int
+		expected []int
+	}{
+		{"NoDependency", []int{1, 2, 3, 4}, []int{}, []int{}},
+		{"SingleItem", []int{1, 2, 3, 4, 5, 6, 7, 8}, []int{6}, []int{6}},
+		{"TwoItemsApart", []int{1, 2, 3, 4, 5, 6, 7, 8}, []int{1, 7}, []int{1, 7}},
+		{"OrderPreserved", []int{5, 3, 9, 1, 7}, []int{9, 3, 7}, []int{3, 9, 7}},
+		{"AllRequired", []int{1, 2, 3}, []int{1, 2, 3}, []int{1, 2, 3}},
+		{"Empty", []int{}, []int{}, []int{}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			minimized, testCount := utils.DeltaDebugMinimize(tc.items, containsAll(tc.required...), 0)
+			assert.Equal(t, tc.expected, minimized)
+			// The worst case complexity of ddmin is n^2 + 3n tests.
+			assert.LessOrEqual(t, testCount, len(tc.items)*len(tc.items)+3*len(tc.items))
+		})
+	}
+}
+
+func TestDeltaDebugMinimize_Budget(t *testing.T) {
+	items := []int{1, 2, 3, 4, 5, 6, 7, 8}
+	testCount := 0
+	test := func(subsequence []int) bool {
+		testCount++
+		return containsAll(2, 5)(subsequence)
+	}
+
+	minimized, performed := utils.DeltaDebugMinimize(items, test, 3)
+	assert.Equal(t, 3, performed)
+	assert.Equal(t, 3, testCount)
+	// The result must still pass the test, though it may not be minimal.
+	assert.True(t, containsAll(2, 5)(minimized))
+	assert.LessOrEqual(t, len(minimized), len(items))
+
+	// The input is not modified.
+	assert.Equal(t, []int{1, 2, 3, 4, 5, 6, 7, 8}, items)
+}
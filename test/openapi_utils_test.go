@@ -0,0 +1,131 @@
+package test
+
+import (
+	"testing"
+
+	"resttracefuzzer/pkg/utils"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestGetParameterSchema tests getting the schema of parameters declaring `schema`, `content` or neither.
+func TestGetParameterSchema(t *testing.T) {
+	stringSchema := openapi3.NewStringSchema().NewRef()
+	objectSchema := openapi3.NewObjectSchema().NewRef()
+
+	param := openapi3.NewQueryParameter("name").WithSchema(stringSchema.Value)
+	assert.Equal(t, openapi3.TypeString, (*utils.GetParameterSchema(param).Value.Type)[0])
+
+	param = openapi3.NewQueryParameter("filter")
+	param.Content = openapi3.Content{
+		"application/json": openapi3.NewMediaType().WithSchemaRef(objectSchema),
+	}
+	assert.Same(t, objectSchema, utils.GetParameterSchema(param))
+
+	param = openapi3.NewQueryParameter("raw")
+	assert.Nil(t, utils.GetParameterSchema(param))
+	assert.Nil(t, utils.GetParameterSchema(nil))
+}
+
+// TestGetContentSchema tests choosing the schema of a content with multiple media types.
+func TestGetContentSchema(t *testing.T) {
+	jsonSchema := openapi3.NewObjectSchema().NewRef()
+	problemJSONSchema := openapi3.NewObjectSchema().NewRef()
+	textSchema := openapi3.NewStringSchema().NewRef()
+
+	content := openapi3.Content{
+		"text/plain":               openapi3.NewMediaType().WithSchemaRef(textSchema),
+		"application/problem+json": openapi3.NewMediaType().WithSchemaRef(problemJSONSchema),
+		"application/json":         openapi3.NewMediaType().WithSchemaRef(jsonSchema),
+	}
+	assert.Same(t, jsonSchema, utils.GetContentSchema(content))
+
+	delete(content, "application/json")
+	assert.Same(t, problemJSONSchema, utils.GetContentSchema(content))
+
+	// Media types without schema are skipped.
+	content["application/problem+json"] = openapi3.NewMediaType()
+	assert.Same(t, textSchema, utils.GetContentSchema(content))
+
+	assert.Nil(t, utils.GetContentSchema(openapi3.Content{"application/json": openapi3.NewMediaType()}))
+	assert.Nil(t, utils.GetRequestBodySchema(&openapi3.RequestBodyRef{Value: openapi3.NewRequestBody()}))
+}
+
+// TestGetResponseSchema tests getting the schema of responses by exact status code, status code class and default.
+func TestGetResponseSchema(t *testing.T) {
+	okSchema := openapi3.NewObjectSchema().NewRef()
+	clientErrorSchema := openapi3.NewObjectSchema().NewRef()
+	defaultSchema := openapi3.NewObjectSchema().NewRef()
+	newResponse := func(schema *openapi3.SchemaRef) *openapi3.Response {
+		return openapi3.NewResponse().WithContent(openapi3.Content{"application/json": openapi3.NewMediaType().WithSchemaRef(schema)})
+	}
+	operation := openapi3.NewOperation()
+	operation.Responses = openapi3.NewResponses(
+		openapi3.WithStatus(200, &openapi3.ResponseRef{Value: newResponse(okSchema)}),
+		openapi3.WithName("4XX", newResponse(clientErrorSchema)),
+		openapi3.WithName("default", newResponse(defaultSchema)),
+	)
+
+	assert.Same(t, okSchema, utils.GetResponseSchema(operation, 200))
+	assert.Same(t, clientErrorSchema, utils.GetResponseSchema(operation, 404))
+	assert.Same(t, defaultSchema, utils.GetResponseSchema(operation, 500))
+	assert.Nil(t, utils.GetResponseSchema(openapi3.NewOperation(), 200))
+	assert.Nil(t, utils.GetResponseSchema(nil, 200))
+}
+
+// TestGetOperationEntitySchemaRef tests getting the component schema ref of the entity from request bodies and success responses.
+func TestGetOperationEntitySchemaRef(t *testing.T) {
+	petSchema := &openapi3.SchemaRef{Ref: "#/components/schemas/Pet", Value: openapi3.NewObjectSchema()}
+	ownerSchema := &openapi3.SchemaRef{Ref: "#/components/schemas/Owner", Value: openapi3.NewObjectSchema()}
+	petsSchema := openapi3.NewArraySchema().NewRef()
+	petsSchema.Value.Items = petSchema
+	newContent := func(schema *openapi3.SchemaRef) openapi3.Content {
+		return openapi3.Content{"application/json": openapi3.NewMediaType().WithSchemaRef(schema)}
+	}
+
+	// Entities of array responses are their items.
+	operation := openapi3.NewOperation()
+	operation.AddResponse(200, openapi3.NewResponse().WithContent(newContent(petsSchema)))
+	assert.Equal(t, "#/components/schemas/Pet", utils.GetOperationEntitySchemaRef(operation))
+
+	// Request body is preferred.
+	operation.RequestBody = &openapi3.RequestBodyRef{Value: openapi3.NewRequestBody().WithContent(newContent(ownerSchema))}
+	assert.Equal(t, "#/components/schemas/Owner", utils.GetOperationEntitySchemaRef(operation))
+
+	// Inline schemas are not component schemas.
+	operation = openapi3.NewOperation()
+	operation.AddResponse(201, openapi3.NewResponse().WithContent(newContent(openapi3.NewObjectSchema().NewRef())))
+	assert.Equal(t, "", utils.GetOperationEntitySchemaRef(operation))
+	assert.Equal(t, "", utils.GetOperationEntitySchemaRef(nil))
+}
+
+// TestMatchEndpointPath tests matching concrete request paths against endpoint templates.
+func TestMatchEndpointPath(t *testing.T) {
+	pathParams, ok := utils.MatchEndpointPath("/pets/{petId}/toys/{toyId}", "/pets/42/toys/ball%20x")
+	assert.True(t, ok)
+	assert.Equal(t, map[string]string{"petId": "42", "toyId": "ball x"}, pathParams)
+
+	pathParams, ok = utils.MatchEndpointPath("/pets", "/pets/")
+	assert.True(t, ok)
+	assert.Empty(t, pathParams)
+
+	// Literal segments and segment counts must match.
+	_, ok = utils.MatchEndpointPath("/pets/{petId}", "/owners/42")
+	assert.False(t, ok)
+	_, ok = utils.MatchEndpointPath("/pets/{petId}", "/pets/42/toys")
+	assert.False(t, ok)
+}
+
+// TestMatchPathGlob tests matching endpoint paths against path globs of operation filters.
+func TestMatchPathGlob(t *testing.T) {
+	assert.True(t, utils.MatchPathGlob("/admin/**", "/admin/users/{id}"))
+	assert.True(t, utils.MatchPathGlob("/admin/**", "/admin"))
+	assert.True(t, utils.MatchPathGlob("/users/*", "/users/{userId}"))
+	assert.True(t, utils.MatchPathGlob("/a/**/z", "/a/z"))
+	assert.True(t, utils.MatchPathGlob("/a/**/z", "/a/b/c/z"))
+
+	assert.False(t, utils.MatchPathGlob("/users/*", "/users/1/x"))
+	assert.False(t, utils.MatchPathGlob("/admin/**", "/administrators"))
+	assert.False(t, utils.MatchPathGlob("/a/**/z", "/a/b/y"))
+}
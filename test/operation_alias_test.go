@@ -0,0 +1,72 @@
+package test
+
+import (
+	"testing"
+
+	"resttracefuzzer/internal/config"
+	"resttracefuzzer/pkg/static"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestAPIManagerOperationAliases tests that only added operations are aliased, and aliases of colliding operationIds keep the API methods of operations.
+func TestAPIManagerOperationAliases(t *testing.T) {
+	systemDoc, err := openapi3.NewLoader().LoadFromData([]byte(`{
+		"openapi": "3.0.0",
+		"info": {"title": "shop", "version": "1.0"},
+		"paths": {
+			"/orders": {"post": {"operationId": "createOrder", "requestBody": {"content": {"application/json": {"schema": {"type": "object"}}}}, "responses": {"201": {"description": "created"}}}},
+			"/orders/upload": {"post": {"operationId": "uploadOrders", "requestBody": {"content": {"text/csv": {"schema": {"type": "string"}}}}, "responses": {"201": {"description": "created"}}}}
+		}
+	}`))
+	assert.NoError(t, err)
+	serviceDoc, err := openapi3.NewLoader().LoadFromData([]byte(`{
+		"openapi": "3.0.0",
+		"info": {"title": "services", "version": "1.0"},
+		"paths": {
+			"/inventory/health": {"get": {"operationId": "InventoryService_Health", "tags": ["APIType_HTTP"], "responses": {"200": {"description": "ok"}}}},
+			"/inventory/{id}": {"get": {"operationId": "InventoryService_Health", "tags": ["APIType_HTTP"], "responses": {"200": {"description": "ok"}}}},
+			"/order.OrderService/Get": {"post": {"operationId": "OrderService_Get", "tags": ["APIType_gRPC"], "responses": {"200": {"description": "ok"}}}},
+			"/order.v2.OrderService/Get": {"post": {"operationId": "OrderService_Get", "tags": ["APIType_gRPC"], "responses": {"200": {"description": "ok"}}}},
+			"/payment/health": {"get": {"operationId": "PaymentService_Health", "tags": ["APIType_HTTP"], "responses": {"200": {"description": "ok"}}}},
+			"/payment/{id}": {"get": {"operationId": "PaymentService_Get", "tags": ["APIType_HTTP"], "responses": {"200": {"description": "ok"}}}}
+		}
+	}`))
+	assert.NoError(t, err)
+
+	if config.GlobalConfig == nil {
+		config.InitConfig()
+	}
+	skipUnsupportedOperations := config.GlobalConfig.SkipUnsupportedOperations
+	config.GlobalConfig.SkipUnsupportedOperations = true
+	defer func() { config.GlobalConfig.SkipUnsupportedOperations = skipUnsupportedOperations }()
+	apiManager := static.NewAPIManager()
+	apiManager.InitFromDocs(systemDoc, serviceDoc)
+
+	// Skipped operations are not aliased.
+	endpoint, ok := apiManager.ResolveOperationAlias("createOrder")
+	assert.True(t, ok)
+	assert.Equal(t, "/orders", endpoint.SimpleAPIMethod.Endpoint)
+	_, ok = apiManager.ResolveOperationAlias("uploadOrders")
+	assert.False(t, ok)
+
+	// The gRPC method of a colliding operationId is kept, and the alias refers to it.
+	grpcMethod := static.SimpleAPIMethod{Method: "Get", Endpoint: "Get", Typ: static.SimpleAPIMethodTypeGRPC}
+	endpoint, ok = apiManager.ResolveOperationAlias("OrderService_Get")
+	assert.True(t, ok)
+	assert.Equal(t, grpcMethod, endpoint.SimpleAPIMethod)
+	endpoint, ok = apiManager.ResolveOperationAlias("OrderService.OrderService_Get")
+	assert.True(t, ok)
+	assert.Equal(t, grpcMethod, endpoint.SimpleAPIMethod)
+	assert.Contains(t, apiManager.ServiceAPIMap["OrderService"], grpcMethod)
+	_, ok = apiManager.ServiceAPIMap["OrderService"][static.SimpleAPIMethod{Method: "OrderService.OrderService_Get", Endpoint: "OrderService.OrderService_Get", Typ: static.SimpleAPIMethodTypeGRPC}]
+	assert.False(t, ok)
+
+	// Same internal HTTP path templates of different services are not collisions.
+	collisionKeys := make([]string, 0)
+	for _, collision := range apiManager.OperationCollisions {
+		collisionKeys = append(collisionKeys, string(collision.Typ)+" "+collision.Key)
+	}
+	assert.ElementsMatch(t, []string{"operationId InventoryService_Health", "operationId OrderService_Get"}, collisionKeys)
+}
@@ -0,0 +1,99 @@
+package test
+
+import (
+	"regexp"
+	"testing"
+
+	"resttracefuzzer/pkg/casemanager"
+	"resttracefuzzer/pkg/static"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestParseOperationHeaderRules tests parsing operation header rules and matching them against API methods.
+func TestParseOperationHeaderRules(t *testing.T) {
+	tests := []struct {
+		name         string
+		rulesStr     string
+		wantErr      bool
+		wantMatches  []static.SimpleAPIMethod
+		wantMismatch []static.SimpleAPIMethod
+	}{
+		{name: "empty", rulesStr: ""},
+		{name: "empty list", rulesStr: "[]"},
+		{
+			name:         "method and path",
+			rulesStr:     `[{"method": "delete", "path": "/users/*", "headers": {"X-Admin-Token": "secret"}}]`,
+			wantMatches:  []static.SimpleAPIMethod{{Method: "DELETE", Endpoint: "/users/{userId}"}},
+			wantMismatch: []static.SimpleAPIMethod{{Method: "GET", Endpoint: "/users/{userId}"}, {Method: "DELETE", Endpoint: "/users/{userId}/orders"}, {Method: "DELETE", Endpoint: "/users"}},
+		},
+		{
+			name:         "any method",
+			rulesStr:     `[{"method": "*", "path": "/admin/*/*", "headers": {"X-Admin-Token": "secret"}}]`,
+			wantMatches:  []static.SimpleAPIMethod{{Method: "GET", Endpoint: "/admin/users/{userId}"}, {Method: "POST", Endpoint: "/admin/users/list"}},
+			wantMismatch: []static.SimpleAPIMethod{{Method: "GET", Endpoint: "/admin/users"}},
+		},
+		{
+			name:         "any endpoint",
+			rulesStr:     `[{"method": "POST", "headers": {"X-Request-Id": "{{uuid}}"}}]`,
+			wantMatches:  []static.SimpleAPIMethod{{Method: "POST", Endpoint: "/"}, {Method: "POST", Endpoint: "/users"}},
+			wantMismatch: []static.SimpleAPIMethod{{Method: "PUT", Endpoint: "/users"}},
+		},
+		{name: "invalid JSON", rulesStr: `[{"method": "POST"`, wantErr: true},
+		{name: "null rule", rulesStr: `[null]`, wantErr: true},
+		{name: "malformed path pattern", rulesStr: `[{"path": "/users/[", "headers": {}}]`, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rules, err := casemanager.ParseOperationHeaderRules(tt.rulesStr)
+			if tt.wantErr {
+				assert.Error(t, err)
+				assert.Nil(t, rules)
+				return
+			}
+			assert.NoError(t, err)
+			assert.NotNil(t, rules)
+			for _, APIMethod := range tt.wantMatches {
+				assert.True(t, rules[0].Matches(APIMethod), "%v should be matched", APIMethod)
+			}
+			for _, APIMethod := range tt.wantMismatch {
+				assert.False(t, rules[0].Matches(APIMethod), "%v should not be matched", APIMethod)
+			}
+		})
+	}
+}
+
+// TestResolveHeaderTemplates tests resolving templates in header values, where unknown templates are kept as is.
+func TestResolveHeaderTemplates(t *testing.T) {
+	tests := []struct {
+		name      string
+		value     string
+		wantMatch string
+	}{
+		{"plain", "Bearer token", `^Bearer token$`},
+		{"uuid", "{{uuid}}", `^[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}$`},
+		{"spaced", "req-{{ uuid }}", `^req-[0-9a-f]{8}-[0-9a-f-]{27}$`},
+		{"now", "{{now}}", `^\d{4}-\d{2}-\d{2}T\d{2}:\d{2}:\d{2}(Z|[+-]\d{2}:\d{2})$`},
+		{"timestamp", "{{timestamp}}", `^\d{10}$`},
+		{"timestamp in milliseconds", "{{timestamp_ms}}", `^\d{13}$`},
+		{"random", "{{random}}", `^\d+$`},
+		{"several templates", "{{timestamp}}.{{random}}", `^\d{10}\.\d+$`},
+		{"unknown template", "{{unknown}}-{{random}}", `^\{\{unknown\}\}-\d+$`},
+		{"unclosed template", "{{uuid", `^\{\{uuid$`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			headers := map[string]string{"X-Test": tt.value}
+			resolved := casemanager.ResolveHeaderTemplates(headers)
+			assert.Regexp(t, regexp.MustCompile(tt.wantMatch), resolved["X-Test"])
+			// Headers are not changed in place.
+			assert.Equal(t, tt.value, headers["X-Test"])
+		})
+	}
+
+	// Each occurrence of a template is resolved separately.
+	resolved := casemanager.ResolveHeaderTemplates(map[string]string{"X-Test": "{{uuid}} {{uuid}}"})
+	uuids := regexp.MustCompile(`\S+`).FindAllString(resolved["X-Test"], -1)
+	assert.Len(t, uuids, 2)
+	assert.NotEqual(t, uuids[0], uuids[1])
+}
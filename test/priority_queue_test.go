@@ -0,0 +1,153 @@
+package test
+
+import (
+	"resttracefuzzer/pkg/utils"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// prioritizedItem is an element of priority queues in tests.
+type prioritizedItem struct {
+	name     string
+	priority int
+}
+
+func priorityOfItem(item *prioritizedItem) int {
+	return item.priority
+}
+
+// popAllNames pops all elements of the queue, and returns their names in popping order.
+func popAllNames(queue *utils.PriorityQueue[*prioritizedItem]) []string {
+	names := make([]string, 0)
+	for {
+		item, ok := queue.Pop()
+		if !ok {
+			return names
+		}
+		names = append(names, item.name)
+	}
+}
+
+func TestPriorityQueuePopOrder(t *testing.T) {
+	queue := utils.NewPriorityQueue(priorityOfItem)
+	queue.Push(&prioritizedItem{"a", 1})
+	queue.Push(&prioritizedItem{"b", 3})
+	queue.Push(&prioritizedItem{"c", 2})
+	queue.Push(&prioritizedItem{"d", 3})
+	queue.Push(&prioritizedItem{"e", 1})
+
+	peeked, ok := queue.Peek()
+	assert.True(t, ok)
+	assert.Equal(t, "b", peeked.name)
+	assert.Equal(t, 5, queue.Len())
+	// Elements of the same priority are popped in insertion order.
+	assert.Equal(t, []string{"b", "d", "c", "a", "e"}, popAllNames(queue))
+
+	_, ok = queue.Pop()
+	assert.False(t, ok)
+	_, ok = queue.Peek()
+	assert.False(t, ok)
+}
+
+func TestPriorityQueueFIFO(t *testing.T) {
+	queue := utils.NewPriorityQueue[*prioritizedItem](nil)
+	queue.Push(&prioritizedItem{"a", 1})
+	queue.Push(&prioritizedItem{"b", 3})
+	queue.Push(&prioritizedItem{"c", 2})
+	assert.Equal(t, []string{"a", "b", "c"}, popAllNames(queue))
+}
+
+func TestPriorityQueueTruncate(t *testing.T) {
+	queue := utils.NewPriorityQueue(priorityOfItem)
+	for i, priority := range []int{5, 1, 4, 1, 3, 9, 2} {
+		queue.Push(&prioritizedItem{string(rune('a' + i)), priority})
+	}
+	// The lowest ones are removed, and the later one is removed first on ties.
+	assert.Equal(t, 3, queue.Truncate(4))
+	assert.Equal(t, 4, queue.Len())
+	assert.Equal(t, 0, queue.Truncate(4))
+	assert.Equal(t, []string{"f", "a", "c", "e"}, popAllNames(queue))
+
+	queue.Push(&prioritizedItem{"x", 1})
+	queue.Push(&prioritizedItem{"y", 1})
+	assert.Equal(t, 1, queue.Truncate(1))
+	assert.Equal(t, []string{"x"}, popAllNames(queue))
+	assert.Equal(t, 0, queue.Truncate(-1))
+}
+
+func TestPriorityQueueDeleteFuncAndFixFunc(t *testing.T) {
+	queue := utils.NewPriorityQueue(priorityOfItem)
+	items := []*prioritizedItem{{"a", 1}, {"b", 2}, {"c", 3}, {"d", 4}}
+	for _, item := range items {
+		queue.Push(item)
+	}
+	assert.Equal(t, 2, queue.DeleteFunc(func(item *prioritizedItem) bool { return item.priority%2 == 0 }))
+	assert.Equal(t, 0, queue.DeleteFunc(func(item *prioritizedItem) bool { return item.name == "z" }))
+	assert.Equal(t, []*prioritizedItem{items[2], items[0]}, queue.Values())
+
+	// The priority only changes after fixing.
+	items[0].priority = 10
+	assert.Equal(t, "c", queue.Values()[0].name)
+	assert.True(t, queue.FixFunc(func(item *prioritizedItem) bool { return item.name == "a" }))
+	assert.False(t, queue.FixFunc(func(item *prioritizedItem) bool { return item.name == "z" }))
+	assert.Equal(t, []string{"a", "c"}, popAllNames(queue))
+}
+
+func TestPriorityQueueFixAll(t *testing.T) {
+	queue := utils.NewPriorityQueue(priorityOfItem)
+	queue.Push(&prioritizedItem{"a", 4})
+	queue.Push(&prioritizedItem{"b", 3})
+	queue.Push(&prioritizedItem{"c", 2})
+	queue.Push(&prioritizedItem{"d", 1})
+
+	// Elements of the same priority after updating are popped in insertion order.
+	queue.FixAll(func(item *prioritizedItem) {
+		item.priority = 5 - item.priority
+		if item.name == "a" {
+			item.priority = 4
+		}
+	})
+	assert.Equal(t, []string{"a", "d", "c", "b"}, popAllNames(queue))
+}
+
+// benchmarkQueueCapacity is the capacity of queues in benchmarks, like a large MaxAllowedScenarios.
+const benchmarkQueueCapacity = 10000
+
+// BenchmarkPriorityQueuePushAndTruncate pushes elements to a full priority queue, truncating it after each push.
+func BenchmarkPriorityQueuePushAndTruncate(b *testing.B) {
+	queue := utils.NewPriorityQueue(priorityOfItem)
+	for i := 0; i < benchmarkQueueCapacity; i++ {
+		queue.Push(&prioritizedItem{priority: utils.SharedRand.IntN(20)})
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		queue.Push(&prioritizedItem{priority: utils.SharedRand.IntN(20)})
+		queue.Truncate(benchmarkQueueCapacity)
+		if i%2 == 0 {
+			queue.Pop()
+		}
+	}
+}
+
+// BenchmarkSortedSlicePushAndTruncate pushes elements to a full slice, sorting and truncating it after each push, for comparison.
+func BenchmarkSortedSlicePushAndTruncate(b *testing.B) {
+	items := make([]*prioritizedItem, 0, benchmarkQueueCapacity+1)
+	for i := 0; i < benchmarkQueueCapacity; i++ {
+		items = append(items, &prioritizedItem{priority: utils.SharedRand.IntN(20)})
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		items = append(items, &prioritizedItem{priority: utils.SharedRand.IntN(20)})
+		sort.Slice(items, func(i, j int) bool {
+			return items[i].priority > items[j].priority
+		})
+		if len(items) > benchmarkQueueCapacity {
+			items = items[:benchmarkQueueCapacity]
+		}
+		if i%2 == 0 {
+			items = items[1:]
+		}
+	}
+}
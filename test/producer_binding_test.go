@@ -0,0 +1,156 @@
+package test
+
+import (
+	"testing"
+
+	"resttracefuzzer/pkg/casemanager"
+	"resttracefuzzer/pkg/resource"
+	"resttracefuzzer/pkg/static"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestCaseManagerBindProducerValues tests binding values in responses of producers to path, query and request body parameters of the consumer,
+// where the closest producer takes precedence, and producers not responding with success are skipped.
+func TestCaseManagerBindProducerValues(t *testing.T) {
+	createProduct := static.SimpleAPIMethod{Method: "POST", Endpoint: "/products", Typ: static.SimpleAPIMethodTypeHTTP}
+	getProduct := static.SimpleAPIMethod{Method: "GET", Endpoint: "/products/{productId}", Typ: static.SimpleAPIMethodTypeHTTP}
+	searchProducts := static.SimpleAPIMethod{Method: "GET", Endpoint: "/search", Typ: static.SimpleAPIMethodTypeHTTP}
+	createOrder := static.SimpleAPIMethod{Method: "POST", Endpoint: "/orders", Typ: static.SimpleAPIMethodTypeHTTP}
+
+	dependencyGraph := static.NewAPIDependencyGraph()
+	binding := static.APIDependencyBinding{
+		SourceProperty: static.SimpleAPIProperty{Name: "id", Typ: static.SimpleAPIPropertyTypeInteger},
+		TargetProperty: static.SimpleAPIProperty{Name: "productId", Typ: static.SimpleAPIPropertyTypeInteger},
+	}
+	for _, consumer := range []static.SimpleAPIMethod{getProduct, searchProducts, createOrder} {
+		dependencyGraph.AddDependency(createProduct, consumer)
+		dependencyGraph.AddBinding(createProduct, consumer, binding)
+	}
+	apiManager := static.NewAPIManager()
+	apiManager.APIDependencyGraph = dependencyGraph
+	caseManager := &casemanager.CaseManager{APIManager: apiManager}
+
+	// newConsumer creates the operation case of the consumer, with parameters and request body populated.
+	newConsumer := func(method static.SimpleAPIMethod) *casemanager.OperationCase {
+		operation := openapi3.NewOperation()
+		operationCase := casemanager.NewOperationCase(method, operation)
+		switch method {
+		case getProduct:
+			operation.AddParameter(openapi3.NewPathParameter("productId"))
+			operationCase.RequestPathParams = map[string]string{"productId": "0"}
+		case searchProducts:
+			operation.AddParameter(openapi3.NewQueryParameter("productId"))
+			operationCase.RequestQueryParams = map[string]string{"productId": "0"}
+		case createOrder:
+			bodyResource, err := resource.NewResourceFromRawBytes([]byte(`{"productId": 0, "items": [{"productId": 0}], "note": "a"}`))
+			assert.NoError(t, err)
+			operationCase.SetRequestBodyByResource(bodyResource)
+		}
+		return operationCase
+	}
+	// newProducer creates the operation case of the producer executed with the response.
+	newProducer := func(statusCode int, responseBody string) *casemanager.OperationCase {
+		operationCase := casemanager.NewOperationCase(createProduct, openapi3.NewOperation())
+		operationCase.ResponseStatusCode = statusCode
+		operationCase.ResponseBody = []byte(responseBody)
+		return operationCase
+	}
+
+	tests := []struct {
+		name            string
+		producers       []*casemanager.OperationCase
+		consumer        static.SimpleAPIMethod
+		negativeMode    bool
+		wantBoundCnt    int
+		wantPathParams  map[string]string
+		wantQueryParams map[string]string
+		wantBody        string
+	}{
+		{
+			name:           "path parameter",
+			producers:      []*casemanager.OperationCase{newProducer(201, `{"id": 7}`)},
+			consumer:       getProduct,
+			wantBoundCnt:   1,
+			wantPathParams: map[string]string{"productId": "7"},
+		},
+		{
+			name:            "query parameter",
+			producers:       []*casemanager.OperationCase{newProducer(201, `{"id": 7}`)},
+			consumer:        searchProducts,
+			wantBoundCnt:    1,
+			wantQueryParams: map[string]string{"productId": "7"},
+		},
+		{
+			// Only the shallowest property of the name in the body is bound.
+			name:         "request body",
+			producers:    []*casemanager.OperationCase{newProducer(201, `{"product": {"id": 7}}`)},
+			consumer:     createOrder,
+			wantBoundCnt: 1,
+			wantBody:     `{"productId": 7, "items": [{"productId": 0}], "note": "a"}`,
+		},
+		{
+			name:           "closest producer",
+			producers:      []*casemanager.OperationCase{newProducer(201, `{"id": 7}`), newProducer(201, `{"id": 8}`)},
+			consumer:       getProduct,
+			wantBoundCnt:   1,
+			wantPathParams: map[string]string{"productId": "8"},
+		},
+		{
+			name:           "non-2xx producer skipped",
+			producers:      []*casemanager.OperationCase{newProducer(201, `{"id": 7}`), newProducer(400, `{"id": 8}`)},
+			consumer:       getProduct,
+			wantBoundCnt:   1,
+			wantPathParams: map[string]string{"productId": "7"},
+		},
+		{
+			name:           "non-JSON producer skipped",
+			producers:      []*casemanager.OperationCase{newProducer(201, `{"id": 7}`), newProducer(201, `created`)},
+			consumer:       getProduct,
+			wantBoundCnt:   1,
+			wantPathParams: map[string]string{"productId": "7"},
+		},
+		{
+			name:           "object value not bound",
+			producers:      []*casemanager.OperationCase{newProducer(201, `{"id": {"value": 7}}`)},
+			consumer:       getProduct,
+			wantPathParams: map[string]string{"productId": "0"},
+		},
+		{
+			name:           "no successful producer",
+			producers:      []*casemanager.OperationCase{newProducer(500, `{"id": 7}`)},
+			consumer:       getProduct,
+			wantPathParams: map[string]string{"productId": "0"},
+		},
+		{
+			name:           "negative mode",
+			producers:      []*casemanager.OperationCase{newProducer(201, `{"id": 7}`)},
+			consumer:       getProduct,
+			negativeMode:   true,
+			wantPathParams: map[string]string{"productId": "0"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			consumer := newConsumer(tt.consumer)
+			consumer.NegativeMode = tt.negativeMode
+			testScenario := casemanager.NewTestScenario(append(tt.producers, consumer))
+			assert.Equal(t, tt.wantBoundCnt, caseManager.BindProducerValues(testScenario, consumer))
+			if tt.wantPathParams != nil {
+				assert.Equal(t, tt.wantPathParams, consumer.RequestPathParams)
+			}
+			if tt.wantQueryParams != nil {
+				assert.Equal(t, tt.wantQueryParams, consumer.RequestQueryParams)
+			}
+			if tt.wantBody != "" {
+				assert.JSONEq(t, tt.wantBody, string(consumer.RequestBody))
+			}
+		})
+	}
+
+	// Producers executed after the consumer are not bound.
+	consumer := newConsumer(getProduct)
+	testScenario := casemanager.NewTestScenario([]*casemanager.OperationCase{consumer, newProducer(201, `{"id": 7}`)})
+	assert.Equal(t, 0, caseManager.BindProducerValues(testScenario, consumer))
+}
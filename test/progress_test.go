@@ -0,0 +1,25 @@
+package test
+
+import (
+	"testing"
+
+	"resttracefuzzer/internal/fuzzer"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestProgressTrackerServerErrors tests that server errors of all requests and those of primary requests are counted separately.
+func TestProgressTrackerServerErrors(t *testing.T) {
+	tracker := fuzzer.NewProgressTracker()
+	// A primary request and its replay both get 500, and a failed request gets no response.
+	tracker.RecordResponse(500, false)
+	tracker.RecordPrimaryServerError()
+	tracker.RecordResponse(500, false)
+	tracker.RecordResponse(0, true)
+
+	progress := tracker.GetProgress()
+	assert.Equal(t, 3, progress.RequestCount)
+	assert.Equal(t, 2, progress.StatusClassCounts[500])
+	assert.Equal(t, 1, progress.StatusClassCounts[0])
+	assert.Equal(t, 1, progress.PrimaryServerErrorCount)
+}
@@ -0,0 +1,33 @@
+package test
+
+import (
+	"resttracefuzzer/pkg/utils"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+// drawSharedRand draws a sequence of random values through utils, for checking reproducibility.
+func drawSharedRand() ([]int, string, uuid.UUID) {
+	ints := make([]int, 0, 10)
+	for range 10 {
+		ints = append(ints, utils.SharedRand.IntN(1000))
+	}
+	return ints, utils.RandStringBytes(16), uuid.New()
+}
+
+func TestSeedSharedRand(t *testing.T) {
+	utils.SeedSharedRand(42)
+	ints1, str1, uuid1 := drawSharedRand()
+	utils.SeedSharedRand(42)
+	ints2, str2, uuid2 := drawSharedRand()
+	assert.Equal(t, ints1, ints2)
+	assert.Equal(t, str1, str2)
+	assert.Equal(t, uuid1, uuid2)
+
+	utils.SeedSharedRand(43)
+	ints3, str3, _ := drawSharedRand()
+	assert.NotEqual(t, ints1, ints3)
+	assert.NotEqual(t, str1, str3)
+}
@@ -0,0 +1,30 @@
+package test
+
+import (
+	"regexp"
+	"resttracefuzzer/pkg/utils"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGenerateStringForPattern(t *testing.T) {
+	patterns := []string{
+		`^[a-z]{3,5}$`,
+		`^\d{4}-\d{2}-\d{2}$`,
+		`^(foo|bar)_[A-Z0-9]+$`,
+		`[^a-z]x?`,
+		`^user-\w*$`,
+	}
+	for _, pattern := range patterns {
+		re := regexp.MustCompile(pattern)
+		for range 20 {
+			generated, err := utils.GenerateStringForPattern(pattern)
+			assert.NoError(t, err)
+			assert.Regexp(t, re, generated, "pattern: %s", pattern)
+		}
+	}
+
+	_, err := utils.GenerateStringForPattern(`(unclosed`)
+	assert.Error(t, err)
+}
@@ -0,0 +1,126 @@
+package test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"resttracefuzzer/pkg/feedback"
+	"resttracefuzzer/pkg/report"
+	"resttracefuzzer/pkg/static"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestScenarioExporterHeaders tests that exported test files keep recorded headers, except credential and trace propagation ones, which are read from env.
+func TestScenarioExporterHeaders(t *testing.T) {
+	testLogReport := report.NewTestLogReport()
+	testLogReport.TestedScenarios = append(testLogReport.TestedScenarios, &report.TestScenarioForReport{
+		TestScenarioUUID: uuid.New(),
+		OperationCases: []*report.OperationCaseForReport{
+			{
+				APIMethod: static.SimpleAPIMethod{Method: "POST", Endpoint: "/orders", Typ: static.SimpleAPIMethodTypeHTTP},
+				RequestHeaders: map[string]string{
+					"Authorization": "Bearer secret-token",
+					"Cookie":        "session=secret-cookie",
+					"traceparent":   "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01",
+					"X-B3-TraceId":  "0af7651916cd43dd8448eb211c80319c",
+					"X-Tenant":      "acme",
+				},
+				RequestBody:        `{"item": "book"}`,
+				ResponseStatusCode: 500,
+			},
+		},
+	})
+
+	tests := []struct {
+		format       string
+		wantContains []string
+	}{
+		{
+			report.ScenarioExportFormatGo,
+			[]string{
+				`"Authorization": os.Getenv("REGRESSION_HEADER_AUTHORIZATION")`,
+				`"Cookie": os.Getenv("REGRESSION_HEADER_COOKIE")`,
+				`"X-B3-TraceId": os.Getenv("REGRESSION_HEADER_X_B3_TRACEID")`,
+				`"traceparent": os.Getenv("REGRESSION_HEADER_TRACEPARENT")`,
+				`"X-Tenant": "acme"`,
+			},
+		},
+		{
+			report.ScenarioExportFormatPython,
+			[]string{
+				`"Authorization": os.environ.get("REGRESSION_HEADER_AUTHORIZATION", "")`,
+				`"Cookie": os.environ.get("REGRESSION_HEADER_COOKIE", "")`,
+				`"X-B3-TraceId": os.environ.get("REGRESSION_HEADER_X_B3_TRACEID", "")`,
+				`"traceparent": os.environ.get("REGRESSION_HEADER_TRACEPARENT", "")`,
+				`"X-Tenant": "acme"`,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			exporter, err := report.NewScenarioExporter(tt.format)
+			assert.NoError(t, err)
+			outputDir := t.TempDir()
+			err = exporter.ExportFailingScenarios(testLogReport, feedback.NewScenarioMinimizer(0), "http://localhost:8080", outputDir)
+			assert.NoError(t, err)
+			content, err := os.ReadFile(filepath.Join(outputDir, exporter.GetFileName()))
+			assert.NoError(t, err)
+			assert.NotContains(t, string(content), "secret")
+			assert.NotContains(t, string(content), "0af7651916cd43dd8448eb211c80319c")
+			for _, want := range tt.wantContains {
+				assert.Contains(t, string(content), want)
+			}
+		})
+	}
+}
+
+// TestScenarioExporterSelection tests that one test is exported for each failure, using the shortest tested scenario, and skipping non-HTTP scenarios.
+func TestScenarioExporterSelection(t *testing.T) {
+	getOrder := static.SimpleAPIMethod{Method: "GET", Endpoint: "/orders/{id}", Typ: static.SimpleAPIMethodTypeHTTP}
+	createOrder := static.SimpleAPIMethod{Method: "POST", Endpoint: "/orders", Typ: static.SimpleAPIMethodTypeHTTP}
+	grpcMethod := static.SimpleAPIMethod{Method: "POST", Endpoint: "/order.OrderService/GetOrder", Typ: static.SimpleAPIMethodTypeGRPC}
+	testLogReport := report.NewTestLogReport()
+	testLogReport.TestedScenarios = append(testLogReport.TestedScenarios,
+		&report.TestScenarioForReport{
+			TestScenarioUUID: uuid.New(),
+			OperationCases: []*report.OperationCaseForReport{
+				{APIMethod: createOrder, ResponseStatusCode: 201},
+				{APIMethod: createOrder, ResponseStatusCode: 201},
+				{APIMethod: getOrder, RequestPathParams: map[string]string{"id": "1"}, ResponseStatusCode: 500},
+			},
+		},
+		&report.TestScenarioForReport{
+			TestScenarioUUID: uuid.New(),
+			OperationCases: []*report.OperationCaseForReport{
+				{APIMethod: createOrder, ResponseStatusCode: 201},
+				{APIMethod: getOrder, RequestPathParams: map[string]string{"id": "2"}, ResponseStatusCode: 500},
+				{APIMethod: getOrder, ResponseStatusCode: 200},
+			},
+		},
+		&report.TestScenarioForReport{
+			TestScenarioUUID: uuid.New(),
+			OperationCases: []*report.OperationCaseForReport{
+				{APIMethod: grpcMethod, ResponseStatusCode: 500},
+			},
+		},
+	)
+
+	exporter, err := report.NewScenarioExporter(report.ScenarioExportFormatGo)
+	assert.NoError(t, err)
+	outputDir := t.TempDir()
+	err = exporter.ExportFailingScenarios(testLogReport, feedback.NewScenarioMinimizer(0), "http://localhost:8080", outputDir)
+	assert.NoError(t, err)
+	content, err := os.ReadFile(filepath.Join(outputDir, "regression_test.go"))
+	assert.NoError(t, err)
+	assert.Contains(t, string(content), "func TestScenario1(")
+	assert.NotContains(t, string(content), "func TestScenario2(")
+	assert.Contains(t, string(content), `"/orders/2"`)
+	assert.NotContains(t, string(content), `"/orders/1"`)
+	assert.NotContains(t, string(content), "OrderService")
+
+	_, err = report.NewScenarioExporter("java")
+	assert.Error(t, err)
+}
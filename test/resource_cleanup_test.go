@@ -0,0 +1,131 @@
+package test
+
+import (
+	"testing"
+
+	"resttracefuzzer/pkg/casemanager"
+	"resttracefuzzer/pkg/feedback"
+	"resttracefuzzer/pkg/static"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/stretchr/testify/assert"
+)
+
+// newCleanupTestAPIManager creates an API manager of users and their orders, which can be created by POST and deleted by DELETE.
+func newCleanupTestAPIManager(t *testing.T) *static.APIManager {
+	doc, err := openapi3.NewLoader().LoadFromData([]byte(`{
+		"openapi": "3.0.0",
+		"info": {"title": "shop", "version": "1.0"},
+		"paths": {
+			"/users": {"post": {"responses": {"201": {"description": "created"}}}},
+			"/users/{userId}": {"delete": {"responses": {"204": {"description": "deleted"}}}},
+			"/users/{userId}/orders": {"post": {"responses": {"201": {"description": "created"}}}},
+			"/users/{userId}/orders/{orderId}": {"delete": {"responses": {"204": {"description": "deleted"}}}}
+		}
+	}`))
+	assert.NoError(t, err)
+	apiManager := static.NewAPIManager()
+	apiManager.InitFromDocs(doc, &openapi3.T{Paths: openapi3.NewPaths()})
+	return apiManager
+}
+
+// newExecutedOperationResult creates the result of an executed HTTP operation case, with the response filled.
+func newExecutedOperationResult(method, endpoint string, pathParams map[string]string, statusCode int, location, body string) *feedback.OperationResult {
+	operationCase := casemanager.NewOperationCase(static.SimpleAPIMethod{Method: method, Endpoint: endpoint, Typ: static.SimpleAPIMethodTypeHTTP}, nil)
+	operationCase.RequestPathParams = pathParams
+	operationCase.ResponseStatusCode = statusCode
+	operationCase.ResponseHeaders = map[string]string{}
+	if location != "" {
+		operationCase.ResponseHeaders[feedback.LocationHeaderKey] = location
+	}
+	operationCase.ResponseBody = []byte(body)
+	return &feedback.OperationResult{OperationCase: operationCase}
+}
+
+// TestCreatedResourceTrackerLocation tests resolving created resources from `Location` headers, with path prefixes of the server base URL.
+func TestCreatedResourceTrackerLocation(t *testing.T) {
+	tests := []struct {
+		name         string
+		endpoint     string
+		pathParams   map[string]string
+		location     string
+		wantPath     string
+		wantResolved bool
+	}{
+		{"relative", "/users", nil, "/users/42", "/users/42", true},
+		{"absolute with base path", "/users", nil, "http://localhost:8080/api/v1/users/42", "/users/42", true},
+		{"nested", "/users/{userId}/orders", map[string]string{"userId": "42"}, "/users/42/orders/7", "/users/42/orders/7", true},
+		{"different collection", "/users", nil, "/orders/42", "", false},
+		{"too short", "/users", nil, "/42", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tracker := feedback.NewCreatedResourceTracker(newCleanupTestAPIManager(t))
+			tracker.EvaluateScenario(casemanager.NewTestScenario(nil), []*feedback.OperationResult{
+				newExecutedOperationResult("POST", tt.endpoint, tt.pathParams, 201, tt.location, ""),
+			})
+			createdResources := tracker.GetCreatedResources()
+			if !tt.wantResolved {
+				assert.Empty(t, createdResources)
+				return
+			}
+			assert.Len(t, createdResources, 1)
+			assert.Equal(t, tt.wantPath, createdResources[0].ResourcePath)
+		})
+	}
+}
+
+// TestCreatedResourceTracker tests tracking resources created and deleted in test scenarios and the cleanup, per server.
+func TestCreatedResourceTracker(t *testing.T) {
+	tracker := feedback.NewCreatedResourceTracker(newCleanupTestAPIManager(t))
+	serverBaseURL := "http://server-a"
+	tracker.ServerBaseURLOf = func(static.SimpleAPIMethod) string { return serverBaseURL }
+
+	results := make([]*feedback.OperationResult, 0)
+	for _, id := range []string{"1", "2", "3", "4"} {
+		results = append(results, newExecutedOperationResult("POST", "/users", nil, 201, "", `{"userId": "`+id+`"}`))
+	}
+	// Numeric ids are formatted without exponent.
+	results = append(results, newExecutedOperationResult("POST", "/users", nil, 201, "", `{"userId": 1000000}`))
+	results = append(results, newExecutedOperationResult("DELETE", "/users/{userId}", map[string]string{"userId": "1000000"}, 204, "", ""))
+	results = append(results, newExecutedOperationResult("POST", "/users", nil, 201, "", `{"userId": 12345678.5}`))
+	// Orders are created under user 1, with its id resolved from the `id` field.
+	results = append(results, newExecutedOperationResult("POST", "/users/{userId}/orders", map[string]string{"userId": "1"}, 201, "", `{"id": "7"}`))
+	// Failed requests do not create resources.
+	results = append(results, newExecutedOperationResult("POST", "/users", nil, 500, "", `{"userId": "5"}`))
+	results = append(results, newExecutedOperationResult("DELETE", "/users/{userId}", map[string]string{"userId": "2"}, 204, "", ""))
+	results = append(results, newExecutedOperationResult("DELETE", "/users/{userId}", map[string]string{"userId": "3"}, 204, "", ""))
+	tracker.EvaluateScenario(casemanager.NewTestScenario(nil), results)
+
+	// The same path on another server is another resource, and deletions on it do not untrack those of server A.
+	serverBaseURL = "http://server-b"
+	tracker.EvaluateScenario(casemanager.NewTestScenario(nil), []*feedback.OperationResult{
+		newExecutedOperationResult("POST", "/users", nil, 201, "/users/1", ""),
+		newExecutedOperationResult("DELETE", "/users/{userId}", map[string]string{"userId": "4"}, 204, "", ""),
+	})
+
+	createdResources := tracker.GetCreatedResources()
+	paths := make([]string, 0, len(createdResources))
+	for _, resource := range createdResources {
+		paths = append(paths, resource.ServerBaseURL+resource.ResourcePath)
+	}
+	assert.Equal(t, []string{"http://server-b/users/1", "http://server-a/users/1/orders/7", "http://server-a/users/12345678.5", "http://server-a/users/4", "http://server-a/users/1"}, paths)
+	assert.Equal(t, map[string]string{"userId": "1", "orderId": "7"}, createdResources[1].PathParams)
+	assert.Equal(t, static.SimpleAPIMethod{Method: "DELETE", Endpoint: "/users/{userId}/orders/{orderId}", Typ: static.SimpleAPIMethodTypeHTTP}, createdResources[1].Deleter)
+
+	// Resources gone in the cleanup are no longer tracked, while those failed to delete are left.
+	result := tracker.RecordCleanup(createdResources[0], 204)
+	assert.True(t, result.Removed)
+	result = tracker.RecordCleanup(createdResources[1], 404)
+	assert.True(t, result.Removed)
+	result = tracker.RecordCleanup(createdResources[2], 204)
+	assert.True(t, result.Removed)
+	result = tracker.RecordCleanup(createdResources[3], 500)
+	assert.False(t, result.Removed)
+	assert.Len(t, tracker.CleanupResults, 4)
+	leftoverResources := tracker.GetCreatedResources()
+	assert.Len(t, leftoverResources, 2)
+	assert.Equal(t, "/users/4", leftoverResources[0].ResourcePath)
+	assert.Equal(t, "/users/1", leftoverResources[1].ResourcePath)
+	assert.Equal(t, "http://server-a", leftoverResources[1].ServerBaseURL)
+}
@@ -0,0 +1,131 @@
+package test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"resttracefuzzer/pkg/feedback"
+	"resttracefuzzer/pkg/resource"
+	"resttracefuzzer/pkg/static"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/stretchr/testify/assert"
+)
+
+// newInvalidationTestResourceManager creates a resource manager with users, among which only `{"id": 1, ...}`, `{"user_id": 1, ...}` and the number `userId` 1 refer to the user 1 by integer IDs.
+func newInvalidationTestResourceManager(t *testing.T) *resource.ResourceManager {
+	resourceManager := resource.NewResourceManager()
+	for _, user := range []string{
+		`{"id": 1, "name": "alice", "age": 30}`,
+		`{"id": 2, "name": "bob", "age": 1}`,
+		`{"id": "1", "name": "carol"}`,
+		`{"user_id": 1, "name": "dave"}`,
+	} {
+		assert.NoError(t, resourceManager.StoreResourcesFromRawObjectBytes([]byte(user), "user", true))
+	}
+	assert.NoError(t, resourceManager.StoreResourcesFromRawObjectBytes([]byte(`1`), "userId", false))
+	assert.NoError(t, resourceManager.StoreResourcesFromRawObjectBytes([]byte(`"1"`), "userId", false))
+	return resourceManager
+}
+
+// getResourceJSONs returns the JSON strings (with sorted keys) of resources of the name in the pool.
+func getResourceJSONs(t *testing.T, resourceManager *resource.ResourceManager, resourceName string) []string {
+	resourceJSONs := make([]string, 0)
+	for _, r := range resourceManager.ResourceNameMap[resourceName] {
+		resourceJSON, err := json.Marshal(r.ToJSONObject())
+		assert.NoError(t, err)
+		resourceJSONs = append(resourceJSONs, string(resourceJSON))
+	}
+	return resourceJSONs
+}
+
+// TestRemoveResourcesReferringTo tests that only resources whose referring fields (by name and type) equal the value are removed.
+func TestRemoveResourcesReferringTo(t *testing.T) {
+	tests := []struct {
+		name        string
+		fieldNames  []string
+		valueType   static.SimpleAPIPropertyType
+		wantUsers   []string
+		wantUserIDs []string
+	}{
+		{
+			name:       "integer id",
+			fieldNames: []string{"id", "userId"},
+			valueType:  static.SimpleAPIPropertyTypeInteger,
+			wantUsers: []string{
+				`{"age":1,"id":2,"name":"bob"}`,
+				`{"id":"1","name":"carol"}`,
+			},
+			wantUserIDs: []string{`"1"`},
+		},
+		{
+			name:       "string id",
+			fieldNames: []string{"id"},
+			valueType:  static.SimpleAPIPropertyTypeString,
+			wantUsers: []string{
+				`{"age":30,"id":1,"name":"alice"}`,
+				`{"age":1,"id":2,"name":"bob"}`,
+				`{"name":"dave","user_id":1}`,
+			},
+			wantUserIDs: []string{`1`},
+		},
+		{
+			name:       "unknown type",
+			fieldNames: []string{"id"},
+			valueType:  static.SimpleAPIPropertyTypeUnknown,
+			wantUsers: []string{
+				`{"age":1,"id":2,"name":"bob"}`,
+				`{"name":"dave","user_id":1}`,
+			},
+			wantUserIDs: []string{},
+		},
+		{
+			name:       "no field names",
+			fieldNames: []string{},
+			valueType:  static.SimpleAPIPropertyTypeInteger,
+			wantUsers: []string{
+				`{"age":30,"id":1,"name":"alice"}`,
+				`{"age":1,"id":2,"name":"bob"}`,
+				`{"id":"1","name":"carol"}`,
+				`{"name":"dave","user_id":1}`,
+			},
+			wantUserIDs: []string{`"1"`},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resourceManager := newInvalidationTestResourceManager(t)
+			resourceManager.RemoveResourcesReferringTo([]string{"userId", "users", "user"}, tt.fieldNames, "1", tt.valueType)
+			assert.ElementsMatch(t, tt.wantUsers, getResourceJSONs(t, resourceManager, "user"))
+			assert.ElementsMatch(t, tt.wantUserIDs, getResourceJSONs(t, resourceManager, "userId"))
+		})
+	}
+}
+
+// TestProcessDeletion tests removing resources referring to the entity deleted, by ID fields of the type declared by the path parameter.
+func TestProcessDeletion(t *testing.T) {
+	doc, err := openapi3.NewLoader().LoadFromData([]byte(`{
+		"openapi": "3.0.0",
+		"info": {"title": "shop", "version": "1.0"},
+		"paths": {
+			"/users/{userId}": {"delete": {
+				"parameters": [{"name": "userId", "in": "path", "required": true, "schema": {"type": "integer"}}],
+				"responses": {"204": {"description": "deleted"}}
+			}}
+		}
+	}`))
+	assert.NoError(t, err)
+	apiManager := static.NewAPIManager()
+	apiManager.InitFromDocs(doc, &openapi3.T{Paths: openapi3.NewPaths()})
+	resourceManager := newInvalidationTestResourceManager(t)
+	responseProcesser := feedback.NewResponseProcesser(apiManager, resourceManager)
+
+	method := static.SimpleAPIMethod{Method: "DELETE", Endpoint: "/users/{userId}", Typ: static.SimpleAPIMethodTypeHTTP}
+	assert.Equal(t, 0, responseProcesser.ProcessDeletion(method, 404, map[string]string{"userId": "1"}))
+	assert.Greater(t, responseProcesser.ProcessDeletion(method, 204, map[string]string{"userId": "1"}), 0)
+	assert.ElementsMatch(t, []string{
+		`{"age":1,"id":2,"name":"bob"}`,
+		`{"id":"1","name":"carol"}`,
+	}, getResourceJSONs(t, resourceManager, "user"))
+	assert.ElementsMatch(t, []string{`"1"`}, getResourceJSONs(t, resourceManager, "userId"))
+}
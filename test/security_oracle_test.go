@@ -0,0 +1,133 @@
+package test
+
+import (
+	"testing"
+
+	"resttracefuzzer/pkg/casemanager"
+	"resttracefuzzer/pkg/feedback"
+	"resttracefuzzer/pkg/static"
+	"resttracefuzzer/pkg/strategy"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+// newSecurityTestPayloadDict creates a SecurityPayloadDict of a few payloads, including one containing an error signature itself.
+func newSecurityTestPayloadDict() *strategy.SecurityPayloadDict {
+	return &strategy.SecurityPayloadDict{
+		Payloads: map[strategy.SecurityPayloadCategory][]string{
+			strategy.SecurityPayloadCategorySQLInjection:     {"' OR '1'='1"},
+			strategy.SecurityPayloadCategoryXSS:              {"<script>alert(1)</script>"},
+			strategy.SecurityPayloadCategoryCommandInjection: {"; echo 'sh: 1: injected'"},
+		},
+	}
+}
+
+// newSecurityOperationCase creates an executed operation case of `POST /users/{id}`, with the request and the response.
+func newSecurityOperationCase(pathParams map[string]string, requestBody string, statusCode int, responseBody string) *casemanager.OperationCase {
+	operationCase := casemanager.NewOperationCase(static.SimpleAPIMethod{Method: "POST", Endpoint: "/users/{id}", Typ: static.SimpleAPIMethodTypeHTTP}, nil)
+	operationCase.RequestPathParams = pathParams
+	operationCase.RequestBody = []byte(requestBody)
+	operationCase.ResponseStatusCode = statusCode
+	operationCase.ResponseBody = []byte(responseBody)
+	return operationCase
+}
+
+// TestSecurityOracleCheckOperationCase tests the heuristics of the security oracle,
+// where only requests carrying payloads are checked, and error signatures echoed from the payload are excluded.
+func TestSecurityOracleCheckOperationCase(t *testing.T) {
+	// securityFindingForTest is the part of a finding checked by the test.
+	type securityFindingForTest struct {
+		typ      feedback.SecurityFindingType
+		category strategy.SecurityPayloadCategory
+		evidence string
+	}
+
+	tests := []struct {
+		name          string
+		operationCase *casemanager.OperationCase
+		want          []securityFindingForTest
+	}{
+		{
+			"SQL error",
+			newSecurityOperationCase(map[string]string{"id": "' OR '1'='1"}, "", 400, "You have an error in your SQL syntax near ''1'"),
+			[]securityFindingForTest{{feedback.SecurityFindingTypeErrorSignature, strategy.SecurityPayloadCategorySQLInjection, "SQL error"}},
+		},
+		{
+			"payload in nested JSON body",
+			newSecurityOperationCase(nil, `{"user": {"tags": ["' OR '1'='1"]}}`, 400, "SQLSTATE[42000]: Syntax error"),
+			[]securityFindingForTest{{feedback.SecurityFindingTypeErrorSignature, strategy.SecurityPayloadCategorySQLInjection, "SQL error"}},
+		},
+		{
+			"reflected XSS",
+			newSecurityOperationCase(nil, `{"name": "<script>alert(1)</script>"}`, 200, `{"name": "<script>alert(1)</script>"}`),
+			[]securityFindingForTest{{feedback.SecurityFindingTypeReflectedPayload, strategy.SecurityPayloadCategoryXSS, "payload reflected in response body"}},
+		},
+		{
+			"escaped XSS",
+			newSecurityOperationCase(nil, `{"name": "<script>alert(1)</script>"}`, 200, `{"name": "&lt;script&gt;alert(1)&lt;/script&gt;"}`),
+			[]securityFindingForTest{},
+		},
+		{
+			"server error",
+			newSecurityOperationCase(map[string]string{"id": "' OR '1'='1"}, "", 500, "internal error"),
+			[]securityFindingForTest{{feedback.SecurityFindingTypeServerError, strategy.SecurityPayloadCategorySQLInjection, "status code 500"}},
+		},
+		{
+			// The response echoes the payload, which contains the shell error signature itself.
+			"signature echoed from payload",
+			newSecurityOperationCase(nil, `{"cmd": "; echo 'sh: 1: injected'"}`, 400, "invalid command: ; echo 'sh: 1: injected'"),
+			[]securityFindingForTest{},
+		},
+		{
+			"no payload",
+			newSecurityOperationCase(map[string]string{"id": "42"}, "", 500, "You have an error in your SQL syntax"),
+			[]securityFindingForTest{},
+		},
+		{
+			"not executed",
+			newSecurityOperationCase(map[string]string{"id": "' OR '1'='1"}, "", 0, ""),
+			[]securityFindingForTest{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			oracle := feedback.NewSecurityOracle(newSecurityTestPayloadDict())
+			findings := oracle.CheckOperationCase(uuid.New(), tt.operationCase)
+			got := make([]securityFindingForTest, 0, len(findings))
+			for _, finding := range findings {
+				got = append(got, securityFindingForTest{finding.Typ, finding.Category, finding.Evidence})
+				assert.Equal(t, tt.operationCase.APIMethod, finding.APIMethod)
+				assert.Equal(t, tt.operationCase.ResponseStatusCode, finding.StatusCode)
+			}
+			assert.Equal(t, tt.want, got)
+			assert.Equal(t, findings, oracle.Findings)
+		})
+	}
+}
+
+// TestSecurityOracleDeduplication tests that findings of the same API method, type, category and evidence are reported once,
+// while those of other API methods are reported separately.
+func TestSecurityOracleDeduplication(t *testing.T) {
+	oracle := feedback.NewSecurityOracle(newSecurityTestPayloadDict())
+	testScenarioUUID := uuid.New()
+	newSQLErrorCase := func() *casemanager.OperationCase {
+		return newSecurityOperationCase(map[string]string{"id": "' OR '1'='1"}, "", 400, "You have an error in your SQL syntax")
+	}
+
+	assert.Len(t, oracle.CheckOperationCase(testScenarioUUID, newSQLErrorCase()), 1)
+	assert.Empty(t, oracle.CheckOperationCase(uuid.New(), newSQLErrorCase()))
+
+	// The same payload with a different evidence is a new finding.
+	serverErrorCase := newSQLErrorCase()
+	serverErrorCase.ResponseStatusCode = 500
+	assert.Len(t, oracle.CheckOperationCase(testScenarioUUID, serverErrorCase), 1)
+
+	// The same finding of another API method is a new finding.
+	otherMethodCase := newSQLErrorCase()
+	otherMethodCase.APIMethod.Method = "PUT"
+	assert.Len(t, oracle.CheckOperationCase(testScenarioUUID, otherMethodCase), 1)
+
+	assert.Len(t, oracle.Findings, 3)
+	assert.Equal(t, testScenarioUUID, oracle.Findings[0].TestScenarioUUID)
+}
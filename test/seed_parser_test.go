@@ -0,0 +1,145 @@
+package test
+
+import (
+	"testing"
+
+	"resttracefuzzer/pkg/casemanager"
+	"resttracefuzzer/pkg/parser"
+	"resttracefuzzer/pkg/static"
+	"resttracefuzzer/pkg/utils"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestSeedHARParser tests parsing HAR entries into seed scenarios by page, dropping headers which should not be replayed.
+func TestSeedHARParser(t *testing.T) {
+	har := `{"log": {
+		"pages": [{"id": "page_1", "title": "Checkout"}],
+		"entries": [
+			{"pageref": "page_1", "request": {"method": "post", "url": "http://localhost:8080/api/orders?dryRun=true", "headers": [
+				{"name": "Content-Type", "value": "application/json"},
+				{"name": "Authorization", "value": "Bearer stale"},
+				{"name": "Cookie", "value": "session=stale"},
+				{"name": "traceparent", "value": "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01"},
+				{"name": "X-B3-TraceId", "value": "0af7651916cd43dd8448eb211c80319c"},
+				{"name": "x-request-id", "value": "recorded"},
+				{"name": "baggage", "value": "k=v"},
+				{"name": "Host", "value": "localhost:8080"},
+				{"name": ":authority", "value": "localhost:8080"},
+				{"name": "X-Tenant", "value": "acme"}
+			], "postData": {"text": "{\"item\": \"book\"}"}}},
+			{"request": {"method": "GET", "url": "http://localhost:8080/api/orders/1", "headers": []}}
+		]
+	}}`
+	seedScenarios, err := parser.NewSeedHARParser().ParseFromBytes([]byte(har))
+	assert.NoError(t, err)
+	assert.Len(t, seedScenarios, 2)
+
+	assert.Equal(t, "Checkout", seedScenarios[0].Name)
+	assert.Len(t, seedScenarios[0].Requests, 1)
+	request := seedScenarios[0].Requests[0]
+	assert.Equal(t, "POST", request.Method)
+	assert.Equal(t, "http://localhost:8080/api/orders?dryRun=true", request.URL)
+	assert.Equal(t, map[string]string{"Content-Type": "application/json", "X-Tenant": "acme"}, request.Headers)
+	assert.Equal(t, `{"item": "book"}`, string(request.Body))
+
+	assert.Equal(t, "entries", seedScenarios[1].Name)
+	assert.Len(t, seedScenarios[1].Requests, 1)
+	assert.Nil(t, seedScenarios[1].Requests[0].Body)
+
+	_, err = parser.NewSeedHARParser().ParseFromBytes([]byte("not json"))
+	assert.Error(t, err)
+}
+
+// TestSeedPostmanParser tests parsing Postman collections into seed scenarios by folder, resolving variables.
+func TestSeedPostmanParser(t *testing.T) {
+	collection := `{
+		"info": {"name": "Shop"},
+		"variable": [{"key": "tenant", "value": "acme"}, {"key": "token", "value": "secret"}],
+		"item": [
+			{"name": "Health", "request": {"url": "{{baseUrl}}/health"}},
+			{"name": "Orders", "item": [
+				{"name": "Create", "request": {
+					"method": "post",
+					"url": {"raw": "{{baseUrl}}/orders/:orderId?tenant={{tenant}}", "variable": [{"key": "orderId", "value": "42"}]},
+					"header": [
+						{"key": "X-Tenant", "value": "{{tenant}}"},
+						{"key": "Authorization", "value": "Bearer {{token}}"},
+						{"key": "traceparent", "value": "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01"},
+						{"key": "X-Disabled", "value": "1", "disabled": true}
+					],
+					"body": {"mode": "raw", "raw": "{\"tenant\": \"{{tenant}}\"}"}
+				}}
+			]},
+			{"name": "Empty", "item": []}
+		]
+	}`
+	seedScenarios, err := parser.NewSeedPostmanParser().ParseFromBytes([]byte(collection))
+	assert.NoError(t, err)
+	assert.Len(t, seedScenarios, 2)
+
+	assert.Equal(t, "Shop", seedScenarios[0].Name)
+	assert.Equal(t, "GET", seedScenarios[0].Requests[0].Method)
+	assert.Equal(t, "/health", seedScenarios[0].Requests[0].URL)
+
+	assert.Equal(t, "Shop/Orders", seedScenarios[1].Name)
+	request := seedScenarios[1].Requests[0]
+	assert.Equal(t, "POST", request.Method)
+	assert.Equal(t, "/orders/42?tenant=acme", request.URL)
+	assert.Equal(t, map[string]string{"X-Tenant": "acme"}, request.Headers)
+	assert.Equal(t, `{"tenant": "acme"}`, string(request.Body))
+}
+
+// TestInjectSeedScenarios tests converting seed scenarios to injected test scenarios, with requests matched to API methods by path relative to the base URL.
+func TestInjectSeedScenarios(t *testing.T) {
+	doc, err := openapi3.NewLoader().LoadFromData([]byte(`{
+		"openapi": "3.0.0",
+		"info": {"title": "shop", "version": "1.0"},
+		"paths": {
+			"/orders": {"post": {"operationId": "createOrder", "responses": {"201": {"description": "created"}}}},
+			"/orders/{orderId}": {"get": {"operationId": "getOrder", "parameters": [{"name": "orderId", "in": "path", "required": true, "schema": {"type": "string"}}], "responses": {"200": {"description": "ok"}}}}
+		}
+	}`))
+	assert.NoError(t, err)
+	apiManager := static.NewAPIManager()
+	apiManager.InitFromDocs(doc, &openapi3.T{Paths: openapi3.NewPaths()})
+	caseManager := &casemanager.CaseManager{
+		APIManager:    apiManager,
+		TestScenarios: utils.NewPriorityQueue[*casemanager.TestScenario](nil),
+	}
+
+	seedScenarios := []*parser.SeedScenario{
+		{
+			Name: "checkout",
+			Requests: []*parser.SeedRequest{
+				{Method: "POST", URL: "http://localhost:8080/api/orders", Headers: map[string]string{"X-Tenant": "acme"}, Body: []byte(`{"item": "book"}`)},
+				{Method: "GET", URL: "http://localhost:8080/static/app.js"},
+				{Method: "GET", URL: "http://localhost:8080/api/orders/42?expand=items"},
+			},
+		},
+		{
+			Name:     "assets",
+			Requests: []*parser.SeedRequest{{Method: "GET", URL: "http://localhost:8080/favicon.ico"}},
+		},
+	}
+	injectedCount := caseManager.InjectSeedScenarios(seedScenarios, "http://localhost:8080/api")
+	assert.Equal(t, 1, injectedCount)
+
+	testScenario, err := caseManager.Pop()
+	assert.NoError(t, err)
+	assert.True(t, testScenario.Injected)
+	assert.Equal(t, casemanager.SeedScenarioEnergy, testScenario.Energy)
+	assert.Len(t, testScenario.OperationCases, 2)
+	createOrder := testScenario.OperationCases[0]
+	assert.Equal(t, static.SimpleAPIMethod{Method: "POST", Endpoint: "/orders", Typ: static.SimpleAPIMethodTypeHTTP}, createOrder.APIMethod)
+	assert.Equal(t, "acme", createOrder.RequestHeaders["X-Tenant"])
+	assert.Equal(t, `{"item": "book"}`, string(createOrder.RequestBody))
+	getOrder := testScenario.OperationCases[1]
+	assert.Equal(t, static.SimpleAPIMethod{Method: "GET", Endpoint: "/orders/{orderId}", Typ: static.SimpleAPIMethodTypeHTTP}, getOrder.APIMethod)
+	assert.Equal(t, map[string]string{"orderId": "42"}, getOrder.RequestPathParams)
+	assert.Equal(t, map[string]string{"expand": "items"}, getOrder.RequestQueryParams)
+
+	_, err = caseManager.Pop()
+	assert.Error(t, err)
+}
@@ -0,0 +1,93 @@
+package test
+
+import (
+	"testing"
+
+	"resttracefuzzer/pkg/static"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestResolveServerURLs tests resolving server URLs of the doc, with templated variables substituted by given values or defaults.
+func TestResolveServerURLs(t *testing.T) {
+	environmentVariable := &openapi3.ServerVariable{Default: "staging", Enum: []string{"staging", "prod"}}
+	portVariable := &openapi3.ServerVariable{Default: "8080"}
+	tests := []struct {
+		name           string
+		servers        openapi3.Servers
+		variableValues map[string]string
+		want           []string
+		wantErr        bool
+	}{
+		{"no servers", nil, nil, []string{}, false},
+		{
+			"defaults",
+			openapi3.Servers{
+				{URL: "https://{env}.example.com:{port}/v1", Variables: map[string]*openapi3.ServerVariable{"env": environmentVariable, "port": portVariable}},
+				{URL: "/v1"},
+			},
+			nil,
+			[]string{"https://staging.example.com:8080/v1", "/v1"},
+			false,
+		},
+		{
+			"overridden",
+			openapi3.Servers{
+				{URL: "https://{env}.example.com:{port}/v1", Variables: map[string]*openapi3.ServerVariable{"env": environmentVariable, "port": portVariable}},
+			},
+			map[string]string{"env": "prod", "port": "443"},
+			[]string{"https://prod.example.com:443/v1"},
+			false,
+		},
+		{
+			"not in enum",
+			openapi3.Servers{
+				{URL: "https://{env}.example.com", Variables: map[string]*openapi3.ServerVariable{"env": environmentVariable}},
+			},
+			map[string]string{"env": "dev"},
+			nil,
+			true,
+		},
+		{
+			"undefined variable",
+			openapi3.Servers{{URL: "https://{region}.example.com"}},
+			nil,
+			nil,
+			true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			serverURLs, err := static.ResolveServerURLs(&openapi3.T{Servers: tt.servers}, tt.variableValues)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, serverURLs)
+		})
+	}
+
+	serverURLs, err := static.ResolveServerURLs(nil, nil)
+	assert.NoError(t, err)
+	assert.Empty(t, serverURLs)
+}
+
+// TestIsAbsoluteServerURL tests telling absolute server URLs from relative ones.
+func TestIsAbsoluteServerURL(t *testing.T) {
+	tests := []struct {
+		serverURL string
+		want      bool
+	}{
+		{"https://example.com/v1", true},
+		{"http://localhost:8080", true},
+		{"/v1", false},
+		{"example.com/v1", false},
+		{"", false},
+		{"http://[::1", false},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, static.IsAbsoluteServerURL(tt.serverURL), tt.serverURL)
+	}
+}
@@ -0,0 +1,136 @@
+package test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"resttracefuzzer/pkg/resource"
+	"resttracefuzzer/pkg/static"
+	"resttracefuzzer/pkg/strategy"
+	"resttracefuzzer/pkg/utils"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/stretchr/testify/assert"
+)
+
+// specExampleDocFixture is an API doc documenting values of a parameter referring to a component schema, and of properties of a composite schema.
+const specExampleDocFixture = `{
+	"openapi": "3.0.0",
+	"info": {"title": "pet store", "version": "1.0"},
+	"paths": {
+		"/pets": {
+			"get": {
+				"parameters": [{"name": "limit", "in": "query", "example": 5, "schema": {"$ref": "#/components/schemas/Limit"}}],
+				"responses": {"200": {"description": "pets"}}
+			},
+			"post": {
+				"requestBody": {"content": {"application/json": {"schema": {"$ref": "#/components/schemas/Pet"}}}},
+				"responses": {"201": {"description": "created"}}
+			}
+		}
+	},
+	"components": {"schemas": {
+		"Limit": {"type": "integer", "example": 10},
+		"Base": {"type": "object", "properties": {"name": {"type": "string", "example": "rex"}}},
+		"Pet": {"allOf": [{"$ref": "#/components/schemas/Base"}, {"type": "object", "properties": {"tag": {"type": "string", "example": "dog"}}}]}
+	}}
+}`
+
+var (
+	// specExampleGetPets is the API method of the fixture documenting a parameter example.
+	specExampleGetPets = static.SimpleAPIMethod{Method: "GET", Endpoint: "/pets", Typ: static.SimpleAPIMethodTypeHTTP}
+
+	// specExamplePostPets is the API method of the fixture whose request body is a composite schema.
+	specExamplePostPets = static.SimpleAPIMethod{Method: "POST", Endpoint: "/pets", Typ: static.SimpleAPIMethodTypeHTTP}
+)
+
+// newSpecExampleFixture loads the API doc fixture, and harvests its documented values into a resource manager.
+func newSpecExampleFixture(t *testing.T) (*openapi3.T, *resource.ResourceManager) {
+	doc, err := openapi3.NewLoader().LoadFromData([]byte(specExampleDocFixture))
+	assert.NoError(t, err)
+	APIMap := map[static.SimpleAPIMethod]*openapi3.Operation{
+		specExampleGetPets:  doc.Paths.Find("/pets").Get,
+		specExamplePostPets: doc.Paths.Find("/pets").Post,
+	}
+	resourceManager := resource.NewResourceManager()
+	assert.Greater(t, resourceManager.StoreSpecExamples(APIMap), 0)
+	return doc, resourceManager
+}
+
+// getSpecExampleJSONs returns the JSON strings of the documented values of the schema path.
+func getSpecExampleJSONs(t *testing.T, resourceManager *resource.ResourceManager, schemaPath string) []string {
+	exampleJSONs := make([]string, 0)
+	for _, example := range resourceManager.SpecExampleMap[schemaPath] {
+		exampleJSON, err := json.Marshal(example.ToJSONObject())
+		assert.NoError(t, err)
+		exampleJSONs = append(exampleJSONs, string(exampleJSON))
+	}
+	return exampleJSONs
+}
+
+// TestStoreSpecExamples tests that documented values are indexed by schema paths,
+// where values of parameters are not indexed by the component schemas they refer to, and values of subschemas are indexed by the composite schema as well.
+func TestStoreSpecExamples(t *testing.T) {
+	doc, resourceManager := newSpecExampleFixture(t)
+	limitParam := doc.Paths.Find("/pets").Get.Parameters[0].Value
+
+	tests := []struct {
+		schemaPath string
+		want       []string
+	}{
+		{"#/components/schemas/Limit", []string{`10`}},
+		{resource.GetParameterSpecExamplePath(specExampleGetPets, limitParam), []string{`5`}},
+		{"#/components/schemas/Base/properties/name", []string{`"rex"`}},
+		{"#/components/schemas/Pet/properties/name", []string{`"rex"`}},
+		{"#/components/schemas/Pet/properties/tag", []string{`"dog"`}},
+		{resource.GetRequestBodySpecExamplePath(specExamplePostPets), []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.schemaPath, func(t *testing.T) {
+			assert.ElementsMatch(t, tt.want, getSpecExampleJSONs(t, resourceManager, tt.schemaPath))
+		})
+	}
+
+	assert.Equal(t, 1, resourceManager.RemoveSpecExamplesOfOperation(specExampleGetPets))
+	assert.Empty(t, getSpecExampleJSONs(t, resourceManager, resource.GetParameterSpecExamplePath(specExampleGetPets, limitParam)))
+	assert.Equal(t, []string{`10`}, getSpecExampleJSONs(t, resourceManager, "#/components/schemas/Limit"))
+}
+
+// TestGenerateValueForSchemaSpecExample tests generating documented values by the schema paths of parameters and request bodies,
+// including properties of resolved composite schemas.
+func TestGenerateValueForSchemaSpecExample(t *testing.T) {
+	doc, resourceManager := newSpecExampleFixture(t)
+	getPets, postPets := doc.Paths.Find("/pets").Get, doc.Paths.Find("/pets").Post
+	limitParam := getPets.Parameters[0].Value
+	valueStrategy := &strategy.SchemaToValueStrategy{
+		ResourceManager:      resourceManager,
+		ValueSourceWeightMap: strategy.NewConstantWeightMapStrategy(map[string]int{strategy.VALUE_SOURCE_SPEC_EXAMPLE: 1}),
+		FormatValueGenerator: strategy.NewFormatValueGenerator(),
+		Rand:                 utils.SharedRand,
+	}
+
+	tests := []struct {
+		name        string
+		schemaPaths []string
+		valueName   string
+		schema      *openapi3.SchemaRef
+		want        []string
+	}{
+		{"parameter", []string{resource.GetParameterSpecExamplePath(specExampleGetPets, limitParam)}, "limit", utils.GetParameterSchema(limitParam), []string{`5`, `10`}},
+		{"component schema only", nil, "limit", utils.GetParameterSchema(limitParam), []string{`10`}},
+		{"composite request body", []string{resource.GetRequestBodySpecExamplePath(specExamplePostPets)}, "", utils.GetRequestBodySchema(postPets.RequestBody), []string{`{"name":"rex","tag":"dog"}`}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for range 20 {
+				valueStrategy.SchemaPaths = tt.schemaPaths
+				value, err := valueStrategy.GenerateValueForSchema(tt.valueName, tt.schema)
+				assert.NoError(t, err)
+				valueJSON, err := json.Marshal(value.ToJSONObject())
+				assert.NoError(t, err)
+				assert.Contains(t, tt.want, string(valueJSON))
+				assert.Equal(t, tt.schemaPaths, valueStrategy.SchemaPaths)
+			}
+		})
+	}
+}
@@ -0,0 +1,54 @@
+package test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"resttracefuzzer/pkg/feedback"
+	"resttracefuzzer/pkg/report"
+	"resttracefuzzer/pkg/resource"
+	"resttracefuzzer/pkg/static"
+
+	"github.com/bytedance/sonic"
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestGenerateSystemReport tests that the response processer is required by the system report, and sections of other sources are left empty if they are nil.
+func TestGenerateSystemReport(t *testing.T) {
+	apiManager := static.NewAPIManager()
+	apiManager.InitFromDocs(&openapi3.T{Paths: openapi3.NewPaths()}, &openapi3.T{Paths: openapi3.NewPaths()})
+	responseProcesser := feedback.NewResponseProcesser(apiManager, resource.NewResourceManager())
+	crudOracle := feedback.NewCRUDOracle(apiManager)
+	crudOracle.Violations = append(crudOracle.Violations, &feedback.CRUDInvariantViolation{})
+
+	tests := []struct {
+		name               string
+		sources            *report.SystemReportSources
+		wantErr            bool
+		wantCRUDViolations int
+	}{
+		{"nil sources", nil, true, 0},
+		{"no response processer", &report.SystemReportSources{CRUDOracle: crudOracle}, true, 0},
+		{"response processer only", &report.SystemReportSources{ResponseProcesser: responseProcesser}, false, 0},
+		{"with CRUD oracle", &report.SystemReportSources{ResponseProcesser: responseProcesser, CRUDOracle: crudOracle}, false, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			outputPath := filepath.Join(t.TempDir(), "system_report.json")
+			err := report.NewSystemReporter(apiManager).GenerateSystemReport(tt.sources, outputPath)
+			if tt.wantErr {
+				assert.Error(t, err)
+				assert.NoFileExists(t, outputPath)
+				return
+			}
+			assert.NoError(t, err)
+			reportBytes, err := os.ReadFile(outputPath)
+			assert.NoError(t, err)
+			var systemTestReport report.SystemTestReport
+			assert.NoError(t, sonic.Unmarshal(reportBytes, &systemTestReport))
+			assert.Len(t, systemTestReport.CRUDInvariantViolations, tt.wantCRUDViolations)
+		})
+	}
+}
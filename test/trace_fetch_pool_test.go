@@ -0,0 +1,80 @@
+package test
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"resttracefuzzer/pkg/feedback/trace"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestTraceFetchPoolBoundedWorkers tests that traces are fetched concurrently by at most the given number of workers.
+func TestTraceFetchPoolBoundedWorkers(t *testing.T) {
+	const workerCount = 2
+	var running, maxRunning atomic.Int32
+	release := make(chan struct{})
+	pool := trace.NewTraceFetchPool(workerCount, func(ctx context.Context, traceID string) (*trace.SimplifiedTrace, []*trace.CallInfo, error) {
+		current := running.Add(1)
+		defer running.Add(-1)
+		for {
+			observed := maxRunning.Load()
+			if current <= observed || maxRunning.CompareAndSwap(observed, current) {
+				break
+			}
+		}
+		<-release
+		return &trace.SimplifiedTrace{TraceID: traceID}, []*trace.CallInfo{}, nil
+	})
+
+	traceIDs := []string{"a", "b", "c", "d"}
+	resultChans := make(chan (<-chan *trace.TraceFetchResult), len(traceIDs))
+	go func() {
+		// Submit blocks while all workers are busy.
+		for _, traceID := range traceIDs {
+			resultChans <- pool.Submit(context.Background(), traceID)
+		}
+		close(resultChans)
+	}()
+	assert.Eventually(t, func() bool { return running.Load() == workerCount }, time.Second, time.Millisecond)
+	close(release)
+
+	gotTraceIDs := make([]string, 0, len(traceIDs))
+	for resultChan := range resultChans {
+		result := <-resultChan
+		assert.NoError(t, result.Err)
+		assert.Equal(t, result.TraceID, result.Trace.TraceID)
+		gotTraceIDs = append(gotTraceIDs, result.TraceID)
+	}
+	pool.Wait()
+	assert.Equal(t, traceIDs, gotTraceIDs)
+	assert.EqualValues(t, workerCount, maxRunning.Load())
+	assert.EqualValues(t, 0, running.Load())
+}
+
+// TestTraceFetchPoolErrors tests that errors of fetching, and contexts done before a worker is available, are returned in results.
+func TestTraceFetchPoolErrors(t *testing.T) {
+	fetchErr := errors.New("fetch failed")
+	release := make(chan struct{})
+	pool := trace.NewTraceFetchPool(1, func(ctx context.Context, traceID string) (*trace.SimplifiedTrace, []*trace.CallInfo, error) {
+		<-release
+		return nil, nil, fetchErr
+	})
+
+	busyResultChan := pool.Submit(context.Background(), "busy")
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	canceledResult := <-pool.Submit(ctx, "canceled")
+	assert.Equal(t, "canceled", canceledResult.TraceID)
+	assert.ErrorIs(t, canceledResult.Err, context.Canceled)
+	assert.Nil(t, canceledResult.Trace)
+
+	close(release)
+	busyResult := <-busyResultChan
+	assert.Equal(t, "busy", busyResult.TraceID)
+	assert.ErrorIs(t, busyResult.Err, fetchErr)
+	pool.Wait()
+}
@@ -0,0 +1,139 @@
+package test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"resttracefuzzer/pkg/feedback/trace"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestTraceQueryToTraceQL tests converting trace queries to TraceQL.
+func TestTraceQueryToTraceQL(t *testing.T) {
+	tests := []struct {
+		name  string
+		query *trace.TraceQuery
+		want  string
+	}{
+		{"empty", &trace.TraceQuery{}, "{}"},
+		{
+			"service and span name",
+			&trace.TraceQuery{ServiceName: "cart", SpanName: "GET /cart"},
+			`{resource.service.name = "cart" && name = "GET /cart"}`,
+		},
+		{
+			"scoped and unscoped attributes sorted",
+			&trace.TraceQuery{Attributes: map[string]string{"span.http.route": "/cart", "http.method": "GET"}},
+			`{.http.method = "GET" && span.http.route = "/cart"}`,
+		},
+		{
+			"quotes escaped",
+			&trace.TraceQuery{ServiceName: `a"b`},
+			`{resource.service.name = "a\"b"}`,
+		},
+		{
+			"request header as array",
+			trace.NewRequestHeaderTraceQuery("X-Request-ID", "a.b"),
+			`{span.http.request.header.x-request-id =~ ".*a\\.b.*"}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.query.ToTraceQL())
+		})
+	}
+}
+
+// TestTraceQueryGetJaegerTags tests converting attributes of trace queries to Jaeger tags.
+func TestTraceQueryGetJaegerTags(t *testing.T) {
+	tests := []struct {
+		name  string
+		query *trace.TraceQuery
+		want  map[string]string
+	}{
+		{"empty", &trace.TraceQuery{}, map[string]string{}},
+		{
+			"scopes removed",
+			&trace.TraceQuery{Attributes: map[string]string{"span.http.route": "/cart", "resource.host.name": "h", "http.method": "GET"}},
+			map[string]string{"http.route": "/cart", "host.name": "h", "http.method": "GET"},
+		},
+		{
+			"request header as array",
+			trace.NewRequestHeaderTraceQuery("X-Request-ID", "abc"),
+			map[string]string{"http.request.header.x-request-id": `["abc"]`},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.query.GetJaegerTags())
+		})
+	}
+}
+
+// fakeQueryTraceFetcher is a TraceFetcher returning the traces, or the error, for all queries.
+type fakeQueryTraceFetcher struct {
+	traces  []*trace.SimplifiedTrace
+	err     error
+	queries []*trace.TraceQuery
+}
+
+func (f *fakeQueryTraceFetcher) FetchFromPath(path string) ([]*trace.SimplifiedTraceSpan, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (f *fakeQueryTraceFetcher) FetchAllFromRemote(ctx context.Context) ([]*trace.SimplifiedTrace, error) {
+	return f.FetchByQueryFromRemote(ctx, &trace.TraceQuery{})
+}
+
+func (f *fakeQueryTraceFetcher) FetchOneByIDFromRemote(ctx context.Context, traceID string) (*trace.SimplifiedTrace, error) {
+	return nil, trace.ErrTraceNotFound
+}
+
+func (f *fakeQueryTraceFetcher) FetchByQueryFromRemote(ctx context.Context, query *trace.TraceQuery) ([]*trace.SimplifiedTrace, error) {
+	f.queries = append(f.queries, query)
+	return f.traces, f.err
+}
+
+// TestTraceManagerPullTraceByQueryAndConvert tests pulling the trace of a request by query.
+func TestTraceManagerPullTraceByQueryAndConvert(t *testing.T) {
+	now := time.Now()
+	earlierTrace := &trace.SimplifiedTrace{TraceID: "earlier", StartTime: now.Add(-time.Second), SpanMap: map[string]*trace.SimplifiedTraceSpan{}}
+	laterTrace := &trace.SimplifiedTrace{TraceID: "later", StartTime: now, SpanMap: map[string]*trace.SimplifiedTraceSpan{}}
+	tests := []struct {
+		name        string
+		traces      []*trace.SimplifiedTrace
+		fetchErr    error
+		wantTraceID string
+		wantErr     error
+	}{
+		{"single trace", []*trace.SimplifiedTrace{laterTrace}, nil, "later", nil},
+		{"earliest of traces", []*trace.SimplifiedTrace{laterTrace, earlierTrace}, nil, "earlier", nil},
+		{"no trace", []*trace.SimplifiedTrace{}, nil, "", trace.ErrTraceNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fetcher := &fakeQueryTraceFetcher{traces: tt.traces, err: tt.fetchErr}
+			traceDB := trace.NewInMemoryTraceDB()
+			manager := &trace.TraceManager{
+				TraceFetcher:  fetcher,
+				TraceDBs:      []trace.TraceDB{traceDB},
+				FetchBreaker:  trace.NewTraceFetchBreaker(3, time.Minute),
+				WaitEstimator: trace.NewTraceWaitEstimator("Tempo", false, 0, 0, 90, 0),
+			}
+			query := trace.NewRequestHeaderTraceQuery("X-Request-ID", "abc")
+			gotTrace, callInfos, err := manager.PullTraceByQueryAndConvert(context.Background(), query)
+			assert.Equal(t, []*trace.TraceQuery{query}, fetcher.queries)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				assert.Nil(t, gotTrace)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantTraceID, gotTrace.TraceID)
+			assert.NotNil(t, callInfos)
+		})
+	}
+}
@@ -0,0 +1,173 @@
+package test
+
+import (
+	"context"
+	"math/rand/v2"
+	"testing"
+	"time"
+
+	"resttracefuzzer/pkg/feedback/trace"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestTraceWaitEstimatorGetPollInterval tests that poll intervals back off exponentially up to the cap, with jitter in [interval/2, interval).
+func TestTraceWaitEstimatorGetPollInterval(t *testing.T) {
+	estimator := trace.NewTraceWaitEstimator("Jaeger", true, 400*time.Millisecond, 10*time.Second, 90, 3)
+	estimator.Rand = rand.New(rand.NewPCG(1, 2))
+
+	tests := []struct {
+		retryCnt     int
+		wantInterval time.Duration
+	}{
+		{1, 100 * time.Millisecond},
+		{2, 200 * time.Millisecond},
+		{3, 400 * time.Millisecond},
+		{4, 800 * time.Millisecond},
+		{5, 800 * time.Millisecond},
+		{20, 800 * time.Millisecond},
+	}
+	for _, tt := range tests {
+		for range 100 {
+			interval := estimator.GetPollInterval(tt.retryCnt)
+			assert.GreaterOrEqual(t, interval, tt.wantInterval/2, "retry %d", tt.retryCnt)
+			assert.Less(t, interval, tt.wantInterval, "retry %d", tt.retryCnt)
+		}
+	}
+
+	// Intervals are reproducible with the same generator.
+	estimator.Rand = rand.New(rand.NewPCG(1, 2))
+	first := estimator.GetPollInterval(3)
+	estimator.Rand = rand.New(rand.NewPCG(1, 2))
+	assert.Equal(t, first, estimator.GetPollInterval(3))
+
+	// Retries back off likewise if it is not adaptive.
+	nonAdaptiveEstimator := trace.NewTraceWaitEstimator("Jaeger", false, 400*time.Millisecond, 10*time.Second, 90, 3)
+	assert.GreaterOrEqual(t, nonAdaptiveEstimator.GetPollInterval(1), 50*time.Millisecond)
+}
+
+// TestTraceWaitEstimatorTryAcquireRetry tests that retries are limited by the retry budget per fetched trace.
+func TestTraceWaitEstimatorTryAcquireRetry(t *testing.T) {
+	tests := []struct {
+		name             string
+		retryBudget      int
+		queryableCount   int
+		wantAcquiredCnt  int
+		wantExhaustedCnt int
+	}{
+		// 10 retries are allowed before any trace is fetched.
+		{"no trace fetched", 1, 0, 10, 1},
+		{"budget per fetched trace", 2, 5, 20, 1},
+		{"unlimited", 0, 0, 50, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			estimator := trace.NewTraceWaitEstimator("Jaeger", true, 400*time.Millisecond, 10*time.Second, 90, tt.retryBudget)
+			for range tt.queryableCount {
+				estimator.RecordQueryable(100*time.Millisecond, true)
+			}
+			acquiredCnt := 0
+			for range 50 {
+				if !estimator.TryAcquireRetry() {
+					break
+				}
+				acquiredCnt++
+			}
+			assert.Equal(t, tt.wantAcquiredCnt, acquiredCnt)
+			stats := estimator.GetStats()
+			assert.Equal(t, tt.wantAcquiredCnt, stats.RetryCount)
+			assert.Equal(t, tt.wantExhaustedCnt, stats.RetryBudgetExhaustedCount)
+			assert.Equal(t, tt.wantExhaustedCnt, stats.MissingCount)
+		})
+	}
+}
+
+// TestTraceWaitEstimatorBoundedDelays tests that percentiles are of the most recent delays, while counts and the max are of all delays.
+func TestTraceWaitEstimatorBoundedDelays(t *testing.T) {
+	estimator := trace.NewTraceWaitEstimator("Tempo", true, 100*time.Millisecond, 10*time.Second, 90, 0)
+	estimator.RecordQueryable(5*time.Second, false)
+	for range 1000 {
+		estimator.RecordQueryable(200*time.Millisecond, false)
+	}
+	stats := estimator.GetStats()
+	assert.Equal(t, 1001, stats.ObservedCount)
+	assert.InDelta(t, 200, stats.P50Ms, 0.001)
+	assert.InDelta(t, 200, stats.P99Ms, 0.001)
+	assert.InDelta(t, 5000, stats.MaxMs, 0.001)
+	assert.Equal(t, 200*time.Millisecond, estimator.GetWait())
+}
+
+// TestNewTraceWaitEstimatorMaxWait tests that the max wait is at least the initial wait, i.e., traces are not fetched again by default.
+func TestNewTraceWaitEstimatorMaxWait(t *testing.T) {
+	tests := []struct {
+		name        string
+		maxWait     time.Duration
+		wantMaxWait time.Duration
+	}{
+		{"unset", 0, time.Second},
+		{"less than initial wait", 500 * time.Millisecond, time.Second},
+		{"greater than initial wait", 5 * time.Second, 5 * time.Second},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			estimator := trace.NewTraceWaitEstimator("Jaeger", true, time.Second, tt.maxWait, 90, 3)
+			assert.Equal(t, tt.wantMaxWait, estimator.MaxWait)
+		})
+	}
+}
+
+// notYetQueryableTraceFetcher is a TraceFetcher of a trace becoming queryable at the queryableFetchCnt-th fetch by ID.
+type notYetQueryableTraceFetcher struct {
+	fakeQueryTraceFetcher
+
+	// queryableFetchCnt is the number of fetches until the trace is queryable.
+	queryableFetchCnt int
+
+	// fetchCnt is the number of fetches by ID.
+	fetchCnt int
+}
+
+func (f *notYetQueryableTraceFetcher) FetchOneByIDFromRemote(ctx context.Context, traceID string) (*trace.SimplifiedTrace, error) {
+	f.fetchCnt++
+	if f.fetchCnt < f.queryableFetchCnt {
+		return nil, trace.ErrTraceNotFound
+	}
+	return &trace.SimplifiedTrace{TraceID: traceID, SpanMap: map[string]*trace.SimplifiedTraceSpan{}}, nil
+}
+
+// TestTraceManagerPullTraceByIDRetries tests that traces not queryable yet are fetched again until the max wait, whether the wait is adaptive or not.
+func TestTraceManagerPullTraceByIDRetries(t *testing.T) {
+	tests := []struct {
+		name         string
+		adaptive     bool
+		maxWait      time.Duration
+		wantFound    bool
+		wantFetchCnt int
+	}{
+		{"adaptive", true, 10 * time.Second, true, 3},
+		{"not adaptive", false, 10 * time.Second, true, 3},
+		{"max wait unset", false, 0, false, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fetcher := &notYetQueryableTraceFetcher{queryableFetchCnt: 3}
+			estimator := trace.NewTraceWaitEstimator("Jaeger", tt.adaptive, 0, tt.maxWait, 90, 0)
+			manager := &trace.TraceManager{
+				TraceFetcher:  fetcher,
+				TraceDBs:      []trace.TraceDB{trace.NewInMemoryTraceDB()},
+				FetchBreaker:  trace.NewTraceFetchBreaker(3, time.Minute),
+				WaitEstimator: estimator,
+			}
+			gotTrace, err := manager.PullTraceByIDAndReturn(context.Background(), "abc")
+			assert.Equal(t, tt.wantFetchCnt, fetcher.fetchCnt)
+			if tt.wantFound {
+				assert.NoError(t, err)
+				assert.Equal(t, "abc", gotTrace.TraceID)
+				assert.Equal(t, 2, estimator.GetStats().RetryCount)
+			} else {
+				assert.ErrorIs(t, err, trace.ErrTraceNotFound)
+				assert.Equal(t, 1, estimator.GetStats().MissingCount)
+			}
+		})
+	}
+}
@@ -0,0 +1,57 @@
+package test
+
+import (
+	"testing"
+
+	"resttracefuzzer/internal/config"
+	"resttracefuzzer/pkg/static"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestAPIManagerOAuth2Operations tests that operations secured by OAuth2 are supported only if an OAuth2 grant type is configured.
+func TestAPIManagerOAuth2Operations(t *testing.T) {
+	doc, err := openapi3.NewLoader().LoadFromData([]byte(`{
+		"openapi": "3.0.0",
+		"info": {"title": "pets", "version": "1.0"},
+		"components": {"securitySchemes": {
+			"oauth": {"type": "oauth2", "flows": {"clientCredentials": {"tokenUrl": "http://auth/token", "scopes": {}}}},
+			"session": {"type": "apiKey", "in": "cookie", "name": "session"}
+		}},
+		"paths": {
+			"/pets": {"get": {"security": [{"oauth": []}], "responses": {"200": {"description": "ok"}}}},
+			"/sessions": {"get": {"security": [{"session": []}], "responses": {"200": {"description": "ok"}}}}
+		}
+	}`))
+	assert.NoError(t, err)
+	if config.GlobalConfig == nil {
+		config.InitConfig()
+	}
+	previousConfig := *config.GlobalConfig
+	defer func() { *config.GlobalConfig = previousConfig }()
+
+	tests := []struct {
+		name            string
+		grantType       string
+		wantUnsupported []string
+	}{
+		{"oauth2 not configured", "", []string{"/pets", "/sessions"}},
+		{"oauth2 configured", "client_credentials", []string{"/sessions"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config.GlobalConfig.Oauth2GrantType = tt.grantType
+			config.GlobalConfig.SkipUnsupportedOperations = true
+			apiManager := static.NewAPIManager()
+			apiManager.InitFromDocs(doc, &openapi3.T{Paths: openapi3.NewPaths()})
+			unsupportedEndpoints := make([]string, 0)
+			for _, unsupportedOperation := range apiManager.UnsupportedOperations {
+				unsupportedEndpoints = append(unsupportedEndpoints, unsupportedOperation.APIMethod.Endpoint)
+			}
+			assert.ElementsMatch(t, tt.wantUnsupported, unsupportedEndpoints)
+			_, exists := apiManager.APIMap[static.SimpleAPIMethod{Method: "GET", Endpoint: "/pets", Typ: static.SimpleAPIMethodTypeHTTP}]
+			assert.Equal(t, tt.grantType != "", exists)
+		})
+	}
+}
@@ -0,0 +1,198 @@
+package test
+
+import (
+	"math"
+	"regexp"
+	"slices"
+	"testing"
+	"unicode/utf8"
+
+	"resttracefuzzer/pkg/resource"
+	"resttracefuzzer/pkg/strategy"
+	"resttracefuzzer/pkg/utils"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/stretchr/testify/assert"
+)
+
+// newRandomSchemaToValueStrategy creates a SchemaToValueStrategy generating random values only, with constraints always satisfied.
+func newRandomSchemaToValueStrategy() *strategy.SchemaToValueStrategy {
+	return &strategy.SchemaToValueStrategy{
+		ResourceManager:      resource.NewResourceManager(),
+		ValueSourceWeightMap: strategy.NewConstantWeightMapStrategy(map[string]int{strategy.VALUE_SOURCE_RANDOM: 1}),
+		FormatValueGenerator: strategy.NewFormatValueGenerator(),
+		Rand:                 utils.SharedRand,
+	}
+}
+
+// TestGenerateValueForSchemaIntegerBounds tests that integers are generated within bounds, including bounds at or beyond the int64 range.
+func TestGenerateValueForSchemaIntegerBounds(t *testing.T) {
+	tests := []struct {
+		name   string
+		min    *float64
+		max    *float64
+		wantLo int64
+		wantHi int64
+	}{
+		{"small range", openapi3.Float64Ptr(-3), openapi3.Float64Ptr(3), -3, 3},
+		{"single value", openapi3.Float64Ptr(7), openapi3.Float64Ptr(7), 7, 7},
+		{"full int64 range", openapi3.Float64Ptr(math.MinInt64), openapi3.Float64Ptr(math.MaxInt64), math.MinInt64, math.MaxInt64},
+		{"beyond int64 range", openapi3.Float64Ptr(-1e30), openapi3.Float64Ptr(1e30), math.MinInt64, math.MaxInt64},
+		{"wider than max int64", openapi3.Float64Ptr(-1), openapi3.Float64Ptr(math.MaxInt64), -1, math.MaxInt64},
+		{"max only at int64 bound", nil, openapi3.Float64Ptr(math.MaxInt64), math.MaxInt64 - 114514, math.MaxInt64},
+		{"min only beyond int64 bound", openapi3.Float64Ptr(1e30), nil, math.MaxInt64, math.MaxInt64},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			valueStrategy := newRandomSchemaToValueStrategy()
+			schema := openapi3.NewIntegerSchema()
+			schema.Min = tt.min
+			schema.Max = tt.max
+			for range 100 {
+				value, err := valueStrategy.GenerateValueForSchema("count", schema.NewRef())
+				assert.NoError(t, err)
+				integer, ok := value.(*resource.ResourceInteger)
+				if !assert.True(t, ok) {
+					return
+				}
+				assert.GreaterOrEqual(t, integer.Value, tt.wantLo)
+				assert.LessOrEqual(t, integer.Value, tt.wantHi)
+			}
+		})
+	}
+}
+
+// TestGenerateValueForSchemaArraySize tests that sizes of generated arrays are capped by MaxArraySize and respect maxItems.
+func TestGenerateValueForSchemaArraySize(t *testing.T) {
+	tests := []struct {
+		name             string
+		minItems         uint64
+		maxItems         *uint64
+		largePercent     int
+		duplicatePercent int
+		wantSize         int
+		wantDuplicate    bool
+	}{
+		{"default", 0, nil, 0, 0, 1, false},
+		{"min items", 3, nil, 0, 0, 3, false},
+		{"huge min items", 1000000, nil, 0, 0, strategy.MaxArraySize, false},
+		{"huge max items", 0, openapi3.Uint64Ptr(math.MaxUint64), 0, 0, 1, false},
+		{"large of huge max items", 0, openapi3.Uint64Ptr(1000000), 100, 0, strategy.MaxArraySize, false},
+		{"duplicate", 0, nil, 0, 100, 2, true},
+		{"duplicate of max items 1", 0, openapi3.Uint64Ptr(1), 0, 100, 1, false},
+		{"duplicate of huge min items", 1000000, nil, 0, 100, strategy.MaxArraySize, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			valueStrategy := newRandomSchemaToValueStrategy()
+			valueStrategy.ArrayLargePercent = tt.largePercent
+			valueStrategy.ArrayDuplicatePercent = tt.duplicatePercent
+			schema := openapi3.NewArraySchema().WithItems(openapi3.NewStringSchema())
+			schema.MinItems = tt.minItems
+			schema.MaxItems = tt.maxItems
+			value, err := valueStrategy.GenerateValueForSchema("tags", schema.NewRef())
+			assert.NoError(t, err)
+			array, ok := value.(*resource.ResourceArray)
+			if !assert.True(t, ok) {
+				return
+			}
+			assert.Len(t, array.Value, tt.wantSize)
+			if tt.wantDuplicate {
+				for _, element := range array.Value {
+					assert.Equal(t, array.Value[0].String(), element.String())
+				}
+			}
+		})
+	}
+}
+
+// generateStringForSchemaForTest generates a string value of the schema by the strategy.
+func generateStringForSchemaForTest(t *testing.T, valueStrategy *strategy.SchemaToValueStrategy, schema *openapi3.Schema) string {
+	value, err := valueStrategy.GenerateValueForSchema("name", schema.NewRef())
+	assert.NoError(t, err)
+	str, ok := value.(*resource.ResourceString)
+	if !assert.True(t, ok) {
+		return ""
+	}
+	return str.Value
+}
+
+// TestGenerateValueForSchemaStringConstraints tests that strings satisfy minLength, maxLength and pattern,
+// where lengths are in characters and capped by MaxStringLength.
+func TestGenerateValueForSchemaStringConstraints(t *testing.T) {
+	tests := []struct {
+		name      string
+		minLength uint64
+		maxLength *uint64
+		pattern   string
+		wantLo    int
+		wantHi    int
+	}{
+		{"min length", 5, nil, "", 5, math.MaxInt},
+		{"max length", 0, openapi3.Uint64Ptr(3), "", 0, 3},
+		{"min and max length", 4, openapi3.Uint64Ptr(4), "", 4, 4},
+		{"huge min length", 1000000, nil, "", strategy.MaxStringLength, strategy.MaxStringLength},
+		{"huge max length", 0, openapi3.Uint64Ptr(math.MaxUint64), "", 0, strategy.MaxStringLength},
+		{"pattern", 0, nil, `^[a-z]{4}-\d{2}$`, 7, 7},
+		{"non-ASCII pattern truncated", 0, openapi3.Uint64Ptr(3), `^[éü]{10}$`, 3, 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			valueStrategy := newRandomSchemaToValueStrategy()
+			schema := openapi3.NewStringSchema()
+			schema.MinLength = tt.minLength
+			schema.MaxLength = tt.maxLength
+			schema.Pattern = tt.pattern
+			for range 20 {
+				value := generateStringForSchemaForTest(t, valueStrategy, schema)
+				assert.True(t, utf8.ValidString(value))
+				assert.GreaterOrEqual(t, utf8.RuneCountInString(value), tt.wantLo)
+				assert.LessOrEqual(t, utf8.RuneCountInString(value), tt.wantHi)
+				if tt.pattern != "" && tt.maxLength == nil {
+					assert.Regexp(t, regexp.MustCompile(tt.pattern), value)
+				}
+			}
+		})
+	}
+}
+
+// TestGenerateValueForSchemaConstraintViolation tests that values violate a declared constraint if violation is always chosen,
+// and satisfy the schema if no declared constraint can be violated.
+func TestGenerateValueForSchemaConstraintViolation(t *testing.T) {
+	tests := []struct {
+		name        string
+		schema      *openapi3.Schema
+		wantViolate bool
+	}{
+		{"min length", &openapi3.Schema{Type: &openapi3.Types{openapi3.TypeString}, MinLength: 5}, true},
+		{"max length", &openapi3.Schema{Type: &openapi3.Types{openapi3.TypeString}, MaxLength: openapi3.Uint64Ptr(3)}, true},
+		{"huge min length", &openapi3.Schema{Type: &openapi3.Types{openapi3.TypeString}, MinLength: 1000000}, true},
+		{"huge max length", &openapi3.Schema{Type: &openapi3.Types{openapi3.TypeString}, MaxLength: openapi3.Uint64Ptr(1000000)}, false},
+		{"pattern", &openapi3.Schema{Type: &openapi3.Types{openapi3.TypeString}, Pattern: `^\d+$`}, true},
+		{"enum", &openapi3.Schema{Type: &openapi3.Types{openapi3.TypeString}, Enum: []any{"a", "b"}}, true},
+		{"integer bounds", &openapi3.Schema{Type: &openapi3.Types{openapi3.TypeInteger}, Min: openapi3.Float64Ptr(10), Max: openapi3.Float64Ptr(20)}, true},
+		{"no constraint", openapi3.NewStringSchema(), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			valueStrategy := newRandomSchemaToValueStrategy()
+			valueStrategy.ConstraintViolationPercent = 100
+			for range 20 {
+				value, err := valueStrategy.GenerateValueForSchema("name", tt.schema.NewRef())
+				assert.NoError(t, err)
+				rawValue := value.ToJSONObject()
+				if str, ok := rawValue.(string); ok {
+					assert.LessOrEqual(t, utf8.RuneCountInString(str), strategy.MaxStringLength)
+				}
+				if tt.wantViolate {
+					assert.Error(t, tt.schema.VisitJSON(rawValue), "%v should violate the schema", rawValue)
+				} else {
+					assert.NoError(t, tt.schema.VisitJSON(rawValue))
+				}
+				if tt.schema.Enum != nil {
+					assert.False(t, slices.Contains(tt.schema.Enum, rawValue))
+				}
+			}
+		})
+	}
+}